@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const usage = `usage: admin <command> [flags]
+
+commands:
+  repair-stale-images   requeue (or fail) images stuck in pending/processing, e.g. after a worker crash
+  reencrypt-secrets     re-encrypt stored credentials with the primary key of SECRETS_ENCRYPTION_KEYS
+  create-user           create a user, optionally as an admin
+  reset-password        set a new password for a user and revoke their sessions
+  promote-admin         grant (or with -revoke, take away) admin rights
+  disable-user          block (or with -enable, unblock) a user from signing in
+  list-users            list users with their admin and disabled state
+  set-region            pin a user's new batches, fonts and watermarks to a data region
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	err := utils.LoadEnv()
+	if err != nil {
+		log.Printf("failed to load env: %v", err)
+	}
+	postgresURL := os.Getenv("POSTGRES_URL")
+	if postgresURL == "" {
+		log.Fatalln("POSTGRES_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", postgresURL)
+	if err != nil {
+		log.Fatalf("failed to connect sql database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		log.Fatalf("failed to ping db: %v", err)
+	}
+
+	dbQueries := database.New(db)
+
+	switch os.Args[1] {
+	case "repair-stale-images":
+		err = repairStaleImages(dbQueries, os.Args[2:])
+	case "reencrypt-secrets":
+		err = reencryptSecrets(dbQueries, os.Args[2:])
+	case "create-user":
+		err = createUser(db, dbQueries, os.Args[2:])
+	case "reset-password":
+		err = resetPassword(dbQueries, os.Args[2:])
+	case "promote-admin":
+		err = promoteAdmin(dbQueries, os.Args[2:])
+	case "disable-user":
+		err = disableUser(dbQueries, os.Args[2:])
+	case "list-users":
+		err = listUsers(dbQueries, os.Args[2:])
+	case "set-region":
+		err = setRegion(dbQueries, os.Args[2:])
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func repairStaleImages(dbQueries *database.Queries, args []string) error {
+	fs := flag.NewFlagSet("repair-stale-images", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", time.Hour, "only repair images not updated for this long")
+	markFailed := fs.Bool("mark-failed", false, "mark stale images as failed instead of requeuing them")
+	dryRun := fs.Bool("dry-run", false, "only print the images that would be repaired")
+	fs.Parse(args)
+
+	images, err := dbQueries.GetStaleImages(context.Background(), time.Now().UTC().Add(-*olderThan))
+	if err != nil {
+		return err
+	}
+	log.Printf("found %d stale images", len(images))
+	if *dryRun || len(images) == 0 {
+		for _, img := range images {
+			log.Printf("%s batch=%s status=%s updated_at=%s", img.ID, img.BatchID, img.Status, img.UpdatedAt.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	if *markFailed {
+		for _, img := range images {
+			err := dbQueries.FailImageByID(context.Background(), database.FailImageByIDParams{
+				ID:            img.ID,
+				FailureReason: sql.NullString{String: "processing stalled", Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		log.Printf("marked %d images as failed", len(images))
+		return nil
+	}
+
+	rabbitMqURL := os.Getenv("RABBIT_MQ_URL")
+	if rabbitMqURL == "" {
+		return fmt.Errorf("RABBIT_MQ_URL is not set")
+	}
+	conn, err := amqp.Dial(rabbitMqURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for _, img := range images {
+		err := dbQueries.UpdateImageByID(context.Background(), database.UpdateImageByIDParams{
+			ID:     img.ID,
+			Status: database.ImageStatusPending,
+		})
+		if err != nil {
+			return err
+		}
+		err = pubsub.PublishJSON(ch, utils.ImageGoDirect, utils.TaskQueue(img.BatchRegion), batch.NewImageTask(img.ID))
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("requeued %d images", len(images))
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// secretRow is one encrypted value of a secretColumn.
+type secretRow struct {
+	ID         uuid.UUID
+	TenantID   uuid.UUID
+	Ciphertext string
+}
+
+// secretColumn is a database column holding utils.Keyring ciphertexts. Every
+// table that stores credentials registers its columns in secretColumns so
+// reencrypt-secrets covers it.
+type secretColumn struct {
+	name   string
+	list   func(ctx context.Context, dbQueries *database.Queries) ([]secretRow, error)
+	update func(ctx context.Context, dbQueries *database.Queries, id uuid.UUID, ciphertext string) error
+}
+
+var secretColumns = []secretColumn{
+	{
+		name: "webhooks.secret",
+		list: func(ctx context.Context, dbQueries *database.Queries) ([]secretRow, error) {
+			webhooks, err := dbQueries.GetWebhookSecrets(ctx)
+			if err != nil {
+				return nil, err
+			}
+			rows := make([]secretRow, len(webhooks))
+			for i, w := range webhooks {
+				rows[i] = secretRow{ID: w.ID, TenantID: w.UserID, Ciphertext: w.Secret}
+			}
+			return rows, nil
+		},
+		update: func(ctx context.Context, dbQueries *database.Queries, id uuid.UUID, ciphertext string) error {
+			return dbQueries.UpdateWebhookSecret(ctx, database.UpdateWebhookSecretParams{ID: id, Secret: ciphertext})
+		},
+	},
+}
+
+// reencryptSecrets re-encrypts every stored secret that was not made with the
+// primary key of SECRETS_ENCRYPTION_KEYS. Run it after putting a new key
+// first, then drop the old key once nothing needs rotation.
+func reencryptSecrets(dbQueries *database.Queries, args []string) error {
+	fs := flag.NewFlagSet("reencrypt-secrets", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only count the secrets that would be re-encrypted")
+	fs.Parse(args)
+
+	spec := os.Getenv("SECRETS_ENCRYPTION_KEYS")
+	if spec == "" {
+		return fmt.Errorf("SECRETS_ENCRYPTION_KEYS is not set")
+	}
+	keyring, err := utils.NewKeyring(spec)
+	if err != nil {
+		return fmt.Errorf("invalid SECRETS_ENCRYPTION_KEYS: %w", err)
+	}
+
+	total := 0
+	for _, column := range secretColumns {
+		rows, err := column.list(context.Background(), dbQueries)
+		if err != nil {
+			return fmt.Errorf("%s: %w", column.name, err)
+		}
+		rotated := 0
+		for _, row := range rows {
+			if !keyring.NeedsRotation(row.Ciphertext) {
+				continue
+			}
+			rotated++
+			if *dryRun {
+				continue
+			}
+			plaintext, err := keyring.Decrypt(row.TenantID, row.Ciphertext)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", column.name, row.ID, err)
+			}
+			ciphertext, err := keyring.Encrypt(row.TenantID, plaintext)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", column.name, row.ID, err)
+			}
+			if err := column.update(context.Background(), dbQueries, row.ID, ciphertext); err != nil {
+				return fmt.Errorf("%s %s: %w", column.name, row.ID, err)
+			}
+		}
+		log.Printf("%s: %d of %d secrets need re-encryption", column.name, rotated, len(rows))
+		total += rotated
+	}
+	if *dryRun {
+		log.Printf("%d secrets would be re-encrypted with key %q", total, keyring.PrimaryKeyID())
+		return nil
+	}
+	log.Printf("re-encrypted %d secrets with key %q", total, keyring.PrimaryKeyID())
+	return nil
+}
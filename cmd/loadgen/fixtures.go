@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type fixture struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type size struct {
+	Width  int
+	Height int
+}
+
+// parseSizes parses a comma separated list of WxH dimensions, e.g. "640x480,1920x1080".
+func parseSizes(s string) ([]size, error) {
+	var sizes []size
+	for _, part := range strings.Split(s, ",") {
+		w, h, ok := strings.Cut(strings.TrimSpace(part), "x")
+		if !ok {
+			return nil, fmt.Errorf("invalid size %q, expected WxH", part)
+		}
+		width, err := strconv.Atoi(w)
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("invalid width in %q", part)
+		}
+		height, err := strconv.Atoi(h)
+		if err != nil || height <= 0 {
+			return nil, fmt.Errorf("invalid height in %q", part)
+		}
+		sizes = append(sizes, size{Width: width, Height: height})
+	}
+	return sizes, nil
+}
+
+// generateFixtures renders count synthetic JPEGs cycling through sizes. The
+// same seed always produces the same images, so runs are comparable.
+func generateFixtures(seed uint64, count int, sizes []size) ([]fixture, error) {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	fixtures := make([]fixture, count)
+	for i := range fixtures {
+		sz := sizes[i%len(sizes)]
+		img := renderImage(rng, sz.Width, sz.Height)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, err
+		}
+		fixtures[i] = fixture{
+			Name:        fmt.Sprintf("fixture-%03d-%dx%d.jpg", i, sz.Width, sz.Height),
+			ContentType: "image/jpeg",
+			Data:        buf.Bytes(),
+		}
+	}
+	return fixtures, nil
+}
+
+// generateWatermark renders a small semi-transparent PNG watermark.
+func generateWatermark() (fixture, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 200; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: uint8(64 + (x+y)%128)})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fixture{}, err
+	}
+	return fixture{Name: "watermark.png", ContentType: "image/png", Data: buf.Bytes()}, nil
+}
+
+// renderImage draws a gradient with per-pixel noise so the encoded size is
+// close to that of a real photo rather than a trivially compressible image.
+func renderImage(rng *rand.Rand, width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	base := color.RGBA{R: uint8(rng.IntN(256)), G: uint8(rng.IntN(256)), B: uint8(rng.IntN(256)), A: 255}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			noise := uint8(rng.IntN(32))
+			img.SetRGBA(x, y, color.RGBA{
+				R: base.R + uint8(x*255/width) + noise,
+				G: base.G + uint8(y*255/height) + noise,
+				B: base.B + noise,
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func writeFixtures(dir string, fixtures []fixture) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, f := range fixtures {
+		if err := os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errRejected = errors.New("batch rejected by server")
+
+type config struct {
+	URL          string
+	Users        int
+	Batches      int
+	Images       int
+	Sizes        string
+	Rate         float64
+	Seed         uint64
+	Watermark    bool
+	Poll         time.Duration
+	Timeout      time.Duration
+	FixturesDir  string
+	FixturesOnly bool
+}
+
+type results struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	rejected  int
+	timedOut  int
+	errors    int
+	imgFailed int
+}
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.URL, "url", "http://localhost:3000/api/v1", "API base URL")
+	flag.IntVar(&cfg.Users, "users", 5, "number of users to register")
+	flag.IntVar(&cfg.Batches, "batches", 20, "total number of batches to submit")
+	flag.IntVar(&cfg.Images, "images", 3, "images per batch")
+	flag.StringVar(&cfg.Sizes, "sizes", "640x480,1920x1080", "comma separated image sizes (WxH), cycled per image")
+	flag.Float64Var(&cfg.Rate, "rate", 1, "target batch submissions per second")
+	flag.Uint64Var(&cfg.Seed, "seed", 1, "seed for the synthetic image generator")
+	flag.BoolVar(&cfg.Watermark, "watermark", true, "attach a watermark to every batch")
+	flag.DurationVar(&cfg.Poll, "poll", 500*time.Millisecond, "interval between batch status checks")
+	flag.DurationVar(&cfg.Timeout, "timeout", 5*time.Minute, "maximum time to wait for a batch to finish processing")
+	flag.StringVar(&cfg.FixturesDir, "fixtures-dir", "", "also write the generated images to this directory")
+	flag.BoolVar(&cfg.FixturesOnly, "fixtures-only", false, "only generate fixtures into -fixtures-dir, do not run the load test")
+	flag.Parse()
+
+	if cfg.Users <= 0 || cfg.Batches <= 0 || cfg.Images <= 0 || cfg.Rate <= 0 {
+		log.Fatalln("users, batches, images and rate must be positive")
+	}
+	if cfg.FixturesOnly && cfg.FixturesDir == "" {
+		log.Fatalln("-fixtures-only requires -fixtures-dir")
+	}
+
+	sizes, err := parseSizes(cfg.Sizes)
+	if err != nil {
+		log.Fatalf("invalid -sizes: %v", err)
+	}
+	fixtures, err := generateFixtures(cfg.Seed, cfg.Images, sizes)
+	if err != nil {
+		log.Fatalf("failed to generate fixtures: %v", err)
+	}
+	var watermark *fixture
+	if cfg.Watermark {
+		wm, err := generateWatermark()
+		if err != nil {
+			log.Fatalf("failed to generate watermark: %v", err)
+		}
+		watermark = &wm
+	}
+
+	if cfg.FixturesDir != "" {
+		all := fixtures
+		if watermark != nil {
+			all = append(append([]fixture{}, fixtures...), *watermark)
+		}
+		if err := writeFixtures(cfg.FixturesDir, all); err != nil {
+			log.Fatalf("failed to write fixtures: %v", err)
+		}
+		log.Printf("wrote %d fixtures to %s", len(all), cfg.FixturesDir)
+	}
+	if cfg.FixturesOnly {
+		return
+	}
+
+	client := &apiClient{baseURL: strings.TrimRight(cfg.URL, "/"), http: &http.Client{Timeout: time.Minute}}
+	runID := time.Now().UTC().Format("20060102150405")
+
+	tokens := make([]string, cfg.Users)
+	for i := range tokens {
+		email := fmt.Sprintf("loadgen-%s-%d@example.com", runID, i)
+		password := fmt.Sprintf("Loadgen-%s-%d!", runID, i)
+		if err := client.register(email, password); err != nil {
+			log.Fatalf("failed to register %s: %v", email, err)
+		}
+		token, err := client.login(email, password)
+		if err != nil {
+			log.Fatalf("failed to login %s: %v", email, err)
+		}
+		tokens[i] = token
+	}
+	log.Printf("registered %d users", len(tokens))
+
+	res := &results{}
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.Rate))
+	defer ticker.Stop()
+
+	start := time.Now()
+	for i := 0; i < cfg.Batches; i++ {
+		if i > 0 {
+			<-ticker.C
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("loadgen-%s-%04d", runID, i)
+			runBatch(client, tokens[i%len(tokens)], name, fixtures, watermark, cfg, res)
+		}(i)
+	}
+	wg.Wait()
+
+	res.report(os.Stdout, cfg, time.Since(start))
+}
+
+func runBatch(client *apiClient, token, name string, fixtures []fixture, watermark *fixture, cfg config, res *results) {
+	start := time.Now()
+	batchID, err := client.createBatch(token, name, fixtures, watermark)
+	if errors.Is(err, errRejected) {
+		res.mu.Lock()
+		res.rejected++
+		res.mu.Unlock()
+		return
+	}
+	if err != nil {
+		log.Printf("%s: %v", name, err)
+		res.mu.Lock()
+		res.errors++
+		res.mu.Unlock()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	failed, err := client.waitForBatch(ctx, token, batchID, cfg.Poll)
+	latency := time.Since(start)
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		res.timedOut++
+	case err != nil:
+		log.Printf("%s: %v", name, err)
+		res.errors++
+	default:
+		res.latencies = append(res.latencies, latency)
+		res.imgFailed += failed
+	}
+}
+
+func (r *results) report(w io.Writer, cfg config, elapsed time.Duration) {
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+
+	fmt.Fprintf(w, "\nbatches:     %d submitted, %d completed, %d rejected (503), %d timed out, %d errors\n",
+		cfg.Batches, len(r.latencies), r.rejected, r.timedOut, r.errors)
+	fmt.Fprintf(w, "images:      %d per batch, %d failed processing\n", cfg.Images, r.imgFailed)
+	fmt.Fprintf(w, "elapsed:     %s\n", elapsed.Round(time.Millisecond))
+	if elapsed > 0 {
+		fmt.Fprintf(w, "throughput:  %.2f images/s\n", float64(len(r.latencies)*cfg.Images)/elapsed.Seconds())
+	}
+	if len(r.latencies) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "end-to-end latency (submit -> all images processed):")
+	for _, p := range []float64{50, 90, 95, 99} {
+		fmt.Fprintf(w, "  p%-3.0f %s\n", p, percentile(r.latencies, p).Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "  max  %s\n", r.latencies[len(r.latencies)-1].Round(time.Millisecond))
+}
+
+// percentile returns the nearest-rank percentile of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+type apiClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+type envelope struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type batchDetail struct {
+	Images []struct {
+		Status string `json:"status"`
+	} `json:"images"`
+}
+
+// finished reports whether no image of the batch is left to process, and how
+// many of them failed.
+func (b batchDetail) finished() (bool, int) {
+	failed := 0
+	for _, img := range b.Images {
+		switch img.Status {
+		case "pending", "processing":
+			return false, 0
+		case "failed":
+			failed++
+		}
+	}
+	return len(b.Images) > 0, failed
+}
+
+func (a *apiClient) register(email, password string) error {
+	body := map[string]string{"email": email, "password": password, "confirm_password": password}
+	_, err := a.doJSON(http.MethodPost, "/register", "", body)
+	return err
+}
+
+func (a *apiClient) login(email, password string) (string, error) {
+	data, err := a.doJSON(http.MethodPost, "/login", "", map[string]string{"email": email, "password": password})
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return "", err
+	}
+	return res.AccessToken, nil
+}
+
+func (a *apiClient) createBatch(token, name string, fixtures []fixture, watermark *fixture) (string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("name", name); err != nil {
+		return "", err
+	}
+	for _, f := range fixtures {
+		if err := writePart(mw, "files", f); err != nil {
+			return "", err
+		}
+	}
+	if watermark != nil {
+		if err := writePart(mw, "watermark", *watermark); err != nil {
+			return "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.baseURL+"/batches", &buf)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		return "", errRejected
+	}
+	data, err := decodeEnvelope(resp)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// waitForBatch polls the batch until every one of its images has finished
+// processing, returning how many of them failed.
+func (a *apiClient) waitForBatch(ctx context.Context, token, batchID string, interval time.Duration) (int, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		data, err := a.doJSON(http.MethodGet, "/batches/"+batchID, token, nil)
+		if err != nil {
+			return 0, err
+		}
+		var batch batchDetail
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return 0, err
+		}
+		if done, failed := batch.finished(); done {
+			return failed, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *apiClient) doJSON(method, path, token string, body any) (json.RawMessage, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, a.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return decodeEnvelope(resp)
+}
+
+func decodeEnvelope(resp *http.Response) (json.RawMessage, error) {
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return nil, fmt.Errorf("%s: %w", resp.Status, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, env.Message)
+	}
+	return env.Data, nil
+}
+
+func writePart(mw *multipart.Writer, field string, f fixture) error {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, f.Name))
+	h.Set("Content-Type", f.ContentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(f.Data)
+	return err
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rickyroynardson/image-go/internal/faults"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const usage = `usage: server [command]
+
+Without a command the API server is started.
+
+commands:
+  config validate   load the configuration, check every dependency and print the redacted effective config
+`
+
+// serverConfig is the environment the API server runs with.
+type serverConfig struct {
+	Env                    string
+	PostgresURL            string
+	JwtSecret              string
+	S3Bucket               string
+	S3CfDistribution       string
+	Regions                []regionConfig
+	RabbitMqURL            string
+	QueueMaxBacklog        int
+	MaxActiveBatches       int
+	LoginMaxFailedAttempts int
+	UploadBandwidthLimit   int
+	PasswordPolicy         utils.PasswordPolicy
+	PasswordHIBPURL        string
+	AppURL                 string
+	SMTPURL                string
+	MailFrom               string
+	Secrets                *utils.Keyring
+	FingerprintKey         []byte
+	FeatureFlags           []string
+	Faults                 faults.Rates
+	// ImportSources are the buckets, optionally narrowed to a prefix as
+	// bucket/prefix, that batches can be created from.
+	ImportSources []string
+	// TrustedProxies are the ranges whose X-Forwarded-For header is
+	// believed when working out client addresses.
+	TrustedProxies []netip.Prefix
+	// SwaggerUI serves the API explorer at /swagger/.
+	SwaggerUI bool
+}
+
+// regionConfig is the storage of an additional data region users can be
+// pinned to.
+type regionConfig struct {
+	Name             string
+	S3Bucket         string
+	S3CfDistribution string
+	// AWSRegion overrides the AWS region of the bucket's client.
+	AWSRegion string
+}
+
+// regionNamePattern restricts region names to what is safe in queue names
+// and environment variable suffixes.
+var regionNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// loadServerConfig reads the whole configuration and reports every problem at
+// once instead of stopping at the first one.
+func loadServerConfig() (serverConfig, error) {
+	cfg := serverConfig{Env: os.Getenv("APP_ENV")}
+	var errs []error
+
+	required := func(key string, dst *string) {
+		*dst = os.Getenv(key)
+		if *dst == "" {
+			errs = append(errs, fmt.Errorf("%s is not set", key))
+		}
+	}
+	optionalInt := func(key string, fallback int, dst *int) {
+		v, err := utils.GetEnvInt(key, fallback)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", key, err))
+		}
+		*dst = v
+	}
+
+	required("POSTGRES_URL", &cfg.PostgresURL)
+	required("JWT_SECRET", &cfg.JwtSecret)
+	required("S3_BUCKET", &cfg.S3Bucket)
+	required("S3_CF_DISTRIBUTION", &cfg.S3CfDistribution)
+	required("RABBIT_MQ_URL", &cfg.RabbitMqURL)
+	if v := os.Getenv("DATA_REGIONS"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if !regionNamePattern.MatchString(name) {
+				errs = append(errs, fmt.Errorf("invalid DATA_REGIONS: bad region name %q", name))
+				continue
+			}
+			suffix := "_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+			region := regionConfig{Name: name, AWSRegion: os.Getenv("AWS_REGION" + suffix)}
+			required("S3_BUCKET"+suffix, &region.S3Bucket)
+			required("S3_CF_DISTRIBUTION"+suffix, &region.S3CfDistribution)
+			cfg.Regions = append(cfg.Regions, region)
+		}
+	}
+	optionalInt("QUEUE_MAX_BACKLOG", 0, &cfg.QueueMaxBacklog)
+	optionalInt("MAX_ACTIVE_BATCHES", 0, &cfg.MaxActiveBatches)
+	optionalInt("LOGIN_MAX_FAILED_ATTEMPTS", 0, &cfg.LoginMaxFailedAttempts)
+	optionalInt("UPLOAD_BANDWIDTH_LIMIT", 0, &cfg.UploadBandwidthLimit)
+
+	policy, err := loadPasswordPolicy()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid password policy: %w", err))
+	}
+	cfg.PasswordPolicy = policy
+	cfg.PasswordHIBPURL = os.Getenv("PASSWORD_HIBP_URL")
+
+	cfg.AppURL = cmp.Or(os.Getenv("APP_URL"), "http://localhost:3000")
+	cfg.SMTPURL = os.Getenv("SMTP_URL")
+	cfg.MailFrom = os.Getenv("MAIL_FROM")
+	if _, err := utils.NewMailer(cfg.SMTPURL, cfg.MailFrom); err != nil {
+		errs = append(errs, fmt.Errorf("invalid mail config: %w", err))
+	}
+	if spec := os.Getenv("SECRETS_ENCRYPTION_KEYS"); spec != "" {
+		cfg.Secrets, err = utils.NewKeyring(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid SECRETS_ENCRYPTION_KEYS: %w", err))
+		}
+	}
+
+	if cfg.FingerprintKey, err = utils.LoadFingerprintKey(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid FINGERPRINT_KEY: %w", err))
+	}
+
+	if v := os.Getenv("FEATURE_FLAGS"); v != "" {
+		for _, flag := range strings.Split(v, ",") {
+			flag = strings.TrimSpace(flag)
+			if !utils.ValidFeatureFlag(flag) {
+				errs = append(errs, fmt.Errorf("invalid FEATURE_FLAGS: bad flag %q", flag))
+				continue
+			}
+			cfg.FeatureFlags = append(cfg.FeatureFlags, flag)
+		}
+	}
+	if cfg.Faults, err = faults.LoadRates(); err != nil {
+		errs = append(errs, err)
+	}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		for _, cidr := range strings.Split(v, ",") {
+			prefix, err := utils.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err))
+				continue
+			}
+			cfg.TrustedProxies = append(cfg.TrustedProxies, prefix)
+		}
+	}
+	if v := os.Getenv("S3_IMPORT_SOURCES"); v != "" {
+		for _, source := range strings.Split(v, ",") {
+			source = strings.TrimSpace(source)
+			bucket, _, _ := strings.Cut(source, "/")
+			if bucket == "" {
+				errs = append(errs, fmt.Errorf("invalid S3_IMPORT_SOURCES: bad source %q", source))
+				continue
+			}
+			// Storage buckets hold every user's objects.
+			if bucket == cfg.S3Bucket || slices.ContainsFunc(cfg.Regions, func(r regionConfig) bool { return r.S3Bucket == bucket }) {
+				errs = append(errs, fmt.Errorf("invalid S3_IMPORT_SOURCES: %q is a storage bucket", bucket))
+				continue
+			}
+			cfg.ImportSources = append(cfg.ImportSources, source)
+		}
+	}
+
+	swaggerUI, err := utils.GetEnvBool("SWAGGER_UI", cfg.Env != "production")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid SWAGGER_UI: %w", err))
+	}
+	cfg.SwaggerUI = swaggerUI
+
+	return cfg, errors.Join(errs...)
+}
+
+// features lists the optional features this configuration turns on, as
+// reported by GET /version.
+func (cfg serverConfig) features() []string {
+	var features []string
+	if cfg.Secrets != nil {
+		features = append(features, "webhooks")
+	}
+	if cfg.SMTPURL != "" {
+		features = append(features, "email")
+	}
+	if cfg.FingerprintKey != nil {
+		features = append(features, "invisible_watermark")
+	}
+	if cfg.PasswordHIBPURL != "" {
+		features = append(features, "breached_password_check")
+	}
+	if len(cfg.Regions) > 0 {
+		features = append(features, "data_residency")
+	}
+	if cfg.QueueMaxBacklog > 0 {
+		features = append(features, "queue_backpressure")
+	}
+	if cfg.MaxActiveBatches > 0 {
+		features = append(features, "active_batch_quota")
+	}
+	if cfg.LoginMaxFailedAttempts > 0 {
+		features = append(features, "login_lockout")
+	}
+	if cfg.UploadBandwidthLimit > 0 {
+		features = append(features, "upload_bandwidth_limit")
+	}
+	if cfg.Faults.Enabled() {
+		features = append(features, "fault_injection")
+	}
+	if len(cfg.ImportSources) > 0 {
+		features = append(features, "s3_import")
+	}
+	return features
+}
+
+func loadPasswordPolicy() (utils.PasswordPolicy, error) {
+	var policy utils.PasswordPolicy
+	var err error
+	if policy.MinLength, err = utils.GetEnvInt("PASSWORD_MIN_LENGTH", 8); err != nil {
+		return policy, fmt.Errorf("PASSWORD_MIN_LENGTH: %w", err)
+	}
+	if policy.RequireUpper, err = utils.GetEnvBool("PASSWORD_REQUIRE_UPPER", false); err != nil {
+		return policy, fmt.Errorf("PASSWORD_REQUIRE_UPPER: %w", err)
+	}
+	if policy.RequireLower, err = utils.GetEnvBool("PASSWORD_REQUIRE_LOWER", false); err != nil {
+		return policy, fmt.Errorf("PASSWORD_REQUIRE_LOWER: %w", err)
+	}
+	if policy.RequireDigit, err = utils.GetEnvBool("PASSWORD_REQUIRE_DIGIT", false); err != nil {
+		return policy, fmt.Errorf("PASSWORD_REQUIRE_DIGIT: %w", err)
+	}
+	if policy.RequireSymbol, err = utils.GetEnvBool("PASSWORD_REQUIRE_SYMBOL", false); err != nil {
+		return policy, fmt.Errorf("PASSWORD_REQUIRE_SYMBOL: %w", err)
+	}
+	if hibpURL := os.Getenv("PASSWORD_HIBP_URL"); hibpURL != "" {
+		policy.BreachedClient = utils.NewHIBPClient(hibpURL)
+	}
+	return policy, nil
+}
+
+// runCommand handles the server's subcommands and returns the exit code.
+func runCommand(args []string) int {
+	if len(args) == 2 && args[0] == "config" && args[1] == "validate" {
+		return validateConfig(os.Stdout)
+	}
+	fmt.Print(usage)
+	return 2
+}
+
+// dependencyCheck is one check run by config validate.
+type dependencyCheck struct {
+	name string
+	run  func(context.Context, serverConfig) error
+}
+
+// validateConfig prints the redacted effective config and checks each
+// dependency, returning 1 if anything is missing or unreachable.
+func validateConfig(w io.Writer) int {
+	if err := utils.LoadEnv(); err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+	cfg, cfgErr := loadServerConfig()
+
+	fmt.Fprintln(w, "effective config:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range cfg.redacted() {
+		fmt.Fprintf(tw, "  %s\t%s\n", row[0], row[1])
+	}
+	tw.Flush()
+
+	if cfgErr != nil {
+		fmt.Fprintln(w, "\nconfig errors:")
+		for _, line := range strings.Split(cfgErr.Error(), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	checks := []dependencyCheck{
+		{"postgres", checkPostgres},
+		{"schema", checkSchema},
+		{"rabbitmq", checkRabbitMQ},
+		{"s3 bucket", checkS3Bucket},
+		{"cloudfront", checkCfDistribution},
+	}
+	for _, region := range cfg.Regions {
+		checks = append(checks,
+			dependencyCheck{"s3 bucket " + region.Name, func(ctx context.Context, _ serverConfig) error {
+				return checkBucket(ctx, region.S3Bucket, region.AWSRegion)
+			}},
+			dependencyCheck{"cloudfront " + region.Name, func(ctx context.Context, _ serverConfig) error {
+				return checkDistribution(ctx, region.S3CfDistribution)
+			}},
+		)
+	}
+
+	fmt.Fprintln(w, "\nchecks:")
+	failed := false
+	for _, check := range checks {
+		if err := check.run(ctx, cfg); err != nil {
+			failed = true
+			fmt.Fprintf(w, "  FAIL  %s: %v\n", check.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "  OK    %s\n", check.name)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// ipExtractor works out client addresses: from X-Forwarded-For when the
+// request came through a trusted proxy, otherwise from the connection, so
+// clients cannot pick the address IP allowlists and auth events see.
+func (cfg serverConfig) ipExtractor() echo.IPExtractor {
+	if len(cfg.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, prefix := range cfg.TrustedProxies {
+		_, ipNet, _ := net.ParseCIDR(prefix.String())
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// redacted returns the config as key/value rows with secrets masked.
+func (cfg serverConfig) redacted() [][2]string {
+	env := cfg.Env
+	if env == "" {
+		env = "(none)"
+	}
+	secret := ""
+	if cfg.JwtSecret != "" {
+		secret = "[redacted]"
+	}
+	secretsKeys := ""
+	if cfg.Secrets != nil {
+		secretsKeys = fmt.Sprintf("[redacted] (primary %s)", cfg.Secrets.PrimaryKeyID())
+	}
+	fingerprintKey := ""
+	if cfg.FingerprintKey != nil {
+		fingerprintKey = "[redacted]"
+	}
+	regions := make([]string, len(cfg.Regions))
+	var regionRows [][2]string
+	for i, region := range cfg.Regions {
+		regions[i] = region.Name
+		suffix := "_" + strings.ToUpper(strings.ReplaceAll(region.Name, "-", "_"))
+		regionRows = append(regionRows,
+			[2]string{"S3_BUCKET" + suffix, region.S3Bucket},
+			[2]string{"S3_CF_DISTRIBUTION" + suffix, region.S3CfDistribution},
+			[2]string{"AWS_REGION" + suffix, region.AWSRegion},
+		)
+	}
+	proxies := make([]string, len(cfg.TrustedProxies))
+	for i, prefix := range cfg.TrustedProxies {
+		proxies[i] = prefix.String()
+	}
+	rows := [][2]string{
+		{"APP_ENV", env},
+		{"POSTGRES_URL", redactURL(cfg.PostgresURL)},
+		{"JWT_SECRET", secret},
+		{"S3_BUCKET", cfg.S3Bucket},
+		{"S3_CF_DISTRIBUTION", cfg.S3CfDistribution},
+		{"DATA_REGIONS", strings.Join(regions, ",")},
+	}
+	rows = append(rows, regionRows...)
+	return append(rows, [][2]string{
+		{"RABBIT_MQ_URL", redactURL(cfg.RabbitMqURL)},
+		{"QUEUE_MAX_BACKLOG", fmt.Sprint(cfg.QueueMaxBacklog)},
+		{"MAX_ACTIVE_BATCHES", fmt.Sprint(cfg.MaxActiveBatches)},
+		{"LOGIN_MAX_FAILED_ATTEMPTS", fmt.Sprint(cfg.LoginMaxFailedAttempts)},
+		{"UPLOAD_BANDWIDTH_LIMIT", fmt.Sprint(cfg.UploadBandwidthLimit)},
+		{"PASSWORD_MIN_LENGTH", fmt.Sprint(cfg.PasswordPolicy.MinLength)},
+		{"PASSWORD_REQUIRE_UPPER", fmt.Sprint(cfg.PasswordPolicy.RequireUpper)},
+		{"PASSWORD_REQUIRE_LOWER", fmt.Sprint(cfg.PasswordPolicy.RequireLower)},
+		{"PASSWORD_REQUIRE_DIGIT", fmt.Sprint(cfg.PasswordPolicy.RequireDigit)},
+		{"PASSWORD_REQUIRE_SYMBOL", fmt.Sprint(cfg.PasswordPolicy.RequireSymbol)},
+		{"PASSWORD_HIBP_URL", cfg.PasswordHIBPURL},
+		{"APP_URL", cfg.AppURL},
+		{"SMTP_URL", redactURL(cfg.SMTPURL)},
+		{"MAIL_FROM", cfg.MailFrom},
+		{"SECRETS_ENCRYPTION_KEYS", secretsKeys},
+		{"FINGERPRINT_KEY", fingerprintKey},
+		{"FEATURE_FLAGS", strings.Join(cfg.FeatureFlags, ",")},
+		{"FAULT_S3_RATE", fmt.Sprint(cfg.Faults.S3)},
+		{"FAULT_AMQP_RATE", fmt.Sprint(cfg.Faults.AMQP)},
+		{"S3_IMPORT_SOURCES", strings.Join(cfg.ImportSources, ",")},
+		{"TRUSTED_PROXIES", strings.Join(proxies, ",")},
+		{"SWAGGER_UI", fmt.Sprint(cfg.SwaggerUI)},
+	}...)
+}
+
+// redactURL masks the password of a connection URL, or the whole value when
+// it cannot be parsed.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return "[redacted]"
+	}
+	return u.Redacted()
+}
+
+func checkPostgres(ctx context.Context, cfg serverConfig) error {
+	db, err := sql.Open("postgres", cfg.PostgresURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.PingContext(ctx)
+}
+
+// checkSchema fails when the database is not migrated far enough for this
+// build.
+func checkSchema(ctx context.Context, cfg serverConfig) error {
+	db, err := sql.Open("postgres", cfg.PostgresURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = utils.CheckSchema(ctx, db)
+	return err
+}
+
+func checkRabbitMQ(_ context.Context, cfg serverConfig) error {
+	conn, err := amqp.Dial(cfg.RabbitMqURL)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func checkS3Bucket(ctx context.Context, cfg serverConfig) error {
+	return checkBucket(ctx, cfg.S3Bucket, "")
+}
+
+// checkBucket verifies the bucket exists and is writable by putting and
+// removing a small probe object. awsRegion overrides the default region.
+func checkBucket(ctx context.Context, bucket, awsRegion string) error {
+	var opts []func(*config.LoadOptions) error
+	if awsRegion != "" {
+		opts = append(opts, config.WithRegion(awsRegion))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg)
+
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return fmt.Errorf("head bucket: %w", err)
+	}
+
+	key := "healthcheck/" + uuid.NewString()
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   strings.NewReader("ok"),
+	})
+	if err != nil {
+		return fmt.Errorf("bucket is not writable: %w", err)
+	}
+	if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("delete probe object %s: %w", key, err)
+	}
+	return nil
+}
+
+func checkCfDistribution(ctx context.Context, cfg serverConfig) error {
+	return checkDistribution(ctx, cfg.S3CfDistribution)
+}
+
+// checkDistribution verifies the distribution domain resolves and answers
+// over HTTPS. Any HTTP status counts, since the root is usually not public.
+func checkDistribution(ctx context.Context, domain string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, utils.GetObjectURL(domain, ""), nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
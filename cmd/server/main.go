@@ -12,53 +12,54 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/go-playground/validator/v10"
-	"github.com/joho/godotenv"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rickyroynardson/image-go/cmd/server/docs"
-	_ "github.com/rickyroynardson/image-go/cmd/server/docs"
-	"github.com/rickyroynardson/image-go/internal/auth"
 	"github.com/rickyroynardson/image-go/internal/batch"
 	"github.com/rickyroynardson/image-go/internal/database"
-	"github.com/rickyroynardson/image-go/internal/image"
-	"github.com/rickyroynardson/image-go/internal/middleware"
+	"github.com/rickyroynardson/image-go/internal/faults"
+	"github.com/rickyroynardson/image-go/internal/notify"
 	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/router"
 	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/rickyroynardson/image-go/internal/webhook"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"golang.org/x/time/rate"
 )
 
+// publishChannels is how many idle channels the server keeps for
+// publishing tasks.
+const publishChannels = 16
+
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
+
+// @securityDefinitions.apikey AdminAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the JWT token of an admin user.
+
+// @securityDefinitions.apikey UploadToken
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and an upload token from POST /batches/{batchID}/upload-token.
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	e := echo.New()
 
-	err := godotenv.Load()
-	if err != nil {
+	if err := utils.LoadEnv(); err != nil {
 		e.Logger.Fatalf("failed to load env: %v", err)
 	}
-	postgresURL := os.Getenv("POSTGRES_URL")
-	if postgresURL == "" {
-		e.Logger.Fatal("POSTGRES_URL is not set")
-	}
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		e.Logger.Fatal("JWT_SECRET is not set")
-	}
-	s3Bucket := os.Getenv("S3_BUCKET")
-	if s3Bucket == "" {
-		e.Logger.Fatal("S3_BUCKET is not set")
-	}
-	s3CfDistribution := os.Getenv("S3_CF_DISTRIBUTION")
-	if s3CfDistribution == "" {
-		e.Logger.Fatal("S3_CF_DISTRIBUTION is not set")
-	}
-	rabbitMqURL := os.Getenv("RABBIT_MQ_URL")
-	if rabbitMqURL == "" {
-		e.Logger.Fatal("RABBIT_MQ_URL is not set")
+	serverCfg, err := loadServerConfig()
+	if err != nil {
+		e.Logger.Fatalf("invalid config:\n%v", err)
 	}
 
 	docs.SwaggerInfo.Title = "Image Go API"
@@ -68,31 +69,84 @@ func main() {
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	docs.SwaggerInfo.Schemes = []string{"http", "https"}
 
-	conn, err := amqp.Dial(rabbitMqURL)
+	if serverCfg.Faults.Enabled() {
+		e.Logger.Warnf("fault injection enabled: s3 %v, amqp %v", serverCfg.Faults.S3, serverCfg.Faults.AMQP)
+	}
+	pubsub.SetFaultRate(serverCfg.Faults.AMQP)
+
+	conn, err := amqp.Dial(serverCfg.RabbitMqURL)
 	if err != nil {
 		e.Logger.Fatalf("failed to connect rabbitmq: %v", err)
 	}
 	defer conn.Close()
+	channels := pubsub.NewChannelPool(conn, publishChannels)
+	defer channels.Close()
 
 	ch, queue, err := pubsub.DeclareAndBind(conn, utils.ImageGoDirect, utils.ImageGoTask, utils.ImageGoTask, pubsub.QueueTypeDurable)
 	e.Logger.Infof("%s declared and bind", queue.Name)
 	defer ch.Close()
+	priorityQueue := utils.PriorityTaskQueue("")
+	priorityCh, queue, err := pubsub.DeclareAndBind(conn, utils.ImageGoDirect, priorityQueue, priorityQueue, pubsub.QueueTypeDurable)
+	if err != nil {
+		e.Logger.Fatalf("failed to declare queue %s: %v", priorityQueue, err)
+	}
+	e.Logger.Infof("%s declared and bind", queue.Name)
+	priorityCh.Close()
 
 	awsCfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
 		e.Logger.Fatalf("failed to load aws config: %v", err)
 	}
-	s3Client := s3.NewFromConfig(awsCfg)
+	s3Client := s3.NewFromConfig(awsCfg, faults.S3Option(serverCfg.Faults.S3))
+
+	// Tasks of a region wait in its queues until a worker of that region
+	// consumes them.
+	regions := make(map[string]utils.RegionStorage, len(serverCfg.Regions))
+	for _, region := range serverCfg.Regions {
+		regions[region.Name] = utils.RegionStorage{
+			S3Bucket:         region.S3Bucket,
+			S3CfDistribution: region.S3CfDistribution,
+			S3Client: s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+				if region.AWSRegion != "" {
+					o.Region = region.AWSRegion
+				}
+			}, faults.S3Option(serverCfg.Faults.S3)),
+		}
+		for _, name := range []string{utils.TaskQueue(region.Name), utils.PriorityTaskQueue(region.Name), utils.CleanupQueue(region.Name)} {
+			regionCh, queue, err := pubsub.DeclareAndBind(conn, utils.ImageGoDirect, name, name, pubsub.QueueTypeDurable)
+			if err != nil {
+				e.Logger.Fatalf("failed to declare queue %s: %v", name, err)
+			}
+			e.Logger.Infof("%s declared and bind", queue.Name)
+			regionCh.Close()
+		}
+	}
+
+	mailer, err := utils.NewMailer(serverCfg.SMTPURL, serverCfg.MailFrom)
+	if err != nil {
+		e.Logger.Fatalf("failed to create mailer: %v", err)
+	}
 
 	cfg := &utils.Config{
-		JwtSecret:        jwtSecret,
-		S3Bucket:         s3Bucket,
-		S3CfDistribution: s3CfDistribution,
-		S3Client:         s3Client,
-		RabbitMQConn:     conn,
+		JwtSecret:              serverCfg.JwtSecret,
+		S3Bucket:               serverCfg.S3Bucket,
+		S3CfDistribution:       serverCfg.S3CfDistribution,
+		S3Client:               s3Client,
+		Regions:                regions,
+		RabbitMQ:               channels,
+		QueueMaxBacklog:        serverCfg.QueueMaxBacklog,
+		MaxActiveBatches:       serverCfg.MaxActiveBatches,
+		LoginMaxFailedAttempts: serverCfg.LoginMaxFailedAttempts,
+		PasswordPolicy:         serverCfg.PasswordPolicy,
+		Mailer:                 mailer,
+		AppURL:                 serverCfg.AppURL,
+		Secrets:                serverCfg.Secrets,
+		FingerprintKey:         serverCfg.FingerprintKey,
+		FeatureFlags:           serverCfg.FeatureFlags,
+		ImportSources:          serverCfg.ImportSources,
 	}
 
-	db, err := sql.Open("postgres", postgresURL)
+	db, err := sql.Open("postgres", serverCfg.PostgresURL)
 	if err != nil {
 		e.Logger.Fatalf("failed to connect sql database: %v", err)
 	}
@@ -109,38 +163,70 @@ func main() {
 	if err := db.PingContext(ctx); err != nil {
 		e.Logger.Fatalf("failed to ping db: %v", err)
 	}
+	// See utils.SchemaVersion for what a deploy may rely on.
+	schemaVersion, err := utils.CheckSchema(ctx, db)
+	if err != nil {
+		e.Logger.Fatalf("failed to check schema: %v", err)
+	}
+	if schemaVersion > utils.SchemaVersion {
+		e.Logger.Warnf("database schema is at version %d, newer than the %d this build knows", schemaVersion, utils.SchemaVersion)
+	}
 
 	dbQueries := database.New(db)
 
 	validator := validator.New(validator.WithRequiredStructEnabled())
 
-	authHandler := auth.NewHandler(validator, dbQueries, cfg)
-	batchHandler := batch.NewHandler(validator, dbQueries, cfg)
-	imageHandler := image.NewHandler(validator, dbQueries, cfg)
+	// Every server gets every status event on a queue of its own, deleted
+	// when it disconnects, and passes them on to its own sockets.
+	hub := notify.NewHub()
+	statusQueue := utils.ImageGoStatus + "." + uuid.NewString()
+	err = pubsub.SubscribeJSON(conn, utils.ImageGoDirect, statusQueue, utils.ImageGoStatus, pubsub.QueueTypeTransient, notify.Relay(hub, dbQueries))
+	if err != nil {
+		e.Logger.Fatalf("failed to subscribe to status events: %v", err)
+	}
 
+	e.IPExtractor = serverCfg.ipExtractor()
 	e.Use(echoMiddleware.CORSWithConfig(echoMiddleware.DefaultCORSConfig))
 	e.Use(echoMiddleware.RateLimiter(echoMiddleware.NewRateLimiterMemoryStore(rate.Limit(20))))
 
 	e.GET("/", func(c echo.Context) error {
 		return c.String(http.StatusOK, "image-go")
 	})
-	e.GET("/swagger/*", echoSwagger.WrapHandler)
-	apiV1 := e.Group("/api/v1")
-	apiV1.POST("/login", authHandler.Login)
-	apiV1.POST("/register", authHandler.Register)
-	apiV1.POST("/refresh", authHandler.Refresh)
-
-	apiV1.Use(middleware.Authenticated(cfg))
-	apiV1.GET("/batches", batchHandler.GetAll)
-	apiV1.GET("/batches/:batchID", batchHandler.GetByID)
-	apiV1.POST("/batches", batchHandler.Create)
-	apiV1.DELETE("/batches/:batchID", batchHandler.DeleteByID)
-
-	apiV1.DELETE("/images/:imageID", imageHandler.DeleteByID)
+	buildInfo := utils.GetBuildInfo(serverCfg.features()...)
+	e.GET("/version", func(c echo.Context) error {
+		return utils.RespondJSON(c, http.StatusOK, "version retrieved successfully", buildInfo)
+	})
+	// The spec is always served so clients can be generated from it; the UI
+	// is left out in production unless SWAGGER_UI is set.
+	e.GET("/openapi.json", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, []byte(docs.SwaggerInfo.ReadDoc()))
+	})
+	if serverCfg.SwaggerUI {
+		e.GET("/swagger/*", echoSwagger.WrapHandler)
+	}
+	router.RegisterAPI(e, router.Options{
+		DB:                   db,
+		DBQueries:            dbQueries,
+		Config:               cfg,
+		Validator:            validator,
+		Hub:                  hub,
+		UploadBandwidthLimit: serverCfg.UploadBandwidthLimit,
+	})
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// Also runs without a limit, so batches left waiting when the quota is
+	// turned off still start.
+	go batch.PollWaitingBatches(ctx, db, dbQueries, cfg, 10*time.Second)
+	go batch.PollDeliveries(ctx, dbQueries, cfg.Mailer, 10*time.Second)
+	go batch.PollTaskOutbox(ctx, dbQueries, cfg, 10*time.Second)
+	// Webhook secrets cannot be read without the keyring; events wait until
+	// a server that has it delivers them.
+	if cfg.Secrets != nil {
+		go webhook.PollEvents(ctx, dbQueries, cfg.Secrets, 10*time.Second)
+	}
+
 	go func() {
 		e.Logger.Fatal(e.Start(":3000"))
 	}()
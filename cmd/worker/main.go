@@ -3,25 +3,33 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rickyroynardson/image-go/internal/batch"
 	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/faults"
 	"github.com/rickyroynardson/image-go/internal/image"
 	"github.com/rickyroynardson/image-go/internal/pubsub"
 	"github.com/rickyroynardson/image-go/internal/utils"
 )
 
+// statusChannels is how many idle channels the worker keeps for publishing
+// image status events.
+const statusChannels = 4
+
 func main() {
-	err := godotenv.Load()
+	err := utils.LoadEnv()
 	if err != nil {
 		log.Fatalf("failed to load env: %v", err)
 	}
@@ -59,33 +67,140 @@ func main() {
 	if err = db.PingContext(ctx); err != nil {
 		log.Fatalf("failed to ping db: %v", err)
 	}
+	// Tasks are not consumed against a schema this build does not support.
+	schemaVersion, err := utils.CheckSchema(ctx, db)
+	if err != nil {
+		log.Fatalf("failed to check schema: %v", err)
+	}
+	if schemaVersion > utils.SchemaVersion {
+		log.Printf("warning: database schema is at version %d, newer than the %d this build knows", schemaVersion, utils.SchemaVersion)
+	}
 
 	dbQueries := database.New(db)
 
+	faultRates, err := faults.LoadRates()
+	if err != nil {
+		log.Fatalf("invalid fault injection config: %v", err)
+	}
+	if faultRates.Enabled() {
+		log.Printf("fault injection enabled: s3 %v, amqp %v", faultRates.S3, faultRates.AMQP)
+	}
+	pubsub.SetFaultRate(faultRates.AMQP)
+
 	awsCfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
 		log.Fatalf("failed to load aws config: %v", err)
 	}
-	s3Client := s3.NewFromConfig(awsCfg)
+	s3Client := s3.NewFromConfig(awsCfg, faults.S3Option(faultRates.S3))
 
+	fingerprintKey, err := utils.LoadFingerprintKey()
+	if err != nil {
+		log.Fatalf("invalid FINGERPRINT_KEY: %v", err)
+	}
+
+	// Each worker serves a single data region: S3_BUCKET and
+	// S3_CF_DISTRIBUTION must be that region's storage.
 	cfg := &utils.Config{
 		S3Bucket:         s3Bucket,
 		S3CfDistribution: s3CfDistribution,
 		S3Client:         s3Client,
+		Region:           os.Getenv("WORKER_REGION"),
+		FingerprintKey:   fingerprintKey,
+	}
+
+	videoEnabled, err := utils.GetEnvBool("VIDEO_PROCESSING_ENABLED", false)
+	if err != nil {
+		log.Fatalf("invalid VIDEO_PROCESSING_ENABLED: %v", err)
+	}
+	if videoEnabled {
+		if err := image.EnableVideoProcessing(); err != nil {
+			log.Fatalf("failed to enable video processing: %v", err)
+		}
+		log.Println("video processing enabled")
 	}
 
+	concurrency, err := utils.GetEnvInt("WORKER_CONCURRENCY", 1)
+	if err != nil || concurrency < 1 {
+		log.Fatalf("invalid WORKER_CONCURRENCY: must be a positive integer")
+	}
+	priorityConcurrency, err := utils.GetEnvInt("WORKER_PRIORITY_CONCURRENCY", 1)
+	if err != nil || priorityConcurrency < 0 {
+		log.Fatalf("invalid WORKER_PRIORITY_CONCURRENCY: must be a positive integer or 0")
+	}
+	db.SetMaxOpenConns(max(2, concurrency+priorityConcurrency+1))
+	decodeMemoryMB, err := utils.GetEnvInt("WORKER_DECODE_MEMORY_MB", 0)
+	if err != nil || decodeMemoryMB < 0 {
+		log.Fatalf("invalid WORKER_DECODE_MEMORY_MB: must be a positive integer")
+	}
+	if decodeMemoryMB > 0 {
+		image.SetDecodeMemory(int64(decodeMemoryMB) << 20)
+	}
+	log.Printf("processing %d images at once, decoding up to %d MB", concurrency, image.DecodeMemory()>>20)
+
+	var features []string
+	if videoEnabled {
+		features = append(features, "video")
+	}
+	var ready atomic.Bool
+	if healthAddr := os.Getenv("WORKER_HEALTH_ADDR"); healthAddr != "" {
+		go serveHealth(healthAddr, &ready, utils.GetBuildInfo(features...))
+	}
+
+	warmCtx, cancelWarm := context.WithTimeout(context.Background(), time.Minute)
+	start := time.Now()
+	err = image.WarmUp(warmCtx, dbQueries, cfg)
+	cancelWarm()
+	if err != nil {
+		log.Fatalf("failed to warm up worker: %v", err)
+	}
+	log.Printf("warm-up finished in %s", time.Since(start).Round(time.Millisecond))
+
 	conn, err := amqp.Dial(rabbitMqURL)
 	if err != nil {
 		log.Fatalf("failed to connect rabbitmq: %v", err)
 	}
 	defer conn.Close()
+	cfg.RabbitMQ = pubsub.NewChannelPool(conn, statusChannels)
+	defer cfg.RabbitMQ.Close()
+
+	// Image tasks need the database; pause them while it is unreachable rather
+	// than failing every image. Each consumer handles one task at a time, and
+	// tasks of batches at their concurrency limit wait in a delay queue. Tasks
+	// of users with a priority boost have consumers of their own, so they do
+	// not wait behind the shared backlog.
+	taskQueue := utils.TaskQueue(cfg.Region)
+	priorityQueue := utils.PriorityTaskQueue(cfg.Region)
+	processImage := image.ProcessImage(db, dbQueries, cfg)
+	for i := range concurrency + priorityConcurrency {
+		queue := taskQueue
+		if i >= concurrency {
+			queue = priorityQueue
+		}
+		err = pubsub.SubscribeJSON(conn, utils.ImageGoDirect, queue, queue, pubsub.QueueTypeDurable, processImage, pubsub.WithHealthCheck(db.PingContext, 5*time.Second), pubsub.WithRetryDelay(image.DeferDelay))
+		if err != nil {
+			log.Fatalf("failed to subscribe json: %v", err)
+		}
+	}
 
-	err = pubsub.SubscribeJSON(conn, utils.ImageGoDirect, utils.ImageGoTask, utils.ImageGoTask, pubsub.QueueTypeDurable, image.ProcessImage(dbQueries, cfg))
+	cleanupQueue := utils.CleanupQueue(cfg.Region)
+	err = pubsub.SubscribeJSON(conn, utils.ImageGoDirect, cleanupQueue, cleanupQueue, pubsub.QueueTypeDurable, image.CleanupObjects(cfg))
 	if err != nil {
 		log.Fatalf("failed to subscribe json: %v", err)
 	}
 
-	log.Println("worker started...")
+	pollCtx, stopPolling := context.WithCancel(context.Background())
+	defer stopPolling()
+	go batch.PollRestores(pollCtx, dbQueries, cfg, 15*time.Minute)
+	go batch.PollExpiredBatches(pollCtx, db, dbQueries, cfg, 5*time.Minute)
+	go batch.PollDeletedBatches(pollCtx, db, dbQueries, cfg, time.Hour)
+	go image.PollOverdueBatches(pollCtx, db, dbQueries, cfg, time.Minute)
+
+	ready.Store(true)
+	if cfg.Region != "" {
+		log.Printf("worker started for data region %s...", cfg.Region)
+	} else {
+		log.Println("worker started...")
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -94,3 +209,27 @@ func main() {
 	log.Println("shutting down worker...")
 	time.Sleep(5 * time.Second)
 }
+
+// serveHealth reports liveness on /healthz and readiness on /readyz, which
+// only succeeds once warm-up is done and the queues are consumed, and the
+// build on /version.
+func serveHealth(addr string, ready *atomic.Bool, info utils.BuildInfo) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	})
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("failed to serve health checks: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetUserBoosts godoc
+// @Summary Get user boosts
+// @Description Retrieve the processing boosts granted to a user, latest first, including ended and revoked ones
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Success 200 {object} utils.SuccessResponse{data=[]UserBoostResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/boosts [get]
+func (h *AdminHandler) GetUserBoosts(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+
+	boosts, err := h.dbQueries.GetUserBoosts(c.Request().Context(), userUUID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	now := time.Now()
+	boostsRes := make([]UserBoostResponse, len(boosts))
+	for i, boost := range boosts {
+		boostsRes[i] = toUserBoostResponse(boost, now)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "boosts retrieved successfully", boostsRes)
+}
+
+// CreateUserBoost godoc
+// @Summary Grant user boost
+// @Description Grant a user a temporary processing boost, e.g. for a launch or a backlog. For duration_minutes from starts_at (default now) the user may run extra_active_batches more batches at once than MAX_ACTIVE_BATCHES, and workers process each batch with concurrency_multiplier times its max_concurrency. With priority its image tasks go to the priority queue of their region, ahead of other users' backlog. When boosts overlap the highest values apply. Times are stored in UTC
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param boost body UserBoostRequest true "User Boost Request"
+// @Success 201 {object} utils.SuccessResponse{data=UserBoostResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/boosts [post]
+func (h *AdminHandler) CreateUserBoost(c echo.Context) error {
+	adminID := c.Get("userID").(uuid.UUID)
+	userUUID := utils.ParamUUID(c, "userID")
+
+	var body UserBoostRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	now := time.Now().UTC()
+	startsAt := now
+	if body.StartsAt != nil {
+		startsAt = body.StartsAt.UTC()
+	}
+	endsAt := startsAt.Add(time.Duration(body.DurationMinutes) * time.Minute)
+	if !endsAt.After(now) {
+		return utils.RespondError(c, http.StatusBadRequest, "boost would already be over")
+	}
+
+	if _, err := h.dbQueries.GetUserByID(c.Request().Context(), userUUID); err != nil {
+		return utils.RespondDBError(c, err, "user")
+	}
+	boost, err := h.dbQueries.CreateUserBoost(c.Request().Context(), database.CreateUserBoostParams{
+		UserID:                userUUID,
+		GrantedBy:             uuid.NullUUID{UUID: adminID, Valid: true},
+		Reason:                body.Reason,
+		ExtraActiveBatches:    int32(body.ExtraActiveBatches),
+		ConcurrencyMultiplier: int32(body.ConcurrencyMultiplier),
+		Priority:              body.Priority,
+		StartsAt:              startsAt,
+		EndsAt:                endsAt,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "boost")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "boost created successfully", toUserBoostResponse(boost, now))
+}
+
+// RevokeUserBoost godoc
+// @Summary Revoke user boost
+// @Description End a user's boost early. Batches already running keep their workers until their current images finish
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param boostID path string true "Boost ID"
+// @Success 200 {object} utils.SuccessResponse{data=UserBoostResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/boosts/{boostID} [delete]
+func (h *AdminHandler) RevokeUserBoost(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+	boostUUID := utils.ParamUUID(c, "boostID")
+
+	boost, err := h.dbQueries.RevokeUserBoost(c.Request().Context(), database.RevokeUserBoostParams{
+		ID:     boostUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "boost not found or already over")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "boost revoked successfully", toUserBoostResponse(boost, time.Now()))
+}
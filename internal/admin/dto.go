@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+type AuthStatBucket struct {
+	Reason      string    `json:"reason"`
+	BucketStart time.Time `json:"bucket_start"`
+	Count       int       `json:"count"`
+}
+
+type AuthStatsResponse struct {
+	Since  time.Time `json:"since"`
+	Bucket string    `json:"bucket"`
+	// Totals has an entry for every reason, zero when it was not recorded.
+	Totals  map[string]int   `json:"totals"`
+	Buckets []AuthStatBucket `json:"buckets"`
+}
+
+type EmailDomainRuleRequest struct {
+	Domain string `json:"domain" validate:"required,fqdn"`
+	Rule   string `json:"rule" validate:"required,oneof=block allow"`
+}
+
+type EmailDomainRuleResponse struct {
+	ID        uuid.UUID                    `json:"id"`
+	Domain    string                       `json:"domain"`
+	Rule      database.EmailDomainRuleType `json:"rule"`
+	CreatedAt time.Time                    `json:"created_at"`
+}
+
+type UserFeaturesResponse struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Features []string  `json:"features"`
+}
+
+type IPAllowlistEntryRequest struct {
+	CIDR        string `json:"cidr" validate:"required,max=64"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+type IPAllowlistEntryResponse struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	CIDR        string    `json:"cidr"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ImportSourceRequest struct {
+	Bucket string `json:"bucket" validate:"required,max=63"`
+	// Prefix narrows the source to the objects under it; it is matched by
+	// whole path segments.
+	Prefix string `json:"prefix" validate:"max=1024"`
+}
+
+type ImportSourceResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Bucket    string    `json:"bucket"`
+	Prefix    string    `json:"prefix"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toImportSourceResponse(source database.UserImportSource) ImportSourceResponse {
+	return ImportSourceResponse{
+		ID:        source.ID,
+		UserID:    source.UserID,
+		Bucket:    source.Bucket,
+		Prefix:    source.Prefix,
+		CreatedAt: source.CreatedAt,
+	}
+}
+
+func toIPAllowlistEntryResponse(entry database.UserIpAllowlistEntry) IPAllowlistEntryResponse {
+	return IPAllowlistEntryResponse{
+		ID:          entry.ID,
+		UserID:      entry.UserID,
+		CIDR:        entry.Cidr,
+		Description: entry.Description,
+		CreatedAt:   entry.CreatedAt,
+	}
+}
+
+type UserBoostRequest struct {
+	DurationMinutes       int        `json:"duration_minutes" validate:"required,min=1,max=10080"`
+	StartsAt              *time.Time `json:"starts_at,omitempty"`
+	ExtraActiveBatches    int        `json:"extra_active_batches" validate:"min=0,max=100"`
+	ConcurrencyMultiplier int        `json:"concurrency_multiplier" validate:"required,min=1,max=10"`
+	Priority              bool       `json:"priority"`
+	Reason                string     `json:"reason" validate:"max=255"`
+}
+
+type UserBoostResponse struct {
+	ID                    uuid.UUID  `json:"id"`
+	UserID                uuid.UUID  `json:"user_id"`
+	GrantedBy             *uuid.UUID `json:"granted_by"`
+	Reason                string     `json:"reason"`
+	ExtraActiveBatches    int        `json:"extra_active_batches"`
+	ConcurrencyMultiplier int        `json:"concurrency_multiplier"`
+	Priority              bool       `json:"priority"`
+	StartsAt              time.Time  `json:"starts_at"`
+	EndsAt                time.Time  `json:"ends_at"`
+	RevokedAt             *time.Time `json:"revoked_at"`
+	Active                bool       `json:"active"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+func toUserBoostResponse(boost database.UserBoost, now time.Time) UserBoostResponse {
+	res := UserBoostResponse{
+		ID:                    boost.ID,
+		UserID:                boost.UserID,
+		Reason:                boost.Reason,
+		ExtraActiveBatches:    int(boost.ExtraActiveBatches),
+		ConcurrencyMultiplier: int(boost.ConcurrencyMultiplier),
+		Priority:              boost.Priority,
+		StartsAt:              boost.StartsAt,
+		EndsAt:                boost.EndsAt,
+		Active:                !boost.RevokedAt.Valid && !boost.StartsAt.After(now) && boost.EndsAt.After(now),
+		CreatedAt:             boost.CreatedAt,
+	}
+	if boost.GrantedBy.Valid {
+		res.GrantedBy = &boost.GrantedBy.UUID
+	}
+	if boost.RevokedAt.Valid {
+		res.RevokedAt = &boost.RevokedAt.Time
+	}
+	return res
+}
@@ -0,0 +1,273 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/auth"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/middleware"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+type AdminHandler struct {
+	validator *validator.Validate
+	dbQueries *database.Queries
+	config    *utils.Config
+}
+
+func NewHandler(validator *validator.Validate, dbQueries *database.Queries, config *utils.Config) *AdminHandler {
+	return &AdminHandler{
+		validator: validator,
+		dbQueries: dbQueries,
+		config:    config,
+	}
+}
+
+// GetAuthStats godoc
+// @Summary Get authentication failure stats
+// @Description Aggregated counts of rejected logins, registrations, token refreshes and account lockouts grouped by reason and time bucket
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param bucket query string false "Time bucket size" Enums(hour, day) default(hour)
+// @Param since query string false "Start of the reporting window (RFC3339), defaults to 24 hours ago"
+// @Success 200 {object} utils.SuccessResponse{data=AuthStatsResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/auth-stats [get]
+func (h *AdminHandler) GetAuthStats(c echo.Context) error {
+	bucket := c.QueryParam("bucket")
+	if bucket == "" {
+		bucket = "hour"
+	}
+	if bucket != "hour" && bucket != "day" {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid bucket, must be hour or day")
+	}
+
+	since := time.Now().UTC().Add(-24 * time.Hour)
+	if sinceParam := c.QueryParam("since"); sinceParam != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceParam)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid since, must be RFC3339")
+		}
+		since = parsed.UTC()
+	}
+
+	stats, err := h.dbQueries.GetAuthEventStats(c.Request().Context(), database.GetAuthEventStatsParams{
+		Bucket: bucket,
+		Since:  since,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	res := AuthStatsResponse{
+		Since:   since,
+		Bucket:  bucket,
+		Totals:  make(map[string]int),
+		Buckets: make([]AuthStatBucket, len(stats)),
+	}
+	for _, reason := range auth.FailureReasons {
+		res.Totals[reason] = 0
+	}
+	res.Totals[middleware.ReasonIPNotAllowed] = 0
+	for i, s := range stats {
+		res.Totals[s.Reason] += int(s.Count)
+		res.Buckets[i] = AuthStatBucket{
+			Reason:      s.Reason,
+			BucketStart: s.BucketStart,
+			Count:       int(s.Count),
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "auth stats retrieved successfully", res)
+}
+
+// GetEmailDomainRules godoc
+// @Summary Get email domain rules
+// @Description Retrieve the email domain block and allow rules enforced on registration
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Success 200 {object} utils.SuccessResponse{data=[]EmailDomainRuleResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/email-domains [get]
+func (h *AdminHandler) GetEmailDomainRules(c echo.Context) error {
+	rules, err := h.dbQueries.GetEmailDomainRules(c.Request().Context())
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	rulesRes := make([]EmailDomainRuleResponse, len(rules))
+	for i, r := range rules {
+		rulesRes[i] = EmailDomainRuleResponse{
+			ID:        r.ID,
+			Domain:    r.Domain,
+			Rule:      r.Rule,
+			CreatedAt: r.CreatedAt,
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "email domain rules retrieved successfully", rulesRes)
+}
+
+// CreateEmailDomainRule godoc
+// @Summary Create email domain rule
+// @Description Block a domain from registering, or allow it when registration is restricted to an allowlist
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminAuth
+// @Param rule body EmailDomainRuleRequest true "Email Domain Rule Request"
+// @Success 201 {object} utils.SuccessResponse{data=EmailDomainRuleResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/email-domains [post]
+func (h *AdminHandler) CreateEmailDomainRule(c echo.Context) error {
+	var body EmailDomainRuleRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	rule, err := h.dbQueries.CreateEmailDomainRule(c.Request().Context(), database.CreateEmailDomainRuleParams{
+		Domain: strings.ToLower(body.Domain),
+		Rule:   database.EmailDomainRuleType(body.Rule),
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "email domain rule")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "email domain rule created successfully", EmailDomainRuleResponse{
+		ID:        rule.ID,
+		Domain:    rule.Domain,
+		Rule:      rule.Rule,
+		CreatedAt: rule.CreatedAt,
+	})
+}
+
+// DeleteEmailDomainRuleByID godoc
+// @Summary Delete email domain rule
+// @Description Delete an email domain rule by its ID
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param ruleID path string true "Rule ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/email-domains/{ruleID} [delete]
+func (h *AdminHandler) DeleteEmailDomainRuleByID(c echo.Context) error {
+	ruleUUID := utils.ParamUUID(c, "ruleID")
+
+	err := h.dbQueries.DeleteEmailDomainRuleByID(c.Request().Context(), ruleUUID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "email domain rule deleted successfully", nil)
+}
+
+// GetUserFeatures godoc
+// @Summary Get user features
+// @Description Retrieve the experimental features enabled for one user, not counting those enabled for everyone through FEATURE_FLAGS
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Success 200 {object} utils.SuccessResponse{data=UserFeaturesResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/features [get]
+func (h *AdminHandler) GetUserFeatures(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+
+	flags, err := h.dbQueries.GetUserFeatureFlags(c.Request().Context(), userUUID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "user features retrieved successfully", UserFeaturesResponse{
+		UserID:   userUUID,
+		Features: utils.FeatureFlags(nil, flags),
+	})
+}
+
+// EnableUserFeature godoc
+// @Summary Enable user feature
+// @Description Turn on an experimental feature for one user, exposing the endpoints registered behind its flag to them
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param flag path string true "Feature flag"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/features/{flag} [put]
+func (h *AdminHandler) EnableUserFeature(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+	flag := c.Param("flag")
+	if !utils.ValidFeatureFlag(flag) {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid feature flag")
+	}
+
+	if _, err := h.dbQueries.GetUserByID(c.Request().Context(), userUUID); err != nil {
+		return utils.RespondDBError(c, err, "user")
+	}
+	err := h.dbQueries.EnableUserFeatureFlag(c.Request().Context(), database.EnableUserFeatureFlagParams{
+		UserID: userUUID,
+		Flag:   flag,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "feature enabled successfully", nil)
+}
+
+// DisableUserFeature godoc
+// @Summary Disable user feature
+// @Description Turn off an experimental feature for one user. Features enabled for everyone through FEATURE_FLAGS stay on
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param flag path string true "Feature flag"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/features/{flag} [delete]
+func (h *AdminHandler) DisableUserFeature(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+
+	err := h.dbQueries.DisableUserFeatureFlag(c.Request().Context(), database.DisableUserFeatureFlagParams{
+		UserID: userUUID,
+		Flag:   c.Param("flag"),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "feature disabled successfully", nil)
+}
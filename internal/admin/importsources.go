@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetUserImportSources godoc
+// @Summary Get user import sources
+// @Description Retrieve the S3 buckets and prefixes a user may create batches from
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Success 200 {object} utils.SuccessResponse{data=[]ImportSourceResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/import-sources [get]
+func (h *AdminHandler) GetUserImportSources(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+
+	sources, err := h.dbQueries.GetUserImportSources(c.Request().Context(), userUUID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	sourcesRes := make([]ImportSourceResponse, len(sources))
+	for i, source := range sources {
+		sourcesRes[i] = toImportSourceResponse(source)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "import sources retrieved successfully", sourcesRes)
+}
+
+// CreateUserImportSource godoc
+// @Summary Add user import source
+// @Description Let a user create batches from the objects under a prefix of an S3 bucket, or the whole bucket without one. The source must lie within S3_IMPORT_SOURCES. Prefixes are matched by whole path segments
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param source body ImportSourceRequest true "Import Source Request"
+// @Success 201 {object} utils.SuccessResponse{data=ImportSourceResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/import-sources [post]
+func (h *AdminHandler) CreateUserImportSource(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+
+	var body ImportSourceRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	prefix := utils.NormalizeImportPrefix(body.Prefix)
+	if !h.config.ImportAllowed(body.Bucket, prefix) {
+		return utils.RespondError(c, http.StatusBadRequest, "bucket or prefix is not in S3_IMPORT_SOURCES")
+	}
+
+	if _, err := h.dbQueries.GetUserByID(c.Request().Context(), userUUID); err != nil {
+		return utils.RespondDBError(c, err, "user")
+	}
+	source, err := h.dbQueries.CreateUserImportSource(c.Request().Context(), database.CreateUserImportSourceParams{
+		UserID: userUUID,
+		Bucket: body.Bucket,
+		Prefix: prefix,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "import source")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "import source created successfully", toImportSourceResponse(source))
+}
+
+// DeleteUserImportSource godoc
+// @Summary Delete user import source
+// @Description Stop a user from creating batches from an S3 source. Batches already created keep their images
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param sourceID path string true "Source ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/import-sources/{sourceID} [delete]
+func (h *AdminHandler) DeleteUserImportSource(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+	sourceUUID := utils.ParamUUID(c, "sourceID")
+
+	deleted, err := h.dbQueries.DeleteUserImportSource(c.Request().Context(), database.DeleteUserImportSourceParams{
+		ID:     sourceUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if deleted == 0 {
+		return utils.RespondError(c, http.StatusNotFound, "import source not found")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "import source deleted successfully", nil)
+}
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetUserIPAllowlist godoc
+// @Summary Get user IP allowlist
+// @Description Retrieve the address ranges a user may call the API from. A user without entries is not restricted
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Success 200 {object} utils.SuccessResponse{data=[]IPAllowlistEntryResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/ip-allowlist [get]
+func (h *AdminHandler) GetUserIPAllowlist(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+
+	entries, err := h.dbQueries.GetUserIPAllowlist(c.Request().Context(), userUUID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	entriesRes := make([]IPAllowlistEntryResponse, len(entries))
+	for i, entry := range entries {
+		entriesRes[i] = toIPAllowlistEntryResponse(entry)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "ip allowlist retrieved successfully", entriesRes)
+}
+
+// CreateUserIPAllowlistEntry godoc
+// @Summary Add user IP allowlist entry
+// @Description Allow a user to call the API from an IPv4 or IPv6 range (CIDR) or single address. Once a user has an entry, requests from anywhere else are rejected with 403 and recorded as ip_not_allowed auth events
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param entry body IPAllowlistEntryRequest true "IP Allowlist Entry Request"
+// @Success 201 {object} utils.SuccessResponse{data=IPAllowlistEntryResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/ip-allowlist [post]
+func (h *AdminHandler) CreateUserIPAllowlistEntry(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+
+	var body IPAllowlistEntryRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	prefix, err := utils.ParseCIDR(body.CIDR)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid cidr")
+	}
+
+	if _, err := h.dbQueries.GetUserByID(c.Request().Context(), userUUID); err != nil {
+		return utils.RespondDBError(c, err, "user")
+	}
+	entry, err := h.dbQueries.CreateIPAllowlistEntry(c.Request().Context(), database.CreateIPAllowlistEntryParams{
+		UserID:      userUUID,
+		Cidr:        prefix.String(),
+		Description: body.Description,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "ip allowlist entry")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "ip allowlist entry created successfully", toIPAllowlistEntryResponse(entry))
+}
+
+// DeleteUserIPAllowlistEntry godoc
+// @Summary Delete user IP allowlist entry
+// @Description Remove a range from a user's IP allowlist. Removing the last one lifts the restriction
+// @Tags admin
+// @Produce json
+// @Security AdminAuth
+// @Param userID path string true "User ID"
+// @Param entryID path string true "Entry ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/users/{userID}/ip-allowlist/{entryID} [delete]
+func (h *AdminHandler) DeleteUserIPAllowlistEntry(c echo.Context) error {
+	userUUID := utils.ParamUUID(c, "userID")
+	entryUUID := utils.ParamUUID(c, "entryID")
+
+	deleted, err := h.dbQueries.DeleteIPAllowlistEntry(c.Request().Context(), database.DeleteIPAllowlistEntryParams{
+		ID:     entryUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if deleted == 0 {
+		return utils.RespondError(c, http.StatusNotFound, "ip allowlist entry not found")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "ip allowlist entry deleted successfully", nil)
+}
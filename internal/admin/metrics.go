@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/auth"
+	"github.com/rickyroynardson/image-go/internal/middleware"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetMetrics godoc
+// @Summary Get authentication failure metrics
+// @Description Counters of rejected logins, registrations and token refreshes by reason, and of login lockouts, since the server started recording them, in the Prometheus text format for scraping with the token of an admin user
+// @Tags admin
+// @Produce plain
+// @Security AdminAuth
+// @Success 200 {string} string
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /admin/metrics [get]
+func (h *AdminHandler) GetMetrics(c echo.Context) error {
+	counts, err := h.dbQueries.CountAuthEventsByReason(c.Request().Context())
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	totals := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		totals[count.Reason] = count.Count
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	return writeAuthMetrics(c.Response(), totals)
+}
+
+// writeAuthMetrics writes totals by auth event reason as Prometheus counters.
+// Refresh failures and lockouts get metrics of their own, every other reason
+// is an auth failure. Known reasons that were not recorded are written as 0.
+func writeAuthMetrics(w io.Writer, totals map[string]int64) error {
+	reasons := append(slices.Clone(auth.FailureReasons), middleware.ReasonIPNotAllowed)
+	for reason := range totals {
+		if !slices.Contains(reasons, reason) {
+			reasons = append(reasons, reason)
+		}
+	}
+	slices.Sort(reasons)
+
+	var b strings.Builder
+	b.WriteString("# HELP imagego_auth_failures_total Rejected login, registration, email change and IP allowlist attempts by reason.\n")
+	b.WriteString("# TYPE imagego_auth_failures_total counter\n")
+	for _, reason := range reasons {
+		if reason == auth.ReasonLoginAccountLocked || strings.HasPrefix(reason, "refresh_") {
+			continue
+		}
+		fmt.Fprintf(&b, "imagego_auth_failures_total{reason=%q} %d\n", reason, totals[reason])
+	}
+	b.WriteString("# HELP imagego_refresh_failures_total Rejected token refreshes by reason.\n")
+	b.WriteString("# TYPE imagego_refresh_failures_total counter\n")
+	for _, reason := range reasons {
+		if strings.HasPrefix(reason, "refresh_") {
+			fmt.Fprintf(&b, "imagego_refresh_failures_total{reason=%q} %d\n", reason, totals[reason])
+		}
+	}
+	b.WriteString("# HELP imagego_login_lockouts_total Logins rejected because the email was locked for the client IP.\n")
+	b.WriteString("# TYPE imagego_login_lockouts_total counter\n")
+	fmt.Fprintf(&b, "imagego_login_lockouts_total %d\n", totals[auth.ReasonLoginAccountLocked])
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
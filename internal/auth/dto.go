@@ -1,6 +1,10 @@
 package auth
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -29,3 +33,32 @@ type LoginResponse struct {
 type RefreshResponse struct {
 	AccessToken string `json:"access_token"`
 }
+
+type SessionResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+type FeaturesResponse struct {
+	Features []string `json:"features"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type EmailChangeResponse struct {
+	NewEmail      string    `json:"new_email"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	UndoExpiresAt time.Time `json:"undo_expires_at"`
+}
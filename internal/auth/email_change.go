@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const (
+	// emailChangeTTL is how long the confirmation link sent to the new address is valid.
+	emailChangeTTL = 24 * time.Hour
+	// emailChangeUndoWindow is how long the old address can undo a change, counted from the request.
+	emailChangeUndoWindow = 7 * 24 * time.Hour
+)
+
+// RequestEmailChange godoc
+// @Summary Request email change
+// @Description Start changing the authenticated user's email. A confirmation link is sent to the new address and a notification with an undo link to the current one; the email only changes once the link is confirmed. Nothing is stored if either email cannot be sent
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param change body ChangeEmailRequest true "Change Email Request"
+// @Success 202 {object} utils.SuccessResponse{data=EmailChangeResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /me/email [post]
+func (h *AuthHandler) RequestEmailChange(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	var body ChangeEmailRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	user, err := dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondDBError(c, err, "account")
+	}
+
+	if err := utils.ComparePassword(user.PasswordHash, body.Password); err != nil {
+		h.recordFailure(c, user.ID, user.Email, ReasonEmailChangeInvalidPassword)
+		return utils.RespondError(c, http.StatusBadRequest, "invalid password")
+	}
+	if strings.EqualFold(body.NewEmail, user.Email) {
+		return utils.RespondError(c, http.StatusBadRequest, "new email is the current email")
+	}
+
+	if h.emailPolicy != nil {
+		if err := h.emailPolicy.Check(c.Request().Context(), body.NewEmail); err != nil {
+			if errors.Is(err, ErrEmailDomainNotAllowed) {
+				return utils.RespondError(c, http.StatusBadRequest, err.Error())
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	if _, err := dbQueries.GetUsersByEmail(c.Request().Context(), body.NewEmail); err == nil {
+		return utils.RespondError(c, http.StatusConflict, "email already in use")
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	confirmToken, err := utils.GenerateRefresh()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	undoToken, err := utils.GenerateRefresh()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	// A new request supersedes any earlier one that was not confirmed.
+	if err := dbQueries.CancelPendingEmailChanges(c.Request().Context(), user.ID); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	now := time.Now().UTC()
+	change, err := dbQueries.CreateEmailChange(c.Request().Context(), database.CreateEmailChangeParams{
+		UserID:           user.ID,
+		OldEmail:         user.Email,
+		NewEmail:         body.NewEmail,
+		ConfirmTokenHash: hashToken(confirmToken),
+		UndoTokenHash:    hashToken(undoToken),
+		ExpiresAt:        now.Add(emailChangeTTL),
+		UndoExpiresAt:    now.Add(emailChangeUndoWindow),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	// The old address is told first: a change it cannot undo must not be
+	// confirmable, so either failure rolls the change back.
+	err = h.config.Mailer.Send(c.Request().Context(), change.OldEmail, "Your email address is being changed",
+		fmt.Sprintf("Someone asked to change the email of your Image Go account to %s.\n\nIf this was not you, cancel the change and sign out every session:\n\n%s\n\nThis link works until %s UTC, also after the change is confirmed.",
+			change.NewEmail, h.emailChangeLink("undo", undoToken), change.UndoExpiresAt.Format(time.RFC1123)))
+	if err != nil {
+		c.Logger().Errorf("failed to send email change notification: %v", err)
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	err = h.config.Mailer.Send(c.Request().Context(), change.NewEmail, "Confirm your new email address",
+		fmt.Sprintf("Confirm that you want to use this address for your Image Go account:\n\n%s\n\nThe link expires at %s UTC.",
+			h.emailChangeLink("confirm", confirmToken), change.ExpiresAt.Format(time.RFC1123)))
+	if err != nil {
+		c.Logger().Errorf("failed to send email change confirmation: %v", err)
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusAccepted, "confirmation sent to the new email", EmailChangeResponse{
+		NewEmail:      change.NewEmail,
+		ExpiresAt:     change.ExpiresAt,
+		UndoExpiresAt: change.UndoExpiresAt,
+	})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm email change
+// @Description Apply a requested email change using the token sent to the new address
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param confirm body EmailChangeTokenRequest true "Email Change Token Request"
+// @Success 200 {object} utils.SuccessResponse{data=User}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /email-change/confirm [post]
+func (h *AuthHandler) ConfirmEmailChange(c echo.Context) error {
+	var body EmailChangeTokenRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	change, err := dbQueries.GetPendingEmailChangeByConfirmToken(c.Request().Context(), hashToken(body.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid or expired token")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	err = dbQueries.UpdateUserEmail(c.Request().Context(), database.UpdateUserEmailParams{
+		Email: change.NewEmail,
+		ID:    change.UserID,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "account")
+	}
+	if err := dbQueries.ConfirmEmailChange(c.Request().Context(), change.ID); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	user, err := dbQueries.GetUserByID(c.Request().Context(), change.UserID)
+	if err != nil {
+		return utils.RespondDBError(c, err, "account")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "email changed successfully", User{
+		ID:        user.ID.String(),
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	})
+}
+
+// UndoEmailChange godoc
+// @Summary Undo email change
+// @Description Cancel a pending email change or revert a confirmed one, and sign out every session, using the token sent to the old address
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param undo body EmailChangeTokenRequest true "Email Change Token Request"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /email-change/undo [post]
+func (h *AuthHandler) UndoEmailChange(c echo.Context) error {
+	var body EmailChangeTokenRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	change, err := dbQueries.GetUndoableEmailChange(c.Request().Context(), hashToken(body.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid or expired token")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	// Whoever asked for the change may still hold a session, whether or not
+	// it was confirmed.
+	if err := dbQueries.RevokeUserRefreshTokens(c.Request().Context(), change.UserID); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	if !change.ConfirmedAt.Valid {
+		if err := dbQueries.CancelEmailChange(c.Request().Context(), change.ID); err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		return utils.RespondJSON(c, http.StatusOK, "email change cancelled, all sessions signed out", nil)
+	}
+
+	err = dbQueries.UpdateUserEmail(c.Request().Context(), database.UpdateUserEmailParams{
+		Email: change.OldEmail,
+		ID:    change.UserID,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "account")
+	}
+	if err := dbQueries.RevertEmailChange(c.Request().Context(), change.ID); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "email change reverted, all sessions signed out", nil)
+}
+
+// emailChangeLink builds the link a user follows to confirm or undo a change.
+func (h *AuthHandler) emailChangeLink(action, token string) string {
+	return fmt.Sprintf("%s/email-change/%s?token=%s", strings.TrimRight(h.config.AppURL, "/"), action, url.QueryEscape(token))
+}
+
+// hashToken returns the hex SHA-256 of a token, so stored refresh and email
+// change tokens cannot be used if the database leaks.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+var ErrEmailDomainNotAllowed = errors.New("email domain is not allowed")
+
+// EmailPolicy decides whether an email address may be used to register.
+type EmailPolicy interface {
+	Check(ctx context.Context, email string) error
+}
+
+// DomainEmailPolicy enforces the block and allow rules stored in email_domain_rules.
+// Blocked domains are always rejected; once any allow rule exists only allowed domains can register.
+// A rule also covers the subdomains of its domain, whole labels only: example.com matches
+// mail.example.com but not badexample.com.
+type DomainEmailPolicy struct {
+	dbQueries *database.Queries
+}
+
+func NewDomainEmailPolicy(dbQueries *database.Queries) *DomainEmailPolicy {
+	return &DomainEmailPolicy{
+		dbQueries: dbQueries,
+	}
+}
+
+func (p *DomainEmailPolicy) Check(ctx context.Context, email string) error {
+	domain := EmailDomain(email)
+	if domain == "" {
+		return ErrEmailDomainNotAllowed
+	}
+
+	policy, err := p.dbQueries.GetEmailDomainPolicy(ctx, domain)
+	if err != nil {
+		return err
+	}
+	if policy.BlockedCount > 0 {
+		return ErrEmailDomainNotAllowed
+	}
+	if policy.AllowCount > 0 && policy.AllowedCount == 0 {
+		return ErrEmailDomainNotAllowed
+	}
+	return nil
+}
+
+// EmailDomain returns the lowercased domain part of an email address.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
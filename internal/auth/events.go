@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+const (
+	ReasonLoginInvalidRequest        = "login_invalid_request"
+	ReasonLoginInvalidCredentials    = "login_invalid_credentials"
+	ReasonLoginAccountLocked         = "login_account_locked"
+	ReasonLoginAccountDisabled       = "login_account_disabled"
+	ReasonRegisterInvalidRequest     = "register_invalid_request"
+	ReasonRegisterEmailRejected      = "register_email_rejected"
+	ReasonRegisterWeakPassword       = "register_weak_password"
+	ReasonRegisterFailed             = "register_failed"
+	ReasonRefreshMissingToken        = "refresh_missing_token"
+	ReasonRefreshInvalidToken        = "refresh_invalid_token"
+	ReasonEmailChangeInvalidPassword = "email_change_invalid_password"
+)
+
+// FailureReasons lists every reason the auth endpoints record, so stats can
+// report the ones that did not occur as zero.
+var FailureReasons = []string{
+	ReasonLoginInvalidRequest,
+	ReasonLoginInvalidCredentials,
+	ReasonLoginAccountLocked,
+	ReasonLoginAccountDisabled,
+	ReasonRegisterInvalidRequest,
+	ReasonRegisterEmailRejected,
+	ReasonRegisterWeakPassword,
+	ReasonRegisterFailed,
+	ReasonRefreshMissingToken,
+	ReasonRefreshInvalidToken,
+	ReasonEmailChangeInvalidPassword,
+}
+
+// recordFailure stores a rejected authentication attempt. Failures to record are
+// logged and never change the response sent to the client.
+func (h *AuthHandler) recordFailure(c echo.Context, userID uuid.UUID, email, reason string) {
+	err := h.dbQueries.CreateAuthEvent(c.Request().Context(), database.CreateAuthEventParams{
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		Email:     sql.NullString{String: email, Valid: email != ""},
+		Reason:    reason,
+		IpAddress: sql.NullString{String: c.RealIP(), Valid: c.RealIP() != ""},
+	})
+	if err != nil {
+		c.Logger().Errorf("failed to record auth event: %v", err)
+	}
+}
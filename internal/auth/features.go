@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetFeatures godoc
+// @Summary Get enabled features
+// @Description Retrieve the experimental features enabled for the authenticated user, either for everyone or for them, so clients can show the matching endpoints
+// @Tags authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=FeaturesResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /me/features [get]
+func (h *AuthHandler) GetFeatures(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	flags, err := h.dbQueries.GetUserFeatureFlags(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "features retrieved successfully", FeaturesResponse{
+		Features: utils.FeatureFlags(h.config.FeatureFlags, flags),
+	})
+}
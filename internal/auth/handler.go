@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	_ "github.com/lib/pq"
 	"github.com/rickyroynardson/image-go/internal/database"
@@ -12,16 +15,18 @@ import (
 )
 
 type AuthHandler struct {
-	validator *validator.Validate
-	dbQueries *database.Queries
-	config    *utils.Config
+	validator   *validator.Validate
+	dbQueries   *database.Queries
+	config      *utils.Config
+	emailPolicy EmailPolicy
 }
 
 func NewHandler(validator *validator.Validate, dbQueries *database.Queries, config *utils.Config) *AuthHandler {
 	return &AuthHandler{
-		validator: validator,
-		dbQueries: dbQueries,
-		config:    config,
+		validator:   validator,
+		dbQueries:   dbQueries,
+		config:      config,
+		emailPolicy: NewDomainEmailPolicy(dbQueries),
 	}
 }
 
@@ -35,27 +40,56 @@ func NewHandler(validator *validator.Validate, dbQueries *database.Queries, conf
 // @Success 200 {object} utils.SuccessResponse{data=LoginResponse}
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /login [post]
 func (h *AuthHandler) Login(c echo.Context) error {
 	var body LoginRequest
 	if err := c.Bind(&body); err != nil {
+		h.recordFailure(c, uuid.Nil, "", ReasonLoginInvalidRequest)
 		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
 	}
 
 	if err := h.validator.Struct(body); err != nil {
+		h.recordFailure(c, uuid.Nil, body.Email, ReasonLoginInvalidRequest)
 		return utils.RespondError(c, http.StatusBadRequest, err.Error())
 	}
 
+	// Failures are counted per email and client IP, so an attacker who only
+	// knows an email cannot lock its owner out from elsewhere.
+	if h.config.LoginMaxFailedAttempts > 0 {
+		failedAttempts, err := h.dbQueries.CountAuthEventsByEmailAndIPSince(c.Request().Context(), database.CountAuthEventsByEmailAndIPSinceParams{
+			Email:     sql.NullString{String: body.Email, Valid: true},
+			IpAddress: sql.NullString{String: c.RealIP(), Valid: c.RealIP() != ""},
+			Reason:    ReasonLoginInvalidCredentials,
+			CreatedAt: time.Now().UTC().Add(-utils.LoginLockoutWindow),
+		})
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if failedAttempts >= int64(h.config.LoginMaxFailedAttempts) {
+			h.recordFailure(c, uuid.Nil, body.Email, ReasonLoginAccountLocked)
+			return utils.RespondError(c, http.StatusTooManyRequests, "account temporarily locked, please try again later")
+		}
+	}
+
 	user, err := h.dbQueries.GetUsersByEmail(c.Request().Context(), body.Email)
 	if err != nil {
+		h.recordFailure(c, uuid.Nil, body.Email, ReasonLoginInvalidCredentials)
 		return utils.RespondError(c, http.StatusUnauthorized, "invalid email or password")
 	}
 
 	if err := utils.ComparePassword(user.PasswordHash, body.Password); err != nil {
+		h.recordFailure(c, user.ID, body.Email, ReasonLoginInvalidCredentials)
 		return utils.RespondError(c, http.StatusUnauthorized, "invalid email or password")
 	}
 
+	if user.DisabledAt.Valid {
+		h.recordFailure(c, user.ID, body.Email, ReasonLoginAccountDisabled)
+		return utils.RespondError(c, http.StatusForbidden, "account is disabled")
+	}
+
 	token, err := utils.GenerateJWT(user.ID, h.config.JwtSecret)
 	if err != nil {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
@@ -68,8 +102,10 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 	refreshToken, err := h.dbQueries.CreateRefreshToken(c.Request().Context(), database.CreateRefreshTokenParams{
 		UserID:    user.ID,
-		Token:     refresh,
+		TokenHash: hashToken(refresh),
 		ExpiresAt: time.Now().UTC().Add(30 * 24 * time.Hour),
+		UserAgent: sql.NullString{String: c.Request().UserAgent(), Valid: c.Request().UserAgent() != ""},
+		IpAddress: sql.NullString{String: c.RealIP(), Valid: c.RealIP() != ""},
 	})
 	if err != nil {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
@@ -77,7 +113,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 	refreshCookie := new(http.Cookie)
 	refreshCookie.Name = "refresh_token"
-	refreshCookie.Value = refreshToken.Token
+	refreshCookie.Value = refresh
 	refreshCookie.Path = "/"
 	refreshCookie.Expires = refreshToken.ExpiresAt
 	refreshCookie.HttpOnly = true
@@ -90,7 +126,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		User         User   `json:"user"`
 	}{
 		AccessToken:  token,
-		RefreshToken: refreshToken.Token,
+		RefreshToken: refresh,
 		User: User{
 			ID:        user.ID.String(),
 			Email:     user.Email,
@@ -111,18 +147,36 @@ func (h *AuthHandler) Login(c echo.Context) error {
 // @Param register body RegisterRequest true "Register Request"
 // @Success 201 {object} utils.SuccessResponse{data=User}
 // @Failure 400 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /register [post]
 func (h *AuthHandler) Register(c echo.Context) error {
 	var body RegisterRequest
 	if err := c.Bind(&body); err != nil {
+		h.recordFailure(c, uuid.Nil, "", ReasonRegisterInvalidRequest)
 		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
 	}
 
 	if err := h.validator.Struct(body); err != nil {
+		h.recordFailure(c, uuid.Nil, body.Email, ReasonRegisterInvalidRequest)
 		return utils.RespondError(c, http.StatusBadRequest, err.Error())
 	}
 
+	if fieldErrors := h.checkPassword(c, body.Password); len(fieldErrors) > 0 {
+		h.recordFailure(c, uuid.Nil, body.Email, ReasonRegisterWeakPassword)
+		return utils.RespondFieldErrors(c, http.StatusBadRequest, "password does not meet the password policy", fieldErrors)
+	}
+
+	if h.emailPolicy != nil {
+		if err := h.emailPolicy.Check(c.Request().Context(), body.Email); err != nil {
+			if errors.Is(err, ErrEmailDomainNotAllowed) {
+				h.recordFailure(c, uuid.Nil, body.Email, ReasonRegisterEmailRejected)
+				return utils.RespondError(c, http.StatusBadRequest, err.Error())
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
 	hashedPassword, err := utils.HashPassword(body.Password)
 	if err != nil {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
@@ -133,7 +187,8 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		PasswordHash: hashedPassword,
 	})
 	if err != nil {
-		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		h.recordFailure(c, uuid.Nil, body.Email, ReasonRegisterFailed)
+		return utils.RespondDBError(c, err, "account")
 	}
 
 	resUser := User{
@@ -163,6 +218,7 @@ func (h *AuthHandler) Refresh(c echo.Context) error {
 	if err != nil {
 		token, err := utils.GetAuthorizationToken(c.Request().Header)
 		if err != nil {
+			h.recordFailure(c, uuid.Nil, "", ReasonRefreshMissingToken)
 			return utils.RespondError(c, http.StatusUnauthorized, "no token")
 		}
 		refreshToken = token
@@ -170,7 +226,7 @@ func (h *AuthHandler) Refresh(c echo.Context) error {
 		refreshToken = cookie.Value
 	}
 
-	token, err := h.dbQueries.GetRefreshToken(c.Request().Context(), refreshToken)
+	token, err := h.dbQueries.GetRefreshToken(c.Request().Context(), hashToken(refreshToken))
 	if err != nil {
 		refreshCookie := new(http.Cookie)
 		refreshCookie.Name = "refresh_token"
@@ -181,6 +237,7 @@ func (h *AuthHandler) Refresh(c echo.Context) error {
 		refreshCookie.Secure = true
 		c.SetCookie(refreshCookie)
 
+		h.recordFailure(c, uuid.Nil, "", ReasonRefreshInvalidToken)
 		return utils.RespondError(c, http.StatusUnauthorized, "invalid token")
 	}
 
@@ -189,9 +246,84 @@ func (h *AuthHandler) Refresh(c echo.Context) error {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 	}
 
+	if err := h.dbQueries.TouchRefreshToken(c.Request().Context(), token.ID); err != nil {
+		c.Logger().Errorf("failed to update refresh token last used: %v", err)
+	}
+
 	return utils.RespondJSON(c, http.StatusOK, "token refreshed successfully", struct {
 		AccessToken string `json:"access_token"`
 	}{
 		AccessToken: accessToken,
 	})
 }
+
+// GetSessions godoc
+// @Summary Get active sessions
+// @Description Retrieve the active sessions (refresh tokens) of the authenticated user with their device info
+// @Tags authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=[]SessionResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /me/sessions [get]
+func (h *AuthHandler) GetSessions(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	tokens, err := h.dbQueries.GetUserActiveRefreshTokens(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	var currentHash string
+	if cookie, err := c.Cookie("refresh_token"); err == nil {
+		currentHash = hashToken(cookie.Value)
+	}
+
+	sessionsRes := make([]SessionResponse, len(tokens))
+	for i, t := range tokens {
+		sessionsRes[i] = SessionResponse{
+			ID:        t.ID,
+			UserAgent: t.UserAgent.String,
+			IPAddress: t.IpAddress.String,
+			CreatedAt: t.CreatedAt,
+			ExpiresAt: t.ExpiresAt,
+			Current:   currentHash != "" && currentHash == t.TokenHash,
+		}
+		if t.LastUsedAt.Valid {
+			sessionsRes[i].LastUsedAt = &t.LastUsedAt.Time
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "sessions retrieved successfully", sessionsRes)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Revoke one of the authenticated user's sessions so its refresh token can no longer be used
+// @Tags authentication
+// @Produce json
+// @Security BearerAuth
+// @Param sessionID path string true "Session ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /me/sessions/{sessionID} [delete]
+func (h *AuthHandler) RevokeSession(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	sessionUUID := utils.ParamUUID(c, "sessionID")
+
+	revoked, err := h.dbQueries.RevokeUserRefreshToken(c.Request().Context(), database.RevokeUserRefreshTokenParams{
+		ID:     sessionUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if revoked == 0 {
+		return utils.RespondError(c, http.StatusNotFound, "session not found")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "session revoked successfully", nil)
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	_ "github.com/lib/pq"
@@ -51,7 +52,10 @@ func setupTestDB(t *testing.T) {
 func cleanupTestData(t *testing.T) {
 	ctx := context.Background()
 
-	_, err := testDB.ExecContext(ctx, "DELETE FROM refresh_tokens")
+	_, err := testDB.ExecContext(ctx, "DELETE FROM auth_events")
+	require.NoError(t, err, "failed to cleanup auth_events")
+
+	_, err = testDB.ExecContext(ctx, "DELETE FROM refresh_tokens")
 	require.NoError(t, err, "failed to cleanup refresh_tokens")
 
 	_, err = testDB.ExecContext(ctx, "DELETE FROM users")
@@ -85,7 +89,7 @@ func createTestRefreshToken(t *testing.T, token string) {
 	user := createTestUser(t, "sample@mail.com", "password")
 	_, err := testQueries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{
 		UserID:    user.ID,
-		Token:     token,
+		TokenHash: hashToken(token),
 		ExpiresAt: time.Now().Add(5 * time.Minute),
 	})
 	require.NoError(t, err)
@@ -205,6 +209,55 @@ func TestLogin(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 			expectedError:  "invalid email or password",
 		},
+		{
+			name: "disabled account",
+			requestBody: LoginRequest{
+				Email:    "disabled@example.com",
+				Password: "password123",
+			},
+			setupData: func(t *testing.T) {
+				createTestUser(t, "disabled@example.com", "password123")
+				_, err := testQueries.UpdateUserDisabledByEmail(context.Background(), database.UpdateUserDisabledByEmailParams{
+					Disabled: true,
+					Email:    "disabled@example.com",
+				})
+				require.NoError(t, err)
+			},
+			expectedStatus: http.StatusForbidden,
+			validateResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var errorResponse utils.ErrorResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+				assert.Equal(t, "account is disabled", errorResponse.Message)
+				assert.Empty(t, rec.Result().Cookies(), "no session is started")
+
+				var reason string
+				require.NoError(t, testDB.QueryRow("SELECT reason FROM auth_events").Scan(&reason))
+				assert.Equal(t, ReasonLoginAccountDisabled, reason)
+			},
+		},
+		{
+			name: "enabled again",
+			requestBody: LoginRequest{
+				Email:    "reenabled@example.com",
+				Password: "password123",
+			},
+			setupData: func(t *testing.T) {
+				createTestUser(t, "reenabled@example.com", "password123")
+				for _, disabled := range []bool{true, false} {
+					_, err := testQueries.UpdateUserDisabledByEmail(context.Background(), database.UpdateUserDisabledByEmailParams{
+						Disabled: disabled,
+						Email:    "reenabled@example.com",
+					})
+					require.NoError(t, err)
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validateResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var response utils.SuccessResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				assert.Equal(t, "login success", response.Message)
+			},
+		},
 		{
 			name: "multiple login attempts create multiple refresh tokens",
 			requestBody: LoginRequest{
@@ -313,6 +366,44 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+// TestLoginLockout checks that failed logins lock an email only for the
+// client IP they came from.
+func TestLoginLockout(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	handler := &AuthHandler{
+		validator: validator,
+		dbQueries: testQueries,
+		config: &utils.Config{
+			JwtSecret:              "test-secret-key-for-integration-tests",
+			LoginMaxFailedAttempts: 2,
+		},
+	}
+	createTestUser(t, "lockout@example.com", "password123")
+
+	login := func(password, remoteAddr string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"email":"lockout@example.com","password":"`+password+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		require.NoError(t, handler.Login(echo.New().NewContext(req, rec)))
+		return rec.Code
+	}
+
+	for range 2 {
+		assert.Equal(t, http.StatusUnauthorized, login("wrongpassword", "198.51.100.1:4000"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, login("password123", "198.51.100.1:4000"))
+	assert.Equal(t, http.StatusOK, login("password123", "198.51.100.2:4000"), "other IPs are not locked out")
+
+	var locked int
+	require.NoError(t, testDB.QueryRow("SELECT COUNT(*) FROM auth_events WHERE reason = $1", ReasonLoginAccountLocked).Scan(&locked))
+	assert.Equal(t, 1, locked)
+}
+
 func TestRegister(t *testing.T) {
 	setupTestDB(t)
 	defer teardownTestDB(t)
@@ -353,7 +444,52 @@ func TestRegister(t *testing.T) {
 			setupData: func(t *testing.T) {
 				createTestUser(t, "test@mail.com", "password")
 			},
-			expectedStatus: http.StatusInternalServerError,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "password too short",
+			requestBody: RegisterRequest{
+				Email:           "test@mail.com",
+				Password:        "short",
+				ConfirmPassword: "short",
+			},
+			setupData:      func(t *testing.T) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "disposable email domain",
+			requestBody: RegisterRequest{
+				Email:           "test@mailinator.com",
+				Password:        "password",
+				ConfirmPassword: "password",
+			},
+			setupData:      func(t *testing.T) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "subdomain of disposable email domain",
+			requestBody: RegisterRequest{
+				Email:           "test@eu.mailinator.com",
+				Password:        "password",
+				ConfirmPassword: "password",
+			},
+			setupData:      func(t *testing.T) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "domain only ending like a disposable one",
+			requestBody: RegisterRequest{
+				Email:           "test@notmailinator.com",
+				Password:        "password",
+				ConfirmPassword: "password",
+			},
+			setupData:      func(t *testing.T) {},
+			expectedStatus: http.StatusCreated,
+			validateResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var res utils.SuccessResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.Equal(t, "register success", res.Message)
+			},
 		},
 		{
 			name: "register success",
@@ -391,6 +527,10 @@ func TestRegister(t *testing.T) {
 			handler := &AuthHandler{
 				validator: validator,
 				dbQueries: testQueries,
+				config: &utils.Config{
+					PasswordPolicy: utils.PasswordPolicy{MinLength: 8},
+				},
+				emailPolicy: NewDomainEmailPolicy(testQueries),
 			}
 
 			// Create request body
@@ -453,30 +593,34 @@ func TestRefreshToken(t *testing.T) {
 		expectedError    string
 		validateResponse func(t *testing.T, rec *httptest.ResponseRecorder)
 		setCookie        bool
+		expectedReasons  []string
 	}{
 		{
-			name:           "missing token",
-			headers:        http.Header{},
-			setupData:      func(t *testing.T) {},
-			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "no token",
+			name:            "missing token",
+			headers:         http.Header{},
+			setupData:       func(t *testing.T) {},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedError:   "no token",
+			expectedReasons: []string{ReasonRefreshMissingToken},
 		},
 		{
 			name: "token from header",
 			headers: http.Header{
 				"Authorization": []string{"Bearer token"},
 			},
-			setupData:      func(t *testing.T) {},
-			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "invalid token",
+			setupData:       func(t *testing.T) {},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedError:   "invalid token",
+			expectedReasons: []string{ReasonRefreshInvalidToken},
 		},
 		{
-			name:           "token from cookie",
-			headers:        http.Header{},
-			setupData:      func(t *testing.T) {},
-			expectedStatus: http.StatusUnauthorized,
-			expectedError:  "invalid token",
-			setCookie:      true,
+			name:            "token from cookie",
+			headers:         http.Header{},
+			setupData:       func(t *testing.T) {},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedError:   "invalid token",
+			setCookie:       true,
+			expectedReasons: []string{ReasonRefreshInvalidToken},
 		},
 		{
 			name: "valid refresh token",
@@ -561,10 +705,215 @@ func TestRefreshToken(t *testing.T) {
 					assert.Equal(t, tt.expectedStatus, rec.Code)
 				}
 			}
+
+			rows, err := testDB.Query("SELECT reason FROM auth_events ORDER BY created_at")
+			require.NoError(t, err)
+			defer rows.Close()
+			var reasons []string
+			for rows.Next() {
+				var reason string
+				require.NoError(t, rows.Scan(&reason))
+				reasons = append(reasons, reason)
+			}
+			require.NoError(t, rows.Err())
+			assert.Equal(t, tt.expectedReasons, reasons)
 		})
 	}
 }
 
+func TestSessions(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	e := echo.New()
+	e.Validator = &CustomValidator{validator: validator}
+	handler := &AuthHandler{
+		validator: validator,
+		dbQueries: testQueries,
+		config:    &utils.Config{JwtSecret: "test-secret-key-for-integration-tests"},
+	}
+
+	user := createTestUser(t, "sessions@example.com", "password123")
+	other := createTestUser(t, "other-sessions@example.com", "password123")
+
+	// login signs in from a device and returns the refresh token it got.
+	login := func(userAgent string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"email":"sessions@example.com","password":"password123"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		require.NoError(t, handler.Login(e.NewContext(req, rec)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == "refresh_token" {
+				return cookie.Value
+			}
+		}
+		t.Fatal("login did not set a refresh token")
+		return ""
+	}
+	sessions := func(refreshToken string) []SessionResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me/sessions", nil)
+		req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("userID", user.ID)
+		require.NoError(t, handler.GetSessions(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res struct {
+			Data []SessionResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Data
+	}
+	revoke := func(userID, sessionID uuid.UUID) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/me/sessions/"+sessionID.String(), nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("userID", userID)
+		utils.SetParamUUID(c, "sessionID", sessionID)
+		require.NoError(t, handler.RevokeSession(c))
+		return rec.Code
+	}
+
+	laptop := login("Laptop Browser")
+	phone := login("Phone App")
+	_, err := testQueries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken("expired"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	listed := sessions(laptop)
+	require.Len(t, listed, 2, "expired sessions are not listed")
+	byAgent := map[string]SessionResponse{}
+	for _, s := range listed {
+		byAgent[s.UserAgent] = s
+	}
+	require.Contains(t, byAgent, "Laptop Browser")
+	require.Contains(t, byAgent, "Phone App")
+	assert.True(t, byAgent["Laptop Browser"].Current)
+	assert.False(t, byAgent["Phone App"].Current)
+	assert.NotEmpty(t, byAgent["Phone App"].IPAddress)
+
+	phoneID := byAgent["Phone App"].ID
+	assert.Equal(t, http.StatusNotFound, revoke(other.ID, phoneID), "sessions of another user")
+	assert.Equal(t, http.StatusOK, revoke(user.ID, phoneID))
+	assert.Equal(t, http.StatusNotFound, revoke(user.ID, phoneID), "already revoked")
+
+	listed = sessions(laptop)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "Laptop Browser", listed[0].UserAgent)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: phone})
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.Refresh(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "a revoked session cannot refresh")
+}
+
+// captureMailer keeps sent emails for tests instead of delivering them.
+type captureMailer struct {
+	sent map[string]string
+}
+
+func (m *captureMailer) Send(_ context.Context, to, _, body string, _ ...utils.MailOption) error {
+	m.sent[to] = body
+	return nil
+}
+
+// token returns the token of the link in the last email sent to to.
+func (m *captureMailer) token(t *testing.T, to string) string {
+	_, after, ok := strings.Cut(m.sent[to], "token=")
+	require.True(t, ok, "no link sent to %s", to)
+	token, _, _ := strings.Cut(after, "\n")
+	return token
+}
+
+func TestEmailChange(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	mailer := &captureMailer{sent: map[string]string{}}
+	handler := &AuthHandler{
+		validator: validator.New(validator.WithRequiredStructEnabled()),
+		dbQueries: testQueries,
+		config:    &utils.Config{Mailer: mailer, AppURL: "http://localhost:3000"},
+	}
+	user := createTestUser(t, "old@example.com", "password123")
+	createTestUser(t, "taken@example.com", "password123")
+	_, err := testQueries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken("hijacked-session"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	do := func(handle echo.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Set("userID", user.ID)
+		require.NoError(t, handle(c))
+		return rec
+	}
+
+	rec := do(handler.RequestEmailChange, `{"new_email":"new@example.com","password":"wrong"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = do(handler.RequestEmailChange, `{"new_email":"taken@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = do(handler.RequestEmailChange, `{"new_email":"new@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	confirmToken := mailer.token(t, "new@example.com")
+	undoToken := mailer.token(t, "old@example.com")
+
+	rec = do(handler.ConfirmEmailChange, `{"token":"not-a-token"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = do(handler.ConfirmEmailChange, `{"token":"`+confirmToken+`"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	changed, err := testQueries.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", changed.Email)
+
+	// The token is single use.
+	rec = do(handler.ConfirmEmailChange, `{"token":"`+confirmToken+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(handler.UndoEmailChange, `{"token":"`+undoToken+`"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	reverted, err := testQueries.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "old@example.com", reverted.Email)
+	sessions, err := testQueries.GetUserActiveRefreshTokens(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// Cancelling a change that was not confirmed signs out every session too.
+	rec = do(handler.RequestEmailChange, `{"new_email":"other@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	_, err = testQueries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken("pending-session"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	rec = do(handler.UndoEmailChange, `{"token":"`+mailer.token(t, "old@example.com")+`"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	sessions, err = testQueries.GetUserActiveRefreshTokens(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	unchanged, err := testQueries.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "old@example.com", unchanged.Email)
+}
+
 // CustomValidator is a wrapper for validator to implement echo.Validator interface
 type CustomValidator struct {
 	validator *validator.Validate
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// checkPassword applies the configured password policy and returns one field error per violation.
+// A breach lookup that cannot be completed is logged and does not block registration.
+func (h *AuthHandler) checkPassword(c echo.Context, password string) []utils.FieldError {
+	policy := h.config.PasswordPolicy
+
+	var fieldErrors []utils.FieldError
+	for _, violation := range policy.Validate(password) {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "password", Message: violation})
+	}
+
+	if len(fieldErrors) == 0 && policy.BreachedClient != nil {
+		count, err := policy.BreachedClient.BreachCount(c.Request().Context(), password)
+		if err != nil {
+			c.Logger().Errorf("failed to check breached password: %v", err)
+		} else if count > 0 {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: "password", Message: "has appeared in a known data breach, choose a different password"})
+		}
+	}
+	return fieldErrors
+}
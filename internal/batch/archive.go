@@ -0,0 +1,343 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/rickyroynardson/image-go/internal/webhook"
+)
+
+// restoreDays is how long restored copies stay retrievable before S3 expires them again.
+const restoreDays = 7
+
+// Archive godoc
+// @Summary Archive batch
+// @Description Move the objects of a fully processed batch to Glacier cold storage. Objects other batches still use, such as originals linked by duplicate uploads, stay in standard storage
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/archive [post]
+func (h *BatchHandler) Archive(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	images, err := h.dbQueries.GetImagesByBatchID(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	if batch.ArchiveStatus == database.BatchArchiveStatusArchived || batch.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is already archived")
+	}
+	for _, img := range images {
+		if img.Status == database.ImageStatusPending || img.Status == database.ImageStatusProcessing {
+			return utils.RespondError(c, http.StatusConflict, "batch is still processing")
+		}
+	}
+
+	storage, err := h.config.Storage(batch.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to archive batch %s: %v", batch.ID, err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+	keys, err := unsharedObjectKeys(c.Request().Context(), h.dbQueries, storage, batch, images)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	for _, key := range keys {
+		_, err := storage.S3Client.CopyObject(c.Request().Context(), &s3.CopyObjectInput{
+			Bucket:            aws.String(storage.S3Bucket),
+			Key:               aws.String(key),
+			CopySource:        aws.String(url.PathEscape(storage.S3Bucket + "/" + key)),
+			StorageClass:      types.StorageClassGlacier,
+			MetadataDirective: types.MetadataDirectiveCopy,
+		})
+		if err != nil {
+			c.Logger().Errorf("failed to archive object %s: %v", key, err)
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	if err := dbQueries.ArchiveBatchByID(c.Request().Context(), batch.ID); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if err := recordEvent(c.Request().Context(), dbQueries, batch, database.BatchEventTypeArchived); err != nil {
+		c.Logger().Errorf("failed to record batch event: %v", err)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "batch archived successfully", nil)
+}
+
+// Restore godoc
+// @Summary Restore batch
+// @Description Take a deleted batch out of the trash, with its images, answering 200 with the batch. Pending images and images failed because the batch was deleted are queued again; 409 when its external_id or that of one of its images was reused meanwhile. For a batch that is not deleted, start restoring the objects of an archived batch from Glacier instead, answering 202; the batch becomes "restored" once every object is retrievable
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=BatchResponse}
+// @Success 202 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/restore [post]
+func (h *BatchHandler) Restore(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return h.restoreDeleted(c, userID, batchUUID)
+	}
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	images, err := h.dbQueries.GetImagesByBatchID(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	if batch.ArchiveStatus != database.BatchArchiveStatusArchived && batch.ArchiveStatus != database.BatchArchiveStatusRestored {
+		return utils.RespondError(c, http.StatusConflict, "batch is not archived")
+	}
+
+	storage, err := h.config.Storage(batch.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to restore batch %s: %v", batch.ID, err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+	for _, key := range batchObjectKeys(storage, batch, images) {
+		_, err := storage.S3Client.RestoreObject(c.Request().Context(), &s3.RestoreObjectInput{
+			Bucket: aws.String(storage.S3Bucket),
+			Key:    aws.String(key),
+			RestoreRequest: &types.RestoreRequest{
+				Days: aws.Int32(restoreDays),
+				GlacierJobParameters: &types.GlacierJobParameters{
+					Tier: types.TierStandard,
+				},
+			},
+		})
+		// Objects shared with other batches were not archived.
+		if err != nil && !strings.Contains(err.Error(), "RestoreAlreadyInProgress") && !strings.Contains(err.Error(), "InvalidObjectState") {
+			c.Logger().Errorf("failed to restore object %s: %v", key, err)
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	err = dbQueries.UpdateBatchArchiveStatus(c.Request().Context(), database.UpdateBatchArchiveStatusParams{
+		ArchiveStatus: database.BatchArchiveStatusRestoring,
+		ID:            batch.ID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if err := recordEvent(c.Request().Context(), dbQueries, batch, database.BatchEventTypeRestoreRequested); err != nil {
+		c.Logger().Errorf("failed to record batch event: %v", err)
+	}
+	return utils.RespondJSON(c, http.StatusAccepted, "batch restore started", nil)
+}
+
+// PollRestores periodically marks restoring batches as restored once S3 reports every object as retrievable.
+func PollRestores(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkRestores(ctx, dbQueries, cfg)
+		}
+	}
+}
+
+func checkRestores(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config) {
+	batches, err := dbQueries.GetBatchesByArchiveStatus(ctx, database.BatchArchiveStatusRestoring)
+	if err != nil {
+		log.Printf("error get restoring batches: %v", err)
+		return
+	}
+
+	for _, batch := range batches {
+		// Batches of other regions are checked by their own workers.
+		storage, err := cfg.Storage(batch.Region)
+		if err != nil {
+			continue
+		}
+		images, err := dbQueries.GetImagesByBatchID(ctx, batch.ID)
+		if err != nil {
+			log.Printf("error get batch images: %v", err)
+			continue
+		}
+
+		restored := true
+		for _, key := range batchObjectKeys(storage, batch, images) {
+			obj, err := storage.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(storage.S3Bucket),
+				Key:    aws.String(key),
+			})
+			if err != nil {
+				restored = false
+				break
+			}
+			if obj.StorageClass != types.StorageClassGlacier {
+				// Shared with other batches, so never archived.
+				continue
+			}
+			if obj.Restore == nil || !strings.Contains(*obj.Restore, `ongoing-request="false"`) {
+				restored = false
+				break
+			}
+		}
+		if !restored {
+			continue
+		}
+
+		err = dbQueries.UpdateBatchArchiveStatus(ctx, database.UpdateBatchArchiveStatusParams{
+			ArchiveStatus: database.BatchArchiveStatusRestored,
+			ID:            batch.ID,
+		})
+		if err != nil {
+			log.Printf("error update batch archive status: %v", err)
+			continue
+		}
+		if err := recordEvent(ctx, dbQueries, batch, database.BatchEventTypeRestored); err != nil {
+			log.Printf("error record batch event: %v", err)
+		}
+		err = webhook.Enqueue(ctx, dbQueries, batch.UserID, webhook.EventBatchRestored, webhook.BatchRestoredData{
+			BatchID:          batch.ID,
+			Name:             batch.Name.String,
+			ExternalID:       batch.ExternalID.String,
+			RetrievableUntil: time.Now().UTC().Add(restoreDays * 24 * time.Hour),
+		})
+		if err != nil {
+			log.Printf("error enqueue batch restored event: %v", err)
+		}
+	}
+}
+
+// sharedObjects finds the objects of a batch that are used outside of it.
+type sharedObjects interface {
+	GetImageKeysSharedOutsideBatch(ctx context.Context, arg database.GetImageKeysSharedOutsideBatchParams) ([]string, error)
+	GetObjectURLsSharedOutsideBatch(ctx context.Context, arg database.GetObjectURLsSharedOutsideBatchParams) ([]string, error)
+	CountOtherBatchesByWatermarkKey(ctx context.Context, arg database.CountOtherBatchesByWatermarkKeyParams) (int64, error)
+}
+
+// unsharedObjectKeys lists the objects of batchObjectKeys that no image of
+// another batch, nor another batch, uses: originals linked by duplicate
+// uploads, processed files shared by similar images and the uploaded
+// watermark of reprocessed batches are left out.
+func unsharedObjectKeys(ctx context.Context, dbQueries sharedObjects, storage utils.RegionStorage, batch database.Batch, images []database.Image) ([]string, error) {
+	originalKeys := make([]string, 0, len(images))
+	var objectURLs []string
+	for _, img := range images {
+		originalKeys = append(originalKeys, img.Key)
+		for _, url := range []sql.NullString{img.ProcessedUrl, img.ThumbnailUrl} {
+			if url.Valid {
+				objectURLs = append(objectURLs, url.String)
+			}
+		}
+	}
+	shared, err := dbQueries.GetImageKeysSharedOutsideBatch(ctx, database.GetImageKeysSharedOutsideBatchParams{
+		Keys:    originalKeys,
+		BatchID: batch.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sharedURLs, err := dbQueries.GetObjectURLsSharedOutsideBatch(ctx, database.GetObjectURLsSharedOutsideBatchParams{
+		Urls:    objectURLs,
+		BatchID: batch.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range sharedURLs {
+		shared = append(shared, utils.GetObjectKey(storage.S3CfDistribution, url))
+	}
+	if batch.WatermarkKey.String != "" {
+		users, err := dbQueries.CountOtherBatchesByWatermarkKey(ctx, database.CountOtherBatchesByWatermarkKeyParams{
+			WatermarkKey: batch.WatermarkKey,
+			ID:           batch.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if users > 0 {
+			shared = append(shared, batch.WatermarkKey.String)
+		}
+	}
+
+	var keys []string
+	for _, key := range batchObjectKeys(storage, batch, images) {
+		if !slices.Contains(shared, key) && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// batchObjectKeys lists the original, processed, thumbnail and watermark objects stored for a batch.
+func batchObjectKeys(storage utils.RegionStorage, batch database.Batch, images []database.Image) []string {
+	var keys []string
+	for _, img := range images {
+		if img.Key != "" {
+			keys = append(keys, img.Key)
+		}
+		if img.ProcessedUrl.Valid {
+			if key := utils.GetObjectKey(storage.S3CfDistribution, img.ProcessedUrl.String); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if img.ThumbnailUrl.Valid {
+			if key := utils.GetObjectKey(storage.S3CfDistribution, img.ThumbnailUrl.String); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if batch.WatermarkKey.Valid && batch.WatermarkKey.String != "" {
+		keys = append(keys, batch.WatermarkKey.String)
+	}
+	return keys
+}
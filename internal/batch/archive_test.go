@@ -0,0 +1,89 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSharedObjects reports the listed keys and URLs as used by other
+// batches.
+type fakeSharedObjects struct {
+	keys            []string
+	urls            []string
+	watermarkShared bool
+}
+
+func (f fakeSharedObjects) GetImageKeysSharedOutsideBatch(_ context.Context, arg database.GetImageKeysSharedOutsideBatchParams) ([]string, error) {
+	var shared []string
+	for _, key := range arg.Keys {
+		if slices.Contains(f.keys, key) {
+			shared = append(shared, key)
+		}
+	}
+	return shared, nil
+}
+
+func (f fakeSharedObjects) GetObjectURLsSharedOutsideBatch(_ context.Context, arg database.GetObjectURLsSharedOutsideBatchParams) ([]string, error) {
+	var shared []string
+	for _, url := range arg.Urls {
+		if slices.Contains(f.urls, url) {
+			shared = append(shared, url)
+		}
+	}
+	return shared, nil
+}
+
+func (f fakeSharedObjects) CountOtherBatchesByWatermarkKey(context.Context, database.CountOtherBatchesByWatermarkKeyParams) (int64, error) {
+	if f.watermarkShared {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func TestUnsharedObjectKeys(t *testing.T) {
+	storage := utils.RegionStorage{S3CfDistribution: "cdn.example.com"}
+	batch := database.Batch{
+		ID:           uuid.New(),
+		WatermarkKey: sql.NullString{String: "watermarks/w.png", Valid: true},
+	}
+	images := []database.Image{
+		{
+			Key:          "originals/a.jpg",
+			ProcessedUrl: sql.NullString{String: "https://cdn.example.com/processed/a.jpg", Valid: true},
+			ThumbnailUrl: sql.NullString{String: "https://cdn.example.com/thumbnails/a.jpg", Valid: true},
+		},
+		// A duplicate linked within the batch lists its original twice.
+		{Key: "originals/a.jpg"},
+		{Key: "originals/b.jpg"},
+	}
+
+	t.Run("nothing shared", func(t *testing.T) {
+		keys, err := unsharedObjectKeys(context.Background(), fakeSharedObjects{}, storage, batch, images)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"originals/a.jpg", "processed/a.jpg", "thumbnails/a.jpg", "originals/b.jpg", "watermarks/w.png"}, keys)
+	})
+
+	t.Run("linked original and similar image files", func(t *testing.T) {
+		shared := fakeSharedObjects{
+			keys: []string{"originals/b.jpg"},
+			urls: []string{"https://cdn.example.com/processed/a.jpg"},
+		}
+		keys, err := unsharedObjectKeys(context.Background(), shared, storage, batch, images)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"originals/a.jpg", "thumbnails/a.jpg", "watermarks/w.png"}, keys)
+	})
+
+	t.Run("watermark of a reprocessed batch", func(t *testing.T) {
+		keys, err := unsharedObjectKeys(context.Background(), fakeSharedObjects{watermarkShared: true}, storage, batch, images)
+		require.NoError(t, err)
+		assert.NotContains(t, keys, "watermarks/w.png")
+	})
+}
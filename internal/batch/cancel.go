@@ -0,0 +1,68 @@
+package batch
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// Cancel godoc
+// @Summary Cancel batch
+// @Description Stop a batch by marking its pending images as cancelled. Workers skip the queued tasks of cancelled images; images already processing still finish
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=BatchResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/cancel [post]
+func (h *BatchHandler) Cancel(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	batch, err := dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	cancelled, err := dbQueries.CancelPendingBatchImages(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if len(cancelled) == 0 {
+		return utils.RespondError(c, http.StatusConflict, "batch has no pending images")
+	}
+	// A waiting batch has nothing left to start.
+	if batch.WaitingSince.Valid {
+		if err := dbQueries.ClearBatchWaiting(c.Request().Context(), batch.ID); err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		batch.WaitingSince = sql.NullTime{}
+	}
+	if err := recordEvent(c.Request().Context(), dbQueries, batch, database.BatchEventTypeCancelled); err != nil {
+		c.Logger().Errorf("failed to record batch event: %v", err)
+	}
+
+	images, err := dbQueries.GetImagesByBatchID(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "batch cancelled successfully", newBatchResponse(batch, images))
+}
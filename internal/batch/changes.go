@@ -0,0 +1,84 @@
+package batch
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetChanges godoc
+// @Summary Get image changes of a batch
+// @Description Poll for images of the batch created, updated or deleted since a cursor, oldest change first. Deleted images are only listed by ID in deleted. Start without since to get every image, then pass the returned next_cursor each time; when has_more is true, ask again right away. Changes show up once every transaction that started before them has finished, so none is skipped
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param since query string false "next_cursor of the previous response"
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} utils.SuccessResponse{data=ImageChangesResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/changes [get]
+func (h *BatchHandler) GetChanges(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	_, limit, err := utils.GetPagination(c)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	var since utils.Cursor
+	if v := c.QueryParam("since"); v != "" {
+		since, err = utils.DecodeCursor(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, err.Error())
+		}
+	}
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	// One extra row tells whether another page follows.
+	images, err := h.dbQueries.GetBatchImageChanges(c.Request().Context(), database.GetBatchImageChangesParams{
+		BatchID:   batch.ID,
+		SinceXid:  since.Seq,
+		SinceID:   since.ID,
+		PageLimit: int32(limit + 1),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	res := ImageChangesResponse{
+		Images:     []ImageResponse{},
+		Deleted:    []uuid.UUID{},
+		NextCursor: since.Encode(),
+		HasMore:    len(images) > limit,
+	}
+	for _, img := range images[:min(len(images), limit)] {
+		if img.DeletedAt.Valid {
+			res.Deleted = append(res.Deleted, img.ID)
+		} else {
+			res.Images = append(res.Images, NewImageResponse(img))
+		}
+		res.NextCursor = utils.Cursor{Seq: img.ChangeXid, ID: img.ID}.Encode()
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "changes retrieved successfully", res)
+}
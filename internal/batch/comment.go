@@ -0,0 +1,306 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetComments godoc
+// @Summary Get batch comments
+// @Description Retrieve the comment threads of a batch, oldest first, with replies nested under their parent
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=[]CommentResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/comments [get]
+func (h *BatchHandler) GetComments(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	comments, err := h.dbQueries.GetBatchComments(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "comments retrieved successfully", buildCommentThreads(comments))
+}
+
+// CreateComment godoc
+// @Summary Comment on batch
+// @Description Add a comment to a batch, optionally about a single image or as a reply to another comment
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param comment body CreateCommentRequest true "Create Comment Request"
+// @Success 201 {object} utils.SuccessResponse{data=CommentResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 422 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/comments [post]
+func (h *BatchHandler) CreateComment(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	var body CreateCommentRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	var parentID, imageID uuid.NullUUID
+	if body.ParentID != nil {
+		_, err := h.dbQueries.GetBatchCommentByID(c.Request().Context(), database.GetBatchCommentByIDParams{
+			ID:      *body.ParentID,
+			BatchID: batch.ID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.RespondError(c, http.StatusBadRequest, "parent comment not found")
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		parentID = uuid.NullUUID{UUID: *body.ParentID, Valid: true}
+	}
+	if body.ImageID != nil {
+		img, err := h.dbQueries.GetImageByID(c.Request().Context(), *body.ImageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if err != nil || img.BatchID != batch.ID {
+			return utils.RespondError(c, http.StatusBadRequest, "image not found in batch")
+		}
+		imageID = uuid.NullUUID{UUID: *body.ImageID, Valid: true}
+	}
+
+	comment, err := h.dbQueries.CreateBatchComment(c.Request().Context(), database.CreateBatchCommentParams{
+		BatchID:  batch.ID,
+		ImageID:  imageID,
+		ParentID: parentID,
+		UserID:   userID,
+		Body:     body.Body,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "comment")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "comment created successfully", toCommentResponse(comment))
+}
+
+// DeleteComment godoc
+// @Summary Delete batch comment
+// @Description Delete one of your own comments on a batch. Replies to it are kept
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param commentID path string true "Comment ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/comments/{commentID} [delete]
+func (h *BatchHandler) DeleteComment(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+	commentUUID := utils.ParamUUID(c, "commentID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	rows, err := h.dbQueries.DeleteBatchCommentByID(c.Request().Context(), database.DeleteBatchCommentByIDParams{
+		ID:      commentUUID,
+		BatchID: batch.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if rows == 0 {
+		return utils.RespondError(c, http.StatusNotFound, "comment not found")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "comment deleted successfully", nil)
+}
+
+// GetActivity godoc
+// @Summary Get batch activity
+// @Description Retrieve the activity feed of a batch, newest first, combining comments and lifecycle events (created, archived, restore_requested, restored, cancelled, transferred, deadline_passed)
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} utils.SuccessResponse{data=[]ActivityResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/activity [get]
+func (h *BatchHandler) GetActivity(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	page, limit, err := utils.GetPagination(c)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	activity, err := h.dbQueries.GetBatchActivity(c.Request().Context(), database.GetBatchActivityParams{
+		BatchID:    batch.ID,
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	total, err := h.dbQueries.CountBatchActivity(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	activityRes := make([]ActivityResponse, len(activity))
+	for i, a := range activity {
+		activityRes[i] = ActivityResponse{
+			ID:        a.ID,
+			Type:      a.Type,
+			UserID:    a.UserID,
+			Body:      a.Body,
+			CreatedAt: a.CreatedAt,
+		}
+		if a.ImageID.Valid {
+			activityRes[i].ImageID = &a.ImageID.UUID
+		}
+		if a.ParentID.Valid {
+			activityRes[i].ParentID = &a.ParentID.UUID
+		}
+	}
+
+	return utils.RespondPaginated(c, http.StatusOK, "activity retrieved successfully", activityRes, utils.NewPaginationMeta(page, limit, int(total)))
+}
+
+// recordEvent adds a lifecycle event to the batch activity feed. Callers only
+// log a failure, the action that triggered the event has already happened.
+func recordEvent(ctx context.Context, dbQueries *database.Queries, batch database.Batch, eventType database.BatchEventType) error {
+	return dbQueries.CreateBatchEvent(ctx, database.CreateBatchEventParams{
+		BatchID: batch.ID,
+		UserID:  batch.UserID,
+		Type:    eventType,
+	})
+}
+
+func toCommentResponse(comment database.BatchComment) CommentResponse {
+	res := CommentResponse{
+		ID:        comment.ID,
+		UserID:    comment.UserID,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Replies:   []CommentResponse{},
+	}
+	if comment.ImageID.Valid {
+		res.ImageID = &comment.ImageID.UUID
+	}
+	if comment.ParentID.Valid {
+		res.ParentID = &comment.ParentID.UUID
+	}
+	return res
+}
+
+// buildCommentThreads nests replies under their parent. Replies whose parent
+// was deleted are promoted to the top level so they stay visible.
+func buildCommentThreads(comments []database.BatchComment) []CommentResponse {
+	byID := make(map[uuid.UUID]*CommentResponse, len(comments))
+	for _, comment := range comments {
+		res := toCommentResponse(comment)
+		byID[comment.ID] = &res
+	}
+
+	var roots []*CommentResponse
+	children := make(map[uuid.UUID][]*CommentResponse)
+	for _, comment := range comments {
+		node := byID[comment.ID]
+		if comment.ParentID.Valid {
+			if _, ok := byID[comment.ParentID.UUID]; ok {
+				children[comment.ParentID.UUID] = append(children[comment.ParentID.UUID], node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	var build func(node *CommentResponse) CommentResponse
+	build = func(node *CommentResponse) CommentResponse {
+		for _, child := range children[node.ID] {
+			node.Replies = append(node.Replies, build(child))
+		}
+		return *node
+	}
+
+	threads := make([]CommentResponse, len(roots))
+	for i, root := range roots {
+		threads[i] = build(root)
+	}
+	return threads
+}
@@ -0,0 +1,471 @@
+package batch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const (
+	// defaultDeliveryTTL is how long delivery links work when the request
+	// sets no expiry.
+	defaultDeliveryTTL = 30 * 24 * time.Hour
+	// maxDeliveriesPerHour is how many recipients a user can send batches to
+	// in an hour.
+	maxDeliveriesPerHour = 100
+	// maxDeliverySends is how often an email is tried before it is given up.
+	maxDeliverySends = 5
+	// deliveryClaimSize is how many due emails one poll sends.
+	deliveryClaimSize = 20
+	// deliveryClaimLease keeps claimed emails from other servers while they
+	// are sent.
+	deliveryClaimLease = 5 * time.Minute
+	// defaultDeliveryMessage is sent when the request has no message.
+	defaultDeliveryMessage = `Hi,
+
+{{.ImageCount}} photos from "{{.BatchName}}" are ready for you:
+
+{{.Link}}
+
+The gallery is available until {{.ExpiresAt}}.`
+)
+
+// deliveryMessageData is what delivery message templates can use.
+type deliveryMessageData struct {
+	Recipient  string
+	BatchName  string
+	ImageCount int
+	Link       string
+	ExpiresAt  string
+}
+
+// CreateDelivery godoc
+// @Summary Send batch to clients
+// @Description Queue an email with a link to the gallery of a completed batch for each recipient. The message is a Go text/template that can use the fields .Recipient, .BatchName, .ImageCount, .Link and .ExpiresAt; the link is appended when the template leaves it out. Each recipient gets their own link, so opens and downloads are tracked per recipient. The emails are sent in the background, and a user can send to at most 100 recipients an hour
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param delivery body CreateDeliveryRequest true "Create Delivery Request"
+// @Success 202 {object} utils.SuccessResponse{data=[]DeliveryResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/deliveries [post]
+func (h *BatchHandler) CreateDelivery(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	var body CreateDeliveryRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	if strings.ContainsAny(body.Subject, "\r\n") {
+		return utils.RespondError(c, http.StatusBadRequest, "subject must be a single line")
+	}
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(cmp.Or(body.Message, defaultDeliveryMessage))
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid message template")
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	batch, err := dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if batch.ArchiveStatus == database.BatchArchiveStatusArchived || batch.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is archived")
+	}
+
+	// The user is locked, so concurrent requests cannot both fit under the
+	// limit.
+	if err := dbQueries.LockUserByID(c.Request().Context(), userID); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	sent, err := dbQueries.CountUserBatchDeliveriesSince(c.Request().Context(), database.CountUserBatchDeliveriesSinceParams{
+		UserID:    userID,
+		CreatedAt: time.Now().UTC().Add(-time.Hour),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if int(sent)+len(body.Recipients) > maxDeliveriesPerHour {
+		return utils.RespondError(c, http.StatusTooManyRequests, fmt.Sprintf("at most %d recipients can be sent batches an hour", maxDeliveriesPerHour))
+	}
+
+	images, err := dbQueries.GetImagesByBatchID(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	completed := 0
+	for _, img := range images {
+		switch img.Status {
+		case database.ImageStatusPending, database.ImageStatusProcessing:
+			return utils.RespondError(c, http.StatusConflict, "batch is still processing")
+		case database.ImageStatusCompleted:
+			completed++
+		}
+	}
+	if completed == 0 {
+		return utils.RespondError(c, http.StatusConflict, "batch has no completed images")
+	}
+
+	ttl := defaultDeliveryTTL
+	if body.ExpiresInDays > 0 {
+		ttl = time.Duration(body.ExpiresInDays) * 24 * time.Hour
+	}
+	expiresAt := time.Now().UTC().Add(ttl)
+	batchName := cmp.Or(batch.Name.String, "your batch")
+	subject := cmp.Or(body.Subject, fmt.Sprintf("Photos from %s", batchName))
+
+	type pendingDelivery struct {
+		recipient, token, message string
+	}
+	pending := make([]pendingDelivery, len(body.Recipients))
+	for i, recipient := range body.Recipients {
+		token, err := utils.GenerateRefresh()
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		// Every message is rendered before anything is sent, so a template
+		// that fails for one recipient sends nothing.
+		message, err := renderDeliveryMessage(tmpl, deliveryMessageData{
+			Recipient:  recipient,
+			BatchName:  batchName,
+			ImageCount: completed,
+			Link:       h.deliveryLink(token),
+			ExpiresAt:  expiresAt.Format(time.RFC1123),
+		})
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("invalid message template: %v", err))
+		}
+		pending[i] = pendingDelivery{recipient: recipient, token: token, message: message}
+	}
+
+	// The emails are queued with the deliveries and sent by PollDeliveries.
+	deliveriesRes := make([]DeliveryResponse, len(pending))
+	for i, p := range pending {
+		delivery, err := dbQueries.CreateBatchDelivery(c.Request().Context(), database.CreateBatchDeliveryParams{
+			BatchID:   batch.ID,
+			UserID:    userID,
+			Recipient: p.recipient,
+			TokenHash: hashDeliveryToken(p.token),
+			Subject:   subject,
+			Message:   p.message,
+			ReplyTo:   sql.NullString{String: body.ReplyTo, Valid: body.ReplyTo != ""},
+			LogoUrl:   sql.NullString{String: body.LogoURL, Valid: body.LogoURL != ""},
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		deliveriesRes[i] = toDeliveryResponse(delivery)
+	}
+
+	return utils.RespondJSON(c, http.StatusAccepted, "batch delivery queued", deliveriesRes)
+}
+
+// GetDeliveries godoc
+// @Summary Get batch deliveries
+// @Description Retrieve every recipient the batch was sent to, newest first, with how often they opened the gallery and downloaded images
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=[]DeliveryResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/deliveries [get]
+func (h *BatchHandler) GetDeliveries(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	deliveries, err := h.dbQueries.GetBatchDeliveries(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	deliveriesRes := make([]DeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		deliveriesRes[i] = toDeliveryResponse(d)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "deliveries retrieved successfully", deliveriesRes)
+}
+
+// GetDeliveryGallery godoc
+// @Summary Open delivery gallery
+// @Description Retrieve the completed images of a delivered batch with the token from the delivery email. Every call counts as an open. Image URLs point to the download endpoint, so downloads are counted
+// @Tags deliveries
+// @Produce json
+// @Param token path string true "Delivery token"
+// @Success 200 {object} utils.SuccessResponse{data=DeliveryGalleryResponse}
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /deliveries/{token} [get]
+func (h *BatchHandler) GetDeliveryGallery(c echo.Context) error {
+	delivery, err := h.dbQueries.GetBatchDeliveryByTokenHash(c.Request().Context(), hashDeliveryToken(c.Param("token")))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "delivery not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if delivery.ArchiveStatus == database.BatchArchiveStatusArchived || delivery.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is archived")
+	}
+
+	images, err := h.dbQueries.GetImagesByBatchID(c.Request().Context(), delivery.BatchID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if err := h.dbQueries.RecordBatchDeliveryOpen(c.Request().Context(), delivery.ID); err != nil {
+		c.Logger().Errorf("failed to record delivery open: %v", err)
+	}
+
+	imagesRes := []DeliveryImageResponse{}
+	for _, img := range images {
+		if img.Status != database.ImageStatusCompleted {
+			continue
+		}
+		imagesRes = append(imagesRes, DeliveryImageResponse{
+			ID:           img.ID,
+			Filename:     img.Filename.String,
+			URL:          deliveryDownloadURL(c, img.ID),
+			ThumbnailURL: img.ThumbnailUrl.String,
+			Width:        nullableInt(img.Width),
+			Height:       nullableInt(img.Height),
+			Blurhash:     img.Blurhash.String,
+		})
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "gallery retrieved successfully", DeliveryGalleryResponse{
+		BatchName: delivery.BatchName.String,
+		Message:   delivery.Message,
+		ReplyTo:   delivery.ReplyTo.String,
+		LogoURL:   delivery.LogoUrl.String,
+		ExpiresAt: delivery.ExpiresAt,
+		Images:    imagesRes,
+	})
+}
+
+// DownloadDeliveryImage godoc
+// @Summary Download delivered image
+// @Description Count a download of one image of a delivered batch and redirect to its processed file
+// @Tags deliveries
+// @Param token path string true "Delivery token"
+// @Param imageID path string true "Image ID"
+// @Success 302
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /deliveries/{token}/images/{imageID}/download [get]
+func (h *BatchHandler) DownloadDeliveryImage(c echo.Context) error {
+	imageUUID := utils.ParamUUID(c, "imageID")
+
+	delivery, err := h.dbQueries.GetBatchDeliveryByTokenHash(c.Request().Context(), hashDeliveryToken(c.Param("token")))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "delivery not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if delivery.ArchiveStatus == database.BatchArchiveStatusArchived || delivery.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is archived")
+	}
+
+	images, err := h.dbQueries.GetImagesByBatchID(c.Request().Context(), delivery.BatchID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	for _, img := range images {
+		if img.ID != imageUUID || img.Status != database.ImageStatusCompleted || !img.ProcessedUrl.Valid {
+			continue
+		}
+		if err := h.dbQueries.RecordBatchDeliveryDownload(c.Request().Context(), delivery.ID); err != nil {
+			c.Logger().Errorf("failed to record delivery download: %v", err)
+		}
+		return c.Redirect(http.StatusFound, img.ProcessedUrl.String)
+	}
+	return utils.RespondError(c, http.StatusNotFound, "image not found")
+}
+
+// renderDeliveryMessage executes tmpl for one recipient, appending the link
+// when the template leaves it out.
+func renderDeliveryMessage(tmpl *template.Template, data deliveryMessageData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	message := buf.String()
+	if !strings.Contains(message, data.Link) {
+		message = strings.TrimRight(message, "\n") + "\n\n" + data.Link
+	}
+	return message, nil
+}
+
+// deliveryDownloadURL is the download link of image imageID in the gallery
+// that c asked for, so downloads from it are counted.
+func deliveryDownloadURL(c echo.Context, imageID uuid.UUID) string {
+	return fmt.Sprintf("%s://%s%s/images/%s/download", c.Scheme(), c.Request().Host, strings.TrimRight(c.Request().URL.EscapedPath(), "/"), imageID)
+}
+
+// deliveryHTMLTemplate shows the sender's logo above the message in clients
+// that display HTML.
+var deliveryHTMLTemplate = htmltemplate.Must(htmltemplate.New("delivery").Parse(`<!DOCTYPE html>
+<html>
+<body>
+{{if .LogoURL}}<p><img src="{{.LogoURL}}" alt="" style="max-width:240px;max-height:80px"></p>
+{{end}}<div style="white-space:pre-wrap">{{.Message}}</div>
+</body>
+</html>`))
+
+// deliveryHTML renders the HTML version of a delivery email.
+func deliveryHTML(message, logoURL string) (string, error) {
+	var buf bytes.Buffer
+	err := deliveryHTMLTemplate.Execute(&buf, struct{ Message, LogoURL string }{message, logoURL})
+	return buf.String(), err
+}
+
+// PollDeliveries sends the queued emails of batch deliveries every interval
+// until ctx is done.
+func PollDeliveries(ctx context.Context, dbQueries *database.Queries, mailer utils.Mailer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sendDueDeliveries(ctx, dbQueries, mailer)
+		}
+	}
+}
+
+// deliveryOutbox is the part of *database.Queries that sends queued
+// delivery emails.
+type deliveryOutbox interface {
+	ClaimDueBatchDeliveries(ctx context.Context, arg database.ClaimDueBatchDeliveriesParams) ([]database.BatchDelivery, error)
+	MarkBatchDeliverySent(ctx context.Context, id uuid.UUID) error
+	RetryBatchDelivery(ctx context.Context, arg database.RetryBatchDeliveryParams) error
+	FailBatchDelivery(ctx context.Context, id uuid.UUID) error
+}
+
+func sendDueDeliveries(ctx context.Context, dbQueries deliveryOutbox, mailer utils.Mailer) {
+	deliveries, err := dbQueries.ClaimDueBatchDeliveries(ctx, database.ClaimDueBatchDeliveriesParams{
+		LeaseSeconds: int32(deliveryClaimLease / time.Second),
+		PageLimit:    deliveryClaimSize,
+	})
+	if err != nil {
+		log.Printf("error claim batch deliveries: %v", err)
+		return
+	}
+	for _, d := range deliveries {
+		if err := sendDelivery(ctx, dbQueries, mailer, d); err != nil {
+			log.Printf("error send batch delivery %s: %v", d.ID, err)
+		}
+	}
+}
+
+// sendDelivery makes one attempt at the email of d, retrying it a few
+// minutes later when it fails. A delivery that is never sent keeps an
+// unusable link. Errors leave d to be claimed again once its lease is over.
+func sendDelivery(ctx context.Context, dbQueries deliveryOutbox, mailer utils.Mailer, d database.BatchDelivery) error {
+	html, err := deliveryHTML(d.Message, d.LogoUrl.String)
+	if err != nil {
+		return err
+	}
+	opts := []utils.MailOption{utils.WithHTML(html)}
+	if d.ReplyTo.Valid {
+		opts = append(opts, utils.WithReplyTo(d.ReplyTo.String))
+	}
+	err = mailer.Send(ctx, d.Recipient, d.Subject, d.Message, opts...)
+	if err == nil {
+		return dbQueries.MarkBatchDeliverySent(ctx, d.ID)
+	}
+	log.Printf("failed to send batch delivery %s: %v", d.ID, err)
+	attempts := int(d.SendAttempts) + 1
+	if attempts >= maxDeliverySends {
+		return dbQueries.FailBatchDelivery(ctx, d.ID)
+	}
+	return dbQueries.RetryBatchDelivery(ctx, database.RetryBatchDeliveryParams{
+		ID:            d.ID,
+		NextAttemptAt: time.Now().UTC().Add(time.Duration(attempts*attempts) * time.Minute),
+	})
+}
+
+func (h *BatchHandler) deliveryLink(token string) string {
+	return fmt.Sprintf("%s/deliveries/%s", strings.TrimRight(h.config.AppURL, "/"), url.PathEscape(token))
+}
+
+// hashDeliveryToken returns the hex SHA-256 of a delivery token, so a leaked
+// database does not open anyone's gallery.
+func hashDeliveryToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toDeliveryResponse(d database.BatchDelivery) DeliveryResponse {
+	return DeliveryResponse{
+		ID:               d.ID,
+		BatchID:          d.BatchID,
+		Recipient:        d.Recipient,
+		Subject:          d.Subject,
+		Message:          d.Message,
+		ReplyTo:          d.ReplyTo.String,
+		LogoURL:          d.LogoUrl.String,
+		ExpiresAt:        d.ExpiresAt,
+		SentAt:           nullableTime(d.SentAt),
+		FailedAt:         nullableTime(d.FailedAt),
+		OpenCount:        int(d.OpenCount),
+		FirstOpenedAt:    nullableTime(d.FirstOpenedAt),
+		LastOpenedAt:     nullableTime(d.LastOpenedAt),
+		DownloadCount:    int(d.DownloadCount),
+		LastDownloadedAt: nullableTime(d.LastDownloadedAt),
+		CreatedAt:        d.CreatedAt,
+	}
+}
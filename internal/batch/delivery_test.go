@@ -0,0 +1,119 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox records what happened to the deliveries it hands out.
+type fakeOutbox struct {
+	due     []database.BatchDelivery
+	sent    []uuid.UUID
+	retried []database.RetryBatchDeliveryParams
+	failed  []uuid.UUID
+}
+
+func (f *fakeOutbox) ClaimDueBatchDeliveries(_ context.Context, _ database.ClaimDueBatchDeliveriesParams) ([]database.BatchDelivery, error) {
+	return f.due, nil
+}
+
+func (f *fakeOutbox) MarkBatchDeliverySent(_ context.Context, id uuid.UUID) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeOutbox) RetryBatchDelivery(_ context.Context, arg database.RetryBatchDeliveryParams) error {
+	f.retried = append(f.retried, arg)
+	return nil
+}
+
+func (f *fakeOutbox) FailBatchDelivery(_ context.Context, id uuid.UUID) error {
+	f.failed = append(f.failed, id)
+	return nil
+}
+
+// fakeMailer keeps the headers of the last email, failing with err.
+type fakeMailer struct {
+	err     error
+	to      string
+	headers utils.MailHeaders
+}
+
+func (m *fakeMailer) Send(_ context.Context, to, _, _ string, opts ...utils.MailOption) error {
+	m.to = to
+	m.headers = utils.NewMailHeaders(opts...)
+	return m.err
+}
+
+func TestSendDueDeliveries(t *testing.T) {
+	delivery := database.BatchDelivery{
+		ID:        uuid.New(),
+		Recipient: "client@example.com",
+		Subject:   "Photos",
+		Message:   "Your photos <3",
+		ReplyTo:   sql.NullString{String: "studio@example.com", Valid: true},
+		LogoUrl:   sql.NullString{String: "https://example.com/logo.png", Valid: true},
+	}
+
+	t.Run("sent", func(t *testing.T) {
+		outbox := &fakeOutbox{due: []database.BatchDelivery{delivery}}
+		mailer := &fakeMailer{}
+		sendDueDeliveries(context.Background(), outbox, mailer)
+		assert.Equal(t, []uuid.UUID{delivery.ID}, outbox.sent)
+		assert.Empty(t, outbox.retried)
+		assert.Equal(t, "client@example.com", mailer.to)
+		assert.Equal(t, "studio@example.com", mailer.headers.ReplyTo)
+		assert.Contains(t, mailer.headers.HTML, `<img src="https://example.com/logo.png"`)
+		assert.Contains(t, mailer.headers.HTML, "Your photos &lt;3")
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		outbox := &fakeOutbox{due: []database.BatchDelivery{delivery}}
+		before := time.Now().UTC()
+		sendDueDeliveries(context.Background(), outbox, &fakeMailer{err: errors.New("connection refused")})
+		assert.Empty(t, outbox.sent)
+		assert.Empty(t, outbox.failed)
+		require.Len(t, outbox.retried, 1)
+		assert.WithinDuration(t, before.Add(time.Minute), outbox.retried[0].NextAttemptAt, 5*time.Second)
+	})
+
+	t.Run("given up", func(t *testing.T) {
+		last := delivery
+		last.SendAttempts = maxDeliverySends - 1
+		outbox := &fakeOutbox{due: []database.BatchDelivery{last}}
+		sendDueDeliveries(context.Background(), outbox, &fakeMailer{err: errors.New("connection refused")})
+		assert.Empty(t, outbox.retried)
+		assert.Equal(t, []uuid.UUID{last.ID}, outbox.failed)
+	})
+}
+
+func TestDeliveryHTML(t *testing.T) {
+	html, err := deliveryHTML("Hi,\n\n<b>photos</b>", "")
+	require.NoError(t, err)
+	assert.NotContains(t, html, "<img")
+	assert.Contains(t, html, "Hi,\n\n&lt;b&gt;photos&lt;/b&gt;")
+
+	// Only http(s) logos are shown.
+	html, err = deliveryHTML("Hi", "javascript:alert(1)")
+	require.NoError(t, err)
+	assert.NotContains(t, html, "javascript:")
+}
+
+func TestDeliveryDownloadURL(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://api.example.com/api/v1/deliveries/abc-123", nil)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	imageID := uuid.New()
+	assert.Equal(t, "http://api.example.com/api/v1/deliveries/abc-123/images/"+imageID.String()+"/download", deliveryDownloadURL(c, imageID))
+}
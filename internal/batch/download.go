@@ -0,0 +1,169 @@
+package batch
+
+import (
+	"archive/zip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// zipEntry is one object of a batch download and its path in the archive.
+type zipEntry struct {
+	name  string
+	key   string
+	image database.Image
+}
+
+// Download godoc
+// @Summary Download batch
+// @Description Stream a ZIP archive of the processed images of a batch, named after their uploaded filenames. With originals=true the originals are included under originals/. Objects are streamed from storage one at a time, so an error part way through ends the download with a truncated archive
+// @Tags batches
+// @Produce application/zip
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param originals query bool false "Include the originals"
+// @Success 200 {file} binary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/download [get]
+func (h *BatchHandler) Download(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	var originals bool
+	if v := c.QueryParam("originals"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid originals")
+		}
+		originals = b
+	}
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if batch.ArchiveStatus == database.BatchArchiveStatusArchived || batch.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is archived")
+	}
+
+	storage, err := h.config.Storage(batch.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to download batch %s: %v", batch.ID, err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	images, err := h.dbQueries.GetImagesByBatchID(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	entries := downloadEntries(storage, images, originals)
+	if len(entries) == 0 {
+		return utils.RespondError(c, http.StatusConflict, "batch has no images to download")
+	}
+
+	// Images are already compressed, so entries are stored as they are and
+	// the archive is written straight to the response.
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/zip")
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="batch-%s.zip"`, batch.ID))
+	res.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(res)
+	for _, entry := range entries {
+		obj, err := storage.S3Client.GetObject(c.Request().Context(), &s3.GetObjectInput{
+			Bucket: aws.String(storage.S3Bucket),
+			Key:    aws.String(entry.key),
+		})
+		if err != nil {
+			c.Logger().Errorf("failed to download batch %s: get object %s: %v", batch.ID, entry.key, err)
+			return nil
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     entry.name,
+			Method:   zip.Store,
+			Modified: entry.image.UpdatedAt,
+		})
+		if err == nil {
+			_, err = io.Copy(w, obj.Body)
+		}
+		obj.Body.Close()
+		if err != nil {
+			c.Logger().Errorf("failed to download batch %s: write %s: %v", batch.ID, entry.name, err)
+			return nil
+		}
+	}
+	if err := zw.Close(); err != nil {
+		c.Logger().Errorf("failed to download batch %s: %v", batch.ID, err)
+	}
+	return nil
+}
+
+// downloadEntries lists the objects of a batch download. Entries are named
+// after the uploaded filename, or the image ID without one, with the
+// extension of the object; repeated names get a -n suffix.
+func downloadEntries(storage utils.RegionStorage, images []database.Image, originals bool) []zipEntry {
+	var entries []zipEntry
+	seen := make(map[string]int)
+	add := func(dir string, img database.Image, key string) {
+		stem := img.ID.String()
+		if img.Filename.Valid {
+			name := path.Base(strings.ReplaceAll(img.Filename.String, "\\", "/"))
+			if name = strings.TrimSuffix(name, path.Ext(name)); name != "" && name != "." && name != ".." {
+				stem = name
+			}
+		}
+		name := dir + stem + path.Ext(key)
+		for n := seen[name]; n > 0; n++ {
+			candidate := fmt.Sprintf("%s%s-%d%s", dir, stem, n, path.Ext(key))
+			if seen[candidate] == 0 {
+				seen[name] = n + 1
+				name = candidate
+				break
+			}
+		}
+		seen[name]++
+		entries = append(entries, zipEntry{name: name, key: key, image: img})
+	}
+
+	for _, img := range images {
+		if img.Status != database.ImageStatusCompleted {
+			continue
+		}
+		if key := utils.GetObjectKey(storage.S3CfDistribution, img.ProcessedUrl.String); key != "" {
+			add("", img, key)
+		}
+	}
+	if originals {
+		for _, img := range images {
+			if img.Key == "" {
+				continue
+			}
+			add("originals/", img, img.Key)
+		}
+	}
+	return entries
+}
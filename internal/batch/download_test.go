@@ -0,0 +1,106 @@
+package batch
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadEntries(t *testing.T) {
+	storage := utils.RegionStorage{S3CfDistribution: "cdn.example.com"}
+	unnamedID := uuid.New()
+	image := func(filename, key string, status database.ImageStatus) database.Image {
+		img := database.Image{
+			ID:       uuid.New(),
+			Key:      key,
+			Status:   status,
+			Filename: sql.NullString{String: filename, Valid: filename != ""},
+		}
+		if status == database.ImageStatusCompleted {
+			img.ProcessedUrl = sql.NullString{String: "https://cdn.example.com/processed/" + img.ID.String() + ".webp", Valid: true}
+		}
+		return img
+	}
+
+	tests := []struct {
+		name      string
+		images    []database.Image
+		originals bool
+		want      []string
+	}{
+		{
+			name:   "named after the upload with the processed extension",
+			images: []database.Image{image("beach.jpg", "raw/a.jpg", database.ImageStatusCompleted)},
+			want:   []string{"beach.webp"},
+		},
+		{
+			name: "unnamed images use their ID",
+			images: []database.Image{func() database.Image {
+				img := image("", "raw/a.jpg", database.ImageStatusCompleted)
+				img.ID = unnamedID
+				img.ProcessedUrl.String = "https://cdn.example.com/processed/x.png"
+				return img
+			}()},
+			want: []string{unnamedID.String() + ".png"},
+		},
+		{
+			name: "only completed images",
+			images: []database.Image{
+				image("done.jpg", "raw/a.jpg", database.ImageStatusCompleted),
+				image("failed.jpg", "raw/b.jpg", database.ImageStatusFailed),
+				image("pending.jpg", "raw/c.jpg", database.ImageStatusPending),
+			},
+			want: []string{"done.webp"},
+		},
+		{
+			name: "processed outside the distribution",
+			images: []database.Image{func() database.Image {
+				img := image("elsewhere.jpg", "raw/a.jpg", database.ImageStatusCompleted)
+				img.ProcessedUrl.String = "https://other.example.com/processed/a.webp"
+				return img
+			}()},
+		},
+		{
+			name: "duplicate names are numbered",
+			images: []database.Image{
+				image("photo.jpg", "raw/a.jpg", database.ImageStatusCompleted),
+				image("photo-1.jpg", "raw/b.jpg", database.ImageStatusCompleted),
+				image("photo.png", "raw/c.png", database.ImageStatusCompleted),
+				image("photo.jpeg", "raw/d.jpg", database.ImageStatusCompleted),
+			},
+			want: []string{"photo.webp", "photo-1.webp", "photo-2.webp", "photo-3.webp"},
+		},
+		{
+			name: "directories are dropped from filenames",
+			images: []database.Image{
+				image("../../etc/passwd.jpg", "raw/a.jpg", database.ImageStatusCompleted),
+				image(`C:\Users\me\pic.jpg`, "raw/b.jpg", database.ImageStatusCompleted),
+			},
+			want: []string{"passwd.webp", "pic.webp"},
+		},
+		{
+			name: "originals of every image follow the processed ones",
+			images: []database.Image{
+				image("photo.jpg", "raw/a.jpg", database.ImageStatusCompleted),
+				image("photo.png", "raw/b.png", database.ImageStatusFailed),
+				image("gone.jpg", "", database.ImageStatusFailed),
+			},
+			originals: true,
+			want:      []string{"photo.webp", "originals/photo.jpg", "originals/photo.png"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, e := range downloadEntries(storage, tt.images, tt.originals) {
+				names = append(names, e.name)
+				assert.NotEmpty(t, e.key)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
@@ -1,49 +1,1066 @@
 package batch
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rickyroynardson/image-go/internal/database"
 )
 
+// ImageTask is the worker message for one image. Output settings are copied
+// from the batch when the task is published; older messages without them
+// fall back to the batch's current settings.
 type ImageTask struct {
-	ImageID uuid.UUID `json:"image_id"`
+	// TaskID identifies one request to process the image, so redelivered
+	// or replayed copies of the message are only applied once. Older
+	// messages have none.
+	TaskID        uuid.UUID             `json:"task_id"`
+	ImageID       uuid.UUID             `json:"image_id"`
+	OutputFormat  database.OutputFormat `json:"output_format,omitempty"`
+	OutputQuality int                   `json:"output_quality,omitempty"`
+}
+
+// NewImageTask returns a task with a new TaskID.
+func NewImageTask(imageID uuid.UUID) ImageTask {
+	return ImageTask{TaskID: uuid.New(), ImageID: imageID}
+}
+
+// ImageStatusEvent is published by workers when an image starts or finishes
+// processing, for the servers to pass on to the sockets watching its batch.
+type ImageStatusEvent struct {
+	ImageID       uuid.UUID   `json:"image_id"`
+	BatchID       uuid.UUID   `json:"batch_id"`
+	UserID        uuid.UUID   `json:"user_id"`
+	Status        ImageStatus `json:"status"`
+	FailureReason string      `json:"failure_reason,omitempty"`
+	ProcessedURL  string      `json:"processed_url,omitempty"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// CleanupTask asks the worker to delete objects that are no longer referenced.
+type CleanupTask struct {
+	Keys []string `json:"keys"`
 }
 
 type ImageResponse struct {
-	ID           uuid.UUID            `json:"id"`
-	BatchID      uuid.UUID            `json:"batch_id"`
-	Key          string               `json:"key"`
-	OriginalURL  string               `json:"original_url"`
-	ProcessedURL string               `json:"processed_url"`
-	Status       database.ImageStatus `json:"status"`
-	CreatedAt    time.Time            `json:"created_at"`
-	UpdatedAt    time.Time            `json:"updated_at"`
+	ID          uuid.UUID `json:"id" example:"9c4e2a7b-1f3d-4b8e-a6c5-0d2f1e3a4b5c"`
+	BatchID     uuid.UUID `json:"batch_id"`
+	ExternalID  string    `json:"external_id"`
+	Key         string    `json:"key"`
+	Filename    string    `json:"filename" example:"portrait.jpg"`
+	OriginalURL string    `json:"original_url"`
+	// SourceURL is where the original of an image created from a URL was
+	// fetched from; original_url stays empty until it has been.
+	SourceURL    string      `json:"source_url"`
+	ProcessedURL string      `json:"processed_url"`
+	ThumbnailURL string      `json:"thumbnail_url"`
+	Status       ImageStatus `json:"status"`
+	// FailureReason says why a failed image could not be processed.
+	FailureReason string `json:"failure_reason" example:"original could not be read"`
+	// Attempts counts the times a worker started processing the image,
+	// including retries.
+	Attempts  int                 `json:"attempts" example:"1"`
+	Placement *WatermarkPlacement `json:"watermark_placement"`
+	// WatermarkOverride holds the batch watermark settings this image
+	// overrides, nil when it uses the batch's.
+	WatermarkOverride *WatermarkOverride `json:"watermark_override"`
+	// AppliedWatermarkPosition is where the worker placed the watermark, with
+	// the auto position resolved to a corner.
+	AppliedWatermarkPosition string   `json:"applied_watermark_position" example:"bottom-right"`
+	Palette                  []string `json:"palette"`
+	BlurHash                 string   `json:"blurhash"`
+	ThumbHash                string   `json:"thumbhash"`
+	Width                    *int     `json:"width"`
+	Height                   *int     `json:"height"`
+	// Original and Processed describe the stored files, so layouts can be
+	// rendered without loading them. They are nil until the image has been
+	// processed.
+	Original  *FileMetadata `json:"original"`
+	Processed *FileMetadata `json:"processed"`
+	// Redactions are the regions hidden before watermarking.
+	Redactions []Redaction `json:"redactions"`
+	// StepTimings are the durations of the batch's pipeline steps from the
+	// last time the image was processed.
+	StepTimings []StepTiming `json:"step_timings"`
+	// SimilarTo is the image whose processed files this one shares because
+	// their uploads looked the same, nil when it was processed on its own.
+	SimilarTo *uuid.UUID `json:"similar_to"`
+	// CapturedAt is when the photo was taken, read from the EXIF
+	// DateTimeOriginal of JPEG originals once processed. It is the camera's
+	// clock, which has no time zone, so it is given as UTC.
+	CapturedAt *time.Time `json:"captured_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// FileMetadata describes an original or processed file. Dimensions and the
+// dominant color are not known for videos.
+type FileMetadata struct {
+	Width  *int `json:"width"`
+	Height *int `json:"height"`
+	// Size is in bytes.
+	Size int64 `json:"size"`
+	// Format is the media type, e.g. image/jpeg.
+	Format string `json:"format"`
+	// DominantColor is the most common color as #rrggbb.
+	DominantColor string `json:"dominant_color"`
+}
+
+// WatermarkPlacement is a rectangle in coordinates normalized to the image
+// size (0..1 from the top-left corner).
+type WatermarkPlacement struct {
+	X      float64 `json:"x" validate:"min=0,max=1"`
+	Y      float64 `json:"y" validate:"min=0,max=1"`
+	Width  float64 `json:"width" validate:"gt=0,max=1"`
+	Height float64 `json:"height" validate:"gt=0,max=1"`
 }
 
+func NewImageResponse(img database.Image) ImageResponse {
+	res := ImageResponse{
+		ID:                       img.ID,
+		BatchID:                  img.BatchID,
+		ExternalID:               img.ExternalID.String,
+		Key:                      img.Key,
+		Filename:                 img.Filename.String,
+		OriginalURL:              img.OriginalUrl,
+		SourceURL:                img.SourceUrl.String,
+		ProcessedURL:             img.ProcessedUrl.String,
+		ThumbnailURL:             img.ThumbnailUrl.String,
+		Status:                   NewImageStatus(img.Status),
+		FailureReason:            img.FailureReason.String,
+		Attempts:                 int(img.Attempts),
+		Palette:                  img.Palette,
+		Width:                    nullableInt(img.Width),
+		Height:                   nullableInt(img.Height),
+		BlurHash:                 img.Blurhash.String,
+		ThumbHash:                img.Thumbhash.String,
+		AppliedWatermarkPosition: string(img.AppliedWatermarkPosition.WatermarkPosition),
+		CapturedAt:               nullableTime(img.CapturedAt),
+		CreatedAt:                img.CreatedAt,
+		UpdatedAt:                img.UpdatedAt,
+	}
+	if img.WatermarkPositionOverride.Valid || img.WatermarkOpacityOverride.Valid || img.WatermarkScaleOverride.Valid {
+		res.WatermarkOverride = &WatermarkOverride{
+			Opacity: nullableInt(img.WatermarkOpacityOverride),
+			Scale:   nullableInt(img.WatermarkScaleOverride),
+		}
+		if img.WatermarkPositionOverride.Valid {
+			res.WatermarkOverride.Position = &img.WatermarkPositionOverride.WatermarkPosition
+		}
+	}
+	if img.OriginalSize.Valid {
+		res.Original = &FileMetadata{
+			Width:         nullableInt(img.OriginalWidth),
+			Height:        nullableInt(img.OriginalHeight),
+			Size:          img.OriginalSize.Int64,
+			Format:        img.OriginalFormat.String,
+			DominantColor: img.OriginalDominantColor.String,
+		}
+	}
+	if img.ProcessedSize.Valid {
+		res.Processed = &FileMetadata{
+			Width:  nullableInt(img.Width),
+			Height: nullableInt(img.Height),
+			Size:   img.ProcessedSize.Int64,
+			Format: img.ProcessedFormat.String,
+		}
+		if len(img.Palette) > 0 {
+			res.Processed.DominantColor = img.Palette[0]
+		}
+	}
+	if redactions, err := DecodeRedactions(img.Redactions); err == nil {
+		res.Redactions = redactions
+	}
+	res.StepTimings = []StepTiming{}
+	if len(img.StepTimings) > 0 {
+		json.Unmarshal(img.StepTimings, &res.StepTimings)
+	}
+	if img.SimilarTo.Valid {
+		res.SimilarTo = &img.SimilarTo.UUID
+	}
+	if img.PlacementX.Valid {
+		res.Placement = &WatermarkPlacement{
+			X:      img.PlacementX.Float64,
+			Y:      img.PlacementY.Float64,
+			Width:  img.PlacementWidth.Float64,
+			Height: img.PlacementHeight.Float64,
+		}
+	}
+	return res
+}
+
+// ImageStatus is the state of an image as the API reports it: pending until
+// a worker picks it up, processing, then completed, failed, cancelled when
+// its batch was cancelled first or expired when its batch's deadline passed
+// first. Statuses are only ever added, as cancelled and expired were, so
+// clients should not fail on one they do not know: such an image has no
+// processed output yet, and the status of its batch tells whether work is
+// left.
+type ImageStatus string
+
+const (
+	ImageStatusPending    ImageStatus = "pending"
+	ImageStatusProcessing ImageStatus = "processing"
+	ImageStatusCompleted  ImageStatus = "completed"
+	ImageStatusFailed     ImageStatus = "failed"
+	ImageStatusCancelled  ImageStatus = "cancelled"
+	ImageStatusExpired    ImageStatus = "expired"
+)
+
+// NewImageStatus maps the stored status of an image to the API's. Both have
+// the same values, the API type only fixes what clients are promised.
+func NewImageStatus(s database.ImageStatus) ImageStatus {
+	return ImageStatus(s)
+}
+
+// Batch statuses, derived from the statuses of a batch's images: waiting
+// while the user is at MaxActiveBatches, pending until one is picked up,
+// processing while any is left, then cancelled when any was cancelled,
+// expired when any was skipped for missing the batch's deadline, failed when
+// any failed and completed otherwise.
+const (
+	BatchStatusWaiting    = "waiting"
+	BatchStatusPending    = "pending"
+	BatchStatusProcessing = "processing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusFailed     = "failed"
+	BatchStatusCancelled  = "cancelled"
+	BatchStatusExpired    = "expired"
+)
+
+// batchSorts are the orders GET /batches accepts, each descending with a
+// leading "-".
+var batchSorts = []string{"created_at", "-created_at", "name", "-name", "image_count", "-image_count"}
+
+// ImageSorts are the orders images of a batch and image searches accept, each
+// descending with a leading "-". Images without a capture time come last.
+var ImageSorts = []string{"created_at", "-created_at", "captured_at", "-captured_at"}
+
 type BatchesResponse struct {
-	ID                   string    `json:"id"`
-	UserID               string    `json:"user_id"`
-	Name                 string    `json:"name"`
-	WatermarkKey         string    `json:"watermark_key"`
-	WatermarkURL         string    `json:"watermark_url"`
-	CreatedAt            time.Time `json:"created_at"`
-	UpdatedAt            time.Time `json:"updated_at"`
-	ImageCount           int       `json:"image_count"`
-	ImagePendingCount    int       `json:"image_pending_count"`
-	ImageProcessingCount int       `json:"image_processing_count"`
-	ImageCompletedCount  int       `json:"image_completed_count"`
-	ImageFailedCount     int       `json:"image_failed_count"`
+	ID                   string         `json:"id" example:"3f2b8c1e-6d4a-4f5b-9a7e-2c1d0e9f8a7b"`
+	UserID               string         `json:"user_id"`
+	ExternalID           string         `json:"external_id"`
+	Name                 string         `json:"name" example:"spring-catalog"`
+	WatermarkKey         string         `json:"watermark_key"`
+	WatermarkURL         string         `json:"watermark_url"`
+	WatermarkID          string         `json:"watermark_id"`
+	WatermarkText        string         `json:"watermark_text"`
+	WatermarkFontID      string         `json:"watermark_font_id"`
+	WatermarkPosition    string         `json:"watermark_position" enums:"top-left,top-right,bottom-left,bottom-right,center,tiled,diagonal,auto" example:"bottom-right"`
+	WatermarkOpacity     int            `json:"watermark_opacity" example:"50"`
+	WatermarkScale       int            `json:"watermark_scale" example:"15"`
+	WatermarkTileSpacing int            `json:"watermark_tile_spacing"`
+	MaxConcurrency       int            `json:"max_concurrency"`
+	MaxWidth             *int           `json:"max_width"`
+	MaxHeight            *int           `json:"max_height"`
+	OutputFormat         string         `json:"output_format" enums:"jpeg,png,webp" example:"jpeg"`
+	OutputQuality        int            `json:"output_quality" example:"50"`
+	JpegProgressive      bool           `json:"jpeg_progressive"`
+	JpegSubsampling      string         `json:"jpeg_subsampling" enums:"444,422,420" example:"420"`
+	PngCompression       string         `json:"png_compression" enums:"default,none,fast,best" example:"default"`
+	SimilarDedupe        string         `json:"similar_dedupe" enums:"off,batch,account" example:"off"`
+	Crop                 *Crop          `json:"crop"`
+	Transforms           []Transform    `json:"transforms"`
+	Pipeline             []PipelineStep `json:"pipeline"`
+	Status               string         `json:"status" enums:"waiting,pending,processing,completed,failed,cancelled,expired" example:"processing"`
+	ArchiveStatus        string         `json:"archive_status" enums:"active,archived,restoring,restored" example:"active"`
+	PreserveFilenames    bool           `json:"preserve_filenames"`
+	PreserveMetadata     bool           `json:"preserve_metadata"`
+	InvisibleWatermark   bool           `json:"invisible_watermark"`
+	CollisionPolicy      string         `json:"collision_policy" enums:"overwrite,suffix,error" example:"suffix"`
+	ExpiresAt            *time.Time     `json:"expires_at"`
+	Deadline             *time.Time     `json:"deadline"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	ImageCount           int            `json:"image_count" example:"120"`
+	ImagePendingCount    int            `json:"image_pending_count"`
+	ImageProcessingCount int            `json:"image_processing_count"`
+	ImageCompletedCount  int            `json:"image_completed_count"`
+	ImageFailedCount     int            `json:"image_failed_count"`
+	ImageCancelledCount  int            `json:"image_cancelled_count"`
+	ImageExpiredCount    int            `json:"image_expired_count"`
 }
 
 type BatchResponse struct {
-	ID           uuid.UUID       `json:"id"`
-	UserID       uuid.UUID       `json:"user_id"`
-	Name         string          `json:"name"`
-	WatermarkKey string          `json:"watermark_key"`
-	WatermarkURL string          `json:"watermark_url"`
-	CreatedAt    time.Time       `json:"created_at"`
-	UpdatedAt    time.Time       `json:"updated_at"`
-	Images       []ImageResponse `json:"images"`
+	ID                   uuid.UUID      `json:"id" example:"3f2b8c1e-6d4a-4f5b-9a7e-2c1d0e9f8a7b"`
+	UserID               uuid.UUID      `json:"user_id"`
+	ExternalID           string         `json:"external_id"`
+	Name                 string         `json:"name" example:"spring-catalog"`
+	WatermarkKey         string         `json:"watermark_key"`
+	WatermarkURL         string         `json:"watermark_url"`
+	WatermarkID          string         `json:"watermark_id"`
+	WatermarkText        string         `json:"watermark_text"`
+	WatermarkFontID      string         `json:"watermark_font_id"`
+	WatermarkPosition    string         `json:"watermark_position" enums:"top-left,top-right,bottom-left,bottom-right,center,tiled,diagonal,auto" example:"bottom-right"`
+	WatermarkOpacity     int            `json:"watermark_opacity" example:"50"`
+	WatermarkScale       int            `json:"watermark_scale" example:"15"`
+	WatermarkTileSpacing int            `json:"watermark_tile_spacing"`
+	MaxConcurrency       int            `json:"max_concurrency"`
+	MaxWidth             *int           `json:"max_width"`
+	MaxHeight            *int           `json:"max_height"`
+	OutputFormat         string         `json:"output_format" enums:"jpeg,png,webp" example:"jpeg"`
+	OutputQuality        int            `json:"output_quality" example:"50"`
+	JpegProgressive      bool           `json:"jpeg_progressive"`
+	JpegSubsampling      string         `json:"jpeg_subsampling" enums:"444,422,420" example:"420"`
+	PngCompression       string         `json:"png_compression" enums:"default,none,fast,best" example:"default"`
+	SimilarDedupe        string         `json:"similar_dedupe" enums:"off,batch,account" example:"off"`
+	Crop                 *Crop          `json:"crop"`
+	Transforms           []Transform    `json:"transforms"`
+	Pipeline             []PipelineStep `json:"pipeline"`
+	Status               string         `json:"status" enums:"waiting,pending,processing,completed,failed,cancelled,expired" example:"processing"`
+	ArchiveStatus        string         `json:"archive_status" enums:"active,archived,restoring,restored" example:"active"`
+	PreserveFilenames    bool           `json:"preserve_filenames"`
+	PreserveMetadata     bool           `json:"preserve_metadata"`
+	InvisibleWatermark   bool           `json:"invisible_watermark"`
+	CollisionPolicy      string         `json:"collision_policy" enums:"overwrite,suffix,error" example:"suffix"`
+	ReportCSV            bool           `json:"report_csv"`
+	// Report links the processing report written when the batch last
+	// finished, nil before then.
+	Report *BatchReportLinks `json:"report"`
+	// ExpiresAt is when the batch is deleted with its files, nil if never.
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Deadline is when images not yet processed are skipped and marked
+	// expired, nil if the batch has none.
+	Deadline  *time.Time      `json:"deadline"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Images    []ImageResponse `json:"images"`
+}
+
+// TrashedBatchResponse is a deleted batch that can still be restored, until
+// PurgeAt.
+type TrashedBatchResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name" example:"spring-catalog"`
+	ExternalID string    `json:"external_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAt    time.Time `json:"purge_at"`
+}
+
+// BatchReportLinks locates the processing report of a batch. CSVURL is
+// empty unless the batch asked for a CSV copy.
+type BatchReportLinks struct {
+	URL         string    `json:"url"`
+	CSVURL      string    `json:"csv_url"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// WatermarkOverride replaces some of the batch's watermark settings for one
+// image. Unset fields fall back to the batch.
+type WatermarkOverride struct {
+	Position *database.WatermarkPosition `json:"watermark_position,omitempty"`
+	Opacity  *int                        `json:"watermark_opacity,omitempty" validate:"omitempty,min=0,max=100"`
+	Scale    *int                        `json:"watermark_scale,omitempty" validate:"omitempty,min=1,max=100"`
+}
+
+// ManifestEntry is one element of the manifest form field of batch creation,
+// matched to an uploaded file by filename.
+type ManifestEntry struct {
+	Filename string `json:"filename" validate:"required"`
+	// ExternalID is the client's own reference for the image, unique among
+	// the user's images.
+	ExternalID string `json:"external_id" validate:"max=255"`
+	// Redactions are blurred or pixelated before the image is watermarked.
+	Redactions []Redaction `json:"redactions" validate:"max=100,dive"`
+	WatermarkOverride
+}
+
+// RedactionType names how a region of an image is hidden.
+type RedactionType string
+
+const (
+	RedactionBlur     RedactionType = "blur"
+	RedactionPixelate RedactionType = "pixelate"
+)
+
+// Redaction is a region hidden in one image, in pixels of the upright
+// original from its top-left corner.
+type Redaction struct {
+	Type   RedactionType `json:"type" validate:"required,oneof=blur pixelate"`
+	X      int           `json:"x" validate:"min=0"`
+	Y      int           `json:"y" validate:"min=0"`
+	Width  int           `json:"width" validate:"min=1"`
+	Height int           `json:"height" validate:"min=1"`
+}
+
+// DecodeRedactions reads the redactions stored on an image.
+func DecodeRedactions(raw json.RawMessage) ([]Redaction, error) {
+	redactions := []Redaction{}
+	if len(raw) == 0 {
+		return redactions, nil
+	}
+	if err := json.Unmarshal(raw, &redactions); err != nil {
+		return nil, err
+	}
+	return redactions, nil
+}
+
+// TransformType names a transform applied after watermarking.
+type TransformType string
+
+const (
+	// TransformBorder surrounds the image with a solid frame Size pixels wide.
+	TransformBorder TransformType = "border"
+	// TransformPad extends the canvas evenly to AspectRatio.
+	TransformPad TransformType = "pad"
+	// TransformLetterbox scales the image to fit Width by Height and centers
+	// it on a canvas of exactly that size.
+	TransformLetterbox TransformType = "letterbox"
+)
+
+// maxTransforms bounds the transforms of a batch.
+const maxTransforms = 10
+
+// Transform is one step of the batch's transforms, applied in order. Added
+// canvas is filled with Color, #rrggbb or #rrggbbaa, white by default.
+type Transform struct {
+	Type        TransformType `json:"type" validate:"required,oneof=border pad letterbox"`
+	Size        int           `json:"size,omitempty" validate:"required_if=Type border,omitempty,min=1,max=1000"`
+	AspectRatio string        `json:"aspect_ratio,omitempty" validate:"required_if=Type pad"`
+	Width       int           `json:"width,omitempty" validate:"required_if=Type letterbox,omitempty,min=1,max=5000"`
+	Height      int           `json:"height,omitempty" validate:"required_if=Type letterbox,omitempty,min=1,max=5000"`
+	Color       string        `json:"color,omitempty" validate:"omitempty,hexcolor"`
+}
+
+// Ratio parses AspectRatio with ParseAspectRatio.
+func (t Transform) Ratio() (width, height int, ok bool) {
+	return ParseAspectRatio(t.AspectRatio)
+}
+
+// ParseAspectRatio parses an aspect ratio written as width:height with whole
+// numbers from 1 to 100, e.g. 16:9.
+func ParseAspectRatio(s string) (width, height int, ok bool) {
+	w, h, found := strings.Cut(s, ":")
+	if !found {
+		return 0, 0, false
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil || width < 1 || width > 100 {
+		return 0, 0, false
+	}
+	height, err = strconv.Atoi(h)
+	if err != nil || height < 1 || height > 100 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// StepType names a step of a processing pipeline.
+type StepType string
+
+const (
+	// StepResize downscales the image to fit Width by Height, or the batch's
+	// max_width and max_height when neither is set.
+	StepResize StepType = "resize"
+	// StepWatermark applies the batch's watermark, if it has one.
+	StepWatermark StepType = "watermark"
+	// StepSharpen applies an unsharp mask of Amount percent.
+	StepSharpen StepType = "sharpen"
+	// StepEncode writes the output file in the batch's output format.
+	StepEncode StepType = "encode"
+)
+
+// maxPipelineSteps bounds the steps of a pipeline.
+const maxPipelineSteps = 10
+
+// PipelineStep is one step of the batch's pipeline, run in order on every
+// image after redactions and the crop.
+type PipelineStep struct {
+	Type   StepType `json:"type" validate:"required,oneof=resize watermark sharpen encode"`
+	Width  int      `json:"width,omitempty" validate:"omitempty,min=1,max=10000"`
+	Height int      `json:"height,omitempty" validate:"omitempty,min=1,max=10000"`
+	Amount int      `json:"amount,omitempty" validate:"omitempty,min=1,max=500"`
+}
+
+// DefaultPipeline is run for batches created without a pipeline.
+var DefaultPipeline = []PipelineStep{{Type: StepResize}, {Type: StepWatermark}, {Type: StepEncode}}
+
+// ValidatePipeline checks the order of steps that are valid on their own:
+// encode must come last and the watermark must be applied exactly once.
+func ValidatePipeline(steps []PipelineStep) error {
+	if len(steps) == 0 || len(steps) > maxPipelineSteps {
+		return fmt.Errorf("pipeline must have between 1 and %d steps", maxPipelineSteps)
+	}
+	watermarks := 0
+	for i, step := range steps {
+		if step.Type == StepEncode && i != len(steps)-1 {
+			return errors.New("pipeline can only encode in its last step")
+		}
+		if step.Type == StepWatermark {
+			watermarks++
+		}
+	}
+	if steps[len(steps)-1].Type != StepEncode {
+		return errors.New("pipeline must end with an encode step")
+	}
+	if watermarks != 1 {
+		return errors.New("pipeline must have exactly one watermark step")
+	}
+	return nil
+}
+
+// DecodePipeline reads the pipeline stored on a batch, DefaultPipeline when
+// it has none.
+func DecodePipeline(raw json.RawMessage) ([]PipelineStep, error) {
+	var steps []PipelineStep
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &steps); err != nil {
+			return nil, err
+		}
+	}
+	if len(steps) == 0 {
+		return DefaultPipeline, nil
+	}
+	return steps, nil
+}
+
+// StepTiming is how long a pipeline step took for one image, summed over the
+// frames of animations.
+type StepTiming struct {
+	Step       StepType `json:"step"`
+	DurationMs float64  `json:"duration_ms"`
+}
+
+// Crop is cut from uploads before they are watermarked: centered at
+// AspectRatio, or the pixel rectangle Rect of the upright original.
+type Crop struct {
+	AspectRatio string    `json:"aspect_ratio,omitempty"`
+	Rect        *CropRect `json:"rect,omitempty"`
+}
+
+type CropRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// newCrop returns the crop stored in a batch's columns, nil when it keeps
+// the whole image.
+func newCrop(aspectRatio sql.NullString, x, y, width, height sql.NullInt32) *Crop {
+	switch {
+	case aspectRatio.Valid:
+		return &Crop{AspectRatio: aspectRatio.String}
+	case x.Valid:
+		return &Crop{Rect: &CropRect{
+			X:      int(x.Int32),
+			Y:      int(y.Int32),
+			Width:  int(width.Int32),
+			Height: int(height.Int32),
+		}}
+	}
+	return nil
+}
+
+// DecodeTransforms reads the transforms stored on a batch.
+func DecodeTransforms(raw json.RawMessage) ([]Transform, error) {
+	transforms := []Transform{}
+	if len(raw) == 0 {
+		return transforms, nil
+	}
+	if err := json.Unmarshal(raw, &transforms); err != nil {
+		return nil, err
+	}
+	return transforms, nil
+}
+
+// DedupePolicy decides what batch creation does with files whose content the
+// user has already uploaded.
+type DedupePolicy string
+
+const (
+	// DedupePolicyAllow uploads duplicates as new images.
+	DedupePolicyAllow DedupePolicy = "allow"
+	// DedupePolicyLink adds duplicates as new images that reuse the stored original.
+	DedupePolicyLink DedupePolicy = "link"
+	// DedupePolicySkip leaves duplicates out of the batch.
+	DedupePolicySkip DedupePolicy = "skip"
+)
+
+// Actions reported for duplicate uploads.
+const (
+	DuplicateActionUploaded = "uploaded"
+	DuplicateActionLinked   = "linked"
+	DuplicateActionSkipped  = "skipped"
+)
+
+type CreateBatchResponse struct {
+	ID uuid.UUID `json:"id"`
+	// Status is waiting when the batch is queued behind the user's other
+	// active batches, pending otherwise.
+	Status     string            `json:"status" enums:"waiting,pending" example:"pending"`
+	Duplicates []DuplicateUpload `json:"duplicates"`
+	// Images lists the images created for the batch, in the order of the
+	// request.
+	Images []AcceptedUpload `json:"images"`
+	// Rejected lists the files left out of the batch, because their content
+	// is not a supported image, does not match their Content-Type or is
+	// corrupt, or because they could not be stored.
+	Rejected []RejectedUpload `json:"rejected"`
+}
+
+// AcceptedUpload is the image created for a file of a create request. Key
+// is the object of its original, shared with an earlier image when it was
+// linked as a duplicate, and empty until a worker fetches an original given
+// by URL.
+type AcceptedUpload struct {
+	Filename string    `json:"filename" example:"photo.jpg"`
+	ImageID  uuid.UUID `json:"image_id"`
+	Key      string    `json:"key"`
+	// Queued is reported by POST /batches: true when the queue confirmed
+	// the image's task before the response. A task it did not confirm is
+	// published again by the server within a few minutes, so the file must
+	// not be sent again. Always false for a waiting batch.
+	Queued *bool `json:"queued,omitempty"`
+}
+
+// RejectedUpload reports a file that was not added to the batch. Retryable
+// files failed on the server's side and can be sent again as they are, in a
+// new batch; the others have to be fixed first.
+type RejectedUpload struct {
+	Filename  string `json:"filename" example:"photo.jpg"`
+	Reason    string `json:"reason" example:"sent as image/png but the content is image/jpeg"`
+	Retryable bool   `json:"retryable"`
+}
+
+// DuplicateUpload reports a file whose content matches an existing image of
+// the user, including earlier files of the same request.
+type DuplicateUpload struct {
+	Filename    string    `json:"filename"`
+	DuplicateOf uuid.UUID `json:"duplicate_of"`
+	Action      string    `json:"action"`
+}
+
+// ImageChangesResponse is a page of images changed since a cursor.
+type ImageChangesResponse struct {
+	Images []ImageResponse `json:"images"`
+	// Deleted are the IDs of images deleted since the cursor.
+	Deleted []uuid.UUID `json:"deleted"`
+	// NextCursor is passed as since to get the changes after these. It stays
+	// the same while nothing changes.
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// BatchSavingsResponse compares the size of a batch's originals with the
+// outputs of its completed images. SavedPercent is negative when the outputs
+// are larger.
+type BatchSavingsResponse struct {
+	ImageCount     int64           `json:"image_count"`
+	OriginalBytes  int64           `json:"original_bytes"`
+	ProcessedBytes int64           `json:"processed_bytes"`
+	SavedBytes     int64           `json:"saved_bytes"`
+	SavedPercent   float64         `json:"saved_percent"`
+	Formats        []FormatSavings `json:"formats"`
+}
+
+// FormatSavings is the share of a batch's savings converted from one format
+// to another.
+type FormatSavings struct {
+	OriginalFormat  string  `json:"original_format"`
+	ProcessedFormat string  `json:"processed_format"`
+	ImageCount      int64   `json:"image_count"`
+	OriginalBytes   int64   `json:"original_bytes"`
+	ProcessedBytes  int64   `json:"processed_bytes"`
+	SavedBytes      int64   `json:"saved_bytes"`
+	SavedPercent    float64 `json:"saved_percent"`
+}
+
+// savedPercent is the share of original saved, to one decimal place.
+func savedPercent(original, processed int64) float64 {
+	if original == 0 {
+		return 0
+	}
+	return math.Round(float64(original-processed)*1000/float64(original)) / 10
+}
+
+type UploadTokenResponse struct {
+	UploadToken string    `json:"upload_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type PresignUploadRequest struct {
+	ContentType string `json:"content_type" validate:"required,oneof=image/jpeg image/png image/webp image/gif"`
+}
+
+type PresignUploadResponse struct {
+	Key       string    `json:"key"`
+	UploadURL string    `json:"upload_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ConfirmUploadRequest struct {
+	Key        string `json:"key" validate:"required"`
+	Filename   string `json:"filename" validate:"max=255"`
+	ExternalID string `json:"external_id" validate:"max=255"`
+}
+
+type CreateCommentRequest struct {
+	Body     string     `json:"body" validate:"required,max=5000"`
+	ParentID *uuid.UUID `json:"parent_id"`
+	ImageID  *uuid.UUID `json:"image_id"`
+}
+
+type CommentResponse struct {
+	ID        uuid.UUID         `json:"id"`
+	UserID    uuid.UUID         `json:"user_id"`
+	ImageID   *uuid.UUID        `json:"image_id"`
+	ParentID  *uuid.UUID        `json:"parent_id"`
+	Body      string            `json:"body"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Replies   []CommentResponse `json:"replies"`
+}
+
+type ActivityResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ImageID   *uuid.UUID `json:"image_id,omitempty"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	Body      string     `json:"body,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// nullableInt returns a pointer to v's value, or nil when it is NULL.
+// newBatchResponse describes a batch with its images.
+func newBatchResponse(batch database.Batch, images []database.Image) BatchResponse {
+	imagesRes := make([]ImageResponse, len(images))
+	for i, img := range images {
+		imagesRes[i] = NewImageResponse(img)
+	}
+
+	var watermarkID, watermarkFontID string
+	if batch.WatermarkID.Valid {
+		watermarkID = batch.WatermarkID.UUID.String()
+	}
+	if batch.WatermarkFontID.Valid {
+		watermarkFontID = batch.WatermarkFontID.UUID.String()
+	}
+	// Transforms are validated before they are stored.
+	transforms, _ := DecodeTransforms(batch.Transforms)
+	pipeline, _ := DecodePipeline(batch.Pipeline)
+	var report *BatchReportLinks
+	if batch.ReportUrl.Valid && batch.ReportGeneratedAt.Valid {
+		report = &BatchReportLinks{
+			URL:         batch.ReportUrl.String,
+			CSVURL:      batch.ReportCsvUrl.String,
+			GeneratedAt: batch.ReportGeneratedAt.Time,
+		}
+	}
+
+	return BatchResponse{
+		ID:                   batch.ID,
+		UserID:               batch.UserID,
+		ExternalID:           batch.ExternalID.String,
+		Name:                 batch.Name.String,
+		WatermarkKey:         batch.WatermarkKey.String,
+		WatermarkURL:         batch.WatermarkUrl.String,
+		WatermarkID:          watermarkID,
+		WatermarkText:        batch.WatermarkText.String,
+		WatermarkFontID:      watermarkFontID,
+		WatermarkPosition:    string(batch.WatermarkPosition),
+		WatermarkOpacity:     int(batch.WatermarkOpacity),
+		WatermarkScale:       int(batch.WatermarkScale),
+		WatermarkTileSpacing: int(batch.WatermarkTileSpacing),
+		MaxConcurrency:       int(batch.MaxConcurrency),
+		MaxWidth:             nullableInt(batch.MaxWidth),
+		MaxHeight:            nullableInt(batch.MaxHeight),
+		OutputFormat:         string(batch.OutputFormat),
+		OutputQuality:        int(batch.OutputQuality),
+		JpegProgressive:      batch.JpegProgressive,
+		JpegSubsampling:      string(batch.JpegSubsampling),
+		PngCompression:       string(batch.PngCompression),
+		SimilarDedupe:        string(batch.SimilarDedupe),
+		Crop:                 newCrop(batch.CropAspectRatio, batch.CropX, batch.CropY, batch.CropWidth, batch.CropHeight),
+		Transforms:           transforms,
+		Pipeline:             pipeline,
+		Status:               batchStatus(batch, images),
+		ArchiveStatus:        string(batch.ArchiveStatus),
+		PreserveFilenames:    batch.PreserveFilenames,
+		PreserveMetadata:     batch.PreserveMetadata,
+		InvisibleWatermark:   batch.InvisibleWatermark,
+		CollisionPolicy:      string(batch.CollisionPolicy),
+		ReportCSV:            batch.ReportCsv,
+		Report:               report,
+		ExpiresAt:            nullableTime(batch.ExpiresAt),
+		Deadline:             nullableTime(batch.Deadline),
+		CreatedAt:            batch.CreatedAt,
+		UpdatedAt:            batch.UpdatedAt,
+		Images:               imagesRes,
+	}
+}
+
+// batchStatus derives the status of a batch the way GetAllUserBatches does.
+func batchStatus(batch database.Batch, images []database.Image) string {
+	var counts ImageStatusCounts
+	for _, img := range images {
+		switch img.Status {
+		case database.ImageStatusPending:
+			counts.Pending++
+		case database.ImageStatusProcessing:
+			counts.Processing++
+		case database.ImageStatusCompleted:
+			counts.Completed++
+		case database.ImageStatusFailed:
+			counts.Failed++
+		case database.ImageStatusCancelled:
+			counts.Cancelled++
+		case database.ImageStatusExpired:
+			counts.Expired++
+		}
+	}
+	return counts.batchStatus(batch)
+}
+
+// ImageStatusCounts counts the images of a batch by status.
+type ImageStatusCounts struct {
+	Pending    int64 `json:"pending"`
+	Processing int64 `json:"processing"`
+	Completed  int64 `json:"completed"`
+	Failed     int64 `json:"failed"`
+	Cancelled  int64 `json:"cancelled"`
+	Expired    int64 `json:"expired"`
+}
+
+func (c ImageStatusCounts) total() int64 {
+	return c.Pending + c.Processing + c.Completed + c.Failed + c.Cancelled + c.Expired
+}
+
+func (c ImageStatusCounts) batchStatus(batch database.Batch) string {
+	if batch.WaitingSince.Valid {
+		return BatchStatusWaiting
+	}
+	switch {
+	case c.Pending == c.total():
+		return BatchStatusPending
+	case c.Pending+c.Processing > 0:
+		return BatchStatusProcessing
+	case c.Cancelled > 0:
+		return BatchStatusCancelled
+	case c.Expired > 0:
+		return BatchStatusExpired
+	case c.Failed > 0:
+		return BatchStatusFailed
+	}
+	return BatchStatusCompleted
+}
+
+// BatchProgressResponse is a lightweight view of how far a batch is. The
+// rate counts images completed or failed over the last five minutes, or
+// since the batch was created when that is more recent; ETASeconds is nil
+// while nothing finished in that window or nothing is left.
+type BatchProgressResponse struct {
+	BatchID         uuid.UUID         `json:"batch_id"`
+	Status          string            `json:"status" enums:"waiting,pending,processing,completed,failed,cancelled,expired" example:"processing"`
+	Total           int64             `json:"total"`
+	Counts          ImageStatusCounts `json:"counts"`
+	PercentDone     float64           `json:"percent_done"`
+	ImagesPerMinute float64           `json:"images_per_minute"`
+	ETASeconds      *int64            `json:"eta_seconds"`
+}
+
+// newBatchProgressResponse derives the rate and ETA of a batch from its
+// counts and the images that finished within the last windowSeconds.
+func newBatchProgressResponse(batch database.Batch, counts ImageStatusCounts, recentlyFinished int64, windowSeconds float64) BatchProgressResponse {
+	res := BatchProgressResponse{
+		BatchID: batch.ID,
+		Status:  counts.batchStatus(batch),
+		Total:   counts.total(),
+		Counts:  counts,
+	}
+	if res.Total > 0 {
+		done := counts.Completed + counts.Failed + counts.Cancelled + counts.Expired
+		res.PercentDone = math.Round(float64(done)/float64(res.Total)*1000) / 10
+	}
+	if recentlyFinished > 0 && windowSeconds > 0 {
+		perSecond := float64(recentlyFinished) / windowSeconds
+		res.ImagesPerMinute = math.Round(perSecond*60*10) / 10
+		if remaining := counts.Pending + counts.Processing; remaining > 0 {
+			eta := int64(math.Ceil(float64(remaining) / perSecond))
+			res.ETASeconds = &eta
+		}
+	}
+	return res
+}
+
+func nullableInt(v sql.NullInt32) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int32)
+	return &n
+}
+
+func nullableTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+// UpdateBatchRequest changes a batch. Nil fields are left unchanged; the
+// watermark fields can only change before processing starts.
+type UpdateBatchRequest struct {
+	Name                 *string `json:"name" validate:"omitempty,max=255"`
+	WatermarkText        *string `json:"watermark_text" validate:"omitempty,min=1,max=100"`
+	WatermarkPosition    *string `json:"watermark_position" validate:"omitempty,oneof=top-left top-right bottom-left bottom-right center tiled diagonal auto"`
+	WatermarkOpacity     *int    `json:"watermark_opacity" validate:"omitempty,min=0,max=100"`
+	WatermarkScale       *int    `json:"watermark_scale" validate:"omitempty,min=1,max=100"`
+	WatermarkTileSpacing *int    `json:"watermark_tile_spacing" validate:"omitempty,min=0,max=500"`
+}
+
+func (r UpdateBatchRequest) changesWatermark() bool {
+	return r.WatermarkText != nil || r.WatermarkPosition != nil || r.WatermarkOpacity != nil || r.WatermarkScale != nil || r.WatermarkTileSpacing != nil
+}
+
+// ReprocessBatchRequest runs the originals of a batch again as a new batch.
+// Nil fields keep the source batch's settings, or take those of preset_id;
+// watermark_id or watermark_text replace its watermark.
+type ReprocessBatchRequest struct {
+	Name                 *string `json:"name" validate:"omitempty,max=255"`
+	PresetID             *string `json:"preset_id" validate:"omitempty,uuid"`
+	WatermarkID          *string `json:"watermark_id" validate:"omitempty,uuid"`
+	WatermarkText        *string `json:"watermark_text" validate:"omitempty,min=1,max=100"`
+	WatermarkFontID      *string `json:"watermark_font_id" validate:"omitempty,uuid"`
+	WatermarkPosition    *string `json:"watermark_position" validate:"omitempty,oneof=top-left top-right bottom-left bottom-right center tiled diagonal auto"`
+	WatermarkOpacity     *int    `json:"watermark_opacity" validate:"omitempty,min=0,max=100"`
+	WatermarkScale       *int    `json:"watermark_scale" validate:"omitempty,min=1,max=100"`
+	WatermarkTileSpacing *int    `json:"watermark_tile_spacing" validate:"omitempty,min=0,max=500"`
+	OutputFormat         *string `json:"output_format" validate:"omitempty,oneof=jpeg png webp"`
+	OutputQuality        *int    `json:"output_quality" validate:"omitempty,min=1,max=100"`
+}
+
+// RemoteBatchSettings are the settings of a batch whose originals are
+// fetched by the workers instead of being uploaded. Omitted settings take the
+// same defaults as uploads.
+type RemoteBatchSettings struct {
+	Name                 string  `json:"name" validate:"max=255"`
+	ExternalID           string  `json:"external_id" validate:"max=255"`
+	PresetID             *string `json:"preset_id" validate:"omitempty,uuid"`
+	WatermarkID          *string `json:"watermark_id" validate:"omitempty,uuid"`
+	WatermarkText        *string `json:"watermark_text" validate:"omitempty,min=1,max=100"`
+	WatermarkFontID      *string `json:"watermark_font_id" validate:"omitempty,uuid"`
+	WatermarkPosition    *string `json:"watermark_position" validate:"omitempty,oneof=top-left top-right bottom-left bottom-right center tiled diagonal auto"`
+	WatermarkOpacity     *int    `json:"watermark_opacity" validate:"omitempty,min=0,max=100"`
+	WatermarkScale       *int    `json:"watermark_scale" validate:"omitempty,min=1,max=100"`
+	WatermarkTileSpacing *int    `json:"watermark_tile_spacing" validate:"omitempty,min=0,max=500"`
+	OutputFormat         *string `json:"output_format" validate:"omitempty,oneof=jpeg png webp"`
+	OutputQuality        *int    `json:"output_quality" validate:"omitempty,min=1,max=100"`
+	ReportCSV            bool    `json:"report_csv"`
+	ExpiresInHours       int     `json:"expires_in_hours" validate:"omitempty,min=1,max=8760"`
+	// Deadline is when images not yet processed are skipped and marked
+	// expired; it must be in the future.
+	Deadline *time.Time `json:"deadline"`
+	// SkipDefaultWatermark leaves a batch without watermark_id or
+	// watermark_text unwatermarked instead of using the default watermark.
+	SkipDefaultWatermark bool `json:"skip_default_watermark"`
+}
+
+// CreateBatchFromURLsRequest creates a batch from originals hosted on the
+// web.
+type CreateBatchFromURLsRequest struct {
+	RemoteBatchSettings
+	Images []RemoteImage `json:"images" validate:"required,min=1,max=500,dive"`
+}
+
+// CreateBatchFromS3Request creates a batch from the images stored under
+// Prefix in an S3 bucket the server is allowed to import from.
+type CreateBatchFromS3Request struct {
+	RemoteBatchSettings
+	Bucket string `json:"bucket" validate:"required,max=63"`
+	Prefix string `json:"prefix" validate:"max=1024"`
+}
+
+// RemoteImage is an original to fetch. Filename defaults to the last
+// segment of the URL path.
+type RemoteImage struct {
+	URL        string `json:"url" validate:"required,http_url,max=2048"`
+	Filename   string `json:"filename" validate:"max=255"`
+	ExternalID string `json:"external_id" validate:"max=255"`
+}
+
+// CreateDeliveryRequest sends a completed batch to clients. Message is a
+// text/template rendered for every recipient.
+type CreateDeliveryRequest struct {
+	Recipients    []string `json:"recipients" validate:"required,min=1,max=20,dive,email,max=255"`
+	Subject       string   `json:"subject" validate:"max=255"`
+	Message       string   `json:"message" validate:"max=5000"`
+	ReplyTo       string   `json:"reply_to" validate:"omitempty,email,max=255"`
+	LogoURL       string   `json:"logo_url" validate:"omitempty,url,startswith=https://,max=2048"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=90"`
+}
+
+// DeliveryResponse is one recipient of a batch delivery and what they did
+// with it. SentAt is nil until the email is sent, and FailedAt is set once
+// it is given up.
+type DeliveryResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	BatchID          uuid.UUID  `json:"batch_id"`
+	Recipient        string     `json:"recipient"`
+	Subject          string     `json:"subject"`
+	Message          string     `json:"message"`
+	ReplyTo          string     `json:"reply_to"`
+	LogoURL          string     `json:"logo_url"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	SentAt           *time.Time `json:"sent_at"`
+	FailedAt         *time.Time `json:"failed_at"`
+	OpenCount        int        `json:"open_count"`
+	FirstOpenedAt    *time.Time `json:"first_opened_at"`
+	LastOpenedAt     *time.Time `json:"last_opened_at"`
+	DownloadCount    int        `json:"download_count"`
+	LastDownloadedAt *time.Time `json:"last_downloaded_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// DeliveryGalleryResponse is what a client sees when opening a delivery link.
+type DeliveryGalleryResponse struct {
+	BatchName string                  `json:"batch_name"`
+	Message   string                  `json:"message"`
+	ReplyTo   string                  `json:"reply_to"`
+	LogoURL   string                  `json:"logo_url"`
+	ExpiresAt time.Time               `json:"expires_at"`
+	Images    []DeliveryImageResponse `json:"images"`
+}
+
+// DeliveryImageResponse is a processed image of a delivered batch.
+type DeliveryImageResponse struct {
+	ID           uuid.UUID `json:"id"`
+	Filename     string    `json:"filename"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Width        *int      `json:"width"`
+	Height       *int      `json:"height"`
+	Blurhash     string    `json:"blurhash"`
+}
+
+// CreateBatchTransferRequest offers a batch to the user registered with Email.
+type CreateBatchTransferRequest struct {
+	Email   string `json:"email" validate:"required,email,max=255"`
+	Message string `json:"message" validate:"max=1000"`
+}
+
+// BatchTransferResponse is a batch offered from one user to another.
+// CreatedAt records the sender's consent and RespondedAt the recipient's
+// answer, or when the sender withdrew the offer.
+type BatchTransferResponse struct {
+	ID          uuid.UUID                    `json:"id"`
+	BatchID     uuid.UUID                    `json:"batch_id"`
+	BatchName   string                       `json:"batch_name"`
+	FromUserID  uuid.UUID                    `json:"from_user_id"`
+	FromEmail   string                       `json:"from_email"`
+	ToUserID    uuid.UUID                    `json:"to_user_id"`
+	ToEmail     string                       `json:"to_email"`
+	Message     string                       `json:"message"`
+	Status      database.BatchTransferStatus `json:"status"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	RespondedAt *time.Time                   `json:"responded_at"`
 }
@@ -0,0 +1,104 @@
+package batch
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedPercent(t *testing.T) {
+	tests := []struct {
+		name      string
+		original  int64
+		processed int64
+		want      float64
+	}{
+		{name: "nothing measured", original: 0, processed: 0, want: 0},
+		{name: "quarter of the size", original: 1000, processed: 250, want: 75},
+		{name: "rounded to one decimal", original: 3, processed: 2, want: 33.3},
+		{name: "half a tenth rounds up", original: 2000, processed: 1999, want: 0.1},
+		{name: "unchanged", original: 500, processed: 500, want: 0},
+		{name: "grew", original: 1000, processed: 1200, want: -20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, savedPercent(tt.original, tt.processed))
+		})
+	}
+}
+
+func TestNewBatchProgressResponse(t *testing.T) {
+	batchID := uuid.New()
+	eta := func(seconds int64) *int64 { return &seconds }
+
+	tests := []struct {
+		name             string
+		waiting          bool
+		counts           ImageStatusCounts
+		recentlyFinished int64
+		windowSeconds    float64
+		want             BatchProgressResponse
+	}{
+		{
+			name: "no images",
+			want: BatchProgressResponse{Status: BatchStatusPending},
+		},
+		{
+			name:    "waiting",
+			waiting: true,
+			counts:  ImageStatusCounts{Pending: 4},
+			want:    BatchProgressResponse{Status: BatchStatusWaiting, Total: 4},
+		},
+		{
+			name:             "nothing finished in the window",
+			counts:           ImageStatusCounts{Pending: 2, Processing: 1, Completed: 1},
+			recentlyFinished: 0,
+			windowSeconds:    300,
+			want:             BatchProgressResponse{Status: BatchStatusProcessing, Total: 4, PercentDone: 25},
+		},
+		{
+			name:             "rate and ETA",
+			counts:           ImageStatusCounts{Pending: 2, Processing: 1, Completed: 1, Failed: 1},
+			recentlyFinished: 2,
+			windowSeconds:    60,
+			want:             BatchProgressResponse{Status: BatchStatusProcessing, Total: 5, PercentDone: 40, ImagesPerMinute: 2, ETASeconds: eta(90)},
+		},
+		{
+			name:             "ETA rounds up",
+			counts:           ImageStatusCounts{Pending: 1, Completed: 2},
+			recentlyFinished: 2,
+			windowSeconds:    3,
+			want:             BatchProgressResponse{Status: BatchStatusProcessing, Total: 3, PercentDone: 66.7, ImagesPerMinute: 40, ETASeconds: eta(2)},
+		},
+		{
+			name:             "empty window",
+			counts:           ImageStatusCounts{Pending: 1, Completed: 1},
+			recentlyFinished: 1,
+			windowSeconds:    0,
+			want:             BatchProgressResponse{Status: BatchStatusProcessing, Total: 2, PercentDone: 50},
+		},
+		{
+			name:             "finished",
+			counts:           ImageStatusCounts{Completed: 2, Cancelled: 1, Expired: 1},
+			recentlyFinished: 2,
+			windowSeconds:    120,
+			want:             BatchProgressResponse{Status: BatchStatusCancelled, Total: 4, PercentDone: 100, ImagesPerMinute: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := database.Batch{ID: batchID}
+			if tt.waiting {
+				batch.WaitingSince = sql.NullTime{Time: time.Now(), Valid: true}
+			}
+			want := tt.want
+			want.BatchID = batchID
+			want.Counts = tt.counts
+			assert.Equal(t, want, newBatchProgressResponse(batch, tt.counts, tt.recentlyFinished, tt.windowSeconds))
+		})
+	}
+}
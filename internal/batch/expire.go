@@ -0,0 +1,193 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// expireBatchLimit caps the batches one pass of PollExpiredBatches or
+// PollDeletedBatches removes, so a backlog is worked off over several passes.
+const expireBatchLimit = 100
+
+// TrashRetention is how long deleted batches stay in the trash, restorable,
+// before PollDeletedBatches purges them with their files.
+const TrashRetention = 30 * 24 * time.Hour
+
+// PollExpiredBatches periodically deletes the batches of the worker's region
+// whose TTL ran out, with their images, and queues the removal of their
+// objects. Batches in the trash are removed too. Batches with images still
+// pending or processing wait for a later pass.
+func PollExpiredBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expireBatches(ctx, db, dbQueries, cfg)
+		}
+	}
+}
+
+func expireBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config) {
+	removeBatches(ctx, db, dbQueries, cfg, "expired", func(q *database.Queries) (database.Batch, error) {
+		batch, err := q.ExpireNextBatch(ctx, cfg.Region)
+		if err != nil {
+			return batch, err
+		}
+		return batch, recordEvent(ctx, q, batch, database.BatchEventTypeExpired)
+	})
+}
+
+// PollDeletedBatches periodically purges the batches of the worker's region
+// that have been in the trash for TrashRetention, with their images, and
+// queues the removal of their objects.
+func PollDeletedBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purgeBatches(ctx, db, dbQueries, cfg)
+		}
+	}
+}
+
+func purgeBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config) {
+	removeBatches(ctx, db, dbQueries, cfg, "purged", func(q *database.Queries) (database.Batch, error) {
+		return q.PurgeNextDeletedBatch(ctx, database.PurgeNextDeletedBatchParams{
+			Region:        cfg.Region,
+			DeletedBefore: sql.NullTime{Time: time.Now().UTC().Add(-TrashRetention), Valid: true},
+		})
+	})
+}
+
+// removeBatches removes up to expireBatchLimit batches picked by claim.
+func removeBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config, action string, claim func(*database.Queries) (database.Batch, error)) {
+	for range expireBatchLimit {
+		removed, err := removeNextBatch(ctx, db, dbQueries, cfg, action, claim)
+		if err != nil {
+			log.Printf("error remove %s batch: %v", action, err)
+			return
+		}
+		if !removed {
+			return
+		}
+	}
+}
+
+// removeNextBatch deletes the images of the batch claim marks as purged in
+// the same transaction, with a cleanup in the task outbox of the objects no
+// other image uses, and publishes the cleanup once committed. It reports
+// false when no batch is due.
+func removeNextBatch(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config, action string, claim func(*database.Queries) (database.Batch, error)) (bool, error) {
+	storage, err := cfg.Storage(cfg.Region)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := dbQueries.WithTx(tx)
+
+	batch, err := claim(qtx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	images, err := qtx.DeleteBatchImages(ctx, batch.ID)
+	if err != nil {
+		return false, err
+	}
+	keys, err := unreferencedObjectKeys(ctx, qtx, storage, batch, images)
+	if err != nil {
+		return false, err
+	}
+	cleanupID, err := queueCleanup(ctx, qtx, batch.Region, keys)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	log.Printf("batch %s %s, removing %d objects", batch.ID, action, len(keys))
+	publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	publishCleanup(publishCtx, dbQueries, cfg, batch.Region, cleanupID, keys)
+	return true, nil
+}
+
+// referencedObjects finds the objects of deleted images that live images or
+// batches still use.
+type referencedObjects interface {
+	GetReferencedImageKeys(ctx context.Context, keys []string) ([]string, error)
+	GetReferencedObjectURLs(ctx context.Context, urls []string) ([]string, error)
+	CountBatchesByWatermarkKey(ctx context.Context, watermarkKey sql.NullString) (int64, error)
+}
+
+// unreferencedObjectKeys lists the objects of a deleted batch, including its
+// reports, leaving out originals linked by duplicate uploads, processed files
+// shared by similar images and watermarks that other images or batches still
+// use.
+func unreferencedObjectKeys(ctx context.Context, dbQueries referencedObjects, storage utils.RegionStorage, batch database.Batch, images []database.Image) ([]string, error) {
+	originalKeys := make([]string, 0, len(images))
+	var objectURLs []string
+	for _, img := range images {
+		originalKeys = append(originalKeys, img.Key)
+		for _, url := range []sql.NullString{img.ProcessedUrl, img.ThumbnailUrl} {
+			if url.Valid {
+				objectURLs = append(objectURLs, url.String)
+			}
+		}
+	}
+	referenced, err := dbQueries.GetReferencedImageKeys(ctx, originalKeys)
+	if err != nil {
+		return nil, err
+	}
+	referencedURLs, err := dbQueries.GetReferencedObjectURLs(ctx, objectURLs)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range referencedURLs {
+		referenced = append(referenced, utils.GetObjectKey(storage.S3CfDistribution, url))
+	}
+	// Reprocessed batches share the uploaded watermark of their source.
+	if batch.WatermarkKey.String != "" {
+		users, err := dbQueries.CountBatchesByWatermarkKey(ctx, batch.WatermarkKey)
+		if err != nil {
+			return nil, err
+		}
+		if users > 0 {
+			referenced = append(referenced, batch.WatermarkKey.String)
+		}
+	}
+
+	var keys []string
+	for _, key := range batchObjectKeys(storage, batch, images) {
+		if !slices.Contains(referenced, key) && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	for _, url := range []sql.NullString{batch.ReportUrl, batch.ReportCsvUrl} {
+		if key := utils.GetObjectKey(storage.S3CfDistribution, url.String); url.Valid && key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,97 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReferencedObjects reports the listed keys and URLs as used by live
+// images.
+type fakeReferencedObjects struct {
+	keys             []string
+	urls             []string
+	watermarkBatches int64
+}
+
+func (f fakeReferencedObjects) GetReferencedImageKeys(_ context.Context, keys []string) ([]string, error) {
+	var referenced []string
+	for _, key := range keys {
+		if slices.Contains(f.keys, key) {
+			referenced = append(referenced, key)
+		}
+	}
+	return referenced, nil
+}
+
+func (f fakeReferencedObjects) GetReferencedObjectURLs(_ context.Context, urls []string) ([]string, error) {
+	var referenced []string
+	for _, url := range urls {
+		if slices.Contains(f.urls, url) {
+			referenced = append(referenced, url)
+		}
+	}
+	return referenced, nil
+}
+
+func (f fakeReferencedObjects) CountBatchesByWatermarkKey(context.Context, sql.NullString) (int64, error) {
+	return f.watermarkBatches, nil
+}
+
+func TestUnreferencedObjectKeys(t *testing.T) {
+	storage := utils.RegionStorage{S3CfDistribution: "cdn.example.com"}
+	batch := database.Batch{
+		ID:           uuid.New(),
+		WatermarkKey: sql.NullString{String: "watermarks/w.png", Valid: true},
+		ReportUrl:    sql.NullString{String: "https://cdn.example.com/reports/r.pdf", Valid: true},
+		ReportCsvUrl: sql.NullString{String: "https://cdn.example.com/reports/r.csv", Valid: true},
+	}
+	images := []database.Image{
+		{
+			Key:          "originals/a.jpg",
+			ProcessedUrl: sql.NullString{String: "https://cdn.example.com/processed/a.jpg", Valid: true},
+			ThumbnailUrl: sql.NullString{String: "https://cdn.example.com/thumbnails/a.jpg", Valid: true},
+		},
+		// A duplicate linked within the batch lists its original twice.
+		{Key: "originals/a.jpg"},
+		{Key: "originals/b.jpg"},
+	}
+
+	tests := []struct {
+		name       string
+		referenced fakeReferencedObjects
+		want       []string
+	}{
+		{
+			name: "nothing referenced",
+			want: []string{"originals/a.jpg", "processed/a.jpg", "thumbnails/a.jpg", "originals/b.jpg", "watermarks/w.png", "reports/r.pdf", "reports/r.csv"},
+		},
+		{
+			name: "linked original and similar image files",
+			referenced: fakeReferencedObjects{
+				keys: []string{"originals/b.jpg"},
+				urls: []string{"https://cdn.example.com/processed/a.jpg"},
+			},
+			want: []string{"originals/a.jpg", "thumbnails/a.jpg", "watermarks/w.png", "reports/r.pdf", "reports/r.csv"},
+		},
+		{
+			name:       "watermark of a reprocessed batch",
+			referenced: fakeReferencedObjects{watermarkBatches: 1},
+			want:       []string{"originals/a.jpg", "processed/a.jpg", "thumbnails/a.jpg", "originals/b.jpg", "reports/r.pdf", "reports/r.csv"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := unreferencedObjectKeys(context.Background(), tt.referenced, storage, batch, images)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, keys)
+		})
+	}
+}
@@ -1,11 +1,22 @@
 package batch
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"mime"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -13,19 +24,23 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/middleware"
 	"github.com/rickyroynardson/image-go/internal/pubsub"
 	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/rickyroynardson/image-go/internal/watermark"
 )
 
 type BatchHandler struct {
 	validator *validator.Validate
+	db        *sql.DB
 	dbQueries *database.Queries
 	config    *utils.Config
 }
 
-func NewHandler(validator *validator.Validate, dbQueries *database.Queries, config *utils.Config) *BatchHandler {
+func NewHandler(validator *validator.Validate, db *sql.DB, dbQueries *database.Queries, config *utils.Config) *BatchHandler {
 	return &BatchHandler{
 		validator: validator,
+		db:        db,
 		dbQueries: dbQueries,
 		config:    config,
 	}
@@ -37,21 +52,113 @@ func NewHandler(validator *validator.Validate, dbQueries *database.Queries, conf
 // @Tags batches
 // @Produce json
 // @Security BearerAuth
+// @Param external_id query string false "Only the batch created with this external ID"
+// @Param status query string false "Batch status" Enums(waiting, pending, processing, completed, failed, cancelled, expired)
+// @Param sort query string false "Sort order, descending with a leading -" Enums(created_at, -created_at, name, -name, image_count, -image_count) default(-created_at)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
 // @Success 200 {object} utils.SuccessResponse{data=[]BatchesResponse}
+// @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /batches [get]
 func (h *BatchHandler) GetAll(c echo.Context) error {
+	return h.listBatches(c, sql.NullString{})
+}
+
+// minSearchLength is the shortest q of a batch search. Trigram indexes
+// cannot narrow down shorter patterns, which would scan every image key.
+const minSearchLength = 3
+
+// Search godoc
+// @Summary Search batches
+// @Description Find the authenticated user's batches whose name or the key of one of their images contains q, case insensitive, e.g. spring-catalog. Results are paged, filtered and sorted like GET /batches
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Text to look for in batch names and image keys (3 to 255 characters, % and _ match themselves)"
+// @Param external_id query string false "Only the batch created with this external ID"
+// @Param status query string false "Batch status" Enums(waiting, pending, processing, completed, failed, cancelled, expired)
+// @Param sort query string false "Sort order, descending with a leading -" Enums(created_at, -created_at, name, -name, image_count, -image_count) default(-created_at)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} utils.SuccessResponse{data=[]BatchesResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/search [get]
+func (h *BatchHandler) Search(c echo.Context) error {
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return utils.RespondError(c, http.StatusBadRequest, "q is required")
+	}
+	if n := utf8.RuneCountInString(q); n < minSearchLength || n > 255 {
+		return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("q must be %d to 255 characters", minSearchLength))
+	}
+	return h.listBatches(c, sql.NullString{String: utils.EscapeLike(q), Valid: true})
+}
+
+// listBatches responds with a page of the user's batches, only those
+// matching q when it is set.
+func (h *BatchHandler) listBatches(c echo.Context, q sql.NullString) error {
 	userID := c.Get("userID").(uuid.UUID)
 
-	batches, err := h.dbQueries.GetAllUserBatches(c.Request().Context(), userID)
+	page, limit, err := utils.GetPagination(c)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	params := database.GetAllUserBatchesParams{
+		UserID:     userID,
+		Q:          q,
+		Sort:       "-created_at",
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	}
+	if externalID := c.QueryParam("external_id"); externalID != "" {
+		params.ExternalID = sql.NullString{String: externalID, Valid: true}
+	}
+	if status := c.QueryParam("status"); status != "" {
+		switch status {
+		case BatchStatusWaiting, BatchStatusPending, BatchStatusProcessing, BatchStatusCompleted, BatchStatusFailed, BatchStatusCancelled, BatchStatusExpired:
+		default:
+			return utils.RespondError(c, http.StatusBadRequest, "invalid status")
+		}
+		params.Status = sql.NullString{String: status, Valid: true}
+	}
+	if sort := c.QueryParam("sort"); sort != "" {
+		if !slices.Contains(batchSorts, sort) {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid sort")
+		}
+		params.Sort = sort
+	}
+
+	batches, err := h.dbQueries.GetAllUserBatches(c.Request().Context(), params)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	total, err := h.dbQueries.CountUserBatches(c.Request().Context(), database.CountUserBatchesParams{
+		UserID:     params.UserID,
+		ExternalID: params.ExternalID,
+		Q:          params.Q,
+		Status:     params.Status,
+	})
 	if err != nil {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 	}
 
 	batchesRes := make([]BatchesResponse, len(batches))
 	for i, b := range batches {
-		var watermarkKey, watermarkURL string
+		// Transforms are validated before they are stored.
+		transforms, _ := DecodeTransforms(b.Transforms)
+		pipeline, _ := DecodePipeline(b.Pipeline)
+		var watermarkKey, watermarkURL, watermarkID, watermarkFontID string
+		if b.WatermarkID.Valid {
+			watermarkID = b.WatermarkID.UUID.String()
+		}
+		if b.WatermarkFontID.Valid {
+			watermarkFontID = b.WatermarkFontID.UUID.String()
+		}
 		if b.WatermarkKey.Valid {
 			watermarkKey = b.WatermarkKey.String
 		}
@@ -61,9 +168,37 @@ func (h *BatchHandler) GetAll(c echo.Context) error {
 		batchesRes[i] = BatchesResponse{
 			ID:                   b.ID.String(),
 			UserID:               b.UserID.String(),
+			ExternalID:           b.ExternalID.String,
 			Name:                 b.Name.String,
 			WatermarkKey:         watermarkKey,
 			WatermarkURL:         watermarkURL,
+			WatermarkID:          watermarkID,
+			WatermarkText:        b.WatermarkText.String,
+			WatermarkFontID:      watermarkFontID,
+			WatermarkPosition:    string(b.WatermarkPosition),
+			WatermarkOpacity:     int(b.WatermarkOpacity),
+			WatermarkScale:       int(b.WatermarkScale),
+			WatermarkTileSpacing: int(b.WatermarkTileSpacing),
+			MaxConcurrency:       int(b.MaxConcurrency),
+			MaxWidth:             nullableInt(b.MaxWidth),
+			MaxHeight:            nullableInt(b.MaxHeight),
+			OutputFormat:         string(b.OutputFormat),
+			OutputQuality:        int(b.OutputQuality),
+			JpegProgressive:      b.JpegProgressive,
+			JpegSubsampling:      string(b.JpegSubsampling),
+			PngCompression:       string(b.PngCompression),
+			SimilarDedupe:        string(b.SimilarDedupe),
+			Crop:                 newCrop(b.CropAspectRatio, b.CropX, b.CropY, b.CropWidth, b.CropHeight),
+			Transforms:           transforms,
+			Pipeline:             pipeline,
+			Status:               b.Status,
+			ArchiveStatus:        string(b.ArchiveStatus),
+			PreserveFilenames:    b.PreserveFilenames,
+			PreserveMetadata:     b.PreserveMetadata,
+			InvisibleWatermark:   b.InvisibleWatermark,
+			CollisionPolicy:      string(b.CollisionPolicy),
+			ExpiresAt:            nullableTime(b.ExpiresAt),
+			Deadline:             nullableTime(b.Deadline),
 			CreatedAt:            b.CreatedAt,
 			UpdatedAt:            b.UpdatedAt,
 			ImageCount:           int(b.ImageCount),
@@ -71,19 +206,24 @@ func (h *BatchHandler) GetAll(c echo.Context) error {
 			ImageProcessingCount: int(b.ImageProcessingCount),
 			ImageCompletedCount:  int(b.ImageCompletedCount),
 			ImageFailedCount:     int(b.ImageFailedCount),
+			ImageCancelledCount:  int(b.ImageCancelledCount),
+			ImageExpiredCount:    int(b.ImageExpiredCount),
 		}
 	}
 
-	return utils.RespondJSON(c, http.StatusOK, "batches retrieved successfully", batchesRes)
+	return utils.RespondPaginated(c, http.StatusOK, "batches retrieved successfully", batchesRes, utils.NewPaginationMeta(page, limit, int(total)))
 }
 
 // GetByID godoc
 // @Summary Get batch by ID
-// @Description Retrieve a specific batch by its ID for the authenticated user
+// @Description Retrieve a specific batch by its ID for the authenticated user. Its images can be ordered and filtered by when they were taken; the status and counts of the batch always cover all of them
 // @Tags batches
 // @Produce json
 // @Security BearerAuth
 // @Param batchID path string true "Batch ID"
+// @Param sort query string false "Image order, descending with a leading -; images without a capture time come last" Enums(created_at, -created_at, captured_at, -captured_at) default(created_at)
+// @Param captured_from query string false "Only images taken at or after, by the camera's clock (RFC3339, the offset is ignored)"
+// @Param captured_to query string false "Only images taken at or before, by the camera's clock (RFC3339, the offset is ignored)"
 // @Success 200 {object} utils.SuccessResponse{data=BatchResponse}
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
@@ -91,13 +231,8 @@ func (h *BatchHandler) GetAll(c echo.Context) error {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /batches/{batchID} [get]
 func (h *BatchHandler) GetByID(c echo.Context) error {
-	batchID := c.Param("batchID")
 	userID := c.Get("userID").(uuid.UUID)
-
-	batchUUID, err := uuid.Parse(batchID)
-	if err != nil {
-		return utils.RespondError(c, http.StatusBadRequest, "invalid batch ID")
-	}
+	batchUUID := utils.ParamUUID(c, "batchID")
 
 	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
 		ID:     batchUUID,
@@ -110,62 +245,237 @@ func (h *BatchHandler) GetByID(c echo.Context) error {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 	}
 
+	sort := c.QueryParam("sort")
+	if sort != "" && !slices.Contains(ImageSorts, sort) {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid sort")
+	}
+	var capturedFrom, capturedTo sql.NullTime
+	if from := c.QueryParam("captured_from"); from != "" {
+		capturedFrom, err = ParseCaptureTime(from)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid captured_from, must be RFC3339")
+		}
+	}
+	if to := c.QueryParam("captured_to"); to != "" {
+		capturedTo, err = ParseCaptureTime(to)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid captured_to, must be RFC3339")
+		}
+	}
+
 	images, err := h.dbQueries.GetImagesByBatchID(c.Request().Context(), batch.ID)
 	if err != nil {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 	}
-	imagesRes := make([]ImageResponse, len(images))
-	for i, img := range images {
-		imagesRes[i] = ImageResponse{
-			ID:           img.ID,
-			BatchID:      img.BatchID,
-			Key:          img.Key,
-			OriginalURL:  img.OriginalUrl,
-			ProcessedURL: img.ProcessedUrl.String,
-			Status:       img.Status,
-			CreatedAt:    img.CreatedAt,
-			UpdatedAt:    img.UpdatedAt,
-		}
-	}
+	res := newBatchResponse(batch, images)
+	res.Images = selectImages(res.Images, sort, capturedFrom, capturedTo)
+	return utils.RespondJSON(c, http.StatusOK, "batch retrieved successfully", res)
+}
 
-	res := BatchResponse{
-		ID:           batch.ID,
-		UserID:       batch.UserID,
-		Name:         batch.Name.String,
-		WatermarkKey: batch.WatermarkKey.String,
-		WatermarkURL: batch.WatermarkUrl.String,
-		CreatedAt:    batch.CreatedAt,
-		UpdatedAt:    batch.UpdatedAt,
-		Images:       imagesRes,
+// ParseCaptureTime parses an RFC3339 bound on capture times. Cameras record
+// them without a time zone, so the offset is dropped and the wall clock kept.
+func ParseCaptureTime(value string) (sql.NullTime, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
 	}
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return sql.NullTime{Time: wall, Valid: true}, nil
+}
 
-	return utils.RespondJSON(c, http.StatusOK, "batch retrieved successfully", res)
+// selectImages keeps the images taken between from and to, when set, in the
+// order of sort, one of ImageSorts. Images come in upload order.
+func selectImages(images []ImageResponse, sort string, from, to sql.NullTime) []ImageResponse {
+	if from.Valid || to.Valid {
+		images = slices.DeleteFunc(images, func(img ImageResponse) bool {
+			return img.CapturedAt == nil || (from.Valid && img.CapturedAt.Before(from.Time)) || (to.Valid && img.CapturedAt.After(to.Time))
+		})
+	}
+	switch sort {
+	case "-created_at":
+		slices.Reverse(images)
+	case "captured_at", "-captured_at":
+		slices.SortStableFunc(images, func(a, b ImageResponse) int {
+			switch {
+			case a.CapturedAt == nil && b.CapturedAt == nil:
+				return 0
+			case a.CapturedAt == nil:
+				return 1
+			case b.CapturedAt == nil:
+				return -1
+			case sort == "-captured_at":
+				return b.CapturedAt.Compare(*a.CapturedAt)
+			}
+			return a.CapturedAt.Compare(*b.CapturedAt)
+		})
+	}
+	return images
 }
 
 // Create godoc
 // @Summary Create batch
-// @Description Create a new batch with images and optional watermark
+// @Description Create a new batch with images and optional watermark. Files are checked by their content, not the Content-Type they are sent with: files that are not JPEG, PNG, WebP or GIF, whose Content-Type names another type, or whose image header is corrupt are left out and listed under rejected. Files that could not be stored are listed there too, marked retryable. The images created are listed under images with their image_id and key, so only the rejected files need to be sent again. When no file is left, the request fails with one error per file, its field being the filename: with 400, or 503 when a file could not be stored.
 // @Tags batches
 // @Accept multipart/form-data
 // @Produce json
 // @Security BearerAuth
 // @Param name formData string false "Batch name"
-// @Param files formData file true "Image files (multiple)"
-// @Param watermark formData file false "Watermark image file"
-// @Success 201 {object} utils.SuccessResponse{data=nil}
+// @Param preset_id formData string false "ID of one of your presets; its settings apply wherever this request leaves them out"
+// @Param files formData file true "Image files (multiple): JPEG, PNG, WebP or GIF"
+// @Param watermark formData file false "Watermark image file, at most 4096 pixels per side"
+// @Param watermark_id formData string false "ID of a watermark from your library, used instead of uploading a watermark image"
+// @Param skip_default_watermark formData bool false "Leave the batch unwatermarked when no watermark is given, instead of using your default watermark"
+// @Param watermark_text formData string false "Text watermark (max 100 characters), used instead of a watermark image"
+// @Param watermark_font_id formData string false "ID of an uploaded font for the text watermark, defaults to Go Regular"
+// @Param watermark_position formData string false "Watermark position" Enums(top-left, top-right, bottom-left, bottom-right, center, tiled, diagonal, auto) default(bottom-right)
+// @Param watermark_opacity formData int false "Watermark opacity in percent (0-100)" default(50)
+// @Param watermark_scale formData int false "Watermark width in percent of the image width (1-100)" default(15)
+// @Param watermark_tile_spacing formData int false "Gap between tiled or diagonal watermarks in percent of the watermark size (0-500)" default(50)
+// @Param max_concurrency formData int false "Maximum images of this batch processed at the same time (1-1000)" default(10)
+// @Param max_width formData int false "Downscale images wider than this many pixels, keeping the aspect ratio (1-10000)"
+// @Param max_height formData int false "Downscale images taller than this many pixels, keeping the aspect ratio (1-10000)"
+// @Param output_format formData string false "Output format" Enums(jpeg, png, webp) default(jpeg)
+// @Param output_quality formData int false "JPEG output quality (1-100)" default(50)
+// @Param jpeg_progressive formData bool false "Encode JPEG output progressively, so it shows in full at low detail while loading"
+// @Param jpeg_subsampling formData string false "JPEG chroma subsampling; 444 keeps full color resolution" Enums(444, 422, 420) default(420)
+// @Param png_compression formData string false "PNG compression level; best gives smaller files but encodes slower" Enums(default, none, fast, best) default(default)
+// @Param preserve_filenames formData bool false "Name processed files after the uploaded filenames"
+// @Param preserve_metadata formData bool false "Keep the original EXIF metadata, including GPS location, in JPEG output"
+// @Param invisible_watermark formData bool false "Hide the batch and user ID in the pixels of still images, in addition to any visible watermark, for POST /images/verify"
+// @Param report_csv formData bool false "Write a CSV copy of the processing report next to the JSON one"
+// @Param expires_in_hours formData int false "Delete the batch with its images and files this many hours after creation (1-8760); kept until deleted when omitted"
+// @Param deadline formData string false "RFC 3339 time the batch must be finished by, e.g. 2026-10-15T18:00:00+07:00; images not processed by then are skipped and marked expired, and the report and batch.completed webhook follow right away"
+// @Param collision_policy formData string false "What to do when a preserved filename is taken: overwrite, suffix (default) or error"
+// @Param external_id formData string false "Your own reference for the batch, unique among your batches (max 255 characters)"
+// @Param manifest formData string false "JSON array overriding the batch watermark per file, setting its external_id, unique among your images, and listing up to 100 regions to blur or pixelate in pixels of the upright original, e.g. [{\"filename\":\"portrait.jpg\",\"external_id\":\"sku-123\",\"watermark_position\":\"bottom-left\",\"watermark_opacity\":30,\"watermark_scale\":25,\"redactions\":[{\"type\":\"blur\",\"x\":120,\"y\":80,\"width\":200,\"height\":60}]}]; omitted fields use the batch settings"
+// @Param dedupe_policy formData string false "What to do with files already uploaded by the user: allow (default) uploads them again, link reuses the stored original, skip leaves them out" Enums(allow, link, skip) default(allow)
+// @Param crop_aspect_ratio formData string false "Center-crop uploads to this width:height before watermarking, e.g. 1:1, 4:5 or 16:9"
+// @Param crop_rect formData string false "Crop uploads to this pixel rectangle of the upright original before watermarking, as x,y,width,height; the part outside the image is ignored"
+// @Param transforms formData string false "JSON array of up to 10 steps applied in order after watermarking: border adds a frame of size pixels, pad extends the canvas to aspect_ratio, letterbox fits the image into width x height (up to 5000 each); added canvas is filled with color (#rrggbb or #rrggbbaa, default white), e.g. [{\"type\":\"letterbox\",\"width\":1200,\"height\":1200,\"color\":\"#f5f5f5\"},{\"type\":\"border\",\"size\":8,\"color\":\"#000000\"}]"
+// @Param pipeline formData string false "JSON array of the steps every image goes through after redactions and the crop, in order: resize (to width and height, or the batch's max_width and max_height), watermark (exactly once), sharpen (amount 1-500 percent, default 100) and encode (last); defaults to [{\"type\":\"resize\"},{\"type\":\"watermark\"},{\"type\":\"encode\"}]"
+// @Param similar_dedupe formData string false "Skip processing images that look the same as an already processed one, by perceptual hash, and share its processed files: off (default), batch matches within this batch, account also matches batches with identical settings" Enums(off, batch, account) default(off)
+// @Success 201 {object} utils.SuccessResponse{data=CreateBatchResponse}
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 422 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
 // @Router /batches [post]
 func (h *BatchHandler) Create(c echo.Context) error {
 	name := c.FormValue("name")
 	userID := c.Get("userID").(uuid.UUID)
 
-	ch, err := h.config.RabbitMQConn.Channel()
+	externalID := c.FormValue("external_id")
+	if utf8.RuneCountInString(externalID) > 255 {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid external_id")
+	}
+
+	var preserveFilenames bool
+	if v := c.FormValue("preserve_filenames"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid preserve_filenames")
+		}
+		preserveFilenames = b
+	}
+	var preserveMetadata bool
+	if v := c.FormValue("preserve_metadata"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid preserve_metadata")
+		}
+		preserveMetadata = b
+	}
+	var invisibleWatermark bool
+	if v := c.FormValue("invisible_watermark"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid invisible_watermark")
+		}
+		invisibleWatermark = b
+	}
+	var skipDefaultWatermark bool
+	if v := c.FormValue("skip_default_watermark"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid skip_default_watermark")
+		}
+		skipDefaultWatermark = b
+	}
+	var reportCSV bool
+	if v := c.FormValue("report_csv"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid report_csv")
+		}
+		reportCSV = b
+	}
+	var expiresAt sql.NullTime
+	if v := c.FormValue("expires_in_hours"); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours < 1 || hours > 8760 {
+			return utils.RespondError(c, http.StatusBadRequest, "expires_in_hours must be between 1 and 8760")
+		}
+		expiresAt = sql.NullTime{Time: time.Now().UTC().Add(time.Duration(hours) * time.Hour), Valid: true}
+	}
+	var deadline sql.NullTime
+	if v := c.FormValue("deadline"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil || !t.After(time.Now()) {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid deadline, must be a future RFC3339 time")
+		}
+		deadline = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	collisionPolicy := database.OutputCollisionPolicySuffix
+	if v := c.FormValue("collision_policy"); v != "" {
+		collisionPolicy = database.OutputCollisionPolicy(v)
+		if !collisionPolicy.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid collision_policy")
+		}
+	}
+	dedupePolicy := DedupePolicyAllow
+	if v := c.FormValue("dedupe_policy"); v != "" {
+		dedupePolicy = DedupePolicy(v)
+		if dedupePolicy != DedupePolicyAllow && dedupePolicy != DedupePolicyLink && dedupePolicy != DedupePolicySkip {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid dedupe_policy")
+		}
+	}
+
+	// Everything the batch stores stays in the user's data region.
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
 	if err != nil {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 	}
-	defer ch.Close()
+	storage, err := h.config.Storage(user.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to create batch: %v", err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+	var preset database.BatchPreset
+	if v := c.FormValue("preset_id"); v != "" {
+		preset, err = userPreset(c.Request().Context(), h.dbQueries, userID, v)
+		if err != nil {
+			return respondSettingsError(c, err)
+		}
+	}
+
+	ch, err := h.config.RabbitMQ.Get()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	defer h.config.RabbitMQ.Put(ch)
+
+	if h.config.QueueMaxBacklog > 0 {
+		backlog, err := pubsub.QueueLength(ch, utils.TaskQueue(user.Region), pubsub.QueueTypeDurable)
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if backlog >= h.config.QueueMaxBacklog {
+			c.Response().Header().Set("Retry-After", "60")
+			return utils.RespondError(c, http.StatusServiceUnavailable, "processing queue is full, please try again later")
+		}
+	}
 
 	const maxMemory = 10 << 20
 	c.Request().ParseMultipartForm(int64(maxMemory))
@@ -182,25 +492,333 @@ func (h *BatchHandler) Create(c echo.Context) error {
 		return utils.RespondError(c, http.StatusBadRequest, "only one watermark file allowed")
 	}
 
+	overrides := make(map[string]WatermarkOverride)
+	externalIDs := make(map[string]string)
+	redactions := make(map[string]json.RawMessage)
+	if v := c.FormValue("manifest"); v != "" {
+		var manifest []ManifestEntry
+		if err := json.Unmarshal([]byte(v), &manifest); err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid manifest")
+		}
+		usedExternalIDs := make(map[string]bool)
+		uploadedNames := make(map[string]int, len(files))
+		for _, file := range files {
+			uploadedNames[file.Filename]++
+		}
+		for _, entry := range manifest {
+			if err := h.validator.Struct(entry); err != nil {
+				return utils.RespondError(c, http.StatusBadRequest, err.Error())
+			}
+			if entry.Position != nil && !entry.Position.Valid() {
+				return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("invalid watermark_position for %s in manifest", entry.Filename))
+			}
+			if uploadedNames[entry.Filename] == 0 {
+				return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("manifest names %s, which was not uploaded", entry.Filename))
+			}
+			if _, ok := overrides[entry.Filename]; ok {
+				return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("manifest names %s more than once", entry.Filename))
+			}
+			overrides[entry.Filename] = entry.WatermarkOverride
+			if len(entry.Redactions) > 0 {
+				raw, err := json.Marshal(entry.Redactions)
+				if err != nil {
+					return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+				}
+				redactions[entry.Filename] = raw
+			}
+			if entry.ExternalID != "" {
+				if usedExternalIDs[entry.ExternalID] {
+					return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("manifest uses external_id %s more than once", entry.ExternalID))
+				}
+				if uploadedNames[entry.Filename] > 1 {
+					return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("manifest gives %s an external_id, but more than one file has that name", entry.Filename))
+				}
+				externalIDs[entry.Filename] = entry.ExternalID
+				usedExternalIDs[entry.ExternalID] = true
+			}
+		}
+	}
+
+	watermarkText := c.FormValue("watermark_text")
+	if watermarkText != "" && len(watermarks) == 1 {
+		return utils.RespondError(c, http.StatusBadRequest, "use either a watermark image or watermark_text, not both")
+	}
+	if utf8.RuneCountInString(watermarkText) > 100 {
+		return utils.RespondError(c, http.StatusBadRequest, "watermark_text must be at most 100 characters")
+	}
+	// The preset replaces the defaults, and the form replaces both.
+	defaults := database.CreateBatchParams{
+		WatermarkPosition: database.WatermarkPositionBottomRight,
+		WatermarkOpacity:  50,
+		WatermarkScale:    15,
+		OutputFormat:      database.OutputFormatJpeg,
+		OutputQuality:     50,
+	}
+	applyPreset(&defaults, preset)
+	watermarkPosition := defaults.WatermarkPosition
+	if v := c.FormValue("watermark_position"); v != "" {
+		watermarkPosition = database.WatermarkPosition(v)
+		if !watermarkPosition.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid watermark_position")
+		}
+	}
+	watermarkOpacity := int(defaults.WatermarkOpacity)
+	if v := c.FormValue("watermark_opacity"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 || o > 100 {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark_opacity must be between 0 and 100")
+		}
+		watermarkOpacity = o
+	}
+	watermarkScale := int(defaults.WatermarkScale)
+	if v := c.FormValue("watermark_scale"); v != "" {
+		sc, err := strconv.Atoi(v)
+		if err != nil || sc < 1 || sc > 100 {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark_scale must be between 1 and 100")
+		}
+		watermarkScale = sc
+	}
+	watermarkTileSpacing := 50
+	if v := c.FormValue("watermark_tile_spacing"); v != "" {
+		ts, err := strconv.Atoi(v)
+		if err != nil || ts < 0 || ts > 500 {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark_tile_spacing must be between 0 and 500")
+		}
+		watermarkTileSpacing = ts
+	}
+	maxConcurrency := 10
+	if v := c.FormValue("max_concurrency"); v != "" {
+		mc, err := strconv.Atoi(v)
+		if err != nil || mc < 1 || mc > 1000 {
+			return utils.RespondError(c, http.StatusBadRequest, "max_concurrency must be between 1 and 1000")
+		}
+		maxConcurrency = mc
+	}
+	maxWidth := defaults.MaxWidth
+	if v := c.FormValue("max_width"); v != "" {
+		mw, err := strconv.Atoi(v)
+		if err != nil || mw < 1 || mw > 10000 {
+			return utils.RespondError(c, http.StatusBadRequest, "max_width must be between 1 and 10000")
+		}
+		maxWidth = sql.NullInt32{Int32: int32(mw), Valid: true}
+	}
+	maxHeight := defaults.MaxHeight
+	if v := c.FormValue("max_height"); v != "" {
+		mh, err := strconv.Atoi(v)
+		if err != nil || mh < 1 || mh > 10000 {
+			return utils.RespondError(c, http.StatusBadRequest, "max_height must be between 1 and 10000")
+		}
+		maxHeight = sql.NullInt32{Int32: int32(mh), Valid: true}
+	}
+	outputFormat := defaults.OutputFormat
+	if v := c.FormValue("output_format"); v != "" {
+		outputFormat = database.OutputFormat(v)
+		if !outputFormat.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid output_format")
+		}
+	}
+	outputQuality := int(defaults.OutputQuality)
+	if v := c.FormValue("output_quality"); v != "" {
+		q, err := strconv.Atoi(v)
+		if err != nil || q < 1 || q > 100 {
+			return utils.RespondError(c, http.StatusBadRequest, "output_quality must be between 1 and 100")
+		}
+		outputQuality = q
+	}
+	var jpegProgressive bool
+	if v := c.FormValue("jpeg_progressive"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid jpeg_progressive")
+		}
+		jpegProgressive = b
+	}
+	jpegSubsampling := database.ChromaSubsampling420
+	if v := c.FormValue("jpeg_subsampling"); v != "" {
+		jpegSubsampling = database.ChromaSubsampling(v)
+		if !jpegSubsampling.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid jpeg_subsampling")
+		}
+	}
+	pngCompression := database.PngCompressionDefault
+	if v := c.FormValue("png_compression"); v != "" {
+		pngCompression = database.PngCompression(v)
+		if !pngCompression.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid png_compression")
+		}
+	}
+	cropAspectRatio := c.FormValue("crop_aspect_ratio")
+	if _, _, ok := ParseAspectRatio(cropAspectRatio); cropAspectRatio != "" && !ok {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid crop_aspect_ratio")
+	}
+	var cropX, cropY, cropWidth, cropHeight sql.NullInt32
+	if v := c.FormValue("crop_rect"); v != "" {
+		if cropAspectRatio != "" {
+			return utils.RespondError(c, http.StatusBadRequest, "crop_rect and crop_aspect_ratio cannot be combined")
+		}
+		rect, ok := parseCropRect(v)
+		if !ok {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid crop_rect")
+		}
+		cropX = sql.NullInt32{Int32: int32(rect.X), Valid: true}
+		cropY = sql.NullInt32{Int32: int32(rect.Y), Valid: true}
+		cropWidth = sql.NullInt32{Int32: int32(rect.Width), Valid: true}
+		cropHeight = sql.NullInt32{Int32: int32(rect.Height), Valid: true}
+	}
+	transforms := json.RawMessage("[]")
+	if v := c.FormValue("transforms"); v != "" {
+		var steps []Transform
+		if err := json.Unmarshal([]byte(v), &steps); err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid transforms")
+		}
+		if len(steps) > maxTransforms {
+			return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("at most %d transforms allowed", maxTransforms))
+		}
+		for _, step := range steps {
+			if err := h.validator.Struct(step); err != nil {
+				return utils.RespondError(c, http.StatusBadRequest, err.Error())
+			}
+			if _, _, ok := step.Ratio(); step.Type == TransformPad && !ok {
+				return utils.RespondError(c, http.StatusBadRequest, "invalid aspect_ratio in transforms")
+			}
+		}
+		transforms, _ = json.Marshal(steps)
+	}
+	pipeline := json.RawMessage("[]")
+	if v := c.FormValue("pipeline"); v != "" {
+		var steps []PipelineStep
+		if err := json.Unmarshal([]byte(v), &steps); err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid pipeline")
+		}
+		for _, step := range steps {
+			if err := h.validator.Struct(step); err != nil {
+				return utils.RespondError(c, http.StatusBadRequest, err.Error())
+			}
+		}
+		if err := ValidatePipeline(steps); err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, err.Error())
+		}
+		pipeline, _ = json.Marshal(steps)
+	}
+	similarDedupe := database.SimilarDedupeOff
+	if v := c.FormValue("similar_dedupe"); v != "" {
+		similarDedupe = database.SimilarDedupe(v)
+		if !similarDedupe.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid similar_dedupe")
+		}
+	}
 	var watermarkURL string
+	var libraryWatermarkID uuid.NullUUID
+	if v := c.FormValue("watermark_id"); v != "" {
+		if len(watermarks) == 1 || watermarkText != "" {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark_id cannot be combined with a watermark image or watermark_text")
+		}
+		watermarkUUID, err := uuid.Parse(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid watermark_id")
+		}
+		libraryWatermark, err := h.dbQueries.GetUserWatermarkByID(c.Request().Context(), database.GetUserWatermarkByIDParams{
+			ID:     watermarkUUID,
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.RespondError(c, http.StatusBadRequest, "watermark not found")
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if libraryWatermark.Region != user.Region {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark is stored in another data region")
+		}
+		libraryWatermarkID = uuid.NullUUID{UUID: libraryWatermark.ID, Valid: true}
+		watermarkURL = libraryWatermark.Url
+	}
+	if len(watermarks) == 0 && watermarkText == "" && !libraryWatermarkID.Valid {
+		fromPreset, ok, err := presetWatermark(c.Request().Context(), h.dbQueries, preset, user.Region)
+		if err != nil {
+			return respondSettingsError(c, err)
+		}
+		if ok {
+			libraryWatermarkID = uuid.NullUUID{UUID: fromPreset.ID, Valid: true}
+			watermarkURL = fromPreset.Url
+		}
+	}
+	if len(watermarks) == 0 && watermarkText == "" && !libraryWatermarkID.Valid && !skipDefaultWatermark {
+		defaultWatermark, ok, err := userDefaultWatermark(c.Request().Context(), h.dbQueries, userID)
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if ok {
+			if defaultWatermark.Region != user.Region {
+				return utils.RespondError(c, http.StatusConflict, "default watermark is stored in another data region")
+			}
+			libraryWatermarkID = uuid.NullUUID{UUID: defaultWatermark.ID, Valid: true}
+			watermarkURL = defaultWatermark.Url
+		}
+	}
+	var watermarkFontID uuid.NullUUID
+	if v := c.FormValue("watermark_font_id"); v != "" {
+		if watermarkText == "" {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark_font_id requires watermark_text")
+		}
+		fontUUID, err := uuid.Parse(v)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid watermark_font_id")
+		}
+		font, err := h.dbQueries.GetUserFontByID(c.Request().Context(), database.GetUserFontByIDParams{
+			ID:     fontUUID,
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.RespondError(c, http.StatusBadRequest, "font not found")
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if font.Region != user.Region {
+			return utils.RespondError(c, http.StatusBadRequest, "font is stored in another data region")
+		}
+		watermarkFontID = uuid.NullUUID{UUID: fontUUID, Valid: true}
+	}
+
+	// Files are stored before the transaction opens, so slow uploads do not
+	// hold its locks. The objects are removed again when the batch is not
+	// created.
+	var stored []string
+	committed := false
+	defer func() {
+		if !committed {
+			removeUploads(context.WithoutCancel(c.Request().Context()), storage, stored)
+		}
+	}()
+
+	// Library watermarks are resolved by the worker through watermark_id,
+	// so their object is not tied to the batch's lifecycle.
 	var watermarkKey string
 	if len(watermarks) == 1 {
-		watermark := watermarks[0]
-		src, err := watermark.Open()
+		file := watermarks[0]
+		src, err := file.Open()
 		if err != nil {
 			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 		}
-		mediaType, _, err := mime.ParseMediaType(watermark.Header.Get("Content-Type"))
+		mediaType, err := sniffUpload(src, file.Header.Get("Content-Type"), isSupportedImageType)
+		var ue *uploadError
+		if errors.As(err, &ue) {
+			return utils.RespondFieldErrors(c, http.StatusBadRequest, "invalid watermark file", []utils.FieldError{{Field: "watermark", Message: ue.reason}})
+		}
 		if err != nil {
-			return utils.RespondError(c, http.StatusBadRequest, "invalid watermark file")
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 		}
-		if mediaType != "image/jpeg" && mediaType != "image/png" {
-			return utils.RespondError(c, http.StatusBadRequest, "unsupported watermark file type")
+		if _, err := watermark.DecodeWatermarkConfig(src); err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, err.Error())
+		}
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 		}
 		assetPath := utils.GetAssetPath(mediaType)
 		fileName := "watermark/" + assetPath
-		_, err = h.config.S3Client.PutObject(c.Request().Context(), &s3.PutObjectInput{
-			Bucket:      aws.String(h.config.S3Bucket),
+		_, err = storage.S3Client.PutObject(c.Request().Context(), &s3.PutObjectInput{
+			Bucket:      aws.String(storage.S3Bucket),
 			Key:         aws.String(fileName),
 			Body:        src,
 			ContentType: aws.String(mediaType),
@@ -208,92 +826,385 @@ func (h *BatchHandler) Create(c echo.Context) error {
 		if err != nil {
 			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 		}
+		stored = append(stored, fileName)
 		watermarkKey = fileName
-		watermarkURL = utils.GetObjectURL(h.config.S3CfDistribution, fileName)
-	}
-
-	batch, err := h.dbQueries.CreateBatch(c.Request().Context(), database.CreateBatchParams{
-		UserID:       userID,
-		Name:         sql.NullString{String: name, Valid: true},
-		WatermarkKey: sql.NullString{String: watermarkKey, Valid: true},
-		WatermarkUrl: sql.NullString{String: watermarkURL, Valid: true},
-	})
-	if err != nil {
-		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		watermarkURL = utils.GetObjectURL(storage.S3CfDistribution, fileName)
 	}
 
-	var imageSuccessCount int
+	var prepared []preparedUpload
+	rejected := []RejectedUpload{}
+	// First upload of each content hash, so repeats within this request are
+	// caught too.
+	firstUploads := make(map[string]int)
 	for _, file := range files {
-		src, err := file.Open()
+		upload, err := h.prepareUpload(c, storage, file, prepared, firstUploads, userID, user.Region, dedupePolicy)
 		if err != nil {
-			fmt.Printf("error opening file: %v", err)
+			var ue *uploadError
+			if !errors.As(err, &ue) {
+				return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+			}
+			rejected = append(rejected, RejectedUpload{Filename: file.Filename, Reason: ue.reason, Retryable: ue.retryable})
 			continue
 		}
+		if upload.stored {
+			stored = append(stored, upload.key)
+		}
+		if upload.action == "" {
+			firstUploads[upload.contentHash] = len(prepared)
+		}
+		prepared = append(prepared, upload)
+	}
+
+	if !slices.ContainsFunc(prepared, func(p preparedUpload) bool { return p.action != DuplicateActionSkipped }) {
+		code, msg := http.StatusBadRequest, "failed to create batch: no valid images uploaded"
+		errs := make([]utils.FieldError, 0, len(rejected))
+		for _, r := range rejected {
+			errs = append(errs, utils.FieldError{Field: r.Filename, Message: r.Reason})
+			if r.Retryable {
+				code, msg = http.StatusServiceUnavailable, "failed to create batch: uploads could not be stored, please try again later"
+			}
+		}
+		return utils.RespondFieldErrors(c, code, msg, errs)
+	}
+
+	batchParams := database.CreateBatchParams{
+		UserID:               userID,
+		Name:                 sql.NullString{String: name, Valid: true},
+		WatermarkKey:         sql.NullString{String: watermarkKey, Valid: true},
+		WatermarkUrl:         sql.NullString{String: watermarkURL, Valid: true},
+		PreserveFilenames:    preserveFilenames,
+		CollisionPolicy:      collisionPolicy,
+		WatermarkText:        sql.NullString{String: watermarkText, Valid: watermarkText != ""},
+		WatermarkFontID:      watermarkFontID,
+		WatermarkPosition:    watermarkPosition,
+		OutputFormat:         outputFormat,
+		OutputQuality:        int32(outputQuality),
+		WatermarkOpacity:     int32(watermarkOpacity),
+		WatermarkScale:       int32(watermarkScale),
+		MaxConcurrency:       int32(maxConcurrency),
+		WatermarkTileSpacing: int32(watermarkTileSpacing),
+		MaxWidth:             maxWidth,
+		MaxHeight:            maxHeight,
+		PreserveMetadata:     preserveMetadata,
+		InvisibleWatermark:   invisibleWatermark,
+		JpegProgressive:      jpegProgressive,
+		JpegSubsampling:      jpegSubsampling,
+		PngCompression:       pngCompression,
+		SimilarDedupe:        similarDedupe,
+		Transforms:           transforms,
+		Pipeline:             pipeline,
+		CropAspectRatio:      sql.NullString{String: cropAspectRatio, Valid: cropAspectRatio != ""},
+		CropX:                cropX,
+		CropY:                cropY,
+		CropWidth:            cropWidth,
+		CropHeight:           cropHeight,
+		WatermarkID:          libraryWatermarkID,
+		ExternalID:           sql.NullString{String: externalID, Valid: externalID != ""},
+		Region:               user.Region,
+		ReportCsv:            reportCSV,
+		ExpiresAt:            expiresAt,
+		Deadline:             deadline,
+	}
 
-		mediaType, _, err := mime.ParseMediaType(file.Header.Get("Content-Type"))
+	var response CreateBatchResponse
+	var publish *taskPublish
+	err = middleware.InTransaction(c, h.db, func(c echo.Context) error {
+		ctx := c.Request().Context()
+		dbQueries := utils.Queries(ctx, h.dbQueries)
+		batch, err := dbQueries.CreateBatch(ctx, batchParams)
 		if err != nil {
-			fmt.Printf("error reading content-type: %v", err)
-			src.Close()
-			continue
+			return utils.RespondDBError(c, err, "batch")
 		}
-		if mediaType != "image/jpeg" && mediaType != "image/png" {
-			fmt.Printf("unsupported file type")
-			src.Close()
-			continue
+
+		var imageTasks []ImageTask
+		duplicates := []DuplicateUpload{}
+		accepted := []AcceptedUpload{}
+		imageIDs := make([]uuid.UUID, len(prepared))
+		for i, upload := range prepared {
+			if upload.action != "" {
+				duplicateOf := upload.existing
+				if upload.firstIndex >= 0 {
+					duplicateOf = imageIDs[upload.firstIndex]
+				}
+				duplicates = append(duplicates, DuplicateUpload{Filename: upload.filename, DuplicateOf: duplicateOf, Action: upload.action})
+			}
+			if upload.action == DuplicateActionSkipped {
+				continue
+			}
+
+			params := database.CreateImageParams{
+				BatchID:     batch.ID,
+				Key:         upload.key,
+				OriginalUrl: upload.url,
+				Filename:    sql.NullString{String: upload.filename, Valid: upload.filename != ""},
+				ContentHash: sql.NullString{String: upload.contentHash, Valid: true},
+				ExternalID:  sql.NullString{String: externalIDs[upload.filename], Valid: externalIDs[upload.filename] != ""},
+				Redactions:  json.RawMessage("[]"),
+			}
+			if raw, ok := redactions[upload.filename]; ok {
+				params.Redactions = raw
+			}
+			if override, ok := overrides[upload.filename]; ok {
+				if override.Position != nil {
+					params.WatermarkPositionOverride = database.NullWatermarkPosition{WatermarkPosition: *override.Position, Valid: true}
+				}
+				if override.Opacity != nil {
+					params.WatermarkOpacityOverride = sql.NullInt32{Int32: int32(*override.Opacity), Valid: true}
+				}
+				if override.Scale != nil {
+					params.WatermarkScaleOverride = sql.NullInt32{Int32: int32(*override.Scale), Valid: true}
+				}
+			}
+			// A failed statement aborts the transaction, so the batch cannot
+			// go on without this file.
+			image, err := dbQueries.CreateImage(ctx, params)
+			if isImageExternalIDTaken(err) {
+				return utils.RespondError(c, http.StatusConflict, fmt.Sprintf("external_id %s is already used by another image", params.ExternalID.String))
+			}
+			if err != nil {
+				return utils.RespondDBError(c, err, "image")
+			}
+			imageIDs[i] = image.ID
+			accepted = append(accepted, AcceptedUpload{Filename: upload.filename, ImageID: image.ID, Key: image.Key})
+
+			task := NewImageTask(image.ID)
+			task.OutputFormat = batch.OutputFormat
+			task.OutputQuality = int(batch.OutputQuality)
+			imageTasks = append(imageTasks, task)
 		}
 
-		assetPath := utils.GetAssetPath(mediaType)
-		fileName := "raw/" + assetPath
-		_, err = h.config.S3Client.PutObject(c.Request().Context(), &s3.PutObjectInput{
-			Bucket:      aws.String(h.config.S3Bucket),
-			Key:         aws.String(fileName),
-			Body:        src,
-			ContentType: aws.String(mediaType),
-		})
+		if err := recordEvent(ctx, dbQueries, batch, database.BatchEventTypeCreated); err != nil {
+			c.Logger().Errorf("failed to record batch event: %v", err)
+		}
+
+		var status string
+		status, publish, err = h.startBatch(c, dbQueries, batch, imageTasks)
 		if err != nil {
-			fmt.Printf("error uploading to s3: %v", err)
-			src.Close()
-			continue
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		utils.AfterCommit(ctx, func() { committed = true })
+
+		response = CreateBatchResponse{
+			ID:         batch.ID,
+			Status:     status,
+			Duplicates: duplicates,
+			Images:     accepted,
+			Rejected:   rejected,
+		}
+		return nil
+	})
+	if err != nil || c.Response().Committed {
+		return err
+	}
+
+	// The tasks are published between the commit and this response, so it
+	// tells which images the queue has taken over.
+	for i, img := range response.Images {
+		queued := publish != nil && publish.queued[img.ImageID]
+		response.Images[i].Queued = &queued
+	}
+	return utils.RespondJSON(c, http.StatusCreated, "batch created successfully", response)
+}
+
+// preparedUpload is a file of a new batch that passed the checks and was
+// stored, or linked to or skipped as a duplicate, before the batch's
+// transaction opens.
+type preparedUpload struct {
+	filename    string
+	key         string
+	url         string
+	contentHash string
+	// stored is set when key was uploaded by this request.
+	stored bool
+	// action is the DuplicateAction of a duplicate, empty otherwise. The
+	// duplicated image is existing, stored before this request, or the
+	// image of prepared upload firstIndex when that is not -1.
+	action     string
+	existing   uuid.UUID
+	firstIndex int
+}
+
+// prepareUpload sniffs and hashes file, looks for an image of the user with
+// the same content, in prepared by firstUploads or stored before, and
+// uploads it unless dedupePolicy links or skips it. Errors the client can
+// fix, or retry, are *uploadError.
+func (h *BatchHandler) prepareUpload(c echo.Context, storage utils.RegionStorage, file *multipart.FileHeader, prepared []preparedUpload, firstUploads map[string]int, userID uuid.UUID, region string, dedupePolicy DedupePolicy) (preparedUpload, error) {
+	filename := file.Filename
+	src, err := file.Open()
+	if err != nil {
+		c.Logger().Errorf("failed to open upload %s: %v", filename, err)
+		return preparedUpload{}, &uploadError{reason: "file could not be read", retryable: true}
+	}
+	defer src.Close()
+
+	mediaType, err := sniffUpload(src, file.Header.Get("Content-Type"), isSupportedUploadType)
+	if err != nil {
+		return preparedUpload{}, err
+	}
+	contentHash, err := hashContent(src)
+	if err != nil {
+		c.Logger().Errorf("failed to hash upload %s: %v", filename, err)
+		return preparedUpload{}, &uploadError{reason: "file could not be read", retryable: true}
+	}
+
+	upload := preparedUpload{filename: filename, contentHash: contentHash, firstIndex: -1}
+	if i, ok := firstUploads[contentHash]; ok {
+		upload.firstIndex = i
+		upload.key, upload.url = prepared[i].key, prepared[i].url
+	} else {
+		existing, err := h.dbQueries.GetUserImageByContentHash(c.Request().Context(), database.GetUserImageByContentHashParams{
+			UserID:      userID,
+			ContentHash: sql.NullString{String: contentHash, Valid: true},
+			Region:      region,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return preparedUpload{}, err
 		}
+		if err == nil {
+			upload.existing = existing.ID
+			upload.key, upload.url = existing.Key, existing.OriginalUrl
+		}
+	}
 
-		objectURL := utils.GetObjectURL(h.config.S3CfDistribution, fileName)
+	duplicate := upload.firstIndex >= 0 || upload.existing != uuid.Nil
+	switch {
+	case duplicate && dedupePolicy == DedupePolicySkip:
+		upload.action = DuplicateActionSkipped
+		return upload, nil
+	case duplicate && dedupePolicy == DedupePolicyLink:
+		upload.action = DuplicateActionLinked
+		return upload, nil
+	case duplicate:
+		upload.action = DuplicateActionUploaded
+	}
+
+	fileName := "raw/" + utils.GetAssetPath(mediaType)
+	_, err = storage.S3Client.PutObject(c.Request().Context(), &s3.PutObjectInput{
+		Bucket:      aws.String(storage.S3Bucket),
+		Key:         aws.String(fileName),
+		Body:        src,
+		ContentType: aws.String(mediaType),
+	})
+	if err != nil {
+		c.Logger().Errorf("failed to store upload %s: %v", filename, err)
+		return preparedUpload{}, &uploadError{reason: "file could not be stored", retryable: true}
+	}
+	upload.key, upload.url, upload.stored = fileName, utils.GetObjectURL(storage.S3CfDistribution, fileName), true
+	return upload, nil
+}
 
-		image, err := h.dbQueries.CreateImage(c.Request().Context(), database.CreateImageParams{
-			BatchID:     batch.ID,
-			Key:         fileName,
-			OriginalUrl: objectURL,
+// removeUploads deletes the objects stored for a batch that was not
+// created. Failures leave the objects behind and are only logged.
+func removeUploads(ctx context.Context, storage utils.RegionStorage, keys []string) {
+	for _, key := range keys {
+		_, err := storage.S3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(storage.S3Bucket),
+			Key:    aws.String(key),
 		})
 		if err != nil {
-			fmt.Printf("error saving image: %s\n", assetPath)
-			continue
+			log.Printf("error remove upload %s: %v", key, err)
+		}
+	}
+}
+
+// Update godoc
+// @Summary Update batch
+// @Description Rename a batch and, until any of its images has been picked up by a worker, change its watermark settings. Omitted fields are left unchanged
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param batch body UpdateBatchRequest true "Update Batch Request"
+// @Success 200 {object} utils.SuccessResponse{data=BatchResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID} [patch]
+func (h *BatchHandler) Update(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	var body UpdateBatchRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
 		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	images, err := h.dbQueries.GetImagesByBatchID(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
 
-		imageTask := ImageTask{
-			ImageID: image.ID,
+	params := database.UpdateBatchByIDParams{
+		ID:     batch.ID,
+		UserID: userID,
+	}
+	if body.Name != nil {
+		params.Name = sql.NullString{String: *body.Name, Valid: true}
+	}
+	if body.changesWatermark() {
+		// Workers read the watermark settings when they pick an image up, so
+		// they can only change while every image is still waiting. The
+		// update checks again, in case a worker picks one up meanwhile.
+		params.ChangesWatermark = true
+		for _, img := range images {
+			if img.Status != database.ImageStatusPending {
+				return utils.RespondError(c, http.StatusConflict, "batch processing has already started")
+			}
 		}
-		err = pubsub.PublishJSON(ch, utils.ImageGoDirect, utils.ImageGoTask, imageTask)
-		if err != nil {
-			fmt.Printf("error publishing message: %v", err)
-			continue
+		if body.WatermarkText != nil {
+			if !batch.WatermarkText.Valid || batch.WatermarkText.String == "" {
+				return utils.RespondError(c, http.StatusBadRequest, "watermark_text can only be changed on batches with a text watermark")
+			}
+			params.WatermarkText = sql.NullString{String: *body.WatermarkText, Valid: true}
+		}
+		if body.WatermarkPosition != nil {
+			params.WatermarkPosition = database.NullWatermarkPosition{WatermarkPosition: database.WatermarkPosition(*body.WatermarkPosition), Valid: true}
+		}
+		if body.WatermarkOpacity != nil {
+			params.WatermarkOpacity = sql.NullInt32{Int32: int32(*body.WatermarkOpacity), Valid: true}
+		}
+		if body.WatermarkScale != nil {
+			params.WatermarkScale = sql.NullInt32{Int32: int32(*body.WatermarkScale), Valid: true}
+		}
+		if body.WatermarkTileSpacing != nil {
+			params.WatermarkTileSpacing = sql.NullInt32{Int32: int32(*body.WatermarkTileSpacing), Valid: true}
 		}
-		fmt.Printf("%s uploaded\n", image.OriginalUrl)
-		imageSuccessCount++
 	}
 
-	if imageSuccessCount == 0 {
-		h.dbQueries.HardDeleteBatchByID(c.Request().Context(), database.HardDeleteBatchByIDParams{
-			ID:     batch.ID,
-			UserID: userID,
-		})
-		return utils.RespondError(c, http.StatusBadRequest, "failed to create batch: no valid images uploaded")
+	batch, err = h.dbQueries.UpdateBatchByID(c.Request().Context(), params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) && params.ChangesWatermark {
+			return utils.RespondError(c, http.StatusConflict, "batch processing has already started")
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 	}
 
-	return utils.RespondJSON(c, http.StatusCreated, "batch created successfully", nil)
+	return utils.RespondJSON(c, http.StatusOK, "batch updated successfully", newBatchResponse(batch, images))
 }
 
 // DeleteByID godoc
 // @Summary Delete batch by ID
-// @Description Delete a specific batch by its ID for the authenticated user
+// @Description Move a specific batch of the authenticated user to the trash. It can be restored with POST /batches/{batchID}/restore for 30 days, after which it is purged with its files
 // @Tags batches
 // @Produce json
 // @Security BearerAuth
@@ -304,15 +1215,10 @@ func (h *BatchHandler) Create(c echo.Context) error {
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /batches/{batchID} [delete]
 func (h *BatchHandler) DeleteByID(c echo.Context) error {
-	batchID := c.Param("batchID")
 	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
 
-	batchUUID, err := uuid.Parse(batchID)
-	if err != nil {
-		return utils.RespondError(c, http.StatusBadRequest, "invalid batch ID")
-	}
-
-	err = h.dbQueries.DeleteBatchByID(c.Request().Context(), database.DeleteBatchByIDParams{
+	err := h.dbQueries.DeleteBatchByID(c.Request().Context(), database.DeleteBatchByIDParams{
 		ID:     batchUUID,
 		UserID: userID,
 	})
@@ -321,3 +1227,105 @@ func (h *BatchHandler) DeleteByID(c echo.Context) error {
 	}
 	return utils.RespondJSON(c, http.StatusOK, "batch deleted successfully", nil)
 }
+
+// hashContent returns the hex SHA-256 of an uploaded file and rewinds it for
+// the upload.
+func hashContent(src multipart.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, src); err != nil {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// maxCropSize bounds each value of a crop rectangle.
+const maxCropSize = 100000
+
+// parseCropRect parses the crop_rect form field, x,y,width,height in pixels.
+func parseCropRect(s string) (CropRect, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return CropRect{}, false
+	}
+	var values [4]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || v < 0 || v > maxCropSize {
+			return CropRect{}, false
+		}
+		values[i] = v
+	}
+	rect := CropRect{X: values[0], Y: values[1], Width: values[2], Height: values[3]}
+	if rect.Width == 0 || rect.Height == 0 {
+		return CropRect{}, false
+	}
+	return rect, true
+}
+
+// imageExternalIDKey is the unique index that keeps the external IDs of the
+// live images of a user apart.
+const imageExternalIDKey = "images_owner_id_external_id_key"
+
+// isImageExternalIDTaken tells whether err is a write that gave an image the
+// external ID of another live image of the user.
+func isImageExternalIDTaken(err error) bool {
+	return errors.Is(utils.MapDBError(err), utils.ErrConflict) && utils.ConstraintName(err) == imageExternalIDKey
+}
+
+// userDefaultWatermark returns the library watermark the user set as the
+// default for batches created without one; ok is false when there is none.
+func userDefaultWatermark(ctx context.Context, dbQueries *database.Queries, userID uuid.UUID) (database.Watermark, bool, error) {
+	watermark, err := dbQueries.GetUserDefaultWatermark(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Watermark{}, false, nil
+	}
+	if err != nil {
+		return database.Watermark{}, false, err
+	}
+	return watermark, true, nil
+}
+
+// startBatch queues the tasks of a new batch, or leaves it waiting when the
+// user is at their active batch limit, and returns its status. The publish
+// of the queued tasks is nil for a waiting batch.
+func (h *BatchHandler) startBatch(c echo.Context, dbQueries *database.Queries, batch database.Batch, tasks []ImageTask) (string, *taskPublish, error) {
+	limit, err := activeBatchLimit(c.Request().Context(), dbQueries, h.config, batch.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+	// The count includes this batch, whose images are already inserted. The
+	// user is locked until the transaction ends, so concurrent creates and
+	// PollWaitingBatches count one after another. FOR NO KEY UPDATE does not
+	// wait for the key share lock the new batch row holds on the user, so two
+	// creates cannot deadlock.
+	if limit > 0 {
+		if err := dbQueries.LockUserBatchQuota(c.Request().Context(), batch.UserID); err != nil {
+			return "", nil, err
+		}
+		active, err := dbQueries.CountActiveUserBatches(c.Request().Context(), batch.UserID)
+		if err != nil {
+			return "", nil, err
+		}
+		if active > limit {
+			return BatchStatusWaiting, nil, dbQueries.SetBatchWaiting(c.Request().Context(), batch.ID)
+		}
+	}
+	publish, err := h.publishAfterCommit(c, dbQueries, batch.UserID, batch.Region, tasks)
+	if err != nil {
+		return "", nil, err
+	}
+	return BatchStatusPending, publish, nil
+}
+
+func isSupportedImageType(mediaType string) bool {
+	return mediaType == "image/jpeg" || mediaType == "image/png" || mediaType == "image/webp"
+}
+
+// isSupportedUploadType also accepts GIFs, which are watermarked frame by
+// frame. Watermarks themselves must be stills.
+func isSupportedUploadType(mediaType string) bool {
+	return isSupportedImageType(mediaType) || mediaType == "image/gif"
+}
@@ -0,0 +1,243 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const (
+	// outboxLease is how long a task in the outbox is left to the publish
+	// after its commit, or to the poller that claimed it, before the next
+	// poll publishes it again.
+	outboxLease     = time.Minute
+	outboxClaimSize = 100
+	// publishTimeout bounds how long the tasks of a request wait for the
+	// broker's confirms.
+	publishTimeout = 10 * time.Second
+)
+
+// queueTasks writes tasks for queue to the outbox with the transaction of
+// dbQueries. They are published by publishAfterCommit or, when that fails, by
+// PollTaskOutbox; a task published twice keeps its TaskID, so the worker
+// applies it once.
+func queueTasks(ctx context.Context, dbQueries *database.Queries, region, queue string, tasks []ImageTask) error {
+	for _, task := range tasks {
+		payload, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		err = dbQueries.CreateOutboxTask(ctx, database.CreateOutboxTaskParams{
+			TaskID:        task.TaskID,
+			ImageID:       uuid.NullUUID{UUID: task.ImageID, Valid: true},
+			Region:        region,
+			Queue:         queue,
+			Payload:       payload,
+			NextAttemptAt: time.Now().UTC().Add(outboxLease),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskPublish tells which tasks publishAfterCommit got the broker to take
+// over, by image ID, once the transaction has committed.
+type taskPublish struct {
+	queued map[uuid.UUID]bool
+}
+
+// publishAfterCommit queues the image tasks of the user for the workers of
+// region in the outbox and publishes them once the request transaction
+// commits, before the response is sent, so the worker never looks up an image
+// that is not visible yet. Tasks the broker does not confirm stay in the
+// outbox for PollTaskOutbox.
+func (h *BatchHandler) publishAfterCommit(c echo.Context, dbQueries *database.Queries, userID uuid.UUID, region string, tasks []ImageTask) (*taskPublish, error) {
+	return queueAfterCommit(c.Request().Context(), dbQueries, h.dbQueries, h.config, userID, region, tasks)
+}
+
+// QueueTasksAfterCommit is publishAfterCommit for handlers of other packages
+// whose request transaction is carried by ctx.
+func QueueTasksAfterCommit(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, userID uuid.UUID, region string, tasks []ImageTask) error {
+	_, err := queueAfterCommit(ctx, utils.Queries(ctx, dbQueries), dbQueries, cfg, userID, region, tasks)
+	return err
+}
+
+// queueAfterCommit writes tasks to the outbox with txQueries and publishes
+// them with dbQueries once the transaction carried by ctx commits.
+func queueAfterCommit(ctx context.Context, txQueries, dbQueries *database.Queries, cfg *utils.Config, userID uuid.UUID, region string, tasks []ImageTask) (*taskPublish, error) {
+	queue, err := TaskQueueFor(ctx, txQueries, userID, region)
+	if err != nil {
+		return nil, err
+	}
+	if err := queueTasks(ctx, txQueries, region, queue, tasks); err != nil {
+		return nil, err
+	}
+	publish := &taskPublish{}
+	utils.AfterCommit(ctx, func() {
+		publishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+		defer cancel()
+		queued, err := publishQueuedTasks(publishCtx, dbQueries, cfg, queue, tasks)
+		if err != nil {
+			log.Printf("error open channel, %d image tasks left in the outbox: %v", len(tasks), err)
+		}
+		publish.queued = queued
+	})
+	return publish, nil
+}
+
+// queueCleanup writes a cleanup of keys by the workers of region to the
+// outbox with the transaction of dbQueries, so the objects are removed once
+// the rows referring to them are deleted for good, and only then. It returns
+// the outbox task for publishCleanup, or uuid.Nil without keys.
+func queueCleanup(ctx context.Context, dbQueries *database.Queries, region string, keys []string) (uuid.UUID, error) {
+	if len(keys) == 0 {
+		return uuid.Nil, nil
+	}
+	payload, err := json.Marshal(CleanupTask{Keys: keys})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	taskID := uuid.New()
+	err = dbQueries.CreateOutboxTask(ctx, database.CreateOutboxTaskParams{
+		TaskID:        taskID,
+		Region:        region,
+		Queue:         utils.CleanupQueue(region),
+		Payload:       payload,
+		NextAttemptAt: time.Now().UTC().Add(outboxLease),
+	})
+	return taskID, err
+}
+
+// publishCleanup publishes the cleanup queueCleanup wrote once its
+// transaction committed and removes it from the outbox. When the broker does
+// not confirm it, PollTaskOutbox publishes it later.
+func publishCleanup(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, region string, taskID uuid.UUID, keys []string) {
+	if taskID == uuid.Nil {
+		return
+	}
+	ch, err := cfg.RabbitMQ.Get()
+	if err != nil {
+		log.Printf("error open channel, cleanup of %d objects left in the outbox: %v", len(keys), err)
+		return
+	}
+	defer cfg.RabbitMQ.Put(ch)
+	if err := pubsub.PublishJSONConfirmed(ctx, ch, utils.ImageGoDirect, utils.CleanupQueue(region), CleanupTask{Keys: keys}); err != nil {
+		log.Printf("error publish cleanup of %d objects, left in the outbox: %v", len(keys), err)
+		return
+	}
+	if err := dbQueries.DeleteOutboxTask(ctx, taskID); err != nil {
+		log.Printf("error remove cleanup task %s from the outbox: %v", taskID, err)
+	}
+}
+
+// QueueCleanupAfterCommit queues a cleanup of keys by the workers of region
+// in the outbox with the request transaction carried by ctx and publishes it
+// once the transaction commits.
+func QueueCleanupAfterCommit(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, region string, keys []string) error {
+	taskID, err := queueCleanup(ctx, utils.Queries(ctx, dbQueries), region, keys)
+	if err != nil {
+		return err
+	}
+	utils.AfterCommit(ctx, func() {
+		publishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+		defer cancel()
+		publishCleanup(publishCtx, dbQueries, cfg, region, taskID, keys)
+	})
+	return nil
+}
+
+// publishQueuedTasks publishes tasks that queueTasks wrote to the outbox of a
+// committed transaction and removes the ones the broker confirmed, returning
+// their image IDs. The others stay in the outbox for PollTaskOutbox.
+func publishQueuedTasks(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, queue string, tasks []ImageTask) (map[uuid.UUID]bool, error) {
+	queued := make(map[uuid.UUID]bool, len(tasks))
+	if len(tasks) == 0 {
+		return queued, nil
+	}
+	ch, err := cfg.RabbitMQ.Get()
+	if err != nil {
+		return queued, err
+	}
+	defer cfg.RabbitMQ.Put(ch)
+
+	for _, task := range tasks {
+		if err := pubsub.PublishJSONConfirmed(ctx, ch, utils.ImageGoDirect, queue, task); err != nil {
+			log.Printf("error publish image task %s, left in the outbox: %v", task.ImageID, err)
+			continue
+		}
+		queued[task.ImageID] = true
+		if err := dbQueries.DeleteOutboxTask(ctx, task.TaskID); err != nil {
+			log.Printf("error remove image task %s from the outbox: %v", task.TaskID, err)
+		}
+	}
+	return queued, nil
+}
+
+// PollTaskOutbox publishes the image tasks whose publish after their commit
+// failed every interval until ctx is done. Claiming a task leases it, so
+// several servers polling at once publish each task once.
+func PollTaskOutbox(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ch, err := cfg.RabbitMQ.Get()
+			if err != nil {
+				log.Printf("error open channel for the task outbox: %v", err)
+				continue
+			}
+			publishOutboxTasks(ctx, dbQueries, func(queue string, payload json.RawMessage) error {
+				publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+				defer cancel()
+				return pubsub.PublishJSONConfirmed(publishCtx, ch, utils.ImageGoDirect, queue, payload)
+			})
+			cfg.RabbitMQ.Put(ch)
+		}
+	}
+}
+
+// taskOutbox is the part of *database.Queries that publishes the outbox.
+type taskOutbox interface {
+	ClaimDueOutboxTasks(ctx context.Context, arg database.ClaimDueOutboxTasksParams) ([]database.TaskOutbox, error)
+	DeleteOutboxTask(ctx context.Context, taskID uuid.UUID) error
+}
+
+// publishOutboxTasks publishes the due tasks of the outbox to their queue and
+// removes the ones that were published. The others are claimed again once
+// their lease is over. Tasks written before the outbox kept their queue go to
+// the shared queue of their region.
+func publishOutboxTasks(ctx context.Context, dbQueries taskOutbox, publish func(queue string, payload json.RawMessage) error) {
+	tasks, err := dbQueries.ClaimDueOutboxTasks(ctx, database.ClaimDueOutboxTasksParams{
+		LeaseSeconds: int32(outboxLease / time.Second),
+		PageLimit:    outboxClaimSize,
+	})
+	if err != nil {
+		log.Printf("error claim outbox tasks: %v", err)
+		return
+	}
+	for _, task := range tasks {
+		queue := task.Queue
+		if queue == "" {
+			queue = utils.TaskQueue(task.Region)
+		}
+		if err := publish(queue, task.Payload); err != nil {
+			log.Printf("error publish outbox task %s: %v", task.TaskID, err)
+			continue
+		}
+		if err := dbQueries.DeleteOutboxTask(ctx, task.TaskID); err != nil {
+			log.Printf("error remove outbox task %s: %v", task.TaskID, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTaskOutbox hands out due tasks and records the ones removed.
+type fakeTaskOutbox struct {
+	due     []database.TaskOutbox
+	deleted []uuid.UUID
+}
+
+func (f *fakeTaskOutbox) ClaimDueOutboxTasks(_ context.Context, _ database.ClaimDueOutboxTasksParams) ([]database.TaskOutbox, error) {
+	return f.due, nil
+}
+
+func (f *fakeTaskOutbox) DeleteOutboxTask(_ context.Context, taskID uuid.UUID) error {
+	f.deleted = append(f.deleted, taskID)
+	return nil
+}
+
+func TestPublishOutboxTasks(t *testing.T) {
+	published := database.TaskOutbox{TaskID: uuid.New(), Region: "ap-southeast-1", Queue: "image_tasks.ap-southeast-1.priority", Payload: json.RawMessage(`{"task_id":"a"}`)}
+	// Written before the outbox kept the queue of a task.
+	failing := database.TaskOutbox{TaskID: uuid.New(), Region: "eu-central-1", Payload: json.RawMessage(`{"task_id":"b"}`)}
+	outbox := &fakeTaskOutbox{due: []database.TaskOutbox{published, failing}}
+
+	var queues []string
+	publishOutboxTasks(context.Background(), outbox, func(queue string, payload json.RawMessage) error {
+		queues = append(queues, queue)
+		if queue != published.Queue {
+			return errors.New("channel closed")
+		}
+		assert.JSONEq(t, `{"task_id":"a"}`, string(payload))
+		return nil
+	})
+
+	assert.Equal(t, []string{"image_tasks.ap-southeast-1.priority", "image_tasks.eu-central-1"}, queues)
+	// The failed task stays for the next claim once its lease is over.
+	assert.Equal(t, []uuid.UUID{published.TaskID}, outbox.deleted)
+}
+
+// fakeBoosts reports the active boost of each user.
+type fakeBoosts map[uuid.UUID]database.GetActiveUserBoostRow
+
+func (f fakeBoosts) GetActiveUserBoost(_ context.Context, userID uuid.UUID) (database.GetActiveUserBoostRow, error) {
+	if boost, ok := f[userID]; ok {
+		return boost, nil
+	}
+	return database.GetActiveUserBoostRow{ConcurrencyMultiplier: 1}, nil
+}
+
+func TestTaskQueueFor(t *testing.T) {
+	priority, boosted, regular := uuid.New(), uuid.New(), uuid.New()
+	boosts := fakeBoosts{
+		priority: {ConcurrencyMultiplier: 1, Priority: true},
+		boosted:  {ExtraActiveBatches: 2, ConcurrencyMultiplier: 3},
+	}
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		region string
+		want   string
+	}{
+		{"priority boost", priority, "", "image_tasks.priority"},
+		{"priority boost in region", priority, "eu-central-1", "image_tasks.eu-central-1.priority"},
+		{"boost without priority", boosted, "eu-central-1", "image_tasks.eu-central-1"},
+		{"no boost", regular, "", "image_tasks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, err := TaskQueueFor(context.Background(), boosts, tt.userID, tt.region)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, queue)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+// userPreset returns the preset of the user that a create request names by
+// preset_id. Errors the client can fix are *settingsError.
+func userPreset(ctx context.Context, dbQueries *database.Queries, userID uuid.UUID, presetID string) (database.BatchPreset, error) {
+	id, err := uuid.Parse(presetID)
+	if err != nil {
+		return database.BatchPreset{}, &settingsError{http.StatusBadRequest, "invalid preset_id"}
+	}
+	preset, err := dbQueries.GetUserBatchPresetByID(ctx, database.GetUserBatchPresetByIDParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return preset, &settingsError{http.StatusBadRequest, "preset not found"}
+	}
+	return preset, err
+}
+
+// presetWatermark returns the library watermark of preset, used by batches
+// created from it without a watermark of their own. It reports false when the
+// preset has none.
+func presetWatermark(ctx context.Context, dbQueries *database.Queries, preset database.BatchPreset, region string) (database.Watermark, bool, error) {
+	if !preset.WatermarkID.Valid {
+		return database.Watermark{}, false, nil
+	}
+	watermark, err := dbQueries.GetUserWatermarkByID(ctx, database.GetUserWatermarkByIDParams{
+		ID:     preset.WatermarkID.UUID,
+		UserID: preset.UserID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return watermark, false, &settingsError{http.StatusConflict, "watermark of the preset was deleted"}
+		}
+		return watermark, false, err
+	}
+	if watermark.Region != region {
+		return watermark, false, &settingsError{http.StatusConflict, "watermark of the preset is stored in another data region"}
+	}
+	return watermark, true, nil
+}
+
+// applyPreset replaces the settings of params that preset sets. Its maximum
+// width and height replace both bounds of params together, so a preset
+// bounding only the width does not keep a height bound from elsewhere.
+func applyPreset(params *database.CreateBatchParams, preset database.BatchPreset) {
+	if preset.WatermarkPosition.Valid {
+		params.WatermarkPosition = preset.WatermarkPosition.WatermarkPosition
+	}
+	if preset.WatermarkOpacity.Valid {
+		params.WatermarkOpacity = preset.WatermarkOpacity.Int32
+	}
+	if preset.WatermarkScale.Valid {
+		params.WatermarkScale = preset.WatermarkScale.Int32
+	}
+	if preset.OutputFormat.Valid {
+		params.OutputFormat = preset.OutputFormat.OutputFormat
+	}
+	if preset.OutputQuality.Valid {
+		params.OutputQuality = preset.OutputQuality.Int32
+	}
+	if preset.MaxWidth.Valid || preset.MaxHeight.Valid {
+		params.MaxWidth = preset.MaxWidth
+		params.MaxHeight = preset.MaxHeight
+	}
+}
@@ -0,0 +1,74 @@
+package batch
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPreset(t *testing.T) {
+	base := database.CreateBatchParams{
+		WatermarkPosition: database.WatermarkPositionBottomRight,
+		WatermarkOpacity:  50,
+		WatermarkScale:    15,
+		OutputFormat:      database.OutputFormatJpeg,
+		OutputQuality:     50,
+		MaxWidth:          sql.NullInt32{Int32: 800, Valid: true},
+		MaxHeight:         sql.NullInt32{Int32: 600, Valid: true},
+	}
+
+	tests := []struct {
+		name   string
+		preset database.BatchPreset
+		want   func(p *database.CreateBatchParams)
+	}{
+		{
+			name:   "empty preset keeps everything",
+			preset: database.BatchPreset{},
+			want:   func(p *database.CreateBatchParams) {},
+		},
+		{
+			name: "set fields replace",
+			preset: database.BatchPreset{
+				WatermarkPosition: database.NullWatermarkPosition{WatermarkPosition: database.WatermarkPositionCenter, Valid: true},
+				WatermarkOpacity:  sql.NullInt32{Int32: 80, Valid: true},
+				WatermarkScale:    sql.NullInt32{Int32: 30, Valid: true},
+				OutputFormat:      database.NullOutputFormat{OutputFormat: database.OutputFormatWebp, Valid: true},
+				OutputQuality:     sql.NullInt32{Int32: 90, Valid: true},
+			},
+			want: func(p *database.CreateBatchParams) {
+				p.WatermarkPosition = database.WatermarkPositionCenter
+				p.WatermarkOpacity = 80
+				p.WatermarkScale = 30
+				p.OutputFormat = database.OutputFormatWebp
+				p.OutputQuality = 90
+			},
+		},
+		{
+			name:   "width alone replaces both bounds",
+			preset: database.BatchPreset{MaxWidth: sql.NullInt32{Int32: 1200, Valid: true}},
+			want: func(p *database.CreateBatchParams) {
+				p.MaxWidth = sql.NullInt32{Int32: 1200, Valid: true}
+				p.MaxHeight = sql.NullInt32{}
+			},
+		},
+		{
+			name:   "height alone replaces both bounds",
+			preset: database.BatchPreset{MaxHeight: sql.NullInt32{Int32: 400, Valid: true}},
+			want: func(p *database.CreateBatchParams) {
+				p.MaxWidth = sql.NullInt32{}
+				p.MaxHeight = sql.NullInt32{Int32: 400, Valid: true}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, want := base, base
+			applyPreset(&got, tt.preset)
+			tt.want(&want)
+			assert.Equal(t, want, got)
+		})
+	}
+}
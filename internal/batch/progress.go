@@ -0,0 +1,66 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetProgress godoc
+// @Summary Get batch progress
+// @Description Count the images of a batch by status and estimate when it finishes from the images completed or failed over the last five minutes, for clients that poll without loading every image
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=BatchProgressResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/progress [get]
+func (h *BatchHandler) GetProgress(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	res, err := Progress(c.Request().Context(), h.dbQueries, batch)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "batch progress retrieved successfully", res)
+}
+
+// Progress counts the images of b and estimates when it finishes.
+func Progress(ctx context.Context, dbQueries *database.Queries, b database.Batch) (BatchProgressResponse, error) {
+	progress, err := dbQueries.GetBatchProgress(ctx, b.ID)
+	if err != nil {
+		return BatchProgressResponse{}, err
+	}
+	counts := ImageStatusCounts{
+		Pending:    progress.Pending,
+		Processing: progress.Processing,
+		Completed:  progress.Completed,
+		Failed:     progress.Failed,
+		Cancelled:  progress.Cancelled,
+		Expired:    progress.Expired,
+	}
+	return newBatchProgressResponse(b, counts, progress.RecentlyFinished, progress.WindowSeconds), nil
+}
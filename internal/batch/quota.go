@@ -0,0 +1,158 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// PollWaitingBatches periodically starts waiting batches, oldest first, for
+// users below MaxActiveBatches. Starting a batch clears its waiting state in
+// one statement and the user is locked while counting, so several servers
+// polling at once start each batch once and keep to the limit.
+func PollWaitingBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			startWaitingBatches(ctx, db, dbQueries, cfg)
+		}
+	}
+}
+
+func startWaitingBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config) {
+	userIDs, err := dbQueries.GetWaitingBatchUserIDs(ctx)
+	if err != nil {
+		log.Printf("error get users with waiting batches: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		if err := startUserBatches(ctx, db, dbQueries, cfg, userID); err != nil {
+			log.Printf("error start waiting batches of user %s: %v", userID, err)
+		}
+	}
+}
+
+// activeBatchLimit is how many batches of the user are processed at the same
+// time: MaxActiveBatches, plus the extra batches of a boost while one is
+// active. 0 means no limit.
+func activeBatchLimit(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, userID uuid.UUID) (int64, error) {
+	if cfg.MaxActiveBatches <= 0 {
+		return 0, nil
+	}
+	boost, err := dbQueries.GetActiveUserBoost(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int64(cfg.MaxActiveBatches) + int64(boost.ExtraActiveBatches), nil
+}
+
+// boostLookup is the part of *database.Queries that tells a user's boost.
+type boostLookup interface {
+	GetActiveUserBoost(ctx context.Context, userID uuid.UUID) (database.GetActiveUserBoostRow, error)
+}
+
+// TaskQueueFor names the queue the image tasks of the user go to in region:
+// the priority queue while the user has an active priority boost, the
+// shared one otherwise.
+func TaskQueueFor(ctx context.Context, dbQueries boostLookup, userID uuid.UUID, region string) (string, error) {
+	boost, err := dbQueries.GetActiveUserBoost(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if boost.Priority {
+		return utils.PriorityTaskQueue(region), nil
+	}
+	return utils.TaskQueue(region), nil
+}
+
+// startedBatch is a batch startUserBatches started, with the tasks it wrote
+// to the outbox for queue.
+type startedBatch struct {
+	id    uuid.UUID
+	queue string
+	tasks []ImageTask
+}
+
+// startUserBatches starts the user's waiting batches until they are at the
+// limit again. Without a limit every waiting batch starts, e.g. after the
+// quota was turned off. The user is locked while counting and starting, as
+// when a batch is created, and the tasks go to the outbox in the same
+// transaction, so a failed publish is retried instead of leaving the batch
+// started without tasks.
+func startUserBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config, userID uuid.UUID) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := dbQueries.WithTx(tx)
+
+	if err := qtx.LockUserBatchQuota(ctx, userID); err != nil {
+		return err
+	}
+	limit, err := activeBatchLimit(ctx, qtx, cfg, userID)
+	if err != nil {
+		return err
+	}
+	active, err := qtx.CountActiveUserBatches(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var started []startedBatch
+	for limit == 0 || active < limit {
+		batch, err := qtx.StartNextWaitingBatch(ctx, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		images, err := qtx.GetImagesByBatchID(ctx, batch.ID)
+		if err != nil {
+			return err
+		}
+		var tasks []ImageTask
+		for _, img := range images {
+			if img.Status != database.ImageStatusPending {
+				continue
+			}
+			task := NewImageTask(img.ID)
+			task.OutputFormat = batch.OutputFormat
+			task.OutputQuality = int(batch.OutputQuality)
+			tasks = append(tasks, task)
+		}
+		queue, err := TaskQueueFor(ctx, qtx, userID, batch.Region)
+		if err != nil {
+			return err
+		}
+		if err := queueTasks(ctx, qtx, batch.Region, queue, tasks); err != nil {
+			return err
+		}
+		started = append(started, startedBatch{id: batch.ID, queue: queue, tasks: tasks})
+		active++
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, b := range started {
+		publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		_, err := publishQueuedTasks(publishCtx, dbQueries, cfg, b.queue, b.tasks)
+		cancel()
+		if err != nil {
+			log.Printf("error open channel, %d image tasks of batch %s left in the outbox: %v", len(b.tasks), b.id, err)
+		}
+		log.Printf("batch %s started with %d images", b.id, len(b.tasks))
+	}
+	return nil
+}
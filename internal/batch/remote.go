@@ -0,0 +1,281 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// settingsError is a problem with the settings of a create request that the
+// client has to fix, answered with status.
+type settingsError struct {
+	status int
+	msg    string
+}
+
+func (e *settingsError) Error() string {
+	return e.msg
+}
+
+// respondSettingsError answers err from remoteBatchParams.
+func respondSettingsError(c echo.Context, err error) error {
+	var se *settingsError
+	if errors.As(err, &se) {
+		return utils.RespondError(c, se.status, se.msg)
+	}
+	return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+}
+
+// remoteBatchParams builds the batch of a request whose originals are fetched
+// by the workers, starting from the settings of its preset. Errors the client
+// can fix are *settingsError.
+func remoteBatchParams(ctx context.Context, dbQueries *database.Queries, user database.User, settings RemoteBatchSettings) (database.CreateBatchParams, error) {
+	if settings.WatermarkID != nil && settings.WatermarkText != nil {
+		return database.CreateBatchParams{}, &settingsError{http.StatusBadRequest, "watermark_id cannot be combined with watermark_text"}
+	}
+	if settings.WatermarkFontID != nil && settings.WatermarkText == nil {
+		return database.CreateBatchParams{}, &settingsError{http.StatusBadRequest, "watermark_font_id requires watermark_text"}
+	}
+
+	params := database.CreateBatchParams{
+		UserID:               user.ID,
+		Name:                 sql.NullString{String: settings.Name, Valid: true},
+		WatermarkKey:         sql.NullString{String: "", Valid: true},
+		WatermarkUrl:         sql.NullString{String: "", Valid: true},
+		CollisionPolicy:      database.OutputCollisionPolicySuffix,
+		WatermarkPosition:    database.WatermarkPositionBottomRight,
+		OutputFormat:         database.OutputFormatJpeg,
+		OutputQuality:        50,
+		WatermarkOpacity:     50,
+		WatermarkScale:       15,
+		MaxConcurrency:       10,
+		WatermarkTileSpacing: 50,
+		JpegSubsampling:      database.ChromaSubsampling420,
+		PngCompression:       database.PngCompressionDefault,
+		SimilarDedupe:        database.SimilarDedupeOff,
+		Transforms:           json.RawMessage("[]"),
+		Pipeline:             json.RawMessage("[]"),
+		ExternalID:           sql.NullString{String: settings.ExternalID, Valid: settings.ExternalID != ""},
+		Region:               user.Region,
+		ReportCsv:            settings.ReportCSV,
+	}
+	if settings.ExpiresInHours > 0 {
+		params.ExpiresAt = sql.NullTime{Time: time.Now().UTC().Add(time.Duration(settings.ExpiresInHours) * time.Hour), Valid: true}
+	}
+	if settings.Deadline != nil {
+		if !settings.Deadline.After(time.Now()) {
+			return params, &settingsError{http.StatusBadRequest, "deadline must be in the future"}
+		}
+		params.Deadline = sql.NullTime{Time: settings.Deadline.UTC(), Valid: true}
+	}
+	var preset database.BatchPreset
+	if settings.PresetID != nil {
+		var err error
+		preset, err = userPreset(ctx, dbQueries, user.ID, *settings.PresetID)
+		if err != nil {
+			return params, err
+		}
+		applyPreset(&params, preset)
+	}
+	if settings.WatermarkID != nil {
+		watermark, err := dbQueries.GetUserWatermarkByID(ctx, database.GetUserWatermarkByIDParams{
+			ID:     uuid.MustParse(*settings.WatermarkID),
+			UserID: user.ID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return params, &settingsError{http.StatusBadRequest, "watermark not found"}
+			}
+			return params, err
+		}
+		if watermark.Region != user.Region {
+			return params, &settingsError{http.StatusBadRequest, "watermark is stored in another data region"}
+		}
+		params.WatermarkID = uuid.NullUUID{UUID: watermark.ID, Valid: true}
+		params.WatermarkUrl = sql.NullString{String: watermark.Url, Valid: true}
+	}
+	if settings.WatermarkText != nil {
+		params.WatermarkText = sql.NullString{String: *settings.WatermarkText, Valid: true}
+	}
+	if settings.WatermarkID == nil && settings.WatermarkText == nil {
+		watermark, ok, err := presetWatermark(ctx, dbQueries, preset, user.Region)
+		if err != nil {
+			return params, err
+		}
+		if ok {
+			params.WatermarkID = uuid.NullUUID{UUID: watermark.ID, Valid: true}
+			params.WatermarkUrl = sql.NullString{String: watermark.Url, Valid: true}
+		}
+	}
+	if !params.WatermarkID.Valid && settings.WatermarkText == nil && !settings.SkipDefaultWatermark {
+		watermark, ok, err := userDefaultWatermark(ctx, dbQueries, user.ID)
+		if err != nil {
+			return params, err
+		}
+		if ok {
+			if watermark.Region != user.Region {
+				return params, &settingsError{http.StatusConflict, "default watermark is stored in another data region"}
+			}
+			params.WatermarkID = uuid.NullUUID{UUID: watermark.ID, Valid: true}
+			params.WatermarkUrl = sql.NullString{String: watermark.Url, Valid: true}
+		}
+	}
+	if settings.WatermarkFontID != nil {
+		font, err := dbQueries.GetUserFontByID(ctx, database.GetUserFontByIDParams{
+			ID:     uuid.MustParse(*settings.WatermarkFontID),
+			UserID: user.ID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return params, &settingsError{http.StatusBadRequest, "font not found"}
+			}
+			return params, err
+		}
+		if font.Region != user.Region {
+			return params, &settingsError{http.StatusBadRequest, "font is stored in another data region"}
+		}
+		params.WatermarkFontID = uuid.NullUUID{UUID: font.ID, Valid: true}
+	}
+	if settings.WatermarkPosition != nil {
+		params.WatermarkPosition = database.WatermarkPosition(*settings.WatermarkPosition)
+	}
+	if settings.WatermarkOpacity != nil {
+		params.WatermarkOpacity = int32(*settings.WatermarkOpacity)
+	}
+	if settings.WatermarkScale != nil {
+		params.WatermarkScale = int32(*settings.WatermarkScale)
+	}
+	if settings.WatermarkTileSpacing != nil {
+		params.WatermarkTileSpacing = int32(*settings.WatermarkTileSpacing)
+	}
+	if settings.OutputFormat != nil {
+		params.OutputFormat = database.OutputFormat(*settings.OutputFormat)
+	}
+	if settings.OutputQuality != nil {
+		params.OutputQuality = int32(*settings.OutputQuality)
+	}
+	return params, nil
+}
+
+// sourceImage is an original for the workers to fetch from SourceURL.
+type sourceImage struct {
+	SourceURL  string
+	Filename   string
+	ExternalID string
+}
+
+// createSourceBatch creates a batch of images the workers fetch from their
+// source URLs and starts it. Originals are stored by the worker that fetches
+// them, so the images start without a key.
+func (h *BatchHandler) createSourceBatch(c echo.Context, dbQueries *database.Queries, params database.CreateBatchParams, sources []sourceImage) error {
+	batch, err := dbQueries.CreateBatch(c.Request().Context(), params)
+	if err != nil {
+		return utils.RespondDBError(c, err, "batch")
+	}
+
+	imageTasks := make([]ImageTask, 0, len(sources))
+	accepted := make([]AcceptedUpload, 0, len(sources))
+	for _, source := range sources {
+		image, err := dbQueries.CreateImage(c.Request().Context(), database.CreateImageParams{
+			BatchID:    batch.ID,
+			Filename:   sql.NullString{String: source.Filename, Valid: source.Filename != ""},
+			ExternalID: sql.NullString{String: source.ExternalID, Valid: source.ExternalID != ""},
+			Redactions: json.RawMessage("[]"),
+			SourceUrl:  sql.NullString{String: source.SourceURL, Valid: true},
+		})
+		if isImageExternalIDTaken(err) {
+			return utils.RespondError(c, http.StatusConflict, fmt.Sprintf("external_id %s is already used by another image", source.ExternalID))
+		}
+		if err != nil {
+			return utils.RespondDBError(c, err, "image")
+		}
+		accepted = append(accepted, AcceptedUpload{Filename: source.Filename, ImageID: image.ID})
+		task := NewImageTask(image.ID)
+		task.OutputFormat = batch.OutputFormat
+		task.OutputQuality = int(batch.OutputQuality)
+		imageTasks = append(imageTasks, task)
+	}
+
+	if err := recordEvent(c.Request().Context(), dbQueries, batch, database.BatchEventTypeCreated); err != nil {
+		c.Logger().Errorf("failed to record batch event: %v", err)
+	}
+	status, _, err := h.startBatch(c, dbQueries, batch, imageTasks)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "batch created successfully", CreateBatchResponse{
+		ID:         batch.ID,
+		Status:     status,
+		Duplicates: []DuplicateUpload{},
+		Images:     accepted,
+		Rejected:   []RejectedUpload{},
+	})
+}
+
+// CreateFromURLs godoc
+// @Summary Create batch from URLs
+// @Description Create a batch from images hosted elsewhere, e.g. to migrate a catalog without downloading it first. Workers fetch each URL (http or https, public addresses only, max 50 MB), store it as the original and process it; images whose URL cannot be fetched or is not a JPEG, PNG, WebP or GIF are marked failed
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batch body CreateBatchFromURLsRequest true "Create Batch From URLs Request"
+// @Success 201 {object} utils.SuccessResponse{data=CreateBatchResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /batches/urls [post]
+func (h *BatchHandler) CreateFromURLs(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	var body CreateBatchFromURLsRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if _, err := h.config.Storage(user.Region); err != nil {
+		c.Logger().Errorf("failed to create batch: %v", err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	params, err := remoteBatchParams(c.Request().Context(), dbQueries, user, body.RemoteBatchSettings)
+	if err != nil {
+		return respondSettingsError(c, err)
+	}
+
+	sources := make([]sourceImage, 0, len(body.Images))
+	for _, remote := range body.Images {
+		filename := remote.Filename
+		if filename == "" {
+			if u, err := url.Parse(remote.URL); err == nil {
+				if base := path.Base(u.Path); base != "/" && base != "." {
+					filename = base
+				}
+			}
+		}
+		sources = append(sources, sourceImage{SourceURL: remote.URL, Filename: filename, ExternalID: remote.ExternalID})
+	}
+	return h.createSourceBatch(c, dbQueries, params, sources)
+}
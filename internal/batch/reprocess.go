@@ -0,0 +1,244 @@
+package batch
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// Reprocess godoc
+// @Summary Reprocess batch
+// @Description Run the originals of a batch through the pipeline again as a new batch, with a different watermark, preset or settings, e.g. to compare watermark styles side by side. The originals already in storage are reused, so nothing is uploaded again; the source batch and its processed images are left untouched. Settings of preset_id replace the source's, and the other fields replace both
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param batch body ReprocessBatchRequest true "Reprocess Batch Request"
+// @Success 201 {object} utils.SuccessResponse{data=CreateBatchResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/reprocess [post]
+// @Router /batches/{batchID}/clone [post]
+func (h *BatchHandler) Reprocess(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	var body ReprocessBatchRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	if body.WatermarkID != nil && body.WatermarkText != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "watermark_id cannot be combined with watermark_text")
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	source, err := dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if source.ArchiveStatus == database.BatchArchiveStatusArchived || source.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is archived")
+	}
+
+	params := database.CreateBatchParams{
+		UserID:               userID,
+		Name:                 source.Name,
+		WatermarkKey:         source.WatermarkKey,
+		WatermarkUrl:         source.WatermarkUrl,
+		PreserveFilenames:    source.PreserveFilenames,
+		CollisionPolicy:      source.CollisionPolicy,
+		WatermarkText:        source.WatermarkText,
+		WatermarkFontID:      source.WatermarkFontID,
+		WatermarkPosition:    source.WatermarkPosition,
+		OutputFormat:         source.OutputFormat,
+		OutputQuality:        source.OutputQuality,
+		WatermarkOpacity:     source.WatermarkOpacity,
+		WatermarkScale:       source.WatermarkScale,
+		MaxConcurrency:       source.MaxConcurrency,
+		WatermarkTileSpacing: source.WatermarkTileSpacing,
+		MaxWidth:             source.MaxWidth,
+		MaxHeight:            source.MaxHeight,
+		PreserveMetadata:     source.PreserveMetadata,
+		InvisibleWatermark:   source.InvisibleWatermark,
+		JpegProgressive:      source.JpegProgressive,
+		JpegSubsampling:      source.JpegSubsampling,
+		PngCompression:       source.PngCompression,
+		SimilarDedupe:        source.SimilarDedupe,
+		Transforms:           source.Transforms,
+		CropAspectRatio:      source.CropAspectRatio,
+		CropX:                source.CropX,
+		CropY:                source.CropY,
+		CropWidth:            source.CropWidth,
+		CropHeight:           source.CropHeight,
+		WatermarkID:          source.WatermarkID,
+		Pipeline:             source.Pipeline,
+		Region:               source.Region,
+		ReportCsv:            source.ReportCsv,
+	}
+	if body.Name != nil {
+		params.Name = sql.NullString{String: *body.Name, Valid: true}
+	}
+	if body.PresetID != nil {
+		preset, err := userPreset(c.Request().Context(), dbQueries, userID, *body.PresetID)
+		if err != nil {
+			return respondSettingsError(c, err)
+		}
+		applyPreset(&params, preset)
+		if body.WatermarkID == nil && body.WatermarkText == nil {
+			watermark, ok, err := presetWatermark(c.Request().Context(), dbQueries, preset, source.Region)
+			if err != nil {
+				return respondSettingsError(c, err)
+			}
+			if ok {
+				params.WatermarkID = uuid.NullUUID{UUID: watermark.ID, Valid: true}
+				params.WatermarkKey = sql.NullString{String: "", Valid: true}
+				params.WatermarkUrl = sql.NullString{String: watermark.Url, Valid: true}
+				params.WatermarkText = sql.NullString{}
+				params.WatermarkFontID = uuid.NullUUID{}
+			}
+		}
+	}
+	// A new watermark replaces the source's, whatever its kind. The
+	// watermark object of the source is shared rather than copied, like
+	// its originals.
+	if body.WatermarkID != nil {
+		watermark, err := dbQueries.GetUserWatermarkByID(c.Request().Context(), database.GetUserWatermarkByIDParams{
+			ID:     uuid.MustParse(*body.WatermarkID),
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.RespondError(c, http.StatusBadRequest, "watermark not found")
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if watermark.Region != source.Region {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark is stored in another data region")
+		}
+		params.WatermarkID = uuid.NullUUID{UUID: watermark.ID, Valid: true}
+		params.WatermarkKey = sql.NullString{String: "", Valid: true}
+		params.WatermarkUrl = sql.NullString{String: watermark.Url, Valid: true}
+		params.WatermarkText = sql.NullString{}
+		params.WatermarkFontID = uuid.NullUUID{}
+	}
+	if body.WatermarkText != nil {
+		params.WatermarkID = uuid.NullUUID{}
+		params.WatermarkKey = sql.NullString{String: "", Valid: true}
+		params.WatermarkUrl = sql.NullString{String: "", Valid: true}
+		params.WatermarkText = sql.NullString{String: *body.WatermarkText, Valid: true}
+	}
+	if body.WatermarkFontID != nil {
+		if !params.WatermarkText.Valid {
+			return utils.RespondError(c, http.StatusBadRequest, "watermark_font_id requires watermark_text")
+		}
+		font, err := dbQueries.GetUserFontByID(c.Request().Context(), database.GetUserFontByIDParams{
+			ID:     uuid.MustParse(*body.WatermarkFontID),
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.RespondError(c, http.StatusBadRequest, "font not found")
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if font.Region != source.Region {
+			return utils.RespondError(c, http.StatusBadRequest, "font is stored in another data region")
+		}
+		params.WatermarkFontID = uuid.NullUUID{UUID: font.ID, Valid: true}
+	}
+	if body.WatermarkPosition != nil {
+		params.WatermarkPosition = database.WatermarkPosition(*body.WatermarkPosition)
+	}
+	if body.WatermarkOpacity != nil {
+		params.WatermarkOpacity = int32(*body.WatermarkOpacity)
+	}
+	if body.WatermarkScale != nil {
+		params.WatermarkScale = int32(*body.WatermarkScale)
+	}
+	if body.WatermarkTileSpacing != nil {
+		params.WatermarkTileSpacing = int32(*body.WatermarkTileSpacing)
+	}
+	if body.OutputFormat != nil {
+		params.OutputFormat = database.OutputFormat(*body.OutputFormat)
+	}
+	if body.OutputQuality != nil {
+		params.OutputQuality = int32(*body.OutputQuality)
+	}
+
+	images, err := dbQueries.GetImagesByBatchID(c.Request().Context(), source.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	batch, err := dbQueries.CreateBatch(c.Request().Context(), params)
+	if err != nil {
+		return utils.RespondDBError(c, err, "batch")
+	}
+
+	// External IDs stay with the source's images.
+	var imageTasks []ImageTask
+	accepted := []AcceptedUpload{}
+	for _, img := range images {
+		if img.Status == database.ImageStatusCancelled {
+			continue
+		}
+		image, err := dbQueries.CreateImage(c.Request().Context(), database.CreateImageParams{
+			BatchID:                   batch.ID,
+			Key:                       img.Key,
+			OriginalUrl:               img.OriginalUrl,
+			SourceUrl:                 img.SourceUrl,
+			Filename:                  img.Filename,
+			ContentHash:               img.ContentHash,
+			WatermarkPositionOverride: img.WatermarkPositionOverride,
+			WatermarkOpacityOverride:  img.WatermarkOpacityOverride,
+			WatermarkScaleOverride:    img.WatermarkScaleOverride,
+			Redactions:                img.Redactions,
+		})
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		accepted = append(accepted, AcceptedUpload{Filename: image.Filename.String, ImageID: image.ID, Key: image.Key})
+		task := NewImageTask(image.ID)
+		task.OutputFormat = batch.OutputFormat
+		task.OutputQuality = int(batch.OutputQuality)
+		imageTasks = append(imageTasks, task)
+	}
+	if len(imageTasks) == 0 {
+		return utils.RespondError(c, http.StatusConflict, "batch has no images to reprocess")
+	}
+
+	if err := recordEvent(c.Request().Context(), dbQueries, batch, database.BatchEventTypeCreated); err != nil {
+		c.Logger().Errorf("failed to record batch event: %v", err)
+	}
+	status, _, err := h.startBatch(c, dbQueries, batch, imageTasks)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "batch reprocessing started", CreateBatchResponse{
+		ID:         batch.ID,
+		Status:     status,
+		Duplicates: []DuplicateUpload{},
+		Images:     accepted,
+		Rejected:   []RejectedUpload{},
+	})
+}
@@ -0,0 +1,124 @@
+package batch
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// maxImportObjects caps the images of one batch created from an S3 prefix.
+const maxImportObjects = 10000
+
+// CreateFromS3 godoc
+// @Summary Create batch from S3
+// @Description Create a batch from the JPEG, PNG, WebP and GIF objects (by extension) under a prefix of an S3 bucket an administrator opened for import to the user, so originals already in S3 need not be uploaded again. Workers copy each object (max 50 MB) into storage and process it; objects that cannot be read or are not images are marked failed. Settings are those of POST /batches/urls
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batch body CreateBatchFromS3Request true "Create Batch From S3 Request"
+// @Success 201 {object} utils.SuccessResponse{data=CreateBatchResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /batches/s3 [post]
+func (h *BatchHandler) CreateFromS3(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	var body CreateBatchFromS3Request
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	if !h.config.ImportAllowed(body.Bucket, body.Prefix) {
+		return utils.RespondError(c, http.StatusForbidden, "bucket or prefix is not open for import")
+	}
+
+	// The server-wide sources only bound what can be granted: each user
+	// imports from the sources granted to them, so nobody lists another
+	// tenant's objects.
+	sources, err := h.dbQueries.GetUserImportSources(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if !slices.ContainsFunc(sources, func(s database.UserImportSource) bool {
+		return utils.ImportSourceCovers(s.Bucket, s.Prefix, body.Bucket, body.Prefix)
+	}) {
+		return utils.RespondError(c, http.StatusForbidden, "bucket or prefix is not open for import")
+	}
+
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	storage, err := h.config.Storage(user.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to create batch: %v", err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	params, err := remoteBatchParams(c.Request().Context(), dbQueries, user, body.RemoteBatchSettings)
+	if err != nil {
+		return respondSettingsError(c, err)
+	}
+
+	// Workers read the objects with the client of the batch's region, so
+	// listing uses it too.
+	var images []sourceImage
+	paginator := s3.NewListObjectsV2Paginator(storage.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(body.Bucket),
+		Prefix: aws.String(body.Prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(c.Request().Context())
+		if err != nil {
+			c.Logger().Errorf("failed to list s3://%s/%s: %v", body.Bucket, body.Prefix, err)
+			return utils.RespondError(c, http.StatusBadRequest, "bucket could not be listed")
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if !isImportableKey(key) {
+				continue
+			}
+			if len(images) == maxImportObjects {
+				return utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("prefix holds more than %d images, import a narrower prefix", maxImportObjects))
+			}
+			images = append(images, sourceImage{
+				SourceURL: (&url.URL{Scheme: "s3", Host: body.Bucket, Path: "/" + key}).String(),
+				Filename:  path.Base(key),
+			})
+		}
+	}
+	if len(images) == 0 {
+		return utils.RespondError(c, http.StatusNotFound, "prefix holds no images")
+	}
+
+	return h.createSourceBatch(c, dbQueries, params, images)
+}
+
+// isImportableKey reports whether an object looks like an image the workers
+// can process, judged by its extension.
+func isImportableKey(key string) bool {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg", ".png", ".webp", ".gif":
+		return true
+	}
+	return false
+}
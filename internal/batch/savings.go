@@ -0,0 +1,66 @@
+package batch
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetSavings godoc
+// @Summary Get batch savings
+// @Description Summarize how much smaller the outputs of a batch are than its originals, overall and by original and output format. Only completed images count, including those linked to a similar image
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=BatchSavingsResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/savings [get]
+func (h *BatchHandler) GetSavings(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	rows, err := h.dbQueries.GetBatchSavings(c.Request().Context(), batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	response := BatchSavingsResponse{Formats: make([]FormatSavings, 0, len(rows))}
+	for _, row := range rows {
+		response.ImageCount += row.ImageCount
+		response.OriginalBytes += row.OriginalBytes
+		response.ProcessedBytes += row.ProcessedBytes
+		response.Formats = append(response.Formats, FormatSavings{
+			OriginalFormat:  row.OriginalFormat,
+			ProcessedFormat: row.ProcessedFormat,
+			ImageCount:      row.ImageCount,
+			OriginalBytes:   row.OriginalBytes,
+			ProcessedBytes:  row.ProcessedBytes,
+			SavedBytes:      row.OriginalBytes - row.ProcessedBytes,
+			SavedPercent:    savedPercent(row.OriginalBytes, row.ProcessedBytes),
+		})
+	}
+	response.SavedBytes = response.OriginalBytes - response.ProcessedBytes
+	response.SavedPercent = savedPercent(response.OriginalBytes, response.ProcessedBytes)
+
+	return utils.RespondJSON(c, http.StatusOK, "batch savings retrieved successfully", response)
+}
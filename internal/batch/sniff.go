@@ -0,0 +1,77 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+
+	_ "golang.org/x/image/webp"
+)
+
+// sniffLen is how much of a file http.DetectContentType looks at.
+const sniffLen = 512
+
+// mediaTypeAliases maps nonstandard types clients send to the one
+// http.DetectContentType reports for the same content.
+var mediaTypeAliases = map[string]string{
+	"image/jpg": "image/jpeg",
+}
+
+// uploadError is why an uploaded file was rejected, reported to the client
+// next to its filename. Retryable errors are not the file's fault.
+type uploadError struct {
+	reason    string
+	retryable bool
+}
+
+func (e *uploadError) Error() string {
+	return e.reason
+}
+
+// sniffUpload works out the media type of an uploaded file from its content
+// instead of trusting the Content-Type the client sent, and checks that the
+// image header decodes. A declared type that differs from the content marks
+// the file as mislabeled; a missing or generic one is not held against it.
+// src is rewound. Errors the client can fix are *uploadError.
+func sniffUpload(src io.ReadSeeker, declared string, supported func(string) bool) (string, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	mediaType := http.DetectContentType(head[:n])
+	if !supported(mediaType) {
+		return "", &uploadError{reason: fmt.Sprintf("unsupported file type %s", mediaType)}
+	}
+
+	if declared != "" {
+		declaredType, _, err := mime.ParseMediaType(declared)
+		if err != nil {
+			return "", &uploadError{reason: "invalid Content-Type"}
+		}
+		if alias, ok := mediaTypeAliases[declaredType]; ok {
+			declaredType = alias
+		}
+		if declaredType != "application/octet-stream" && declaredType != mediaType {
+			return "", &uploadError{reason: fmt.Sprintf("sent as %s but the content is %s", declaredType, mediaType)}
+		}
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	cfg, _, err := image.DecodeConfig(src)
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return "", &uploadError{reason: "corrupt image: the header cannot be read"}
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return mediaType, nil
+}
@@ -0,0 +1,70 @@
+package batch
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffUpload(t *testing.T) {
+	photo := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	photo.Set(1, 1, color.RGBA{200, 30, 30, 255})
+	var jpegBuf, pngBuf, gifBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegBuf, photo, nil))
+	require.NoError(t, png.Encode(&pngBuf, photo))
+	require.NoError(t, gif.Encode(&gifBuf, photo, nil))
+	// A PNG signature followed by a header that does not decode.
+	corruptPNG := append(append([]byte{}, pngBuf.Bytes()[:8]...), bytes.Repeat([]byte{0xff}, 64)...)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		declared  string
+		supported func(string) bool
+		want      string
+		reason    string
+	}{
+		{name: "jpeg", data: jpegBuf.Bytes(), declared: "image/jpeg", want: "image/jpeg"},
+		{name: "jpg alias", data: jpegBuf.Bytes(), declared: "image/jpg", want: "image/jpeg"},
+		{name: "parameters", data: pngBuf.Bytes(), declared: "image/png; name=logo.png", want: "image/png"},
+		{name: "missing Content-Type", data: pngBuf.Bytes(), want: "image/png"},
+		{name: "generic Content-Type", data: jpegBuf.Bytes(), declared: "application/octet-stream", want: "image/jpeg"},
+		{name: "gif upload", data: gifBuf.Bytes(), declared: "image/gif", supported: isSupportedUploadType, want: "image/gif"},
+		{name: "gif watermark", data: gifBuf.Bytes(), declared: "image/gif", reason: "unsupported file type image/gif"},
+		{name: "not an image", data: []byte("hello, world"), declared: "image/png", reason: "unsupported file type text/plain; charset=utf-8"},
+		{name: "mislabeled", data: pngBuf.Bytes(), declared: "image/jpeg", reason: "sent as image/jpeg but the content is image/png"},
+		{name: "jpg alias on a png", data: pngBuf.Bytes(), declared: "image/jpg", reason: "sent as image/jpeg but the content is image/png"},
+		{name: "invalid Content-Type", data: jpegBuf.Bytes(), declared: "image/", reason: "invalid Content-Type"},
+		{name: "corrupt header", data: corruptPNG, declared: "image/png", reason: "corrupt image: the header cannot be read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supported := tt.supported
+			if supported == nil {
+				supported = isSupportedImageType
+			}
+			src := bytes.NewReader(tt.data)
+			mediaType, err := sniffUpload(src, tt.declared, supported)
+			if tt.reason != "" {
+				var uploadErr *uploadError
+				require.ErrorAs(t, err, &uploadErr)
+				assert.Equal(t, tt.reason, uploadErr.reason)
+				assert.False(t, uploadErr.retryable)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, mediaType)
+			rest, err := io.ReadAll(src)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, rest, "src is rewound")
+		})
+	}
+}
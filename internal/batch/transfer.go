@@ -0,0 +1,289 @@
+package batch
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// CreateTransfer godoc
+// @Summary Offer batch to another user
+// @Description Offer a batch to another account, e.g. when a freelancer hands a project to the studio. The recipient is emailed and the batch moves to them, with its images, comments and deliveries, once they accept; until then it stays yours and the offer can be withdrawn. Only batches with nothing pending or processing can be offered, and only to users in the same data region. A batch has at most one open offer
+// @Tags batches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Param transfer body CreateBatchTransferRequest true "Create Batch Transfer Request"
+// @Success 201 {object} utils.SuccessResponse{data=BatchTransferResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/transfer [post]
+func (h *BatchHandler) CreateTransfer(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	var body CreateBatchTransferRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	batch, err := dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	progress, err := Progress(c.Request().Context(), dbQueries, batch)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if batch.WaitingSince.Valid || progress.Counts.Pending > 0 || progress.Counts.Processing > 0 {
+		return utils.RespondError(c, http.StatusConflict, "batch is still processing")
+	}
+
+	recipient, err := dbQueries.GetUsersByEmail(c.Request().Context(), body.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "recipient not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if recipient.ID == userID {
+		return utils.RespondError(c, http.StatusBadRequest, "batch cannot be transferred to yourself")
+	}
+	if recipient.Region != batch.Region {
+		return utils.RespondError(c, http.StatusConflict, "recipient's data is stored in another data region")
+	}
+
+	transfer, err := dbQueries.CreateBatchTransfer(c.Request().Context(), database.CreateBatchTransferParams{
+		BatchID:    batch.ID,
+		FromUserID: userID,
+		ToUserID:   recipient.ID,
+		Message:    body.Message,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "batch transfer")
+	}
+	row, err := dbQueries.GetBatchTransferByID(c.Request().Context(), transfer.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	utils.AfterCommit(c.Request().Context(), func() {
+		err := h.config.Mailer.Send(c.Request().Context(), recipient.Email, "A batch was offered to you",
+			fmt.Sprintf("%s wants to transfer the batch %q to your Image Go account.\n\n%s\n\nAccept or decline it at %s/transfers.",
+				row.FromEmail, row.BatchName.String, body.Message, strings.TrimRight(h.config.AppURL, "/")))
+		if err != nil {
+			c.Logger().Errorf("failed to send transfer %s: %v", transfer.ID, err)
+		}
+	})
+
+	return utils.RespondJSON(c, http.StatusCreated, "batch transfer offered successfully", toBatchTransferResponse(row))
+}
+
+// CancelTransfer godoc
+// @Summary Withdraw batch transfer
+// @Description Withdraw the open transfer offer of a batch before the recipient accepts it
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 200 {object} utils.SuccessResponse{data=BatchTransferResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/transfer [delete]
+func (h *BatchHandler) CancelTransfer(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	transfer, err := h.dbQueries.CancelBatchTransfer(c.Request().Context(), database.CancelBatchTransferParams{
+		BatchID:    batchUUID,
+		FromUserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch transfer not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	row, err := h.dbQueries.GetBatchTransferByID(c.Request().Context(), transfer.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "batch transfer withdrawn successfully", toBatchTransferResponse(row))
+}
+
+// GetTransfers godoc
+// @Summary Get batch transfers
+// @Description Retrieve the batch transfers offered to or by the authenticated user, newest first. The status of each records both sides: the sender consented when offering it (created_at), the recipient when accepting or declining it (responded_at)
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Filter by status" Enums(pending, accepted, declined, cancelled)
+// @Success 200 {object} utils.SuccessResponse{data=[]BatchTransferResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /transfers [get]
+func (h *BatchHandler) GetTransfers(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	params := database.GetUserBatchTransfersParams{UserID: userID}
+	if status := c.QueryParam("status"); status != "" {
+		params.Status = database.NullBatchTransferStatus{BatchTransferStatus: database.BatchTransferStatus(status), Valid: true}
+		if !params.Status.BatchTransferStatus.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid status")
+		}
+	}
+
+	transfers, err := h.dbQueries.GetUserBatchTransfers(c.Request().Context(), params)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	transfersRes := make([]BatchTransferResponse, len(transfers))
+	for i, transfer := range transfers {
+		transfersRes[i] = toBatchTransferResponse(database.GetBatchTransferByIDRow(transfer))
+	}
+	return utils.RespondJSON(c, http.StatusOK, "batch transfers retrieved successfully", transfersRes)
+}
+
+// AcceptTransfer godoc
+// @Summary Accept batch transfer
+// @Description Accept a batch offered to you. The batch, its images and their files become yours and disappear from the sender's account; objects stay where they are stored. It no longer refers to the library watermark or font of the sender. Fails while the batch is processing again, or when you already have a batch or image with one of its external_ids
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param transferID path string true "Transfer ID"
+// @Success 200 {object} utils.SuccessResponse{data=BatchTransferResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /transfers/{transferID}/accept [post]
+func (h *BatchHandler) AcceptTransfer(c echo.Context) error {
+	return h.respondTransfer(c, database.BatchTransferStatusAccepted)
+}
+
+// DeclineTransfer godoc
+// @Summary Decline batch transfer
+// @Description Decline a batch offered to you; it stays with the sender
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param transferID path string true "Transfer ID"
+// @Success 200 {object} utils.SuccessResponse{data=BatchTransferResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /transfers/{transferID}/decline [post]
+func (h *BatchHandler) DeclineTransfer(c echo.Context) error {
+	return h.respondTransfer(c, database.BatchTransferStatusDeclined)
+}
+
+// respondTransfer records the recipient's answer to a pending transfer and
+// moves the batch when it is accepted.
+func (h *BatchHandler) respondTransfer(c echo.Context, status database.BatchTransferStatus) error {
+	userID := c.Get("userID").(uuid.UUID)
+	transferUUID := utils.ParamUUID(c, "transferID")
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	transfer, err := dbQueries.LockIncomingBatchTransfer(c.Request().Context(), database.LockIncomingBatchTransferParams{
+		ID:       transferUUID,
+		ToUserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch transfer not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	if status == database.BatchTransferStatusAccepted {
+		user, err := dbQueries.GetUserByID(c.Request().Context(), userID)
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		batch, err := dbQueries.TransferBatch(c.Request().Context(), database.TransferBatchParams{
+			ID:         transfer.BatchID,
+			FromUserID: transfer.FromUserID,
+			ToUserID:   userID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.RespondError(c, http.StatusConflict, "batch is processing or no longer available")
+			}
+			if isImageExternalIDTaken(err) {
+				return utils.RespondError(c, http.StatusConflict, "you already have an image with the external_id of one of its images")
+			}
+			if errors.Is(utils.MapDBError(err), utils.ErrConflict) {
+				return utils.RespondError(c, http.StatusConflict, "you already have a batch with this external_id")
+			}
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		// The user may have moved since the offer.
+		if batch.Region != user.Region {
+			return utils.RespondError(c, http.StatusConflict, "batch is stored in another data region")
+		}
+		if err := recordEvent(c.Request().Context(), dbQueries, batch, database.BatchEventTypeTransferred); err != nil {
+			c.Logger().Errorf("failed to record batch event: %v", err)
+		}
+	}
+
+	transfer, err = dbQueries.RespondBatchTransfer(c.Request().Context(), database.RespondBatchTransferParams{
+		ID:     transfer.ID,
+		Status: status,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	row, err := dbQueries.GetBatchTransferByID(c.Request().Context(), transfer.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "batch transfer "+string(status)+" successfully", toBatchTransferResponse(row))
+}
+
+func toBatchTransferResponse(transfer database.GetBatchTransferByIDRow) BatchTransferResponse {
+	res := BatchTransferResponse{
+		ID:         transfer.ID,
+		BatchID:    transfer.BatchID,
+		BatchName:  transfer.BatchName.String,
+		FromUserID: transfer.FromUserID,
+		FromEmail:  transfer.FromEmail,
+		ToUserID:   transfer.ToUserID,
+		ToEmail:    transfer.ToEmail,
+		Message:    transfer.Message,
+		Status:     transfer.Status,
+		CreatedAt:  transfer.CreatedAt,
+	}
+	if transfer.RespondedAt.Valid {
+		res.RespondedAt = &transfer.RespondedAt.Time
+	}
+	return res
+}
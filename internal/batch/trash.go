@@ -0,0 +1,114 @@
+package batch
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetTrash godoc
+// @Summary Get deleted batches
+// @Description Retrieve the authenticated user's deleted batches, most recently deleted first. They can be restored with POST /batches/{batchID}/restore until purge_at, when they are removed with their files
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} utils.SuccessResponse{data=[]TrashedBatchResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /trash [get]
+func (h *BatchHandler) GetTrash(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	page, limit, err := utils.GetPagination(c)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	batches, err := h.dbQueries.GetUserTrash(c.Request().Context(), database.GetUserTrashParams{
+		UserID: userID,
+		Limit:  int32(limit),
+		Offset: int32((page - 1) * limit),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	total, err := h.dbQueries.CountUserTrash(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	res := make([]TrashedBatchResponse, len(batches))
+	for i, b := range batches {
+		res[i] = TrashedBatchResponse{
+			ID:         b.ID,
+			Name:       b.Name.String,
+			ExternalID: b.ExternalID.String,
+			CreatedAt:  b.CreatedAt,
+			DeletedAt:  b.DeletedAt.Time,
+			PurgeAt:    b.DeletedAt.Time.Add(TrashRetention),
+		}
+	}
+
+	return utils.RespondPaginated(c, http.StatusOK, "deleted batches retrieved successfully", res, utils.NewPaginationMeta(page, limit, int(total)))
+}
+
+// FailureBatchDeleted is the failure reason of images whose task found their
+// batch in the trash. Restoring the batch queues them again.
+const FailureBatchDeleted = "batch was deleted"
+
+// restoreDeleted takes a batch of the user out of the trash. Its external ID,
+// or that of one of its images, may have been reused meanwhile, which is
+// answered as a conflict. Pending images and those failed because the batch
+// was deleted are queued again, unless the batch is waiting to start.
+func (h *BatchHandler) restoreDeleted(c echo.Context, userID, batchID uuid.UUID) error {
+	ctx := c.Request().Context()
+	dbQueries := utils.Queries(ctx, h.dbQueries)
+
+	batch, err := dbQueries.RestoreDeletedBatch(ctx, database.RestoreDeletedBatchParams{
+		ID:     batchID,
+		UserID: userID,
+	})
+	if isImageExternalIDTaken(err) {
+		return utils.RespondError(c, http.StatusConflict, "another image has taken the external_id of one of its images")
+	}
+	if errors.Is(utils.MapDBError(err), utils.ErrConflict) {
+		return utils.RespondError(c, http.StatusConflict, "another batch has taken its external_id")
+	}
+	if err != nil {
+		return utils.RespondDBError(c, err, "batch")
+	}
+
+	requeued, err := dbQueries.RequeueRestoredBatchImages(ctx, database.RequeueRestoredBatchImagesParams{
+		BatchID:       batch.ID,
+		FailureReason: sql.NullString{String: FailureBatchDeleted, Valid: true},
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	// Images of a waiting batch are queued when the batch starts.
+	if len(requeued) > 0 && !batch.WaitingSince.Valid {
+		tasks := make([]ImageTask, len(requeued))
+		for i, imageID := range requeued {
+			tasks[i] = NewImageTask(imageID)
+			tasks[i].OutputFormat = batch.OutputFormat
+			tasks[i].OutputQuality = int(batch.OutputQuality)
+		}
+		if _, err := h.publishAfterCommit(c, dbQueries, userID, batch.Region, tasks); err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	images, err := dbQueries.GetImagesByBatchID(ctx, batch.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "batch restored successfully", newBatchResponse(batch, images))
+}
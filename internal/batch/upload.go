@@ -0,0 +1,243 @@
+package batch
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const uploadTokenTTL = 15 * time.Minute
+
+// CreateUploadToken godoc
+// @Summary Create upload token
+// @Description Issue a short-lived token that only allows uploading images into this batch through the direct upload endpoints
+// @Tags batches
+// @Produce json
+// @Security BearerAuth
+// @Param batchID path string true "Batch ID"
+// @Success 201 {object} utils.SuccessResponse{data=UploadTokenResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /batches/{batchID}/upload-token [post]
+func (h *BatchHandler) CreateUploadToken(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	batchUUID := utils.ParamUUID(c, "batchID")
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	token, err := utils.GenerateUploadJWT(userID, batch.ID, h.config.JwtSecret, uploadTokenTTL)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "upload token created successfully", UploadTokenResponse{
+		UploadToken: token,
+		ExpiresAt:   time.Now().UTC().Add(uploadTokenTTL),
+	})
+}
+
+// PresignUpload godoc
+// @Summary Presign direct upload
+// @Description Get a presigned S3 URL to PUT one image for the batch the upload token is scoped to
+// @Tags uploads
+// @Accept json
+// @Produce json
+// @Security UploadToken
+// @Param presign body PresignUploadRequest true "Presign Upload Request"
+// @Success 201 {object} utils.SuccessResponse{data=PresignUploadResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /uploads/presign [post]
+func (h *BatchHandler) PresignUpload(c echo.Context) error {
+	batchID := c.Get("batchID").(uuid.UUID)
+
+	var body PresignUploadRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	batch, err := h.dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchID,
+		UserID: c.Get("userID").(uuid.UUID),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	storage, err := h.config.Storage(batch.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to presign upload for batch %s: %v", batchID, err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	fileName := directUploadPrefix(batchID) + utils.GetAssetPath(body.ContentType)
+	presignClient := s3.NewPresignClient(storage.S3Client)
+	req, err := presignClient.PresignPutObject(c.Request().Context(), &s3.PutObjectInput{
+		Bucket:      aws.String(storage.S3Bucket),
+		Key:         aws.String(fileName),
+		ContentType: aws.String(body.ContentType),
+	}, s3.WithPresignExpires(uploadTokenTTL))
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "upload presigned successfully", PresignUploadResponse{
+		Key:       fileName,
+		UploadURL: req.URL,
+		ExpiresAt: time.Now().UTC().Add(uploadTokenTTL),
+	})
+}
+
+// ConfirmUpload godoc
+// @Summary Confirm direct upload
+// @Description Register an image uploaded through a presigned URL and queue it for processing. Confirming a key again returns the image it already registered.
+// @Tags uploads
+// @Accept json
+// @Produce json
+// @Security UploadToken
+// @Param confirm body ConfirmUploadRequest true "Confirm Upload Request"
+// @Success 200 {object} utils.SuccessResponse{data=ImageResponse}
+// @Success 201 {object} utils.SuccessResponse{data=ImageResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /uploads/confirm [post]
+func (h *BatchHandler) ConfirmUpload(c echo.Context) error {
+	batchID := c.Get("batchID").(uuid.UUID)
+
+	var body ConfirmUploadRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	if !strings.HasPrefix(body.Key, directUploadPrefix(batchID)) {
+		return utils.RespondError(c, http.StatusBadRequest, "key does not belong to this batch")
+	}
+
+	dbQueries := utils.Queries(c.Request().Context(), h.dbQueries)
+	batch, err := dbQueries.GetUserBatchByID(c.Request().Context(), database.GetUserBatchByIDParams{
+		ID:     batchID,
+		UserID: c.Get("userID").(uuid.UUID),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "batch not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	storage, err := h.config.Storage(batch.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to confirm upload for batch %s: %v", batchID, err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	// Confirmations of the batch are serialized so a retried confirm finds
+	// the image the first one registered instead of adding another.
+	if err := dbQueries.LockBatch(c.Request().Context(), batchID); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	existing, err := dbQueries.GetBatchImageByKey(c.Request().Context(), database.GetBatchImageByKeyParams{
+		BatchID: batchID,
+		Key:     body.Key,
+	})
+	if err == nil {
+		return utils.RespondJSON(c, http.StatusOK, "upload already confirmed", NewImageResponse(existing))
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	contentHash, err := hashObject(c.Request().Context(), storage, body.Key)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "uploaded object not found")
+	}
+
+	image, err := dbQueries.CreateImage(c.Request().Context(), database.CreateImageParams{
+		BatchID:     batchID,
+		Key:         body.Key,
+		OriginalUrl: utils.GetObjectURL(storage.S3CfDistribution, body.Key),
+		Filename:    sql.NullString{String: body.Filename, Valid: body.Filename != ""},
+		ContentHash: sql.NullString{String: contentHash, Valid: true},
+		ExternalID:  sql.NullString{String: body.ExternalID, Valid: body.ExternalID != ""},
+		Redactions:  json.RawMessage("[]"),
+	})
+	if isImageExternalIDTaken(err) {
+		return utils.RespondError(c, http.StatusConflict, "external_id is already used by another image")
+	}
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	// Images of a waiting batch are queued when the batch starts.
+	if !batch.WaitingSince.Valid {
+		if _, err := h.publishAfterCommit(c, dbQueries, batch.UserID, batch.Region, []ImageTask{NewImageTask(image.ID)}); err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "upload confirmed successfully", NewImageResponse(image))
+}
+
+// directUploadPrefix namespaces direct uploads by batch so a token cannot confirm objects of another batch.
+func directUploadPrefix(batchID uuid.UUID) string {
+	return "raw/" + batchID.String() + "/"
+}
+
+// hashObject returns the hex SHA-256 of an uploaded object, as stored in
+// content_hash for images uploaded through the batch form.
+func hashObject(ctx context.Context, storage utils.RegionStorage, key string) (string, error) {
+	obj, err := storage.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(storage.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer obj.Body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, obj.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
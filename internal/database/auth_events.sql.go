@@ -0,0 +1,128 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: auth_events.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countAuthEventsByEmailAndIPSince = `-- name: CountAuthEventsByEmailAndIPSince :one
+SELECT COUNT(*) FROM auth_events WHERE email = $1 AND ip_address = $2 AND reason = $3 AND created_at > $4
+`
+
+type CountAuthEventsByEmailAndIPSinceParams struct {
+	Email     sql.NullString
+	IpAddress sql.NullString
+	Reason    string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountAuthEventsByEmailAndIPSince(ctx context.Context, arg CountAuthEventsByEmailAndIPSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAuthEventsByEmailAndIPSince,
+		arg.Email,
+		arg.IpAddress,
+		arg.Reason,
+		arg.CreatedAt,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAuthEventsByReason = `-- name: CountAuthEventsByReason :many
+SELECT reason, COUNT(*) AS count FROM auth_events GROUP BY reason ORDER BY reason
+`
+
+type CountAuthEventsByReasonRow struct {
+	Reason string
+	Count  int64
+}
+
+func (q *Queries) CountAuthEventsByReason(ctx context.Context) ([]CountAuthEventsByReasonRow, error) {
+	rows, err := q.db.QueryContext(ctx, countAuthEventsByReason)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountAuthEventsByReasonRow
+	for rows.Next() {
+		var i CountAuthEventsByReasonRow
+		if err := rows.Scan(&i.Reason, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createAuthEvent = `-- name: CreateAuthEvent :exec
+INSERT INTO auth_events(user_id, email, reason, ip_address) VALUES ($1, $2, $3, $4)
+`
+
+type CreateAuthEventParams struct {
+	UserID    uuid.NullUUID
+	Email     sql.NullString
+	Reason    string
+	IpAddress sql.NullString
+}
+
+func (q *Queries) CreateAuthEvent(ctx context.Context, arg CreateAuthEventParams) error {
+	_, err := q.db.ExecContext(ctx, createAuthEvent,
+		arg.UserID,
+		arg.Email,
+		arg.Reason,
+		arg.IpAddress,
+	)
+	return err
+}
+
+const getAuthEventStats = `-- name: GetAuthEventStats :many
+SELECT reason, date_trunc($1::text, created_at)::timestamp AS bucket_start, COUNT(*) AS count FROM auth_events WHERE created_at >= $2 GROUP BY reason, bucket_start ORDER BY bucket_start DESC, reason
+`
+
+type GetAuthEventStatsParams struct {
+	Bucket string
+	Since  time.Time
+}
+
+type GetAuthEventStatsRow struct {
+	Reason      string
+	BucketStart time.Time
+	Count       int64
+}
+
+func (q *Queries) GetAuthEventStats(ctx context.Context, arg GetAuthEventStatsParams) ([]GetAuthEventStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAuthEventStats, arg.Bucket, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAuthEventStatsRow
+	for rows.Next() {
+		var i GetAuthEventStatsRow
+		if err := rows.Scan(&i.Reason, &i.BucketStart, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
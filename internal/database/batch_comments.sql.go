@@ -0,0 +1,128 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: batch_comments.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createBatchComment = `-- name: CreateBatchComment :one
+INSERT INTO batch_comments(batch_id, image_id, parent_id, user_id, body) VALUES ($1, $2, $3, $4, $5) RETURNING id, batch_id, image_id, parent_id, user_id, body, created_at, updated_at, deleted_at
+`
+
+type CreateBatchCommentParams struct {
+	BatchID  uuid.UUID
+	ImageID  uuid.NullUUID
+	ParentID uuid.NullUUID
+	UserID   uuid.UUID
+	Body     string
+}
+
+func (q *Queries) CreateBatchComment(ctx context.Context, arg CreateBatchCommentParams) (BatchComment, error) {
+	row := q.db.QueryRowContext(ctx, createBatchComment,
+		arg.BatchID,
+		arg.ImageID,
+		arg.ParentID,
+		arg.UserID,
+		arg.Body,
+	)
+	var i BatchComment
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.ImageID,
+		&i.ParentID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteBatchCommentByID = `-- name: DeleteBatchCommentByID :execrows
+UPDATE batch_comments SET deleted_at = NOW() WHERE id = $1 AND batch_id = $2 AND user_id = $3 AND deleted_at IS NULL
+`
+
+type DeleteBatchCommentByIDParams struct {
+	ID      uuid.UUID
+	BatchID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) DeleteBatchCommentByID(ctx context.Context, arg DeleteBatchCommentByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBatchCommentByID, arg.ID, arg.BatchID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getBatchCommentByID = `-- name: GetBatchCommentByID :one
+SELECT id, batch_id, image_id, parent_id, user_id, body, created_at, updated_at, deleted_at FROM batch_comments WHERE id = $1 AND batch_id = $2 AND deleted_at IS NULL
+`
+
+type GetBatchCommentByIDParams struct {
+	ID      uuid.UUID
+	BatchID uuid.UUID
+}
+
+func (q *Queries) GetBatchCommentByID(ctx context.Context, arg GetBatchCommentByIDParams) (BatchComment, error) {
+	row := q.db.QueryRowContext(ctx, getBatchCommentByID, arg.ID, arg.BatchID)
+	var i BatchComment
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.ImageID,
+		&i.ParentID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getBatchComments = `-- name: GetBatchComments :many
+SELECT id, batch_id, image_id, parent_id, user_id, body, created_at, updated_at, deleted_at FROM batch_comments WHERE batch_id = $1 AND deleted_at IS NULL ORDER BY created_at
+`
+
+func (q *Queries) GetBatchComments(ctx context.Context, batchID uuid.UUID) ([]BatchComment, error) {
+	rows, err := q.db.QueryContext(ctx, getBatchComments, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BatchComment
+	for rows.Next() {
+		var i BatchComment
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.ImageID,
+			&i.ParentID,
+			&i.UserID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,294 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: batch_deliveries.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueBatchDeliveries = `-- name: ClaimDueBatchDeliveries :many
+UPDATE batch_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $1::int) WHERE d.id IN (SELECT q.id FROM batch_deliveries q WHERE q.sent_at IS NULL AND q.failed_at IS NULL AND q.next_attempt_at <= NOW() ORDER BY q.next_attempt_at LIMIT $2 FOR UPDATE SKIP LOCKED) RETURNING d.id, d.batch_id, d.user_id, d.recipient, d.token_hash, d.subject, d.message, d.reply_to, d.logo_url, d.expires_at, d.sent_at, d.open_count, d.first_opened_at, d.last_opened_at, d.download_count, d.last_downloaded_at, d.created_at, d.send_attempts, d.next_attempt_at, d.failed_at
+`
+
+type ClaimDueBatchDeliveriesParams struct {
+	LeaseSeconds int32
+	PageLimit    int32
+}
+
+func (q *Queries) ClaimDueBatchDeliveries(ctx context.Context, arg ClaimDueBatchDeliveriesParams) ([]BatchDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueBatchDeliveries, arg.LeaseSeconds, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BatchDelivery
+	for rows.Next() {
+		var i BatchDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.UserID,
+			&i.Recipient,
+			&i.TokenHash,
+			&i.Subject,
+			&i.Message,
+			&i.ReplyTo,
+			&i.LogoUrl,
+			&i.ExpiresAt,
+			&i.SentAt,
+			&i.OpenCount,
+			&i.FirstOpenedAt,
+			&i.LastOpenedAt,
+			&i.DownloadCount,
+			&i.LastDownloadedAt,
+			&i.CreatedAt,
+			&i.SendAttempts,
+			&i.NextAttemptAt,
+			&i.FailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUserBatchDeliveriesSince = `-- name: CountUserBatchDeliveriesSince :one
+SELECT COUNT(*) FROM batch_deliveries WHERE user_id = $1 AND created_at > $2
+`
+
+type CountUserBatchDeliveriesSinceParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountUserBatchDeliveriesSince(ctx context.Context, arg CountUserBatchDeliveriesSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserBatchDeliveriesSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createBatchDelivery = `-- name: CreateBatchDelivery :one
+INSERT INTO batch_deliveries(batch_id, user_id, recipient, token_hash, subject, message, reply_to, logo_url, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, batch_id, user_id, recipient, token_hash, subject, message, reply_to, logo_url, expires_at, sent_at, open_count, first_opened_at, last_opened_at, download_count, last_downloaded_at, created_at, send_attempts, next_attempt_at, failed_at
+`
+
+type CreateBatchDeliveryParams struct {
+	BatchID   uuid.UUID
+	UserID    uuid.UUID
+	Recipient string
+	TokenHash string
+	Subject   string
+	Message   string
+	ReplyTo   sql.NullString
+	LogoUrl   sql.NullString
+	ExpiresAt time.Time
+}
+
+func (q *Queries) CreateBatchDelivery(ctx context.Context, arg CreateBatchDeliveryParams) (BatchDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createBatchDelivery,
+		arg.BatchID,
+		arg.UserID,
+		arg.Recipient,
+		arg.TokenHash,
+		arg.Subject,
+		arg.Message,
+		arg.ReplyTo,
+		arg.LogoUrl,
+		arg.ExpiresAt,
+	)
+	var i BatchDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.UserID,
+		&i.Recipient,
+		&i.TokenHash,
+		&i.Subject,
+		&i.Message,
+		&i.ReplyTo,
+		&i.LogoUrl,
+		&i.ExpiresAt,
+		&i.SentAt,
+		&i.OpenCount,
+		&i.FirstOpenedAt,
+		&i.LastOpenedAt,
+		&i.DownloadCount,
+		&i.LastDownloadedAt,
+		&i.CreatedAt,
+		&i.SendAttempts,
+		&i.NextAttemptAt,
+		&i.FailedAt,
+	)
+	return i, err
+}
+
+const failBatchDelivery = `-- name: FailBatchDelivery :exec
+UPDATE batch_deliveries SET send_attempts = send_attempts + 1, failed_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) FailBatchDelivery(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, failBatchDelivery, id)
+	return err
+}
+
+const getBatchDeliveries = `-- name: GetBatchDeliveries :many
+SELECT id, batch_id, user_id, recipient, token_hash, subject, message, reply_to, logo_url, expires_at, sent_at, open_count, first_opened_at, last_opened_at, download_count, last_downloaded_at, created_at, send_attempts, next_attempt_at, failed_at FROM batch_deliveries WHERE batch_id = $1 ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) GetBatchDeliveries(ctx context.Context, batchID uuid.UUID) ([]BatchDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getBatchDeliveries, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BatchDelivery
+	for rows.Next() {
+		var i BatchDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.UserID,
+			&i.Recipient,
+			&i.TokenHash,
+			&i.Subject,
+			&i.Message,
+			&i.ReplyTo,
+			&i.LogoUrl,
+			&i.ExpiresAt,
+			&i.SentAt,
+			&i.OpenCount,
+			&i.FirstOpenedAt,
+			&i.LastOpenedAt,
+			&i.DownloadCount,
+			&i.LastDownloadedAt,
+			&i.CreatedAt,
+			&i.SendAttempts,
+			&i.NextAttemptAt,
+			&i.FailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBatchDeliveryByTokenHash = `-- name: GetBatchDeliveryByTokenHash :one
+SELECT d.id, d.batch_id, d.user_id, d.recipient, d.token_hash, d.subject, d.message, d.reply_to, d.logo_url, d.expires_at, d.sent_at, d.open_count, d.first_opened_at, d.last_opened_at, d.download_count, d.last_downloaded_at, d.created_at, d.send_attempts, d.next_attempt_at, d.failed_at, b.name AS batch_name, b.archive_status FROM batch_deliveries d INNER JOIN batches b ON b.id = d.batch_id WHERE d.token_hash = $1 AND d.sent_at IS NOT NULL AND d.expires_at > NOW() AND b.deleted_at IS NULL
+`
+
+type GetBatchDeliveryByTokenHashRow struct {
+	ID               uuid.UUID
+	BatchID          uuid.UUID
+	UserID           uuid.UUID
+	Recipient        string
+	TokenHash        string
+	Subject          string
+	Message          string
+	ReplyTo          sql.NullString
+	LogoUrl          sql.NullString
+	ExpiresAt        time.Time
+	SentAt           sql.NullTime
+	OpenCount        int32
+	FirstOpenedAt    sql.NullTime
+	LastOpenedAt     sql.NullTime
+	DownloadCount    int32
+	LastDownloadedAt sql.NullTime
+	CreatedAt        time.Time
+	SendAttempts     int32
+	NextAttemptAt    time.Time
+	FailedAt         sql.NullTime
+	BatchName        sql.NullString
+	ArchiveStatus    BatchArchiveStatus
+}
+
+func (q *Queries) GetBatchDeliveryByTokenHash(ctx context.Context, tokenHash string) (GetBatchDeliveryByTokenHashRow, error) {
+	row := q.db.QueryRowContext(ctx, getBatchDeliveryByTokenHash, tokenHash)
+	var i GetBatchDeliveryByTokenHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.UserID,
+		&i.Recipient,
+		&i.TokenHash,
+		&i.Subject,
+		&i.Message,
+		&i.ReplyTo,
+		&i.LogoUrl,
+		&i.ExpiresAt,
+		&i.SentAt,
+		&i.OpenCount,
+		&i.FirstOpenedAt,
+		&i.LastOpenedAt,
+		&i.DownloadCount,
+		&i.LastDownloadedAt,
+		&i.CreatedAt,
+		&i.SendAttempts,
+		&i.NextAttemptAt,
+		&i.FailedAt,
+		&i.BatchName,
+		&i.ArchiveStatus,
+	)
+	return i, err
+}
+
+const markBatchDeliverySent = `-- name: MarkBatchDeliverySent :exec
+UPDATE batch_deliveries SET send_attempts = send_attempts + 1, sent_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) MarkBatchDeliverySent(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markBatchDeliverySent, id)
+	return err
+}
+
+const recordBatchDeliveryDownload = `-- name: RecordBatchDeliveryDownload :exec
+UPDATE batch_deliveries SET download_count = download_count + 1, last_downloaded_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) RecordBatchDeliveryDownload(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordBatchDeliveryDownload, id)
+	return err
+}
+
+const recordBatchDeliveryOpen = `-- name: RecordBatchDeliveryOpen :exec
+UPDATE batch_deliveries SET open_count = open_count + 1, first_opened_at = COALESCE(first_opened_at, NOW()), last_opened_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) RecordBatchDeliveryOpen(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordBatchDeliveryOpen, id)
+	return err
+}
+
+const retryBatchDelivery = `-- name: RetryBatchDelivery :exec
+UPDATE batch_deliveries SET send_attempts = send_attempts + 1, next_attempt_at = $2 WHERE id = $1
+`
+
+type RetryBatchDeliveryParams struct {
+	ID            uuid.UUID
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) RetryBatchDelivery(ctx context.Context, arg RetryBatchDeliveryParams) error {
+	_, err := q.db.ExecContext(ctx, retryBatchDelivery, arg.ID, arg.NextAttemptAt)
+	return err
+}
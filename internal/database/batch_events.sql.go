@@ -0,0 +1,94 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: batch_events.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countBatchActivity = `-- name: CountBatchActivity :one
+SELECT ((SELECT COUNT(*) FROM batch_comments c WHERE c.batch_id = $1 AND c.deleted_at IS NULL) + (SELECT COUNT(*) FROM batch_events e WHERE e.batch_id = $1))::bigint AS total
+`
+
+func (q *Queries) CountBatchActivity(ctx context.Context, batchID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBatchActivity, batchID)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const createBatchEvent = `-- name: CreateBatchEvent :exec
+INSERT INTO batch_events(batch_id, user_id, type) VALUES ($1, $2, $3)
+`
+
+type CreateBatchEventParams struct {
+	BatchID uuid.UUID
+	UserID  uuid.UUID
+	Type    BatchEventType
+}
+
+func (q *Queries) CreateBatchEvent(ctx context.Context, arg CreateBatchEventParams) error {
+	_, err := q.db.ExecContext(ctx, createBatchEvent, arg.BatchID, arg.UserID, arg.Type)
+	return err
+}
+
+const getBatchActivity = `-- name: GetBatchActivity :many
+SELECT a.id, a.type, a.user_id, a.image_id, a.parent_id, a.body, a.created_at FROM (
+    SELECT c.id, 'comment'::text AS type, c.user_id, c.image_id, c.parent_id, c.body, c.created_at FROM batch_comments c WHERE c.batch_id = $1 AND c.deleted_at IS NULL
+    UNION ALL
+    SELECT e.id, e.type::text AS type, e.user_id, NULL::uuid AS image_id, NULL::uuid AS parent_id, ''::text AS body, e.created_at FROM batch_events e WHERE e.batch_id = $1
+) a ORDER BY a.created_at DESC, a.id DESC LIMIT $3 OFFSET $2
+`
+
+type GetBatchActivityParams struct {
+	BatchID    uuid.UUID
+	PageOffset int32
+	PageLimit  int32
+}
+
+type GetBatchActivityRow struct {
+	ID        uuid.UUID
+	Type      string
+	UserID    uuid.UUID
+	ImageID   uuid.NullUUID
+	ParentID  uuid.NullUUID
+	Body      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetBatchActivity(ctx context.Context, arg GetBatchActivityParams) ([]GetBatchActivityRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBatchActivity, arg.BatchID, arg.PageOffset, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBatchActivityRow
+	for rows.Next() {
+		var i GetBatchActivityRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.UserID,
+			&i.ImageID,
+			&i.ParentID,
+			&i.Body,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
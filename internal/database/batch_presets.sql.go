@@ -0,0 +1,201 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: batch_presets.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const createBatchPreset = `-- name: CreateBatchPreset :one
+INSERT INTO batch_presets(user_id, name, watermark_id, watermark_position, watermark_opacity, watermark_scale, output_format, output_quality, max_width, max_height) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, user_id, name, watermark_id, watermark_position, watermark_opacity, watermark_scale, output_format, output_quality, max_width, max_height, created_at, updated_at
+`
+
+type CreateBatchPresetParams struct {
+	UserID            uuid.UUID
+	Name              string
+	WatermarkID       uuid.NullUUID
+	WatermarkPosition NullWatermarkPosition
+	WatermarkOpacity  sql.NullInt32
+	WatermarkScale    sql.NullInt32
+	OutputFormat      NullOutputFormat
+	OutputQuality     sql.NullInt32
+	MaxWidth          sql.NullInt32
+	MaxHeight         sql.NullInt32
+}
+
+func (q *Queries) CreateBatchPreset(ctx context.Context, arg CreateBatchPresetParams) (BatchPreset, error) {
+	row := q.db.QueryRowContext(ctx, createBatchPreset,
+		arg.UserID,
+		arg.Name,
+		arg.WatermarkID,
+		arg.WatermarkPosition,
+		arg.WatermarkOpacity,
+		arg.WatermarkScale,
+		arg.OutputFormat,
+		arg.OutputQuality,
+		arg.MaxWidth,
+		arg.MaxHeight,
+	)
+	var i BatchPreset
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkID,
+		&i.WatermarkPosition,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteBatchPresetByID = `-- name: DeleteBatchPresetByID :execrows
+DELETE FROM batch_presets WHERE id = $1 AND user_id = $2
+`
+
+type DeleteBatchPresetByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteBatchPresetByID(ctx context.Context, arg DeleteBatchPresetByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBatchPresetByID, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserBatchPresetByID = `-- name: GetUserBatchPresetByID :one
+SELECT id, user_id, name, watermark_id, watermark_position, watermark_opacity, watermark_scale, output_format, output_quality, max_width, max_height, created_at, updated_at FROM batch_presets WHERE id = $1 AND user_id = $2
+`
+
+type GetUserBatchPresetByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetUserBatchPresetByID(ctx context.Context, arg GetUserBatchPresetByIDParams) (BatchPreset, error) {
+	row := q.db.QueryRowContext(ctx, getUserBatchPresetByID, arg.ID, arg.UserID)
+	var i BatchPreset
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkID,
+		&i.WatermarkPosition,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserBatchPresets = `-- name: GetUserBatchPresets :many
+SELECT id, user_id, name, watermark_id, watermark_position, watermark_opacity, watermark_scale, output_format, output_quality, max_width, max_height, created_at, updated_at FROM batch_presets WHERE user_id = $1 ORDER BY name
+`
+
+func (q *Queries) GetUserBatchPresets(ctx context.Context, userID uuid.UUID) ([]BatchPreset, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBatchPresets, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BatchPreset
+	for rows.Next() {
+		var i BatchPreset
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.WatermarkID,
+			&i.WatermarkPosition,
+			&i.WatermarkOpacity,
+			&i.WatermarkScale,
+			&i.OutputFormat,
+			&i.OutputQuality,
+			&i.MaxWidth,
+			&i.MaxHeight,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateBatchPreset = `-- name: UpdateBatchPreset :one
+UPDATE batch_presets SET name = $3, watermark_id = $4, watermark_position = $5, watermark_opacity = $6, watermark_scale = $7, output_format = $8, output_quality = $9, max_width = $10, max_height = $11, updated_at = NOW() WHERE id = $1 AND user_id = $2 RETURNING id, user_id, name, watermark_id, watermark_position, watermark_opacity, watermark_scale, output_format, output_quality, max_width, max_height, created_at, updated_at
+`
+
+type UpdateBatchPresetParams struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	Name              string
+	WatermarkID       uuid.NullUUID
+	WatermarkPosition NullWatermarkPosition
+	WatermarkOpacity  sql.NullInt32
+	WatermarkScale    sql.NullInt32
+	OutputFormat      NullOutputFormat
+	OutputQuality     sql.NullInt32
+	MaxWidth          sql.NullInt32
+	MaxHeight         sql.NullInt32
+}
+
+func (q *Queries) UpdateBatchPreset(ctx context.Context, arg UpdateBatchPresetParams) (BatchPreset, error) {
+	row := q.db.QueryRowContext(ctx, updateBatchPreset,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.WatermarkID,
+		arg.WatermarkPosition,
+		arg.WatermarkOpacity,
+		arg.WatermarkScale,
+		arg.OutputFormat,
+		arg.OutputQuality,
+		arg.MaxWidth,
+		arg.MaxHeight,
+	)
+	var i BatchPreset
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkID,
+		&i.WatermarkPosition,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
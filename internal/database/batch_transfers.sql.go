@@ -0,0 +1,281 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: batch_transfers.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const cancelBatchTransfer = `-- name: CancelBatchTransfer :one
+UPDATE batch_transfers SET status = 'cancelled', responded_at = NOW() WHERE batch_id = $1 AND from_user_id = $2 AND status = 'pending' RETURNING id, batch_id, from_user_id, to_user_id, message, status, created_at, responded_at
+`
+
+type CancelBatchTransferParams struct {
+	BatchID    uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) CancelBatchTransfer(ctx context.Context, arg CancelBatchTransferParams) (BatchTransfer, error) {
+	row := q.db.QueryRowContext(ctx, cancelBatchTransfer, arg.BatchID, arg.FromUserID)
+	var i BatchTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.FromUserID,
+		&i.ToUserID,
+		&i.Message,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const createBatchTransfer = `-- name: CreateBatchTransfer :one
+INSERT INTO batch_transfers(batch_id, from_user_id, to_user_id, message) VALUES ($1, $2, $3, $4) RETURNING id, batch_id, from_user_id, to_user_id, message, status, created_at, responded_at
+`
+
+type CreateBatchTransferParams struct {
+	BatchID    uuid.UUID
+	FromUserID uuid.UUID
+	ToUserID   uuid.UUID
+	Message    string
+}
+
+func (q *Queries) CreateBatchTransfer(ctx context.Context, arg CreateBatchTransferParams) (BatchTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createBatchTransfer,
+		arg.BatchID,
+		arg.FromUserID,
+		arg.ToUserID,
+		arg.Message,
+	)
+	var i BatchTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.FromUserID,
+		&i.ToUserID,
+		&i.Message,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const getBatchTransferByID = `-- name: GetBatchTransferByID :one
+SELECT t.id, t.batch_id, t.from_user_id, t.to_user_id, t.message, t.status, t.created_at, t.responded_at, b.name AS batch_name, f.email AS from_email, r.email AS to_email FROM batch_transfers t INNER JOIN batches b ON b.id = t.batch_id INNER JOIN users f ON f.id = t.from_user_id INNER JOIN users r ON r.id = t.to_user_id WHERE t.id = $1
+`
+
+type GetBatchTransferByIDRow struct {
+	ID          uuid.UUID
+	BatchID     uuid.UUID
+	FromUserID  uuid.UUID
+	ToUserID    uuid.UUID
+	Message     string
+	Status      BatchTransferStatus
+	CreatedAt   time.Time
+	RespondedAt sql.NullTime
+	BatchName   sql.NullString
+	FromEmail   string
+	ToEmail     string
+}
+
+func (q *Queries) GetBatchTransferByID(ctx context.Context, id uuid.UUID) (GetBatchTransferByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getBatchTransferByID, id)
+	var i GetBatchTransferByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.FromUserID,
+		&i.ToUserID,
+		&i.Message,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+		&i.BatchName,
+		&i.FromEmail,
+		&i.ToEmail,
+	)
+	return i, err
+}
+
+const getUserBatchTransfers = `-- name: GetUserBatchTransfers :many
+SELECT t.id, t.batch_id, t.from_user_id, t.to_user_id, t.message, t.status, t.created_at, t.responded_at, b.name AS batch_name, f.email AS from_email, r.email AS to_email FROM batch_transfers t INNER JOIN batches b ON b.id = t.batch_id INNER JOIN users f ON f.id = t.from_user_id INNER JOIN users r ON r.id = t.to_user_id WHERE (t.from_user_id = $1 OR t.to_user_id = $1) AND ($2::batch_transfer_status IS NULL OR t.status = $2::batch_transfer_status) ORDER BY t.created_at DESC, t.id DESC
+`
+
+type GetUserBatchTransfersParams struct {
+	UserID uuid.UUID
+	Status NullBatchTransferStatus
+}
+
+type GetUserBatchTransfersRow struct {
+	ID          uuid.UUID
+	BatchID     uuid.UUID
+	FromUserID  uuid.UUID
+	ToUserID    uuid.UUID
+	Message     string
+	Status      BatchTransferStatus
+	CreatedAt   time.Time
+	RespondedAt sql.NullTime
+	BatchName   sql.NullString
+	FromEmail   string
+	ToEmail     string
+}
+
+func (q *Queries) GetUserBatchTransfers(ctx context.Context, arg GetUserBatchTransfersParams) ([]GetUserBatchTransfersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBatchTransfers, arg.UserID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserBatchTransfersRow
+	for rows.Next() {
+		var i GetUserBatchTransfersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.FromUserID,
+			&i.ToUserID,
+			&i.Message,
+			&i.Status,
+			&i.CreatedAt,
+			&i.RespondedAt,
+			&i.BatchName,
+			&i.FromEmail,
+			&i.ToEmail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockIncomingBatchTransfer = `-- name: LockIncomingBatchTransfer :one
+SELECT id, batch_id, from_user_id, to_user_id, message, status, created_at, responded_at FROM batch_transfers WHERE id = $1 AND to_user_id = $2 AND status = 'pending' FOR UPDATE
+`
+
+type LockIncomingBatchTransferParams struct {
+	ID       uuid.UUID
+	ToUserID uuid.UUID
+}
+
+func (q *Queries) LockIncomingBatchTransfer(ctx context.Context, arg LockIncomingBatchTransferParams) (BatchTransfer, error) {
+	row := q.db.QueryRowContext(ctx, lockIncomingBatchTransfer, arg.ID, arg.ToUserID)
+	var i BatchTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.FromUserID,
+		&i.ToUserID,
+		&i.Message,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const respondBatchTransfer = `-- name: RespondBatchTransfer :one
+UPDATE batch_transfers SET status = $2, responded_at = NOW() WHERE id = $1 AND status = 'pending' RETURNING id, batch_id, from_user_id, to_user_id, message, status, created_at, responded_at
+`
+
+type RespondBatchTransferParams struct {
+	ID     uuid.UUID
+	Status BatchTransferStatus
+}
+
+func (q *Queries) RespondBatchTransfer(ctx context.Context, arg RespondBatchTransferParams) (BatchTransfer, error) {
+	row := q.db.QueryRowContext(ctx, respondBatchTransfer, arg.ID, arg.Status)
+	var i BatchTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.FromUserID,
+		&i.ToUserID,
+		&i.Message,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const transferBatch = `-- name: TransferBatch :one
+UPDATE batches b SET user_id = $1, watermark_id = NULL, watermark_font_id = NULL, updated_at = NOW() WHERE b.id = $2 AND b.user_id = $3 AND b.deleted_at IS NULL AND b.waiting_since IS NULL AND NOT EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing')) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
+`
+
+type TransferBatchParams struct {
+	ToUserID   uuid.UUID
+	ID         uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) TransferBatch(ctx context.Context, arg TransferBatchParams) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, transferBatch, arg.ToUserID, arg.ID, arg.FromUserID)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
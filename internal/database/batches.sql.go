@@ -8,20 +8,202 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const archiveBatchByID = `-- name: ArchiveBatchByID :exec
+UPDATE batches SET archive_status = 'archived', archived_at = NOW(), updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) ArchiveBatchByID(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, archiveBatchByID, id)
+	return err
+}
+
+const claimBatchReport = `-- name: ClaimBatchReport :one
+UPDATE batches b SET report_generated_at = NOW() WHERE b.id = (SELECT i.batch_id FROM images i WHERE i.id = $1) AND b.deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM images p WHERE p.batch_id = b.id AND p.deleted_at IS NULL AND p.status IN ('pending', 'processing')) AND (b.report_generated_at IS NULL OR b.report_generated_at < (SELECT MAX(u.updated_at) FROM images u WHERE u.batch_id = b.id AND u.deleted_at IS NULL)) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
+`
+
+func (q *Queries) ClaimBatchReport(ctx context.Context, id uuid.UUID) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, claimBatchReport, id)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
+const clearBatchWaiting = `-- name: ClearBatchWaiting :exec
+UPDATE batches SET waiting_since = NULL, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) ClearBatchWaiting(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearBatchWaiting, id)
+	return err
+}
+
+const countActiveUserBatches = `-- name: CountActiveUserBatches :one
+SELECT COUNT(*) FROM batches b WHERE b.user_id = $1 AND b.deleted_at IS NULL AND b.waiting_since IS NULL AND EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing'))
+`
+
+func (q *Queries) CountActiveUserBatches(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveUserBatches, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBatchesByWatermarkKey = `-- name: CountBatchesByWatermarkKey :one
+SELECT COUNT(*) FROM batches WHERE watermark_key = $1 AND purged_at IS NULL
+`
+
+func (q *Queries) CountBatchesByWatermarkKey(ctx context.Context, watermarkKey sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBatchesByWatermarkKey, watermarkKey)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countOtherBatchesByWatermarkKey = `-- name: CountOtherBatchesByWatermarkKey :one
+SELECT COUNT(*) FROM batches WHERE watermark_key = $1 AND id <> $2 AND purged_at IS NULL
+`
+
+type CountOtherBatchesByWatermarkKeyParams struct {
+	WatermarkKey sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) CountOtherBatchesByWatermarkKey(ctx context.Context, arg CountOtherBatchesByWatermarkKeyParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherBatchesByWatermarkKey, arg.WatermarkKey, arg.ID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserBatches = `-- name: CountUserBatches :one
+SELECT COUNT(*) FROM (SELECT batch_status(b.waiting_since, c.image_count, c.image_pending_count, c.image_processing_count, c.image_cancelled_count, c.image_expired_count, c.image_failed_count) AS status FROM batches b CROSS JOIN LATERAL (SELECT COUNT(i.id) AS image_count, COUNT(i.id) FILTER (WHERE i.status = 'pending') AS image_pending_count, COUNT(i.id) FILTER (WHERE i.status = 'processing') AS image_processing_count, COUNT(i.id) FILTER (WHERE i.status = 'completed') AS image_completed_count, COUNT(i.id) FILTER (WHERE i.status = 'failed') AS image_failed_count, COUNT(i.id) FILTER (WHERE i.status = 'cancelled') AS image_cancelled_count, COUNT(i.id) FILTER (WHERE i.status = 'expired') AS image_expired_count FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL) c WHERE b.user_id = $1 AND b.deleted_at IS NULL AND c.image_count > 0 AND ($2::text IS NULL OR b.external_id = $2::text) AND ($3::text IS NULL OR b.name ILIKE '%' || $3::text || '%' ESCAPE '\' OR b.id IN (SELECT k.batch_id FROM images k WHERE k.deleted_at IS NULL AND k.key ILIKE '%' || $3::text || '%' ESCAPE '\'))) s WHERE $4::text IS NULL OR s.status = $4::text
+`
+
+type CountUserBatchesParams struct {
+	UserID     uuid.UUID
+	ExternalID sql.NullString
+	Q          sql.NullString
+	Status     sql.NullString
+}
+
+func (q *Queries) CountUserBatches(ctx context.Context, arg CountUserBatchesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserBatches,
+		arg.UserID,
+		arg.ExternalID,
+		arg.Q,
+		arg.Status,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserTrash = `-- name: CountUserTrash :one
+SELECT COUNT(*) FROM batches WHERE user_id = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL
+`
+
+func (q *Queries) CountUserTrash(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserTrash, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createBatch = `-- name: CreateBatch :one
-INSERT INTO batches(user_id, name, watermark_key, watermark_url) VALUES ($1, $2, $3, $4) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key
+INSERT INTO batches(user_id, name, watermark_key, watermark_url, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, region, report_csv, expires_at, deadline) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
 `
 
 type CreateBatchParams struct {
-	UserID       uuid.UUID
-	Name         sql.NullString
-	WatermarkKey sql.NullString
-	WatermarkUrl sql.NullString
+	UserID               uuid.UUID
+	Name                 sql.NullString
+	WatermarkKey         sql.NullString
+	WatermarkUrl         sql.NullString
+	PreserveFilenames    bool
+	CollisionPolicy      OutputCollisionPolicy
+	WatermarkText        sql.NullString
+	WatermarkFontID      uuid.NullUUID
+	WatermarkPosition    WatermarkPosition
+	OutputFormat         OutputFormat
+	OutputQuality        int32
+	WatermarkOpacity     int32
+	WatermarkScale       int32
+	MaxConcurrency       int32
+	WatermarkTileSpacing int32
+	MaxWidth             sql.NullInt32
+	MaxHeight            sql.NullInt32
+	PreserveMetadata     bool
+	InvisibleWatermark   bool
+	JpegProgressive      bool
+	JpegSubsampling      ChromaSubsampling
+	PngCompression       PngCompression
+	SimilarDedupe        SimilarDedupe
+	ExternalID           sql.NullString
+	Transforms           json.RawMessage
+	CropAspectRatio      sql.NullString
+	CropX                sql.NullInt32
+	CropY                sql.NullInt32
+	CropWidth            sql.NullInt32
+	CropHeight           sql.NullInt32
+	WatermarkID          uuid.NullUUID
+	Pipeline             json.RawMessage
+	Region               string
+	ReportCsv            bool
+	ExpiresAt            sql.NullTime
+	Deadline             sql.NullTime
 }
 
 func (q *Queries) CreateBatch(ctx context.Context, arg CreateBatchParams) (Batch, error) {
@@ -30,6 +212,38 @@ func (q *Queries) CreateBatch(ctx context.Context, arg CreateBatchParams) (Batch
 		arg.Name,
 		arg.WatermarkKey,
 		arg.WatermarkUrl,
+		arg.PreserveFilenames,
+		arg.CollisionPolicy,
+		arg.WatermarkText,
+		arg.WatermarkFontID,
+		arg.WatermarkPosition,
+		arg.OutputFormat,
+		arg.OutputQuality,
+		arg.WatermarkOpacity,
+		arg.WatermarkScale,
+		arg.MaxConcurrency,
+		arg.WatermarkTileSpacing,
+		arg.MaxWidth,
+		arg.MaxHeight,
+		arg.PreserveMetadata,
+		arg.InvisibleWatermark,
+		arg.JpegProgressive,
+		arg.JpegSubsampling,
+		arg.PngCompression,
+		arg.SimilarDedupe,
+		arg.ExternalID,
+		arg.Transforms,
+		arg.CropAspectRatio,
+		arg.CropX,
+		arg.CropY,
+		arg.CropWidth,
+		arg.CropHeight,
+		arg.WatermarkID,
+		arg.Pipeline,
+		arg.Region,
+		arg.ReportCsv,
+		arg.ExpiresAt,
+		arg.Deadline,
 	)
 	var i Batch
 	err := row.Scan(
@@ -41,12 +255,51 @@ func (q *Queries) CreateBatch(ctx context.Context, arg CreateBatchParams) (Batch
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
 	)
 	return i, err
 }
 
 const deleteBatchByID = `-- name: DeleteBatchByID :exec
-UPDATE batches SET deleted_at = NOW() WHERE id = $1 AND user_id = $2
+UPDATE batches SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type DeleteBatchByIDParams struct {
@@ -59,10 +312,79 @@ func (q *Queries) DeleteBatchByID(ctx context.Context, arg DeleteBatchByIDParams
 	return err
 }
 
+const expireNextBatch = `-- name: ExpireNextBatch :one
+UPDATE batches SET deleted_at = COALESCE(deleted_at, NOW()), purged_at = NOW(), updated_at = NOW() WHERE id = (SELECT b.id FROM batches b WHERE b.region = $1 AND b.expires_at <= NOW() AND b.purged_at IS NULL AND NOT EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing')) ORDER BY b.expires_at LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
+`
+
+func (q *Queries) ExpireNextBatch(ctx context.Context, region string) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, expireNextBatch, region)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
 const getAllUserBatches = `-- name: GetAllUserBatches :many
-SELECT b.id, b.user_id, b.name, b.watermark_url, b.created_at, b.updated_at, b.deleted_at, b.watermark_key, COUNT(i.id) as image_count, COUNT(i.id) FILTER (WHERE i.status = 'pending') AS image_pending_count, COUNT(i.id) FILTER (WHERE i.status = 'processing') AS image_processing_count, COUNT(i.id) FILTER (WHERE i.status = 'completed') AS image_completed_count, COUNT(i.id) FILTER (WHERE i.status = 'failed') AS image_failed_count FROM batches b INNER JOIN images i ON i.batch_id = b.id AND i.deleted_at IS NULL WHERE b.user_id = $1 AND b.deleted_at IS NULL GROUP BY b.id ORDER BY b.created_at DESC
+SELECT id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at, image_count, image_pending_count, image_processing_count, image_completed_count, image_failed_count, image_cancelled_count, image_expired_count, status FROM (SELECT b.id, b.user_id, b.name, b.watermark_url, b.created_at, b.updated_at, b.deleted_at, b.watermark_key, b.archive_status, b.archived_at, b.preserve_filenames, b.collision_policy, b.watermark_text, b.watermark_font_id, b.watermark_position, b.output_format, b.output_quality, b.watermark_opacity, b.watermark_scale, b.max_concurrency, b.watermark_tile_spacing, b.max_width, b.max_height, b.preserve_metadata, b.invisible_watermark, b.jpeg_progressive, b.jpeg_subsampling, b.png_compression, b.similar_dedupe, b.external_id, b.transforms, b.crop_aspect_ratio, b.crop_x, b.crop_y, b.crop_width, b.crop_height, b.watermark_id, b.pipeline, b.waiting_since, b.region, b.report_csv, b.report_url, b.report_csv_url, b.report_generated_at, b.expires_at, b.deadline, b.purged_at, c.image_count, c.image_pending_count, c.image_processing_count, c.image_completed_count, c.image_failed_count, c.image_cancelled_count, c.image_expired_count, batch_status(b.waiting_since, c.image_count, c.image_pending_count, c.image_processing_count, c.image_cancelled_count, c.image_expired_count, c.image_failed_count) AS status FROM batches b CROSS JOIN LATERAL (SELECT COUNT(i.id) AS image_count, COUNT(i.id) FILTER (WHERE i.status = 'pending') AS image_pending_count, COUNT(i.id) FILTER (WHERE i.status = 'processing') AS image_processing_count, COUNT(i.id) FILTER (WHERE i.status = 'completed') AS image_completed_count, COUNT(i.id) FILTER (WHERE i.status = 'failed') AS image_failed_count, COUNT(i.id) FILTER (WHERE i.status = 'cancelled') AS image_cancelled_count, COUNT(i.id) FILTER (WHERE i.status = 'expired') AS image_expired_count FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL) c WHERE b.user_id = $1 AND b.deleted_at IS NULL AND c.image_count > 0 AND ($2::text IS NULL OR b.external_id = $2::text) AND ($3::text IS NULL OR b.name ILIKE '%' || $3::text || '%' ESCAPE '\' OR b.id IN (SELECT k.batch_id FROM images k WHERE k.deleted_at IS NULL AND k.key ILIKE '%' || $3::text || '%' ESCAPE '\'))) s WHERE $4::text IS NULL OR s.status = $4::text ORDER BY CASE WHEN $5::text = 'name' THEN s.name END ASC, CASE WHEN $5::text = '-name' THEN s.name END DESC, CASE WHEN $5::text = 'image_count' THEN s.image_count END ASC, CASE WHEN $5::text = '-image_count' THEN s.image_count END DESC, CASE WHEN $5::text = 'created_at' THEN s.created_at END ASC, s.created_at DESC, s.id DESC LIMIT $7 OFFSET $6
 `
 
+type GetAllUserBatchesParams struct {
+	UserID     uuid.UUID
+	ExternalID sql.NullString
+	Q          sql.NullString
+	Status     sql.NullString
+	Sort       string
+	PageOffset int32
+	PageLimit  int32
+}
+
 type GetAllUserBatchesRow struct {
 	ID                   uuid.UUID
 	UserID               uuid.UUID
@@ -72,15 +394,65 @@ type GetAllUserBatchesRow struct {
 	UpdatedAt            time.Time
 	DeletedAt            sql.NullTime
 	WatermarkKey         sql.NullString
+	ArchiveStatus        BatchArchiveStatus
+	ArchivedAt           sql.NullTime
+	PreserveFilenames    bool
+	CollisionPolicy      OutputCollisionPolicy
+	WatermarkText        sql.NullString
+	WatermarkFontID      uuid.NullUUID
+	WatermarkPosition    WatermarkPosition
+	OutputFormat         OutputFormat
+	OutputQuality        int32
+	WatermarkOpacity     int32
+	WatermarkScale       int32
+	MaxConcurrency       int32
+	WatermarkTileSpacing int32
+	MaxWidth             sql.NullInt32
+	MaxHeight            sql.NullInt32
+	PreserveMetadata     bool
+	InvisibleWatermark   bool
+	JpegProgressive      bool
+	JpegSubsampling      ChromaSubsampling
+	PngCompression       PngCompression
+	SimilarDedupe        SimilarDedupe
+	ExternalID           sql.NullString
+	Transforms           json.RawMessage
+	CropAspectRatio      sql.NullString
+	CropX                sql.NullInt32
+	CropY                sql.NullInt32
+	CropWidth            sql.NullInt32
+	CropHeight           sql.NullInt32
+	WatermarkID          uuid.NullUUID
+	Pipeline             json.RawMessage
+	WaitingSince         sql.NullTime
+	Region               string
+	ReportCsv            bool
+	ReportUrl            sql.NullString
+	ReportCsvUrl         sql.NullString
+	ReportGeneratedAt    sql.NullTime
+	ExpiresAt            sql.NullTime
+	Deadline             sql.NullTime
+	PurgedAt             sql.NullTime
 	ImageCount           int64
 	ImagePendingCount    int64
 	ImageProcessingCount int64
 	ImageCompletedCount  int64
 	ImageFailedCount     int64
+	ImageCancelledCount  int64
+	ImageExpiredCount    int64
+	Status               string
 }
 
-func (q *Queries) GetAllUserBatches(ctx context.Context, userID uuid.UUID) ([]GetAllUserBatchesRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllUserBatches, userID)
+func (q *Queries) GetAllUserBatches(ctx context.Context, arg GetAllUserBatchesParams) ([]GetAllUserBatchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUserBatches,
+		arg.UserID,
+		arg.ExternalID,
+		arg.Q,
+		arg.Status,
+		arg.Sort,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -97,11 +469,139 @@ func (q *Queries) GetAllUserBatches(ctx context.Context, userID uuid.UUID) ([]Ge
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.WatermarkKey,
+			&i.ArchiveStatus,
+			&i.ArchivedAt,
+			&i.PreserveFilenames,
+			&i.CollisionPolicy,
+			&i.WatermarkText,
+			&i.WatermarkFontID,
+			&i.WatermarkPosition,
+			&i.OutputFormat,
+			&i.OutputQuality,
+			&i.WatermarkOpacity,
+			&i.WatermarkScale,
+			&i.MaxConcurrency,
+			&i.WatermarkTileSpacing,
+			&i.MaxWidth,
+			&i.MaxHeight,
+			&i.PreserveMetadata,
+			&i.InvisibleWatermark,
+			&i.JpegProgressive,
+			&i.JpegSubsampling,
+			&i.PngCompression,
+			&i.SimilarDedupe,
+			&i.ExternalID,
+			&i.Transforms,
+			&i.CropAspectRatio,
+			&i.CropX,
+			&i.CropY,
+			&i.CropWidth,
+			&i.CropHeight,
+			&i.WatermarkID,
+			&i.Pipeline,
+			&i.WaitingSince,
+			&i.Region,
+			&i.ReportCsv,
+			&i.ReportUrl,
+			&i.ReportCsvUrl,
+			&i.ReportGeneratedAt,
+			&i.ExpiresAt,
+			&i.Deadline,
+			&i.PurgedAt,
 			&i.ImageCount,
 			&i.ImagePendingCount,
 			&i.ImageProcessingCount,
 			&i.ImageCompletedCount,
 			&i.ImageFailedCount,
+			&i.ImageCancelledCount,
+			&i.ImageExpiredCount,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBatchOwnerByID = `-- name: GetBatchOwnerByID :one
+SELECT user_id FROM batches WHERE id = $1
+`
+
+func (q *Queries) GetBatchOwnerByID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getBatchOwnerByID, id)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getBatchesByArchiveStatus = `-- name: GetBatchesByArchiveStatus :many
+SELECT id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at FROM batches WHERE archive_status = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetBatchesByArchiveStatus(ctx context.Context, archiveStatus BatchArchiveStatus) ([]Batch, error) {
+	rows, err := q.db.QueryContext(ctx, getBatchesByArchiveStatus, archiveStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Batch
+	for rows.Next() {
+		var i Batch
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.WatermarkUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.WatermarkKey,
+			&i.ArchiveStatus,
+			&i.ArchivedAt,
+			&i.PreserveFilenames,
+			&i.CollisionPolicy,
+			&i.WatermarkText,
+			&i.WatermarkFontID,
+			&i.WatermarkPosition,
+			&i.OutputFormat,
+			&i.OutputQuality,
+			&i.WatermarkOpacity,
+			&i.WatermarkScale,
+			&i.MaxConcurrency,
+			&i.WatermarkTileSpacing,
+			&i.MaxWidth,
+			&i.MaxHeight,
+			&i.PreserveMetadata,
+			&i.InvisibleWatermark,
+			&i.JpegProgressive,
+			&i.JpegSubsampling,
+			&i.PngCompression,
+			&i.SimilarDedupe,
+			&i.ExternalID,
+			&i.Transforms,
+			&i.CropAspectRatio,
+			&i.CropX,
+			&i.CropY,
+			&i.CropWidth,
+			&i.CropHeight,
+			&i.WatermarkID,
+			&i.Pipeline,
+			&i.WaitingSince,
+			&i.Region,
+			&i.ReportCsv,
+			&i.ReportUrl,
+			&i.ReportCsvUrl,
+			&i.ReportGeneratedAt,
+			&i.ExpiresAt,
+			&i.Deadline,
+			&i.PurgedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -116,8 +616,67 @@ func (q *Queries) GetAllUserBatches(ctx context.Context, userID uuid.UUID) ([]Ge
 	return items, nil
 }
 
+const getNextOverdueBatch = `-- name: GetNextOverdueBatch :one
+SELECT b.id, b.user_id, b.name, b.watermark_url, b.created_at, b.updated_at, b.deleted_at, b.watermark_key, b.archive_status, b.archived_at, b.preserve_filenames, b.collision_policy, b.watermark_text, b.watermark_font_id, b.watermark_position, b.output_format, b.output_quality, b.watermark_opacity, b.watermark_scale, b.max_concurrency, b.watermark_tile_spacing, b.max_width, b.max_height, b.preserve_metadata, b.invisible_watermark, b.jpeg_progressive, b.jpeg_subsampling, b.png_compression, b.similar_dedupe, b.external_id, b.transforms, b.crop_aspect_ratio, b.crop_x, b.crop_y, b.crop_width, b.crop_height, b.watermark_id, b.pipeline, b.waiting_since, b.region, b.report_csv, b.report_url, b.report_csv_url, b.report_generated_at, b.expires_at, b.deadline, b.purged_at FROM batches b WHERE b.region = $1 AND b.deadline <= NOW() AND b.deleted_at IS NULL AND EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status = 'pending') ORDER BY b.deadline LIMIT 1 FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) GetNextOverdueBatch(ctx context.Context, region string) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, getNextOverdueBatch, region)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
 const getUserBatchByID = `-- name: GetUserBatchByID :one
-SELECT id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key FROM batches WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+SELECT id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at FROM batches WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type GetUserBatchByIDParams struct {
@@ -137,20 +696,506 @@ func (q *Queries) GetUserBatchByID(ctx context.Context, arg GetUserBatchByIDPara
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
+const getUserBatchIDs = `-- name: GetUserBatchIDs :many
+SELECT id FROM batches WHERE user_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserBatchIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBatchIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserTrash = `-- name: GetUserTrash :many
+SELECT id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at FROM batches WHERE user_id = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL ORDER BY deleted_at DESC, id DESC LIMIT $2 OFFSET $3
+`
+
+type GetUserTrashParams struct {
+	UserID uuid.UUID
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) GetUserTrash(ctx context.Context, arg GetUserTrashParams) ([]Batch, error) {
+	rows, err := q.db.QueryContext(ctx, getUserTrash, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Batch
+	for rows.Next() {
+		var i Batch
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.WatermarkUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.WatermarkKey,
+			&i.ArchiveStatus,
+			&i.ArchivedAt,
+			&i.PreserveFilenames,
+			&i.CollisionPolicy,
+			&i.WatermarkText,
+			&i.WatermarkFontID,
+			&i.WatermarkPosition,
+			&i.OutputFormat,
+			&i.OutputQuality,
+			&i.WatermarkOpacity,
+			&i.WatermarkScale,
+			&i.MaxConcurrency,
+			&i.WatermarkTileSpacing,
+			&i.MaxWidth,
+			&i.MaxHeight,
+			&i.PreserveMetadata,
+			&i.InvisibleWatermark,
+			&i.JpegProgressive,
+			&i.JpegSubsampling,
+			&i.PngCompression,
+			&i.SimilarDedupe,
+			&i.ExternalID,
+			&i.Transforms,
+			&i.CropAspectRatio,
+			&i.CropX,
+			&i.CropY,
+			&i.CropWidth,
+			&i.CropHeight,
+			&i.WatermarkID,
+			&i.Pipeline,
+			&i.WaitingSince,
+			&i.Region,
+			&i.ReportCsv,
+			&i.ReportUrl,
+			&i.ReportCsvUrl,
+			&i.ReportGeneratedAt,
+			&i.ExpiresAt,
+			&i.Deadline,
+			&i.PurgedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWaitingBatchUserIDs = `-- name: GetWaitingBatchUserIDs :many
+SELECT DISTINCT user_id FROM batches WHERE waiting_since IS NOT NULL AND deleted_at IS NULL
+`
+
+func (q *Queries) GetWaitingBatchUserIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getWaitingBatchUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockBatch = `-- name: LockBatch :exec
+SELECT id FROM batches WHERE id = $1 FOR NO KEY UPDATE
+`
+
+func (q *Queries) LockBatch(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockBatch, id)
+	return err
+}
+
+const lockBatchMaxConcurrency = `-- name: LockBatchMaxConcurrency :one
+SELECT (b.max_concurrency * COALESCE((SELECT MAX(ub.concurrency_multiplier) FROM user_boosts ub WHERE ub.user_id = b.user_id AND ub.revoked_at IS NULL AND ub.starts_at <= NOW() AND ub.ends_at > NOW()), 1))::int AS max_concurrency FROM batches b WHERE b.id = $1 FOR UPDATE OF b
+`
+
+func (q *Queries) LockBatchMaxConcurrency(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, lockBatchMaxConcurrency, id)
+	var max_concurrency int32
+	err := row.Scan(&max_concurrency)
+	return max_concurrency, err
+}
+
+const purgeNextDeletedBatch = `-- name: PurgeNextDeletedBatch :one
+UPDATE batches SET purged_at = NOW(), updated_at = NOW() WHERE id = (SELECT b.id FROM batches b WHERE b.region = $1 AND b.deleted_at <= $2 AND b.purged_at IS NULL ORDER BY b.deleted_at LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
+`
+
+type PurgeNextDeletedBatchParams struct {
+	Region        string
+	DeletedBefore sql.NullTime
+}
+
+func (q *Queries) PurgeNextDeletedBatch(ctx context.Context, arg PurgeNextDeletedBatchParams) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, purgeNextDeletedBatch, arg.Region, arg.DeletedBefore)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
 	)
 	return i, err
 }
 
-const hardDeleteBatchByID = `-- name: HardDeleteBatchByID :exec
-DELETE FROM batches WHERE id = $1 AND user_id = $2
+const restoreDeletedBatch = `-- name: RestoreDeletedBatch :one
+UPDATE batches SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL AND purged_at IS NULL RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
 `
 
-type HardDeleteBatchByIDParams struct {
+type RestoreDeletedBatchParams struct {
 	ID     uuid.UUID
 	UserID uuid.UUID
 }
 
-func (q *Queries) HardDeleteBatchByID(ctx context.Context, arg HardDeleteBatchByIDParams) error {
-	_, err := q.db.ExecContext(ctx, hardDeleteBatchByID, arg.ID, arg.UserID)
+func (q *Queries) RestoreDeletedBatch(ctx context.Context, arg RestoreDeletedBatchParams) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, restoreDeletedBatch, arg.ID, arg.UserID)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
+const setBatchReportURLs = `-- name: SetBatchReportURLs :exec
+UPDATE batches SET report_url = $2, report_csv_url = $3 WHERE id = $1
+`
+
+type SetBatchReportURLsParams struct {
+	ID           uuid.UUID
+	ReportUrl    sql.NullString
+	ReportCsvUrl sql.NullString
+}
+
+func (q *Queries) SetBatchReportURLs(ctx context.Context, arg SetBatchReportURLsParams) error {
+	_, err := q.db.ExecContext(ctx, setBatchReportURLs, arg.ID, arg.ReportUrl, arg.ReportCsvUrl)
+	return err
+}
+
+const setBatchWaiting = `-- name: SetBatchWaiting :exec
+UPDATE batches SET waiting_since = NOW() WHERE id = $1
+`
+
+func (q *Queries) SetBatchWaiting(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, setBatchWaiting, id)
+	return err
+}
+
+const startNextWaitingBatch = `-- name: StartNextWaitingBatch :one
+UPDATE batches SET waiting_since = NULL, updated_at = NOW() WHERE id = (SELECT w.id FROM batches w WHERE w.user_id = $1 AND w.waiting_since IS NOT NULL AND w.deleted_at IS NULL ORDER BY w.waiting_since, w.id LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
+`
+
+func (q *Queries) StartNextWaitingBatch(ctx context.Context, userID uuid.UUID) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, startNextWaitingBatch, userID)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
+const updateBatchArchiveStatus = `-- name: UpdateBatchArchiveStatus :exec
+UPDATE batches SET archive_status = $1, updated_at = NOW() WHERE id = $2
+`
+
+type UpdateBatchArchiveStatusParams struct {
+	ArchiveStatus BatchArchiveStatus
+	ID            uuid.UUID
+}
+
+func (q *Queries) UpdateBatchArchiveStatus(ctx context.Context, arg UpdateBatchArchiveStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateBatchArchiveStatus, arg.ArchiveStatus, arg.ID)
 	return err
 }
+
+const updateBatchByID = `-- name: UpdateBatchByID :one
+UPDATE batches b SET name = COALESCE($1, name), watermark_text = COALESCE($2, watermark_text), watermark_position = COALESCE($3::watermark_position, watermark_position), watermark_opacity = COALESCE($4, watermark_opacity), watermark_scale = COALESCE($5, watermark_scale), watermark_tile_spacing = COALESCE($6, watermark_tile_spacing), updated_at = NOW() WHERE b.id = $7 AND b.user_id = $8 AND b.deleted_at IS NULL AND (NOT $9::bool OR NOT EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.status <> 'pending' AND i.deleted_at IS NULL)) RETURNING id, user_id, name, watermark_url, created_at, updated_at, deleted_at, watermark_key, archive_status, archived_at, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, waiting_since, region, report_csv, report_url, report_csv_url, report_generated_at, expires_at, deadline, purged_at
+`
+
+type UpdateBatchByIDParams struct {
+	Name                 sql.NullString
+	WatermarkText        sql.NullString
+	WatermarkPosition    NullWatermarkPosition
+	WatermarkOpacity     sql.NullInt32
+	WatermarkScale       sql.NullInt32
+	WatermarkTileSpacing sql.NullInt32
+	ID                   uuid.UUID
+	UserID               uuid.UUID
+	ChangesWatermark     bool
+}
+
+func (q *Queries) UpdateBatchByID(ctx context.Context, arg UpdateBatchByIDParams) (Batch, error) {
+	row := q.db.QueryRowContext(ctx, updateBatchByID,
+		arg.Name,
+		arg.WatermarkText,
+		arg.WatermarkPosition,
+		arg.WatermarkOpacity,
+		arg.WatermarkScale,
+		arg.WatermarkTileSpacing,
+		arg.ID,
+		arg.UserID,
+		arg.ChangesWatermark,
+	)
+	var i Batch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.WatermarkUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.WatermarkKey,
+		&i.ArchiveStatus,
+		&i.ArchivedAt,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkFontID,
+		&i.WatermarkPosition,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.MaxConcurrency,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.ExternalID,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.WatermarkID,
+		&i.Pipeline,
+		&i.WaitingSince,
+		&i.Region,
+		&i.ReportCsv,
+		&i.ReportUrl,
+		&i.ReportCsvUrl,
+		&i.ReportGeneratedAt,
+		&i.ExpiresAt,
+		&i.Deadline,
+		&i.PurgedAt,
+	)
+	return i, err
+}
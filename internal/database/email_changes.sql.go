@@ -0,0 +1,139 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: email_changes.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const cancelEmailChange = `-- name: CancelEmailChange :exec
+UPDATE email_changes SET cancelled_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) CancelEmailChange(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, cancelEmailChange, id)
+	return err
+}
+
+const cancelPendingEmailChanges = `-- name: CancelPendingEmailChanges :exec
+UPDATE email_changes SET cancelled_at = NOW() WHERE user_id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL
+`
+
+func (q *Queries) CancelPendingEmailChanges(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, cancelPendingEmailChanges, userID)
+	return err
+}
+
+const confirmEmailChange = `-- name: ConfirmEmailChange :exec
+UPDATE email_changes SET confirmed_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) ConfirmEmailChange(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, confirmEmailChange, id)
+	return err
+}
+
+const createEmailChange = `-- name: CreateEmailChange :one
+INSERT INTO email_changes(user_id, old_email, new_email, confirm_token_hash, undo_token_hash, expires_at, undo_expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, user_id, old_email, new_email, confirm_token_hash, undo_token_hash, expires_at, undo_expires_at, confirmed_at, cancelled_at, reverted_at, created_at
+`
+
+type CreateEmailChangeParams struct {
+	UserID           uuid.UUID
+	OldEmail         string
+	NewEmail         string
+	ConfirmTokenHash string
+	UndoTokenHash    string
+	ExpiresAt        time.Time
+	UndoExpiresAt    time.Time
+}
+
+func (q *Queries) CreateEmailChange(ctx context.Context, arg CreateEmailChangeParams) (EmailChange, error) {
+	row := q.db.QueryRowContext(ctx, createEmailChange,
+		arg.UserID,
+		arg.OldEmail,
+		arg.NewEmail,
+		arg.ConfirmTokenHash,
+		arg.UndoTokenHash,
+		arg.ExpiresAt,
+		arg.UndoExpiresAt,
+	)
+	var i EmailChange
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OldEmail,
+		&i.NewEmail,
+		&i.ConfirmTokenHash,
+		&i.UndoTokenHash,
+		&i.ExpiresAt,
+		&i.UndoExpiresAt,
+		&i.ConfirmedAt,
+		&i.CancelledAt,
+		&i.RevertedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPendingEmailChangeByConfirmToken = `-- name: GetPendingEmailChangeByConfirmToken :one
+SELECT id, user_id, old_email, new_email, confirm_token_hash, undo_token_hash, expires_at, undo_expires_at, confirmed_at, cancelled_at, reverted_at, created_at FROM email_changes WHERE confirm_token_hash = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW() FOR UPDATE
+`
+
+func (q *Queries) GetPendingEmailChangeByConfirmToken(ctx context.Context, confirmTokenHash string) (EmailChange, error) {
+	row := q.db.QueryRowContext(ctx, getPendingEmailChangeByConfirmToken, confirmTokenHash)
+	var i EmailChange
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OldEmail,
+		&i.NewEmail,
+		&i.ConfirmTokenHash,
+		&i.UndoTokenHash,
+		&i.ExpiresAt,
+		&i.UndoExpiresAt,
+		&i.ConfirmedAt,
+		&i.CancelledAt,
+		&i.RevertedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUndoableEmailChange = `-- name: GetUndoableEmailChange :one
+SELECT id, user_id, old_email, new_email, confirm_token_hash, undo_token_hash, expires_at, undo_expires_at, confirmed_at, cancelled_at, reverted_at, created_at FROM email_changes WHERE undo_token_hash = $1 AND cancelled_at IS NULL AND reverted_at IS NULL AND undo_expires_at > NOW() FOR UPDATE
+`
+
+func (q *Queries) GetUndoableEmailChange(ctx context.Context, undoTokenHash string) (EmailChange, error) {
+	row := q.db.QueryRowContext(ctx, getUndoableEmailChange, undoTokenHash)
+	var i EmailChange
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OldEmail,
+		&i.NewEmail,
+		&i.ConfirmTokenHash,
+		&i.UndoTokenHash,
+		&i.ExpiresAt,
+		&i.UndoExpiresAt,
+		&i.ConfirmedAt,
+		&i.CancelledAt,
+		&i.RevertedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const revertEmailChange = `-- name: RevertEmailChange :exec
+UPDATE email_changes SET reverted_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) RevertEmailChange(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revertEmailChange, id)
+	return err
+}
@@ -0,0 +1,91 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: email_domain_rules.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createEmailDomainRule = `-- name: CreateEmailDomainRule :one
+INSERT INTO email_domain_rules(domain, rule) VALUES ($1, $2) RETURNING id, domain, rule, created_at
+`
+
+type CreateEmailDomainRuleParams struct {
+	Domain string
+	Rule   EmailDomainRuleType
+}
+
+func (q *Queries) CreateEmailDomainRule(ctx context.Context, arg CreateEmailDomainRuleParams) (EmailDomainRule, error) {
+	row := q.db.QueryRowContext(ctx, createEmailDomainRule, arg.Domain, arg.Rule)
+	var i EmailDomainRule
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Rule,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteEmailDomainRuleByID = `-- name: DeleteEmailDomainRuleByID :exec
+DELETE FROM email_domain_rules WHERE id = $1
+`
+
+func (q *Queries) DeleteEmailDomainRuleByID(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteEmailDomainRuleByID, id)
+	return err
+}
+
+const getEmailDomainPolicy = `-- name: GetEmailDomainPolicy :one
+SELECT COUNT(*) FILTER (WHERE rule = 'block' AND (domain = $1 OR right($1, length(domain) + 1) = '.' || domain)) AS blocked_count, COUNT(*) FILTER (WHERE rule = 'allow') AS allow_count, COUNT(*) FILTER (WHERE rule = 'allow' AND (domain = $1 OR right($1, length(domain) + 1) = '.' || domain)) AS allowed_count FROM email_domain_rules
+`
+
+type GetEmailDomainPolicyRow struct {
+	BlockedCount int64
+	AllowCount   int64
+	AllowedCount int64
+}
+
+func (q *Queries) GetEmailDomainPolicy(ctx context.Context, domain string) (GetEmailDomainPolicyRow, error) {
+	row := q.db.QueryRowContext(ctx, getEmailDomainPolicy, domain)
+	var i GetEmailDomainPolicyRow
+	err := row.Scan(&i.BlockedCount, &i.AllowCount, &i.AllowedCount)
+	return i, err
+}
+
+const getEmailDomainRules = `-- name: GetEmailDomainRules :many
+SELECT id, domain, rule, created_at FROM email_domain_rules ORDER BY rule, domain
+`
+
+func (q *Queries) GetEmailDomainRules(ctx context.Context) ([]EmailDomainRule, error) {
+	rows, err := q.db.QueryContext(ctx, getEmailDomainRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EmailDomainRule
+	for rows.Next() {
+		var i EmailDomainRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Rule,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,83 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: feature_flags.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const disableUserFeatureFlag = `-- name: DisableUserFeatureFlag :exec
+DELETE FROM user_feature_flags WHERE user_id = $1 AND flag = $2
+`
+
+type DisableUserFeatureFlagParams struct {
+	UserID uuid.UUID
+	Flag   string
+}
+
+func (q *Queries) DisableUserFeatureFlag(ctx context.Context, arg DisableUserFeatureFlagParams) error {
+	_, err := q.db.ExecContext(ctx, disableUserFeatureFlag, arg.UserID, arg.Flag)
+	return err
+}
+
+const enableUserFeatureFlag = `-- name: EnableUserFeatureFlag :exec
+INSERT INTO user_feature_flags(user_id, flag) VALUES ($1, $2) ON CONFLICT DO NOTHING
+`
+
+type EnableUserFeatureFlagParams struct {
+	UserID uuid.UUID
+	Flag   string
+}
+
+func (q *Queries) EnableUserFeatureFlag(ctx context.Context, arg EnableUserFeatureFlagParams) error {
+	_, err := q.db.ExecContext(ctx, enableUserFeatureFlag, arg.UserID, arg.Flag)
+	return err
+}
+
+const getUserFeatureFlags = `-- name: GetUserFeatureFlags :many
+SELECT flag FROM user_feature_flags WHERE user_id = $1 ORDER BY flag
+`
+
+func (q *Queries) GetUserFeatureFlags(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getUserFeatureFlags, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var flag string
+		if err := rows.Scan(&flag); err != nil {
+			return nil, err
+		}
+		items = append(items, flag)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hasUserFeatureFlag = `-- name: HasUserFeatureFlag :one
+SELECT EXISTS(SELECT 1 FROM user_feature_flags WHERE user_id = $1 AND flag = $2)
+`
+
+type HasUserFeatureFlagParams struct {
+	UserID uuid.UUID
+	Flag   string
+}
+
+func (q *Queries) HasUserFeatureFlag(ctx context.Context, arg HasUserFeatureFlagParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasUserFeatureFlag, arg.UserID, arg.Flag)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
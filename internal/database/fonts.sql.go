@@ -0,0 +1,168 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: fonts.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createFont = `-- name: CreateFont :one
+INSERT INTO fonts(user_id, name, key, format, size_bytes, region) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, user_id, name, key, format, size_bytes, created_at, deleted_at, region
+`
+
+type CreateFontParams struct {
+	UserID    uuid.UUID
+	Name      string
+	Key       string
+	Format    string
+	SizeBytes int64
+	Region    string
+}
+
+func (q *Queries) CreateFont(ctx context.Context, arg CreateFontParams) (Font, error) {
+	row := q.db.QueryRowContext(ctx, createFont,
+		arg.UserID,
+		arg.Name,
+		arg.Key,
+		arg.Format,
+		arg.SizeBytes,
+		arg.Region,
+	)
+	var i Font
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Key,
+		&i.Format,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Region,
+	)
+	return i, err
+}
+
+const deleteFontByID = `-- name: DeleteFontByID :one
+UPDATE fonts SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL RETURNING id, user_id, name, key, format, size_bytes, created_at, deleted_at, region
+`
+
+type DeleteFontByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteFontByID(ctx context.Context, arg DeleteFontByIDParams) (Font, error) {
+	row := q.db.QueryRowContext(ctx, deleteFontByID, arg.ID, arg.UserID)
+	var i Font
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Key,
+		&i.Format,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Region,
+	)
+	return i, err
+}
+
+const getQueuedWatermarkFontKeys = `-- name: GetQueuedWatermarkFontKeys :many
+SELECT f.key FROM fonts f
+WHERE f.deleted_at IS NULL AND EXISTS (SELECT 1 FROM batches b JOIN images i ON i.batch_id = b.id WHERE b.watermark_font_id = f.id AND b.deleted_at IS NULL AND i.status IN ('pending', 'processing') AND i.deleted_at IS NULL)
+ORDER BY f.created_at DESC, f.id
+LIMIT $1
+`
+
+func (q *Queries) GetQueuedWatermarkFontKeys(ctx context.Context, limit int32) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getQueuedWatermarkFontKeys, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		items = append(items, key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserFontByID = `-- name: GetUserFontByID :one
+SELECT id, user_id, name, key, format, size_bytes, created_at, deleted_at, region FROM fonts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type GetUserFontByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetUserFontByID(ctx context.Context, arg GetUserFontByIDParams) (Font, error) {
+	row := q.db.QueryRowContext(ctx, getUserFontByID, arg.ID, arg.UserID)
+	var i Font
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Key,
+		&i.Format,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Region,
+	)
+	return i, err
+}
+
+const getUserFonts = `-- name: GetUserFonts :many
+SELECT id, user_id, name, key, format, size_bytes, created_at, deleted_at, region FROM fonts WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUserFonts(ctx context.Context, userID uuid.UUID) ([]Font, error) {
+	rows, err := q.db.QueryContext(ctx, getUserFonts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Font
+	for rows.Next() {
+		var i Font
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Key,
+			&i.Format,
+			&i.SizeBytes,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Region,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
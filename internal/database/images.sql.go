@@ -8,24 +8,184 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
+const cancelPendingBatchImages = `-- name: CancelPendingBatchImages :many
+UPDATE images SET status = 'cancelled', updated_at = NOW() WHERE batch_id = $1 AND status = 'pending' AND deleted_at IS NULL RETURNING id
+`
+
+func (q *Queries) CancelPendingBatchImages(ctx context.Context, batchID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, cancelPendingBatchImages, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimImageSlot = `-- name: ClaimImageSlot :execrows
+UPDATE images i SET status = 'processing', failure_reason = NULL, attempts = i.attempts + 1, updated_at = NOW()
+WHERE i.id = $1 AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing') AND (
+    i.status = 'processing'
+    OR (SELECT COUNT(*) FROM images p WHERE p.batch_id = $2 AND p.status = 'processing' AND p.deleted_at IS NULL AND p.updated_at > $3) < $4::bigint
+)
+`
+
+type ClaimImageSlotParams struct {
+	ID             uuid.UUID
+	BatchID        uuid.UUID
+	ActiveSince    time.Time
+	MaxConcurrency int64
+}
+
+func (q *Queries) ClaimImageSlot(ctx context.Context, arg ClaimImageSlotParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimImageSlot,
+		arg.ID,
+		arg.BatchID,
+		arg.ActiveSince,
+		arg.MaxConcurrency,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const completeImageByID = `-- name: CompleteImageByID :exec
+UPDATE images SET status = 'completed', updated_at = NOW(), processed_url = $1, palette = $2, blurhash = $3, thumbhash = $4, width = $5, height = $6, thumbnail_url = $7, applied_watermark_position = $8, processed_size = $9, processed_format = $10, original_width = $11, original_height = $12, original_size = $13, original_format = $14, original_dominant_color = $15, phash = $16, step_timings = $17, captured_at = $18, similar_to = NULL WHERE id = $19 AND deleted_at IS NULL
+`
+
+type CompleteImageByIDParams struct {
+	ProcessedUrl             sql.NullString
+	Palette                  []string
+	Blurhash                 sql.NullString
+	Thumbhash                sql.NullString
+	Width                    sql.NullInt32
+	Height                   sql.NullInt32
+	ThumbnailUrl             sql.NullString
+	AppliedWatermarkPosition NullWatermarkPosition
+	ProcessedSize            sql.NullInt64
+	ProcessedFormat          sql.NullString
+	OriginalWidth            sql.NullInt32
+	OriginalHeight           sql.NullInt32
+	OriginalSize             sql.NullInt64
+	OriginalFormat           sql.NullString
+	OriginalDominantColor    sql.NullString
+	Phash                    sql.NullInt64
+	StepTimings              json.RawMessage
+	CapturedAt               sql.NullTime
+	ID                       uuid.UUID
+}
+
+func (q *Queries) CompleteImageByID(ctx context.Context, arg CompleteImageByIDParams) error {
+	_, err := q.db.ExecContext(ctx, completeImageByID,
+		arg.ProcessedUrl,
+		pq.Array(arg.Palette),
+		arg.Blurhash,
+		arg.Thumbhash,
+		arg.Width,
+		arg.Height,
+		arg.ThumbnailUrl,
+		arg.AppliedWatermarkPosition,
+		arg.ProcessedSize,
+		arg.ProcessedFormat,
+		arg.OriginalWidth,
+		arg.OriginalHeight,
+		arg.OriginalSize,
+		arg.OriginalFormat,
+		arg.OriginalDominantColor,
+		arg.Phash,
+		arg.StepTimings,
+		arg.CapturedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const countSearchUserImages = `-- name: CountSearchUserImages :one
+SELECT COUNT(*) FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE b.user_id = $1 AND b.deleted_at IS NULL AND i.deleted_at IS NULL AND ($2::text IS NULL OR i.filename ILIKE '%' || $2::text || '%' ESCAPE '\') AND ($3::image_status IS NULL OR i.status = $3::image_status) AND ($4::timestamp IS NULL OR i.created_at >= $4::timestamp) AND ($5::timestamp IS NULL OR i.created_at <= $5::timestamp) AND ($6::uuid IS NULL OR i.batch_id = $6::uuid) AND ($7::text IS NULL OR i.external_id = $7::text) AND ($8::timestamp IS NULL OR i.captured_at >= $8::timestamp) AND ($9::timestamp IS NULL OR i.captured_at <= $9::timestamp)
+`
+
+type CountSearchUserImagesParams struct {
+	UserID       uuid.UUID
+	Filename     sql.NullString
+	Status       NullImageStatus
+	FromTime     sql.NullTime
+	ToTime       sql.NullTime
+	BatchID      uuid.NullUUID
+	ExternalID   sql.NullString
+	CapturedFrom sql.NullTime
+	CapturedTo   sql.NullTime
+}
+
+func (q *Queries) CountSearchUserImages(ctx context.Context, arg CountSearchUserImagesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSearchUserImages,
+		arg.UserID,
+		arg.Filename,
+		arg.Status,
+		arg.FromTime,
+		arg.ToTime,
+		arg.BatchID,
+		arg.ExternalID,
+		arg.CapturedFrom,
+		arg.CapturedTo,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createImage = `-- name: CreateImage :one
-INSERT INTO images(batch_id, key, original_url) VALUES($1, $2, $3) RETURNING id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at
+INSERT INTO images(batch_id, key, original_url, filename, content_hash, watermark_position_override, watermark_opacity_override, watermark_scale_override, external_id, redactions, source_url) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at, filename, placement_x, placement_y, placement_width, placement_height, palette, blurhash, width, height, thumbhash, thumbnail_url, content_hash, applied_watermark_position, watermark_position_override, watermark_opacity_override, watermark_scale_override, original_width, original_height, original_size, original_format, original_dominant_color, processed_size, processed_format, phash, similar_to, external_id, redactions, step_timings, source_url, failure_reason, captured_at, attempts, change_xid, owner_id, batch_deleted
 `
 
 type CreateImageParams struct {
-	BatchID     uuid.UUID
-	Key         string
-	OriginalUrl string
+	BatchID                   uuid.UUID
+	Key                       string
+	OriginalUrl               string
+	Filename                  sql.NullString
+	ContentHash               sql.NullString
+	WatermarkPositionOverride NullWatermarkPosition
+	WatermarkOpacityOverride  sql.NullInt32
+	WatermarkScaleOverride    sql.NullInt32
+	ExternalID                sql.NullString
+	Redactions                json.RawMessage
+	SourceUrl                 sql.NullString
 }
 
 func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (Image, error) {
-	row := q.db.QueryRowContext(ctx, createImage, arg.BatchID, arg.Key, arg.OriginalUrl)
+	row := q.db.QueryRowContext(ctx, createImage,
+		arg.BatchID,
+		arg.Key,
+		arg.OriginalUrl,
+		arg.Filename,
+		arg.ContentHash,
+		arg.WatermarkPositionOverride,
+		arg.WatermarkOpacityOverride,
+		arg.WatermarkScaleOverride,
+		arg.ExternalID,
+		arg.Redactions,
+		arg.SourceUrl,
+	)
 	var i Image
 	err := row.Scan(
 		&i.ID,
@@ -37,10 +197,117 @@ func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (Image
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.Filename,
+		&i.PlacementX,
+		&i.PlacementY,
+		&i.PlacementWidth,
+		&i.PlacementHeight,
+		pq.Array(&i.Palette),
+		&i.Blurhash,
+		&i.Width,
+		&i.Height,
+		&i.Thumbhash,
+		&i.ThumbnailUrl,
+		&i.ContentHash,
+		&i.AppliedWatermarkPosition,
+		&i.WatermarkPositionOverride,
+		&i.WatermarkOpacityOverride,
+		&i.WatermarkScaleOverride,
+		&i.OriginalWidth,
+		&i.OriginalHeight,
+		&i.OriginalSize,
+		&i.OriginalFormat,
+		&i.OriginalDominantColor,
+		&i.ProcessedSize,
+		&i.ProcessedFormat,
+		&i.Phash,
+		&i.SimilarTo,
+		&i.ExternalID,
+		&i.Redactions,
+		&i.StepTimings,
+		&i.SourceUrl,
+		&i.FailureReason,
+		&i.CapturedAt,
+		&i.Attempts,
+		&i.ChangeXid,
+		&i.OwnerID,
+		&i.BatchDeleted,
 	)
 	return i, err
 }
 
+const deleteBatchImages = `-- name: DeleteBatchImages :many
+UPDATE images SET deleted_at = NOW(), updated_at = NOW() WHERE batch_id = $1 AND deleted_at IS NULL RETURNING id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at, filename, placement_x, placement_y, placement_width, placement_height, palette, blurhash, width, height, thumbhash, thumbnail_url, content_hash, applied_watermark_position, watermark_position_override, watermark_opacity_override, watermark_scale_override, original_width, original_height, original_size, original_format, original_dominant_color, processed_size, processed_format, phash, similar_to, external_id, redactions, step_timings, source_url, failure_reason, captured_at, attempts, change_xid, owner_id, batch_deleted
+`
+
+func (q *Queries) DeleteBatchImages(ctx context.Context, batchID uuid.UUID) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, deleteBatchImages, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.Key,
+			&i.OriginalUrl,
+			&i.ProcessedUrl,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Filename,
+			&i.PlacementX,
+			&i.PlacementY,
+			&i.PlacementWidth,
+			&i.PlacementHeight,
+			pq.Array(&i.Palette),
+			&i.Blurhash,
+			&i.Width,
+			&i.Height,
+			&i.Thumbhash,
+			&i.ThumbnailUrl,
+			&i.ContentHash,
+			&i.AppliedWatermarkPosition,
+			&i.WatermarkPositionOverride,
+			&i.WatermarkOpacityOverride,
+			&i.WatermarkScaleOverride,
+			&i.OriginalWidth,
+			&i.OriginalHeight,
+			&i.OriginalSize,
+			&i.OriginalFormat,
+			&i.OriginalDominantColor,
+			&i.ProcessedSize,
+			&i.ProcessedFormat,
+			&i.Phash,
+			&i.SimilarTo,
+			&i.ExternalID,
+			&i.Redactions,
+			&i.StepTimings,
+			&i.SourceUrl,
+			&i.FailureReason,
+			&i.CapturedAt,
+			&i.Attempts,
+			&i.ChangeXid,
+			&i.OwnerID,
+			&i.BatchDeleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteImageByID = `-- name: DeleteImageByID :exec
 UPDATE images SET deleted_at = NOW() WHERE id = $1 AND batch_id = ANY($2::UUID[])
 `
@@ -55,22 +322,482 @@ func (q *Queries) DeleteImageByID(ctx context.Context, arg DeleteImageByIDParams
 	return err
 }
 
+const deleteUserImagesByIDs = `-- name: DeleteUserImagesByIDs :many
+UPDATE images i SET deleted_at = NOW() FROM batches b WHERE b.id = i.batch_id AND b.user_id = $1 AND b.deleted_at IS NULL AND i.id = ANY($2::UUID[]) AND i.deleted_at IS NULL RETURNING i.id, i.batch_id, i.key, i.original_url, i.processed_url, i.status, i.created_at, i.updated_at, i.deleted_at, i.filename, i.placement_x, i.placement_y, i.placement_width, i.placement_height, i.palette, i.blurhash, i.width, i.height, i.thumbhash, i.thumbnail_url, i.content_hash, i.applied_watermark_position, i.watermark_position_override, i.watermark_opacity_override, i.watermark_scale_override, i.original_width, i.original_height, i.original_size, i.original_format, i.original_dominant_color, i.processed_size, i.processed_format, i.phash, i.similar_to, i.external_id, i.redactions, i.step_timings, i.source_url, i.failure_reason, i.captured_at, i.attempts, i.change_xid, i.owner_id, i.batch_deleted, b.region AS batch_region
+`
+
+type DeleteUserImagesByIDsParams struct {
+	UserID   uuid.UUID
+	ImageIds []uuid.UUID
+}
+
+type DeleteUserImagesByIDsRow struct {
+	ID                        uuid.UUID
+	BatchID                   uuid.UUID
+	Key                       string
+	OriginalUrl               string
+	ProcessedUrl              sql.NullString
+	Status                    ImageStatus
+	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+	DeletedAt                 sql.NullTime
+	Filename                  sql.NullString
+	PlacementX                sql.NullFloat64
+	PlacementY                sql.NullFloat64
+	PlacementWidth            sql.NullFloat64
+	PlacementHeight           sql.NullFloat64
+	Palette                   []string
+	Blurhash                  sql.NullString
+	Width                     sql.NullInt32
+	Height                    sql.NullInt32
+	Thumbhash                 sql.NullString
+	ThumbnailUrl              sql.NullString
+	ContentHash               sql.NullString
+	AppliedWatermarkPosition  NullWatermarkPosition
+	WatermarkPositionOverride NullWatermarkPosition
+	WatermarkOpacityOverride  sql.NullInt32
+	WatermarkScaleOverride    sql.NullInt32
+	OriginalWidth             sql.NullInt32
+	OriginalHeight            sql.NullInt32
+	OriginalSize              sql.NullInt64
+	OriginalFormat            sql.NullString
+	OriginalDominantColor     sql.NullString
+	ProcessedSize             sql.NullInt64
+	ProcessedFormat           sql.NullString
+	Phash                     sql.NullInt64
+	SimilarTo                 uuid.NullUUID
+	ExternalID                sql.NullString
+	Redactions                json.RawMessage
+	StepTimings               json.RawMessage
+	SourceUrl                 sql.NullString
+	FailureReason             sql.NullString
+	CapturedAt                sql.NullTime
+	Attempts                  int32
+	ChangeXid                 int64
+	OwnerID                   uuid.UUID
+	BatchDeleted              bool
+	BatchRegion               string
+}
+
+func (q *Queries) DeleteUserImagesByIDs(ctx context.Context, arg DeleteUserImagesByIDsParams) ([]DeleteUserImagesByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, deleteUserImagesByIDs, arg.UserID, pq.Array(arg.ImageIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeleteUserImagesByIDsRow
+	for rows.Next() {
+		var i DeleteUserImagesByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.Key,
+			&i.OriginalUrl,
+			&i.ProcessedUrl,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Filename,
+			&i.PlacementX,
+			&i.PlacementY,
+			&i.PlacementWidth,
+			&i.PlacementHeight,
+			pq.Array(&i.Palette),
+			&i.Blurhash,
+			&i.Width,
+			&i.Height,
+			&i.Thumbhash,
+			&i.ThumbnailUrl,
+			&i.ContentHash,
+			&i.AppliedWatermarkPosition,
+			&i.WatermarkPositionOverride,
+			&i.WatermarkOpacityOverride,
+			&i.WatermarkScaleOverride,
+			&i.OriginalWidth,
+			&i.OriginalHeight,
+			&i.OriginalSize,
+			&i.OriginalFormat,
+			&i.OriginalDominantColor,
+			&i.ProcessedSize,
+			&i.ProcessedFormat,
+			&i.Phash,
+			&i.SimilarTo,
+			&i.ExternalID,
+			&i.Redactions,
+			&i.StepTimings,
+			&i.SourceUrl,
+			&i.FailureReason,
+			&i.CapturedAt,
+			&i.Attempts,
+			&i.ChangeXid,
+			&i.OwnerID,
+			&i.BatchDeleted,
+			&i.BatchRegion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const expireImageByID = `-- name: ExpireImageByID :execrows
+UPDATE images SET status = 'expired', failure_reason = 'batch deadline passed', updated_at = NOW() WHERE id = $1 AND status IN ('pending', 'processing') AND deleted_at IS NULL
+`
+
+func (q *Queries) ExpireImageByID(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, expireImageByID, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const expirePendingBatchImages = `-- name: ExpirePendingBatchImages :many
+UPDATE images SET status = 'expired', failure_reason = 'batch deadline passed', updated_at = NOW() WHERE batch_id = $1 AND status = 'pending' AND deleted_at IS NULL RETURNING id
+`
+
+func (q *Queries) ExpirePendingBatchImages(ctx context.Context, batchID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, expirePendingBatchImages, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const failImageByID = `-- name: FailImageByID :exec
+UPDATE images SET status = 'failed', processed_url = NULL, failure_reason = $2, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+`
+
+type FailImageByIDParams struct {
+	ID            uuid.UUID
+	FailureReason sql.NullString
+}
+
+func (q *Queries) FailImageByID(ctx context.Context, arg FailImageByIDParams) error {
+	_, err := q.db.ExecContext(ctx, failImageByID, arg.ID, arg.FailureReason)
+	return err
+}
+
+const getBatchImageByKey = `-- name: GetBatchImageByKey :one
+SELECT id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at, filename, placement_x, placement_y, placement_width, placement_height, palette, blurhash, width, height, thumbhash, thumbnail_url, content_hash, applied_watermark_position, watermark_position_override, watermark_opacity_override, watermark_scale_override, original_width, original_height, original_size, original_format, original_dominant_color, processed_size, processed_format, phash, similar_to, external_id, redactions, step_timings, source_url, failure_reason, captured_at, attempts, change_xid, owner_id, batch_deleted FROM images WHERE batch_id = $1 AND key = $2 AND deleted_at IS NULL ORDER BY created_at LIMIT 1
+`
+
+type GetBatchImageByKeyParams struct {
+	BatchID uuid.UUID
+	Key     string
+}
+
+func (q *Queries) GetBatchImageByKey(ctx context.Context, arg GetBatchImageByKeyParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, getBatchImageByKey, arg.BatchID, arg.Key)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.Key,
+		&i.OriginalUrl,
+		&i.ProcessedUrl,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Filename,
+		&i.PlacementX,
+		&i.PlacementY,
+		&i.PlacementWidth,
+		&i.PlacementHeight,
+		pq.Array(&i.Palette),
+		&i.Blurhash,
+		&i.Width,
+		&i.Height,
+		&i.Thumbhash,
+		&i.ThumbnailUrl,
+		&i.ContentHash,
+		&i.AppliedWatermarkPosition,
+		&i.WatermarkPositionOverride,
+		&i.WatermarkOpacityOverride,
+		&i.WatermarkScaleOverride,
+		&i.OriginalWidth,
+		&i.OriginalHeight,
+		&i.OriginalSize,
+		&i.OriginalFormat,
+		&i.OriginalDominantColor,
+		&i.ProcessedSize,
+		&i.ProcessedFormat,
+		&i.Phash,
+		&i.SimilarTo,
+		&i.ExternalID,
+		&i.Redactions,
+		&i.StepTimings,
+		&i.SourceUrl,
+		&i.FailureReason,
+		&i.CapturedAt,
+		&i.Attempts,
+		&i.ChangeXid,
+		&i.OwnerID,
+		&i.BatchDeleted,
+	)
+	return i, err
+}
+
+const getBatchImageChanges = `-- name: GetBatchImageChanges :many
+SELECT id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at, filename, placement_x, placement_y, placement_width, placement_height, palette, blurhash, width, height, thumbhash, thumbnail_url, content_hash, applied_watermark_position, watermark_position_override, watermark_opacity_override, watermark_scale_override, original_width, original_height, original_size, original_format, original_dominant_color, processed_size, processed_format, phash, similar_to, external_id, redactions, step_timings, source_url, failure_reason, captured_at, attempts, change_xid, owner_id, batch_deleted FROM images WHERE batch_id = $1 AND (change_xid, id) > ($2::bigint, $3::uuid) AND change_xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint ORDER BY change_xid, id LIMIT $4
+`
+
+type GetBatchImageChangesParams struct {
+	BatchID   uuid.UUID
+	SinceXid  int64
+	SinceID   uuid.UUID
+	PageLimit int32
+}
+
+func (q *Queries) GetBatchImageChanges(ctx context.Context, arg GetBatchImageChangesParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, getBatchImageChanges,
+		arg.BatchID,
+		arg.SinceXid,
+		arg.SinceID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.Key,
+			&i.OriginalUrl,
+			&i.ProcessedUrl,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Filename,
+			&i.PlacementX,
+			&i.PlacementY,
+			&i.PlacementWidth,
+			&i.PlacementHeight,
+			pq.Array(&i.Palette),
+			&i.Blurhash,
+			&i.Width,
+			&i.Height,
+			&i.Thumbhash,
+			&i.ThumbnailUrl,
+			&i.ContentHash,
+			&i.AppliedWatermarkPosition,
+			&i.WatermarkPositionOverride,
+			&i.WatermarkOpacityOverride,
+			&i.WatermarkScaleOverride,
+			&i.OriginalWidth,
+			&i.OriginalHeight,
+			&i.OriginalSize,
+			&i.OriginalFormat,
+			&i.OriginalDominantColor,
+			&i.ProcessedSize,
+			&i.ProcessedFormat,
+			&i.Phash,
+			&i.SimilarTo,
+			&i.ExternalID,
+			&i.Redactions,
+			&i.StepTimings,
+			&i.SourceUrl,
+			&i.FailureReason,
+			&i.CapturedAt,
+			&i.Attempts,
+			&i.ChangeXid,
+			&i.OwnerID,
+			&i.BatchDeleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBatchProgress = `-- name: GetBatchProgress :one
+SELECT COUNT(*) FILTER (WHERE status = 'pending') AS pending, COUNT(*) FILTER (WHERE status = 'processing') AS processing, COUNT(*) FILTER (WHERE status = 'completed') AS completed, COUNT(*) FILTER (WHERE status = 'failed') AS failed, COUNT(*) FILTER (WHERE status = 'cancelled') AS cancelled, COUNT(*) FILTER (WHERE status = 'expired') AS expired, COUNT(*) FILTER (WHERE status IN ('completed', 'failed') AND updated_at > NOW() - INTERVAL '5 minutes') AS recently_finished, COALESCE(EXTRACT(EPOCH FROM LEAST(INTERVAL '5 minutes', NOW() - MIN(created_at))), 0)::float8 AS window_seconds FROM images WHERE batch_id = $1 AND deleted_at IS NULL
+`
+
+type GetBatchProgressRow struct {
+	Pending          int64
+	Processing       int64
+	Completed        int64
+	Failed           int64
+	Cancelled        int64
+	Expired          int64
+	RecentlyFinished int64
+	WindowSeconds    float64
+}
+
+func (q *Queries) GetBatchProgress(ctx context.Context, batchID uuid.UUID) (GetBatchProgressRow, error) {
+	row := q.db.QueryRowContext(ctx, getBatchProgress, batchID)
+	var i GetBatchProgressRow
+	err := row.Scan(
+		&i.Pending,
+		&i.Processing,
+		&i.Completed,
+		&i.Failed,
+		&i.Cancelled,
+		&i.Expired,
+		&i.RecentlyFinished,
+		&i.WindowSeconds,
+	)
+	return i, err
+}
+
+const getBatchSavings = `-- name: GetBatchSavings :many
+SELECT COALESCE(original_format, '')::text AS original_format, COALESCE(processed_format, '')::text AS processed_format, COUNT(*) AS image_count, SUM(original_size)::bigint AS original_bytes, SUM(processed_size)::bigint AS processed_bytes FROM images WHERE batch_id = $1 AND status = 'completed' AND deleted_at IS NULL AND original_size IS NOT NULL AND processed_size IS NOT NULL GROUP BY 1, 2 ORDER BY 1, 2
+`
+
+type GetBatchSavingsRow struct {
+	OriginalFormat  string
+	ProcessedFormat string
+	ImageCount      int64
+	OriginalBytes   int64
+	ProcessedBytes  int64
+}
+
+func (q *Queries) GetBatchSavings(ctx context.Context, batchID uuid.UUID) ([]GetBatchSavingsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBatchSavings, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBatchSavingsRow
+	for rows.Next() {
+		var i GetBatchSavingsRow
+		if err := rows.Scan(
+			&i.OriginalFormat,
+			&i.ProcessedFormat,
+			&i.ImageCount,
+			&i.OriginalBytes,
+			&i.ProcessedBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getImageByID = `-- name: GetImageByID :one
-SELECT i.id, i.batch_id, i.key, i.original_url, i.processed_url, i.status, i.created_at, i.updated_at, i.deleted_at, b.watermark_url, b.watermark_key FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE i.id = $1 AND i.deleted_at IS NULL AND b.deleted_at IS NULL
+SELECT i.id, i.batch_id, i.key, i.original_url, i.processed_url, i.status, i.created_at, i.updated_at, i.deleted_at, i.filename, i.placement_x, i.placement_y, i.placement_width, i.placement_height, i.palette, i.blurhash, i.width, i.height, i.thumbhash, i.thumbnail_url, i.content_hash, i.applied_watermark_position, i.watermark_position_override, i.watermark_opacity_override, i.watermark_scale_override, i.original_width, i.original_height, i.original_size, i.original_format, i.original_dominant_color, i.processed_size, i.processed_format, i.phash, i.similar_to, i.external_id, i.redactions, i.step_timings, i.source_url, i.failure_reason, i.captured_at, i.attempts, i.change_xid, i.owner_id, i.batch_deleted, b.region AS batch_region, b.watermark_url, b.watermark_key, b.preserve_filenames, b.collision_policy, b.watermark_text, b.watermark_position, b.watermark_opacity, b.watermark_scale, b.watermark_tile_spacing, b.max_width, b.max_height, b.preserve_metadata, b.invisible_watermark, b.user_id, b.output_format, b.output_quality, b.jpeg_progressive, b.jpeg_subsampling, b.png_compression, b.similar_dedupe, b.transforms, b.crop_aspect_ratio, b.crop_x, b.crop_y, b.crop_width, b.crop_height, b.pipeline, b.deadline AS batch_deadline, f.key AS watermark_font_key, w.key AS library_watermark_key FROM images i INNER JOIN batches b ON b.id = i.batch_id LEFT JOIN fonts f ON f.id = b.watermark_font_id AND f.deleted_at IS NULL LEFT JOIN watermarks w ON w.id = b.watermark_id WHERE i.id = $1 AND i.deleted_at IS NULL AND b.deleted_at IS NULL
 `
 
 type GetImageByIDRow struct {
-	ID           uuid.UUID
-	BatchID      uuid.UUID
-	Key          string
-	OriginalUrl  string
-	ProcessedUrl sql.NullString
-	Status       ImageStatus
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    sql.NullTime
-	WatermarkUrl sql.NullString
-	WatermarkKey sql.NullString
+	ID                        uuid.UUID
+	BatchID                   uuid.UUID
+	Key                       string
+	OriginalUrl               string
+	ProcessedUrl              sql.NullString
+	Status                    ImageStatus
+	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+	DeletedAt                 sql.NullTime
+	Filename                  sql.NullString
+	PlacementX                sql.NullFloat64
+	PlacementY                sql.NullFloat64
+	PlacementWidth            sql.NullFloat64
+	PlacementHeight           sql.NullFloat64
+	Palette                   []string
+	Blurhash                  sql.NullString
+	Width                     sql.NullInt32
+	Height                    sql.NullInt32
+	Thumbhash                 sql.NullString
+	ThumbnailUrl              sql.NullString
+	ContentHash               sql.NullString
+	AppliedWatermarkPosition  NullWatermarkPosition
+	WatermarkPositionOverride NullWatermarkPosition
+	WatermarkOpacityOverride  sql.NullInt32
+	WatermarkScaleOverride    sql.NullInt32
+	OriginalWidth             sql.NullInt32
+	OriginalHeight            sql.NullInt32
+	OriginalSize              sql.NullInt64
+	OriginalFormat            sql.NullString
+	OriginalDominantColor     sql.NullString
+	ProcessedSize             sql.NullInt64
+	ProcessedFormat           sql.NullString
+	Phash                     sql.NullInt64
+	SimilarTo                 uuid.NullUUID
+	ExternalID                sql.NullString
+	Redactions                json.RawMessage
+	StepTimings               json.RawMessage
+	SourceUrl                 sql.NullString
+	FailureReason             sql.NullString
+	CapturedAt                sql.NullTime
+	Attempts                  int32
+	ChangeXid                 int64
+	OwnerID                   uuid.UUID
+	BatchDeleted              bool
+	BatchRegion               string
+	WatermarkUrl              sql.NullString
+	WatermarkKey              sql.NullString
+	PreserveFilenames         bool
+	CollisionPolicy           OutputCollisionPolicy
+	WatermarkText             sql.NullString
+	WatermarkPosition         WatermarkPosition
+	WatermarkOpacity          int32
+	WatermarkScale            int32
+	WatermarkTileSpacing      int32
+	MaxWidth                  sql.NullInt32
+	MaxHeight                 sql.NullInt32
+	PreserveMetadata          bool
+	InvisibleWatermark        bool
+	UserID                    uuid.UUID
+	OutputFormat              OutputFormat
+	OutputQuality             int32
+	JpegProgressive           bool
+	JpegSubsampling           ChromaSubsampling
+	PngCompression            PngCompression
+	SimilarDedupe             SimilarDedupe
+	Transforms                json.RawMessage
+	CropAspectRatio           sql.NullString
+	CropX                     sql.NullInt32
+	CropY                     sql.NullInt32
+	CropWidth                 sql.NullInt32
+	CropHeight                sql.NullInt32
+	Pipeline                  json.RawMessage
+	BatchDeadline             sql.NullTime
+	WatermarkFontKey          sql.NullString
+	LibraryWatermarkKey       sql.NullString
 }
 
 func (q *Queries) GetImageByID(ctx context.Context, id uuid.UUID) (GetImageByIDRow, error) {
@@ -86,14 +813,110 @@ func (q *Queries) GetImageByID(ctx context.Context, id uuid.UUID) (GetImageByIDR
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.Filename,
+		&i.PlacementX,
+		&i.PlacementY,
+		&i.PlacementWidth,
+		&i.PlacementHeight,
+		pq.Array(&i.Palette),
+		&i.Blurhash,
+		&i.Width,
+		&i.Height,
+		&i.Thumbhash,
+		&i.ThumbnailUrl,
+		&i.ContentHash,
+		&i.AppliedWatermarkPosition,
+		&i.WatermarkPositionOverride,
+		&i.WatermarkOpacityOverride,
+		&i.WatermarkScaleOverride,
+		&i.OriginalWidth,
+		&i.OriginalHeight,
+		&i.OriginalSize,
+		&i.OriginalFormat,
+		&i.OriginalDominantColor,
+		&i.ProcessedSize,
+		&i.ProcessedFormat,
+		&i.Phash,
+		&i.SimilarTo,
+		&i.ExternalID,
+		&i.Redactions,
+		&i.StepTimings,
+		&i.SourceUrl,
+		&i.FailureReason,
+		&i.CapturedAt,
+		&i.Attempts,
+		&i.ChangeXid,
+		&i.OwnerID,
+		&i.BatchDeleted,
+		&i.BatchRegion,
 		&i.WatermarkUrl,
 		&i.WatermarkKey,
+		&i.PreserveFilenames,
+		&i.CollisionPolicy,
+		&i.WatermarkText,
+		&i.WatermarkPosition,
+		&i.WatermarkOpacity,
+		&i.WatermarkScale,
+		&i.WatermarkTileSpacing,
+		&i.MaxWidth,
+		&i.MaxHeight,
+		&i.PreserveMetadata,
+		&i.InvisibleWatermark,
+		&i.UserID,
+		&i.OutputFormat,
+		&i.OutputQuality,
+		&i.JpegProgressive,
+		&i.JpegSubsampling,
+		&i.PngCompression,
+		&i.SimilarDedupe,
+		&i.Transforms,
+		&i.CropAspectRatio,
+		&i.CropX,
+		&i.CropY,
+		&i.CropWidth,
+		&i.CropHeight,
+		&i.Pipeline,
+		&i.BatchDeadline,
+		&i.WatermarkFontKey,
+		&i.LibraryWatermarkKey,
 	)
 	return i, err
 }
 
+const getImageKeysSharedOutsideBatch = `-- name: GetImageKeysSharedOutsideBatch :many
+SELECT DISTINCT key FROM images WHERE key = ANY($1::TEXT[]) AND batch_id <> $2 AND deleted_at IS NULL
+`
+
+type GetImageKeysSharedOutsideBatchParams struct {
+	Keys    []string
+	BatchID uuid.UUID
+}
+
+func (q *Queries) GetImageKeysSharedOutsideBatch(ctx context.Context, arg GetImageKeysSharedOutsideBatchParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getImageKeysSharedOutsideBatch, pq.Array(arg.Keys), arg.BatchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		items = append(items, key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getImagesByBatchID = `-- name: GetImagesByBatchID :many
-SELECT id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at FROM images WHERE batch_id = $1 AND deleted_at IS NULL ORDER BY created_at
+SELECT id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at, filename, placement_x, placement_y, placement_width, placement_height, palette, blurhash, width, height, thumbhash, thumbnail_url, content_hash, applied_watermark_position, watermark_position_override, watermark_opacity_override, watermark_scale_override, original_width, original_height, original_size, original_format, original_dominant_color, processed_size, processed_format, phash, similar_to, external_id, redactions, step_timings, source_url, failure_reason, captured_at, attempts, change_xid, owner_id, batch_deleted FROM images WHERE batch_id = $1 AND deleted_at IS NULL ORDER BY created_at
 `
 
 func (q *Queries) GetImagesByBatchID(ctx context.Context, batchID uuid.UUID) ([]Image, error) {
@@ -115,6 +938,271 @@ func (q *Queries) GetImagesByBatchID(ctx context.Context, batchID uuid.UUID) ([]
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.Filename,
+			&i.PlacementX,
+			&i.PlacementY,
+			&i.PlacementWidth,
+			&i.PlacementHeight,
+			pq.Array(&i.Palette),
+			&i.Blurhash,
+			&i.Width,
+			&i.Height,
+			&i.Thumbhash,
+			&i.ThumbnailUrl,
+			&i.ContentHash,
+			&i.AppliedWatermarkPosition,
+			&i.WatermarkPositionOverride,
+			&i.WatermarkOpacityOverride,
+			&i.WatermarkScaleOverride,
+			&i.OriginalWidth,
+			&i.OriginalHeight,
+			&i.OriginalSize,
+			&i.OriginalFormat,
+			&i.OriginalDominantColor,
+			&i.ProcessedSize,
+			&i.ProcessedFormat,
+			&i.Phash,
+			&i.SimilarTo,
+			&i.ExternalID,
+			&i.Redactions,
+			&i.StepTimings,
+			&i.SourceUrl,
+			&i.FailureReason,
+			&i.CapturedAt,
+			&i.Attempts,
+			&i.ChangeXid,
+			&i.OwnerID,
+			&i.BatchDeleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getObjectURLsSharedOutsideBatch = `-- name: GetObjectURLsSharedOutsideBatch :many
+SELECT DISTINCT u.url::TEXT FROM images i CROSS JOIN LATERAL (VALUES (i.processed_url), (i.thumbnail_url)) AS u(url) WHERE u.url = ANY($1::TEXT[]) AND i.batch_id <> $2 AND i.deleted_at IS NULL
+`
+
+type GetObjectURLsSharedOutsideBatchParams struct {
+	Urls    []string
+	BatchID uuid.UUID
+}
+
+func (q *Queries) GetObjectURLsSharedOutsideBatch(ctx context.Context, arg GetObjectURLsSharedOutsideBatchParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getObjectURLsSharedOutsideBatch, pq.Array(arg.Urls), arg.BatchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var u_url string
+		if err := rows.Scan(&u_url); err != nil {
+			return nil, err
+		}
+		items = append(items, u_url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReferencedImageKeys = `-- name: GetReferencedImageKeys :many
+SELECT DISTINCT key FROM images WHERE key = ANY($1::TEXT[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetReferencedImageKeys(ctx context.Context, keys []string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getReferencedImageKeys, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		items = append(items, key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReferencedObjectURLs = `-- name: GetReferencedObjectURLs :many
+SELECT DISTINCT u.url::TEXT FROM images i CROSS JOIN LATERAL (VALUES (i.processed_url), (i.thumbnail_url)) AS u(url) WHERE u.url = ANY($1::TEXT[]) AND i.deleted_at IS NULL
+`
+
+func (q *Queries) GetReferencedObjectURLs(ctx context.Context, urls []string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getReferencedObjectURLs, pq.Array(urls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var u_url string
+		if err := rows.Scan(&u_url); err != nil {
+			return nil, err
+		}
+		items = append(items, u_url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSimilarImage = `-- name: GetSimilarImage :one
+SELECT i.id FROM images i INNER JOIN batches b ON b.id = i.batch_id INNER JOIN batches cur ON cur.id = $1::UUID WHERE b.user_id = cur.user_id AND b.region = cur.region AND i.id <> $2::UUID AND i.status = 'completed' AND i.processed_url IS NOT NULL AND i.phash IS NOT NULL AND i.deleted_at IS NULL AND b.deleted_at IS NULL AND b.archive_status = 'active' AND i.watermark_position_override IS NULL AND i.watermark_opacity_override IS NULL AND i.watermark_scale_override IS NULL AND i.placement_x IS NULL AND i.redactions = '[]' AND (b.id = cur.id OR (cur.similar_dedupe = 'account' AND NOT b.invisible_watermark AND NOT cur.invisible_watermark AND b.watermark_key IS NOT DISTINCT FROM cur.watermark_key AND b.watermark_id IS NOT DISTINCT FROM cur.watermark_id AND b.watermark_text IS NOT DISTINCT FROM cur.watermark_text AND b.watermark_font_id IS NOT DISTINCT FROM cur.watermark_font_id AND b.watermark_position = cur.watermark_position AND b.watermark_opacity = cur.watermark_opacity AND b.watermark_scale = cur.watermark_scale AND b.watermark_tile_spacing = cur.watermark_tile_spacing AND b.max_width IS NOT DISTINCT FROM cur.max_width AND b.max_height IS NOT DISTINCT FROM cur.max_height AND b.preserve_metadata = cur.preserve_metadata AND b.output_format = cur.output_format AND b.output_quality = cur.output_quality AND b.jpeg_progressive = cur.jpeg_progressive AND b.jpeg_subsampling = cur.jpeg_subsampling AND b.png_compression = cur.png_compression AND b.transforms = cur.transforms AND b.pipeline = cur.pipeline AND b.crop_aspect_ratio IS NOT DISTINCT FROM cur.crop_aspect_ratio AND b.crop_x IS NOT DISTINCT FROM cur.crop_x AND b.crop_y IS NOT DISTINCT FROM cur.crop_y AND b.crop_width IS NOT DISTINCT FROM cur.crop_width AND b.crop_height IS NOT DISTINCT FROM cur.crop_height)) AND bit_count((i.phash # $3::BIGINT)::BIT(64)) <= $4::INTEGER ORDER BY bit_count((i.phash # $3::BIGINT)::BIT(64)), i.created_at LIMIT 1
+`
+
+type GetSimilarImageParams struct {
+	BatchID     uuid.UUID
+	ID          uuid.UUID
+	Phash       int64
+	MaxDistance int32
+}
+
+func (q *Queries) GetSimilarImage(ctx context.Context, arg GetSimilarImageParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getSimilarImage,
+		arg.BatchID,
+		arg.ID,
+		arg.Phash,
+		arg.MaxDistance,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getStaleImages = `-- name: GetStaleImages :many
+SELECT i.id, i.batch_id, i.key, i.original_url, i.processed_url, i.status, i.created_at, i.updated_at, i.deleted_at, i.filename, i.placement_x, i.placement_y, i.placement_width, i.placement_height, i.palette, i.blurhash, i.width, i.height, i.thumbhash, i.thumbnail_url, i.content_hash, i.applied_watermark_position, i.watermark_position_override, i.watermark_opacity_override, i.watermark_scale_override, i.original_width, i.original_height, i.original_size, i.original_format, i.original_dominant_color, i.processed_size, i.processed_format, i.phash, i.similar_to, i.external_id, i.redactions, i.step_timings, i.source_url, i.failure_reason, i.captured_at, i.attempts, i.change_xid, i.owner_id, i.batch_deleted, b.region AS batch_region FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE i.status IN ('pending', 'processing') AND i.updated_at < $1 AND i.deleted_at IS NULL AND b.waiting_since IS NULL ORDER BY i.updated_at
+`
+
+type GetStaleImagesRow struct {
+	ID                        uuid.UUID
+	BatchID                   uuid.UUID
+	Key                       string
+	OriginalUrl               string
+	ProcessedUrl              sql.NullString
+	Status                    ImageStatus
+	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+	DeletedAt                 sql.NullTime
+	Filename                  sql.NullString
+	PlacementX                sql.NullFloat64
+	PlacementY                sql.NullFloat64
+	PlacementWidth            sql.NullFloat64
+	PlacementHeight           sql.NullFloat64
+	Palette                   []string
+	Blurhash                  sql.NullString
+	Width                     sql.NullInt32
+	Height                    sql.NullInt32
+	Thumbhash                 sql.NullString
+	ThumbnailUrl              sql.NullString
+	ContentHash               sql.NullString
+	AppliedWatermarkPosition  NullWatermarkPosition
+	WatermarkPositionOverride NullWatermarkPosition
+	WatermarkOpacityOverride  sql.NullInt32
+	WatermarkScaleOverride    sql.NullInt32
+	OriginalWidth             sql.NullInt32
+	OriginalHeight            sql.NullInt32
+	OriginalSize              sql.NullInt64
+	OriginalFormat            sql.NullString
+	OriginalDominantColor     sql.NullString
+	ProcessedSize             sql.NullInt64
+	ProcessedFormat           sql.NullString
+	Phash                     sql.NullInt64
+	SimilarTo                 uuid.NullUUID
+	ExternalID                sql.NullString
+	Redactions                json.RawMessage
+	StepTimings               json.RawMessage
+	SourceUrl                 sql.NullString
+	FailureReason             sql.NullString
+	CapturedAt                sql.NullTime
+	Attempts                  int32
+	ChangeXid                 int64
+	OwnerID                   uuid.UUID
+	BatchDeleted              bool
+	BatchRegion               string
+}
+
+func (q *Queries) GetStaleImages(ctx context.Context, updatedAt time.Time) ([]GetStaleImagesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStaleImages, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStaleImagesRow
+	for rows.Next() {
+		var i GetStaleImagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.Key,
+			&i.OriginalUrl,
+			&i.ProcessedUrl,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Filename,
+			&i.PlacementX,
+			&i.PlacementY,
+			&i.PlacementWidth,
+			&i.PlacementHeight,
+			pq.Array(&i.Palette),
+			&i.Blurhash,
+			&i.Width,
+			&i.Height,
+			&i.Thumbhash,
+			&i.ThumbnailUrl,
+			&i.ContentHash,
+			&i.AppliedWatermarkPosition,
+			&i.WatermarkPositionOverride,
+			&i.WatermarkOpacityOverride,
+			&i.WatermarkScaleOverride,
+			&i.OriginalWidth,
+			&i.OriginalHeight,
+			&i.OriginalSize,
+			&i.OriginalFormat,
+			&i.OriginalDominantColor,
+			&i.ProcessedSize,
+			&i.ProcessedFormat,
+			&i.Phash,
+			&i.SimilarTo,
+			&i.ExternalID,
+			&i.Redactions,
+			&i.StepTimings,
+			&i.SourceUrl,
+			&i.FailureReason,
+			&i.CapturedAt,
+			&i.Attempts,
+			&i.ChangeXid,
+			&i.OwnerID,
+			&i.BatchDeleted,
+			&i.BatchRegion,
 		); err != nil {
 			return nil, err
 		}
@@ -129,6 +1217,361 @@ func (q *Queries) GetImagesByBatchID(ctx context.Context, batchID uuid.UUID) ([]
 	return items, nil
 }
 
+const getUserImageByContentHash = `-- name: GetUserImageByContentHash :one
+SELECT i.id, i.batch_id, i.key, i.original_url, i.processed_url, i.status, i.created_at, i.updated_at, i.deleted_at, i.filename, i.placement_x, i.placement_y, i.placement_width, i.placement_height, i.palette, i.blurhash, i.width, i.height, i.thumbhash, i.thumbnail_url, i.content_hash, i.applied_watermark_position, i.watermark_position_override, i.watermark_opacity_override, i.watermark_scale_override, i.original_width, i.original_height, i.original_size, i.original_format, i.original_dominant_color, i.processed_size, i.processed_format, i.phash, i.similar_to, i.external_id, i.redactions, i.step_timings, i.source_url, i.failure_reason, i.captured_at, i.attempts, i.change_xid, i.owner_id, i.batch_deleted FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE b.user_id = $1 AND i.content_hash = $2 AND b.region = $3 AND b.archive_status = 'active' AND i.deleted_at IS NULL AND b.deleted_at IS NULL ORDER BY i.created_at LIMIT 1
+`
+
+type GetUserImageByContentHashParams struct {
+	UserID      uuid.UUID
+	ContentHash sql.NullString
+	Region      string
+}
+
+func (q *Queries) GetUserImageByContentHash(ctx context.Context, arg GetUserImageByContentHashParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, getUserImageByContentHash, arg.UserID, arg.ContentHash, arg.Region)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.Key,
+		&i.OriginalUrl,
+		&i.ProcessedUrl,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Filename,
+		&i.PlacementX,
+		&i.PlacementY,
+		&i.PlacementWidth,
+		&i.PlacementHeight,
+		pq.Array(&i.Palette),
+		&i.Blurhash,
+		&i.Width,
+		&i.Height,
+		&i.Thumbhash,
+		&i.ThumbnailUrl,
+		&i.ContentHash,
+		&i.AppliedWatermarkPosition,
+		&i.WatermarkPositionOverride,
+		&i.WatermarkOpacityOverride,
+		&i.WatermarkScaleOverride,
+		&i.OriginalWidth,
+		&i.OriginalHeight,
+		&i.OriginalSize,
+		&i.OriginalFormat,
+		&i.OriginalDominantColor,
+		&i.ProcessedSize,
+		&i.ProcessedFormat,
+		&i.Phash,
+		&i.SimilarTo,
+		&i.ExternalID,
+		&i.Redactions,
+		&i.StepTimings,
+		&i.SourceUrl,
+		&i.FailureReason,
+		&i.CapturedAt,
+		&i.Attempts,
+		&i.ChangeXid,
+		&i.OwnerID,
+		&i.BatchDeleted,
+	)
+	return i, err
+}
+
+const getUserImageByID = `-- name: GetUserImageByID :one
+SELECT i.id, i.batch_id, i.key, i.original_url, i.processed_url, i.status, i.created_at, i.updated_at, i.deleted_at, i.filename, i.placement_x, i.placement_y, i.placement_width, i.placement_height, i.palette, i.blurhash, i.width, i.height, i.thumbhash, i.thumbnail_url, i.content_hash, i.applied_watermark_position, i.watermark_position_override, i.watermark_opacity_override, i.watermark_scale_override, i.original_width, i.original_height, i.original_size, i.original_format, i.original_dominant_color, i.processed_size, i.processed_format, i.phash, i.similar_to, i.external_id, i.redactions, i.step_timings, i.source_url, i.failure_reason, i.captured_at, i.attempts, i.change_xid, i.owner_id, i.batch_deleted, b.archive_status, b.region AS batch_region FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE i.id = $1 AND b.user_id = $2 AND i.deleted_at IS NULL AND b.deleted_at IS NULL
+`
+
+type GetUserImageByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+type GetUserImageByIDRow struct {
+	Image         Image
+	ArchiveStatus BatchArchiveStatus
+	BatchRegion   string
+}
+
+func (q *Queries) GetUserImageByID(ctx context.Context, arg GetUserImageByIDParams) (GetUserImageByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserImageByID, arg.ID, arg.UserID)
+	var i GetUserImageByIDRow
+	err := row.Scan(
+		&i.Image.ID,
+		&i.Image.BatchID,
+		&i.Image.Key,
+		&i.Image.OriginalUrl,
+		&i.Image.ProcessedUrl,
+		&i.Image.Status,
+		&i.Image.CreatedAt,
+		&i.Image.UpdatedAt,
+		&i.Image.DeletedAt,
+		&i.Image.Filename,
+		&i.Image.PlacementX,
+		&i.Image.PlacementY,
+		&i.Image.PlacementWidth,
+		&i.Image.PlacementHeight,
+		pq.Array(&i.Image.Palette),
+		&i.Image.Blurhash,
+		&i.Image.Width,
+		&i.Image.Height,
+		&i.Image.Thumbhash,
+		&i.Image.ThumbnailUrl,
+		&i.Image.ContentHash,
+		&i.Image.AppliedWatermarkPosition,
+		&i.Image.WatermarkPositionOverride,
+		&i.Image.WatermarkOpacityOverride,
+		&i.Image.WatermarkScaleOverride,
+		&i.Image.OriginalWidth,
+		&i.Image.OriginalHeight,
+		&i.Image.OriginalSize,
+		&i.Image.OriginalFormat,
+		&i.Image.OriginalDominantColor,
+		&i.Image.ProcessedSize,
+		&i.Image.ProcessedFormat,
+		&i.Image.Phash,
+		&i.Image.SimilarTo,
+		&i.Image.ExternalID,
+		&i.Image.Redactions,
+		&i.Image.StepTimings,
+		&i.Image.SourceUrl,
+		&i.Image.FailureReason,
+		&i.Image.CapturedAt,
+		&i.Image.Attempts,
+		&i.Image.ChangeXid,
+		&i.Image.OwnerID,
+		&i.Image.BatchDeleted,
+		&i.ArchiveStatus,
+		&i.BatchRegion,
+	)
+	return i, err
+}
+
+const linkSimilarImageByID = `-- name: LinkSimilarImageByID :execrows
+UPDATE images i SET status = 'completed', updated_at = NOW(), similar_to = s.id, processed_url = s.processed_url, thumbnail_url = s.thumbnail_url, palette = s.palette, blurhash = s.blurhash, thumbhash = s.thumbhash, width = s.width, height = s.height, applied_watermark_position = s.applied_watermark_position, processed_size = s.processed_size, processed_format = s.processed_format, original_width = $1, original_height = $2, original_size = $3, original_format = $4, original_dominant_color = $5, phash = $6, captured_at = $7 FROM images s WHERE i.id = $8 AND s.id = $9 AND i.deleted_at IS NULL AND s.deleted_at IS NULL AND s.status = 'completed' AND s.processed_url IS NOT NULL
+`
+
+type LinkSimilarImageByIDParams struct {
+	OriginalWidth         sql.NullInt32
+	OriginalHeight        sql.NullInt32
+	OriginalSize          sql.NullInt64
+	OriginalFormat        sql.NullString
+	OriginalDominantColor sql.NullString
+	Phash                 sql.NullInt64
+	CapturedAt            sql.NullTime
+	ID                    uuid.UUID
+	SimilarTo             uuid.UUID
+}
+
+func (q *Queries) LinkSimilarImageByID(ctx context.Context, arg LinkSimilarImageByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, linkSimilarImageByID,
+		arg.OriginalWidth,
+		arg.OriginalHeight,
+		arg.OriginalSize,
+		arg.OriginalFormat,
+		arg.OriginalDominantColor,
+		arg.Phash,
+		arg.CapturedAt,
+		arg.ID,
+		arg.SimilarTo,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const requeueRestoredBatchImages = `-- name: RequeueRestoredBatchImages :many
+UPDATE images SET status = 'pending', failure_reason = NULL, updated_at = NOW() WHERE batch_id = $1 AND deleted_at IS NULL AND (status = 'pending' OR (status = 'failed' AND failure_reason = $2)) RETURNING id
+`
+
+type RequeueRestoredBatchImagesParams struct {
+	BatchID       uuid.UUID
+	FailureReason sql.NullString
+}
+
+func (q *Queries) RequeueRestoredBatchImages(ctx context.Context, arg RequeueRestoredBatchImagesParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, requeueRestoredBatchImages, arg.BatchID, arg.FailureReason)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const retryUserImagesByIDs = `-- name: RetryUserImagesByIDs :many
+UPDATE images i SET status = 'pending', updated_at = NOW() FROM batches b WHERE b.id = i.batch_id AND b.user_id = $1 AND b.deleted_at IS NULL AND b.archive_status IN ('active', 'restored') AND i.id = ANY($2::UUID[]) AND i.deleted_at IS NULL AND i.status = 'failed' RETURNING i.id, b.region AS batch_region
+`
+
+type RetryUserImagesByIDsParams struct {
+	UserID   uuid.UUID
+	ImageIds []uuid.UUID
+}
+
+type RetryUserImagesByIDsRow struct {
+	ID          uuid.UUID
+	BatchRegion string
+}
+
+func (q *Queries) RetryUserImagesByIDs(ctx context.Context, arg RetryUserImagesByIDsParams) ([]RetryUserImagesByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, retryUserImagesByIDs, arg.UserID, pq.Array(arg.ImageIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RetryUserImagesByIDsRow
+	for rows.Next() {
+		var i RetryUserImagesByIDsRow
+		if err := rows.Scan(&i.ID, &i.BatchRegion); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchUserImages = `-- name: SearchUserImages :many
+SELECT i.id, i.batch_id, i.key, i.original_url, i.processed_url, i.status, i.created_at, i.updated_at, i.deleted_at, i.filename, i.placement_x, i.placement_y, i.placement_width, i.placement_height, i.palette, i.blurhash, i.width, i.height, i.thumbhash, i.thumbnail_url, i.content_hash, i.applied_watermark_position, i.watermark_position_override, i.watermark_opacity_override, i.watermark_scale_override, i.original_width, i.original_height, i.original_size, i.original_format, i.original_dominant_color, i.processed_size, i.processed_format, i.phash, i.similar_to, i.external_id, i.redactions, i.step_timings, i.source_url, i.failure_reason, i.captured_at, i.attempts, i.change_xid, i.owner_id, i.batch_deleted FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE b.user_id = $1 AND b.deleted_at IS NULL AND i.deleted_at IS NULL AND ($2::text IS NULL OR i.filename ILIKE '%' || $2::text || '%' ESCAPE '\') AND ($3::image_status IS NULL OR i.status = $3::image_status) AND ($4::timestamp IS NULL OR i.created_at >= $4::timestamp) AND ($5::timestamp IS NULL OR i.created_at <= $5::timestamp) AND ($6::uuid IS NULL OR i.batch_id = $6::uuid) AND ($7::text IS NULL OR i.external_id = $7::text) AND ($8::timestamp IS NULL OR i.captured_at >= $8::timestamp) AND ($9::timestamp IS NULL OR i.captured_at <= $9::timestamp) ORDER BY CASE WHEN $10::text = 'captured_at' THEN i.captured_at END ASC NULLS LAST, CASE WHEN $10::text = '-captured_at' THEN i.captured_at END DESC NULLS LAST, CASE WHEN $10::text = 'created_at' THEN i.created_at END ASC, i.created_at DESC, i.id DESC LIMIT $12 OFFSET $11
+`
+
+type SearchUserImagesParams struct {
+	UserID       uuid.UUID
+	Filename     sql.NullString
+	Status       NullImageStatus
+	FromTime     sql.NullTime
+	ToTime       sql.NullTime
+	BatchID      uuid.NullUUID
+	ExternalID   sql.NullString
+	CapturedFrom sql.NullTime
+	CapturedTo   sql.NullTime
+	Sort         string
+	PageOffset   int32
+	PageLimit    int32
+}
+
+func (q *Queries) SearchUserImages(ctx context.Context, arg SearchUserImagesParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, searchUserImages,
+		arg.UserID,
+		arg.Filename,
+		arg.Status,
+		arg.FromTime,
+		arg.ToTime,
+		arg.BatchID,
+		arg.ExternalID,
+		arg.CapturedFrom,
+		arg.CapturedTo,
+		arg.Sort,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.Key,
+			&i.OriginalUrl,
+			&i.ProcessedUrl,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Filename,
+			&i.PlacementX,
+			&i.PlacementY,
+			&i.PlacementWidth,
+			&i.PlacementHeight,
+			pq.Array(&i.Palette),
+			&i.Blurhash,
+			&i.Width,
+			&i.Height,
+			&i.Thumbhash,
+			&i.ThumbnailUrl,
+			&i.ContentHash,
+			&i.AppliedWatermarkPosition,
+			&i.WatermarkPositionOverride,
+			&i.WatermarkOpacityOverride,
+			&i.WatermarkScaleOverride,
+			&i.OriginalWidth,
+			&i.OriginalHeight,
+			&i.OriginalSize,
+			&i.OriginalFormat,
+			&i.OriginalDominantColor,
+			&i.ProcessedSize,
+			&i.ProcessedFormat,
+			&i.Phash,
+			&i.SimilarTo,
+			&i.ExternalID,
+			&i.Redactions,
+			&i.StepTimings,
+			&i.SourceUrl,
+			&i.FailureReason,
+			&i.CapturedAt,
+			&i.Attempts,
+			&i.ChangeXid,
+			&i.OwnerID,
+			&i.BatchDeleted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setImageOriginal = `-- name: SetImageOriginal :exec
+UPDATE images SET key = $1, original_url = $2, content_hash = $3, updated_at = NOW() WHERE id = $4 AND deleted_at IS NULL
+`
+
+type SetImageOriginalParams struct {
+	Key         string
+	OriginalUrl string
+	ContentHash sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetImageOriginal(ctx context.Context, arg SetImageOriginalParams) error {
+	_, err := q.db.ExecContext(ctx, setImageOriginal,
+		arg.Key,
+		arg.OriginalUrl,
+		arg.ContentHash,
+		arg.ID,
+	)
+	return err
+}
+
 const updateImageByID = `-- name: UpdateImageByID :exec
 UPDATE images SET processed_url = $1, status = $2, updated_at = NOW() WHERE id = $3 AND deleted_at IS NULL
 `
@@ -143,3 +1586,73 @@ func (q *Queries) UpdateImageByID(ctx context.Context, arg UpdateImageByIDParams
 	_, err := q.db.ExecContext(ctx, updateImageByID, arg.ProcessedUrl, arg.Status, arg.ID)
 	return err
 }
+
+const updateImageWatermarkPlacement = `-- name: UpdateImageWatermarkPlacement :one
+UPDATE images SET placement_x = $1, placement_y = $2, placement_width = $3, placement_height = $4, processed_url = NULL, thumbnail_url = NULL, similar_to = NULL, status = 'pending', updated_at = NOW() WHERE id = $5 AND deleted_at IS NULL RETURNING id, batch_id, key, original_url, processed_url, status, created_at, updated_at, deleted_at, filename, placement_x, placement_y, placement_width, placement_height, palette, blurhash, width, height, thumbhash, thumbnail_url, content_hash, applied_watermark_position, watermark_position_override, watermark_opacity_override, watermark_scale_override, original_width, original_height, original_size, original_format, original_dominant_color, processed_size, processed_format, phash, similar_to, external_id, redactions, step_timings, source_url, failure_reason, captured_at, attempts, change_xid, owner_id, batch_deleted
+`
+
+type UpdateImageWatermarkPlacementParams struct {
+	PlacementX      sql.NullFloat64
+	PlacementY      sql.NullFloat64
+	PlacementWidth  sql.NullFloat64
+	PlacementHeight sql.NullFloat64
+	ID              uuid.UUID
+}
+
+func (q *Queries) UpdateImageWatermarkPlacement(ctx context.Context, arg UpdateImageWatermarkPlacementParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, updateImageWatermarkPlacement,
+		arg.PlacementX,
+		arg.PlacementY,
+		arg.PlacementWidth,
+		arg.PlacementHeight,
+		arg.ID,
+	)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.BatchID,
+		&i.Key,
+		&i.OriginalUrl,
+		&i.ProcessedUrl,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Filename,
+		&i.PlacementX,
+		&i.PlacementY,
+		&i.PlacementWidth,
+		&i.PlacementHeight,
+		pq.Array(&i.Palette),
+		&i.Blurhash,
+		&i.Width,
+		&i.Height,
+		&i.Thumbhash,
+		&i.ThumbnailUrl,
+		&i.ContentHash,
+		&i.AppliedWatermarkPosition,
+		&i.WatermarkPositionOverride,
+		&i.WatermarkOpacityOverride,
+		&i.WatermarkScaleOverride,
+		&i.OriginalWidth,
+		&i.OriginalHeight,
+		&i.OriginalSize,
+		&i.OriginalFormat,
+		&i.OriginalDominantColor,
+		&i.ProcessedSize,
+		&i.ProcessedFormat,
+		&i.Phash,
+		&i.SimilarTo,
+		&i.ExternalID,
+		&i.Redactions,
+		&i.StepTimings,
+		&i.SourceUrl,
+		&i.FailureReason,
+		&i.CapturedAt,
+		&i.Attempts,
+		&i.ChangeXid,
+		&i.OwnerID,
+		&i.BatchDeleted,
+	)
+	return i, err
+}
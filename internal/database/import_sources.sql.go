@@ -0,0 +1,85 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: import_sources.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createUserImportSource = `-- name: CreateUserImportSource :one
+INSERT INTO user_import_sources(user_id, bucket, prefix) VALUES ($1, $2, $3) RETURNING id, user_id, bucket, prefix, created_at
+`
+
+type CreateUserImportSourceParams struct {
+	UserID uuid.UUID
+	Bucket string
+	Prefix string
+}
+
+func (q *Queries) CreateUserImportSource(ctx context.Context, arg CreateUserImportSourceParams) (UserImportSource, error) {
+	row := q.db.QueryRowContext(ctx, createUserImportSource, arg.UserID, arg.Bucket, arg.Prefix)
+	var i UserImportSource
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Bucket,
+		&i.Prefix,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteUserImportSource = `-- name: DeleteUserImportSource :execrows
+DELETE FROM user_import_sources WHERE id = $1 AND user_id = $2
+`
+
+type DeleteUserImportSourceParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteUserImportSource(ctx context.Context, arg DeleteUserImportSourceParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserImportSource, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserImportSources = `-- name: GetUserImportSources :many
+SELECT id, user_id, bucket, prefix, created_at FROM user_import_sources WHERE user_id = $1 ORDER BY bucket, prefix
+`
+
+func (q *Queries) GetUserImportSources(ctx context.Context, userID uuid.UUID) ([]UserImportSource, error) {
+	rows, err := q.db.QueryContext(ctx, getUserImportSources, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserImportSource
+	for rows.Next() {
+		var i UserImportSource
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Bucket,
+			&i.Prefix,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,112 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: ip_allowlists.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createIPAllowlistEntry = `-- name: CreateIPAllowlistEntry :one
+INSERT INTO user_ip_allowlist_entries(user_id, cidr, description) VALUES ($1, $2, $3) RETURNING id, user_id, cidr, description, created_at
+`
+
+type CreateIPAllowlistEntryParams struct {
+	UserID      uuid.UUID
+	Cidr        string
+	Description string
+}
+
+func (q *Queries) CreateIPAllowlistEntry(ctx context.Context, arg CreateIPAllowlistEntryParams) (UserIpAllowlistEntry, error) {
+	row := q.db.QueryRowContext(ctx, createIPAllowlistEntry, arg.UserID, arg.Cidr, arg.Description)
+	var i UserIpAllowlistEntry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Cidr,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteIPAllowlistEntry = `-- name: DeleteIPAllowlistEntry :execrows
+DELETE FROM user_ip_allowlist_entries WHERE id = $1 AND user_id = $2
+`
+
+type DeleteIPAllowlistEntryParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteIPAllowlistEntry(ctx context.Context, arg DeleteIPAllowlistEntryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteIPAllowlistEntry, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserAllowedCIDRs = `-- name: GetUserAllowedCIDRs :many
+SELECT cidr FROM user_ip_allowlist_entries WHERE user_id = $1
+`
+
+func (q *Queries) GetUserAllowedCIDRs(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getUserAllowedCIDRs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var cidr string
+		if err := rows.Scan(&cidr); err != nil {
+			return nil, err
+		}
+		items = append(items, cidr)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserIPAllowlist = `-- name: GetUserIPAllowlist :many
+SELECT id, user_id, cidr, description, created_at FROM user_ip_allowlist_entries WHERE user_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) GetUserIPAllowlist(ctx context.Context, userID uuid.UUID) ([]UserIpAllowlistEntry, error) {
+	rows, err := q.db.QueryContext(ctx, getUserIPAllowlist, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserIpAllowlistEntry
+	for rows.Next() {
+		var i UserIpAllowlistEntry
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Cidr,
+			&i.Description,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,12 +7,290 @@ package database
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type BatchArchiveStatus string
+
+const (
+	BatchArchiveStatusActive    BatchArchiveStatus = "active"
+	BatchArchiveStatusArchived  BatchArchiveStatus = "archived"
+	BatchArchiveStatusRestoring BatchArchiveStatus = "restoring"
+	BatchArchiveStatusRestored  BatchArchiveStatus = "restored"
+)
+
+func (e *BatchArchiveStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = BatchArchiveStatus(s)
+	case string:
+		*e = BatchArchiveStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for BatchArchiveStatus: %T", src)
+	}
+	return nil
+}
+
+type NullBatchArchiveStatus struct {
+	BatchArchiveStatus BatchArchiveStatus
+	Valid              bool // Valid is true if BatchArchiveStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullBatchArchiveStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.BatchArchiveStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.BatchArchiveStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullBatchArchiveStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.BatchArchiveStatus), nil
+}
+
+func (e BatchArchiveStatus) Valid() bool {
+	switch e {
+	case BatchArchiveStatusActive,
+		BatchArchiveStatusArchived,
+		BatchArchiveStatusRestoring,
+		BatchArchiveStatusRestored:
+		return true
+	}
+	return false
+}
+
+type BatchEventType string
+
+const (
+	BatchEventTypeCreated          BatchEventType = "created"
+	BatchEventTypeArchived         BatchEventType = "archived"
+	BatchEventTypeRestoreRequested BatchEventType = "restore_requested"
+	BatchEventTypeRestored         BatchEventType = "restored"
+	BatchEventTypeCancelled        BatchEventType = "cancelled"
+	BatchEventTypeTransferred      BatchEventType = "transferred"
+	BatchEventTypeExpired          BatchEventType = "expired"
+	BatchEventTypeDeadlinePassed   BatchEventType = "deadline_passed"
+)
+
+func (e *BatchEventType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = BatchEventType(s)
+	case string:
+		*e = BatchEventType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for BatchEventType: %T", src)
+	}
+	return nil
+}
+
+type NullBatchEventType struct {
+	BatchEventType BatchEventType
+	Valid          bool // Valid is true if BatchEventType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullBatchEventType) Scan(value interface{}) error {
+	if value == nil {
+		ns.BatchEventType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.BatchEventType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullBatchEventType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.BatchEventType), nil
+}
+
+func (e BatchEventType) Valid() bool {
+	switch e {
+	case BatchEventTypeCreated,
+		BatchEventTypeArchived,
+		BatchEventTypeRestoreRequested,
+		BatchEventTypeRestored,
+		BatchEventTypeCancelled,
+		BatchEventTypeTransferred,
+		BatchEventTypeExpired,
+		BatchEventTypeDeadlinePassed:
+		return true
+	}
+	return false
+}
+
+type BatchTransferStatus string
+
+const (
+	BatchTransferStatusPending   BatchTransferStatus = "pending"
+	BatchTransferStatusAccepted  BatchTransferStatus = "accepted"
+	BatchTransferStatusDeclined  BatchTransferStatus = "declined"
+	BatchTransferStatusCancelled BatchTransferStatus = "cancelled"
+)
+
+func (e *BatchTransferStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = BatchTransferStatus(s)
+	case string:
+		*e = BatchTransferStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for BatchTransferStatus: %T", src)
+	}
+	return nil
+}
+
+type NullBatchTransferStatus struct {
+	BatchTransferStatus BatchTransferStatus
+	Valid               bool // Valid is true if BatchTransferStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullBatchTransferStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.BatchTransferStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.BatchTransferStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullBatchTransferStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.BatchTransferStatus), nil
+}
+
+func (e BatchTransferStatus) Valid() bool {
+	switch e {
+	case BatchTransferStatusPending,
+		BatchTransferStatusAccepted,
+		BatchTransferStatusDeclined,
+		BatchTransferStatusCancelled:
+		return true
+	}
+	return false
+}
+
+type ChromaSubsampling string
+
+const (
+	ChromaSubsampling444 ChromaSubsampling = "444"
+	ChromaSubsampling422 ChromaSubsampling = "422"
+	ChromaSubsampling420 ChromaSubsampling = "420"
+)
+
+func (e *ChromaSubsampling) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ChromaSubsampling(s)
+	case string:
+		*e = ChromaSubsampling(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ChromaSubsampling: %T", src)
+	}
+	return nil
+}
+
+type NullChromaSubsampling struct {
+	ChromaSubsampling ChromaSubsampling
+	Valid             bool // Valid is true if ChromaSubsampling is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullChromaSubsampling) Scan(value interface{}) error {
+	if value == nil {
+		ns.ChromaSubsampling, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ChromaSubsampling.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullChromaSubsampling) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ChromaSubsampling), nil
+}
+
+func (e ChromaSubsampling) Valid() bool {
+	switch e {
+	case ChromaSubsampling444,
+		ChromaSubsampling422,
+		ChromaSubsampling420:
+		return true
+	}
+	return false
+}
+
+type EmailDomainRuleType string
+
+const (
+	EmailDomainRuleTypeBlock EmailDomainRuleType = "block"
+	EmailDomainRuleTypeAllow EmailDomainRuleType = "allow"
+)
+
+func (e *EmailDomainRuleType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EmailDomainRuleType(s)
+	case string:
+		*e = EmailDomainRuleType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EmailDomainRuleType: %T", src)
+	}
+	return nil
+}
+
+type NullEmailDomainRuleType struct {
+	EmailDomainRuleType EmailDomainRuleType
+	Valid               bool // Valid is true if EmailDomainRuleType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEmailDomainRuleType) Scan(value interface{}) error {
+	if value == nil {
+		ns.EmailDomainRuleType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EmailDomainRuleType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEmailDomainRuleType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EmailDomainRuleType), nil
+}
+
+func (e EmailDomainRuleType) Valid() bool {
+	switch e {
+	case EmailDomainRuleTypeBlock,
+		EmailDomainRuleTypeAllow:
+		return true
+	}
+	return false
+}
+
 type ImageStatus string
 
 const (
@@ -20,6 +298,8 @@ const (
 	ImageStatusProcessing ImageStatus = "processing"
 	ImageStatusCompleted  ImageStatus = "completed"
 	ImageStatusFailed     ImageStatus = "failed"
+	ImageStatusCancelled  ImageStatus = "cancelled"
+	ImageStatusExpired    ImageStatus = "expired"
 )
 
 func (e *ImageStatus) Scan(src interface{}) error {
@@ -57,43 +337,685 @@ func (ns NullImageStatus) Value() (driver.Value, error) {
 	return string(ns.ImageStatus), nil
 }
 
+func (e ImageStatus) Valid() bool {
+	switch e {
+	case ImageStatusPending,
+		ImageStatusProcessing,
+		ImageStatusCompleted,
+		ImageStatusFailed,
+		ImageStatusCancelled,
+		ImageStatusExpired:
+		return true
+	}
+	return false
+}
+
+type OutputCollisionPolicy string
+
+const (
+	OutputCollisionPolicyOverwrite OutputCollisionPolicy = "overwrite"
+	OutputCollisionPolicySuffix    OutputCollisionPolicy = "suffix"
+	OutputCollisionPolicyError     OutputCollisionPolicy = "error"
+)
+
+func (e *OutputCollisionPolicy) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = OutputCollisionPolicy(s)
+	case string:
+		*e = OutputCollisionPolicy(s)
+	default:
+		return fmt.Errorf("unsupported scan type for OutputCollisionPolicy: %T", src)
+	}
+	return nil
+}
+
+type NullOutputCollisionPolicy struct {
+	OutputCollisionPolicy OutputCollisionPolicy
+	Valid                 bool // Valid is true if OutputCollisionPolicy is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullOutputCollisionPolicy) Scan(value interface{}) error {
+	if value == nil {
+		ns.OutputCollisionPolicy, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.OutputCollisionPolicy.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullOutputCollisionPolicy) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.OutputCollisionPolicy), nil
+}
+
+func (e OutputCollisionPolicy) Valid() bool {
+	switch e {
+	case OutputCollisionPolicyOverwrite,
+		OutputCollisionPolicySuffix,
+		OutputCollisionPolicyError:
+		return true
+	}
+	return false
+}
+
+type OutputFormat string
+
+const (
+	OutputFormatJpeg OutputFormat = "jpeg"
+	OutputFormatPng  OutputFormat = "png"
+	OutputFormatWebp OutputFormat = "webp"
+)
+
+func (e *OutputFormat) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = OutputFormat(s)
+	case string:
+		*e = OutputFormat(s)
+	default:
+		return fmt.Errorf("unsupported scan type for OutputFormat: %T", src)
+	}
+	return nil
+}
+
+type NullOutputFormat struct {
+	OutputFormat OutputFormat
+	Valid        bool // Valid is true if OutputFormat is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullOutputFormat) Scan(value interface{}) error {
+	if value == nil {
+		ns.OutputFormat, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.OutputFormat.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullOutputFormat) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.OutputFormat), nil
+}
+
+func (e OutputFormat) Valid() bool {
+	switch e {
+	case OutputFormatJpeg,
+		OutputFormatPng,
+		OutputFormatWebp:
+		return true
+	}
+	return false
+}
+
+type PngCompression string
+
+const (
+	PngCompressionDefault PngCompression = "default"
+	PngCompressionNone    PngCompression = "none"
+	PngCompressionFast    PngCompression = "fast"
+	PngCompressionBest    PngCompression = "best"
+)
+
+func (e *PngCompression) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PngCompression(s)
+	case string:
+		*e = PngCompression(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PngCompression: %T", src)
+	}
+	return nil
+}
+
+type NullPngCompression struct {
+	PngCompression PngCompression
+	Valid          bool // Valid is true if PngCompression is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPngCompression) Scan(value interface{}) error {
+	if value == nil {
+		ns.PngCompression, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PngCompression.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPngCompression) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PngCompression), nil
+}
+
+func (e PngCompression) Valid() bool {
+	switch e {
+	case PngCompressionDefault,
+		PngCompressionNone,
+		PngCompressionFast,
+		PngCompressionBest:
+		return true
+	}
+	return false
+}
+
+type SimilarDedupe string
+
+const (
+	SimilarDedupeOff     SimilarDedupe = "off"
+	SimilarDedupeBatch   SimilarDedupe = "batch"
+	SimilarDedupeAccount SimilarDedupe = "account"
+)
+
+func (e *SimilarDedupe) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SimilarDedupe(s)
+	case string:
+		*e = SimilarDedupe(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SimilarDedupe: %T", src)
+	}
+	return nil
+}
+
+type NullSimilarDedupe struct {
+	SimilarDedupe SimilarDedupe
+	Valid         bool // Valid is true if SimilarDedupe is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullSimilarDedupe) Scan(value interface{}) error {
+	if value == nil {
+		ns.SimilarDedupe, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.SimilarDedupe.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullSimilarDedupe) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.SimilarDedupe), nil
+}
+
+func (e SimilarDedupe) Valid() bool {
+	switch e {
+	case SimilarDedupeOff,
+		SimilarDedupeBatch,
+		SimilarDedupeAccount:
+		return true
+	}
+	return false
+}
+
+type TaskOutcome string
+
+const (
+	TaskOutcomeCompleted TaskOutcome = "completed"
+	TaskOutcomeLinked    TaskOutcome = "linked"
+	TaskOutcomeFailed    TaskOutcome = "failed"
+)
+
+func (e *TaskOutcome) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TaskOutcome(s)
+	case string:
+		*e = TaskOutcome(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TaskOutcome: %T", src)
+	}
+	return nil
+}
+
+type NullTaskOutcome struct {
+	TaskOutcome TaskOutcome
+	Valid       bool // Valid is true if TaskOutcome is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTaskOutcome) Scan(value interface{}) error {
+	if value == nil {
+		ns.TaskOutcome, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TaskOutcome.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTaskOutcome) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TaskOutcome), nil
+}
+
+func (e TaskOutcome) Valid() bool {
+	switch e {
+	case TaskOutcomeCompleted,
+		TaskOutcomeLinked,
+		TaskOutcomeFailed:
+		return true
+	}
+	return false
+}
+
+type WatermarkPosition string
+
+const (
+	WatermarkPositionTopLeft     WatermarkPosition = "top-left"
+	WatermarkPositionTopRight    WatermarkPosition = "top-right"
+	WatermarkPositionBottomLeft  WatermarkPosition = "bottom-left"
+	WatermarkPositionBottomRight WatermarkPosition = "bottom-right"
+	WatermarkPositionCenter      WatermarkPosition = "center"
+	WatermarkPositionTiled       WatermarkPosition = "tiled"
+	WatermarkPositionDiagonal    WatermarkPosition = "diagonal"
+	WatermarkPositionAuto        WatermarkPosition = "auto"
+)
+
+func (e *WatermarkPosition) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WatermarkPosition(s)
+	case string:
+		*e = WatermarkPosition(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WatermarkPosition: %T", src)
+	}
+	return nil
+}
+
+type NullWatermarkPosition struct {
+	WatermarkPosition WatermarkPosition
+	Valid             bool // Valid is true if WatermarkPosition is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWatermarkPosition) Scan(value interface{}) error {
+	if value == nil {
+		ns.WatermarkPosition, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WatermarkPosition.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWatermarkPosition) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WatermarkPosition), nil
+}
+
+func (e WatermarkPosition) Valid() bool {
+	switch e {
+	case WatermarkPositionTopLeft,
+		WatermarkPositionTopRight,
+		WatermarkPositionBottomLeft,
+		WatermarkPositionBottomRight,
+		WatermarkPositionCenter,
+		WatermarkPositionTiled,
+		WatermarkPositionDiagonal,
+		WatermarkPositionAuto:
+		return true
+	}
+	return false
+}
+
+type AuthEvent struct {
+	ID        uuid.UUID
+	UserID    uuid.NullUUID
+	Email     sql.NullString
+	Reason    string
+	IpAddress sql.NullString
+	CreatedAt time.Time
+}
+
 type Batch struct {
-	ID           uuid.UUID
-	UserID       uuid.UUID
-	Name         sql.NullString
-	WatermarkUrl sql.NullString
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    sql.NullTime
-	WatermarkKey sql.NullString
+	ID                   uuid.UUID
+	UserID               uuid.UUID
+	Name                 sql.NullString
+	WatermarkUrl         sql.NullString
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	DeletedAt            sql.NullTime
+	WatermarkKey         sql.NullString
+	ArchiveStatus        BatchArchiveStatus
+	ArchivedAt           sql.NullTime
+	PreserveFilenames    bool
+	CollisionPolicy      OutputCollisionPolicy
+	WatermarkText        sql.NullString
+	WatermarkFontID      uuid.NullUUID
+	WatermarkPosition    WatermarkPosition
+	OutputFormat         OutputFormat
+	OutputQuality        int32
+	WatermarkOpacity     int32
+	WatermarkScale       int32
+	MaxConcurrency       int32
+	WatermarkTileSpacing int32
+	MaxWidth             sql.NullInt32
+	MaxHeight            sql.NullInt32
+	PreserveMetadata     bool
+	InvisibleWatermark   bool
+	JpegProgressive      bool
+	JpegSubsampling      ChromaSubsampling
+	PngCompression       PngCompression
+	SimilarDedupe        SimilarDedupe
+	ExternalID           sql.NullString
+	Transforms           json.RawMessage
+	CropAspectRatio      sql.NullString
+	CropX                sql.NullInt32
+	CropY                sql.NullInt32
+	CropWidth            sql.NullInt32
+	CropHeight           sql.NullInt32
+	WatermarkID          uuid.NullUUID
+	Pipeline             json.RawMessage
+	WaitingSince         sql.NullTime
+	Region               string
+	ReportCsv            bool
+	ReportUrl            sql.NullString
+	ReportCsvUrl         sql.NullString
+	ReportGeneratedAt    sql.NullTime
+	ExpiresAt            sql.NullTime
+	Deadline             sql.NullTime
+	PurgedAt             sql.NullTime
+}
+
+type BatchComment struct {
+	ID        uuid.UUID
+	BatchID   uuid.UUID
+	ImageID   uuid.NullUUID
+	ParentID  uuid.NullUUID
+	UserID    uuid.UUID
+	Body      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt sql.NullTime
+}
+
+type BatchDelivery struct {
+	ID               uuid.UUID
+	BatchID          uuid.UUID
+	UserID           uuid.UUID
+	Recipient        string
+	TokenHash        string
+	Subject          string
+	Message          string
+	ReplyTo          sql.NullString
+	LogoUrl          sql.NullString
+	ExpiresAt        time.Time
+	SentAt           sql.NullTime
+	OpenCount        int32
+	FirstOpenedAt    sql.NullTime
+	LastOpenedAt     sql.NullTime
+	DownloadCount    int32
+	LastDownloadedAt sql.NullTime
+	CreatedAt        time.Time
+	SendAttempts     int32
+	NextAttemptAt    time.Time
+	FailedAt         sql.NullTime
+}
+
+type BatchEvent struct {
+	ID        uuid.UUID
+	BatchID   uuid.UUID
+	UserID    uuid.UUID
+	Type      BatchEventType
+	CreatedAt time.Time
+}
+
+type BatchPreset struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	Name              string
+	WatermarkID       uuid.NullUUID
+	WatermarkPosition NullWatermarkPosition
+	WatermarkOpacity  sql.NullInt32
+	WatermarkScale    sql.NullInt32
+	OutputFormat      NullOutputFormat
+	OutputQuality     sql.NullInt32
+	MaxWidth          sql.NullInt32
+	MaxHeight         sql.NullInt32
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type BatchTransfer struct {
+	ID          uuid.UUID
+	BatchID     uuid.UUID
+	FromUserID  uuid.UUID
+	ToUserID    uuid.UUID
+	Message     string
+	Status      BatchTransferStatus
+	CreatedAt   time.Time
+	RespondedAt sql.NullTime
+}
+
+type EmailChange struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	OldEmail         string
+	NewEmail         string
+	ConfirmTokenHash string
+	UndoTokenHash    string
+	ExpiresAt        time.Time
+	UndoExpiresAt    time.Time
+	ConfirmedAt      sql.NullTime
+	CancelledAt      sql.NullTime
+	RevertedAt       sql.NullTime
+	CreatedAt        time.Time
+}
+
+type EmailDomainRule struct {
+	ID        uuid.UUID
+	Domain    string
+	Rule      EmailDomainRuleType
+	CreatedAt time.Time
+}
+
+type Font struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Key       string
+	Format    string
+	SizeBytes int64
+	CreatedAt time.Time
+	DeletedAt sql.NullTime
+	Region    string
 }
 
 type Image struct {
-	ID           uuid.UUID
-	BatchID      uuid.UUID
-	Key          string
-	OriginalUrl  string
-	ProcessedUrl sql.NullString
-	Status       ImageStatus
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    sql.NullTime
+	ID                        uuid.UUID
+	BatchID                   uuid.UUID
+	Key                       string
+	OriginalUrl               string
+	ProcessedUrl              sql.NullString
+	Status                    ImageStatus
+	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+	DeletedAt                 sql.NullTime
+	Filename                  sql.NullString
+	PlacementX                sql.NullFloat64
+	PlacementY                sql.NullFloat64
+	PlacementWidth            sql.NullFloat64
+	PlacementHeight           sql.NullFloat64
+	Palette                   []string
+	Blurhash                  sql.NullString
+	Width                     sql.NullInt32
+	Height                    sql.NullInt32
+	Thumbhash                 sql.NullString
+	ThumbnailUrl              sql.NullString
+	ContentHash               sql.NullString
+	AppliedWatermarkPosition  NullWatermarkPosition
+	WatermarkPositionOverride NullWatermarkPosition
+	WatermarkOpacityOverride  sql.NullInt32
+	WatermarkScaleOverride    sql.NullInt32
+	OriginalWidth             sql.NullInt32
+	OriginalHeight            sql.NullInt32
+	OriginalSize              sql.NullInt64
+	OriginalFormat            sql.NullString
+	OriginalDominantColor     sql.NullString
+	ProcessedSize             sql.NullInt64
+	ProcessedFormat           sql.NullString
+	Phash                     sql.NullInt64
+	SimilarTo                 uuid.NullUUID
+	ExternalID                sql.NullString
+	Redactions                json.RawMessage
+	StepTimings               json.RawMessage
+	SourceUrl                 sql.NullString
+	FailureReason             sql.NullString
+	CapturedAt                sql.NullTime
+	Attempts                  int32
+	ChangeXid                 int64
+	OwnerID                   uuid.UUID
+	BatchDeleted              bool
+}
+
+type ProcessedTask struct {
+	TaskID      uuid.UUID
+	ImageID     uuid.UUID
+	OptionsHash string
+	Outcome     TaskOutcome
+	CreatedAt   time.Time
 }
 
 type RefreshToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	TokenHash  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	UserAgent  sql.NullString
+	IpAddress  sql.NullString
+	LastUsedAt sql.NullTime
+}
+
+type TaskOutbox struct {
+	TaskID        uuid.UUID
+	ImageID       uuid.NullUUID
+	Region        string
+	Payload       json.RawMessage
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	Queue         string
+}
+
+type User struct {
+	ID                 uuid.UUID
+	Email              string
+	PasswordHash       string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          sql.NullTime
+	IsAdmin            bool
+	DisabledAt         sql.NullTime
+	Region             string
+	DefaultWatermarkID uuid.NullUUID
+}
+
+type UserBoost struct {
+	ID                    uuid.UUID
+	UserID                uuid.UUID
+	GrantedBy             uuid.NullUUID
+	Reason                string
+	ExtraActiveBatches    int32
+	ConcurrencyMultiplier int32
+	StartsAt              time.Time
+	EndsAt                time.Time
+	RevokedAt             sql.NullTime
+	CreatedAt             time.Time
+	Priority              bool
+}
+
+type UserFeatureFlag struct {
+	UserID    uuid.UUID
+	Flag      string
+	CreatedAt time.Time
+}
+
+type UserImportSource struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
-	Token     string
+	Bucket    string
+	Prefix    string
 	CreatedAt time.Time
-	ExpiresAt time.Time
-	RevokedAt sql.NullTime
 }
 
-type User struct {
-	ID           uuid.UUID
-	Email        string
-	PasswordHash string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    sql.NullTime
+type UserIpAllowlistEntry struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Cidr        string
+	Description string
+	CreatedAt   time.Time
+}
+
+type Watermark struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	Key         string
+	Url         string
+	ContentType string
+	Width       int32
+	Height      int32
+	SizeBytes   int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   sql.NullTime
+	Region      string
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt sql.NullTime
+}
+
+type WebhookDelivery struct {
+	ID              uuid.UUID
+	WebhookID       uuid.UUID
+	Event           string
+	Payload         json.RawMessage
+	StatusCode      sql.NullInt32
+	Error           sql.NullString
+	LatencyMs       int32
+	ResponseExcerpt sql.NullString
+	CreatedAt       time.Time
+	EventID         uuid.NullUUID
+}
+
+type WebhookEvent struct {
+	ID            uuid.UUID
+	WebhookID     uuid.UUID
+	Event         string
+	Payload       json.RawMessage
+	Attempts      int32
+	NextAttemptAt time.Time
+	DeliveredAt   sql.NullTime
+	FailedAt      sql.NullTime
+	CreatedAt     time.Time
 }
@@ -0,0 +1,53 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: processed_tasks.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createProcessedTask = `-- name: CreateProcessedTask :execrows
+INSERT INTO processed_tasks(task_id, image_id, options_hash, outcome) VALUES($1, $2, $3, $4) ON CONFLICT (task_id) DO UPDATE SET image_id = EXCLUDED.image_id, options_hash = EXCLUDED.options_hash, outcome = EXCLUDED.outcome, created_at = NOW() WHERE processed_tasks.outcome = 'failed' AND EXISTS (SELECT 1 FROM images i WHERE i.id = EXCLUDED.image_id AND i.status IN ('pending', 'processing'))
+`
+
+type CreateProcessedTaskParams struct {
+	TaskID      uuid.UUID
+	ImageID     uuid.UUID
+	OptionsHash string
+	Outcome     TaskOutcome
+}
+
+func (q *Queries) CreateProcessedTask(ctx context.Context, arg CreateProcessedTaskParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createProcessedTask,
+		arg.TaskID,
+		arg.ImageID,
+		arg.OptionsHash,
+		arg.Outcome,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getProcessedTask = `-- name: GetProcessedTask :one
+SELECT task_id, image_id, options_hash, outcome, created_at FROM processed_tasks WHERE task_id = $1
+`
+
+func (q *Queries) GetProcessedTask(ctx context.Context, taskID uuid.UUID) (ProcessedTask, error) {
+	row := q.db.QueryRowContext(ctx, getProcessedTask, taskID)
+	var i ProcessedTask
+	err := row.Scan(
+		&i.TaskID,
+		&i.ImageID,
+		&i.OptionsHash,
+		&i.Outcome,
+		&i.CreatedAt,
+	)
+	return i, err
+}
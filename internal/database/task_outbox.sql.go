@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: task_outbox.sql
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueOutboxTasks = `-- name: ClaimDueOutboxTasks :many
+UPDATE task_outbox t SET next_attempt_at = NOW() + make_interval(secs => $1::int) WHERE t.task_id IN (SELECT d.task_id FROM task_outbox d WHERE d.next_attempt_at <= NOW() ORDER BY d.next_attempt_at LIMIT $2 FOR UPDATE SKIP LOCKED) RETURNING t.task_id, t.image_id, t.region, t.payload, t.next_attempt_at, t.created_at, t.queue
+`
+
+type ClaimDueOutboxTasksParams struct {
+	LeaseSeconds int32
+	PageLimit    int32
+}
+
+func (q *Queries) ClaimDueOutboxTasks(ctx context.Context, arg ClaimDueOutboxTasksParams) ([]TaskOutbox, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueOutboxTasks, arg.LeaseSeconds, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TaskOutbox
+	for rows.Next() {
+		var i TaskOutbox
+		if err := rows.Scan(
+			&i.TaskID,
+			&i.ImageID,
+			&i.Region,
+			&i.Payload,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.Queue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOutboxTask = `-- name: CreateOutboxTask :exec
+INSERT INTO task_outbox(task_id, image_id, region, queue, payload, next_attempt_at) VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateOutboxTaskParams struct {
+	TaskID        uuid.UUID
+	ImageID       uuid.NullUUID
+	Region        string
+	Queue         string
+	Payload       json.RawMessage
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) CreateOutboxTask(ctx context.Context, arg CreateOutboxTaskParams) error {
+	_, err := q.db.ExecContext(ctx, createOutboxTask,
+		arg.TaskID,
+		arg.ImageID,
+		arg.Region,
+		arg.Queue,
+		arg.Payload,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const deleteOutboxTask = `-- name: DeleteOutboxTask :exec
+DELETE FROM task_outbox WHERE task_id = $1
+`
+
+func (q *Queries) DeleteOutboxTask(ctx context.Context, taskID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteOutboxTask, taskID)
+	return err
+}
@@ -0,0 +1,140 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: user_boosts.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createUserBoost = `-- name: CreateUserBoost :one
+INSERT INTO user_boosts(user_id, granted_by, reason, extra_active_batches, concurrency_multiplier, priority, starts_at, ends_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, user_id, granted_by, reason, extra_active_batches, concurrency_multiplier, starts_at, ends_at, revoked_at, created_at, priority
+`
+
+type CreateUserBoostParams struct {
+	UserID                uuid.UUID
+	GrantedBy             uuid.NullUUID
+	Reason                string
+	ExtraActiveBatches    int32
+	ConcurrencyMultiplier int32
+	Priority              bool
+	StartsAt              time.Time
+	EndsAt                time.Time
+}
+
+func (q *Queries) CreateUserBoost(ctx context.Context, arg CreateUserBoostParams) (UserBoost, error) {
+	row := q.db.QueryRowContext(ctx, createUserBoost,
+		arg.UserID,
+		arg.GrantedBy,
+		arg.Reason,
+		arg.ExtraActiveBatches,
+		arg.ConcurrencyMultiplier,
+		arg.Priority,
+		arg.StartsAt,
+		arg.EndsAt,
+	)
+	var i UserBoost
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GrantedBy,
+		&i.Reason,
+		&i.ExtraActiveBatches,
+		&i.ConcurrencyMultiplier,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.Priority,
+	)
+	return i, err
+}
+
+const getActiveUserBoost = `-- name: GetActiveUserBoost :one
+SELECT COALESCE(MAX(extra_active_batches), 0)::int AS extra_active_batches, COALESCE(MAX(concurrency_multiplier), 1)::int AS concurrency_multiplier, COALESCE(BOOL_OR(priority), false)::bool AS priority FROM user_boosts WHERE user_id = $1 AND revoked_at IS NULL AND starts_at <= NOW() AND ends_at > NOW()
+`
+
+type GetActiveUserBoostRow struct {
+	ExtraActiveBatches    int32
+	ConcurrencyMultiplier int32
+	Priority              bool
+}
+
+func (q *Queries) GetActiveUserBoost(ctx context.Context, userID uuid.UUID) (GetActiveUserBoostRow, error) {
+	row := q.db.QueryRowContext(ctx, getActiveUserBoost, userID)
+	var i GetActiveUserBoostRow
+	err := row.Scan(&i.ExtraActiveBatches, &i.ConcurrencyMultiplier, &i.Priority)
+	return i, err
+}
+
+const getUserBoosts = `-- name: GetUserBoosts :many
+SELECT id, user_id, granted_by, reason, extra_active_batches, concurrency_multiplier, starts_at, ends_at, revoked_at, created_at, priority FROM user_boosts WHERE user_id = $1 ORDER BY starts_at DESC
+`
+
+func (q *Queries) GetUserBoosts(ctx context.Context, userID uuid.UUID) ([]UserBoost, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBoosts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserBoost
+	for rows.Next() {
+		var i UserBoost
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GrantedBy,
+			&i.Reason,
+			&i.ExtraActiveBatches,
+			&i.ConcurrencyMultiplier,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+			&i.Priority,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserBoost = `-- name: RevokeUserBoost :one
+UPDATE user_boosts SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND ends_at > NOW() RETURNING id, user_id, granted_by, reason, extra_active_batches, concurrency_multiplier, starts_at, ends_at, revoked_at, created_at, priority
+`
+
+type RevokeUserBoostParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RevokeUserBoost(ctx context.Context, arg RevokeUserBoostParams) (UserBoost, error) {
+	row := q.db.QueryRowContext(ctx, revokeUserBoost, arg.ID, arg.UserID)
+	var i UserBoost
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GrantedBy,
+		&i.Reason,
+		&i.ExtraActiveBatches,
+		&i.ConcurrencyMultiplier,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.Priority,
+	)
+	return i, err
+}
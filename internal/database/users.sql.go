@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,8 +41,30 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, password_hash, created_at, updated_at, deleted_at, is_admin, disabled_at, region, default_watermark_id FROM users WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.DisabledAt,
+		&i.Region,
+		&i.DefaultWatermarkID,
+	)
+	return i, err
+}
+
 const getUsersByEmail = `-- name: GetUsersByEmail :one
-SELECT id, email, password_hash, created_at, updated_at, deleted_at FROM users WHERE email = $1 AND deleted_at IS NULL
+SELECT id, email, password_hash, created_at, updated_at, deleted_at, is_admin, disabled_at, region, default_watermark_id FROM users WHERE email = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetUsersByEmail(ctx context.Context, email string) (User, error) {
@@ -54,6 +77,152 @@ func (q *Queries) GetUsersByEmail(ctx context.Context, email string) (User, erro
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.DisabledAt,
+		&i.Region,
+		&i.DefaultWatermarkID,
 	)
 	return i, err
 }
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, email, is_admin, disabled_at, created_at FROM users WHERE deleted_at IS NULL ORDER BY created_at, id LIMIT $1 OFFSET $2
+`
+
+type ListUsersParams struct {
+	Limit  int32
+	Offset int32
+}
+
+type ListUsersRow struct {
+	ID         uuid.UUID
+	Email      string
+	IsAdmin    bool
+	DisabledAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.IsAdmin,
+			&i.DisabledAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserBatchQuota = `-- name: LockUserBatchQuota :exec
+SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE
+`
+
+func (q *Queries) LockUserBatchQuota(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockUserBatchQuota, id)
+	return err
+}
+
+const lockUserByID = `-- name: LockUserByID :exec
+SELECT id FROM users WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockUserByID(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockUserByID, id)
+	return err
+}
+
+const updateUserAdminByEmail = `-- name: UpdateUserAdminByEmail :one
+UPDATE users SET is_admin = $1, updated_at = NOW() WHERE email = $2 AND deleted_at IS NULL RETURNING id
+`
+
+type UpdateUserAdminByEmailParams struct {
+	IsAdmin bool
+	Email   string
+}
+
+func (q *Queries) UpdateUserAdminByEmail(ctx context.Context, arg UpdateUserAdminByEmailParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, updateUserAdminByEmail, arg.IsAdmin, arg.Email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const updateUserDisabledByEmail = `-- name: UpdateUserDisabledByEmail :one
+UPDATE users SET disabled_at = CASE WHEN $1::BOOLEAN THEN COALESCE(disabled_at, NOW()) END, updated_at = NOW() WHERE email = $2 AND deleted_at IS NULL RETURNING id
+`
+
+type UpdateUserDisabledByEmailParams struct {
+	Disabled bool
+	Email    string
+}
+
+func (q *Queries) UpdateUserDisabledByEmail(ctx context.Context, arg UpdateUserDisabledByEmailParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, updateUserDisabledByEmail, arg.Disabled, arg.Email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :exec
+UPDATE users SET email = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL
+`
+
+type UpdateUserEmailParams struct {
+	Email string
+	ID    uuid.UUID
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserEmail, arg.Email, arg.ID)
+	return err
+}
+
+const updateUserPasswordByEmail = `-- name: UpdateUserPasswordByEmail :one
+UPDATE users SET password_hash = $1, updated_at = NOW() WHERE email = $2 AND deleted_at IS NULL RETURNING id
+`
+
+type UpdateUserPasswordByEmailParams struct {
+	PasswordHash string
+	Email        string
+}
+
+func (q *Queries) UpdateUserPasswordByEmail(ctx context.Context, arg UpdateUserPasswordByEmailParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPasswordByEmail, arg.PasswordHash, arg.Email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const updateUserRegionByEmail = `-- name: UpdateUserRegionByEmail :one
+UPDATE users SET region = $1, updated_at = NOW() WHERE email = $2 AND deleted_at IS NULL RETURNING id
+`
+
+type UpdateUserRegionByEmailParams struct {
+	Region string
+	Email  string
+}
+
+func (q *Queries) UpdateUserRegionByEmail(ctx context.Context, arg UpdateUserRegionByEmailParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRegionByEmail, arg.Region, arg.Email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
@@ -0,0 +1,220 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: watermarks.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createWatermark = `-- name: CreateWatermark :one
+INSERT INTO watermarks(user_id, name, key, url, content_type, width, height, size_bytes, region) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, user_id, name, key, url, content_type, width, height, size_bytes, created_at, updated_at, deleted_at, region
+`
+
+type CreateWatermarkParams struct {
+	UserID      uuid.UUID
+	Name        string
+	Key         string
+	Url         string
+	ContentType string
+	Width       int32
+	Height      int32
+	SizeBytes   int64
+	Region      string
+}
+
+func (q *Queries) CreateWatermark(ctx context.Context, arg CreateWatermarkParams) (Watermark, error) {
+	row := q.db.QueryRowContext(ctx, createWatermark,
+		arg.UserID,
+		arg.Name,
+		arg.Key,
+		arg.Url,
+		arg.ContentType,
+		arg.Width,
+		arg.Height,
+		arg.SizeBytes,
+		arg.Region,
+	)
+	var i Watermark
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Key,
+		&i.Url,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Region,
+	)
+	return i, err
+}
+
+const deleteWatermarkByID = `-- name: DeleteWatermarkByID :execrows
+WITH cleared AS (
+    UPDATE users u SET default_watermark_id = NULL, updated_at = NOW() WHERE u.id = $2 AND u.default_watermark_id = $1
+)
+UPDATE watermarks w SET deleted_at = NOW() WHERE w.id = $1 AND w.user_id = $2 AND w.deleted_at IS NULL
+`
+
+type DeleteWatermarkByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteWatermarkByID(ctx context.Context, arg DeleteWatermarkByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWatermarkByID, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserDefaultWatermark = `-- name: GetUserDefaultWatermark :one
+SELECT w.id, w.user_id, w.name, w.key, w.url, w.content_type, w.width, w.height, w.size_bytes, w.created_at, w.updated_at, w.deleted_at, w.region FROM watermarks w JOIN users u ON u.default_watermark_id = w.id WHERE u.id = $1 AND w.deleted_at IS NULL
+`
+
+func (q *Queries) GetUserDefaultWatermark(ctx context.Context, id uuid.UUID) (Watermark, error) {
+	row := q.db.QueryRowContext(ctx, getUserDefaultWatermark, id)
+	var i Watermark
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Key,
+		&i.Url,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Region,
+	)
+	return i, err
+}
+
+const getUserWatermarkByID = `-- name: GetUserWatermarkByID :one
+SELECT id, user_id, name, key, url, content_type, width, height, size_bytes, created_at, updated_at, deleted_at, region FROM watermarks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type GetUserWatermarkByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetUserWatermarkByID(ctx context.Context, arg GetUserWatermarkByIDParams) (Watermark, error) {
+	row := q.db.QueryRowContext(ctx, getUserWatermarkByID, arg.ID, arg.UserID)
+	var i Watermark
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Key,
+		&i.Url,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Region,
+	)
+	return i, err
+}
+
+const getUserWatermarks = `-- name: GetUserWatermarks :many
+SELECT id, user_id, name, key, url, content_type, width, height, size_bytes, created_at, updated_at, deleted_at, region FROM watermarks WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUserWatermarks(ctx context.Context, userID uuid.UUID) ([]Watermark, error) {
+	rows, err := q.db.QueryContext(ctx, getUserWatermarks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Watermark
+	for rows.Next() {
+		var i Watermark
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Key,
+			&i.Url,
+			&i.ContentType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Region,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserDefaultWatermark = `-- name: SetUserDefaultWatermark :exec
+UPDATE users SET default_watermark_id = $2, updated_at = NOW() WHERE id = $1
+`
+
+type SetUserDefaultWatermarkParams struct {
+	ID                 uuid.UUID
+	DefaultWatermarkID uuid.NullUUID
+}
+
+func (q *Queries) SetUserDefaultWatermark(ctx context.Context, arg SetUserDefaultWatermarkParams) error {
+	_, err := q.db.ExecContext(ctx, setUserDefaultWatermark, arg.ID, arg.DefaultWatermarkID)
+	return err
+}
+
+const updateWatermarkName = `-- name: UpdateWatermarkName :one
+UPDATE watermarks SET name = $1, updated_at = NOW() WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL RETURNING id, user_id, name, key, url, content_type, width, height, size_bytes, created_at, updated_at, deleted_at, region
+`
+
+type UpdateWatermarkNameParams struct {
+	Name   string
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) UpdateWatermarkName(ctx context.Context, arg UpdateWatermarkNameParams) (Watermark, error) {
+	row := q.db.QueryRowContext(ctx, updateWatermarkName, arg.Name, arg.ID, arg.UserID)
+	var i Watermark
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Key,
+		&i.Url,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Region,
+	)
+	return i, err
+}
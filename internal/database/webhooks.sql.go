@@ -0,0 +1,392 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhooks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueWebhookEvents = `-- name: ClaimDueWebhookEvents :many
+UPDATE webhook_events e SET next_attempt_at = NOW() + make_interval(secs => $1::int) WHERE e.id IN (SELECT d.id FROM webhook_events d WHERE d.delivered_at IS NULL AND d.failed_at IS NULL AND d.next_attempt_at <= NOW() ORDER BY d.next_attempt_at LIMIT $2 FOR UPDATE SKIP LOCKED) RETURNING e.id, e.webhook_id, e.event, e.payload, e.attempts, e.next_attempt_at, e.delivered_at, e.failed_at, e.created_at
+`
+
+type ClaimDueWebhookEventsParams struct {
+	LeaseSeconds int32
+	PageLimit    int32
+}
+
+func (q *Queries) ClaimDueWebhookEvents(ctx context.Context, arg ClaimDueWebhookEventsParams) ([]WebhookEvent, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueWebhookEvents, arg.LeaseSeconds, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEvent
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.DeliveredAt,
+			&i.FailedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countWebhookDeliveries = `-- name: CountWebhookDeliveries :one
+SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1
+`
+
+func (q *Queries) CountWebhookDeliveries(ctx context.Context, webhookID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countWebhookDeliveries, webhookID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks(user_id, url, secret) VALUES ($1, $2, $3) RETURNING id, user_id, url, secret, created_at, updated_at, deleted_at
+`
+
+type CreateWebhookParams struct {
+	UserID uuid.UUID
+	Url    string
+	Secret string
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook, arg.UserID, arg.Url, arg.Secret)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries(webhook_id, event, payload, status_code, error, latency_ms, response_excerpt, event_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, webhook_id, event, payload, status_code, error, latency_ms, response_excerpt, created_at, event_id
+`
+
+type CreateWebhookDeliveryParams struct {
+	WebhookID       uuid.UUID
+	Event           string
+	Payload         json.RawMessage
+	StatusCode      sql.NullInt32
+	Error           sql.NullString
+	LatencyMs       int32
+	ResponseExcerpt sql.NullString
+	EventID         uuid.NullUUID
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookDelivery,
+		arg.WebhookID,
+		arg.Event,
+		arg.Payload,
+		arg.StatusCode,
+		arg.Error,
+		arg.LatencyMs,
+		arg.ResponseExcerpt,
+		arg.EventID,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.StatusCode,
+		&i.Error,
+		&i.LatencyMs,
+		&i.ResponseExcerpt,
+		&i.CreatedAt,
+		&i.EventID,
+	)
+	return i, err
+}
+
+const deleteWebhookByID = `-- name: DeleteWebhookByID :exec
+UPDATE webhooks SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type DeleteWebhookByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteWebhookByID(ctx context.Context, arg DeleteWebhookByIDParams) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhookByID, arg.ID, arg.UserID)
+	return err
+}
+
+const enqueueWebhookEvents = `-- name: EnqueueWebhookEvents :exec
+INSERT INTO webhook_events(webhook_id, event, payload) SELECT w.id, $1::text, $2::jsonb FROM webhooks w WHERE w.user_id = $3 AND w.deleted_at IS NULL
+`
+
+type EnqueueWebhookEventsParams struct {
+	Event   string
+	Payload json.RawMessage
+	UserID  uuid.UUID
+}
+
+func (q *Queries) EnqueueWebhookEvents(ctx context.Context, arg EnqueueWebhookEventsParams) error {
+	_, err := q.db.ExecContext(ctx, enqueueWebhookEvents, arg.Event, arg.Payload, arg.UserID)
+	return err
+}
+
+const failWebhookEvent = `-- name: FailWebhookEvent :exec
+UPDATE webhook_events SET attempts = attempts + 1, failed_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) FailWebhookEvent(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, failWebhookEvent, id)
+	return err
+}
+
+const getUserWebhookByID = `-- name: GetUserWebhookByID :one
+SELECT id, user_id, url, secret, created_at, updated_at, deleted_at FROM webhooks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type GetUserWebhookByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetUserWebhookByID(ctx context.Context, arg GetUserWebhookByIDParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getUserWebhookByID, arg.ID, arg.UserID)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserWebhooks = `-- name: GetUserWebhooks :many
+SELECT id, user_id, url, secret, created_at, updated_at, deleted_at FROM webhooks WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUserWebhooks(ctx context.Context, userID uuid.UUID) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, getUserWebhooks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookByID = `-- name: GetWebhookByID :one
+SELECT id, user_id, url, secret, created_at, updated_at, deleted_at FROM webhooks WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetWebhookByID(ctx context.Context, id uuid.UUID) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookByID, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT id, webhook_id, event, payload, status_code, error, latency_ms, response_excerpt, created_at, event_id FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC LIMIT $3 OFFSET $2
+`
+
+type GetWebhookDeliveriesParams struct {
+	WebhookID  uuid.UUID
+	PageOffset int32
+	PageLimit  int32
+}
+
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookDeliveries, arg.WebhookID, arg.PageOffset, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.StatusCode,
+			&i.Error,
+			&i.LatencyMs,
+			&i.ResponseExcerpt,
+			&i.CreatedAt,
+			&i.EventID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDeliveryByID = `-- name: GetWebhookDeliveryByID :one
+SELECT id, webhook_id, event, payload, status_code, error, latency_ms, response_excerpt, created_at, event_id FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2
+`
+
+type GetWebhookDeliveryByIDParams struct {
+	ID        uuid.UUID
+	WebhookID uuid.UUID
+}
+
+func (q *Queries) GetWebhookDeliveryByID(ctx context.Context, arg GetWebhookDeliveryByIDParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookDeliveryByID, arg.ID, arg.WebhookID)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.StatusCode,
+		&i.Error,
+		&i.LatencyMs,
+		&i.ResponseExcerpt,
+		&i.CreatedAt,
+		&i.EventID,
+	)
+	return i, err
+}
+
+const getWebhookSecrets = `-- name: GetWebhookSecrets :many
+SELECT id, user_id, secret FROM webhooks
+`
+
+type GetWebhookSecretsRow struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Secret string
+}
+
+func (q *Queries) GetWebhookSecrets(ctx context.Context) ([]GetWebhookSecretsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWebhookSecretsRow
+	for rows.Next() {
+		var i GetWebhookSecretsRow
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Secret); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markWebhookEventDelivered = `-- name: MarkWebhookEventDelivered :exec
+UPDATE webhook_events SET attempts = attempts + 1, delivered_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) MarkWebhookEventDelivered(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markWebhookEventDelivered, id)
+	return err
+}
+
+const retryWebhookEvent = `-- name: RetryWebhookEvent :exec
+UPDATE webhook_events SET attempts = attempts + 1, next_attempt_at = $2 WHERE id = $1
+`
+
+type RetryWebhookEventParams struct {
+	ID            uuid.UUID
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) RetryWebhookEvent(ctx context.Context, arg RetryWebhookEventParams) error {
+	_, err := q.db.ExecContext(ctx, retryWebhookEvent, arg.ID, arg.NextAttemptAt)
+	return err
+}
+
+const updateWebhookSecret = `-- name: UpdateWebhookSecret :exec
+UPDATE webhooks SET secret = $1 WHERE id = $2
+`
+
+type UpdateWebhookSecretParams struct {
+	Secret string
+	ID     uuid.UUID
+}
+
+func (q *Queries) UpdateWebhookSecret(ctx context.Context, arg UpdateWebhookSecretParams) error {
+	_, err := q.db.ExecContext(ctx, updateWebhookSecret, arg.Secret, arg.ID)
+	return err
+}
@@ -0,0 +1,81 @@
+// Package faults injects random failures into S3 and RabbitMQ operations, so
+// retries, backoff and dead-lettering can be exercised in integration tests
+// and staging game days. It is off unless configured and refuses to run in
+// production.
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// ErrInjected is the cause of every injected failure.
+var ErrInjected = errors.New("injected fault")
+
+// Rates are the probabilities, from 0 to 1, with which operations fail.
+type Rates struct {
+	S3   float64
+	AMQP float64
+}
+
+// Enabled reports whether any failures are injected.
+func (r Rates) Enabled() bool {
+	return r.S3 > 0 || r.AMQP > 0
+}
+
+// LoadRates reads FAULT_S3_RATE and FAULT_AMQP_RATE. Setting either with
+// APP_ENV=production is an error.
+func LoadRates() (Rates, error) {
+	var rates Rates
+	var errs []error
+	for key, dst := range map[string]*float64{"FAULT_S3_RATE": &rates.S3, "FAULT_AMQP_RATE": &rates.AMQP} {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			errs = append(errs, fmt.Errorf("invalid %s: must be between 0 and 1", key))
+			continue
+		}
+		*dst = rate
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Rates{}, err
+	}
+	if rates.Enabled() && os.Getenv("APP_ENV") == "production" {
+		return Rates{}, errors.New("fault injection cannot be enabled in production")
+	}
+	return rates, nil
+}
+
+// Fail reports whether an operation should fail, with probability rate.
+func Fail(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+// S3Option makes an S3 client fail requests with probability rate before
+// they are sent. Injected failures are not retried by the SDK.
+func S3Option(rate float64) func(*s3.Options) {
+	return func(o *s3.Options) {
+		if rate <= 0 {
+			return
+		}
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("InjectFault", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				if Fail(rate) {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, fmt.Errorf("s3 %s: %w", awsmiddleware.GetOperationName(ctx), ErrInjected)
+				}
+				return next.HandleInitialize(ctx, in)
+			}), middleware.Before)
+		})
+	}
+}
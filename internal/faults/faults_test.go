@@ -0,0 +1,39 @@
+package faults
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRates(t *testing.T) {
+	t.Setenv("APP_ENV", "staging")
+	t.Setenv("FAULT_S3_RATE", "0.25")
+	t.Setenv("FAULT_AMQP_RATE", "")
+	rates, err := LoadRates()
+	require.NoError(t, err)
+	assert.Equal(t, Rates{S3: 0.25}, rates)
+	assert.True(t, rates.Enabled())
+
+	t.Setenv("FAULT_AMQP_RATE", "1.5")
+	_, err = LoadRates()
+	assert.ErrorContains(t, err, "invalid FAULT_AMQP_RATE")
+
+	t.Setenv("FAULT_AMQP_RATE", "")
+	t.Setenv("APP_ENV", "production")
+	_, err = LoadRates()
+	assert.Error(t, err)
+
+	t.Setenv("FAULT_S3_RATE", "0")
+	rates, err = LoadRates()
+	require.NoError(t, err)
+	assert.False(t, rates.Enabled())
+}
+
+func TestFail(t *testing.T) {
+	for range 100 {
+		assert.False(t, Fail(0))
+		assert.True(t, Fail(1))
+	}
+}
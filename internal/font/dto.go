@@ -0,0 +1,15 @@
+package font
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FontResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Format    string    `json:"format"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
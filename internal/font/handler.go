@@ -0,0 +1,227 @@
+package font
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"golang.org/x/image/font/opentype"
+)
+
+// maxFontSize is the largest font file accepted for upload.
+const maxFontSize = 5 << 20
+
+var ErrUnsupportedFont = errors.New("unsupported font file, expected TTF or OTF")
+
+type FontHandler struct {
+	validator *validator.Validate
+	dbQueries *database.Queries
+	config    *utils.Config
+}
+
+func NewHandler(validator *validator.Validate, dbQueries *database.Queries, config *utils.Config) *FontHandler {
+	return &FontHandler{
+		validator: validator,
+		dbQueries: dbQueries,
+		config:    config,
+	}
+}
+
+// GetAll godoc
+// @Summary Get list of fonts
+// @Description Retrieve the fonts uploaded by the authenticated user
+// @Tags fonts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=[]FontResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /fonts [get]
+func (h *FontHandler) GetAll(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	fonts, err := h.dbQueries.GetUserFonts(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	fontsRes := make([]FontResponse, len(fonts))
+	for i, f := range fonts {
+		fontsRes[i] = toFontResponse(f)
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "fonts retrieved successfully", fontsRes)
+}
+
+// Upload godoc
+// @Summary Upload font
+// @Description Upload a TTF or OTF font (max 5 MB) to use for text watermarks
+// @Tags fonts
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param name formData string false "Font name, defaults to the file name"
+// @Param file formData file true "Font file (.ttf or .otf)"
+// @Success 201 {object} utils.SuccessResponse{data=FontResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 413 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /fonts [post]
+func (h *FontHandler) Upload(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "no font file uploaded")
+	}
+	if file.Size > maxFontSize {
+		return utils.RespondError(c, http.StatusRequestEntityTooLarge, "font file too large")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxFontSize+1))
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if len(data) > maxFontSize {
+		return utils.RespondError(c, http.StatusRequestEntityTooLarge, "font file too large")
+	}
+
+	format, err := ValidateFont(data)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	name := c.FormValue("name")
+	if name == "" {
+		name = strings.TrimSuffix(file.Filename, "."+format)
+	}
+	if len(name) > 255 {
+		return utils.RespondError(c, http.StatusBadRequest, "font name too long")
+	}
+
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	storage, err := h.config.Storage(user.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to upload font: %v", err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	key := "fonts/" + userID.String() + "/" + uuid.NewString() + "." + format
+	_, err = storage.S3Client.PutObject(c.Request().Context(), &s3.PutObjectInput{
+		Bucket:      aws.String(storage.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("font/" + format),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	font, err := h.dbQueries.CreateFont(c.Request().Context(), database.CreateFontParams{
+		UserID:    userID,
+		Name:      name,
+		Key:       key,
+		Format:    format,
+		SizeBytes: int64(len(data)),
+		Region:    user.Region,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "font uploaded successfully", toFontResponse(font))
+}
+
+// DeleteByID godoc
+// @Summary Delete font
+// @Description Delete a font and its file. Batches already using it fall back to the default font
+// @Tags fonts
+// @Produce json
+// @Security BearerAuth
+// @Param fontID path string true "Font ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /fonts/{fontID} [delete]
+func (h *FontHandler) DeleteByID(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	fontUUID := utils.ParamUUID(c, "fontID")
+
+	font, err := h.dbQueries.DeleteFontByID(c.Request().Context(), database.DeleteFontByIDParams{
+		ID:     fontUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "font")
+	}
+
+	// Batches no longer see the font once it is deleted, so its file can go.
+	storage, err := h.config.Storage(font.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to delete font object %s: %v", font.Key, err)
+		return utils.RespondJSON(c, http.StatusOK, "font deleted successfully", nil)
+	}
+	_, err = storage.S3Client.DeleteObject(c.Request().Context(), &s3.DeleteObjectInput{
+		Bucket: aws.String(storage.S3Bucket),
+		Key:    aws.String(font.Key),
+	})
+	if err != nil {
+		c.Logger().Errorf("failed to delete font object %s: %v", font.Key, err)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "font deleted successfully", nil)
+}
+
+// ValidateFont checks that data is a parseable TrueType or OpenType font and
+// returns its file extension ("ttf" or "otf").
+func ValidateFont(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", ErrUnsupportedFont
+	}
+
+	var format string
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+		format = "ttf"
+	case "OTTO":
+		format = "otf"
+	default:
+		return "", ErrUnsupportedFont
+	}
+
+	if _, err := opentype.Parse(data); err != nil {
+		return "", ErrUnsupportedFont
+	}
+	return format, nil
+}
+
+func toFontResponse(f database.Font) FontResponse {
+	return FontResponse{
+		ID:        f.ID,
+		Name:      f.Name,
+		Format:    f.Format,
+		SizeBytes: f.SizeBytes,
+		CreatedAt: f.CreatedAt,
+	}
+}
@@ -0,0 +1,34 @@
+package font
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestValidateFont(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat string
+		wantErr    bool
+	}{
+		{name: "truetype font", data: goregular.TTF, wantFormat: "ttf"},
+		{name: "empty file", data: nil, wantErr: true},
+		{name: "not a font", data: []byte("\x89PNG\r\n\x1a\n"), wantErr: true},
+		{name: "truncated font", data: goregular.TTF[:512], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ValidateFont(tt.data)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnsupportedFont)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFormat, format)
+		})
+	}
+}
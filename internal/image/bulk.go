@@ -0,0 +1,191 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// maxDeleteObjects is the most keys S3 accepts in one DeleteObjects call.
+const maxDeleteObjects = 1000
+
+// BulkDelete godoc
+// @Summary Delete images in bulk
+// @Description Delete up to 500 of the authenticated user's images at once. IDs that do not exist or belong to someone else are ignored; stored objects are removed in the background once the delete is committed
+// @Tags images
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param images body BulkImagesRequest true "Bulk Images Request"
+// @Success 200 {object} utils.SuccessResponse{data=BulkImagesResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /images [delete]
+func (h *ImageHandler) BulkDelete(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	var body BulkImagesRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	dbQueries := utils.Queries(ctx, h.dbQueries)
+	images, err := dbQueries.DeleteUserImagesByIDs(ctx, database.DeleteUserImagesByIDsParams{
+		UserID:   userID,
+		ImageIds: body.ImageIDs,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	// Originals linked by duplicate uploads and processed files shared by
+	// similar images stay until the last image using them is deleted.
+	originalKeys := make([]string, len(images))
+	var objectURLs []string
+	for i, img := range images {
+		originalKeys[i] = img.Key
+		for _, url := range []sql.NullString{img.ProcessedUrl, img.ThumbnailUrl} {
+			if url.Valid {
+				objectURLs = append(objectURLs, url.String)
+			}
+		}
+	}
+	referenced, err := dbQueries.GetReferencedImageKeys(ctx, originalKeys)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	referencedURLs, err := dbQueries.GetReferencedObjectURLs(ctx, objectURLs)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	// Objects are removed by the workers of the region holding them.
+	imageIDs := make([]uuid.UUID, len(images))
+	keys := make(map[string][]string)
+	for i, img := range images {
+		imageIDs[i] = img.ID
+		region := img.BatchRegion
+		if img.Key != "" && !slices.Contains(referenced, img.Key) && !slices.Contains(keys[region], img.Key) {
+			keys[region] = append(keys[region], img.Key)
+		}
+		storage, err := h.config.Storage(region)
+		if err != nil {
+			c.Logger().Errorf("failed to clean up processed objects of image %s: %v", img.ID, err)
+			continue
+		}
+		for _, url := range []sql.NullString{img.ProcessedUrl, img.ThumbnailUrl} {
+			if !url.Valid || slices.Contains(referencedURLs, url.String) {
+				continue
+			}
+			if key := utils.GetObjectKey(storage.S3CfDistribution, url.String); key != "" && !slices.Contains(keys[region], key) {
+				keys[region] = append(keys[region], key)
+			}
+		}
+	}
+	// The cleanups are queued with the deletes, so they are neither lost
+	// nor run for images whose delete is rolled back.
+	for region, regionKeys := range keys {
+		if err := batch.QueueCleanupAfterCommit(ctx, h.dbQueries, h.config, region, regionKeys); err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "images deleted successfully", BulkImagesResponse{ImageIDs: imageIDs})
+}
+
+// BulkRetry godoc
+// @Summary Retry images in bulk
+// @Description Queue up to 500 failed images for processing again. Images that are not failed, belong to someone else, or sit in an archived batch are ignored. Either every listed image is queued or none is
+// @Tags images
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param images body BulkImagesRequest true "Bulk Images Request"
+// @Success 200 {object} utils.SuccessResponse{data=BulkImagesResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /images/retry [post]
+func (h *ImageHandler) BulkRetry(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	var body BulkImagesRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	retried, err := utils.Queries(ctx, h.dbQueries).RetryUserImagesByIDs(ctx, database.RetryUserImagesByIDsParams{
+		UserID:   userID,
+		ImageIds: body.ImageIDs,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	// The tasks are queued with the retries and published once they are
+	// committed, so an image is never left pending without a task.
+	imageIDs := make([]uuid.UUID, len(retried))
+	tasks := make(map[string][]batch.ImageTask)
+	for i, img := range retried {
+		imageIDs[i] = img.ID
+		tasks[img.BatchRegion] = append(tasks[img.BatchRegion], batch.NewImageTask(img.ID))
+	}
+	for region, regionTasks := range tasks {
+		if err := batch.QueueTasksAfterCommit(ctx, h.dbQueries, h.config, userID, region, regionTasks); err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "images queued for retry", BulkImagesResponse{ImageIDs: imageIDs})
+}
+
+// CleanupObjects deletes objects of removed images from S3 in chunks of up to 1000 keys.
+func CleanupObjects(cfg *utils.Config) func(batch.CleanupTask) pubsub.AckType {
+	return func(m batch.CleanupTask) pubsub.AckType {
+		for start := 0; start < len(m.Keys); start += maxDeleteObjects {
+			end := min(start+maxDeleteObjects, len(m.Keys))
+			objects := make([]types.ObjectIdentifier, 0, end-start)
+			for _, key := range m.Keys[start:end] {
+				objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+			}
+
+			out, err := cfg.S3Client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
+				Bucket: aws.String(cfg.S3Bucket),
+				Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				log.Printf("error delete objects, requeuing: %v", err)
+				return pubsub.NackRequeue
+			}
+			for _, e := range out.Errors {
+				log.Printf("error delete object %s: %s", aws.ToString(e.Key), aws.ToString(e.Message))
+			}
+		}
+
+		log.Printf("%d objects cleaned up", len(m.Keys))
+		return pubsub.Ack
+	}
+}
@@ -0,0 +1,136 @@
+package image
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"slices"
+
+	"github.com/buckket/go-blurhash"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// paletteSize is how many dominant colors are kept per image.
+	paletteSize = 5
+	// colorSampleSize is the longest side images are shrunk to before their
+	// colors are analysed; placeholders do not need more detail.
+	colorSampleSize = 64
+	// blurHashComponents is the number of BlurHash components along the
+	// longer side of the image.
+	blurHashComponents = 4
+)
+
+// imageColors describes an image for loading placeholders.
+type imageColors struct {
+	// Palette holds the dominant colors as #rrggbb, most common first.
+	Palette  []string
+	BlurHash string
+	// ThumbHash is base64-encoded.
+	ThumbHash string
+}
+
+// extractColors computes the dominant palette, BlurHash and ThumbHash of img.
+func extractColors(img image.Image) (*imageColors, error) {
+	sample := shrink(img, colorSampleSize)
+	if sample.Bounds().Empty() {
+		return nil, fmt.Errorf("empty image")
+	}
+
+	xComponents, yComponents := blurHashComponents, blurHashComponents
+	if w, h := sample.Bounds().Dx(), sample.Bounds().Dy(); w > h {
+		yComponents = max(1, blurHashComponents*h/w)
+	} else {
+		xComponents = max(1, blurHashComponents*w/h)
+	}
+	hash, err := blurhash.Encode(xComponents, yComponents, sample)
+	if err != nil {
+		return nil, fmt.Errorf("encode blurhash: %w", err)
+	}
+	thumbHash, err := encodeThumbHash(sample)
+	if err != nil {
+		return nil, fmt.Errorf("encode thumbhash: %w", err)
+	}
+	return &imageColors{
+		Palette:   dominantColors(sample, paletteSize),
+		BlurHash:  hash,
+		ThumbHash: base64.StdEncoding.EncodeToString(thumbHash),
+	}, nil
+}
+
+// dominantColor returns the most common color of img as #rrggbb, or "" when
+// it has no opaque pixels.
+func dominantColor(img image.Image) string {
+	if img.Bounds().Empty() {
+		return ""
+	}
+	palette := dominantColors(shrink(img, colorSampleSize), 1)
+	if len(palette) == 0 {
+		return ""
+	}
+	return palette[0]
+}
+
+// shrink scales img so its longest side is at most size pixels.
+func shrink(img image.Image, size int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w > h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// dominantColors groups the opaque pixels of img into buckets of similar
+// colors and returns the average color of the n largest buckets.
+func dominantColors(img *image.RGBA, n int) []string {
+	type bucket struct {
+		r, g, b, count int
+	}
+	// 4 bits per channel is coarse enough to merge shades of one color.
+	buckets := make(map[int]*bucket)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A < 128 {
+				continue
+			}
+			key := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.r += int(c.R)
+			bk.g += int(c.G)
+			bk.b += int(c.B)
+			bk.count++
+		}
+	}
+
+	keys := make([]int, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	// Ties are broken by key so the palette is stable across runs.
+	slices.SortFunc(keys, func(a, b int) int {
+		if c := cmp.Compare(buckets[b].count, buckets[a].count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	palette := make([]string, 0, min(n, len(keys)))
+	for _, key := range keys[:min(n, len(keys))] {
+		bk := buckets[key]
+		palette = append(palette, fmt.Sprintf("#%02x%02x%02x", bk.r/bk.count, bk.g/bk.count, bk.b/bk.count))
+	}
+	return palette
+}
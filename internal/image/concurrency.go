@@ -0,0 +1,50 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+// slotActiveWindow is how long a processing image holds one of its batch's
+// slots without being touched. Older ones are treated as abandoned, e.g. by a
+// crashed worker, so they cannot block the batch forever.
+const slotActiveWindow = 30 * time.Minute
+
+// DeferDelay is how long a task of a batch that is at its limit waits in the
+// delay queue before it is tried again, so a queue holding only that batch
+// is not spun through. Workers subscribe with pubsub.WithRetryDelay of it.
+const DeferDelay = 2 * time.Second
+
+// claimSlot marks the image processing if its batch has fewer than
+// max_concurrency images in flight, multiplied while its user has a boost,
+// and reports whether it did. The batch row
+// is locked first so concurrent workers count the same images.
+func claimSlot(ctx context.Context, db *sql.DB, dbQueries *database.Queries, img database.GetImageByIDRow) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := dbQueries.WithTx(tx)
+
+	maxConcurrency, err := qtx.LockBatchMaxConcurrency(ctx, img.BatchID)
+	if err != nil {
+		return false, err
+	}
+	claimed, err := qtx.ClaimImageSlot(ctx, database.ClaimImageSlotParams{
+		ID:             img.ID,
+		BatchID:        img.BatchID,
+		ActiveSince:    time.Now().UTC().Add(-slotActiveWindow),
+		MaxConcurrency: int64(maxConcurrency),
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return claimed == 1, nil
+}
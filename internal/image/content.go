@@ -0,0 +1,162 @@
+package image
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// GetContent godoc
+// @Summary Get processed image
+// @Description Stream the processed image through the API, for deployments where the bucket and CDN are not public. Supports Range requests and conditional requests with If-None-Match or If-Modified-Since
+// @Tags images
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param imageID path string true "Image ID"
+// @Param Range header string false "Byte range, e.g. bytes=0-1023"
+// @Success 200 {file} binary
+// @Success 206 {file} binary
+// @Success 304
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 416 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /images/{imageID}/content [get]
+func (h *ImageHandler) GetContent(c echo.Context) error {
+	return h.streamObject(c, false)
+}
+
+// GetOriginal godoc
+// @Summary Get original image
+// @Description Stream the uploaded original through the API, for deployments where the bucket and CDN are not public. Supports Range requests and conditional requests with If-None-Match or If-Modified-Since
+// @Tags images
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param imageID path string true "Image ID"
+// @Param Range header string false "Byte range, e.g. bytes=0-1023"
+// @Success 200 {file} binary
+// @Success 206 {file} binary
+// @Success 304
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 416 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /images/{imageID}/original [get]
+func (h *ImageHandler) GetOriginal(c echo.Context) error {
+	return h.streamObject(c, true)
+}
+
+// streamObject proxies the original or processed object of one of the user's
+// images, passing range and conditional headers on to S3. Originals never
+// change, so they may be cached for good; processed images are replaced when
+// an image is processed again and must be revalidated.
+func (h *ImageHandler) streamObject(c echo.Context, original bool) error {
+	userID := c.Get("userID").(uuid.UUID)
+	imageUUID := utils.ParamUUID(c, "imageID")
+
+	img, err := h.dbQueries.GetUserImageByID(c.Request().Context(), database.GetUserImageByIDParams{
+		ID:     imageUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "image not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if img.ArchiveStatus == database.BatchArchiveStatusArchived || img.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is archived")
+	}
+
+	storage, err := h.config.Storage(img.BatchRegion)
+	if err != nil {
+		c.Logger().Errorf("failed to stream image %s: %v", img.Image.ID, err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	key := img.Image.Key
+	cacheControl := "private, max-age=31536000, immutable"
+	if original && key == "" {
+		return utils.RespondError(c, http.StatusNotFound, "image has not been fetched")
+	}
+	if !original {
+		key = utils.GetObjectKey(storage.S3CfDistribution, img.Image.ProcessedUrl.String)
+		if key == "" {
+			return utils.RespondError(c, http.StatusNotFound, "image has not been processed")
+		}
+		cacheControl = "private, no-cache"
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(storage.S3Bucket),
+		Key:    aws.String(key),
+	}
+	req := c.Request()
+	if v := req.Header.Get("Range"); v != "" {
+		input.Range = aws.String(v)
+	}
+	if v := req.Header.Get("If-None-Match"); v != "" {
+		input.IfNoneMatch = aws.String(v)
+	} else if v := req.Header.Get("If-Modified-Since"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			input.IfModifiedSince = aws.Time(t)
+		}
+	}
+
+	obj, err := storage.S3Client.GetObject(req.Context(), input)
+	if err != nil {
+		var respErr *awshttp.ResponseError
+		if errors.As(err, &respErr) {
+			switch respErr.HTTPStatusCode() {
+			case http.StatusNotModified:
+				c.Response().Header().Set("Cache-Control", cacheControl)
+				return c.NoContent(http.StatusNotModified)
+			case http.StatusNotFound:
+				return utils.RespondError(c, http.StatusNotFound, "image not found")
+			case http.StatusRequestedRangeNotSatisfiable:
+				return utils.RespondError(c, http.StatusRequestedRangeNotSatisfiable, "invalid range")
+			}
+		}
+		c.Logger().Errorf("failed to get object %s: %v", key, err)
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	defer obj.Body.Close()
+
+	header := c.Response().Header()
+	header.Set("Cache-Control", cacheControl)
+	header.Set("Accept-Ranges", "bytes")
+	if obj.ETag != nil {
+		header.Set("ETag", *obj.ETag)
+	}
+	if obj.LastModified != nil {
+		header.Set("Last-Modified", obj.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if obj.ContentLength != nil {
+		header.Set("Content-Length", strconv.FormatInt(*obj.ContentLength, 10))
+	}
+	status := http.StatusOK
+	if obj.ContentRange != nil {
+		header.Set("Content-Range", *obj.ContentRange)
+		status = http.StatusPartialContent
+	}
+	contentType := aws.ToString(obj.ContentType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return c.Stream(status, contentType, obj.Body)
+}
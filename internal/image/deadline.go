@@ -0,0 +1,97 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// overdueBatchLimit caps the batches one pass of PollOverdueBatches expires,
+// so a backlog is worked off over several passes.
+const overdueBatchLimit = 100
+
+// PollOverdueBatches periodically marks the pending images of the worker's
+// region whose batch is past its deadline as expired, instead of leaving
+// them queued until a worker gets to them, and finishes those batches:
+// their report is written and their batch.completed webhooks queued once no
+// image is processing any more. Images already processing still finish.
+func PollOverdueBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expireOverdueBatches(ctx, db, dbQueries, cfg)
+		}
+	}
+}
+
+func expireOverdueBatches(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config) {
+	for range overdueBatchLimit {
+		expired, err := expireNextOverdueBatch(ctx, db, dbQueries, cfg)
+		if err != nil {
+			log.Printf("error expire overdue batch: %v", err)
+			return
+		}
+		if !expired {
+			return
+		}
+	}
+}
+
+// expireNextOverdueBatch expires the pending images of the batch whose
+// deadline passed longest ago and records the event in one transaction, then
+// finishes the batch. It reports false when no batch is due.
+func expireNextOverdueBatch(ctx context.Context, db *sql.DB, dbQueries *database.Queries, cfg *utils.Config) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := dbQueries.WithTx(tx)
+
+	b, err := qtx.GetNextOverdueBatch(ctx, cfg.Region)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	expired, err := qtx.ExpirePendingBatchImages(ctx, b.ID)
+	if err != nil {
+		return false, err
+	}
+	// A waiting batch has nothing left to start.
+	if b.WaitingSince.Valid {
+		if err := qtx.ClearBatchWaiting(ctx, b.ID); err != nil {
+			return false, err
+		}
+	}
+	err = qtx.CreateBatchEvent(ctx, database.CreateBatchEventParams{
+		BatchID: b.ID,
+		UserID:  b.UserID,
+		Type:    database.BatchEventTypeDeadlinePassed,
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	log.Printf("batch %s is past its deadline, %d images expired", b.ID, len(expired))
+	if len(expired) == 0 {
+		return true, nil
+	}
+	if err := finishBatch(ctx, dbQueries, cfg, expired[0]); err != nil {
+		log.Printf("error finishing batch %s: %v", b.ID, err)
+	}
+	return true, nil
+}
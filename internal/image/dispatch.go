@@ -0,0 +1,143 @@
+package image
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+// ErrInvalidMedia marks originals that can never be processed (corrupt or
+// unsupported data), as opposed to transient failures worth retrying.
+var ErrInvalidMedia = errors.New("invalid media")
+
+// processedMedia is the output of a mediaProcessor.
+type processedMedia struct {
+	Data      []byte
+	MediaType string
+	// Width, Height, Thumbnail and Colors are only computed for stills. The
+	// thumbnail has the same media type as Data.
+	Width     int
+	Height    int
+	Thumbnail []byte
+	Colors    *imageColors
+	// WatermarkPosition is where the watermark was placed, set when the
+	// batch position was used rather than a placement rectangle.
+	WatermarkPosition database.WatermarkPosition
+	// OriginalWidth, OriginalHeight and OriginalColor describe the upload,
+	// upright, and are only computed for stills and GIFs.
+	OriginalWidth  int
+	OriginalHeight int
+	OriginalColor  string
+	// PHash is the perceptual hash of the upright upload, nil for media
+	// other than stills and GIFs.
+	PHash *uint64
+	// StepTimings are the durations of the pipeline steps, nil for media
+	// that does not run the pipeline.
+	StepTimings []batch.StepTiming
+}
+
+// mediaProcessor turns an original into its processed asset.
+type mediaProcessor func(ctx context.Context, data []byte, watermark image.Image, opts renderOptions) (processedMedia, error)
+
+// mediaProcessors routes originals by their sniffed media type. Stills and
+// GIFs are always handled; other media are added by optional processors at
+// startup, before the worker subscribes.
+var mediaProcessors = map[string]mediaProcessor{
+	"image/jpeg": processStill,
+	"image/png":  processStill,
+	"image/webp": processStill,
+	"image/gif":  processGIF,
+}
+
+// processorFor picks the processor for data based on its content, ignoring
+// the content type the client claimed on upload.
+func processorFor(data []byte) (mediaProcessor, error) {
+	mediaType := http.DetectContentType(data)
+	processor, ok := mediaProcessors[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported media type %s", ErrInvalidMedia, mediaType)
+	}
+	return processor, nil
+}
+
+// thumbnailSize is the longest side of the thumbnails made for stills.
+const thumbnailSize = 320
+
+// processStill turns an image upright according to its EXIF orientation,
+// hides its redactions, applies the batch crop and runs the batch pipeline,
+// which resizes, watermarks and sharpens it before it is re-encoded along
+// with a thumbnail. The batch transforms follow the other steps and the
+// invisible watermark is embedded right before encoding. Encoding drops all metadata unless the
+// batch preserves it, which only applies to JPEG output. The dominant colors and
+// BlurHash are taken from the rendered image, so placeholders match what is
+// served; failing to compute them does not fail the image.
+func processStill(_ context.Context, data []byte, watermark image.Image, opts renderOptions) (processedMedia, error) {
+	img, err := decodeImage(data)
+	if err != nil {
+		return processedMedia{}, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+	}
+	exif := jpegExif(data)
+	img = orient(img, exifOrientation(exif))
+	original := img.Bounds().Size()
+	originalColor := dominantColor(img)
+	hash := perceptualHash(img)
+	img, err = redactImage(img, opts.Redactions)
+	if err != nil {
+		return processedMedia{}, err
+	}
+	img, err = cropImage(img, opts.Crop)
+	if err != nil {
+		return processedMedia{}, err
+	}
+	timings := newStepTimings(opts)
+	rendered, err := applyTransforms(runPipeline(img, watermark, &opts, timings), opts.Transforms)
+	if err != nil {
+		return processedMedia{}, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+	}
+	if opts.Fingerprint != nil {
+		embedFingerprint(rendered, opts.Fingerprint)
+	}
+	start := time.Now()
+	res, mediaType, err := encodeImage(rendered, opts)
+	if err != nil {
+		return processedMedia{}, err
+	}
+	addStepTime(timings, len(timings)-1, start)
+	if opts.PreserveMetadata && exif != nil && mediaType == "image/jpeg" {
+		res = withExif(res, withoutThumbnail(withoutOrientation(exif)))
+	}
+	thumbnail, _, err := encodeImage(fitWithin(rendered, thumbnailSize, thumbnailSize), opts)
+	if err != nil {
+		return processedMedia{}, fmt.Errorf("encode thumbnail: %w", err)
+	}
+	colors, err := extractColors(rendered)
+	if err != nil {
+		log.Printf("error extract colors: %v", err)
+	}
+	size := rendered.Bounds().Size()
+	processed := processedMedia{
+		Data:           res,
+		MediaType:      mediaType,
+		Width:          size.X,
+		Height:         size.Y,
+		Thumbnail:      thumbnail,
+		Colors:         colors,
+		OriginalWidth:  original.X,
+		OriginalHeight: original.Y,
+		OriginalColor:  originalColor,
+		PHash:          &hash,
+		StepTimings:    timings,
+	}
+	if watermark != nil && opts.Placement == nil {
+		processed.WatermarkPosition = cmp.Or(opts.Position, database.WatermarkPositionBottomRight)
+	}
+	return processed, nil
+}
@@ -0,0 +1,25 @@
+package image
+
+import "github.com/google/uuid"
+
+type BulkImagesRequest struct {
+	ImageIDs []uuid.UUID `json:"image_ids" validate:"required,min=1,max=500"`
+}
+
+type BulkImagesResponse struct {
+	ImageIDs []uuid.UUID `json:"image_ids"`
+}
+
+type VerifyImageResponse struct {
+	// Found reports whether the file carries an intact invisible watermark
+	// made by this server.
+	Found bool `json:"found"`
+	// Valid reports whether the embedded batch exists and belongs to the
+	// embedded user.
+	Valid bool `json:"valid"`
+	// Owned reports whether the embedded batch is yours. BatchID and UserID
+	// are only set when it is.
+	Owned   bool       `json:"owned"`
+	BatchID *uuid.UUID `json:"batch_id"`
+	UserID  *uuid.UUID `json:"user_id"`
+}
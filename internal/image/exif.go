@@ -0,0 +1,300 @@
+package image
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"image"
+	"strings"
+	"time"
+)
+
+// exifHeader starts the payload of a JPEG APP1 segment holding EXIF data.
+var exifHeader = []byte("Exif\x00\x00")
+
+const (
+	jpegSOI  = 0xd8
+	jpegAPP1 = 0xe1
+	jpegSOS  = 0xda
+
+	exifStripOffsetsTag     = 0x0111
+	exifOrientationTag      = 0x0112
+	exifStripByteCountsTag  = 0x0117
+	exifThumbnailOffsetTag  = 0x0201
+	exifThumbnailLengthTag  = 0x0202
+	exifIFDPointerTag       = 0x8769
+	exifDateTimeOriginalTag = 0x9003
+
+	// exifDateTimeLayout is how EXIF writes times: the camera's clock, with
+	// no time zone.
+	exifDateTimeLayout = "2006:01:02 15:04:05"
+)
+
+// jpegExif returns the payload of the EXIF APP1 segment of a JPEG, including
+// the "Exif" header, or nil when there is none.
+func jpegExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xff || data[1] != jpegSOI {
+		return nil
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return nil
+		}
+		marker := data[i+1]
+		if marker == jpegSOS {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil
+		}
+		payload := data[i+4 : i+2+length]
+		if marker == jpegAPP1 && bytes.HasPrefix(payload, exifHeader) {
+			return payload
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+// exifOrientationOffset finds the value of the orientation tag in IFD0 and
+// returns its offset in exif along with the byte order, or -1 when missing.
+func exifOrientationOffset(exif []byte) (int, binary.ByteOrder) {
+	tiff := len(exifHeader)
+	if len(exif) < tiff+8 {
+		return -1, nil
+	}
+	var order binary.ByteOrder
+	switch string(exif[tiff : tiff+2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return -1, nil
+	}
+
+	ifd := tiff + int(order.Uint32(exif[tiff+4:]))
+	if ifd < tiff || ifd+2 > len(exif) {
+		return -1, nil
+	}
+	count := int(order.Uint16(exif[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(exif) {
+			return -1, nil
+		}
+		if order.Uint16(exif[entry:]) == exifOrientationTag {
+			// A SHORT value is stored inline at the start of the value field.
+			return entry + 8, order
+		}
+	}
+	return -1, nil
+}
+
+// captureTime returns when an original was taken, from the EXIF of JPEGs,
+// and is unset for other originals.
+func captureTime(data []byte) sql.NullTime {
+	t, ok := exifCaptureTime(jpegExif(data))
+	return sql.NullTime{Time: t, Valid: ok}
+}
+
+// exifCaptureTime returns the DateTimeOriginal of exif, when the photo was
+// taken by the camera's clock, and reports false when it has none.
+func exifCaptureTime(exif []byte) (time.Time, bool) {
+	tiff := len(exifHeader)
+	if len(exif) < tiff+8 {
+		return time.Time{}, false
+	}
+	var order binary.ByteOrder
+	switch string(exif[tiff : tiff+2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}, false
+	}
+
+	pointer := exifIFDEntry(exif, order, tiff+int(order.Uint32(exif[tiff+4:])), exifIFDPointerTag)
+	if pointer < 0 {
+		return time.Time{}, false
+	}
+	entry := exifIFDEntry(exif, order, tiff+int(order.Uint32(exif[pointer+8:])), exifDateTimeOriginalTag)
+	if entry < 0 {
+		return time.Time{}, false
+	}
+	// The 20 byte ASCII value is too long to be inline, so the field holds
+	// its offset.
+	value := tiff + int(order.Uint32(exif[entry+8:]))
+	if value < tiff || value+len(exifDateTimeLayout) > len(exif) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(exifDateTimeLayout, strings.TrimSpace(string(exif[value:value+len(exifDateTimeLayout)])))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// exifIFDEntry returns the offset in exif of the 12 byte entry of tag in the
+// IFD at offset ifd, or -1 when missing.
+func exifIFDEntry(exif []byte, order binary.ByteOrder, ifd int, tag uint16) int {
+	if ifd < len(exifHeader) || ifd+2 > len(exif) {
+		return -1
+	}
+	count := int(order.Uint16(exif[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(exif) {
+			return -1
+		}
+		if order.Uint16(exif[entry:]) == tag {
+			return entry
+		}
+	}
+	return -1
+}
+
+// exifOrientation returns the EXIF orientation (1-8), or 1 when exif has none.
+func exifOrientation(exif []byte) int {
+	offset, order := exifOrientationOffset(exif)
+	if offset < 0 {
+		return 1
+	}
+	o := int(order.Uint16(exif[offset:]))
+	if o < 1 || o > 8 {
+		return 1
+	}
+	return o
+}
+
+// withoutOrientation returns a copy of exif whose orientation is reset to
+// normal, for output that has already been rotated.
+func withoutOrientation(exif []byte) []byte {
+	out := bytes.Clone(exif)
+	if offset, order := exifOrientationOffset(out); offset >= 0 {
+		order.PutUint16(out[offset:], 1)
+	}
+	return out
+}
+
+// withoutThumbnail returns a copy of exif without IFD1, which holds the
+// embedded thumbnail of the original. The thumbnail shows the image as it
+// was shot, before redactions, crops and watermarks, so it must not travel
+// with the output. Its pixel data and directory are zeroed and IFD0 no
+// longer links to it.
+func withoutThumbnail(exif []byte) []byte {
+	out := bytes.Clone(exif)
+	tiff := len(exifHeader)
+	if len(out) < tiff+8 {
+		return out
+	}
+	var order binary.ByteOrder
+	switch string(out[tiff : tiff+2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return out
+	}
+
+	ifd0 := tiff + int(order.Uint32(out[tiff+4:]))
+	if ifd0 < tiff || ifd0+2 > len(out) {
+		return out
+	}
+	next := ifd0 + 2 + int(order.Uint16(out[ifd0:]))*12
+	if next+4 > len(out) {
+		return out
+	}
+	ifd1 := tiff + int(order.Uint32(out[next:]))
+	order.PutUint32(out[next:], 0)
+	if ifd1 == tiff || ifd1+2 > len(out) {
+		return out
+	}
+
+	// The thumbnail is either one JPEG stream or, uncompressed, one strip;
+	// only single-valued offsets are stored inline.
+	wipe := func(offsetTag, lengthTag uint16) {
+		offset, length := exifIFDEntry(out, order, ifd1, offsetTag), exifIFDEntry(out, order, ifd1, lengthTag)
+		if offset < 0 || length < 0 || order.Uint32(out[offset+4:]) != 1 || order.Uint32(out[length+4:]) != 1 {
+			return
+		}
+		start := tiff + int(exifLongOrShort(out[offset:], order))
+		end := start + int(exifLongOrShort(out[length:], order))
+		if start >= tiff && start <= end && end <= len(out) {
+			clear(out[start:end])
+		}
+	}
+	wipe(exifThumbnailOffsetTag, exifThumbnailLengthTag)
+	wipe(exifStripOffsetsTag, exifStripByteCountsTag)
+
+	end := ifd1 + 2 + int(order.Uint16(out[ifd1:]))*12 + 4
+	clear(out[ifd1:min(end, len(out))])
+	return out
+}
+
+// exifLongOrShort reads the inline value of a 12 byte IFD entry holding a
+// single SHORT or LONG.
+func exifLongOrShort(entry []byte, order binary.ByteOrder) uint32 {
+	if order.Uint16(entry[2:]) == 3 {
+		return uint32(order.Uint16(entry[8:]))
+	}
+	return order.Uint32(entry[8:])
+}
+
+// withExif inserts exif as an APP1 segment right after the SOI marker of an
+// encoded JPEG. Payloads too large for one segment are dropped.
+func withExif(jpegData, exif []byte) []byte {
+	if len(exif)+2 > 0xffff || len(jpegData) < 2 {
+		return jpegData
+	}
+	out := make([]byte, 0, len(jpegData)+len(exif)+4)
+	out = append(out, jpegData[:2]...)
+	out = append(out, 0xff, jpegAPP1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(exif)+2))
+	out = append(out, exif...)
+	return append(out, jpegData[2:]...)
+}
+
+// orient rotates and flips img so it displays upright for the given EXIF
+// orientation. Pixels are moved as whole RGBA words rather than through
+// At and Set, which would allocate a color per pixel.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := toRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	// Orientations 5-8 swap width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], row[x*4:x*4+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,170 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const (
+	// maxSourceSize bounds an original fetched from a URL.
+	maxSourceSize = 50 << 20
+	// sourceFetchTimeout bounds fetching one original, body included.
+	sourceFetchTimeout = 2 * time.Minute
+	// maxSourceRedirects caps the redirects followed for one original.
+	maxSourceRedirects = 5
+)
+
+// ErrFetchSource wraps every reason the original of an image created from a
+// URL could not be fetched. Such images are failed rather than retried.
+var ErrFetchSource = errors.New("fetch source")
+
+// sourceClient fetches originals from public addresses only, checked on
+// every connection so redirects and DNS changes cannot point workers at
+// internal services.
+var sourceClient = &http.Client{
+	Timeout: sourceFetchTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: utils.DialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxSourceRedirects {
+			return errors.New("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+// fetchSource downloads an original and returns it with its media type.
+func fetchSource(ctx context.Context, client *http.Client, sourceURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFetchSource, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFetchSource, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%w: unexpected status %d", ErrFetchSource, resp.StatusCode)
+	}
+	return readSource(resp.Body)
+}
+
+// fetchS3Source reads an original imported from an S3 object, named by an
+// s3://bucket/key URL.
+func fetchS3Source(ctx context.Context, client *s3.Client, sourceURL string) ([]byte, string, error) {
+	bucket, key, err := parseS3Source(sourceURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFetchSource, err)
+	}
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFetchSource, err)
+	}
+	defer obj.Body.Close()
+	return readSource(obj.Body)
+}
+
+// parseS3Source splits an s3://bucket/key URL.
+func parseS3Source(sourceURL string) (string, string, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", "", err
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "s3" || u.Host == "" || key == "" {
+		return "", "", fmt.Errorf("invalid s3 source %q", sourceURL)
+	}
+	return u.Host, key, nil
+}
+
+// readSource reads a fetched original and returns it with its media type.
+// Only the still and GIF formats accepted for uploads are returned.
+func readSource(r io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSourceSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFetchSource, err)
+	}
+	if len(data) > maxSourceSize {
+		return nil, "", fmt.Errorf("%w: larger than %d MB", ErrFetchSource, maxSourceSize>>20)
+	}
+	mediaType := http.DetectContentType(data)
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/webp", "image/gif":
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported content %s", ErrFetchSource, mediaType)
+	}
+	return data, mediaType, nil
+}
+
+// storeSource fetches the original of an image created from a URL or an S3
+// object, stores it like an upload and points img at it.
+func storeSource(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, img *database.GetImageByIDRow) error {
+	var data []byte
+	var mediaType string
+	var err error
+	if strings.HasPrefix(img.SourceUrl.String, "s3://") {
+		data, mediaType, err = fetchS3Source(ctx, cfg.S3Client, img.SourceUrl.String)
+	} else {
+		data, mediaType, err = fetchSource(ctx, sourceClient, img.SourceUrl.String)
+	}
+	if err != nil {
+		return err
+	}
+
+	key := "raw/" + utils.GetAssetPath(mediaType)
+	_, err = cfg.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(cfg.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(mediaType),
+	})
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	contentHash := hex.EncodeToString(sum[:])
+	originalURL := utils.GetObjectURL(cfg.S3CfDistribution, key)
+	err = dbQueries.SetImageOriginal(ctx, database.SetImageOriginalParams{
+		Key:         key,
+		OriginalUrl: originalURL,
+		ContentHash: sql.NullString{String: contentHash, Valid: true},
+		ID:          img.ID,
+	})
+	if err != nil {
+		return err
+	}
+	img.Key = key
+	img.OriginalUrl = originalURL
+	img.ContentHash = sql.NullString{String: contentHash, Valid: true}
+	return nil
+}
@@ -0,0 +1,52 @@
+package image
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchSource(t *testing.T) {
+	jpg := sampleJPEG(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			w.Write(jpg)
+		case "/page.html":
+			w.Write([]byte("<html><body>not an image</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	data, mediaType, err := fetchSource(context.Background(), srv.Client(), srv.URL+"/photo.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", mediaType)
+	assert.Equal(t, jpg, data)
+
+	_, _, err = fetchSource(context.Background(), srv.Client(), srv.URL+"/page.html")
+	assert.ErrorIs(t, err, ErrFetchSource)
+	_, _, err = fetchSource(context.Background(), srv.Client(), srv.URL+"/missing.jpg")
+	assert.ErrorIs(t, err, ErrFetchSource)
+
+	// The worker's client refuses to connect to the loopback test server.
+	_, _, err = fetchSource(context.Background(), sourceClient, srv.URL+"/photo.jpg")
+	assert.ErrorIs(t, err, ErrFetchSource)
+}
+
+func TestParseS3Source(t *testing.T) {
+	bucket, key, err := parseS3Source("s3://legacy/2019/spring%20shoot/a%25b.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", bucket)
+	assert.Equal(t, "2019/spring shoot/a%b.jpg", key)
+
+	for _, bad := range []string{"https://legacy/a.jpg", "s3:///a.jpg", "s3://legacy", "s3://legacy/"} {
+		_, _, err := parseS3Source(bad)
+		assert.Error(t, err, bad)
+	}
+}
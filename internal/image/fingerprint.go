@@ -0,0 +1,158 @@
+package image
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"image"
+	"math"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// fingerprintBlock is the side of the pixel blocks that each carry one
+	// bit, matching the JPEG block size so the payload lives in the DC
+	// coefficients that lossy encoding keeps best.
+	fingerprintBlock = 8
+	// fingerprintStep is the quantization step of the block brightness.
+	// Blocks shift by at most half a step, which is not visible, and bits
+	// survive brightness errors up to a quarter step, enough for JPEG at the
+	// default quality.
+	fingerprintStep = 12
+	// fingerprintMACSize is how much of the HMAC-SHA256 of the IDs the
+	// payload keeps.
+	fingerprintMACSize = 8
+	// fingerprintSize is the payload size: batch ID, user ID and MAC.
+	fingerprintSize = 16 + 16 + fingerprintMACSize
+)
+
+// fingerprintPayload encodes the IDs embedded by the invisible watermark,
+// authenticated with key so that marks cannot be forged for other batches.
+func fingerprintPayload(key []byte, batchID, userID uuid.UUID) []byte {
+	payload := make([]byte, 0, fingerprintSize)
+	payload = append(payload, batchID[:]...)
+	payload = append(payload, userID[:]...)
+	return append(payload, fingerprintMAC(key, payload)...)
+}
+
+// parseFingerprint reverses fingerprintPayload, reporting false when the MAC
+// does not match.
+func parseFingerprint(key []byte, payload []byte) (batchID, userID uuid.UUID, ok bool) {
+	if len(payload) != fingerprintSize {
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !hmac.Equal(fingerprintMAC(key, payload[:32]), payload[32:]) {
+		return uuid.Nil, uuid.Nil, false
+	}
+	copy(batchID[:], payload[:16])
+	copy(userID[:], payload[16:32])
+	return batchID, userID, true
+}
+
+func fingerprintMAC(key, ids []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(ids)
+	return mac.Sum(nil)[:fingerprintMACSize]
+}
+
+// embedFingerprint hides payload in img by quantization index modulation of
+// the brightness of 8x8 blocks: each block's mean luma is moved onto one of
+// two interleaved lattices depending on its bit. The payload repeats over all
+// blocks so extraction can take a majority vote, which makes it survive
+// clipped blocks and compression noise. Images with fewer blocks than payload
+// bits are left unchanged.
+func embedFingerprint(img *image.RGBA, payload []byte) {
+	bits := len(payload) * 8
+	blocks := fingerprintBlocks(img.Bounds())
+	if len(blocks) < bits {
+		return
+	}
+	for i, r := range blocks {
+		bit := payload[i%bits/8] >> (7 - i%8) & 1
+		mean := blockLuma(img, r)
+		offset := float64(bit) * fingerprintStep / 2
+		target := math.Round((mean-offset)/fingerprintStep)*fingerprintStep + offset
+		// Stay within the luma range so black and white blocks keep their bit.
+		if target < 0 {
+			target += fingerprintStep
+		} else if target > 255 {
+			target -= fingerprintStep
+		}
+		shiftBlock(img, r, int(math.Round(target-mean)))
+	}
+}
+
+// extractFingerprint reads a payload of fingerprintSize bytes embedded by
+// embedFingerprint. It reports false when img is too small to hold one.
+func extractFingerprint(img image.Image) ([]byte, bool) {
+	bits := fingerprintSize * 8
+	blocks := fingerprintBlocks(img.Bounds())
+	if len(blocks) < bits {
+		return nil, false
+	}
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, rgba.Bounds().Min, draw.Src)
+	}
+	votes := make([]int, bits)
+	for i, r := range blocks {
+		rem := math.Mod(blockLuma(rgba, r), fingerprintStep)
+		dist0 := min(rem, fingerprintStep-rem)
+		dist1 := math.Abs(rem - fingerprintStep/2)
+		if dist1 < dist0 {
+			votes[i%bits]++
+		} else {
+			votes[i%bits]--
+		}
+	}
+	payload := make([]byte, fingerprintSize)
+	for i, v := range votes {
+		if v > 0 {
+			payload[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return payload, true
+}
+
+// fingerprintBlocks lists the whole 8x8 blocks of bounds in raster order.
+func fingerprintBlocks(bounds image.Rectangle) []image.Rectangle {
+	var blocks []image.Rectangle
+	for y := bounds.Min.Y; y+fingerprintBlock <= bounds.Max.Y; y += fingerprintBlock {
+		for x := bounds.Min.X; x+fingerprintBlock <= bounds.Max.X; x += fingerprintBlock {
+			blocks = append(blocks, image.Rect(x, y, x+fingerprintBlock, y+fingerprintBlock))
+		}
+	}
+	return blocks
+}
+
+// blockLuma is the mean luma of the pixels of img within r.
+func blockLuma(img *image.RGBA, r image.Rectangle) float64 {
+	var sum float64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			sum += 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+		}
+	}
+	return sum / float64(r.Dx()*r.Dy())
+}
+
+// shiftBlock adds delta to every color channel within r, which shifts luma
+// by delta. Channels are clamped to the pixel's (premultiplied) alpha.
+func shiftBlock(img *image.RGBA, r image.Rectangle, delta int) {
+	if delta == 0 {
+		return
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			a := int(c.A)
+			c.R = uint8(min(max(int(c.R)+delta, 0), a))
+			c.G = uint8(min(max(int(c.G)+delta, 0), a))
+			c.B = uint8(min(max(int(c.B)+delta, 0), a))
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// maxCachedFonts bounds how many parsed uploaded fonts the worker keeps.
+const maxCachedFonts = 64
+
+var errInvalidFont = errors.New("invalid font")
+
+// fontCache keeps parsed fonts by object key. Uploaded fonts are never
+// rewritten under the same key, so entries do not go stale.
+type fontCache struct {
+	mu      sync.Mutex
+	fonts   map[string]*opentype.Font
+	regular *opentype.Font
+}
+
+var fonts = &fontCache{fonts: make(map[string]*opentype.Font)}
+
+// defaultFont returns the parsed Go Regular fallback font.
+func (fc *fontCache) defaultFont() (*opentype.Font, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.regular == nil {
+		f, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			return nil, err
+		}
+		fc.regular = f
+	}
+	return fc.regular, nil
+}
+
+// get returns the font stored under key, downloading and parsing it on a
+// miss. Unparseable fonts are reported as errInvalidFont.
+func (fc *fontCache) get(ctx context.Context, cfg *utils.Config, key string) (*opentype.Font, error) {
+	fc.mu.Lock()
+	f, ok := fc.fonts[key]
+	fc.mu.Unlock()
+	if ok {
+		return f, nil
+	}
+
+	obj, err := cfg.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+
+	data, err := io.ReadAll(obj.Body)
+	if err != nil {
+		return nil, err
+	}
+	f, err = opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidFont, err)
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if len(fc.fonts) >= maxCachedFonts {
+		for k := range fc.fonts {
+			delete(fc.fonts, k)
+			break
+		}
+	}
+	fc.fonts[key] = f
+	return f, nil
+}
+
+// forBatch returns the batch's uploaded font, or nil to use the default.
+func (fc *fontCache) forBatch(ctx context.Context, cfg *utils.Config, key sql.NullString) (*opentype.Font, error) {
+	if !key.Valid {
+		return nil, nil
+	}
+	return fc.get(ctx, cfg, key.String)
+}
+
+// WarmUp prepares the worker before it starts consuming: it parses the
+// default font, preloads the fonts of batches with queued images, newest
+// first and no more than the cache holds, and renders one watermark through
+// each encoder, so the first messages do not pay for that setup.
+func WarmUp(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config) error {
+	regular, err := fonts.defaultFont()
+	if err != nil {
+		return fmt.Errorf("parse default font: %w", err)
+	}
+
+	keys, err := dbQueries.GetQueuedWatermarkFontKeys(ctx, maxCachedFonts)
+	if err != nil {
+		return fmt.Errorf("get queued fonts: %w", err)
+	}
+	for _, key := range keys {
+		// A font that fails here fails its images later; it must not block startup.
+		if _, err := fonts.get(ctx, cfg, key); err != nil {
+			log.Printf("error preload font %s: %v", key, err)
+		}
+	}
+
+	watermark, err := renderTextWatermark("warm-up", regular)
+	if err != nil {
+		return fmt.Errorf("render warm-up watermark: %w", err)
+	}
+	for _, format := range []database.OutputFormat{database.OutputFormatJpeg, database.OutputFormatPng, database.OutputFormatWebp} {
+		if _, _, err := encodeImage(watermark, renderOptions{Format: format}); err != nil {
+			return fmt.Errorf("warm up %s encoder: %w", format, err)
+		}
+	}
+	return nil
+}
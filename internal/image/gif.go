@@ -0,0 +1,159 @@
+package image
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"log"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+	"golang.org/x/image/draw"
+)
+
+// maxAnimationPixels bounds the pixels of all frames of an animated GIF
+// together, since each frame is composited at full size.
+const maxAnimationPixels = 4 * maxImagePixels
+
+// processGIF watermarks every frame of a GIF and re-encodes it as an animated
+// GIF, keeping the frame delays and loop count. Frames are composited onto a
+// full canvas first, so watermarks are not cut by frames that only update part
+// of the image, and are written back in their original palette. The output
+// format of the batch does not apply; the thumbnail, placeholders and the auto
+// position are taken from the first frame.
+func processGIF(_ context.Context, data []byte, watermark image.Image, opts renderOptions) (processedMedia, error) {
+	cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processedMedia{}, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return processedMedia{}, fmt.Errorf("%w: invalid image dimensions %dx%d", ErrInvalidMedia, cfg.Width, cfg.Height)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return processedMedia{}, fmt.Errorf("%w: %v", ErrInvalidMedia, ErrImageTooLarge)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return processedMedia{}, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+	}
+	if len(anim.Image)*cfg.Width*cfg.Height > maxAnimationPixels {
+		return processedMedia{}, fmt.Errorf("%w: %v", ErrInvalidMedia, ErrImageTooLarge)
+	}
+
+	out := &gif.GIF{LoopCount: anim.LoopCount}
+	canvas := image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+	var first image.Image
+	timings := newStepTimings(opts)
+	for i, frame := range anim.Image {
+		var disposal byte
+		if i < len(anim.Disposal) {
+			disposal = anim.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(canvas.Bounds())
+			copy(previous.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		redacted, err := redactImage(canvas, opts.Redactions)
+		if err != nil {
+			return processedMedia{}, err
+		}
+		cropped, err := cropImage(redacted, opts.Crop)
+		if err != nil {
+			return processedMedia{}, err
+		}
+		rendered, err := applyTransforms(runPipeline(cropped, watermark, &opts, timings), opts.Transforms)
+		if err != nil {
+			return processedMedia{}, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+		}
+		if i == 0 {
+			first = rendered
+		}
+
+		out.Image = append(out.Image, toPaletted(rendered, framePalette(anim, frame)))
+		out.Delay = append(out.Delay, anim.Delay[i])
+		// Every output frame is a full composite, so clear it before the next.
+		out.Disposal = append(out.Disposal, gif.DisposalBackground)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	start := time.Now()
+	var res bytes.Buffer
+	if err := gif.EncodeAll(&res, out); err != nil {
+		return processedMedia{}, err
+	}
+	addStepTime(timings, len(timings)-1, start)
+	var thumbnail bytes.Buffer
+	if err := gif.Encode(&thumbnail, fitWithin(first, thumbnailSize, thumbnailSize), nil); err != nil {
+		return processedMedia{}, fmt.Errorf("encode thumbnail: %w", err)
+	}
+	colors, err := extractColors(first)
+	if err != nil {
+		log.Printf("error extract colors: %v", err)
+	}
+	size := first.Bounds().Size()
+	hash := perceptualHash(anim.Image[0])
+	processed := processedMedia{
+		Data:           res.Bytes(),
+		MediaType:      "image/gif",
+		Width:          size.X,
+		Height:         size.Y,
+		Thumbnail:      thumbnail.Bytes(),
+		Colors:         colors,
+		OriginalWidth:  cfg.Width,
+		OriginalHeight: cfg.Height,
+		OriginalColor:  dominantColor(anim.Image[0]),
+		PHash:          &hash,
+		StepTimings:    timings,
+	}
+	if watermark != nil && opts.Placement == nil {
+		processed.WatermarkPosition = cmp.Or(opts.Position, database.WatermarkPositionBottomRight)
+	}
+	return processed, nil
+}
+
+// framePalette is the palette frame is written back in: its local color
+// table, else the global one of the GIF, else the Plan 9 palette, so frames
+// that come without colors of their own still map onto something.
+func framePalette(anim *gif.GIF, frame *image.Paletted) color.Palette {
+	if len(frame.Palette) > 0 {
+		return frame.Palette
+	}
+	if global, ok := anim.Config.ColorModel.(color.Palette); ok && len(global) > 0 {
+		return global
+	}
+	return palette.Plan9
+}
+
+// toPaletted maps img onto p without dithering. Most pixels of a GIF frame
+// are already palette colors, so lookups are cached.
+func toPaletted(img image.Image, p color.Palette) *image.Paletted {
+	b := img.Bounds()
+	dst := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), p)
+	cache := make(map[color.Color]uint8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.At(x, y)
+			idx, ok := cache[c]
+			if !ok {
+				idx = uint8(p.Index(c))
+				cache[c] = idx
+			}
+			dst.SetColorIndex(x-b.Min.X, y-b.Min.Y, idx)
+		}
+	}
+	return dst
+}
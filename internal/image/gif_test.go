@@ -0,0 +1,33 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFramePalette(t *testing.T) {
+	local := color.Palette{color.Black, color.White}
+	global := color.Palette{color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	rect := image.Rect(0, 0, 2, 2)
+
+	tests := []struct {
+		name  string
+		anim  *gif.GIF
+		frame *image.Paletted
+		want  color.Palette
+	}{
+		{"local color table", &gif.GIF{Config: image.Config{ColorModel: global}}, image.NewPaletted(rect, local), local},
+		{"global color table", &gif.GIF{Config: image.Config{ColorModel: global}}, image.NewPaletted(rect, nil), global},
+		{"no color table", &gif.GIF{}, image.NewPaletted(rect, nil), palette.Plan9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, framePalette(tt.anim, tt.frame))
+		})
+	}
+}
@@ -1,11 +1,16 @@
 package image
 
 import (
+	"database/sql"
 	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/batch"
 	"github.com/rickyroynardson/image-go/internal/database"
 	"github.com/rickyroynardson/image-go/internal/utils"
 )
@@ -24,6 +29,155 @@ func NewHandler(validator *validator.Validate, dbQueries *database.Queries, conf
 	}
 }
 
+// Search godoc
+// @Summary Search images
+// @Description Search the authenticated user's images across all batches
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param filename query string false "Part of the original filename (case insensitive, up to 255 characters)"
+// @Param status query string false "Image status" Enums(pending, processing, completed, failed, cancelled, expired)
+// @Param from query string false "Uploaded at or after (RFC3339)"
+// @Param to query string false "Uploaded at or before (RFC3339)"
+// @Param batch query string false "Batch ID"
+// @Param external_id query string false "External ID given to the image on upload (up to 255 characters)"
+// @Param captured_from query string false "Taken at or after, by the camera's clock (RFC3339, the offset is ignored)"
+// @Param captured_to query string false "Taken at or before, by the camera's clock (RFC3339, the offset is ignored)"
+// @Param sort query string false "Sort order, descending with a leading -; images without a capture time come last" Enums(created_at, -created_at, captured_at, -captured_at) default(-created_at)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} utils.SuccessResponse{data=[]batch.ImageResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /images [get]
+func (h *ImageHandler) Search(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	page, limit, err := utils.GetPagination(c)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	params := database.SearchUserImagesParams{
+		UserID:     userID,
+		Sort:       "-created_at",
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	}
+	if filename := c.QueryParam("filename"); filename != "" {
+		if utf8.RuneCountInString(filename) > 255 {
+			return utils.RespondError(c, http.StatusBadRequest, "filename must be at most 255 characters")
+		}
+		params.Filename = sql.NullString{String: utils.EscapeLike(filename), Valid: true}
+	}
+	if status := c.QueryParam("status"); status != "" {
+		imageStatus := database.ImageStatus(status)
+		if !imageStatus.Valid() {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid status")
+		}
+		params.Status = database.NullImageStatus{ImageStatus: imageStatus, Valid: true}
+	}
+	if from := c.QueryParam("from"); from != "" {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid from, must be RFC3339")
+		}
+		params.FromTime = sql.NullTime{Time: fromTime.UTC(), Valid: true}
+	}
+	if to := c.QueryParam("to"); to != "" {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid to, must be RFC3339")
+		}
+		params.ToTime = sql.NullTime{Time: toTime.UTC(), Valid: true}
+	}
+	if batchID := c.QueryParam("batch"); batchID != "" {
+		batchUUID, err := uuid.Parse(batchID)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid batch ID")
+		}
+		params.BatchID = uuid.NullUUID{UUID: batchUUID, Valid: true}
+	}
+	if externalID := c.QueryParam("external_id"); externalID != "" {
+		if utf8.RuneCountInString(externalID) > 255 {
+			return utils.RespondError(c, http.StatusBadRequest, "external_id must be at most 255 characters")
+		}
+		params.ExternalID = sql.NullString{String: externalID, Valid: true}
+	}
+	if from := c.QueryParam("captured_from"); from != "" {
+		params.CapturedFrom, err = batch.ParseCaptureTime(from)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid captured_from, must be RFC3339")
+		}
+	}
+	if to := c.QueryParam("captured_to"); to != "" {
+		params.CapturedTo, err = batch.ParseCaptureTime(to)
+		if err != nil {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid captured_to, must be RFC3339")
+		}
+	}
+	if sort := c.QueryParam("sort"); sort != "" {
+		if !slices.Contains(batch.ImageSorts, sort) {
+			return utils.RespondError(c, http.StatusBadRequest, "invalid sort")
+		}
+		params.Sort = sort
+	}
+
+	images, err := h.dbQueries.SearchUserImages(c.Request().Context(), params)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	total, err := h.dbQueries.CountSearchUserImages(c.Request().Context(), database.CountSearchUserImagesParams{
+		UserID:       params.UserID,
+		Filename:     params.Filename,
+		Status:       params.Status,
+		FromTime:     params.FromTime,
+		ToTime:       params.ToTime,
+		BatchID:      params.BatchID,
+		ExternalID:   params.ExternalID,
+		CapturedFrom: params.CapturedFrom,
+		CapturedTo:   params.CapturedTo,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	imagesRes := make([]batch.ImageResponse, len(images))
+	for i, img := range images {
+		imagesRes[i] = batch.NewImageResponse(img)
+	}
+
+	return utils.RespondPaginated(c, http.StatusOK, "images retrieved successfully", imagesRes, utils.NewPaginationMeta(page, limit, int(total)))
+}
+
+// GetByID godoc
+// @Summary Get an image by ID
+// @Description Retrieve one of the authenticated user's images, with its status, why it failed and how many times a worker started processing it
+// @Tags images
+// @Param imageID path string true "Image ID"
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=batch.ImageResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /images/{imageID} [get]
+func (h *ImageHandler) GetByID(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	imageUUID := utils.ParamUUID(c, "imageID")
+
+	img, err := h.dbQueries.GetUserImageByID(c.Request().Context(), database.GetUserImageByIDParams{
+		ID:     imageUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "image")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "image retrieved successfully", batch.NewImageResponse(img.Image))
+}
+
 // DeleteByID godoc
 // @Summary Delete an image by ID
 // @Description Delete an image by its ID for the authenticated user
@@ -37,22 +191,13 @@ func NewHandler(validator *validator.Validate, dbQueries *database.Queries, conf
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /images/{imageID} [delete]
 func (h *ImageHandler) DeleteByID(c echo.Context) error {
-	imageID := c.Param("imageID")
 	userID := c.Get("userID").(uuid.UUID)
+	imageUUID := utils.ParamUUID(c, "imageID")
 
-	imageUUID, err := uuid.Parse(imageID)
-	if err != nil {
-		return utils.RespondError(c, http.StatusBadRequest, "invalid image ID")
-	}
-
-	batches, err := h.dbQueries.GetAllUserBatches(c.Request().Context(), userID)
+	batchIDs, err := h.dbQueries.GetUserBatchIDs(c.Request().Context(), userID)
 	if err != nil {
 		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
 	}
-	batchIDs := make([]uuid.UUID, len(batches))
-	for i, b := range batches {
-		batchIDs[i] = b.ID
-	}
 
 	err = h.dbQueries.DeleteImageByID(c.Request().Context(), database.DeleteImageByIDParams{
 		ID:      imageUUID,
@@ -0,0 +1,510 @@
+package image
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+// The standard library only writes baseline 4:2:0 JPEGs, which smears fine
+// colored detail and cannot be shown progressively while loading. This
+// encoder adds progressive output and 4:4:4 and 4:2:2 subsampling. It uses
+// the same quantization and Huffman tables as image/jpeg so quality values
+// mean the same, and progressive files are split by spectral selection only.
+
+// jpegOptions tune encodeJPEG.
+type jpegOptions struct {
+	Quality     int
+	Progressive bool
+	// Subsampling defaults to 4:2:0.
+	Subsampling database.ChromaSubsampling
+}
+
+// jpegZigzag maps zig-zag positions to natural (row-major) positions.
+var jpegZigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// jpegQuant are the luminance and chrominance tables of section K.1 of the
+// spec in zig-zag order, before scaling for quality.
+var jpegQuant = [2][64]int{
+	{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	},
+	{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	},
+}
+
+// jpegHuffman are the luminance DC, luminance AC, chrominance DC and
+// chrominance AC tables of section K.3 of the spec, as code counts per length
+// followed by the symbols.
+var jpegHuffman = [4]struct {
+	counts  [16]byte
+	symbols []byte
+}{
+	{
+		[16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125},
+		[]byte{
+			0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+			0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+			0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+			0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+			0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+			0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+			0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+			0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+			0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+			0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+			0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+			0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+			0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+			0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+			0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+			0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+			0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+			0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+			0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+			0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+	{
+		[16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119},
+		[]byte{
+			0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+			0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+			0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+			0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+			0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+			0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+			0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+			0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+			0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+			0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+			0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+			0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+			0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+			0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+			0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+			0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+			0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+			0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+}
+
+// jpegCodes holds the code of every symbol of jpegHuffman, with the code
+// length in the top 8 bits.
+var jpegCodes [4][256]uint32
+
+// jpegDCTCos[u][x] is the 1D DCT basis scaled so that applying it to rows and
+// columns gives the 2D DCT of the spec.
+var jpegDCTCos [8][8]float64
+
+func init() {
+	for t, spec := range jpegHuffman {
+		code, k := uint32(0), 0
+		for length, count := range spec.counts {
+			for range count {
+				jpegCodes[t][spec.symbols[k]] = uint32(length+1)<<24 | code
+				code++
+				k++
+			}
+			code <<= 1
+		}
+	}
+	for u := range 8 {
+		c := 0.5
+		if u == 0 {
+			c = 0.5 / math.Sqrt2
+		}
+		for x := range 8 {
+			jpegDCTCos[u][x] = c * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+}
+
+// jpegComponent is one color channel, as quantized DCT coefficients in
+// zig-zag order for each block of the MCU-aligned plane.
+type jpegComponent struct {
+	id     byte
+	h, v   int
+	table  int
+	blocks [][64]int16
+	// stride is the number of blocks per row. Non-interleaved scans only
+	// cover the usedX by usedY blocks that overlap the image.
+	stride       int
+	usedX, usedY int
+}
+
+// jpegScan is one scan of a progressive file: a band of coefficients of some
+// components.
+type jpegScan struct {
+	components []int
+	start, end int
+}
+
+// encodeJPEG writes img to w as a JPEG. Baseline 4:2:0 output, the default,
+// is left to image/jpeg, which is much faster. Otherwise baseline files are
+// transformed and written one MCU row at a time, and progressive files keep
+// only the quantized coefficients, two bytes per sample, between scans.
+func encodeJPEG(w io.Writer, img image.Image, opts jpegOptions) error {
+	quality := min(max(opts.Quality, 1), 100)
+	if !opts.Progressive && (opts.Subsampling == "" || opts.Subsampling == database.ChromaSubsampling420) {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width >= 1<<16 || height >= 1<<16 {
+		return errors.New("image is too large to encode as JPEG")
+	}
+	rgba := toRGBA(img)
+
+	scale := 200 - quality*2
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+	var quant [2][64]int32
+	for t := range quant {
+		for i, q := range jpegQuant[t] {
+			quant[t][i] = int32(min(max((q*scale+50)/100, 1), 255))
+		}
+	}
+
+	lumaH, lumaV := 2, 2
+	switch opts.Subsampling {
+	case database.ChromaSubsampling444:
+		lumaH, lumaV = 1, 1
+	case database.ChromaSubsampling422:
+		lumaH, lumaV = 2, 1
+	}
+	mcusX := (width + 8*lumaH - 1) / (8 * lumaH)
+	mcusY := (height + 8*lumaV - 1) / (8 * lumaV)
+	// Progressive scans go over the whole image several times, so they need
+	// every block; a baseline scan only the MCU row being written.
+	rowsKept := 1
+	if opts.Progressive {
+		rowsKept = mcusY
+	}
+	components := []*jpegComponent{
+		{id: 1, h: lumaH, v: lumaV, table: 0},
+		{id: 2, h: 1, v: 1, table: 1},
+		{id: 3, h: 1, v: 1, table: 1},
+	}
+	for _, comp := range components {
+		comp.stride = mcusX * comp.h
+		comp.usedX = ((width*comp.h+lumaH-1)/lumaH + 7) / 8
+		comp.usedY = ((height*comp.v+lumaV-1)/lumaV + 7) / 8
+		comp.blocks = make([][64]int16, comp.stride*comp.v*rowsKept)
+	}
+
+	planeWidth, rowHeight := mcusX*8*lumaH, 8*lumaV
+	var planes [3][]float32
+	for i := range planes {
+		planes[i] = make([]float32, planeWidth*rowHeight)
+	}
+	// transformRow fills the blocks of MCU row my from the image.
+	transformRow := func(my int) {
+		jpegPlanes(rgba, my*rowHeight, planeWidth, &planes)
+		for i, comp := range components {
+			plane, stride := planes[i], planeWidth
+			if comp.h != lumaH || comp.v != lumaV {
+				plane = jpegDownsample(plane, planeWidth, lumaH/comp.h, lumaV/comp.v)
+				stride /= lumaH / comp.h
+			}
+			first := my % rowsKept * comp.v * comp.stride
+			for b := range comp.v * comp.stride {
+				x, y := b%comp.stride*8, b/comp.stride*8
+				jpegFDCT(plane[y*stride+x:], stride, &quant[comp.table], &comp.blocks[first+b])
+			}
+		}
+	}
+
+	e := &jpegEncoder{w: bufio.NewWriter(w)}
+	e.w.Write([]byte{0xff, 0xd8})
+	e.writeDQT(&quant)
+	e.writeSOF(width, height, components, opts.Progressive)
+	e.writeDHT()
+	prevDC := make([]int32, len(components))
+	if !opts.Progressive {
+		e.writeSOS(components, []int{0, 1, 2}, 0, 63)
+		for my := range mcusY {
+			transformRow(my)
+			e.writeMCURow(components, 0, mcusX, 63, prevDC)
+		}
+		e.flushBits()
+	} else {
+		for my := range mcusY {
+			transformRow(my)
+		}
+		e.writeSOS(components, []int{0, 1, 2}, 0, 0)
+		for my := range mcusY {
+			e.writeMCURow(components, my, mcusX, 0, prevDC)
+		}
+		e.flushBits()
+		// A low-frequency luma band first gives a usable preview early.
+		for _, scan := range []jpegScan{
+			{components: []int{0}, start: 1, end: 5},
+			{components: []int{1}, start: 1, end: 63},
+			{components: []int{2}, start: 1, end: 63},
+			{components: []int{0}, start: 6, end: 63},
+		} {
+			e.writeSOS(components, scan.components, scan.start, scan.end)
+			e.writeBand(components[scan.components[0]], scan.start, scan.end)
+		}
+	}
+	e.w.Write([]byte{0xff, 0xd9})
+	return e.w.Flush()
+}
+
+// jpegPlanes converts the rows of img from y0 on into the Y, Cb and Cr
+// planes, which are width samples wide, repeating the last row and column to
+// fill the padding.
+func jpegPlanes(img *image.RGBA, y0, width int, planes *[3][]float32) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	rows := len(planes[0]) / width
+	for y := range rows {
+		row := img.Pix[min(y0+y, h-1)*img.Stride:]
+		for x := range width {
+			p := row[min(x, w-1)*4:]
+			yy, cb, cr := color.RGBToYCbCr(p[0], p[1], p[2])
+			planes[0][y*width+x] = float32(yy)
+			planes[1][y*width+x] = float32(cb)
+			planes[2][y*width+x] = float32(cr)
+		}
+	}
+}
+
+// jpegDownsample averages fx by fy boxes of a plane of the given width.
+func jpegDownsample(plane []float32, width, fx, fy int) []float32 {
+	height := len(plane) / width
+	outWidth, outHeight := width/fx, height/fy
+	out := make([]float32, outWidth*outHeight)
+	for y := range outHeight {
+		for x := range outWidth {
+			var sum float32
+			for dy := range fy {
+				for dx := range fx {
+					sum += plane[(y*fy+dy)*width+x*fx+dx]
+				}
+			}
+			out[y*outWidth+x] = sum / float32(fx*fy)
+		}
+	}
+	return out
+}
+
+// jpegFDCT transforms the 8x8 block at the start of samples, whose rows are
+// stride apart, and quantizes it into dst in zig-zag order.
+func jpegFDCT(samples []float32, stride int, quant *[64]int32, dst *[64]int16) {
+	var rows [64]float64
+	for y := range 8 {
+		for u := range 8 {
+			var sum float64
+			for x := range 8 {
+				sum += (float64(samples[y*stride+x]) - 128) * jpegDCTCos[u][x]
+			}
+			rows[y*8+u] = sum
+		}
+	}
+	for zz, natural := range jpegZigzag {
+		u, v := natural%8, natural/8
+		var sum float64
+		for y := range 8 {
+			sum += rows[y*8+u] * jpegDCTCos[v][y]
+		}
+		coef := int32(math.Round(sum / float64(quant[zz])))
+		// 8-bit samples keep coefficients within 11 bits.
+		dst[zz] = int16(min(max(coef, -1023), 1023))
+	}
+}
+
+// jpegEncoder writes markers and entropy-coded data. Write errors stick in
+// the bufio.Writer and are returned by its Flush.
+type jpegEncoder struct {
+	w     *bufio.Writer
+	acc   uint32
+	nBits uint
+}
+
+func (e *jpegEncoder) writeMarker(marker byte, length int) {
+	e.w.Write([]byte{0xff, marker, byte(length >> 8), byte(length)})
+}
+
+func (e *jpegEncoder) writeDQT(quant *[2][64]int32) {
+	e.writeMarker(0xdb, 2+2*65)
+	for t := range quant {
+		e.w.WriteByte(byte(t))
+		for _, q := range quant[t] {
+			e.w.WriteByte(byte(q))
+		}
+	}
+}
+
+func (e *jpegEncoder) writeSOF(width, height int, components []*jpegComponent, progressive bool) {
+	marker := byte(0xc0)
+	if progressive {
+		marker = 0xc2
+	}
+	e.writeMarker(marker, 8+3*len(components))
+	e.w.Write([]byte{8, byte(height >> 8), byte(height), byte(width >> 8), byte(width), byte(len(components))})
+	for _, comp := range components {
+		e.w.Write([]byte{comp.id, byte(comp.h<<4 | comp.v), byte(comp.table)})
+	}
+}
+
+func (e *jpegEncoder) writeDHT() {
+	length := 2
+	for _, spec := range jpegHuffman {
+		length += 1 + 16 + len(spec.symbols)
+	}
+	e.writeMarker(0xc4, length)
+	for t, spec := range jpegHuffman {
+		// Tables 0 and 2 are DC (class 0), 1 and 3 are AC (class 1).
+		e.w.WriteByte(byte(t%2<<4 | t/2))
+		e.w.Write(spec.counts[:])
+		e.w.Write(spec.symbols)
+	}
+}
+
+func (e *jpegEncoder) writeSOS(components []*jpegComponent, scan []int, start, end int) {
+	e.writeMarker(0xda, 6+2*len(scan))
+	e.w.WriteByte(byte(len(scan)))
+	for _, i := range scan {
+		e.w.Write([]byte{components[i].id, byte(components[i].table<<4 | components[i].table)})
+	}
+	e.w.Write([]byte{byte(start), byte(end), 0})
+}
+
+// writeMCURow writes the blocks of MCU row my of all components, MCU by MCU,
+// with the coefficients up to end: 63 for a baseline scan, 0 for a
+// progressive DC scan. prevDC carries the DC predictions from row to row.
+func (e *jpegEncoder) writeMCURow(components []*jpegComponent, my, mcusX, end int, prevDC []int32) {
+	for mx := range mcusX {
+		for i, comp := range components {
+			for by := range comp.v {
+				for bx := range comp.h {
+					block := &comp.blocks[(my*comp.v+by)*comp.stride+mx*comp.h+bx]
+					e.emitValue(2*comp.table, 0, int32(block[0])-prevDC[i])
+					prevDC[i] = int32(block[0])
+					e.emitAC(2*comp.table+1, block, 1, end)
+				}
+			}
+		}
+	}
+}
+
+// writeBand writes coefficients start to end of the blocks of comp that
+// overlap the image, for a progressive AC scan.
+func (e *jpegEncoder) writeBand(comp *jpegComponent, start, end int) {
+	for y := range comp.usedY {
+		for x := range comp.usedX {
+			e.emitAC(2*comp.table+1, &comp.blocks[y*comp.stride+x], start, end)
+		}
+	}
+	e.flushBits()
+}
+
+// emitAC run-length codes coefficients start to end of block.
+func (e *jpegEncoder) emitAC(table int, block *[64]int16, start, end int) {
+	run := int32(0)
+	for zz := start; zz <= end; zz++ {
+		if block[zz] == 0 {
+			run++
+			continue
+		}
+		for run > 15 {
+			e.emitSymbol(table, 0xf0)
+			run -= 16
+		}
+		e.emitValue(table, run, int32(block[zz]))
+		run = 0
+	}
+	if run > 0 {
+		e.emitSymbol(table, 0x00)
+	}
+}
+
+// emitValue writes the symbol for a run of zeros followed by value, then the
+// bits of value.
+func (e *jpegEncoder) emitValue(table int, run, value int32) {
+	magnitude := value
+	if value < 0 {
+		magnitude = -value
+		value--
+	}
+	size := uint(bits.Len32(uint32(magnitude)))
+	e.emitSymbol(table, byte(run<<4)|byte(size))
+	if size > 0 {
+		e.emitBits(uint32(value)&(1<<size-1), size)
+	}
+}
+
+func (e *jpegEncoder) emitSymbol(table int, symbol byte) {
+	code := jpegCodes[table][symbol]
+	e.emitBits(code&(1<<24-1), uint(code>>24))
+}
+
+// emitBits writes the low n bits of b, n at most 16, stuffing a zero byte
+// after every 0xff.
+func (e *jpegEncoder) emitBits(b uint32, n uint) {
+	e.acc = e.acc<<n | b
+	e.nBits += n
+	for e.nBits >= 8 {
+		c := byte(e.acc >> (e.nBits - 8))
+		e.w.WriteByte(c)
+		if c == 0xff {
+			e.w.WriteByte(0x00)
+		}
+		e.nBits -= 8
+	}
+	e.acc &= 1<<e.nBits - 1
+}
+
+// flushBits pads the last byte of a scan with ones.
+func (e *jpegEncoder) flushBits() {
+	if e.nBits > 0 {
+		e.emitBits(1<<(8-e.nBits)-1, 8-e.nBits)
+	}
+}
@@ -0,0 +1,134 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/webhook"
+)
+
+// errTaskRecorded means another delivery of the task already recorded its
+// outcome, so this one must not change anything.
+var errTaskRecorded = errors.New("task outcome already recorded")
+
+// taskOptionsHash identifies what a task asked for, so a replayed message
+// that reuses a task ID with different options can be told apart.
+func taskOptionsHash(m batch.ImageTask) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%d", m.ImageID, m.OutputFormat, m.OutputQuality))
+	return hex.EncodeToString(sum[:])
+}
+
+// taskRecorded reports whether the processed_tasks ledger already holds the
+// outcome of m, whose image has the given status. Tasks without an ID predate
+// the ledger and never are. A failure only stands while the image is not
+// pending: once it is set back to pending, for a retry or before a task is
+// replayed from a dead-letter queue, the task runs again.
+func taskRecorded(ctx context.Context, dbQueries *database.Queries, m batch.ImageTask, status database.ImageStatus) (bool, error) {
+	if m.TaskID == uuid.Nil {
+		return false, nil
+	}
+	recorded, err := dbQueries.GetProcessedTask(ctx, m.TaskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if recorded.Outcome == database.TaskOutcomeFailed && status == database.ImageStatusPending {
+		return false, nil
+	}
+	if recorded.ImageID != m.ImageID || recorded.OptionsHash != taskOptionsHash(m) {
+		log.Printf("task %s was replayed with different contents than it was %s with", m.TaskID, recorded.Outcome)
+	}
+	return true, nil
+}
+
+// finishTask applies the final status of m's image and records the outcome
+// in the ledger in one transaction. The ledger row is inserted first, so of
+// two deliveries finishing at once the second waits for the first and then
+// fails with errTaskRecorded, leaving the image as the first one left it. A
+// recorded failure is replaced while the image is pending or processing
+// again.
+func finishTask(ctx context.Context, db *sql.DB, dbQueries *database.Queries, m batch.ImageTask, outcome database.TaskOutcome, apply func(*database.Queries) error) error {
+	if m.TaskID == uuid.Nil {
+		return apply(dbQueries)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := dbQueries.WithTx(tx)
+
+	inserted, err := qtx.CreateProcessedTask(ctx, database.CreateProcessedTaskParams{
+		TaskID:      m.TaskID,
+		ImageID:     m.ImageID,
+		OptionsHash: taskOptionsHash(m),
+		Outcome:     outcome,
+	})
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return errTaskRecorded
+	}
+	if err := apply(qtx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// finishedAck is the ack for a task whose outcome finishTask returned err
+// for: done once the outcome is recorded, an ack of a replay when another
+// delivery recorded it first, and a requeue when it could not be recorded.
+func finishedAck(err error, done pubsub.AckType) (pubsub.AckType, bool) {
+	switch {
+	case err == nil:
+		return done, false
+	case errors.Is(err, errTaskRecorded):
+		return pubsub.Ack, true
+	default:
+		return pubsub.NackRequeue, false
+	}
+}
+
+// failTask marks m's image failed with a reason shown to its owner and
+// returns the ack for its message: a discard, unless the failure could not
+// be recorded.
+func failTask(db *sql.DB, dbQueries *database.Queries, m batch.ImageTask, reason string) (pubsub.AckType, bool) {
+	err := finishTask(context.Background(), db, dbQueries, m, database.TaskOutcomeFailed, func(q *database.Queries) error {
+		err := q.FailImageByID(context.Background(), database.FailImageByIDParams{
+			ID:            m.ImageID,
+			FailureReason: sql.NullString{String: reason, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		// Queued with the failure, so a replayed task does not send it again.
+		img, err := q.GetImageByID(context.Background(), m.ImageID)
+		if err != nil {
+			return err
+		}
+		return webhook.Enqueue(context.Background(), q, img.UserID, webhook.EventImageFailed, webhook.ImageFailedData{
+			ImageID:       img.ID,
+			BatchID:       img.BatchID,
+			ExternalID:    img.ExternalID.String,
+			Filename:      img.Filename.String,
+			FailureReason: reason,
+		})
+	})
+	if err != nil && !errors.Is(err, errTaskRecorded) {
+		log.Printf("error mark image %s failed, requeuing: %v", m.ImageID, err)
+	}
+	return finishedAck(err, pubsub.NackDiscard)
+}
@@ -0,0 +1,34 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinishedAck(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		done       pubsub.AckType
+		wantAck    pubsub.AckType
+		wantReplay bool
+	}{
+		{name: "completed", done: pubsub.Ack, wantAck: pubsub.Ack},
+		{name: "failed", done: pubsub.NackDiscard, wantAck: pubsub.NackDiscard},
+		{name: "recorded by another delivery", err: errTaskRecorded, done: pubsub.NackDiscard, wantAck: pubsub.Ack, wantReplay: true},
+		{name: "recorded, wrapped", err: fmt.Errorf("finish: %w", errTaskRecorded), done: pubsub.Ack, wantAck: pubsub.Ack, wantReplay: true},
+		{name: "ledger error", err: errors.New("connection reset"), done: pubsub.Ack, wantAck: pubsub.NackRequeue},
+		{name: "ledger error while failing", err: errors.New("connection reset"), done: pubsub.NackDiscard, wantAck: pubsub.NackRequeue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack, replay := finishedAck(tt.err, tt.done)
+			assert.Equal(t, tt.wantAck, ack)
+			assert.Equal(t, tt.wantReplay, replay)
+		})
+	}
+}
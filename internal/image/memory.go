@@ -0,0 +1,133 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// decodeWait is how long a task waits for room in the decode budget before
+// it is requeued for a later turn, possibly on another worker.
+const decodeWait = 10 * time.Second
+
+// defaultDecodeMemory is the budget when the worker's memory cannot be read.
+const defaultDecodeMemory = 1 << 30
+
+// decodeMemory bounds the decoded size of the images a worker processes at
+// once, so several large originals handled together cannot run it out of
+// memory even though each fits maxImagePixels.
+var decodeMemory = newMemoryBudget(availableDecodeMemory())
+
+// SetDecodeMemory sets the bytes the worker's concurrent decodes may take
+// together. It must be called before the worker subscribes.
+func SetDecodeMemory(limit int64) {
+	decodeMemory = newMemoryBudget(limit)
+}
+
+// DecodeMemory returns the bytes the worker's concurrent decodes may take
+// together.
+func DecodeMemory() int64 {
+	return decodeMemory.limit
+}
+
+// memoryBudget is a weighted semaphore over bytes.
+type memoryBudget struct {
+	mu       sync.Mutex
+	limit    int64
+	used     int64
+	released chan struct{}
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{limit: limit, released: make(chan struct{})}
+}
+
+// acquire takes n bytes of the budget, waiting up to wait for other decodes to
+// release theirs, and reports whether it did. A request larger than the whole
+// budget is let through once nothing else holds any, so it cannot wait forever.
+func (b *memoryBudget) acquire(n int64, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		b.mu.Lock()
+		if b.used == 0 || b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return true
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// release returns n bytes acquired before and wakes the waiting requests.
+func (b *memoryBudget) release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// decodedSize estimates the memory decoding data takes from its header, four
+// bytes per pixel. It is 0 for media the image decoders do not read, such as
+// videos.
+func decodedSize(data []byte) int64 {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return 0
+	}
+	return int64(cfg.Width) * int64(cfg.Height) * 4
+}
+
+// availableDecodeMemory gives decoding half of the memory the worker may use:
+// GOMEMLIMIT when set, else the limit of its cgroup, else the memory of the
+// machine.
+func availableDecodeMemory() int64 {
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		return limit / 2
+	}
+	if data, err := os.ReadFile("/sys/fs/cgroup/memory.max"); err == nil {
+		if limit, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			return limit / 2
+		}
+	}
+	if total, ok := memTotal(); ok {
+		return total / 2
+	}
+	return defaultDecodeMemory
+}
+
+// memTotal reads the memory of the machine from /proc/meminfo.
+func memTotal() (int64, bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			return kb << 10, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,39 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	b := newMemoryBudget(100)
+
+	assert.True(t, b.acquire(60, 0))
+	assert.True(t, b.acquire(40, 0))
+	assert.False(t, b.acquire(1, 10*time.Millisecond), "budget is full")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.release(40)
+	}()
+	assert.True(t, b.acquire(30, time.Second), "waits for a release")
+
+	b.release(60)
+	b.release(30)
+	assert.True(t, b.acquire(500, 0), "oversized request runs alone")
+	assert.False(t, b.acquire(1, 0))
+}
+
+func TestDecodedSize(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 30, 20))))
+
+	assert.Equal(t, int64(30*20*4), decodedSize(buf.Bytes()))
+	assert.Zero(t, decodedSize([]byte("not an image")))
+}
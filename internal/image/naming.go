@@ -0,0 +1,159 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// maxSuffixAttempts caps how many -1, -2, ... suffixes are tried for a
+// preserved filename before the image is failed.
+const maxSuffixAttempts = 1000
+
+// outputOwnerMetadata is the object metadata naming the image a processed
+// object with a preserved filename was written for.
+const outputOwnerMetadata = "image-id"
+
+var ErrOutputKeyExists = errors.New("output key already exists")
+
+// outputKey returns the processed key for a preserved filename, adding a -n
+// suffix when n > 0, e.g. processed/<batchID>/beach-2.jpg.
+func outputKey(batchID uuid.UUID, filename, mediaType string, n int) string {
+	stem := sanitizeFilename(filename)
+	if stem == "" {
+		stem = "image"
+	}
+	if n > 0 {
+		stem = fmt.Sprintf("%s-%d", stem, n)
+	}
+	return fmt.Sprintf("processed/%s/%s%s", batchID, stem, outputExt(mediaType))
+}
+
+func outputExt(mediaType string) string {
+	if mediaType == "image/jpeg" {
+		return ".jpg"
+	}
+	if _, subtype, ok := strings.Cut(mediaType, "/"); ok {
+		return "." + subtype
+	}
+	return ".bin"
+}
+
+// sanitizeFilename strips any directory and extension from an uploaded
+// filename and replaces characters that are unsafe in object keys.
+func sanitizeFilename(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name), "._")
+}
+
+// putProcessedImage uploads the processed image and returns the key it was
+// stored under. Batches with preserved filenames are written with a
+// conditional put so concurrent workers apply the collision policy atomically.
+// A key already holding the output of the same image, left by an earlier
+// delivery of its task, is overwritten rather than treated as a collision.
+func putProcessedImage(ctx context.Context, cfg *utils.Config, img database.GetImageByIDRow, data []byte, mediaType string) (string, error) {
+	if !img.PreserveFilenames {
+		key := "processed/" + utils.GetAssetPath(mediaType)
+		_, err := cfg.S3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(cfg.S3Bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(data),
+			ContentType: aws.String(mediaType),
+		})
+		return key, err
+	}
+
+	for n := 0; n <= maxSuffixAttempts; n++ {
+		key := outputKey(img.BatchID, img.Filename.String, mediaType, n)
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(cfg.S3Bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(data),
+			ContentType: aws.String(mediaType),
+			Metadata:    map[string]string{outputOwnerMetadata: img.ID.String()},
+		}
+		if img.CollisionPolicy != database.OutputCollisionPolicyOverwrite {
+			input.IfNoneMatch = aws.String("*")
+		}
+
+		_, err := cfg.S3Client.PutObject(ctx, input)
+		if err == nil {
+			return key, nil
+		}
+		if !isPreconditionFailed(err) {
+			return "", err
+		}
+		owned, err := ownsOutput(ctx, cfg, key, img.ID)
+		if err != nil {
+			return "", err
+		}
+		if owned {
+			input.IfNoneMatch = nil
+			input.Body = bytes.NewReader(data)
+			if _, err := cfg.S3Client.PutObject(ctx, input); err != nil {
+				return "", err
+			}
+			return key, nil
+		}
+		if img.CollisionPolicy == database.OutputCollisionPolicyError {
+			return "", fmt.Errorf("%w: %s", ErrOutputKeyExists, key)
+		}
+	}
+	return "", fmt.Errorf("%w: no free suffix for %q", ErrOutputKeyExists, img.Filename.String)
+}
+
+// ownsOutput tells whether the object stored under key was written for the
+// image imageID.
+func ownsOutput(ctx context.Context, cfg *utils.Config, key string, imageID uuid.UUID) (bool, error) {
+	head, err := cfg.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return false, err
+	}
+	return head.Metadata[outputOwnerMetadata] == imageID.String(), nil
+}
+
+// thumbnailKey returns where the thumbnail of the processed object stored
+// under processedKey goes, mirroring its path under thumbnails/.
+func thumbnailKey(processedKey string) string {
+	return "thumbnails/" + strings.TrimPrefix(processedKey, "processed/")
+}
+
+// putThumbnail uploads the thumbnail of the processed object stored under
+// processedKey and returns its key.
+func putThumbnail(ctx context.Context, cfg *utils.Config, processedKey string, data []byte, mediaType string) (string, error) {
+	key := thumbnailKey(processedKey)
+	_, err := cfg.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(cfg.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(mediaType),
+	})
+	return key, err
+}
+
+func isPreconditionFailed(err error) bool {
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusPreconditionFailed
+}
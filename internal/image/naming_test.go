@@ -0,0 +1,134 @@
+package image
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputKey(t *testing.T) {
+	batchID := uuid.MustParse("7b0c8f2e-3f4a-4a39-9d7e-2f4b2b7f6a10")
+
+	tests := []struct {
+		name      string
+		filename  string
+		mediaType string
+		n         int
+		want      string
+	}{
+		{name: "keeps stem", filename: "beach.png", want: "processed/" + batchID.String() + "/beach.jpg"},
+		{name: "adds suffix", filename: "beach.png", n: 2, want: "processed/" + batchID.String() + "/beach-2.jpg"},
+		{name: "strips directories", filename: `C:\photos\..\summer/beach.jpeg`, want: "processed/" + batchID.String() + "/beach.jpg"},
+		{name: "replaces unsafe characters", filename: "my photo #1?.jpg", want: "processed/" + batchID.String() + "/my_photo__1.jpg"},
+		{name: "falls back when empty", filename: "", want: "processed/" + batchID.String() + "/image.jpg"},
+		{name: "uses the output media type", filename: "clip.gif", mediaType: "video/mp4", want: "processed/" + batchID.String() + "/clip.mp4"},
+		{name: "falls back for dot files", filename: "..", want: "processed/" + batchID.String() + "/image.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, outputKey(batchID, tt.filename, cmp.Or(tt.mediaType, "image/jpeg"), tt.n))
+		})
+	}
+}
+
+func TestThumbnailKey(t *testing.T) {
+	assert.Equal(t, "thumbnails/7b0c8f2e-3f4a-4a39-9d7e-2f4b2b7f6a10/beach.jpg", thumbnailKey("processed/7b0c8f2e-3f4a-4a39-9d7e-2f4b2b7f6a10/beach.jpg"))
+	assert.Equal(t, "thumbnails/2025/01/abc.webp", thumbnailKey("processed/2025/01/abc.webp"))
+}
+
+// fakeBucket is an S3 endpoint that keeps the owner metadata of each object
+// and honors If-None-Match: * on PUT.
+type fakeBucket struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	owner, exists := b.owners[r.URL.Path]
+	switch r.Method {
+	case http.MethodPut:
+		io.Copy(io.Discard, r.Body)
+		if exists && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			io.WriteString(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+			return
+		}
+		b.owners[r.URL.Path] = r.Header.Get("X-Amz-Meta-Image-Id")
+	case http.MethodHead:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Amz-Meta-Image-Id", owner)
+	}
+}
+
+func TestPutProcessedImage(t *testing.T) {
+	batchID := uuid.MustParse("7b0c8f2e-3f4a-4a39-9d7e-2f4b2b7f6a10")
+	imageID, otherID := uuid.New(), uuid.New()
+	taken := "/bucket/processed/" + batchID.String() + "/beach.jpg"
+
+	tests := []struct {
+		name    string
+		policy  database.OutputCollisionPolicy
+		owner   uuid.UUID
+		want    string
+		wantErr error
+	}{
+		{name: "another image errors", policy: database.OutputCollisionPolicyError, owner: otherID, wantErr: ErrOutputKeyExists},
+		{name: "another image gets a suffix", policy: database.OutputCollisionPolicySuffix, owner: otherID, want: "processed/" + batchID.String() + "/beach-1.jpg"},
+		{name: "redelivery keeps its key", policy: database.OutputCollisionPolicyError, owner: imageID, want: "processed/" + batchID.String() + "/beach.jpg"},
+		{name: "redelivery keeps its key with suffixes", policy: database.OutputCollisionPolicySuffix, owner: imageID, want: "processed/" + batchID.String() + "/beach.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := &fakeBucket{owners: map[string]string{taken: tt.owner.String()}}
+			srv := httptest.NewServer(bucket)
+			defer srv.Close()
+			cfg := &utils.Config{
+				S3Bucket: "bucket",
+				S3Client: s3.New(s3.Options{
+					BaseEndpoint:               aws.String(srv.URL),
+					Region:                     "us-east-1",
+					UsePathStyle:               true,
+					Credentials:                credentials.NewStaticCredentialsProvider("key", "secret", ""),
+					RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+					RetryMaxAttempts:           1,
+				}),
+			}
+			img := database.GetImageByIDRow{
+				ID:                imageID,
+				BatchID:           batchID,
+				Filename:          sql.NullString{String: "beach.png", Valid: true},
+				PreserveFilenames: true,
+				CollisionPolicy:   tt.policy,
+			}
+
+			key, err := putProcessedImage(context.Background(), cfg, img, []byte("jpeg"), "image/jpeg")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, key)
+			assert.Equal(t, imageID.String(), bucket.owners["/bucket/"+key])
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"math"
+	"net/http"
+	"slices"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// phashSampleSize is the side of the grayscale square images are shrunk
+	// to before their DCT is taken.
+	phashSampleSize = 32
+	// phashSize is the side of the block of low frequencies kept, giving a
+	// 64-bit hash.
+	phashSize = 8
+	// maxPHashDistance is how many bits two perceptual hashes may differ in
+	// for their images to count as the same. It tolerates re-encoding and
+	// resizing, not edits.
+	maxPHashDistance = 4
+)
+
+// phashCos[u][x] is the DCT-II basis for frequency u at sample x.
+var phashCos = func() (t [phashSize][phashSampleSize]float64) {
+	for u := range phashSize {
+		for x := range phashSampleSize {
+			t[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * phashSampleSize))
+		}
+	}
+	return t
+}()
+
+// perceptualHash computes the DCT-based pHash of img: each bit tells whether
+// one of the 8x8 lowest frequencies of its luminance is above their median.
+// Images that look the same hash to the same or nearby values, which are
+// compared by their Hamming distance.
+func perceptualHash(img image.Image) uint64 {
+	gray := image.NewGray(image.Rect(0, 0, phashSampleSize, phashSampleSize))
+	draw.BiLinear.Scale(gray, gray.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	// Rows first, then columns, keeping only the frequencies hashed.
+	var rows [phashSampleSize][phashSize]float64
+	for y := range phashSampleSize {
+		for u := range phashSize {
+			var sum float64
+			for x := range phashSampleSize {
+				sum += float64(gray.Pix[y*gray.Stride+x]) * phashCos[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	coeffs := make([]float64, 0, phashSize*phashSize)
+	for v := range phashSize {
+		for u := range phashSize {
+			var sum float64
+			for y := range phashSampleSize {
+				sum += rows[y][u] * phashCos[v][y]
+			}
+			coeffs = append(coeffs, sum)
+		}
+	}
+
+	sorted := slices.Clone(coeffs)
+	slices.Sort(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	var hash uint64
+	for i, c := range coeffs {
+		if c > median {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+// originalInfo describes an upload without processing it.
+type originalInfo struct {
+	Width  int
+	Height int
+	Color  string
+	PHash  uint64
+}
+
+// inspectOriginal decodes a still or the first frame of a GIF the same way
+// the processors do, so its hash can be compared with theirs. ok is false
+// for other media and undecodable data.
+func inspectOriginal(data []byte) (info originalInfo, ok bool) {
+	var img image.Image
+	var size image.Point
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/webp":
+		decoded, err := decodeImage(data)
+		if err != nil {
+			return originalInfo{}, false
+		}
+		img = orient(decoded, exifOrientation(jpegExif(data)))
+		size = img.Bounds().Size()
+	case "image/gif":
+		cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+		if err != nil || cfg.Width*cfg.Height > maxImagePixels {
+			return originalInfo{}, false
+		}
+		img, err = gif.Decode(bytes.NewReader(data))
+		if err != nil {
+			return originalInfo{}, false
+		}
+		size = image.Pt(cfg.Width, cfg.Height)
+	default:
+		return originalInfo{}, false
+	}
+	return originalInfo{
+		Width:  size.X,
+		Height: size.Y,
+		Color:  dominantColor(img),
+		PHash:  perceptualHash(img),
+	}, true
+}
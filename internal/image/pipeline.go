@@ -0,0 +1,108 @@
+package image
+
+import (
+	"cmp"
+	"image"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"golang.org/x/image/draw"
+)
+
+// defaultSharpenAmount is the strength of sharpen steps that set none.
+const defaultSharpenAmount = 100
+
+// pipelineSteps returns opts.Pipeline, or batch.DefaultPipeline when it is
+// empty.
+func pipelineSteps(opts renderOptions) []batch.PipelineStep {
+	if len(opts.Pipeline) == 0 {
+		return batch.DefaultPipeline
+	}
+	return opts.Pipeline
+}
+
+// newStepTimings returns a zero timing for every step run for opts.
+func newStepTimings(opts renderOptions) []batch.StepTiming {
+	steps := pipelineSteps(opts)
+	timings := make([]batch.StepTiming, len(steps))
+	for i, step := range steps {
+		timings[i].Step = step.Type
+	}
+	return timings
+}
+
+// addStepTime adds the time since start to timings[i].
+func addStepTime(timings []batch.StepTiming, i int, start time.Time) {
+	timings[i].DurationMs += float64(time.Since(start).Microseconds()) / 1000
+}
+
+// runPipeline runs the pipeline steps before encode on one image or
+// frame, adding their durations to timings. The watermark step resolves the
+// auto position the first time it runs, so later frames keep it.
+func runPipeline(img image.Image, watermark image.Image, opts *renderOptions, timings []batch.StepTiming) *image.RGBA {
+	for i, step := range pipelineSteps(*opts) {
+		start := time.Now()
+		switch step.Type {
+		case batch.StepResize:
+			if step.Width > 0 || step.Height > 0 {
+				img = fitWithin(img, step.Width, step.Height)
+			} else {
+				img = fitWithin(img, opts.MaxWidth, opts.MaxHeight)
+			}
+		case batch.StepWatermark:
+			if watermark != nil && opts.Placement == nil && opts.Position == database.WatermarkPositionAuto {
+				opts.Position = leastSalientCorner(img, watermark.Bounds(), opts.Scale)
+			}
+			img = renderImage(img, watermark, *opts)
+		case batch.StepSharpen:
+			img = sharpen(toRGBA(img), cmp.Or(step.Amount, defaultSharpenAmount))
+		default:
+			continue
+		}
+		addStepTime(timings, i, start)
+	}
+	return toRGBA(img)
+}
+
+// toRGBA returns img as an RGBA image with its origin at 0,0, copying it
+// only when it is not one already.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// sharpen applies an unsharp mask: every channel moves away from the mean of
+// its 3x3 neighbourhood by amount percent of the difference. Colors stay
+// premultiplied, so they are clamped to the pixel's alpha.
+func sharpen(img *image.RGBA, amount int) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	k := float64(amount) / 100
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var sum [3]int
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					j := img.PixOffset(min(max(x+dx, b.Min.X), b.Max.X-1), min(max(y+dy, b.Min.Y), b.Max.Y-1))
+					sum[0] += int(img.Pix[j])
+					sum[1] += int(img.Pix[j+1])
+					sum[2] += int(img.Pix[j+2])
+				}
+			}
+			i := img.PixOffset(x, y)
+			alpha := float64(img.Pix[i+3])
+			for c := range sum {
+				v := float64(img.Pix[i+c])
+				out.Pix[i+c] = uint8(min(max(v+k*(v-float64(sum[c])/9), 0), alpha) + 0.5)
+			}
+			out.Pix[i+3] = img.Pix[i+3]
+		}
+	}
+	return out
+}
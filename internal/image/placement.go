@@ -0,0 +1,154 @@
+package image
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// SetWatermarkPlacement godoc
+// @Summary Set watermark placement
+// @Description Place the watermark of one image inside an explicit rectangle (normalized 0..1 coordinates) instead of the automatic bottom-right position. Completed or failed images are processed again
+// @Tags images
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param imageID path string true "Image ID"
+// @Param placement body batch.WatermarkPlacement true "Watermark Placement"
+// @Success 200 {object} utils.SuccessResponse{data=batch.ImageResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /images/{imageID}/watermark-placement [put]
+func (h *ImageHandler) SetWatermarkPlacement(c echo.Context) error {
+	var body batch.WatermarkPlacement
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	if body.X+body.Width > 1 || body.Y+body.Height > 1 {
+		return utils.RespondError(c, http.StatusBadRequest, "placement must lie within the image")
+	}
+
+	return h.updatePlacement(c, database.UpdateImageWatermarkPlacementParams{
+		PlacementX:      sql.NullFloat64{Float64: body.X, Valid: true},
+		PlacementY:      sql.NullFloat64{Float64: body.Y, Valid: true},
+		PlacementWidth:  sql.NullFloat64{Float64: body.Width, Valid: true},
+		PlacementHeight: sql.NullFloat64{Float64: body.Height, Valid: true},
+	})
+}
+
+// ClearWatermarkPlacement godoc
+// @Summary Clear watermark placement
+// @Description Go back to automatic bottom-right watermark placement for one image. Completed or failed images are processed again
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param imageID path string true "Image ID"
+// @Success 200 {object} utils.SuccessResponse{data=batch.ImageResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /images/{imageID}/watermark-placement [delete]
+func (h *ImageHandler) ClearWatermarkPlacement(c echo.Context) error {
+	return h.updatePlacement(c, database.UpdateImageWatermarkPlacementParams{})
+}
+
+// updatePlacement stores the placement and requeues the image unless it is
+// still waiting in the queue, in which case the worker picks it up anyway.
+func (h *ImageHandler) updatePlacement(c echo.Context, params database.UpdateImageWatermarkPlacementParams) error {
+	userID := c.Get("userID").(uuid.UUID)
+	imageUUID := utils.ParamUUID(c, "imageID")
+
+	img, err := h.dbQueries.GetUserImageByID(c.Request().Context(), database.GetUserImageByIDParams{
+		ID:     imageUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "image not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if img.Image.Status == database.ImageStatusProcessing {
+		return utils.RespondError(c, http.StatusConflict, "image is still processing")
+	}
+	if img.ArchiveStatus == database.BatchArchiveStatusArchived || img.ArchiveStatus == database.BatchArchiveStatusRestoring {
+		return utils.RespondError(c, http.StatusConflict, "batch is archived")
+	}
+	storage, err := h.config.Storage(img.BatchRegion)
+	if err != nil {
+		c.Logger().Errorf("failed to update placement of image %s: %v", img.Image.ID, err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	ch, err := h.config.RabbitMQ.Get()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	defer h.config.RabbitMQ.Put(ch)
+
+	params.ID = img.Image.ID
+	updated, err := h.dbQueries.UpdateImageWatermarkPlacement(c.Request().Context(), params)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	if img.Image.Status != database.ImageStatusPending {
+		// Processed files shared with similar images are left to them.
+		var objectURLs []string
+		for _, url := range []sql.NullString{img.Image.ProcessedUrl, img.Image.ThumbnailUrl} {
+			if url.Valid {
+				objectURLs = append(objectURLs, url.String)
+			}
+		}
+		referenced, err := h.dbQueries.GetReferencedObjectURLs(c.Request().Context(), objectURLs)
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		for _, url := range []sql.NullString{img.Image.ProcessedUrl, img.Image.ThumbnailUrl} {
+			if !url.Valid || slices.Contains(referenced, url.String) {
+				continue
+			}
+			if key := utils.GetObjectKey(storage.S3CfDistribution, url.String); key != "" {
+				_, err := storage.S3Client.DeleteObject(c.Request().Context(), &s3.DeleteObjectInput{
+					Bucket: aws.String(storage.S3Bucket),
+					Key:    aws.String(key),
+				})
+				if err != nil {
+					c.Logger().Errorf("failed to delete processed object %s: %v", key, err)
+				}
+			}
+		}
+
+		queue, err := batch.TaskQueueFor(c.Request().Context(), h.dbQueries, userID, img.BatchRegion)
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+		err = pubsub.PublishJSON(ch, utils.ImageGoDirect, queue, batch.NewImageTask(img.Image.ID))
+		if err != nil {
+			return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "watermark placement updated successfully", batch.NewImageResponse(updated))
+}
@@ -0,0 +1,382 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+)
+
+// maxImagePixels bounds the decoded size of an upload so a small file that
+// claims huge dimensions cannot exhaust the worker's memory.
+const maxImagePixels = 50_000_000
+
+var ErrImageTooLarge = errors.New("image dimensions exceed limit")
+
+// decodeImage decodes a JPEG, PNG or WebP after checking its header, rejecting
+// corrupt data and oversized dimensions with an error instead of panicking.
+func decodeImage(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("invalid image dimensions %dx%d", cfg.Width, cfg.Height)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// fitWithin downscales img, preserving its aspect ratio, so it is at most
+// maxWidth by maxHeight pixels. A bound of 0 is ignored, and images are never
+// upscaled.
+func fitWithin(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	ratio := 1.0
+	if maxWidth > 0 && w > maxWidth {
+		ratio = float64(maxWidth) / float64(w)
+	}
+	if maxHeight > 0 && h > maxHeight {
+		ratio = min(ratio, float64(maxHeight)/float64(h))
+	}
+	if ratio == 1 {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(1, int(float64(w)*ratio+0.5)), max(1, int(float64(h)*ratio+0.5))))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// textWatermarkSize is the point size text watermarks are rasterized at before
+// being scaled like an image watermark.
+const textWatermarkSize = 96
+
+// renderTextWatermark draws text in white on a transparent canvas using the
+// given font, or Go Regular when f is nil.
+func renderTextWatermark(text string, f *opentype.Font) (image.Image, error) {
+	if f == nil {
+		var err error
+		if f, err = fonts.defaultFont(); err != nil {
+			return nil, err
+		}
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    textWatermarkSize,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	d := &font.Drawer{Face: face, Src: image.White}
+	width := d.MeasureString(text).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("text watermark %q renders empty", text)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	d.Dst = img
+	d.Dot = fixed.P(0, metrics.Ascent.Ceil())
+	d.DrawString(text)
+	return img, nil
+}
+
+// placement is a watermark rectangle normalized to the image size.
+type placement struct {
+	X, Y, Width, Height float64
+}
+
+// renderOptions controls where the watermark is composited and how the
+// result is encoded. An explicit per-image placement takes precedence over the
+// batch position.
+type renderOptions struct {
+	Position  database.WatermarkPosition
+	Placement *placement
+	// Opacity is the watermark opacity in percent, 0 hiding it; nil uses
+	// defaultWatermarkOpacity.
+	Opacity *int
+	Scale   int
+	// TileSpacing is the gap between tiles in percent of the watermark size.
+	TileSpacing int
+	// Redactions are hidden in the upright original before anything else.
+	Redactions []batch.Redaction
+	// Crop is cut from the upright original after redacting; nil keeps the
+	// whole image.
+	Crop *cropSpec
+	// Pipeline is the batch's steps, run after the crop; empty runs
+	// batch.DefaultPipeline. Resize steps without bounds of their own use
+	// MaxWidth and MaxHeight.
+	Pipeline []batch.PipelineStep
+	// MaxWidth and MaxHeight bound the output size; 0 means no limit.
+	MaxWidth  int
+	MaxHeight int
+	// PreserveMetadata copies the original's EXIF into JPEG output.
+	PreserveMetadata bool
+	// Fingerprint is hidden in the pixels by the invisible watermark; nil
+	// disables it.
+	Fingerprint []byte
+	Format      database.OutputFormat
+	Quality     int
+	// Progressive and Subsampling tune JPEG output, PNGCompression PNG output.
+	Progressive    bool
+	Subsampling    database.ChromaSubsampling
+	PNGCompression database.PngCompression
+	// Transforms run in order after the watermark, before the invisible
+	// watermark is embedded.
+	Transforms []batch.Transform
+}
+
+// renderImage applies the watermark (if any) to src. The watermark is scaled
+// to opts.Scale percent of the image width (15% by default) and placed at the
+// batch position with 1% padding (bottom-right by default), repeated across
+// the image for the tiled and diagonal positions, or fitted into the image's
+// placement rectangle when one is set, at opts.Opacity percent opacity (50%
+// by default).
+func renderImage(src image.Image, watermark image.Image, opts renderOptions) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+
+	draw.Draw(dst, bounds, src, bounds.Min, draw.Src)
+	if watermark == nil || watermark.Bounds().Empty() {
+		return dst
+	}
+
+	var rects []image.Rectangle
+	switch {
+	case opts.Placement != nil:
+		rects = []image.Rectangle{placedRect(bounds, watermark.Bounds(), *opts.Placement)}
+	case opts.Position == database.WatermarkPositionTiled || opts.Position == database.WatermarkPositionDiagonal:
+		rects = []image.Rectangle{positionedRect(bounds, watermark.Bounds(), database.WatermarkPositionTopLeft, opts.Scale)}
+	default:
+		rects = []image.Rectangle{positionedRect(bounds, watermark.Bounds(), opts.Position, opts.Scale)}
+	}
+	if rects[0].Empty() {
+		return dst
+	}
+
+	// Fade the scaled watermark once so every tile shares the same pixels.
+	alpha := uint8((opts.opacity()*255 + 50) / 100)
+	scaled := image.NewRGBA(image.Rect(0, 0, rects[0].Dx(), rects[0].Dy()))
+	draw.BiLinear.Scale(scaled, scaled.Bounds(), watermark, watermark.Bounds(), draw.Over, nil)
+	faded := image.NewRGBA(scaled.Bounds())
+	draw.DrawMask(faded, faded.Bounds(), scaled, image.Point{}, image.NewUniform(color.Alpha{alpha}), image.Point{}, draw.Src)
+
+	if opts.Placement == nil && opts.Position == database.WatermarkPositionDiagonal {
+		for _, center := range diagonalCenters(bounds, faded.Bounds().Size(), opts.TileSpacing) {
+			draw.BiLinear.Transform(dst, rotationAround(center, faded.Bounds().Size(), diagonalAngle), faded, faded.Bounds(), draw.Over, nil)
+		}
+		return dst
+	}
+	if opts.Placement == nil && opts.Position == database.WatermarkPositionTiled {
+		rects = tiledRects(bounds, rects[0], opts.TileSpacing)
+	}
+	for _, r := range rects {
+		draw.Draw(dst, r, faded, image.Point{}, draw.Over)
+	}
+	return dst
+}
+
+// defaultQuality is the JPEG quality used when a batch does not set one.
+const defaultQuality = 50
+
+// encodeImage encodes img in the requested output format and returns the
+// bytes with their media type. Only the options of that format apply.
+func encodeImage(img image.Image, opts renderOptions) ([]byte, string, error) {
+	quality := opts.Quality
+	if quality <= 0 || quality > 100 {
+		quality = defaultQuality
+	}
+
+	var res bytes.Buffer
+	switch opts.Format {
+	case database.OutputFormatPng:
+		encoder := png.Encoder{CompressionLevel: pngCompressionLevels[opts.PNGCompression]}
+		if err := encoder.Encode(&res, img); err != nil {
+			return nil, "", err
+		}
+		return res.Bytes(), "image/png", nil
+	case database.OutputFormatWebp:
+		// WebP output is lossless, so quality does not apply.
+		if err := nativewebp.Encode(&res, img, nil); err != nil {
+			return nil, "", err
+		}
+		return res.Bytes(), "image/webp", nil
+	default:
+		err := encodeJPEG(&res, img, jpegOptions{
+			Quality:     quality,
+			Progressive: opts.Progressive,
+			Subsampling: opts.Subsampling,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		return res.Bytes(), "image/jpeg", nil
+	}
+}
+
+// pngCompressionLevels maps the batch setting to the encoder's levels; unset
+// means png.DefaultCompression.
+var pngCompressionLevels = map[database.PngCompression]png.CompressionLevel{
+	database.PngCompressionNone: png.NoCompression,
+	database.PngCompressionFast: png.BestSpeed,
+	database.PngCompressionBest: png.BestCompression,
+}
+
+// defaultWatermarkOpacity is the watermark opacity in percent when a batch
+// does not set one, matching the default of the batches table.
+const defaultWatermarkOpacity = 50
+
+// opacity is opts.Opacity clamped to 0-100, or defaultWatermarkOpacity.
+func (opts renderOptions) opacity() int {
+	if opts.Opacity == nil {
+		return defaultWatermarkOpacity
+	}
+	return min(max(*opts.Opacity, 0), 100)
+}
+
+// defaultWatermarkScale is the watermark width in percent of the image width
+// when a batch does not set one.
+const defaultWatermarkScale = 15
+
+// positionedRect scales the watermark to scalePercent of the image width and
+// places it at one of the corners or the centre with 1% padding. Watermarks
+// that would not fit inside the padding at that scale, such as tall logos on
+// landscape photos, are shrunk to fit, keeping their aspect ratio.
+func positionedRect(bounds, wBounds image.Rectangle, position database.WatermarkPosition, scalePercent int) image.Rectangle {
+	if scalePercent <= 0 || scalePercent > 100 {
+		scalePercent = defaultWatermarkScale
+	}
+	padding := int(float64(bounds.Dy()) * 0.01)
+	maxWidth, maxHeight := bounds.Dx()-2*padding, bounds.Dy()-2*padding
+
+	targetWidth := bounds.Dx() * scalePercent / 100
+	targetHeight := int(float64(wBounds.Dy()) * float64(targetWidth) / float64(wBounds.Dx()))
+	if targetWidth > maxWidth {
+		targetWidth = maxWidth
+		targetHeight = int(float64(wBounds.Dy()) * float64(targetWidth) / float64(wBounds.Dx()))
+	}
+	if targetHeight > maxHeight {
+		targetHeight = maxHeight
+		targetWidth = int(float64(wBounds.Dx()) * float64(targetHeight) / float64(wBounds.Dy()))
+	}
+	if targetWidth <= 0 || targetHeight <= 0 {
+		return image.Rectangle{}
+	}
+
+	var x, y int
+	switch position {
+	case database.WatermarkPositionTopLeft:
+		x, y = bounds.Min.X+padding, bounds.Min.Y+padding
+	case database.WatermarkPositionTopRight:
+		x, y = bounds.Max.X-targetWidth-padding, bounds.Min.Y+padding
+	case database.WatermarkPositionBottomLeft:
+		x, y = bounds.Min.X+padding, bounds.Max.Y-targetHeight-padding
+	case database.WatermarkPositionCenter:
+		x, y = bounds.Min.X+(bounds.Dx()-targetWidth)/2, bounds.Min.Y+(bounds.Dy()-targetHeight)/2
+	default:
+		x, y = bounds.Max.X-targetWidth-padding, bounds.Max.Y-targetHeight-padding
+	}
+	return image.Rect(x, y, x+targetWidth, y+targetHeight)
+}
+
+// tiledRects repeats the watermark rectangle across the image in a grid with
+// a gap of spacing percent of the watermark size between tiles.
+func tiledRects(bounds, first image.Rectangle, spacing int) []image.Rectangle {
+	if first.Empty() {
+		return nil
+	}
+	stepX := first.Dx() + first.Dx()*max(spacing, 0)/100
+	stepY := first.Dy() + first.Dy()*max(spacing, 0)/100
+
+	var rects []image.Rectangle
+	for y := first.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := first.Min.X; x < bounds.Max.X; x += stepX {
+			rects = append(rects, image.Rect(x, y, x+first.Dx(), y+first.Dy()))
+		}
+	}
+	return rects
+}
+
+// diagonalAngle rotates diagonal tiles so they rise from left to right.
+const diagonalAngle = -math.Pi / 4
+
+// diagonalCenters returns the centres of watermark tiles rotated by
+// diagonalAngle, laid out in rows that are shifted by half a step so the
+// tiles line up along diagonals. The gap between the rotated tiles is spacing
+// percent of their size, and tiles overhang the edges so the whole image is
+// covered.
+func diagonalCenters(bounds image.Rectangle, size image.Point, spacing int) []image.Point {
+	if size.X <= 0 || size.Y <= 0 {
+		return nil
+	}
+	// Bounding box of the watermark rotated by 45 degrees.
+	extent := float64(size.X+size.Y) * math.Sqrt2 / 2
+	step := max(int(extent*float64(100+max(spacing, 0))/100), 1)
+
+	var centers []image.Point
+	for row, y := 0, bounds.Min.Y; y < bounds.Max.Y+step; row, y = row+1, y+step {
+		offset := 0
+		if row%2 == 1 {
+			offset = step / 2
+		}
+		for x := bounds.Min.X + offset; x < bounds.Max.X+step; x += step {
+			centers = append(centers, image.Pt(x, y))
+		}
+	}
+	return centers
+}
+
+// rotationAround maps a size-sized source onto the destination rotated by
+// angle radians about center.
+func rotationAround(center, size image.Point, angle float64) f64.Aff3 {
+	sin, cos := math.Sincos(angle)
+	hw, hh := float64(size.X)/2, float64(size.Y)/2
+	return f64.Aff3{
+		cos, -sin, float64(center.X) - cos*hw + sin*hh,
+		sin, cos, float64(center.Y) - sin*hw - cos*hh,
+	}
+}
+
+// placedRect fits the watermark inside the normalized placement rectangle,
+// keeping its aspect ratio and centring it.
+func placedRect(bounds, wBounds image.Rectangle, place placement) image.Rectangle {
+	areaWidth := place.Width * float64(bounds.Dx())
+	areaHeight := place.Height * float64(bounds.Dy())
+	scale := min(areaWidth/float64(wBounds.Dx()), areaHeight/float64(wBounds.Dy()))
+	targetWidth := int(float64(wBounds.Dx()) * scale)
+	targetHeight := int(float64(wBounds.Dy()) * scale)
+	if targetWidth <= 0 || targetHeight <= 0 {
+		return image.Rectangle{}
+	}
+
+	x := bounds.Min.X + int(place.X*float64(bounds.Dx())+(areaWidth-float64(targetWidth))/2)
+	y := bounds.Min.Y + int(place.Y*float64(bounds.Dy())+(areaHeight-float64(targetHeight))/2)
+	return image.Rect(x, y, x+targetWidth, y+targetHeight).Intersect(bounds)
+}
@@ -0,0 +1,882 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+)
+
+func sampleJPEG(t testing.TB) []byte {
+	var buf bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		img.Set(x, x%48, color.RGBA{R: 200, A: 255})
+	}
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func samplePNG(t testing.TB) []byte {
+	var buf bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	img.Set(1, 1, color.NRGBA{G: 255, A: 128})
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func sampleWebP(t testing.TB) []byte {
+	var buf bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	img.Set(3, 3, color.NRGBA{B: 255, A: 255})
+	require.NoError(t, nativewebp.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// exifWithOrientation returns a minimal big-endian EXIF payload whose IFD0
+// only holds the orientation tag.
+func exifWithOrientation(orientation uint16) []byte {
+	var buf bytes.Buffer
+	buf.Write(exifHeader)
+	buf.WriteString("MM\x00\x2a")
+	binary.Write(&buf, binary.BigEndian, uint32(8))
+	binary.Write(&buf, binary.BigEndian, uint16(1))
+	binary.Write(&buf, binary.BigEndian, []uint16{exifOrientationTag, 3})
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	binary.Write(&buf, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&buf, binary.BigEndian, uint32(0))
+	return buf.Bytes()
+}
+
+// exifWithThumbnail returns a minimal big-endian EXIF payload whose IFD0
+// holds the orientation tag and links to an IFD1 with a JPEG thumbnail.
+func exifWithThumbnail(orientation uint16, thumbnail []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(exifHeader)
+	buf.WriteString("MM\x00\x2a")
+	binary.Write(&buf, binary.BigEndian, uint32(8))
+	// IFD0 at 8, linking to IFD1 at 26.
+	binary.Write(&buf, binary.BigEndian, uint16(1))
+	binary.Write(&buf, binary.BigEndian, []uint16{exifOrientationTag, 3})
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	binary.Write(&buf, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&buf, binary.BigEndian, uint32(26))
+	// IFD1 at 26, the thumbnail at 56.
+	binary.Write(&buf, binary.BigEndian, uint16(2))
+	binary.Write(&buf, binary.BigEndian, []uint16{exifThumbnailOffsetTag, 4})
+	binary.Write(&buf, binary.BigEndian, []uint32{1, 56})
+	binary.Write(&buf, binary.BigEndian, []uint16{exifThumbnailLengthTag, 4})
+	binary.Write(&buf, binary.BigEndian, []uint32{1, uint32(len(thumbnail)), 0})
+	buf.Write(thumbnail)
+	return buf.Bytes()
+}
+
+// exifWithCaptureTime returns a minimal little-endian EXIF payload whose Exif
+// IFD only holds DateTimeOriginal.
+func exifWithCaptureTime(dateTime string) []byte {
+	var buf bytes.Buffer
+	buf.Write(exifHeader)
+	buf.WriteString("II\x2a\x00")
+	binary.Write(&buf, binary.LittleEndian, uint32(8))
+	// IFD0 at 8, pointing to the Exif IFD at 26.
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, []uint16{exifIFDPointerTag, 4})
+	binary.Write(&buf, binary.LittleEndian, []uint32{1, 26, 0})
+	// Exif IFD at 26, its value at 44.
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, []uint16{exifDateTimeOriginalTag, 2})
+	binary.Write(&buf, binary.LittleEndian, []uint32{20, 44, 0})
+	buf.WriteString(dateTime + "\x00")
+	return buf.Bytes()
+}
+
+// oversizedPNG returns a PNG header whose IHDR claims dimensions far beyond
+// maxImagePixels, without any pixel data.
+func oversizedPNG() []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 100_000)
+	binary.BigEndian.PutUint32(ihdr[4:], 100_000)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func TestDecodeImage(t *testing.T) {
+	jpg := sampleJPEG(t)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "valid jpeg", data: jpg},
+		{name: "valid png", data: samplePNG(t)},
+		{name: "empty", data: nil, wantErr: true},
+		{name: "truncated jpeg", data: jpg[:len(jpg)/2], wantErr: true},
+		{name: "valid webp", data: sampleWebP(t)},
+		{name: "truncated webp", data: []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), wantErr: true},
+		{name: "oversized dimensions", data: oversizedPNG(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodeImage(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, img.Bounds().Empty())
+		})
+	}
+
+	_, err := decodeImage(oversizedPNG())
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
+
+func TestProcessorFor(t *testing.T) {
+	process, err := processorFor(sampleJPEG(t))
+	require.NoError(t, err)
+	out, err := process(context.Background(), samplePNG(t), nil, renderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", out.MediaType)
+	assert.NotEmpty(t, out.Data)
+	assert.Positive(t, out.Width)
+	thumbnail, _, err := image.DecodeConfig(bytes.NewReader(out.Thumbnail))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, max(thumbnail.Width, thumbnail.Height), thumbnailSize)
+	require.NotNil(t, out.Colors)
+	assert.NotEmpty(t, out.Colors.BlurHash)
+	assert.NotEmpty(t, out.Colors.ThumbHash)
+
+	_, err = processorFor([]byte("plain text upload"))
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+
+	_, err = processStill(context.Background(), []byte("\x89PNG\r\n\x1a\n"), nil, renderOptions{})
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+}
+
+func TestProcessGIF(t *testing.T) {
+	p := color.Palette{color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 0, 0, 255}}
+	background := image.NewPaletted(image.Rect(0, 0, 100, 50), p)
+	// The second frame only repaints the top-left corner.
+	patch := image.NewPaletted(image.Rect(0, 0, 10, 10), p)
+	draw.Draw(patch, patch.Bounds(), image.NewUniform(p[2]), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, &gif.GIF{
+		Image:     []*image.Paletted{background, patch},
+		Delay:     []int{10, 20},
+		LoopCount: 3,
+	}))
+	watermark := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	draw.Draw(watermark, watermark.Bounds(), image.White, image.Point{}, draw.Src)
+
+	process, err := processorFor(buf.Bytes())
+	require.NoError(t, err)
+	out, err := process(context.Background(), buf.Bytes(), watermark, renderOptions{Opacity: opacity(100), Scale: 20})
+	require.NoError(t, err)
+	assert.Equal(t, "image/gif", out.MediaType)
+	assert.Equal(t, 100, out.Width)
+	assert.Equal(t, 100, out.OriginalWidth)
+	assert.Equal(t, 50, out.OriginalHeight)
+	assert.Equal(t, "#000000", out.OriginalColor)
+	assert.Equal(t, database.WatermarkPositionBottomRight, out.WatermarkPosition)
+	require.NotNil(t, out.Colors)
+
+	anim, err := gif.DecodeAll(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 2)
+	assert.Equal(t, []int{10, 20}, anim.Delay)
+	assert.Equal(t, 3, anim.LoopCount)
+	for i, frame := range anim.Image {
+		assert.Equal(t, image.Rect(0, 0, 100, 50), frame.Bounds(), "frame %d", i)
+		assert.Equal(t, p[1], frame.At(90, 45), "frame %d is watermarked", i)
+	}
+	assert.Equal(t, p[2], anim.Image[1].At(5, 5))
+	assert.Equal(t, p[0], anim.Image[1].At(50, 25), "second frame keeps the first underneath")
+
+	_, err = processGIF(context.Background(), []byte("GIF89a"), nil, renderOptions{})
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+}
+
+func TestExifOrientation(t *testing.T) {
+	// A 64x48 JPEG shot with the camera turned clockwise.
+	data := withExif(sampleJPEG(t), exifWithOrientation(6))
+	exif := jpegExif(data)
+	require.NotNil(t, exif)
+	assert.Equal(t, 6, exifOrientation(exif))
+	assert.Equal(t, 1, exifOrientation(withoutOrientation(exif)))
+	assert.Nil(t, jpegExif(sampleJPEG(t)))
+
+	out, err := processStill(context.Background(), data, nil, renderOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 48, out.Width)
+	assert.Equal(t, 64, out.Height)
+	assert.Nil(t, jpegExif(out.Data), "metadata is stripped by default")
+
+	out, err = processStill(context.Background(), data, nil, renderOptions{MaxWidth: 24})
+	require.NoError(t, err)
+	assert.Equal(t, 24, out.Width)
+	assert.Equal(t, 48, out.OriginalWidth, "original size is reported upright")
+	assert.Equal(t, 64, out.OriginalHeight)
+	assert.NotEmpty(t, out.OriginalColor)
+
+	out, err = processStill(context.Background(), data, nil, renderOptions{PreserveMetadata: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, exifOrientation(jpegExif(out.Data)), "preserved metadata is marked upright")
+	_, err = jpeg.Decode(bytes.NewReader(out.Data))
+	assert.NoError(t, err)
+}
+
+func TestWithoutThumbnail(t *testing.T) {
+	thumbnail := []byte("\xff\xd8unredacted thumbnail\xff\xd9")
+	exif := exifWithThumbnail(6, thumbnail)
+	require.True(t, bytes.Contains(exif, thumbnail))
+
+	out := withoutThumbnail(exif)
+	assert.False(t, bytes.Contains(out, thumbnail), "thumbnail data is wiped")
+	assert.Equal(t, 6, exifOrientation(out), "IFD0 is kept")
+	assert.True(t, bytes.Contains(exif, thumbnail), "the input is not modified")
+	assert.Equal(t, withoutThumbnail(exifWithOrientation(6)), exifWithOrientation(6), "payloads without IFD1 are unchanged")
+
+	data := withExif(sampleJPEG(t), exif)
+	res, err := processStill(context.Background(), data, nil, renderOptions{PreserveMetadata: true})
+	require.NoError(t, err)
+	assert.NotNil(t, jpegExif(res.Data))
+	assert.False(t, bytes.Contains(res.Data, thumbnail), "preserved metadata drops the original's thumbnail")
+}
+
+func TestExifCaptureTime(t *testing.T) {
+	data := withExif(sampleJPEG(t), exifWithCaptureTime("2024:05:01 10:20:30"))
+	capturedAt, ok := exifCaptureTime(jpegExif(data))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC), capturedAt)
+
+	_, ok = exifCaptureTime(exifWithCaptureTime("0000:00:00 00:00:00"))
+	assert.False(t, ok, "unset clocks are ignored")
+	_, ok = exifCaptureTime(exifWithOrientation(6))
+	assert.False(t, ok)
+	_, ok = exifCaptureTime(nil)
+	assert.False(t, ok)
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.White)
+
+	tests := []struct {
+		orientation int
+		size        image.Point
+		white       image.Point
+	}{
+		{orientation: 1, size: image.Pt(3, 2), white: image.Pt(0, 0)},
+		{orientation: 2, size: image.Pt(3, 2), white: image.Pt(2, 0)},
+		{orientation: 3, size: image.Pt(3, 2), white: image.Pt(2, 1)},
+		{orientation: 4, size: image.Pt(3, 2), white: image.Pt(0, 1)},
+		{orientation: 5, size: image.Pt(2, 3), white: image.Pt(0, 0)},
+		{orientation: 6, size: image.Pt(2, 3), white: image.Pt(1, 0)},
+		{orientation: 7, size: image.Pt(2, 3), white: image.Pt(1, 2)},
+		{orientation: 8, size: image.Pt(2, 3), white: image.Pt(0, 2)},
+	}
+	// Decoded JPEGs are not RGBA and sub-images do not start at 0,0.
+	offset := image.NewNRGBA(image.Rect(5, 7, 8, 9))
+	offset.Set(5, 7, color.White)
+
+	for _, src := range []image.Image{img, offset} {
+		for _, tt := range tests {
+			out := orient(src, tt.orientation)
+			assert.Equal(t, tt.size, out.Bounds().Size(), "orientation %d", tt.orientation)
+			if tt.orientation == 1 && src == image.Image(offset) {
+				// Returned as it is, origin included.
+				continue
+			}
+			r, _, _, _ := out.At(tt.white.X, tt.white.Y).RGBA()
+			assert.Equal(t, uint32(0xffff), r, "orientation %d", tt.orientation)
+		}
+	}
+}
+
+func TestFitWithin(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+
+	tests := []struct {
+		name                string
+		maxWidth, maxHeight int
+		want                image.Point
+	}{
+		{name: "no limit", want: image.Pt(800, 600)},
+		{name: "width", maxWidth: 200, want: image.Pt(200, 150)},
+		{name: "height", maxHeight: 100, want: image.Pt(133, 100)},
+		{name: "both, height binds", maxWidth: 200, maxHeight: 100, want: image.Pt(133, 100)},
+		{name: "never upscales", maxWidth: 10000, maxHeight: 1000, want: image.Pt(800, 600)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, fitWithin(img, tt.maxWidth, tt.maxHeight).Bounds().Size())
+		})
+	}
+}
+
+func TestExtractColors(t *testing.T) {
+	// Three quarters red, one quarter blue.
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(300, 0, 400, 200), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+
+	colors, err := extractColors(img)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(colors.Palette), 2)
+	assert.Equal(t, "#ff0000", colors.Palette[0])
+	assert.Contains(t, colors.Palette, "#0000ff")
+	assert.LessOrEqual(t, len(colors.Palette), paletteSize)
+	// 4x2 components for a landscape image.
+	assert.Len(t, colors.BlurHash, 6+2*(4*2-1))
+}
+
+func TestEncodeThumbHash(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 75))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 200, G: 100, B: 50, A: 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 50, 75), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+
+	// 5 header bytes, then 22 luminance and 2x5 color terms at 4 bits each.
+	hash, err := encodeThumbHash(img)
+	require.NoError(t, err)
+	assert.Len(t, hash, 5+(22+5+5)/2)
+	assert.Zero(t, hash[2]&0x80, "opaque image flagged as transparent")
+	assert.NotZero(t, hash[4]&0x80, "landscape flag")
+
+	// Transparency adds the alpha byte and sets its flag.
+	draw.Draw(img, image.Rect(0, 0, 100, 10), image.Transparent, image.Point{}, draw.Src)
+	hash, err = encodeThumbHash(img)
+	require.NoError(t, err)
+	assert.NotZero(t, hash[2]&0x80)
+
+	_, err = encodeThumbHash(image.NewRGBA(image.Rect(0, 0, 200, 10)))
+	assert.Error(t, err)
+}
+
+func TestPerceptualHash(t *testing.T) {
+	scene := func(x0, y0 int) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+		for y := 0; y < 240; y++ {
+			for x := 0; x < 320; x++ {
+				img.SetRGBA(x, y, color.RGBA{uint8(x * 255 / 320), 120, uint8(y), 255})
+			}
+		}
+		draw.Draw(img, image.Rect(x0, y0, x0+100, y0+80), image.NewUniform(color.RGBA{A: 255}), image.Point{}, draw.Src)
+		return img
+	}
+	original := scene(40, 30)
+	hash := perceptualHash(original)
+
+	// Re-encoded at low quality and half the size it still matches.
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, fitWithin(original, 160, 120), &jpeg.Options{Quality: 30}))
+	info, ok := inspectOriginal(buf.Bytes())
+	require.True(t, ok)
+	assert.Equal(t, 160, info.Width)
+	assert.Equal(t, 120, info.Height)
+	assert.LessOrEqual(t, bits.OnesCount64(hash^info.PHash), maxPHashDistance)
+
+	// Moving the subject does not.
+	assert.Greater(t, bits.OnesCount64(hash^perceptualHash(scene(180, 130))), maxPHashDistance)
+
+	_, ok = inspectOriginal([]byte("not an image"))
+	assert.False(t, ok)
+}
+
+func TestRenderImage(t *testing.T) {
+	src, err := decodeImage(sampleJPEG(t))
+	require.NoError(t, err)
+	watermark, err := decodeImage(samplePNG(t))
+	require.NoError(t, err)
+
+	rendered := renderImage(src, watermark, renderOptions{})
+
+	tests := []struct {
+		format        database.OutputFormat
+		wantMediaType string
+		wantDecoded   string
+	}{
+		{format: "", wantMediaType: "image/jpeg", wantDecoded: "jpeg"},
+		{format: database.OutputFormatJpeg, wantMediaType: "image/jpeg", wantDecoded: "jpeg"},
+		{format: database.OutputFormatPng, wantMediaType: "image/png", wantDecoded: "png"},
+		{format: database.OutputFormatWebp, wantMediaType: "image/webp", wantDecoded: "webp"},
+	}
+	for _, tt := range tests {
+		out, mediaType, err := encodeImage(rendered, renderOptions{Format: tt.format, Quality: 90})
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantMediaType, mediaType)
+
+		_, format, err := image.Decode(bytes.NewReader(out))
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantDecoded, format)
+	}
+}
+
+func TestRenderImageOpacity(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(src, src.Bounds(), image.Black, image.Point{}, draw.Src)
+	wm := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	draw.Draw(wm, wm.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// The watermark covers (168,83)-(198,98) at the default bottom-right position.
+	tests := []struct {
+		name    string
+		opacity *int
+		want    uint8
+	}{
+		{name: "hidden", opacity: opacity(0), want: 0},
+		{name: "half", opacity: opacity(50), want: 128},
+		{name: "opaque", opacity: opacity(100), want: 255},
+		{name: "omitted", opacity: nil, want: 128},
+	}
+	for _, tt := range tests {
+		out := renderImage(src, wm, renderOptions{Opacity: tt.opacity})
+		r, _, _, _ := out.At(180, 90).RGBA()
+		assert.Equal(t, tt.want, uint8(r>>8), tt.name)
+	}
+}
+
+func opacity(percent int) *int {
+	return &percent
+}
+
+func TestRenderImageDiagonal(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	draw.Draw(src, src.Bounds(), image.Black, image.Point{}, draw.Src)
+	wm := image.NewRGBA(image.Rect(0, 0, 40, 10))
+	draw.Draw(wm, wm.Bounds(), image.White, image.Point{}, draw.Src)
+
+	covered := func(spacing int) int {
+		out := renderImage(src, wm, renderOptions{Position: database.WatermarkPositionDiagonal, Opacity: opacity(100), Scale: 10, TileSpacing: spacing})
+		n := 0
+		for y := 0; y < 400; y++ {
+			for x := 0; x < 400; x++ {
+				if r, _, _, _ := out.At(x, y).RGBA(); r > 0 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	// Tiles reach every quadrant, and a wider gap leaves fewer of them.
+	out := renderImage(src, wm, renderOptions{Position: database.WatermarkPositionDiagonal, Opacity: opacity(100), Scale: 10, TileSpacing: 50})
+	for _, q := range []image.Rectangle{image.Rect(0, 0, 200, 200), image.Rect(200, 0, 400, 200), image.Rect(0, 200, 200, 400), image.Rect(200, 200, 400, 400)} {
+		found := false
+		for y := q.Min.Y; y < q.Max.Y && !found; y++ {
+			for x := q.Min.X; x < q.Max.X && !found; x++ {
+				r, _, _, _ := out.At(x, y).RGBA()
+				found = r > 0
+			}
+		}
+		assert.True(t, found, "no tile in %v", q)
+	}
+	assert.Greater(t, covered(0), covered(200))
+}
+
+func TestApplyTransforms(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	draw.Draw(src, src.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+
+	out, err := applyTransforms(src, []batch.Transform{
+		{Type: batch.TransformPad, AspectRatio: "1:1", Color: "#00f"},
+		{Type: batch.TransformBorder, Size: 4, Color: "#000000"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(108, 108), out.Bounds().Size())
+	assert.Equal(t, color.RGBA{A: 255}, out.RGBAAt(0, 0), "border")
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, out.RGBAAt(54, 10), "padding above")
+	assert.Equal(t, red, out.RGBAAt(54, 54))
+
+	out, err = applyTransforms(src, []batch.Transform{{Type: batch.TransformLetterbox, Width: 300, Height: 200}})
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(300, 200), out.Bounds().Size())
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, out.RGBAAt(150, 10), "white bars by default")
+	assert.Equal(t, red, out.RGBAAt(5, 100), "upscaled to the full width")
+
+	_, err = applyTransforms(src, []batch.Transform{{Type: batch.TransformPad, AspectRatio: "100:1"}, {Type: batch.TransformPad, AspectRatio: "1:100"}})
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
+
+func TestCropImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 160, 90))
+	src.Set(80, 45, color.RGBA{R: 255, A: 255})
+
+	out, err := cropImage(src, &cropSpec{Ratio: image.Pt(1, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 90, 90), out.Bounds())
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, out.At(45, 45), "centered")
+
+	out, err = cropImage(src, &cropSpec{Ratio: image.Pt(16, 9)})
+	require.NoError(t, err)
+	assert.Same(t, src, out, "already at the ratio")
+
+	out, err = cropImage(src, &cropSpec{Rect: image.Rect(70, 40, 200, 200)})
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 90, 50), out.Bounds(), "clipped to the image")
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, out.At(10, 5))
+
+	_, err = cropImage(src, &cropSpec{Rect: image.Rect(200, 0, 300, 50)})
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+}
+
+func TestRedactImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for y := range 100 {
+		for x := range 100 {
+			if (x+y)%2 == 0 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	for _, typ := range []batch.RedactionType{batch.RedactionBlur, batch.RedactionPixelate} {
+		out, err := redactImage(src, []batch.Redaction{{Type: typ, X: 20, Y: 20, Width: 48, Height: 48}})
+		require.NoError(t, err, typ)
+		r, _, _, _ := out.At(40, 40).RGBA()
+		assert.InDelta(t, 0x7fff, r, 0x800, "%s averages the checkerboard", typ)
+		assert.Equal(t, src.At(10, 10), out.At(10, 10), "%s leaves the rest alone", typ)
+	}
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, src.RGBAAt(40, 40), "source untouched")
+
+	_, err := redactImage(src, []batch.Redaction{{Type: batch.RedactionBlur, X: 100, Y: 0, Width: 10, Height: 10}})
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+}
+
+func TestRunPipeline(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	opts := renderOptions{
+		Pipeline: []batch.PipelineStep{
+			{Type: batch.StepResize, Width: 100},
+			{Type: batch.StepSharpen},
+			{Type: batch.StepEncode},
+		},
+		MaxWidth: 300,
+	}
+	timings := newStepTimings(opts)
+	out := runPipeline(src, nil, &opts, timings)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), out.Bounds(), "step bounds win over the batch's")
+
+	steps := make([]batch.StepType, len(timings))
+	for i, timing := range timings {
+		steps[i] = timing.Step
+	}
+	assert.Equal(t, []batch.StepType{batch.StepResize, batch.StepSharpen, batch.StepEncode}, steps)
+	assert.Len(t, newStepTimings(renderOptions{}), len(batch.DefaultPipeline))
+}
+
+func TestSharpen(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := range 10 {
+		for x := range 10 {
+			v := uint8(96)
+			if x >= 5 {
+				v = 160
+			}
+			src.Set(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	out := sharpen(src, 100)
+	assert.Less(t, out.RGBAAt(4, 5).R, uint8(96), "dark side of the edge darkens")
+	assert.Greater(t, out.RGBAAt(5, 5).R, uint8(160), "light side of the edge lightens")
+	assert.Equal(t, src.RGBAAt(0, 5), out.RGBAAt(0, 5), "flat areas are left alone")
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := map[string]color.Color{
+		"":          color.White,
+		"#fff":      color.NRGBA{255, 255, 255, 255},
+		"#1234":     color.NRGBA{0x11, 0x22, 0x33, 0x44},
+		"#336699":   color.NRGBA{0x33, 0x66, 0x99, 0xff},
+		"#33669980": color.NRGBA{0x33, 0x66, 0x99, 0x80},
+	}
+	for s, want := range tests {
+		got, err := parseHexColor(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"336699", "#12345", "#gggggg"} {
+		_, err := parseHexColor(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestEncodeJPEG(t *testing.T) {
+	// An odd size, so blocks and MCUs hang over the edges.
+	src := image.NewRGBA(image.Rect(0, 0, 75, 41))
+	for y := 0; y < 41; y++ {
+		for x := 0; x < 75; x++ {
+			src.SetRGBA(x, y, color.RGBA{uint8(x * 3), uint8(y * 6), uint8(255 - x*3), 255})
+		}
+	}
+
+	tests := []struct {
+		subsampling database.ChromaSubsampling
+		wantRatio   image.YCbCrSubsampleRatio
+	}{
+		{subsampling: "", wantRatio: image.YCbCrSubsampleRatio420},
+		{subsampling: database.ChromaSubsampling420, wantRatio: image.YCbCrSubsampleRatio420},
+		{subsampling: database.ChromaSubsampling422, wantRatio: image.YCbCrSubsampleRatio422},
+		{subsampling: database.ChromaSubsampling444, wantRatio: image.YCbCrSubsampleRatio444},
+	}
+	for _, tt := range tests {
+		for _, progressive := range []bool{false, true} {
+			var out bytes.Buffer
+			err := encodeJPEG(&out, src, jpegOptions{Quality: 90, Progressive: progressive, Subsampling: tt.subsampling})
+			require.NoError(t, err)
+			// SOF2 marks a progressive file, SOF0 a baseline one.
+			assert.Equal(t, progressive, bytes.Contains(out.Bytes(), []byte{0xff, 0xc2}))
+
+			decoded, err := jpeg.Decode(&out)
+			require.NoError(t, err, "subsampling %q, progressive %v", tt.subsampling, progressive)
+			ycbcr, ok := decoded.(*image.YCbCr)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantRatio, ycbcr.SubsampleRatio)
+			assert.Equal(t, src.Bounds(), decoded.Bounds())
+
+			var diff float64
+			for y := 0; y < 41; y++ {
+				for x := 0; x < 75; x++ {
+					r1, g1, b1, _ := src.At(x, y).RGBA()
+					r2, g2, b2, _ := decoded.At(x, y).RGBA()
+					diff += math.Abs(float64(r1>>8)-float64(r2>>8)) + math.Abs(float64(g1>>8)-float64(g2>>8)) + math.Abs(float64(b1>>8)-float64(b2>>8))
+				}
+			}
+			assert.Less(t, diff/(75*41*3), 4.0, "subsampling %q, progressive %v", tt.subsampling, progressive)
+		}
+	}
+
+	// Baseline 4:2:0 is left to image/jpeg.
+	var ours, std bytes.Buffer
+	require.NoError(t, encodeJPEG(&ours, src, jpegOptions{Quality: 90}))
+	require.NoError(t, jpeg.Encode(&std, src, &jpeg.Options{Quality: 90}))
+	assert.Equal(t, std.Bytes(), ours.Bytes())
+}
+
+func TestFingerprint(t *testing.T) {
+	batchID, userID := uuid.New(), uuid.New()
+	key := []byte("fingerprint-key")
+	// A gradient with black and white bands, which cannot shift both ways.
+	rendered := image.NewRGBA(image.Rect(0, 0, 256, 192))
+	for y := 0; y < 192; y++ {
+		for x := 0; x < 256; x++ {
+			c := color.RGBA{uint8(x), uint8(y), uint8((x + y) / 2), 255}
+			if y < 16 {
+				c = color.RGBA{A: 255}
+			} else if y >= 176 {
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			rendered.SetRGBA(x, y, c)
+		}
+	}
+	plain := renderImage(rendered, nil, renderOptions{})
+	embedFingerprint(rendered, fingerprintPayload(key, batchID, userID))
+
+	for _, format := range []database.OutputFormat{database.OutputFormatPng, database.OutputFormatJpeg} {
+		out, _, err := encodeImage(rendered, renderOptions{Format: format})
+		require.NoError(t, err)
+		decoded, err := decodeImage(out)
+		require.NoError(t, err)
+		payload, ok := extractFingerprint(decoded)
+		require.True(t, ok)
+		gotBatch, gotUser, ok := parseFingerprint(key, payload)
+		require.True(t, ok, "%s output keeps the fingerprint", format)
+		assert.Equal(t, batchID, gotBatch)
+		assert.Equal(t, userID, gotUser)
+		_, _, ok = parseFingerprint([]byte("other-key"), payload)
+		assert.False(t, ok, "marks only verify with the key they were made with")
+	}
+
+	forged := fingerprintPayload([]byte("guessed-key"), batchID, userID)
+	_, _, ok := parseFingerprint(key, forged)
+	assert.False(t, ok, "marks made without the key are rejected")
+
+	payload, ok := extractFingerprint(plain)
+	require.True(t, ok)
+	_, _, ok = parseFingerprint(key, payload)
+	assert.False(t, ok, "unmarked images have no fingerprint")
+
+	_, ok = extractFingerprint(image.NewRGBA(image.Rect(0, 0, 64, 48)))
+	assert.False(t, ok, "too small to hold a fingerprint")
+}
+
+func TestLeastSalientCorner(t *testing.T) {
+	// A flat sky with a busy, skin-toned subject in the bottom-right corner.
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 90, G: 150, B: 230, A: 255}), image.Point{}, draw.Src)
+	for y := 200; y < 300; y++ {
+		for x := 250; x < 400; x++ {
+			if (x/4+y/4)%2 == 0 {
+				img.Set(x, y, color.RGBA{R: 220, G: 160, B: 130, A: 255})
+			}
+		}
+	}
+	// Some texture in the top-left, less than the subject.
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 120; x += 10 {
+			img.Set(x, y, color.Black)
+		}
+	}
+	wm := image.Rect(0, 0, 200, 100)
+
+	got := leastSalientCorner(img, wm, 25)
+	assert.Contains(t, []database.WatermarkPosition{database.WatermarkPositionTopRight, database.WatermarkPositionBottomLeft}, got)
+
+	// Equally flat corners keep the default.
+	flat := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	assert.Equal(t, database.WatermarkPositionBottomRight, leastSalientCorner(flat, wm, 25))
+
+	out, err := processStill(context.Background(), sampleJPEG(t), image.NewRGBA(wm), renderOptions{Position: database.WatermarkPositionAuto})
+	require.NoError(t, err)
+	assert.NotEqual(t, database.WatermarkPositionAuto, out.WatermarkPosition)
+	assert.NotEmpty(t, out.WatermarkPosition)
+}
+
+func TestWatermarkRect(t *testing.T) {
+	bounds := image.Rect(0, 0, 1000, 500)
+	watermark := image.Rect(0, 0, 200, 100)
+
+	// 15% of the width with 1% (5px) padding, bottom-right by default.
+	assert.Equal(t, image.Rect(845, 420, 995, 495), positionedRect(bounds, watermark, "", 0))
+	assert.Equal(t, image.Rect(5, 5, 155, 80), positionedRect(bounds, watermark, database.WatermarkPositionTopLeft, 0))
+	assert.Equal(t, image.Rect(845, 5, 995, 80), positionedRect(bounds, watermark, database.WatermarkPositionTopRight, 0))
+	assert.Equal(t, image.Rect(5, 420, 155, 495), positionedRect(bounds, watermark, database.WatermarkPositionBottomLeft, 0))
+	assert.Equal(t, image.Rect(425, 212, 575, 287), positionedRect(bounds, watermark, database.WatermarkPositionCenter, 0))
+
+	// A 40% scale makes the watermark 400x200.
+	assert.Equal(t, image.Rect(595, 295, 995, 495), positionedRect(bounds, watermark, "", 40))
+
+	// Tiles of 150x75 repeat every 225x112 pixels starting at the top-left.
+	tiles := tiledRects(bounds, positionedRect(bounds, watermark, database.WatermarkPositionTopLeft, 0), 50)
+	assert.Len(t, tiles, 5*5)
+	assert.Equal(t, image.Rect(230, 117, 380, 192), tiles[6])
+
+	// Without spacing the tiles touch.
+	tiles = tiledRects(bounds, positionedRect(bounds, watermark, database.WatermarkPositionTopLeft, 0), 0)
+	assert.Equal(t, image.Rect(155, 80, 305, 155), tiles[8])
+
+	// Fitted into a 0.2x0.4 (200x200) area at the top-left, centred vertically.
+	assert.Equal(t, image.Rect(0, 50, 200, 150), placedRect(bounds, watermark, placement{X: 0, Y: 0, Width: 0.2, Height: 0.4}))
+
+	// A tall watermark is shrunk to the height inside the padding instead of
+	// overflowing the image, and so is one that is too wide at 100%.
+	assert.Equal(t, image.Rect(873, 5, 995, 495), positionedRect(bounds, image.Rect(0, 0, 100, 400), "", 0))
+	assert.Equal(t, image.Rect(15, 5, 995, 495), positionedRect(bounds, watermark, "", 100))
+
+	// Tiny images leave no room for the watermark.
+	assert.True(t, positionedRect(image.Rect(0, 0, 5, 5), watermark, "", 0).Empty())
+}
+
+func TestRenderTextWatermark(t *testing.T) {
+	watermark, err := renderTextWatermark("© Studio", nil)
+	require.NoError(t, err)
+	assert.Greater(t, watermark.Bounds().Dx(), watermark.Bounds().Dy())
+
+	bold, err := opentype.Parse(gobold.TTF)
+	require.NoError(t, err)
+	boldWatermark, err := renderTextWatermark("© Studio", bold)
+	require.NoError(t, err)
+	assert.NotEqual(t, watermark.Bounds(), boldWatermark.Bounds())
+
+	f, err := fonts.forBatch(context.Background(), nil, sql.NullString{})
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	_, err = renderTextWatermark("", nil)
+	assert.Error(t, err)
+}
+
+// FuzzDecodeImage feeds arbitrary bytes through the worker's decode and render
+// steps. Corrupt input must come back as an error, never a panic.
+func FuzzDecodeImage(f *testing.F) {
+	jpg := sampleJPEG(f)
+	pngData := samplePNG(f)
+	f.Add(jpg)
+	f.Add(pngData)
+	f.Add(jpg[:len(jpg)/2])
+	f.Add(pngData[:len(pngData)-8])
+	f.Add(sampleWebP(f))
+	f.Add([]byte("RIFF\x24\x00\x00\x00WEBPVP8 "))
+	f.Add(oversizedPNG())
+	f.Add([]byte{})
+
+	watermark, err := decodeImage(pngData)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Keep iterations fast; the pixel limit itself is covered by TestDecodeImage.
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && cfg.Width*cfg.Height > 1<<20 {
+			t.Skip()
+		}
+
+		img, err := decodeImage(data)
+		if err != nil {
+			return
+		}
+		if _, _, err := encodeImage(renderImage(img, watermark, renderOptions{}), renderOptions{}); err != nil {
+			t.Fatalf("encode decoded image: %v", err)
+		}
+	})
+}
+
+// FuzzWatermark checks that arbitrary watermark uploads, including degenerate
+// sizes, never panic while being scaled onto the base image.
+func FuzzWatermark(f *testing.F) {
+	f.Add(samplePNG(f))
+	f.Add(sampleJPEG(f))
+	f.Add([]byte("\x89PNG\r\n\x1a\n"))
+
+	src, err := decodeImage(sampleJPEG(f))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && cfg.Width*cfg.Height > 1<<20 {
+			t.Skip()
+		}
+
+		watermark, err := decodeImage(data)
+		if err != nil {
+			return
+		}
+		if _, _, err := encodeImage(renderImage(src, watermark, renderOptions{}), renderOptions{}); err != nil {
+			t.Fatalf("encode with watermark: %v", err)
+		}
+	})
+}
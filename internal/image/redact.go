@@ -0,0 +1,63 @@
+package image
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"golang.org/x/image/draw"
+)
+
+// redactionCells is about how many cells the shorter side of a redaction is
+// averaged into; fewer cells hide more detail.
+const redactionCells = 6
+
+// redactImage hides each redaction of an upright original by averaging it
+// into coarse cells, drawn as blocks when pixelating and interpolated between
+// when blurring. The averages discard the detail, so it cannot be sharpened
+// back. Regions are clipped to the image and fail with ErrInvalidMedia when
+// they miss it entirely, so an image is never published without a redaction
+// it asked for.
+func redactImage(img image.Image, redactions []batch.Redaction) (image.Image, error) {
+	if len(redactions) == 0 {
+		return img, nil
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, img, b.Min, draw.Src)
+	for _, rd := range redactions {
+		r := image.Rect(rd.X, rd.Y, rd.X+rd.Width, rd.Y+rd.Height).Add(b.Min).Intersect(b)
+		if r.Empty() {
+			return nil, fmt.Errorf("%w: redaction at %d,%d is outside the %dx%d image", ErrInvalidMedia, rd.X, rd.Y, b.Dx(), b.Dy())
+		}
+
+		block := max(8, min(r.Dx(), r.Dy())/redactionCells)
+		cells := image.NewRGBA(image.Rect(0, 0, (r.Dx()+block-1)/block, (r.Dy()+block-1)/block))
+		for cy := range cells.Rect.Dy() {
+			for cx := range cells.Rect.Dx() {
+				cell := image.Rect(cx*block, cy*block, (cx+1)*block, (cy+1)*block).Add(r.Min).Intersect(r)
+				var sum [4]int
+				for y := cell.Min.Y; y < cell.Max.Y; y++ {
+					row := out.Pix[out.PixOffset(cell.Min.X, y):out.PixOffset(cell.Max.X, y)]
+					for i := 0; i < len(row); i += 4 {
+						for c := range sum {
+							sum[c] += int(row[i+c])
+						}
+					}
+				}
+				n := cell.Dx() * cell.Dy()
+				i := cells.PixOffset(cx, cy)
+				for c := range sum {
+					cells.Pix[i+c] = uint8(sum[c] / n)
+				}
+			}
+		}
+
+		var scaler draw.Scaler = draw.NearestNeighbor
+		if rd.Type == batch.RedactionBlur {
+			scaler = draw.BiLinear
+		}
+		scaler.Scale(out, r, cells, cells.Bounds(), draw.Src, nil)
+	}
+	return out, nil
+}
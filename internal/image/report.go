@@ -0,0 +1,195 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/rickyroynardson/image-go/internal/webhook"
+)
+
+// batchReport is the processing report written when a batch has no pending
+// or processing images left.
+type batchReport struct {
+	BatchID     uuid.UUID                    `json:"batch_id"`
+	Name        string                       `json:"name"`
+	ExternalID  string                       `json:"external_id"`
+	GeneratedAt time.Time                    `json:"generated_at"`
+	Counts      map[database.ImageStatus]int `json:"counts"`
+	Images      []reportImage                `json:"images"`
+}
+
+type reportImage struct {
+	ID              uuid.UUID            `json:"id"`
+	Filename        string               `json:"filename"`
+	ExternalID      string               `json:"external_id"`
+	Status          database.ImageStatus `json:"status"`
+	FailureReason   string               `json:"failure_reason"`
+	OriginalSize    int64                `json:"original_size"`
+	OriginalFormat  string               `json:"original_format"`
+	ProcessedSize   int64                `json:"processed_size"`
+	ProcessedFormat string               `json:"processed_format"`
+	ProcessedURL    string               `json:"processed_url"`
+	StepTimings     []batch.StepTiming   `json:"step_timings"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// reportKeys returns the object keys of a batch's JSON and CSV reports.
+// They are fixed per batch, so a newer report replaces the last one.
+func reportKeys(batchID uuid.UUID) (string, string) {
+	prefix := fmt.Sprintf("reports/%s/report", batchID)
+	return prefix + ".json", prefix + ".csv"
+}
+
+func newBatchReport(b database.Batch, images []database.Image) batchReport {
+	report := batchReport{
+		BatchID:     b.ID,
+		Name:        b.Name.String,
+		ExternalID:  b.ExternalID.String,
+		GeneratedAt: b.ReportGeneratedAt.Time,
+		Counts:      map[database.ImageStatus]int{},
+		Images:      make([]reportImage, 0, len(images)),
+	}
+	for _, img := range images {
+		report.Counts[img.Status]++
+		timings := []batch.StepTiming{}
+		if len(img.StepTimings) > 0 {
+			json.Unmarshal(img.StepTimings, &timings)
+		}
+		report.Images = append(report.Images, reportImage{
+			ID:              img.ID,
+			Filename:        img.Filename.String,
+			ExternalID:      img.ExternalID.String,
+			Status:          img.Status,
+			FailureReason:   img.FailureReason.String,
+			OriginalSize:    img.OriginalSize.Int64,
+			OriginalFormat:  img.OriginalFormat.String,
+			ProcessedSize:   img.ProcessedSize.Int64,
+			ProcessedFormat: img.ProcessedFormat.String,
+			ProcessedURL:    img.ProcessedUrl.String,
+			StepTimings:     timings,
+			CreatedAt:       img.CreatedAt,
+			UpdatedAt:       img.UpdatedAt,
+		})
+	}
+	return report
+}
+
+// csv renders the images of the report one per row. Step timings are left
+// out, they do not have a fixed set of columns.
+func (r batchReport) csv() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "filename", "external_id", "status", "failure_reason", "original_size", "original_format", "processed_size", "processed_format", "processed_url", "created_at", "updated_at"})
+	for _, img := range r.Images {
+		w.Write([]string{
+			img.ID.String(),
+			img.Filename,
+			img.ExternalID,
+			string(img.Status),
+			img.FailureReason,
+			strconv.FormatInt(img.OriginalSize, 10),
+			img.OriginalFormat,
+			strconv.FormatInt(img.ProcessedSize, 10),
+			img.ProcessedFormat,
+			img.ProcessedURL,
+			img.CreatedAt.Format(time.RFC3339),
+			img.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// finishBatch writes the report of the batch of imageID and queues its
+// batch.completed webhooks once none of its images are left to process. The
+// claim lets only one worker do so per change to the batch's images; a batch
+// still processing is not an error.
+func finishBatch(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, imageID uuid.UUID) error {
+	b, err := dbQueries.ClaimBatchReport(ctx, imageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	reportURL, reportErr := writeBatchReport(ctx, dbQueries, cfg, b)
+	// Webhooks are told about the batch even when its report failed.
+	progress, err := batch.Progress(ctx, dbQueries, b)
+	if err != nil {
+		return errors.Join(reportErr, err)
+	}
+	err = webhook.Enqueue(ctx, dbQueries, b.UserID, webhook.EventBatchCompleted, webhook.BatchCompletedData{
+		BatchID:    b.ID,
+		Name:       b.Name.String,
+		ExternalID: b.ExternalID.String,
+		Status:     progress.Status,
+		Total:      progress.Total,
+		Completed:  progress.Counts.Completed,
+		Failed:     progress.Counts.Failed,
+		Cancelled:  progress.Counts.Cancelled,
+		Expired:    progress.Counts.Expired,
+		ReportURL:  reportURL,
+	})
+	return errors.Join(reportErr, err)
+}
+
+// writeBatchReport writes the report of b and returns its URL.
+func writeBatchReport(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, b database.Batch) (string, error) {
+	images, err := dbQueries.GetImagesByBatchID(ctx, b.ID)
+	if err != nil {
+		return "", err
+	}
+	report := newBatchReport(b, images)
+
+	jsonKey, csvKey := reportKeys(b.ID)
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	if err := putReport(ctx, cfg, jsonKey, data, "application/json"); err != nil {
+		return "", err
+	}
+	links := database.SetBatchReportURLsParams{
+		ID:        b.ID,
+		ReportUrl: sql.NullString{String: utils.GetObjectURL(cfg.S3CfDistribution, jsonKey), Valid: true},
+	}
+	if b.ReportCsv {
+		data, err := report.csv()
+		if err != nil {
+			return "", err
+		}
+		if err := putReport(ctx, cfg, csvKey, data, "text/csv"); err != nil {
+			return "", err
+		}
+		links.ReportCsvUrl = sql.NullString{String: utils.GetObjectURL(cfg.S3CfDistribution, csvKey), Valid: true}
+	}
+	if err := dbQueries.SetBatchReportURLs(ctx, links); err != nil {
+		return "", err
+	}
+	return links.ReportUrl.String, nil
+}
+
+func putReport(ctx context.Context, cfg *utils.Config, key string, data []byte, contentType string) error {
+	_, err := cfg.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(cfg.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
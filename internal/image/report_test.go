@@ -0,0 +1,69 @@
+package image
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBatchReport(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	b := database.Batch{
+		ID:                uuid.New(),
+		Name:              sql.NullString{String: "spring", Valid: true},
+		ReportGeneratedAt: sql.NullTime{Time: now, Valid: true},
+	}
+	images := []database.Image{
+		{
+			ID:              uuid.New(),
+			Filename:        sql.NullString{String: "a.jpg", Valid: true},
+			Status:          database.ImageStatusCompleted,
+			OriginalSize:    sql.NullInt64{Int64: 2000, Valid: true},
+			ProcessedSize:   sql.NullInt64{Int64: 800, Valid: true},
+			ProcessedFormat: sql.NullString{String: "jpeg", Valid: true},
+			StepTimings:     []byte(`[{"step":"resize","duration_ms":1.5}]`),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		},
+		{
+			ID:            uuid.New(),
+			Filename:      sql.NullString{String: "b, final.png", Valid: true},
+			Status:        database.ImageStatusFailed,
+			FailureReason: sql.NullString{String: "original could not be read", Valid: true},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		},
+	}
+
+	report := newBatchReport(b, images)
+	assert.Equal(t, "spring", report.Name)
+	assert.Equal(t, now, report.GeneratedAt)
+	assert.Equal(t, map[database.ImageStatus]int{database.ImageStatusCompleted: 1, database.ImageStatusFailed: 1}, report.Counts)
+	require.Len(t, report.Images, 2)
+	assert.Len(t, report.Images[0].StepTimings, 1)
+	assert.Empty(t, report.Images[1].StepTimings)
+	assert.NotNil(t, report.Images[1].StepTimings)
+
+	data, err := report.csv()
+	require.NoError(t, err)
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "failure_reason", rows[0][4])
+	assert.Equal(t, []string{"a.jpg", "completed", "", "2000"}, []string{rows[1][1], rows[1][3], rows[1][4], rows[1][5]})
+	assert.Equal(t, []string{"b, final.png", "failed", "original could not be read"}, []string{rows[2][1], rows[2][3], rows[2][4]})
+}
+
+func TestReportKeys(t *testing.T) {
+	id := uuid.MustParse("7f1d2a4e-3c5b-4d6e-8f90-1a2b3c4d5e6f")
+	jsonKey, csvKey := reportKeys(id)
+	assert.Equal(t, "reports/7f1d2a4e-3c5b-4d6e-8f90-1a2b3c4d5e6f/report.json", jsonKey)
+	assert.Equal(t, "reports/7f1d2a4e-3c5b-4d6e-8f90-1a2b3c4d5e6f/report.csv", csvKey)
+}
@@ -0,0 +1,97 @@
+package image
+
+import (
+	"image"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+// saliencySampleSize is the longest side images are shrunk to before the
+// saliency map is computed.
+const saliencySampleSize = 128
+
+// skinWeight is how much a skin-toned pixel adds to its saliency, so faces
+// and people count as important even where they are smooth.
+const skinWeight = 64
+
+// autoPositionCandidates are the corners the auto position chooses from, in
+// order of preference when they are equally busy.
+var autoPositionCandidates = []database.WatermarkPosition{
+	database.WatermarkPositionBottomRight,
+	database.WatermarkPositionBottomLeft,
+	database.WatermarkPositionTopRight,
+	database.WatermarkPositionTopLeft,
+}
+
+// leastSalientCorner returns the corner where a watermark of wBounds at
+// scalePercent would cover the least important part of img. Importance is
+// approximated by local contrast (edges and texture) plus skin tones.
+func leastSalientCorner(img image.Image, wBounds image.Rectangle, scalePercent int) database.WatermarkPosition {
+	sample := shrink(img, saliencySampleSize)
+	saliency := saliencyMap(sample)
+	w := sample.Rect.Dx()
+
+	best, bestScore := autoPositionCandidates[0], -1.0
+	for _, position := range autoPositionCandidates {
+		r := positionedRect(sample.Rect, wBounds, position, scalePercent)
+		if r.Empty() {
+			continue
+		}
+		var sum int
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				sum += saliency[y*w+x]
+			}
+		}
+		score := float64(sum) / float64(r.Dx()*r.Dy())
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = position, score
+		}
+	}
+	return best
+}
+
+// saliencyMap scores every pixel of img by its luminance gradient, adding
+// skinWeight for skin-toned pixels.
+func saliencyMap(img *image.RGBA) []int {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	luma := make([]int, w*h)
+	saliency := make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.RGBAAt(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+			luma[y*w+x] = (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+			if isSkinTone(int(c.R), int(c.G), int(c.B)) {
+				saliency[y*w+x] = skinWeight
+			}
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			var dx, dy int
+			if x+1 < w {
+				dx = abs(luma[i+1] - luma[i])
+			}
+			if y+1 < h {
+				dy = abs(luma[i+w] - luma[i])
+			}
+			saliency[i] += dx + dy
+		}
+	}
+	return saliency
+}
+
+// isSkinTone is the classic RGB skin rule for daylight photos.
+func isSkinTone(r, g, b int) bool {
+	return r > 95 && g > 40 && b > 20 &&
+		max(r, g, b)-min(r, g, b) > 15 &&
+		abs(r-g) > 15 && r > g && r > b
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -1,16 +1,16 @@
 package image
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"image"
-	"image/color"
-	"image/jpeg"
-	_ "image/png"
+	"io"
 	"log"
-
-	"golang.org/x/image/draw"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -20,16 +20,101 @@ import (
 	"github.com/rickyroynardson/image-go/internal/utils"
 )
 
-func ProcessImage(dbQueries *database.Queries, cfg *utils.Config) func(batch.ImageTask) pubsub.AckType {
+func ProcessImage(db *sql.DB, dbQueries *database.Queries, cfg *utils.Config) func(batch.ImageTask) pubsub.AckType {
+	process := processTask(db, dbQueries, cfg)
 	return func(m batch.ImageTask) pubsub.AckType {
+		ack, replay := process(m)
+		// Only a finished task can be the batch's last one. The delivery
+		// that recorded a replayed task's outcome already did this.
+		if !replay && (ack == pubsub.Ack || ack == pubsub.NackDiscard) {
+			publishStoredStatus(context.Background(), dbQueries, cfg, m.ImageID)
+			if err := finishBatch(context.Background(), dbQueries, cfg, m.ImageID); err != nil {
+				log.Printf("error finishing the batch of image %s: %v", m.ImageID, err)
+			}
+		}
+		return ack
+	}
+}
+
+func processTask(db *sql.DB, dbQueries *database.Queries, cfg *utils.Config) func(batch.ImageTask) (pubsub.AckType, bool) {
+	return func(m batch.ImageTask) (pubsub.AckType, bool) {
 		img, err := dbQueries.GetImageByID(context.Background(), m.ImageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("error get image, requeuing: %v", err)
+			return pubsub.NackRequeue, false
+		}
+		// The image or its batch is deleted. Images of a batch in the trash
+		// are queued again when it is restored.
 		if err != nil {
 			log.Printf("error get image, discarding message: %v", err)
-			dbQueries.UpdateImageByID(context.Background(), database.UpdateImageByIDParams{
-				ID:     m.ImageID,
-				Status: database.ImageStatusFailed,
+			dbQueries.FailImageByID(context.Background(), database.FailImageByIDParams{
+				ID:            m.ImageID,
+				FailureReason: sql.NullString{String: batch.FailureBatchDeleted, Valid: true},
 			})
-			return pubsub.NackDiscard
+			return pubsub.NackDiscard, false
+		}
+		switch img.Status {
+		case database.ImageStatusCancelled, database.ImageStatusExpired:
+			log.Printf("image %s was %s, skipping task %s", m.ImageID, img.Status, m.TaskID)
+			return pubsub.Ack, false
+		case database.ImageStatusCompleted, database.ImageStatusFailed:
+			// Every task is published for a pending image, so this one is
+			// stale: the image was finished by another task since.
+			log.Printf("image %s is already %s, skipping task %s", m.ImageID, img.Status, m.TaskID)
+			return pubsub.Ack, true
+		}
+		// An image finished after its batch's deadline is of no use to the
+		// client, so it is skipped rather than delivered late.
+		if img.BatchDeadline.Valid && !time.Now().Before(img.BatchDeadline.Time) {
+			expired, err := dbQueries.ExpireImageByID(context.Background(), img.ID)
+			if err != nil {
+				log.Printf("error expire image, requeuing: %v", err)
+				return pubsub.NackRequeue, false
+			}
+			if expired > 0 {
+				log.Printf("batch of image %s is past its deadline, skipping task %s", m.ImageID, m.TaskID)
+				return pubsub.Ack, false
+			}
+		}
+
+		recorded, err := taskRecorded(context.Background(), dbQueries, m, img.Status)
+		if err != nil {
+			log.Printf("error get processed task, requeuing: %v", err)
+			return pubsub.NackRequeue, false
+		}
+		if recorded {
+			log.Printf("task %s of image %s was already processed, acking replay", m.TaskID, m.ImageID)
+			return pubsub.Ack, true
+		}
+
+		// A task routed to the wrong region must not copy the image out of
+		// its own.
+		if img.BatchRegion != cfg.Region {
+			log.Printf("image %s belongs to data region %q, not %q, discarding message", m.ImageID, img.BatchRegion, cfg.Region)
+			return failTask(db, dbQueries, m, "task was routed to another data region")
+		}
+
+		claimed, err := claimSlot(context.Background(), db, dbQueries, img)
+		if err != nil {
+			log.Printf("error claim processing slot, requeuing: %v", err)
+			return pubsub.NackRequeue, false
+		}
+		if !claimed {
+			return pubsub.RequeueDelayed, false
+		}
+		img.Status = database.ImageStatusProcessing
+		publishStatus(cfg, img)
+
+		// Images created from a URL get their original on first processing.
+		if img.Key == "" && img.SourceUrl.Valid {
+			if err := storeSource(context.Background(), dbQueries, cfg, &img); err != nil {
+				if errors.Is(err, ErrFetchSource) {
+					log.Printf("error fetch source of image %s, discarding message: %v", m.ImageID, err)
+					return failTask(db, dbQueries, m, err.Error())
+				}
+				log.Printf("error store source, requeuing: %v", err)
+				return pubsub.NackRequeue, false
+			}
 		}
 
 		obj, err := cfg.S3Client.GetObject(context.Background(), &s3.GetObjectInput{
@@ -38,111 +123,309 @@ func ProcessImage(dbQueries *database.Queries, cfg *utils.Config) func(batch.Ima
 		})
 		if err != nil {
 			log.Printf("error get object, discarding message: %v", err)
-			dbQueries.UpdateImageByID(context.Background(), database.UpdateImageByIDParams{
-				ID:     m.ImageID,
-				Status: database.ImageStatusFailed,
-			})
-			return pubsub.NackDiscard
+			return failTask(db, dbQueries, m, "original could not be read")
 		}
 		defer obj.Body.Close()
 
 		// TODO: cache watermark image to avoid multiple s3 get object in short time
 		var watermarkImg image.Image
-		if img.WatermarkKey.Valid && img.WatermarkKey.String != "" {
+		// Batches using a library watermark have no watermark of their own.
+		watermarkKey := img.WatermarkKey.String
+		if img.LibraryWatermarkKey.Valid {
+			watermarkKey = img.LibraryWatermarkKey.String
+		}
+		if watermarkKey != "" {
 			watermarkObj, err := cfg.S3Client.GetObject(context.Background(), &s3.GetObjectInput{
 				Bucket: aws.String(cfg.S3Bucket),
-				Key:    aws.String(img.WatermarkKey.String),
+				Key:    aws.String(watermarkKey),
 			})
 			if err != nil {
 				log.Printf("error get watermark object, requeuing: %v", err)
-				return pubsub.NackRequeue
+				return pubsub.NackRequeue, false
 			}
 			defer watermarkObj.Body.Close()
 
-			decodedImg, _, err := image.Decode(watermarkObj.Body)
+			data, err := io.ReadAll(watermarkObj.Body)
+			if err != nil {
+				log.Printf("error read watermark object, requeuing: %v", err)
+				return pubsub.NackRequeue, false
+			}
+			watermarkImg, err = decodeImage(data)
+			if err != nil {
+				log.Printf("error decode watermark image, discarding message: %v", err)
+				return failTask(db, dbQueries, m, "watermark could not be decoded")
+			}
+		}
+
+		if watermarkImg == nil && img.WatermarkText.Valid && img.WatermarkText.String != "" {
+			f, err := fonts.forBatch(context.Background(), cfg, img.WatermarkFontKey)
+			if err != nil && !errors.Is(err, errInvalidFont) {
+				log.Printf("error get watermark font, requeuing: %v", err)
+				return pubsub.NackRequeue, false
+			}
+			if err == nil {
+				watermarkImg, err = renderTextWatermark(img.WatermarkText.String, f)
+			}
 			if err != nil {
-				log.Printf("error decode watermark image, requeuing: %v", err)
-				return pubsub.NackRequeue
+				log.Printf("error render text watermark, discarding message: %v", err)
+				return failTask(db, dbQueries, m, "text watermark could not be rendered")
 			}
-			watermarkImg = decodedImg
 		}
 
-		decodedImg, _, err := image.Decode(obj.Body)
+		data, err := io.ReadAll(obj.Body)
 		if err != nil {
-			log.Printf("error decode image, requeuing: %v", err)
-			dbQueries.UpdateImageByID(context.Background(), database.UpdateImageByIDParams{
-				ID:     m.ImageID,
-				Status: database.ImageStatusProcessing,
-			})
-			return pubsub.NackRequeue
+			log.Printf("error read object, requeuing: %v", err)
+			return pubsub.NackRequeue, false
+		}
+		process, err := processorFor(data)
+		if err != nil {
+			log.Printf("error dispatch media, discarding message: %v", err)
+			return failTask(db, dbQueries, m, err.Error())
 		}
 
-		bounds := decodedImg.Bounds()
-		dst := image.NewRGBA(bounds)
+		// Decoded images take far more memory than their files, so the
+		// tasks a worker handles at once share a budget. A task that finds
+		// no room in time goes to the back of the queue.
+		size := decodedSize(data)
+		if !decodeMemory.acquire(size, decodeWait) {
+			log.Printf("image %s needs %d MB to decode, more than is free, requeuing", m.ImageID, size>>20)
+			return pubsub.RequeueLast, false
+		}
+		release := sync.OnceFunc(func() { decodeMemory.release(size) })
+		defer release()
 
-		draw.Draw(dst, bounds, decodedImg, image.Point{}, draw.Src)
-		baseWidth := bounds.Dx()
-		baseHeight := bounds.Dy()
-		if watermarkImg != nil {
-			wBounds := watermarkImg.Bounds()
-			wWidth := wBounds.Dx()
-			wHeight := wBounds.Dy()
+		// Tasks carry the batch's output settings when they are published;
+		// any others were asked for explicitly and are rendered anew.
+		batchOutput := (m.OutputFormat == "" || m.OutputFormat == img.OutputFormat) && (m.OutputQuality == 0 || m.OutputQuality == int(img.OutputQuality))
+		if img.SimilarDedupe != database.SimilarDedupeOff && batchOutput {
+			if done, replay := linkSimilarImage(context.Background(), db, dbQueries, m, img, data); done {
+				return pubsub.Ack, replay
+			}
+		}
 
-			targetWidth := int(float64(baseWidth) * 0.15)
-			scale := float64(targetWidth) / float64(wWidth)
-			targetHeight := int(float64(wHeight) * scale)
+		var place *placement
+		if img.PlacementX.Valid {
+			place = &placement{
+				X:      img.PlacementX.Float64,
+				Y:      img.PlacementY.Float64,
+				Width:  img.PlacementWidth.Float64,
+				Height: img.PlacementHeight.Float64,
+			}
+		}
 
-			resizedWatermark := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
-			draw.BiLinear.Scale(resizedWatermark, resizedWatermark.Bounds(), watermarkImg, wBounds, draw.Over, nil)
+		transforms, err := batch.DecodeTransforms(img.Transforms)
+		if err != nil {
+			log.Printf("error decode transforms, discarding message: %v", err)
+			return failTask(db, dbQueries, m, "invalid transforms")
+		}
 
-			alphaMask := image.NewUniform(color.Alpha{128})
-			padding := int(float64(baseHeight) * 0.01)
-			watermarkX := baseWidth - targetWidth - padding
-			watermarkY := baseHeight - targetHeight - padding
-			watermarkRect := image.Rect(watermarkX, watermarkY, watermarkX+targetWidth, watermarkY+targetHeight)
+		redactions, err := batch.DecodeRedactions(img.Redactions)
+		if err != nil {
+			log.Printf("error decode redactions, discarding message: %v", err)
+			return failTask(db, dbQueries, m, "invalid redactions")
+		}
+
+		pipeline, err := batch.DecodePipeline(img.Pipeline)
+		if err != nil {
+			log.Printf("error decode pipeline, discarding message: %v", err)
+			return failTask(db, dbQueries, m, "invalid pipeline")
+		}
 
-			draw.DrawMask(dst, watermarkRect, resizedWatermark, image.Point{}, alphaMask, image.Point{}, draw.Over)
+		var crop *cropSpec
+		if w, h, ok := batch.ParseAspectRatio(img.CropAspectRatio.String); ok {
+			crop = &cropSpec{Ratio: image.Pt(w, h)}
+		} else if img.CropX.Valid {
+			x, y := int(img.CropX.Int32), int(img.CropY.Int32)
+			crop = &cropSpec{Rect: image.Rect(x, y, x+int(img.CropWidth.Int32), y+int(img.CropHeight.Int32))}
 		}
 
-		var res bytes.Buffer
-		err = jpeg.Encode(&res, dst, &jpeg.Options{
-			Quality: 50,
-		})
+		opts := renderOptions{
+			Position:         img.WatermarkPosition,
+			Placement:        place,
+			Redactions:       redactions,
+			Crop:             crop,
+			Pipeline:         pipeline,
+			Scale:            int(img.WatermarkScale),
+			TileSpacing:      int(img.WatermarkTileSpacing),
+			MaxWidth:         int(img.MaxWidth.Int32),
+			MaxHeight:        int(img.MaxHeight.Int32),
+			PreserveMetadata: img.PreserveMetadata,
+			Format:           img.OutputFormat,
+			Quality:          int(img.OutputQuality),
+			Progressive:      img.JpegProgressive,
+			Subsampling:      img.JpegSubsampling,
+			PNGCompression:   img.PngCompression,
+			Transforms:       transforms,
+		}
+		if img.WatermarkPositionOverride.Valid {
+			opts.Position = img.WatermarkPositionOverride.WatermarkPosition
+		}
+		opacity := int(img.WatermarkOpacity)
+		if img.WatermarkOpacityOverride.Valid {
+			opacity = int(img.WatermarkOpacityOverride.Int32)
+		}
+		opts.Opacity = &opacity
+		if img.WatermarkScaleOverride.Valid {
+			opts.Scale = int(img.WatermarkScaleOverride.Int32)
+		}
+		if img.InvisibleWatermark {
+			if len(cfg.FingerprintKey) == 0 {
+				log.Printf("image %s needs an invisible watermark but FINGERPRINT_KEY is not set, discarding message", m.ImageID)
+				return failTask(db, dbQueries, m, "invisible watermarks are not enabled on this server")
+			}
+			opts.Fingerprint = fingerprintPayload(cfg.FingerprintKey, img.BatchID, img.UserID)
+		}
+		if m.OutputFormat != "" {
+			opts.Format = m.OutputFormat
+		}
+		if m.OutputQuality != 0 {
+			opts.Quality = m.OutputQuality
+		}
+
+		res, err := process(context.Background(), data, watermarkImg, opts)
+		release()
+		if errors.Is(err, ErrInvalidMedia) {
+			log.Printf("error process media, discarding message: %v", err)
+			return failTask(db, dbQueries, m, err.Error())
+		}
+		// Requeued images keep the processing status their slot claim set.
 		if err != nil {
-			log.Printf("error encode image, requeuing: %v", err)
-			dbQueries.UpdateImageByID(context.Background(), database.UpdateImageByIDParams{
-				ID:     m.ImageID,
-				Status: database.ImageStatusProcessing,
-			})
-			return pubsub.NackRequeue
+			log.Printf("error encode media, requeuing: %v", err)
+			return pubsub.NackRequeue, false
 		}
 
-		mediaType := "image/jpeg"
-		assetPath := utils.GetAssetPath(mediaType)
-		fileName := "processed/" + assetPath
-		_, err = cfg.S3Client.PutObject(context.Background(), &s3.PutObjectInput{
-			Bucket:      aws.String(cfg.S3Bucket),
-			Key:         aws.String(fileName),
-			Body:        &res,
-			ContentType: aws.String(mediaType),
-		})
+		fileName, err := putProcessedImage(context.Background(), cfg, img, res.Data, res.MediaType)
+		if errors.Is(err, ErrOutputKeyExists) {
+			log.Printf("error uploading processed image, discarding message: %v", err)
+			return failTask(db, dbQueries, m, err.Error())
+		}
 		if err != nil {
 			log.Printf("error uploading processed image, requeuing: %v", err)
-			dbQueries.UpdateImageByID(context.Background(), database.UpdateImageByIDParams{
-				ID:     m.ImageID,
-				Status: database.ImageStatusProcessing,
-			})
-			return pubsub.NackRequeue
+			return pubsub.NackRequeue, false
 		}
 
-		objectURL := utils.GetObjectURL(cfg.S3CfDistribution, fileName)
-		dbQueries.UpdateImageByID(context.Background(), database.UpdateImageByIDParams{
-			ID:           img.ID,
-			ProcessedUrl: sql.NullString{String: objectURL, Valid: true},
-			Status:       database.ImageStatusCompleted,
+		// Everything learned while processing is written at once, so a
+		// successful image costs one write besides claiming its slot.
+		completed := database.CompleteImageByIDParams{
+			ID:                    img.ID,
+			ProcessedUrl:          sql.NullString{String: utils.GetObjectURL(cfg.S3CfDistribution, fileName), Valid: true},
+			ProcessedSize:         sql.NullInt64{Int64: int64(len(res.Data)), Valid: true},
+			ProcessedFormat:       sql.NullString{String: res.MediaType, Valid: true},
+			OriginalSize:          sql.NullInt64{Int64: int64(len(data)), Valid: true},
+			OriginalFormat:        sql.NullString{String: http.DetectContentType(data), Valid: true},
+			OriginalDominantColor: sql.NullString{String: res.OriginalColor, Valid: res.OriginalColor != ""},
+			StepTimings:           json.RawMessage("[]"),
+			CapturedAt:            captureTime(data),
+		}
+		if len(res.StepTimings) > 0 {
+			completed.StepTimings, err = json.Marshal(res.StepTimings)
+			if err != nil {
+				log.Printf("error encode step timings: %v", err)
+				completed.StepTimings = json.RawMessage("[]")
+			}
+		}
+		if res.PHash != nil {
+			completed.Phash = sql.NullInt64{Int64: int64(*res.PHash), Valid: true}
+		}
+		if res.OriginalWidth > 0 {
+			completed.OriginalWidth = sql.NullInt32{Int32: int32(res.OriginalWidth), Valid: true}
+			completed.OriginalHeight = sql.NullInt32{Int32: int32(res.OriginalHeight), Valid: true}
+		}
+		if res.Width > 0 {
+			completed.Width = sql.NullInt32{Int32: int32(res.Width), Valid: true}
+			completed.Height = sql.NullInt32{Int32: int32(res.Height), Valid: true}
+			// The image is still usable without a thumbnail, so a failed
+			// upload only leaves thumbnail_url empty.
+			if len(res.Thumbnail) > 0 {
+				thumbKey, err := putThumbnail(context.Background(), cfg, fileName, res.Thumbnail, res.MediaType)
+				if err != nil {
+					log.Printf("error uploading thumbnail: %v", err)
+				} else {
+					completed.ThumbnailUrl = sql.NullString{String: utils.GetObjectURL(cfg.S3CfDistribution, thumbKey), Valid: true}
+				}
+			}
+			if res.Colors != nil {
+				completed.Palette = res.Colors.Palette
+				completed.Blurhash = sql.NullString{String: res.Colors.BlurHash, Valid: true}
+				completed.Thumbhash = sql.NullString{String: res.Colors.ThumbHash, Valid: true}
+			}
+			if res.WatermarkPosition != "" {
+				completed.AppliedWatermarkPosition = database.NullWatermarkPosition{WatermarkPosition: res.WatermarkPosition, Valid: true}
+			}
+		}
+		err = finishTask(context.Background(), db, dbQueries, m, database.TaskOutcomeCompleted, func(q *database.Queries) error {
+			return q.CompleteImageByID(context.Background(), completed)
 		})
+		if errors.Is(err, errTaskRecorded) {
+			log.Printf("task %s of image %s was finished by another delivery", m.TaskID, m.ImageID)
+		} else if err != nil {
+			log.Printf("error complete image, requeuing: %v", err)
+		} else {
+			log.Printf("%s processed", fileName)
+		}
+		return finishedAck(err, pubsub.Ack)
+	}
+}
 
-		log.Printf("%s processed", fileName)
-		return pubsub.Ack
+// linkSimilarImage completes img with the processed files of an image that
+// looks the same and was rendered with the same settings, instead of
+// processing it again. Images with their own watermark settings or
+// redactions are always processed. It reports whether the task is done, and
+// whether that was by another delivery of it that finished first. Failing to
+// find or link one is not an error, the image is then processed as usual.
+func linkSimilarImage(ctx context.Context, db *sql.DB, dbQueries *database.Queries, m batch.ImageTask, img database.GetImageByIDRow, data []byte) (done, replay bool) {
+	if img.WatermarkPositionOverride.Valid || img.WatermarkOpacityOverride.Valid || img.WatermarkScaleOverride.Valid || img.PlacementX.Valid || string(img.Redactions) != "[]" {
+		return false, false
 	}
+	info, ok := inspectOriginal(data)
+	if !ok {
+		return false, false
+	}
+
+	source, err := dbQueries.GetSimilarImage(ctx, database.GetSimilarImageParams{
+		BatchID:     img.BatchID,
+		ID:          img.ID,
+		Phash:       int64(info.PHash),
+		MaxDistance: maxPHashDistance,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("error get similar image: %v", err)
+		}
+		return false, false
+	}
+
+	err = finishTask(ctx, db, dbQueries, m, database.TaskOutcomeLinked, func(q *database.Queries) error {
+		linked, err := q.LinkSimilarImageByID(ctx, database.LinkSimilarImageByIDParams{
+			ID:                    img.ID,
+			SimilarTo:             source,
+			Phash:                 sql.NullInt64{Int64: int64(info.PHash), Valid: true},
+			OriginalWidth:         sql.NullInt32{Int32: int32(info.Width), Valid: true},
+			OriginalHeight:        sql.NullInt32{Int32: int32(info.Height), Valid: true},
+			OriginalSize:          sql.NullInt64{Int64: int64(len(data)), Valid: true},
+			OriginalFormat:        sql.NullString{String: http.DetectContentType(data), Valid: true},
+			OriginalDominantColor: sql.NullString{String: info.Color, Valid: info.Color != ""},
+			CapturedAt:            captureTime(data),
+		})
+		if err == nil && linked == 0 {
+			err = errNotLinked
+		}
+		return err
+	})
+	if errors.Is(err, errTaskRecorded) {
+		log.Printf("task %s of image %s was finished by another delivery", m.TaskID, m.ImageID)
+		return true, true
+	}
+	if err != nil {
+		if !errors.Is(err, errNotLinked) {
+			log.Printf("error link similar image: %v", err)
+		}
+		return false, false
+	}
+	log.Printf("image %s shares the processed files of similar image %s", img.ID, source)
+	return true, false
 }
+
+// errNotLinked means the image or its similar image changed before they
+// could be linked.
+var errNotLinked = errors.New("similar image not linked")
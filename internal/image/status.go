@@ -0,0 +1,48 @@
+package image
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// publishStatus tells the servers img changed status, for the sockets
+// watching its batch. Clients load the batch again when they reconnect, so
+// an event that cannot be published is only logged.
+func publishStatus(cfg *utils.Config, img database.GetImageByIDRow) {
+	ch, err := cfg.RabbitMQ.Get()
+	if err != nil {
+		log.Printf("error open channel for status of image %s: %v", img.ID, err)
+		return
+	}
+	defer cfg.RabbitMQ.Put(ch)
+
+	err = pubsub.PublishJSON(ch, utils.ImageGoDirect, utils.ImageGoStatus, batch.ImageStatusEvent{
+		ImageID:       img.ID,
+		BatchID:       img.BatchID,
+		UserID:        img.UserID,
+		Status:        batch.NewImageStatus(img.Status),
+		FailureReason: img.FailureReason.String,
+		ProcessedURL:  img.ProcessedUrl.String,
+		UpdatedAt:     img.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("error publish status of image %s: %v", img.ID, err)
+	}
+}
+
+// publishStoredStatus publishes the status of imageID as stored, once a task
+// is done with it.
+func publishStoredStatus(ctx context.Context, dbQueries *database.Queries, cfg *utils.Config, imageID uuid.UUID) {
+	img, err := dbQueries.GetImageByID(ctx, imageID)
+	if err != nil {
+		// Deleted images have nobody left to tell.
+		return
+	}
+	publishStatus(cfg, img)
+}
@@ -0,0 +1,140 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// thumbHashMaxSize is the largest side ThumbHash encodes; bigger inputs are
+// slower without a better result.
+const thumbHashMaxSize = 100
+
+// encodeThumbHash returns the ThumbHash of img, following the reference
+// encoder at https://github.com/evanw/thumbhash. Unlike BlurHash it keeps the
+// aspect ratio and transparency of the image.
+func encodeThumbHash(img *image.RGBA) ([]byte, error) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w == 0 || h == 0 || w > thumbHashMaxSize || h > thumbHashMaxSize {
+		return nil, fmt.Errorf("%dx%d doesn't fit in %dx%d", w, h, thumbHashMaxSize, thumbHashMaxSize)
+	}
+	round := func(v float64) int { return int(math.Floor(v + 0.5)) }
+
+	// Average color, weighted by alpha.
+	var avgR, avgG, avgB, avgA float64
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			alpha := float64(c.A) / 255
+			avgR += alpha / 255 * float64(c.R)
+			avgG += alpha / 255 * float64(c.G)
+			avgB += alpha / 255 * float64(c.B)
+			avgA += alpha
+		}
+	}
+	if avgA > 0 {
+		avgR /= avgA
+		avgG /= avgA
+		avgB /= avgA
+	}
+
+	hasAlpha := avgA < float64(w*h)
+	lLimit := 7
+	if hasAlpha {
+		// Fewer luminance components leave room for the alpha channel.
+		lLimit = 5
+	}
+	lx := max(1, round(float64(lLimit*w)/float64(max(w, h))))
+	ly := max(1, round(float64(lLimit*h)/float64(max(w, h))))
+
+	// Convert to luminance, yellow-blue, red-green and alpha, composited over
+	// the average color.
+	n := w * h
+	l, p, q, a := make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.RGBAAt(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+			alpha := float64(c.A) / 255
+			r := avgR*(1-alpha) + alpha/255*float64(c.R)
+			g := avgG*(1-alpha) + alpha/255*float64(c.G)
+			b := avgB*(1-alpha) + alpha/255*float64(c.B)
+			i := x + y*w
+			l[i] = (r + g + b) / 3
+			p[i] = (r+g)/2 - b
+			q[i] = r - g
+			a[i] = alpha
+		}
+	}
+
+	// encodeChannel runs a DCT over channel and returns the constant term,
+	// the varying terms normalized to 0..1 and their scale.
+	encodeChannel := func(channel []float64, nx, ny int) (float64, []float64, float64) {
+		var dc, scale float64
+		var ac []float64
+		fx := make([]float64, w)
+		for cy := 0; cy < ny; cy++ {
+			for cx := 0; cx*ny < nx*(ny-cy); cx++ {
+				for x := 0; x < w; x++ {
+					fx[x] = math.Cos(math.Pi / float64(w) * float64(cx) * (float64(x) + 0.5))
+				}
+				var f float64
+				for y := 0; y < h; y++ {
+					fy := math.Cos(math.Pi / float64(h) * float64(cy) * (float64(y) + 0.5))
+					for x := 0; x < w; x++ {
+						f += channel[x+y*w] * fx[x] * fy
+					}
+				}
+				f /= float64(n)
+				if cx > 0 || cy > 0 {
+					ac = append(ac, f)
+					scale = max(scale, math.Abs(f))
+				} else {
+					dc = f
+				}
+			}
+		}
+		if scale > 0 {
+			for i := range ac {
+				ac[i] = 0.5 + 0.5/scale*ac[i]
+			}
+		}
+		return dc, ac, scale
+	}
+	lDC, lAC, lScale := encodeChannel(l, max(3, lx), max(3, ly))
+	pDC, pAC, pScale := encodeChannel(p, 3, 3)
+	qDC, qAC, qScale := encodeChannel(q, 3, 3)
+
+	isLandscape := w > h
+	header24 := round(63*lDC) | round(31.5+31.5*pDC)<<6 | round(31.5+31.5*qDC)<<12 | round(31*lScale)<<18
+	header16 := round(63*pScale)<<3 | round(63*qScale)<<9
+	if hasAlpha {
+		header24 |= 1 << 23
+	}
+	if isLandscape {
+		header16 |= ly | 1<<15
+	} else {
+		header16 |= lx
+	}
+	hash := []byte{byte(header24), byte(header24 >> 8), byte(header24 >> 16), byte(header16), byte(header16 >> 8)}
+
+	acs := [][]float64{lAC, pAC, qAC}
+	if hasAlpha {
+		aDC, aAC, aScale := encodeChannel(a, 5, 5)
+		hash = append(hash, byte(round(15*aDC)|round(15*aScale)<<4))
+		acs = append(acs, aAC)
+	}
+
+	// Pack the varying terms as 4-bit values, two per byte.
+	acIndex := 0
+	for _, ac := range acs {
+		for _, f := range ac {
+			if acIndex&1 == 0 {
+				hash = append(hash, byte(round(15*f)))
+			} else {
+				hash[len(hash)-1] |= byte(round(15*f) << 4)
+			}
+			acIndex++
+		}
+	}
+	return hash, nil
+}
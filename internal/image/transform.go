@@ -0,0 +1,135 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"golang.org/x/image/draw"
+)
+
+// applyTransforms runs the batch's transforms over a rendered image in
+// order. Each step that adds canvas fills it with the step's color. Results
+// over maxImagePixels fail with ErrImageTooLarge.
+func applyTransforms(img *image.RGBA, transforms []batch.Transform) (*image.RGBA, error) {
+	for _, t := range transforms {
+		size := img.Bounds().Size()
+		var canvas, dst image.Rectangle
+		switch t.Type {
+		case batch.TransformBorder:
+			canvas = image.Rect(0, 0, size.X+2*t.Size, size.Y+2*t.Size)
+			dst = image.Rect(t.Size, t.Size, t.Size+size.X, t.Size+size.Y)
+		case batch.TransformPad:
+			rw, rh, ok := t.Ratio()
+			if !ok {
+				return nil, fmt.Errorf("invalid aspect ratio %q", t.AspectRatio)
+			}
+			w, h := size.X, size.Y
+			if w*rh > h*rw {
+				h = (w*rh + rw - 1) / rw
+			} else {
+				w = (h*rw + rh - 1) / rh
+			}
+			canvas = image.Rect(0, 0, w, h)
+			dst = centered(canvas, size)
+		case batch.TransformLetterbox:
+			canvas = image.Rect(0, 0, t.Width, t.Height)
+			ratio := min(float64(t.Width)/float64(size.X), float64(t.Height)/float64(size.Y))
+			dst = centered(canvas, image.Pt(
+				min(t.Width, max(1, int(float64(size.X)*ratio+0.5))),
+				min(t.Height, max(1, int(float64(size.Y)*ratio+0.5))),
+			))
+		default:
+			return nil, fmt.Errorf("unknown transform %q", t.Type)
+		}
+		if canvas.Dx()*canvas.Dy() > maxImagePixels {
+			return nil, ErrImageTooLarge
+		}
+
+		fill, err := parseHexColor(t.Color)
+		if err != nil {
+			return nil, err
+		}
+		out := image.NewRGBA(canvas)
+		draw.Draw(out, canvas, image.NewUniform(fill), image.Point{}, draw.Src)
+		if dst.Size() == size {
+			draw.Draw(out, dst, img, img.Bounds().Min, draw.Over)
+		} else {
+			draw.CatmullRom.Scale(out, dst, img, img.Bounds(), draw.Over, nil)
+		}
+		img = out
+	}
+	return img, nil
+}
+
+// cropSpec is the batch crop: centered to Ratio (width:height) when it is
+// set, otherwise to Rect.
+type cropSpec struct {
+	Ratio image.Point
+	Rect  image.Rectangle
+}
+
+// cropImage cuts spec out of img into a new image at the origin. Rectangles
+// are clipped to the image and fail with ErrInvalidMedia when they miss it
+// entirely.
+func cropImage(img image.Image, spec *cropSpec) (image.Image, error) {
+	if spec == nil {
+		return img, nil
+	}
+	b := img.Bounds()
+	var r image.Rectangle
+	if spec.Ratio.X > 0 && spec.Ratio.Y > 0 {
+		w, h := b.Dx(), b.Dy()
+		if w*spec.Ratio.Y > h*spec.Ratio.X {
+			w = max(1, h*spec.Ratio.X/spec.Ratio.Y)
+		} else {
+			h = max(1, w*spec.Ratio.Y/spec.Ratio.X)
+		}
+		r = centered(b, image.Pt(w, h))
+	} else {
+		r = spec.Rect.Add(b.Min).Intersect(b)
+		if r.Empty() {
+			return nil, fmt.Errorf("%w: crop rectangle %v is outside the %dx%d image", ErrInvalidMedia, spec.Rect, b.Dx(), b.Dy())
+		}
+	}
+	if r == b {
+		return img, nil
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst, nil
+}
+
+// centered returns a rectangle of the given size in the middle of canvas.
+func centered(canvas image.Rectangle, size image.Point) image.Rectangle {
+	origin := canvas.Min.Add(canvas.Size().Sub(size).Div(2))
+	return image.Rectangle{Min: origin, Max: origin.Add(size)}
+}
+
+// parseHexColor parses #rgb, #rgba, #rrggbb or #rrggbbaa; empty is white.
+func parseHexColor(s string) (color.Color, error) {
+	if s == "" {
+		return color.White, nil
+	}
+	if s[0] != '#' {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	hex := s[1:]
+	if len(hex) == 3 || len(hex) == 4 {
+		expanded := make([]byte, 0, 2*len(hex))
+		for i := range len(hex) {
+			expanded = append(expanded, hex[i], hex[i])
+		}
+		hex = string(expanded)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
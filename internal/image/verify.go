@@ -0,0 +1,88 @@
+package image
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// maxVerifySize bounds files uploaded for invisible watermark verification.
+const maxVerifySize = 20 << 20
+
+// Verify godoc
+// @Summary Verify invisible watermark
+// @Description Extract the invisible watermark from an image file and check that it was made by this server and that the embedded batch exists and belongs to the embedded user. The batch and user IDs are only returned when the batch is yours. The file must be a processed image at its original size; resized or cropped copies lose the watermark
+// @Tags images
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Image file (JPEG, PNG or WebP)"
+// @Success 200 {object} utils.SuccessResponse{data=VerifyImageResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 413 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /images/verify [post]
+func (h *ImageHandler) Verify(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	if len(h.config.FingerprintKey) == 0 {
+		return utils.RespondError(c, http.StatusServiceUnavailable, "invisible watermarks are not enabled on this server")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "no image file uploaded")
+	}
+	if file.Size > maxVerifySize {
+		return utils.RespondError(c, http.StatusRequestEntityTooLarge, "image file too large")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxVerifySize+1))
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if len(data) > maxVerifySize {
+		return utils.RespondError(c, http.StatusRequestEntityTooLarge, "image file too large")
+	}
+
+	img, err := decodeImage(data)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid image file")
+	}
+
+	var res VerifyImageResponse
+	if payload, ok := extractFingerprint(img); ok {
+		batchID, markedUserID, ok := parseFingerprint(h.config.FingerprintKey, payload)
+		if ok {
+			res.Found = true
+
+			owner, err := h.dbQueries.GetBatchOwnerByID(c.Request().Context(), batchID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+			}
+			res.Valid = err == nil && owner == markedUserID
+			// Other users only learn whether the mark is genuine, not whose
+			// batch it names.
+			if res.Valid && owner == userID {
+				res.Owned = true
+				res.BatchID = &batchID
+				res.UserID = &markedUserID
+			}
+		}
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "image verified successfully", res)
+}
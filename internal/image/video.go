@@ -0,0 +1,121 @@
+//go:build video
+
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+// videoTimeout bounds a single ffmpeg run so a pathological upload cannot
+// block the consumer.
+const videoTimeout = 5 * time.Minute
+
+// EnableVideoProcessing routes short videos and GIFs to the ffmpeg-based
+// processor, which outputs H.264 MP4 instead of an animated GIF. It must be called before the worker
+// subscribes and fails when ffmpeg is not on PATH.
+func EnableVideoProcessing() error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("video processing needs ffmpeg: %w", err)
+	}
+	for _, mediaType := range []string{"video/mp4", "video/webm", "image/gif"} {
+		mediaProcessors[mediaType] = processVideo
+	}
+	return nil
+}
+
+func processVideo(ctx context.Context, data []byte, watermark image.Image, opts renderOptions) (processedMedia, error) {
+	if len(opts.Redactions) > 0 {
+		return processedMedia{}, fmt.Errorf("%w: videos cannot be redacted", ErrInvalidMedia)
+	}
+	ctx, cancel := context.WithTimeout(ctx, videoTimeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "image-go-video-*")
+	if err != nil {
+		return processedMedia{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return processedMedia{}, err
+	}
+	output := filepath.Join(dir, "output.mp4")
+
+	// libx264 with yuv420p needs even dimensions, which GIFs often lack.
+	const evenSize = "scale=trunc(iw/2)*2:trunc(ih/2)*2,format=yuv420p"
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", input}
+	if watermark != nil {
+		watermarkPath := filepath.Join(dir, "watermark.png")
+		if err := writePNG(watermarkPath, watermark); err != nil {
+			return processedMedia{}, err
+		}
+		args = append(args, "-i", watermarkPath, "-filter_complex", overlayFilter(opts)+","+evenSize)
+	} else {
+		args = append(args, "-vf", evenSize)
+	}
+	args = append(args, "-c:v", "libx264", "-c:a", "aac", "-movflags", "+faststart", "-y", output)
+
+	out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return processedMedia{}, fmt.Errorf("%w: ffmpeg: %s", ErrInvalidMedia, strings.TrimSpace(string(out)))
+		}
+		return processedMedia{}, fmt.Errorf("ffmpeg: %w", err)
+	}
+
+	res, err := os.ReadFile(output)
+	if err != nil {
+		return processedMedia{}, err
+	}
+	return processedMedia{Data: res, MediaType: "video/mp4"}, nil
+}
+
+// overlayFilter scales the watermark relative to the video width and places
+// it like the still pipeline does. Tiled, diagonal and auto placement are not
+// supported for video and fall back to bottom-right.
+func overlayFilter(opts renderOptions) string {
+	scale := opts.Scale
+	if scale <= 0 || scale > 100 {
+		scale = defaultWatermarkScale
+	}
+	width, x, y := fmt.Sprintf("%.2f", float64(scale)/100), "W-w-H*0.01", "H-h-H*0.01"
+	if opts.Placement != nil {
+		width = fmt.Sprintf("%f", opts.Placement.Width)
+		x = fmt.Sprintf("W*%f", opts.Placement.X)
+		y = fmt.Sprintf("H*%f", opts.Placement.Y)
+	} else {
+		switch opts.Position {
+		case database.WatermarkPositionTopLeft:
+			x, y = "H*0.01", "H*0.01"
+		case database.WatermarkPositionTopRight:
+			x, y = "W-w-H*0.01", "H*0.01"
+		case database.WatermarkPositionBottomLeft:
+			x, y = "H*0.01", "H-h-H*0.01"
+		case database.WatermarkPositionCenter:
+			x, y = "(W-w)/2", "(H-h)/2"
+		}
+	}
+	return fmt.Sprintf("[1:v][0:v]scale2ref=w=main_w*%s:h=ow/mdar[wm][base];[wm]format=rgba,colorchannelmixer=aa=%.2f[wma];[base][wma]overlay=x=%s:y=%s", width, float64(opts.opacity())/100, x, y)
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
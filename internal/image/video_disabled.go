@@ -0,0 +1,11 @@
+//go:build !video
+
+package image
+
+import "errors"
+
+// EnableVideoProcessing reports that this worker was built without video
+// support. Build with -tags video to include the ffmpeg-based processor.
+func EnableVideoProcessing() error {
+	return errors.New("worker built without video support, rebuild with -tags video")
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// Admin must run after Authenticated and only lets users flagged as admin through.
+func Admin(dbQueries *database.Queries) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID := c.Get("userID").(uuid.UUID)
+			user, err := dbQueries.GetUserByID(c.Request().Context(), userID)
+			if err != nil || !user.IsAdmin {
+				return utils.RespondError(c, http.StatusForbidden, "forbidden")
+			}
+			return next(c)
+		}
+	}
+}
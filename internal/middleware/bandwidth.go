@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+)
+
+const minUploadBurst = 32 << 10
+
+type userLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// UploadBandwidthLimit throttles request bodies to bytesPerSecond per authenticated user, shared across
+// the user's concurrent uploads. It must run after Authenticated. A non-positive limit disables throttling.
+func UploadBandwidthLimit(bytesPerSecond int) echo.MiddlewareFunc {
+	var mu sync.Mutex
+	limiters := make(map[uuid.UUID]*userLimiter)
+	burst := max(bytesPerSecond, minUploadBurst)
+
+	getLimiter := func(userID uuid.UUID) *rate.Limiter {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		for id, l := range limiters {
+			if now.Sub(l.lastSeen) > 10*time.Minute {
+				delete(limiters, id)
+			}
+		}
+		l, ok := limiters[userID]
+		if !ok {
+			l = &userLimiter{limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), burst)}
+			limiters[userID] = l
+		}
+		l.lastSeen = now
+		return l.limiter
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if bytesPerSecond <= 0 {
+				return next(c)
+			}
+			userID := c.Get("userID").(uuid.UUID)
+			req := c.Request()
+			req.Body = &rateLimitedReader{
+				ctx:     req.Context(),
+				body:    req.Body,
+				limiter: getLimiter(userID),
+			}
+			return next(c)
+		}
+	}
+}
+
+type rateLimitedReader struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+	n, err := r.body.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *rateLimitedReader) Close() error {
+	return r.body.Close()
+}
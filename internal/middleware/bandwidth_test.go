@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadBandwidthLimit(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	newServer := func(bytesPerSecond int) *echo.Echo {
+		e := echo.New()
+		e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Set("userID", uuid.MustParse(c.Request().Header.Get("X-User")))
+				return next(c)
+			}
+		})
+		read := func(c echo.Context) error {
+			if _, err := io.Copy(io.Discard, c.Request().Body); err != nil {
+				return err
+			}
+			return c.NoContent(http.StatusOK)
+		}
+		e.POST("/batches", read, UploadBandwidthLimit(bytesPerSecond))
+		return e
+	}
+	upload := func(e *echo.Echo, userID uuid.UUID) time.Duration {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/batches", bytes.NewReader(make([]byte, minUploadBurst)))
+		req.Header.Set("X-User", userID.String())
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return time.Since(start)
+	}
+
+	t.Run("throttled", func(t *testing.T) {
+		// The first upload spends the burst; the next one of the same user
+		// waits about a second.
+		e := newServer(minUploadBurst)
+		assert.Less(t, upload(e, alice), 500*time.Millisecond)
+		assert.GreaterOrEqual(t, upload(e, alice), 500*time.Millisecond)
+	})
+
+	t.Run("per user", func(t *testing.T) {
+		e := newServer(minUploadBurst)
+		assert.Less(t, upload(e, alice), 500*time.Millisecond)
+		assert.Less(t, upload(e, bob), 500*time.Millisecond)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		e := newServer(0)
+		for range 3 {
+			assert.Less(t, upload(e, alice), 500*time.Millisecond)
+		}
+	})
+}
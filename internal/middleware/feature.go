@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// FeatureFlag must run after Authenticated and hides an experimental route
+// behind flag: it answers 404 as if the route did not exist unless the flag
+// is enabled for everyone through the config or for the user.
+func FeatureFlag(dbQueries *database.Queries, config *utils.Config, flag string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if slices.Contains(config.FeatureFlags, flag) {
+				return next(c)
+			}
+			userID := c.Get("userID").(uuid.UUID)
+			enabled, err := dbQueries.HasUserFeatureFlag(c.Request().Context(), database.HasUserFeatureFlagParams{
+				UserID: userID,
+				Flag:   flag,
+			})
+			if err != nil {
+				return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+			}
+			if !enabled {
+				return echo.ErrNotFound
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// ReasonIPNotAllowed is the auth event recorded for requests rejected by
+// IPAllowlist.
+const ReasonIPNotAllowed = "ip_not_allowed"
+
+// IPAllowlist must run after Authenticated or UploadAuthenticated and rejects
+// requests of users with an IP allowlist that come from outside of it. Users
+// without entries are not restricted. The client address is c.RealIP(), so
+// it is only as trustworthy as the server's TRUSTED_PROXIES.
+func IPAllowlist(dbQueries *database.Queries) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID := c.Get("userID").(uuid.UUID)
+			cidrs, err := dbQueries.GetUserAllowedCIDRs(c.Request().Context(), userID)
+			if err != nil {
+				return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+			}
+			if len(cidrs) == 0 || utils.IPAllowed(c.RealIP(), cidrs) {
+				return next(c)
+			}
+
+			err = dbQueries.CreateAuthEvent(c.Request().Context(), database.CreateAuthEventParams{
+				UserID:    uuid.NullUUID{UUID: userID, Valid: true},
+				Reason:    ReasonIPNotAllowed,
+				IpAddress: sql.NullString{String: c.RealIP(), Valid: c.RealIP() != ""},
+			})
+			if err != nil {
+				c.Logger().Errorf("failed to record auth event: %v", err)
+			}
+			return utils.RespondError(c, http.StatusForbidden, "access from this IP address is not allowed")
+		}
+	}
+}
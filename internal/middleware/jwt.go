@@ -14,11 +14,51 @@ func Authenticated(config *utils.Config) echo.MiddlewareFunc {
 			if err != nil {
 				return utils.RespondError(c, http.StatusUnauthorized, err.Error())
 			}
-			userID, err := utils.ValidateJWT(token, config.JwtSecret)
+			userID, expiresAt, err := utils.ValidateJWTExpiry(token, config.JwtSecret)
 			if err != nil {
 				return utils.RespondError(c, http.StatusUnauthorized, err.Error())
 			}
 			c.Set("userID", userID)
+			c.Set("tokenExpiresAt", expiresAt)
+			return next(c)
+		}
+	}
+}
+
+// SocketAuthenticated accepts an access token in the Authorization header, or a socket ticket in the
+// ticket query parameter for clients that cannot set headers. tokenExpiresAt is when the access token,
+// or the one the ticket was issued for, expires.
+func SocketAuthenticated(config *utils.Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if ticket := c.QueryParam("ticket"); ticket != "" {
+				userID, expiresAt, err := utils.ValidateSocketTicket(ticket, config.JwtSecret)
+				if err != nil {
+					return utils.RespondError(c, http.StatusUnauthorized, err.Error())
+				}
+				c.Set("userID", userID)
+				c.Set("tokenExpiresAt", expiresAt)
+				return next(c)
+			}
+			return Authenticated(config)(next)(c)
+		}
+	}
+}
+
+// UploadAuthenticated accepts only batch-scoped upload tokens and exposes the user and batch IDs they grant.
+func UploadAuthenticated(config *utils.Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, err := utils.GetAuthorizationToken(c.Request().Header)
+			if err != nil {
+				return utils.RespondError(c, http.StatusUnauthorized, err.Error())
+			}
+			userID, batchID, err := utils.ValidateUploadJWT(token, config.JwtSecret)
+			if err != nil {
+				return utils.RespondError(c, http.StatusUnauthorized, err.Error())
+			}
+			c.Set("userID", userID)
+			c.Set("batchID", batchID)
 			return next(c)
 		}
 	}
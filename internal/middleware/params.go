@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// UUIDParams parses every path parameter named like batchID or imageID as a
+// UUID before the handler runs, for utils.ParamUUID. A malformed one fails the
+// request with 400 Bad Request, "invalid batch ID" and the parameter in
+// errors.
+func UUIDParams() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var message string
+			var errs []utils.FieldError
+			for _, name := range c.ParamNames() {
+				if !strings.HasSuffix(name, "ID") {
+					continue
+				}
+				id, err := uuid.Parse(c.Param(name))
+				if err != nil {
+					if message == "" {
+						message = "invalid " + strings.TrimSuffix(name, "ID") + " ID"
+					}
+					errs = append(errs, utils.FieldError{Field: name, Message: "must be a UUID"})
+					continue
+				}
+				utils.SetParamUUID(c, name, id)
+			}
+			if len(errs) > 0 {
+				return utils.RespondFieldErrors(c, http.StatusBadRequest, message, errs)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDParams(t *testing.T) {
+	batchID := uuid.New()
+	imageID := uuid.New()
+
+	e := echo.New()
+	var gotBatch, gotImage uuid.UUID
+	var gotFlag string
+	e.GET("/batches/:batchID/images/:imageID/:flag", func(c echo.Context) error {
+		gotBatch = utils.ParamUUID(c, "batchID")
+		gotImage = utils.ParamUUID(c, "imageID")
+		gotFlag = c.Param("flag")
+		return c.NoContent(http.StatusOK)
+	}, UUIDParams())
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		message string
+		fields  []string
+	}{
+		{
+			name:   "valid",
+			path:   "/batches/" + batchID.String() + "/images/" + imageID.String() + "/beta",
+			status: http.StatusOK,
+		},
+		{
+			name:    "malformed",
+			path:    "/batches/nope/images/" + imageID.String() + "/beta",
+			status:  http.StatusBadRequest,
+			message: "invalid batch ID",
+			fields:  []string{"batchID"},
+		},
+		{
+			name:    "several malformed",
+			path:    "/batches/nope/images/nope/beta",
+			status:  http.StatusBadRequest,
+			message: "invalid batch ID",
+			fields:  []string{"batchID", "imageID"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBatch, gotImage, gotFlag = uuid.Nil, uuid.Nil, ""
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, batchID, gotBatch)
+				assert.Equal(t, imageID, gotImage)
+				assert.Equal(t, "beta", gotFlag, "parameters not named like IDs are left alone")
+				return
+			}
+
+			var body utils.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.message, body.Message)
+			var fields []string
+			for _, fe := range body.Errors {
+				fields = append(fields, fe.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+			assert.Equal(t, uuid.Nil, gotBatch, "the handler does not run")
+		})
+	}
+}
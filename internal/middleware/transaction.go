@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+// Transaction runs the handler inside a database transaction carried by the request context (see
+// utils.Queries). It commits when the handler succeeds with a 2xx/3xx response and rolls back otherwise.
+// The response is held back until the commit succeeds, so clients never see a success that was lost.
+func Transaction(db *sql.DB) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return InTransaction(c, db, next)
+		}
+	}
+}
+
+// InTransaction runs fn the way Transaction runs a handler, for handlers that do slow work such as
+// storage uploads before they open their transaction. When fn succeeds without writing a response, the
+// caller writes it after the commit.
+func InTransaction(c echo.Context, db *sql.DB, fn echo.HandlerFunc) error {
+	req := c.Request()
+	sqlTx, err := db.BeginTx(req.Context(), nil)
+	if err != nil {
+		c.Logger().Errorf("failed to begin transaction: %v", err)
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	tx := &utils.Tx{Tx: sqlTx}
+	c.SetRequest(req.WithContext(utils.ContextWithTx(req.Context(), tx)))
+	defer c.SetRequest(req)
+
+	res := c.Response()
+	w := res.Writer
+	buf := &bufferedResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	res.Writer = buf
+
+	err = fn(c)
+	res.Writer = w
+
+	if err != nil || buf.status >= http.StatusBadRequest {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			c.Logger().Errorf("failed to roll back transaction: %v", rbErr)
+		}
+		if res.Committed {
+			if flushErr := buf.flush(); flushErr != nil {
+				c.Logger().Errorf("failed to write response: %v", flushErr)
+			}
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.Logger().Errorf("failed to commit transaction: %v", err)
+		res.Committed = false
+		res.Size = 0
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if !res.Committed {
+		return nil
+	}
+	return buf.flush()
+}
+
+// bufferedResponseWriter holds the status and body until the transaction outcome is known.
+// Headers are written straight to the underlying writer, which only sends them on WriteHeader.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponseWriter) flush() error {
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	return err
+}
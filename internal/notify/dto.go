@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of the messages sent to sockets.
+const (
+	MessageImageStatus   = "image.status"
+	MessageBatchProgress = "batch.progress"
+	MessageSubscribed    = "subscribed"
+	MessageUnsubscribed  = "unsubscribed"
+	MessageError         = "error"
+)
+
+// Message is sent to sockets as JSON. Data is a batch.ImageStatusEvent for
+// image.status and a batch.BatchProgressResponse for batch.progress.
+type Message struct {
+	Type    string    `json:"type"`
+	BatchID uuid.UUID `json:"batch_id,omitzero"`
+	Data    any       `json:"data,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Command is sent by clients to start or stop watching a batch.
+type Command struct {
+	Action  string `json:"action" validate:"required,oneof=subscribe unsubscribe"`
+	BatchID string `json:"batch_id" validate:"required,uuid"`
+}
+
+// TicketResponse is a ticket for opening GET /ws without the Authorization
+// header.
+type TicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// maxWatchedBatches caps the batches one socket watches.
+	maxWatchedBatches = 100
+	// maxCommandSize bounds a message from a client.
+	maxCommandSize = 1 << 10
+	// ticketTTL is how long a socket ticket can be used to connect.
+	ticketTTL = 30 * time.Second
+	// ownershipCheckInterval is how often a socket checks that its user
+	// still owns the batches it watches, e.g. after a transfer.
+	ownershipCheckInterval = time.Minute
+)
+
+type NotifyHandler struct {
+	validator *validator.Validate
+	dbQueries *database.Queries
+	config    *utils.Config
+	hub       *Hub
+}
+
+func NewHandler(validator *validator.Validate, dbQueries *database.Queries, config *utils.Config, hub *Hub) *NotifyHandler {
+	return &NotifyHandler{
+		validator: validator,
+		dbQueries: dbQueries,
+		config:    config,
+		hub:       hub,
+	}
+}
+
+// CreateTicket godoc
+// @Summary Create a WebSocket ticket
+// @Description Issue a ticket for opening GET /ws as ?ticket=..., for clients such as browsers that cannot set the Authorization header on a WebSocket. The ticket can be used for 30 seconds, and the socket it opens is closed when the access token used here expires
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} utils.SuccessResponse{data=TicketResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /ws/tickets [post]
+func (h *NotifyHandler) CreateTicket(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	sessionExpiresAt := c.Get("tokenExpiresAt").(time.Time)
+
+	ticket, err := utils.GenerateSocketTicket(userID, sessionExpiresAt, h.config.JwtSecret, ticketTTL)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusCreated, "ticket created successfully", TicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: time.Now().UTC().Add(ticketTTL),
+	})
+}
+
+// Socket godoc
+// @Summary Watch batches over a WebSocket
+// @Description Upgrade to a WebSocket that pushes status changes of batches instead of polling. Authenticate with the Authorization header, or with a ticket from POST /ws/tickets in the ticket query parameter. Send {"action": "subscribe", "batch_id": "..."} to watch one of your batches (up to 100 per socket) and {"action": "unsubscribe", ...} to stop. Every message has a type: subscribed and unsubscribed confirm commands, or report a batch that is no longer yours, e.g. after a transfer; image.status carries an image that started or finished processing; batch.progress carries the batch's progress as returned by GET /batches/{batchID}/progress and follows every image.status as well as every subscribe; error explains a rejected command. The socket is closed when the access token it was opened with expires, and sockets that fall behind are closed too; reconnect and subscribe again
+// @Tags notifications
+// @Security BearerAuth
+// @Param ticket query string false "Ticket from POST /ws/tickets, instead of the Authorization header"
+// @Success 101
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /ws [get]
+func (h *NotifyHandler) Socket(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	expiresAt := c.Get("tokenExpiresAt").(time.Time)
+
+	server := websocket.Server{
+		// Sockets are authenticated by the Authorization header or a
+		// ticket, never by cookies, so any origin may open one.
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = maxCommandSize
+			sub := h.hub.Subscribe(userID)
+			defer sub.Close()
+
+			go writeMessages(ws, sub)
+			go h.superviseSocket(ws.Request().Context(), sub, userID, expiresAt)
+			h.readCommands(ws.Request().Context(), ws, sub, userID)
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// superviseSocket closes the subscriber, and so its socket, when the token
+// the socket was opened with expires, and until then stops watching the
+// batches the user no longer owns every ownershipCheckInterval.
+func (h *NotifyHandler) superviseSocket(ctx context.Context, sub *Subscriber, userID uuid.UUID, expiresAt time.Time) {
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+	ticker := time.NewTicker(ownershipCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expiry.C:
+			sub.Send(Message{Type: MessageError, Error: "token expired, reconnect with a new one"})
+			sub.Close()
+			return
+		case <-ticker.C:
+			h.dropLostBatches(ctx, sub, userID)
+		}
+	}
+}
+
+// dropLostBatches stops watching the batches of sub that userID no longer
+// owns, telling the client with an unsubscribed message.
+func (h *NotifyHandler) dropLostBatches(ctx context.Context, sub *Subscriber, userID uuid.UUID) {
+	for _, batchID := range sub.Batches() {
+		_, err := h.dbQueries.GetUserBatchByID(ctx, database.GetUserBatchByIDParams{
+			ID:     batchID,
+			UserID: userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			sub.Unwatch(batchID)
+			if !sub.Send(Message{Type: MessageUnsubscribed, BatchID: batchID, Error: "batch not found"}) {
+				sub.Close()
+			}
+		}
+	}
+}
+
+// writeMessages sends the subscriber's messages until either the socket or
+// the subscriber is closed.
+func writeMessages(ws *websocket.Conn, sub *Subscriber) {
+	for msg := range sub.Messages() {
+		if err := websocket.JSON.Send(ws, msg); err != nil {
+			break
+		}
+	}
+	// Also ends readCommands when the subscriber fell behind.
+	ws.Close()
+}
+
+// readCommands applies the client's commands until the socket closes.
+func (h *NotifyHandler) readCommands(ctx context.Context, ws *websocket.Conn, sub *Subscriber, userID uuid.UUID) {
+	send := func(msg Message) {
+		if !sub.Send(msg) {
+			sub.Close()
+		}
+	}
+
+	for {
+		var cmd Command
+		if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				send(Message{Type: MessageError, Error: "invalid command"})
+				continue
+			}
+			return
+		}
+		if err := h.validator.Struct(cmd); err != nil {
+			send(Message{Type: MessageError, Error: err.Error()})
+			continue
+		}
+		batchID := uuid.MustParse(cmd.BatchID)
+
+		switch cmd.Action {
+		case "subscribe":
+			b, err := h.dbQueries.GetUserBatchByID(ctx, database.GetUserBatchByIDParams{
+				ID:     batchID,
+				UserID: userID,
+			})
+			if err != nil {
+				msg := "internal server error"
+				if errors.Is(err, sql.ErrNoRows) {
+					msg = "batch not found"
+				}
+				send(Message{Type: MessageError, BatchID: batchID, Error: msg})
+				continue
+			}
+			if !sub.Watch(b.ID, maxWatchedBatches) {
+				send(Message{Type: MessageError, BatchID: batchID, Error: "too many batches watched"})
+				continue
+			}
+			send(Message{Type: MessageSubscribed, BatchID: b.ID})
+
+			// Changes from before the subscription are covered by the
+			// current progress.
+			progress, err := batch.Progress(ctx, h.dbQueries, b)
+			if err != nil {
+				send(Message{Type: MessageError, BatchID: batchID, Error: "internal server error"})
+				continue
+			}
+			send(Message{Type: MessageBatchProgress, BatchID: b.ID, Data: progress})
+		case "unsubscribe":
+			sub.Unwatch(batchID)
+			send(Message{Type: MessageUnsubscribed, BatchID: batchID})
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many messages may wait for a socket before it is
+// considered too slow and dropped.
+const subscriberBuffer = 64
+
+// Hub passes messages on to the subscribers watching their batch. It only
+// knows the sockets of this server; every server gets all status events.
+type Hub struct {
+	mu       sync.RWMutex
+	watchers map[uuid.UUID]map[*Subscriber]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{watchers: map[uuid.UUID]map[*Subscriber]struct{}{}}
+}
+
+// Subscriber receives the messages of the batches it watches, as long as
+// they belong to its user.
+type Subscriber struct {
+	hub      *Hub
+	userID   uuid.UUID
+	messages chan Message
+
+	mu      sync.Mutex
+	batches map[uuid.UUID]struct{}
+	closed  bool
+}
+
+// Subscribe returns a subscriber of userID that watches no batch yet. It
+// must be closed once its socket is gone.
+func (h *Hub) Subscribe(userID uuid.UUID) *Subscriber {
+	return &Subscriber{
+		hub:      h,
+		userID:   userID,
+		messages: make(chan Message, subscriberBuffer),
+		batches:  map[uuid.UUID]struct{}{},
+	}
+}
+
+// Watched reports whether any subscriber watches batchID, so events nobody
+// listens to are not enriched from the database.
+func (h *Hub) Watched(batchID uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.watchers[batchID]) > 0
+}
+
+// Publish sends msg to the subscribers of userID, the batch's owner,
+// watching msg.BatchID, so sockets of a previous owner of a transferred
+// batch get nothing. Subscribers whose buffer is full are closed rather
+// than blocking the others; their clients reconnect and load the batch
+// again.
+func (h *Hub) Publish(userID uuid.UUID, msg Message) {
+	// Subscribers lock themselves before the hub, so they are sent to
+	// after the hub is unlocked.
+	h.mu.RLock()
+	subscribers := make([]*Subscriber, 0, len(h.watchers[msg.BatchID]))
+	for s := range h.watchers[msg.BatchID] {
+		if s.userID == userID {
+			subscribers = append(subscribers, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range subscribers {
+		if !s.Send(msg) {
+			s.Close()
+		}
+	}
+}
+
+// Messages returns the messages for the subscriber's socket, closed when the
+// subscriber is.
+func (s *Subscriber) Messages() <-chan Message {
+	return s.messages
+}
+
+// Watch starts sending the messages of batchID. It reports false when the
+// subscriber already watches maxWatched batches.
+func (s *Subscriber) Watch(batchID uuid.UUID, maxWatched int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if _, ok := s.batches[batchID]; ok {
+		return true
+	}
+	if len(s.batches) >= maxWatched {
+		return false
+	}
+	s.batches[batchID] = struct{}{}
+
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if s.hub.watchers[batchID] == nil {
+		s.hub.watchers[batchID] = map[*Subscriber]struct{}{}
+	}
+	s.hub.watchers[batchID][s] = struct{}{}
+	return true
+}
+
+// Batches returns the batches the subscriber watches.
+func (s *Subscriber) Batches() []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Collect(maps.Keys(s.batches))
+}
+
+// Unwatch stops sending the messages of batchID.
+func (s *Subscriber) Unwatch(batchID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.batches, batchID)
+	s.hub.unwatch(s, batchID)
+}
+
+// Send queues msg for the socket without waiting. It reports false when the
+// buffer is full.
+func (s *Subscriber) Send(msg Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	select {
+	case s.messages <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops watching every batch and closes Messages.
+func (s *Subscriber) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for batchID := range s.batches {
+		s.hub.unwatch(s, batchID)
+	}
+	close(s.messages)
+}
+
+func (h *Hub) unwatch(s *Subscriber, batchID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.watchers[batchID], s)
+	if len(h.watchers[batchID]) == 0 {
+		delete(h.watchers, batchID)
+	}
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	userID, batchID := uuid.New(), uuid.New()
+	watching := hub.Subscribe(userID)
+	other := hub.Subscribe(userID)
+	previousOwner := hub.Subscribe(uuid.New())
+	require.True(t, watching.Watch(batchID, 10))
+	require.True(t, other.Watch(uuid.New(), 10))
+	require.True(t, previousOwner.Watch(batchID, 10))
+	assert.True(t, hub.Watched(batchID))
+	assert.Equal(t, []uuid.UUID{batchID}, watching.Batches())
+
+	hub.Publish(userID, Message{Type: MessageImageStatus, BatchID: batchID})
+	require.Len(t, watching.Messages(), 1)
+	assert.Equal(t, batchID, (<-watching.Messages()).BatchID)
+	assert.Empty(t, other.Messages())
+	assert.Empty(t, previousOwner.Messages(), "events of a transferred batch only reach its owner")
+
+	watching.Unwatch(batchID)
+	previousOwner.Unwatch(batchID)
+	assert.False(t, hub.Watched(batchID))
+	hub.Publish(userID, Message{Type: MessageImageStatus, BatchID: batchID})
+	assert.Empty(t, watching.Messages())
+}
+
+func TestSubscriberWatchLimit(t *testing.T) {
+	sub := NewHub().Subscribe(uuid.New())
+	batchID := uuid.New()
+	assert.True(t, sub.Watch(batchID, 1))
+	assert.True(t, sub.Watch(batchID, 1))
+	assert.False(t, sub.Watch(uuid.New(), 1))
+}
+
+func TestHubClosesSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	userID, batchID := uuid.New(), uuid.New()
+	sub := hub.Subscribe(userID)
+	require.True(t, sub.Watch(batchID, 10))
+
+	for range subscriberBuffer + 1 {
+		hub.Publish(userID, Message{Type: MessageImageStatus, BatchID: batchID})
+	}
+	assert.False(t, hub.Watched(batchID))
+	n := 0
+	for range sub.Messages() {
+		n++
+	}
+	assert.Equal(t, subscriberBuffer, n)
+	assert.False(t, sub.Watch(batchID, 10))
+}
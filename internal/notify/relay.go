@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"log"
+
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+)
+
+// Relay returns the handler of the image status events workers publish. It
+// passes each event on to the sockets watching its batch, followed by the
+// batch's new progress. Events are always acked: a socket that misses one
+// loads the batch again when it reconnects.
+func Relay(hub *Hub, dbQueries *database.Queries) func(batch.ImageStatusEvent) pubsub.AckType {
+	return func(e batch.ImageStatusEvent) pubsub.AckType {
+		if !hub.Watched(e.BatchID) {
+			return pubsub.Ack
+		}
+		hub.Publish(e.UserID, Message{Type: MessageImageStatus, BatchID: e.BatchID, Data: e})
+
+		b, err := dbQueries.GetUserBatchByID(context.Background(), database.GetUserBatchByIDParams{
+			ID:     e.BatchID,
+			UserID: e.UserID,
+		})
+		if err != nil {
+			log.Printf("error get batch %s for status event: %v", e.BatchID, err)
+			return pubsub.Ack
+		}
+		progress, err := batch.Progress(context.Background(), dbQueries, b)
+		if err != nil {
+			log.Printf("error get progress of batch %s: %v", e.BatchID, err)
+			return pubsub.Ack
+		}
+		hub.Publish(b.UserID, Message{Type: MessageBatchProgress, BatchID: b.ID, Data: progress})
+		return pubsub.Ack
+	}
+}
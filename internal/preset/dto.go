@@ -0,0 +1,37 @@
+package preset
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PresetRequest creates or replaces a preset. Settings left out are not
+// part of the preset, so batches created from it use their own value or the
+// batch default.
+type PresetRequest struct {
+	Name              string  `json:"name" validate:"required,max=255"`
+	WatermarkID       *string `json:"watermark_id" validate:"omitempty,uuid"`
+	WatermarkPosition *string `json:"watermark_position" validate:"omitempty,oneof=top-left top-right bottom-left bottom-right center tiled diagonal auto"`
+	WatermarkOpacity  *int    `json:"watermark_opacity" validate:"omitempty,min=0,max=100"`
+	WatermarkScale    *int    `json:"watermark_scale" validate:"omitempty,min=1,max=100"`
+	OutputFormat      *string `json:"output_format" validate:"omitempty,oneof=jpeg png webp"`
+	OutputQuality     *int    `json:"output_quality" validate:"omitempty,min=1,max=100"`
+	MaxWidth          *int    `json:"max_width" validate:"omitempty,min=1,max=10000"`
+	MaxHeight         *int    `json:"max_height" validate:"omitempty,min=1,max=10000"`
+}
+
+type PresetResponse struct {
+	ID                uuid.UUID  `json:"id"`
+	Name              string     `json:"name"`
+	WatermarkID       *uuid.UUID `json:"watermark_id"`
+	WatermarkPosition *string    `json:"watermark_position"`
+	WatermarkOpacity  *int       `json:"watermark_opacity"`
+	WatermarkScale    *int       `json:"watermark_scale"`
+	OutputFormat      *string    `json:"output_format"`
+	OutputQuality     *int       `json:"output_quality"`
+	MaxWidth          *int       `json:"max_width"`
+	MaxHeight         *int       `json:"max_height"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,291 @@
+package preset
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+var errWatermarkNotFound = errors.New("watermark not found")
+
+type PresetHandler struct {
+	validator *validator.Validate
+	dbQueries *database.Queries
+}
+
+func NewHandler(validator *validator.Validate, dbQueries *database.Queries) *PresetHandler {
+	return &PresetHandler{
+		validator: validator,
+		dbQueries: dbQueries,
+	}
+}
+
+// GetAll godoc
+// @Summary Get list of presets
+// @Description Retrieve the batch presets of the authenticated user, by name
+// @Tags presets
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=[]PresetResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /presets [get]
+func (h *PresetHandler) GetAll(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	presets, err := h.dbQueries.GetUserBatchPresets(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	presetsRes := make([]PresetResponse, len(presets))
+	for i, p := range presets {
+		presetsRes[i] = toPresetResponse(p)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "presets retrieved successfully", presetsRes)
+}
+
+// GetByID godoc
+// @Summary Get preset
+// @Description Retrieve a batch preset of the authenticated user
+// @Tags presets
+// @Produce json
+// @Security BearerAuth
+// @Param presetID path string true "Preset ID"
+// @Success 200 {object} utils.SuccessResponse{data=PresetResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /presets/{presetID} [get]
+func (h *PresetHandler) GetByID(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	presetUUID := utils.ParamUUID(c, "presetID")
+
+	preset, err := h.dbQueries.GetUserBatchPresetByID(c.Request().Context(), database.GetUserBatchPresetByIDParams{
+		ID:     presetUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "preset")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "preset retrieved successfully", toPresetResponse(preset))
+}
+
+// Create godoc
+// @Summary Create preset
+// @Description Save a named set of batch settings, to be referenced by preset_id when creating batches instead of repeating them. The watermark must be in your library
+// @Tags presets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param preset body PresetRequest true "Preset Request"
+// @Success 201 {object} utils.SuccessResponse{data=PresetResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /presets [post]
+func (h *PresetHandler) Create(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	var body PresetRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	watermarkID, err := h.libraryWatermark(c.Request().Context(), userID, body.WatermarkID)
+	if err != nil {
+		if errors.Is(err, errWatermarkNotFound) {
+			return utils.RespondError(c, http.StatusBadRequest, err.Error())
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	preset, err := h.dbQueries.CreateBatchPreset(c.Request().Context(), database.CreateBatchPresetParams{
+		UserID:            userID,
+		Name:              body.Name,
+		WatermarkID:       watermarkID,
+		WatermarkPosition: nullPosition(body.WatermarkPosition),
+		WatermarkOpacity:  nullInt(body.WatermarkOpacity),
+		WatermarkScale:    nullInt(body.WatermarkScale),
+		OutputFormat:      nullFormat(body.OutputFormat),
+		OutputQuality:     nullInt(body.OutputQuality),
+		MaxWidth:          nullInt(body.MaxWidth),
+		MaxHeight:         nullInt(body.MaxHeight),
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "preset")
+	}
+	return utils.RespondJSON(c, http.StatusCreated, "preset created successfully", toPresetResponse(preset))
+}
+
+// Update godoc
+// @Summary Replace preset
+// @Description Replace the name and settings of a batch preset; settings left out are removed from it. Batches already created from the preset keep their settings
+// @Tags presets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param presetID path string true "Preset ID"
+// @Param preset body PresetRequest true "Preset Request"
+// @Success 200 {object} utils.SuccessResponse{data=PresetResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /presets/{presetID} [put]
+func (h *PresetHandler) Update(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	presetUUID := utils.ParamUUID(c, "presetID")
+
+	var body PresetRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	watermarkID, err := h.libraryWatermark(c.Request().Context(), userID, body.WatermarkID)
+	if err != nil {
+		if errors.Is(err, errWatermarkNotFound) {
+			return utils.RespondError(c, http.StatusBadRequest, err.Error())
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	preset, err := h.dbQueries.UpdateBatchPreset(c.Request().Context(), database.UpdateBatchPresetParams{
+		ID:                presetUUID,
+		UserID:            userID,
+		Name:              body.Name,
+		WatermarkID:       watermarkID,
+		WatermarkPosition: nullPosition(body.WatermarkPosition),
+		WatermarkOpacity:  nullInt(body.WatermarkOpacity),
+		WatermarkScale:    nullInt(body.WatermarkScale),
+		OutputFormat:      nullFormat(body.OutputFormat),
+		OutputQuality:     nullInt(body.OutputQuality),
+		MaxWidth:          nullInt(body.MaxWidth),
+		MaxHeight:         nullInt(body.MaxHeight),
+	})
+	if err != nil {
+		return utils.RespondDBError(c, err, "preset")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "preset updated successfully", toPresetResponse(preset))
+}
+
+// DeleteByID godoc
+// @Summary Delete preset
+// @Description Delete a batch preset. Batches already created from it keep their settings
+// @Tags presets
+// @Produce json
+// @Security BearerAuth
+// @Param presetID path string true "Preset ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /presets/{presetID} [delete]
+func (h *PresetHandler) DeleteByID(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	presetUUID := utils.ParamUUID(c, "presetID")
+
+	deleted, err := h.dbQueries.DeleteBatchPresetByID(c.Request().Context(), database.DeleteBatchPresetByIDParams{
+		ID:     presetUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if deleted == 0 {
+		return utils.RespondError(c, http.StatusNotFound, "preset not found")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "preset deleted successfully", nil)
+}
+
+// libraryWatermark checks that id, if given, is a watermark in the user's
+// library.
+func (h *PresetHandler) libraryWatermark(ctx context.Context, userID uuid.UUID, id *string) (uuid.NullUUID, error) {
+	if id == nil {
+		return uuid.NullUUID{}, nil
+	}
+	watermark, err := h.dbQueries.GetUserWatermarkByID(ctx, database.GetUserWatermarkByIDParams{
+		ID:     uuid.MustParse(*id),
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.NullUUID{}, errWatermarkNotFound
+		}
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: watermark.ID, Valid: true}, nil
+}
+
+func nullInt(v *int) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(*v), Valid: true}
+}
+
+func nullPosition(v *string) database.NullWatermarkPosition {
+	if v == nil {
+		return database.NullWatermarkPosition{}
+	}
+	return database.NullWatermarkPosition{WatermarkPosition: database.WatermarkPosition(*v), Valid: true}
+}
+
+func nullFormat(v *string) database.NullOutputFormat {
+	if v == nil {
+		return database.NullOutputFormat{}
+	}
+	return database.NullOutputFormat{OutputFormat: database.OutputFormat(*v), Valid: true}
+}
+
+func intPtr(v sql.NullInt32) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int32)
+	return &i
+}
+
+func toPresetResponse(p database.BatchPreset) PresetResponse {
+	res := PresetResponse{
+		ID:               p.ID,
+		Name:             p.Name,
+		WatermarkOpacity: intPtr(p.WatermarkOpacity),
+		WatermarkScale:   intPtr(p.WatermarkScale),
+		OutputQuality:    intPtr(p.OutputQuality),
+		MaxWidth:         intPtr(p.MaxWidth),
+		MaxHeight:        intPtr(p.MaxHeight),
+		CreatedAt:        p.CreatedAt,
+		UpdatedAt:        p.UpdatedAt,
+	}
+	if p.WatermarkID.Valid {
+		res.WatermarkID = &p.WatermarkID.UUID
+	}
+	if p.WatermarkPosition.Valid {
+		position := string(p.WatermarkPosition.WatermarkPosition)
+		res.WatermarkPosition = &position
+	}
+	if p.OutputFormat.Valid {
+		format := string(p.OutputFormat.OutputFormat)
+		res.OutputFormat = &format
+	}
+	return res
+}
@@ -0,0 +1,28 @@
+package preset
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToPresetResponse(t *testing.T) {
+	watermarkID := uuid.New()
+	res := toPresetResponse(database.BatchPreset{
+		Name:          "catalog",
+		WatermarkID:   uuid.NullUUID{UUID: watermarkID, Valid: true},
+		OutputFormat:  database.NullOutputFormat{OutputFormat: database.OutputFormatWebp, Valid: true},
+		OutputQuality: sql.NullInt32{Int32: 0, Valid: false},
+		MaxWidth:      sql.NullInt32{Int32: 1600, Valid: true},
+	})
+
+	assert.Equal(t, &watermarkID, res.WatermarkID)
+	assert.Equal(t, "webp", *res.OutputFormat)
+	assert.Equal(t, 1600, *res.MaxWidth)
+	assert.Nil(t, res.WatermarkPosition)
+	assert.Nil(t, res.OutputQuality)
+	assert.Nil(t, res.MaxHeight)
+}
@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// compressThreshold is the body size above which messages are gzipped.
+// Small tasks stay plain so they remain readable in the management UI.
+const compressThreshold = 8 << 10
+
+// maxDecodedSize bounds how large a gzipped body may grow when decoded, so a
+// small malformed or hostile message cannot exhaust the consumer's memory.
+const maxDecodedSize = 64 << 20
+
+// errBodyTooLarge is returned for gzipped bodies over maxDecodedSize.
+var errBodyTooLarge = fmt.Errorf("decoded body larger than %d bytes", maxDecodedSize)
+
+// encodingGzip is the content encoding of gzipped message bodies.
+const encodingGzip = "gzip"
+
+// encodeBody gzips data when it is larger than compressThreshold and returns
+// the body along with its content encoding, empty for plain bodies.
+func encodeBody(data []byte) ([]byte, string, error) {
+	if len(data) <= compressThreshold {
+		return data, "", nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), encodingGzip, nil
+}
+
+// decodeBody reverses encodeBody using the message's content encoding.
+func decodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	switch contentEncoding {
+	case "", "identity":
+		return body, nil
+	case encodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		data, err := io.ReadAll(io.LimitReader(zr, maxDecodedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxDecodedSize {
+			return nil, errBodyTooLarge
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+}
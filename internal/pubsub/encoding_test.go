@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		wantEncoding string
+	}{
+		{name: "small bodies stay plain", data: []byte(`{"image_id":"1"}`), wantEncoding: ""},
+		{name: "large bodies are gzipped", data: []byte(`{"manifest":"` + strings.Repeat("a", compressThreshold) + `"}`), wantEncoding: encodingGzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentEncoding, err := encodeBody(tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEncoding, contentEncoding)
+			if contentEncoding != "" {
+				assert.Less(t, len(body), len(tt.data))
+			}
+
+			decoded, err := decodeBody(body, contentEncoding)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, decoded)
+		})
+	}
+
+	_, err := decodeBody([]byte("{}"), "br")
+	assert.Error(t, err)
+}
+
+func TestDecodeBodyTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(make([]byte, maxDecodedSize+1))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	_, err = decodeBody(buf.Bytes(), encodingGzip)
+	assert.ErrorIs(t, err, errBodyTooLarge)
+}
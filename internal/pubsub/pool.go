@@ -0,0 +1,81 @@
+package pubsub
+
+import (
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ChannelPool keeps open channels of one connection for publishing, so
+// requests do not open and close a channel each. Channels the broker closed,
+// e.g. after a failed passive declare, are never handed out again; Get opens
+// a replacement instead.
+type ChannelPool struct {
+	conn     *amqp.Connection
+	channels chan *amqp.Channel
+	mu       sync.Mutex
+	closed   bool
+}
+
+// NewChannelPool returns a pool of conn that keeps up to size idle channels.
+// Channels are opened on demand.
+func NewChannelPool(conn *amqp.Connection, size int) *ChannelPool {
+	return &ChannelPool{
+		conn:     conn,
+		channels: make(chan *amqp.Channel, size),
+	}
+}
+
+// Get returns an open channel, reusing an idle one when there is one. It
+// must be given back with Put.
+func (p *ChannelPool) Get() (*amqp.Channel, error) {
+	for {
+		select {
+		case ch := <-p.channels:
+			if ch.IsClosed() {
+				continue
+			}
+			return ch, nil
+		default:
+			return p.conn.Channel()
+		}
+	}
+}
+
+// Put gives ch back to the pool. Closed channels are dropped, and channels
+// beyond the pool's size or given back after Close are closed.
+func (p *ChannelPool) Put(ch *amqp.Channel) {
+	if ch == nil || ch.IsClosed() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		ch.Close()
+		return
+	}
+	select {
+	case p.channels <- ch:
+	default:
+		ch.Close()
+	}
+}
+
+// Close closes the idle channels. Channels still in use are closed when
+// they are given back.
+func (p *ChannelPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for {
+		select {
+		case ch := <-p.channels:
+			ch.Close()
+		default:
+			return
+		}
+	}
+}
@@ -3,23 +3,75 @@ package pubsub
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rickyroynardson/image-go/internal/faults"
 )
 
+// faultRate is the probability with which publishes fail and deliveries are
+// requeued before their handler runs; see SetFaultRate.
+var faultRate float64
+
+// SetFaultRate makes publishes fail and deliveries get requeued unhandled
+// with probability rate, to exercise retries outside production. It must be
+// called before publishing or subscribing.
+func SetFaultRate(rate float64) {
+	faultRate = rate
+}
+
+// PublishJSON publishes val as JSON, gzipping bodies larger than
+// compressThreshold and marking them with a content encoding that
+// SubscribeJSON honors.
 func PublishJSON[T any](ch *amqp.Channel, exchange, key string, val T) error {
-	data, err := json.Marshal(val)
+	msg, err := jsonPublishing(key, val)
 	if err != nil {
 		return err
 	}
+	return ch.PublishWithContext(context.Background(), exchange, key, false, false, msg)
+}
 
-	err = ch.PublishWithContext(context.Background(), exchange, key, false, false, amqp.Publishing{
-		ContentType:  "application/json",
-		Body:         data,
-		DeliveryMode: amqp.Persistent,
-	})
+// PublishJSONConfirmed publishes val like PublishJSON and waits until the
+// broker confirms it has taken the message over, putting ch into confirm
+// mode. A message the broker refuses is an error.
+func PublishJSONConfirmed[T any](ctx context.Context, ch *amqp.Channel, exchange, key string, val T) error {
+	msg, err := jsonPublishing(key, val)
+	if err != nil {
+		return err
+	}
+	if err := ch.Confirm(false); err != nil {
+		return err
+	}
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	ack, err := confirmation.WaitContext(ctx)
 	if err != nil {
 		return err
 	}
+	if !ack {
+		return fmt.Errorf("publish to %s: refused by the broker", key)
+	}
 	return nil
 }
+
+func jsonPublishing[T any](key string, val T) (amqp.Publishing, error) {
+	if faults.Fail(faultRate) {
+		return amqp.Publishing{}, fmt.Errorf("publish to %s: %w", key, faults.ErrInjected)
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+	body, contentEncoding, err := encodeBody(data)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+	return amqp.Publishing{
+		ContentType:     "application/json",
+		ContentEncoding: contentEncoding,
+		Body:            body,
+		DeliveryMode:    amqp.Persistent,
+	}, nil
+}
@@ -26,3 +26,12 @@ func DeclareAndBind(conn *amqp.Connection, exchange, queueName, key string, queu
 	}
 	return ch, queue, nil
 }
+
+// QueueLength returns the number of messages ready for delivery in an existing queue.
+func QueueLength(ch *amqp.Channel, queueName string, queueType QueueType) (int, error) {
+	queue, err := ch.QueueDeclarePassive(queueName, queueType == QueueTypeDurable, queueType != QueueTypeDurable, queueType != QueueTypeDurable, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	return queue.Messages, nil
+}
@@ -1,10 +1,15 @@
 package pubsub
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rickyroynardson/image-go/internal/faults"
 )
 
 type AckType int
@@ -13,9 +18,62 @@ const (
 	Ack AckType = iota
 	NackRequeue
 	NackDiscard
+	// RequeueLast publishes the message again at the back of its queue and acks
+	// the original, so messages queued behind it are handled first.
+	RequeueLast
+	// RequeueDelayed publishes the message to the delay queue of
+	// WithRetryDelay and acks the original, so it comes back to the end of
+	// its queue after the delay without the handler waiting for it. Without
+	// a delay queue it is RequeueLast.
+	RequeueDelayed
 )
 
-func SubscribeJSON[T any](conn *amqp.Connection, exchange, queueName, key string, queueType QueueType, handler func(T) AckType) error {
+// SubscribeOption configures SubscribeJSON.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	healthCheck    func(context.Context) error
+	healthInterval time.Duration
+	retryDelay     time.Duration
+	delayQueue     string
+}
+
+// healthCheckTimeout bounds a single dependency health check.
+const healthCheckTimeout = 5 * time.Second
+
+// WithHealthCheck runs check before handling each message. While it fails the
+// subscriber stops consuming and requeues the messages it already received,
+// so an outage of a dependency such as the database does not fail valid
+// work, and resumes once check passes again, polling every interval.
+func WithHealthCheck(check func(context.Context) error, interval time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.healthCheck = check
+		o.healthInterval = interval
+	}
+}
+
+// WithRetryDelay declares a delay queue next to a durable queue for the
+// messages handled with RequeueDelayed. They wait there for delay, by a
+// message TTL, and are then dead-lettered back to the queue.
+func WithRetryDelay(delay time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.retryDelay = delay
+	}
+}
+
+// DelayQueueName is the delay queue of WithRetryDelay for queueName. The
+// delay is part of the name, since a queue's TTL cannot change once it is
+// declared.
+func DelayQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s.delay-%dms", queueName, delay.Milliseconds())
+}
+
+func SubscribeJSON[T any](conn *amqp.Connection, exchange, queueName, key string, queueType QueueType, handler func(T) AckType, options ...SubscribeOption) error {
+	var opts subscribeOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
 	ch, queue, err := DeclareAndBind(conn, exchange, queueName, key, queueType)
 	if err != nil {
 		return err
@@ -26,28 +84,132 @@ func SubscribeJSON[T any](conn *amqp.Connection, exchange, queueName, key string
 		return err
 	}
 
-	msgCh, err := ch.Consume(queue.Name, "", false, false, false, false, nil)
+	if opts.retryDelay > 0 && queueType == QueueTypeDurable {
+		// Expired messages go through the default exchange, which routes
+		// them straight back to the queue by its name.
+		delayQueue, err := ch.QueueDeclare(DelayQueueName(queue.Name, opts.retryDelay), true, false, false, false, amqp.Table{
+			"x-message-ttl":             opts.retryDelay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue.Name,
+		})
+		if err != nil {
+			return err
+		}
+		opts.delayQueue = delayQueue.Name
+	}
+
+	consumer := queue.Name + "-" + uuid.NewString()
+	msgCh, err := ch.Consume(queue.Name, consumer, false, false, false, false, nil)
 	if err != nil {
 		return err
 	}
 	go func() {
-		for m := range msgCh {
-			var msg T
-			err := json.Unmarshal(m.Body, &msg)
-			if err != nil {
-				log.Printf("error unmarshal msg body: %v\n", err)
-				continue
+		for {
+			if !consumeJSON(ch, consumer, msgCh, handler, opts) {
+				return
 			}
-			ackType := handler(msg)
-			switch ackType {
-			case Ack:
-				m.Ack(false)
-			case NackRequeue:
-				m.Nack(false, true)
-			case NackDiscard:
-				m.Nack(false, false)
+			waitHealthy(opts)
+			log.Printf("dependencies healthy again, resuming %s\n", queue.Name)
+			msgCh, err = ch.Consume(queue.Name, consumer, false, false, false, false, nil)
+			if err != nil {
+				log.Printf("error resuming consumer of %s: %v\n", queue.Name, err)
+				return
 			}
 		}
 	}()
 	return nil
 }
+
+// consumeJSON handles deliveries until msgCh closes, returning false, or a
+// health check fails, returning true after it stopped consuming.
+func consumeJSON[T any](ch *amqp.Channel, consumer string, msgCh <-chan amqp.Delivery, handler func(T) AckType, opts subscribeOptions) bool {
+	for m := range msgCh {
+		if opts.healthCheck != nil {
+			if err := checkHealth(opts); err != nil {
+				log.Printf("dependency unhealthy, pausing consumer: %v\n", err)
+				m.Nack(false, true)
+				if err := ch.Cancel(consumer, false); err != nil {
+					log.Printf("error cancelling consumer: %v\n", err)
+				}
+				// Hand back whatever was prefetched before the cancel.
+				for m := range msgCh {
+					m.Nack(false, true)
+				}
+				return true
+			}
+		}
+
+		if faults.Fail(faultRate) {
+			log.Printf("injected fault, requeuing msg\n")
+			m.Nack(false, true)
+			continue
+		}
+
+		// A body that cannot be read never will be; discard it rather than
+		// leave it unacked on the channel.
+		data, err := decodeBody(m.Body, m.ContentEncoding)
+		if err != nil {
+			log.Printf("error decode msg body, discarding: %v\n", err)
+			m.Nack(false, false)
+			continue
+		}
+		var msg T
+		err = json.Unmarshal(data, &msg)
+		if err != nil {
+			log.Printf("error unmarshal msg body, discarding: %v\n", err)
+			m.Nack(false, false)
+			continue
+		}
+		ackType := handler(msg)
+		switch ackType {
+		case Ack:
+			m.Ack(false)
+		case NackRequeue:
+			m.Nack(false, true)
+		case NackDiscard:
+			m.Nack(false, false)
+		case RequeueLast:
+			republish(ch, m, m.Exchange, m.RoutingKey)
+		case RequeueDelayed:
+			exchange, key := m.Exchange, m.RoutingKey
+			if opts.delayQueue != "" {
+				exchange, key = "", opts.delayQueue
+			}
+			republish(ch, m, exchange, key)
+		}
+	}
+	return false
+}
+
+// republish publishes a copy of m with key to exchange and acks m, or
+// requeues m when the publish fails.
+func republish(ch *amqp.Channel, m amqp.Delivery, exchange, key string) {
+	err := ch.PublishWithContext(context.Background(), exchange, key, false, false, amqp.Publishing{
+		ContentType:     m.ContentType,
+		ContentEncoding: m.ContentEncoding,
+		Body:            m.Body,
+		DeliveryMode:    amqp.Persistent,
+	})
+	if err != nil {
+		log.Printf("error republishing msg, requeuing: %v\n", err)
+		m.Nack(false, true)
+		return
+	}
+	m.Ack(false)
+}
+
+func checkHealth(opts subscribeOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	return opts.healthCheck(ctx)
+}
+
+// waitHealthy blocks until the health check passes.
+func waitHealthy(opts subscribeOptions) {
+	for {
+		time.Sleep(opts.healthInterval)
+		if err := checkHealth(opts); err == nil {
+			return
+		}
+	}
+}
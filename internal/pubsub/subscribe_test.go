@@ -0,0 +1,15 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelayQueueName(t *testing.T) {
+	assert.Equal(t, "image-go_task.delay-2000ms", DelayQueueName("image-go_task", 2*time.Second))
+	// A changed delay gets a queue of its own rather than clashing with the
+	// TTL of the old one.
+	assert.NotEqual(t, DelayQueueName("image-go_task", 2*time.Second), DelayQueueName("image-go_task", 5*time.Second))
+}
@@ -0,0 +1,166 @@
+// Package router registers the routes of the API, so the server and the
+// integration tests serve the same set.
+package router
+
+import (
+	"database/sql"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/admin"
+	"github.com/rickyroynardson/image-go/internal/auth"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/font"
+	"github.com/rickyroynardson/image-go/internal/image"
+	"github.com/rickyroynardson/image-go/internal/middleware"
+	"github.com/rickyroynardson/image-go/internal/notify"
+	"github.com/rickyroynardson/image-go/internal/preset"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/rickyroynardson/image-go/internal/watermark"
+	"github.com/rickyroynardson/image-go/internal/webhook"
+)
+
+// Options are what the handlers and middleware of the API are built from.
+type Options struct {
+	DB        *sql.DB
+	DBQueries *database.Queries
+	Config    *utils.Config
+	Validator *validator.Validate
+	// Hub passes image status events on to the sockets of GET /ws.
+	Hub *notify.Hub
+	// UploadBandwidthLimit throttles multipart batch uploads to this many
+	// bytes per second per user; 0 leaves them unthrottled.
+	UploadBandwidthLimit int
+}
+
+// RegisterAPI registers every route under /api/v1 on e. Routes outside the
+// API, such as the docs, are left to the caller.
+func RegisterAPI(e *echo.Echo, opts Options) {
+	db, dbQueries, cfg, validator := opts.DB, opts.DBQueries, opts.Config, opts.Validator
+
+	authHandler := auth.NewHandler(validator, dbQueries, cfg)
+	batchHandler := batch.NewHandler(validator, db, dbQueries, cfg)
+	imageHandler := image.NewHandler(validator, dbQueries, cfg)
+	adminHandler := admin.NewHandler(validator, dbQueries, cfg)
+	fontHandler := font.NewHandler(validator, dbQueries, cfg)
+	webhookHandler := webhook.NewHandler(validator, dbQueries, cfg)
+	watermarkHandler := watermark.NewHandler(validator, dbQueries, cfg)
+	presetHandler := preset.NewHandler(validator, dbQueries)
+	notifyHandler := notify.NewHandler(validator, dbQueries, cfg, opts.Hub)
+
+	apiV1 := e.Group("/api/v1")
+	apiV1.POST("/login", authHandler.Login)
+	apiV1.POST("/register", authHandler.Register)
+	apiV1.POST("/refresh", authHandler.Refresh)
+	apiV1.POST("/email-change/confirm", authHandler.ConfirmEmailChange, middleware.Transaction(db))
+	apiV1.POST("/email-change/undo", authHandler.UndoEmailChange, middleware.Transaction(db))
+
+	apiV1.GET("/deliveries/:token", batchHandler.GetDeliveryGallery)
+	apiV1.GET("/deliveries/:token/images/:imageID/download", batchHandler.DownloadDeliveryImage, middleware.UUIDParams())
+
+	uploadsV1 := apiV1.Group("/uploads", middleware.UploadAuthenticated(cfg), middleware.IPAllowlist(dbQueries))
+	uploadsV1.POST("/presign", batchHandler.PresignUpload)
+	uploadsV1.POST("/confirm", batchHandler.ConfirmUpload, middleware.Transaction(db))
+
+	// Browsers cannot set headers on WebSockets, so /ws also takes a ticket.
+	apiV1.GET("/ws", notifyHandler.Socket, middleware.SocketAuthenticated(cfg), middleware.IPAllowlist(dbQueries))
+
+	// Handlers read ID path parameters with utils.ParamUUID.
+	apiV1.Use(middleware.Authenticated(cfg), middleware.IPAllowlist(dbQueries), middleware.UUIDParams())
+	apiV1.GET("/me/sessions", authHandler.GetSessions)
+	apiV1.DELETE("/me/sessions/:sessionID", authHandler.RevokeSession)
+	apiV1.POST("/me/email", authHandler.RequestEmailChange, middleware.Transaction(db))
+	apiV1.GET("/me/features", authHandler.GetFeatures)
+	apiV1.POST("/ws/tickets", notifyHandler.CreateTicket)
+
+	// Experimental endpoints ship dark: register them with
+	// middleware.FeatureFlag(dbQueries, cfg, "<flag>") so they answer 404
+	// until the flag is enabled in FEATURE_FLAGS or for the user.
+
+	apiV1.GET("/batches", batchHandler.GetAll)
+	apiV1.GET("/batches/search", batchHandler.Search)
+	apiV1.GET("/trash", batchHandler.GetTrash)
+	apiV1.GET("/batches/:batchID", batchHandler.GetByID)
+	apiV1.POST("/batches", batchHandler.Create, middleware.UploadBandwidthLimit(opts.UploadBandwidthLimit))
+	apiV1.POST("/batches/urls", batchHandler.CreateFromURLs, middleware.Transaction(db))
+	apiV1.POST("/batches/s3", batchHandler.CreateFromS3, middleware.FeatureFlag(dbQueries, cfg, "s3_import"), middleware.Transaction(db))
+	apiV1.PATCH("/batches/:batchID", batchHandler.Update)
+	apiV1.DELETE("/batches/:batchID", batchHandler.DeleteByID)
+	apiV1.POST("/batches/:batchID/upload-token", batchHandler.CreateUploadToken)
+	apiV1.POST("/batches/:batchID/archive", batchHandler.Archive, middleware.Transaction(db))
+	apiV1.POST("/batches/:batchID/restore", batchHandler.Restore, middleware.Transaction(db))
+	apiV1.POST("/batches/:batchID/cancel", batchHandler.Cancel, middleware.Transaction(db))
+	apiV1.POST("/batches/:batchID/reprocess", batchHandler.Reprocess, middleware.Transaction(db))
+	apiV1.POST("/batches/:batchID/clone", batchHandler.Reprocess, middleware.Transaction(db))
+	apiV1.GET("/batches/:batchID/comments", batchHandler.GetComments)
+	apiV1.POST("/batches/:batchID/comments", batchHandler.CreateComment)
+	apiV1.DELETE("/batches/:batchID/comments/:commentID", batchHandler.DeleteComment)
+	apiV1.GET("/batches/:batchID/activity", batchHandler.GetActivity)
+	apiV1.GET("/batches/:batchID/changes", batchHandler.GetChanges)
+	apiV1.GET("/batches/:batchID/progress", batchHandler.GetProgress)
+	apiV1.GET("/batches/:batchID/savings", batchHandler.GetSavings)
+	apiV1.GET("/batches/:batchID/download", batchHandler.Download)
+	apiV1.GET("/batches/:batchID/deliveries", batchHandler.GetDeliveries)
+	apiV1.POST("/batches/:batchID/deliveries", batchHandler.CreateDelivery, middleware.Transaction(db))
+	apiV1.POST("/batches/:batchID/transfer", batchHandler.CreateTransfer, middleware.Transaction(db))
+	apiV1.DELETE("/batches/:batchID/transfer", batchHandler.CancelTransfer)
+	apiV1.GET("/transfers", batchHandler.GetTransfers)
+	apiV1.POST("/transfers/:transferID/accept", batchHandler.AcceptTransfer, middleware.Transaction(db))
+	apiV1.POST("/transfers/:transferID/decline", batchHandler.DeclineTransfer, middleware.Transaction(db))
+
+	apiV1.GET("/fonts", fontHandler.GetAll)
+	apiV1.POST("/fonts", fontHandler.Upload)
+	apiV1.DELETE("/fonts/:fontID", fontHandler.DeleteByID)
+
+	apiV1.GET("/watermarks", watermarkHandler.GetAll)
+	apiV1.POST("/watermarks", watermarkHandler.Upload)
+	apiV1.GET("/watermarks/:watermarkID", watermarkHandler.GetByID)
+	apiV1.PATCH("/watermarks/:watermarkID", watermarkHandler.Update)
+	apiV1.DELETE("/watermarks/:watermarkID", watermarkHandler.DeleteByID)
+	apiV1.PUT("/watermarks/:watermarkID/default", watermarkHandler.SetDefault)
+	apiV1.DELETE("/watermarks/default", watermarkHandler.ClearDefault)
+
+	apiV1.GET("/presets", presetHandler.GetAll)
+	apiV1.POST("/presets", presetHandler.Create)
+	apiV1.GET("/presets/:presetID", presetHandler.GetByID)
+	apiV1.PUT("/presets/:presetID", presetHandler.Update)
+	apiV1.DELETE("/presets/:presetID", presetHandler.DeleteByID)
+
+	apiV1.GET("/images", imageHandler.Search)
+	apiV1.DELETE("/images", imageHandler.BulkDelete, middleware.Transaction(db))
+	apiV1.POST("/images/retry", imageHandler.BulkRetry, middleware.Transaction(db))
+	apiV1.POST("/images/verify", imageHandler.Verify)
+	apiV1.GET("/images/:imageID", imageHandler.GetByID)
+	apiV1.DELETE("/images/:imageID", imageHandler.DeleteByID)
+	apiV1.GET("/images/:imageID/content", imageHandler.GetContent)
+	apiV1.GET("/images/:imageID/original", imageHandler.GetOriginal)
+	apiV1.PUT("/images/:imageID/watermark-placement", imageHandler.SetWatermarkPlacement)
+	apiV1.DELETE("/images/:imageID/watermark-placement", imageHandler.ClearWatermarkPlacement)
+
+	apiV1.GET("/webhooks", webhookHandler.GetAll)
+	apiV1.POST("/webhooks", webhookHandler.Create)
+	apiV1.DELETE("/webhooks/:webhookID", webhookHandler.DeleteByID)
+	apiV1.POST("/webhooks/:webhookID/test", webhookHandler.Test)
+	apiV1.GET("/webhooks/:webhookID/deliveries", webhookHandler.GetDeliveries)
+	apiV1.POST("/webhooks/:webhookID/deliveries/:deliveryID/redeliver", webhookHandler.Redeliver)
+
+	adminV1 := apiV1.Group("/admin", middleware.Admin(dbQueries))
+	adminV1.GET("/auth-stats", adminHandler.GetAuthStats)
+	adminV1.GET("/metrics", adminHandler.GetMetrics)
+	adminV1.GET("/email-domains", adminHandler.GetEmailDomainRules)
+	adminV1.POST("/email-domains", adminHandler.CreateEmailDomainRule)
+	adminV1.DELETE("/email-domains/:ruleID", adminHandler.DeleteEmailDomainRuleByID)
+	adminV1.GET("/users/:userID/features", adminHandler.GetUserFeatures)
+	adminV1.PUT("/users/:userID/features/:flag", adminHandler.EnableUserFeature)
+	adminV1.DELETE("/users/:userID/features/:flag", adminHandler.DisableUserFeature)
+	adminV1.GET("/users/:userID/ip-allowlist", adminHandler.GetUserIPAllowlist)
+	adminV1.POST("/users/:userID/ip-allowlist", adminHandler.CreateUserIPAllowlistEntry)
+	adminV1.DELETE("/users/:userID/ip-allowlist/:entryID", adminHandler.DeleteUserIPAllowlistEntry)
+	adminV1.GET("/users/:userID/import-sources", adminHandler.GetUserImportSources)
+	adminV1.POST("/users/:userID/import-sources", adminHandler.CreateUserImportSource)
+	adminV1.DELETE("/users/:userID/import-sources/:sourceID", adminHandler.DeleteUserImportSource)
+	adminV1.GET("/users/:userID/boosts", adminHandler.GetUserBoosts)
+	adminV1.POST("/users/:userID/boosts", adminHandler.CreateUserBoost)
+	adminV1.DELETE("/users/:userID/boosts/:boostID", adminHandler.RevokeUserBoost)
+}
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/cmd/server/docs"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegisterAPIMatchesDocs checks that every documented endpoint is served
+// and every served endpoint is documented.
+func TestRegisterAPIMatchesDocs(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &spec))
+	param := regexp.MustCompile(`\{(\w+)\}`)
+	documented := map[string]bool{}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" /api/v1"+param.ReplaceAllString(path, ":$1")] = true
+		}
+	}
+
+	e := echo.New()
+	RegisterAPI(e, Options{Config: &utils.Config{}})
+	served := map[string]bool{}
+	for _, route := range e.Routes() {
+		if route.Method == echo.RouteNotFound || route.Method == http.MethodOptions {
+			continue
+		}
+		served[route.Method+" "+route.Path] = true
+	}
+
+	for route := range documented {
+		assert.True(t, served[route], "%s is documented but not served", route)
+	}
+	for route := range served {
+		assert.True(t, documented[route], "%s is served but not documented", route)
+	}
+}
+
+// TestDocsSecuritySchemes checks that admin and upload-token endpoints are
+// documented with their own security scheme, the public ones with none and
+// every other one with BearerAuth.
+func TestDocsSecuritySchemes(t *testing.T) {
+	var spec struct {
+		Paths               map[string]map[string]struct{ Security []map[string][]string } `json:"paths"`
+		SecurityDefinitions map[string]json.RawMessage                                     `json:"securityDefinitions"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &spec))
+	assert.ElementsMatch(t, []string{"AdminAuth", "BearerAuth", "UploadToken"}, slices.Collect(maps.Keys(spec.SecurityDefinitions)))
+
+	public := map[string]bool{
+		"POST /register":             true,
+		"POST /login":                true,
+		"POST /refresh":              true,
+		"POST /email-change/confirm": true,
+		"POST /email-change/undo":    true,
+		"GET /deliveries/{token}":    true,
+		"GET /deliveries/{token}/images/{imageID}/download": true,
+	}
+	for path, operations := range spec.Paths {
+		for method, op := range operations {
+			route := strings.ToUpper(method) + " " + path
+			var want []string
+			switch {
+			case public[route]:
+			case strings.HasPrefix(path, "/admin/"):
+				want = []string{"AdminAuth"}
+			case strings.HasPrefix(path, "/uploads/"):
+				want = []string{"UploadToken"}
+			default:
+				want = []string{"BearerAuth"}
+			}
+			var got []string
+			for _, requirement := range op.Security {
+				for scheme := range requirement {
+					got = append(got, scheme)
+				}
+			}
+			assert.Equal(t, want, got, route)
+		}
+	}
+}
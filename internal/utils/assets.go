@@ -19,6 +19,15 @@ func GetObjectURL(cfDistribution, key string) string {
 	return fmt.Sprintf("https://%s/%s", cfDistribution, key)
 }
 
+// GetObjectKey reverses GetObjectURL, returning an empty string for URLs outside the distribution.
+func GetObjectKey(cfDistribution, objectURL string) string {
+	prefix := fmt.Sprintf("https://%s/", cfDistribution)
+	if !strings.HasPrefix(objectURL, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(objectURL, prefix)
+}
+
 func mediaTypeToExt(mediaType string) string {
 	parts := strings.Split(mediaType, "/")
 	if len(parts) != 2 {
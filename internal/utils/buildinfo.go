@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"runtime"
+	"runtime/debug"
+	"slices"
+)
+
+// GitSHA and BuildTime are set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/rickyroynardson/image-go/internal/utils.GitSHA=$(git rev-parse HEAD) -X github.com/rickyroynardson/image-go/internal/utils.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Builds without them fall back to the VCS stamp of the Go toolchain.
+var (
+	GitSHA    string
+	BuildTime string
+)
+
+// BuildInfo describes the running binary, so support can tell which build
+// and which optional features are deployed.
+type BuildInfo struct {
+	GitSHA    string   `json:"git_sha"`
+	BuildTime string   `json:"build_time"`
+	GoVersion string   `json:"go_version"`
+	Features  []string `json:"features"`
+}
+
+// GetBuildInfo returns the build metadata along with the given features,
+// sorted. Unknown values are reported as "unknown".
+func GetBuildInfo(features ...string) BuildInfo {
+	info := BuildInfo{
+		GitSHA:    GitSHA,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Features:  slices.Sorted(slices.Values(features)),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		var modified bool
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.GitSHA == "" {
+					info.GitSHA = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if GitSHA == "" && info.GitSHA != "" && modified {
+			info.GitSHA += "-dirty"
+		}
+	}
+	if info.GitSHA == "" {
+		info.GitSHA = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	if info.Features == nil {
+		info.Features = []string{}
+	}
+	return info
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBuildInfo(t *testing.T) {
+	info := GetBuildInfo()
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.NotEmpty(t, info.GitSHA)
+	assert.NotEmpty(t, info.BuildTime)
+	assert.NotNil(t, info.Features)
+
+	GitSHA, BuildTime = "abc123", "2026-10-15T08:00:00Z"
+	t.Cleanup(func() { GitSHA, BuildTime = "", "" })
+	info = GetBuildInfo("webhooks", "video")
+	assert.Equal(t, "abc123", info.GitSHA)
+	assert.Equal(t, "2026-10-15T08:00:00Z", info.BuildTime)
+	assert.Equal(t, []string{"video", "webhooks"}, info.Features)
+}
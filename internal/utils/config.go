@@ -1,17 +1,189 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/joho/godotenv"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
 )
 
 const ImageGoDirect = "image-go_direct"
 const ImageGoTask = "image_tasks"
+const ImageGoCleanup = "image_cleanup"
+
+// ImageGoStatus is the routing key of image status events. Every server
+// binds a queue of its own to it.
+const ImageGoStatus = "image_status"
+
+// LoginLockoutWindow is the period over which failed logins are counted towards a lockout.
+const LoginLockoutWindow = 15 * time.Minute
+
+// ErrRegionUnavailable is returned for data regions this process has no
+// storage for. Objects of such regions are never written elsewhere.
+var ErrRegionUnavailable = errors.New("data region is not available")
+
+// RegionStorage is the bucket and CDN of one data region.
+type RegionStorage struct {
+	S3Bucket         string
+	S3CfDistribution string
+	S3Client         *s3.Client
+}
 
 type Config struct {
 	JwtSecret        string
 	S3Bucket         string
 	S3CfDistribution string
 	S3Client         *s3.Client
-	RabbitMQConn     *amqp.Connection
+	// Region names the data region of S3Bucket, S3CfDistribution and
+	// S3Client, empty for the default one.
+	Region string
+	// Regions holds the storage of the other data regions users can be
+	// pinned to.
+	Regions map[string]RegionStorage
+	// RabbitMQ hands out the channels tasks are published on.
+	RabbitMQ        *pubsub.ChannelPool
+	QueueMaxBacklog int
+	// MaxActiveBatches is how many batches of one user are processed at the
+	// same time; later ones wait. 0 means no limit.
+	MaxActiveBatches       int
+	LoginMaxFailedAttempts int
+	PasswordPolicy         PasswordPolicy
+	Mailer                 Mailer
+	// AppURL is the base URL of links sent in emails.
+	AppURL string
+	// Secrets encrypts stored credentials; nil when no keys are configured.
+	Secrets *Keyring
+	// FingerprintKey authenticates the IDs hidden by invisible watermarks;
+	// nil when they are not enabled.
+	FingerprintKey []byte
+	// FeatureFlags are the experimental features enabled for every user.
+	FeatureFlags []string
+	// ImportSources are the buckets, optionally narrowed to a prefix as
+	// bucket/prefix, that batches can be created from.
+	ImportSources []string
+}
+
+// ImportAllowed reports whether the objects under prefix in bucket can be
+// imported into a batch by anyone. Users still need a source of their own
+// that covers it, see ImportSourceCovers.
+func (c *Config) ImportAllowed(bucket, prefix string) bool {
+	for _, source := range c.ImportSources {
+		b, p, _ := strings.Cut(source, "/")
+		if ImportSourceCovers(b, p, bucket, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportSourceCovers reports whether the source sourceBucket/sourcePrefix
+// holds every object under prefix in bucket. Prefixes are matched by whole
+// path segments, so a source "a/team" covers "a/team/2024/" but not
+// "a/team-b/", nor "a/team", which would list "a/team-b/" too.
+func ImportSourceCovers(sourceBucket, sourcePrefix, bucket, prefix string) bool {
+	if sourceBucket != bucket {
+		return false
+	}
+	sourcePrefix = NormalizeImportPrefix(sourcePrefix)
+	return sourcePrefix == "" || strings.HasPrefix(prefix, sourcePrefix)
+}
+
+// NormalizeImportPrefix returns prefix as a whole path segment: without a
+// leading slash and, unless empty, with a trailing one.
+func NormalizeImportPrefix(prefix string) string {
+	prefix = strings.TrimLeft(prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+// LoadEnv loads the .env.<APP_ENV> profile (when APP_ENV is set) and then
+// .env. Variables already set in the environment win over both files, and the
+// profile wins over .env. A missing .env is not an error.
+func LoadEnv() error {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		if err := godotenv.Load(".env." + env); err != nil {
+			return fmt.Errorf("load profile %q: %w", env, err)
+		}
+	}
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("load .env: %w", err)
+	}
+	return nil
+}
+
+// GetEnvInt reads an optional integer environment variable, returning fallback when it is unset.
+func GetEnvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// GetEnvBool reads an optional boolean environment variable, returning fallback when it is unset.
+func GetEnvBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// minFingerprintKeyLen is the shortest FINGERPRINT_KEY accepted.
+const minFingerprintKeyLen = 32
+
+// LoadFingerprintKey reads FINGERPRINT_KEY, the secret shared by servers and
+// workers that authenticates invisible watermarks. It returns nil when unset.
+func LoadFingerprintKey() ([]byte, error) {
+	key := os.Getenv("FINGERPRINT_KEY")
+	if key == "" {
+		return nil, nil
+	}
+	if len(key) < minFingerprintKeyLen {
+		return nil, fmt.Errorf("must be at least %d characters", minFingerprintKeyLen)
+	}
+	return []byte(key), nil
+}
+
+// Storage returns the bucket and CDN of region, failing with
+// ErrRegionUnavailable when none is configured.
+func (c *Config) Storage(region string) (RegionStorage, error) {
+	if region == c.Region {
+		return RegionStorage{S3Bucket: c.S3Bucket, S3CfDistribution: c.S3CfDistribution, S3Client: c.S3Client}, nil
+	}
+	if storage, ok := c.Regions[region]; ok {
+		return storage, nil
+	}
+	return RegionStorage{}, fmt.Errorf("%w: %q", ErrRegionUnavailable, region)
+}
+
+// TaskQueue names the image task queue of region, consumed by its workers.
+func TaskQueue(region string) string {
+	if region == "" {
+		return ImageGoTask
+	}
+	return ImageGoTask + "." + region
+}
+
+// PriorityTaskQueue names the image task queue of region for users with an
+// active priority boost. Workers consume it next to TaskQueue, so its tasks
+// skip the backlog of the shared queue.
+func PriorityTaskQueue(region string) string {
+	return TaskQueue(region) + ".priority"
+}
+
+// CleanupQueue names the object cleanup queue of region.
+func CleanupQueue(region string) string {
+	if region == "" {
+		return ImageGoCleanup
+	}
+	return ImageGoCleanup + "." + region
 }
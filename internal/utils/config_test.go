@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("S3_BUCKET=base\nQUEUE_MAX_BACKLOG=10\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env.staging"), []byte("S3_BUCKET=staging\n"), 0o600))
+
+	t.Setenv("APP_ENV", "staging")
+	t.Setenv("S3_BUCKET", "")
+	os.Unsetenv("S3_BUCKET")
+	t.Setenv("QUEUE_MAX_BACKLOG", "")
+	os.Unsetenv("QUEUE_MAX_BACKLOG")
+	t.Setenv("S3_CF_DISTRIBUTION", "from-env")
+
+	require.NoError(t, LoadEnv())
+	assert.Equal(t, "staging", os.Getenv("S3_BUCKET"))
+	assert.Equal(t, "10", os.Getenv("QUEUE_MAX_BACKLOG"))
+	assert.Equal(t, "from-env", os.Getenv("S3_CF_DISTRIBUTION"))
+
+	t.Setenv("APP_ENV", "production")
+	assert.Error(t, LoadEnv())
+}
+
+func TestConfigStorage(t *testing.T) {
+	cfg := &Config{
+		S3Bucket: "default",
+		Regions:  map[string]RegionStorage{"eu": {S3Bucket: "eu-bucket"}},
+	}
+
+	storage, err := cfg.Storage("")
+	require.NoError(t, err)
+	assert.Equal(t, "default", storage.S3Bucket)
+
+	storage, err = cfg.Storage("eu")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-bucket", storage.S3Bucket)
+
+	_, err = cfg.Storage("us")
+	assert.ErrorIs(t, err, ErrRegionUnavailable)
+
+	worker := &Config{S3Bucket: "eu-bucket", Region: "eu"}
+	_, err = worker.Storage("")
+	assert.ErrorIs(t, err, ErrRegionUnavailable)
+
+	assert.Equal(t, ImageGoTask, TaskQueue(""))
+	assert.Equal(t, ImageGoTask+".eu", TaskQueue("eu"))
+	assert.Equal(t, ImageGoCleanup+".eu", CleanupQueue("eu"))
+}
+
+func TestConfigImportAllowed(t *testing.T) {
+	cfg := &Config{ImportSources: []string{"legacy", "shared/studio-a/", "team/spring"}}
+
+	assert.True(t, cfg.ImportAllowed("legacy", ""))
+	assert.True(t, cfg.ImportAllowed("legacy", "2019/"))
+	assert.True(t, cfg.ImportAllowed("shared", "studio-a/"))
+	assert.True(t, cfg.ImportAllowed("shared", "studio-a/spring/"))
+	assert.False(t, cfg.ImportAllowed("shared", ""))
+	assert.False(t, cfg.ImportAllowed("shared", "studio-b/"))
+	assert.False(t, cfg.ImportAllowed("other", ""))
+	assert.True(t, cfg.ImportAllowed("team", "spring/"))
+	assert.False(t, cfg.ImportAllowed("team", "spring"))
+	assert.False(t, cfg.ImportAllowed("team", "spring-b/"))
+	assert.False(t, cfg.ImportAllowed("shared", "studio-ab/"))
+	assert.False(t, (&Config{}).ImportAllowed("legacy", ""))
+}
+
+func TestImportSourceCovers(t *testing.T) {
+	assert.True(t, ImportSourceCovers("a", "", "a", "anything/"))
+	assert.True(t, ImportSourceCovers("a", "team", "a", "team/"))
+	assert.True(t, ImportSourceCovers("a", "/team/", "a", "team/2024/"))
+	assert.False(t, ImportSourceCovers("a", "team", "a", "team"))
+	assert.False(t, ImportSourceCovers("a", "team", "a", "team-b/"))
+	assert.False(t, ImportSourceCovers("a", "team", "b", "team/"))
+}
+
+func TestNormalizeImportPrefix(t *testing.T) {
+	assert.Equal(t, "", NormalizeImportPrefix(""))
+	assert.Equal(t, "", NormalizeImportPrefix("/"))
+	assert.Equal(t, "team/", NormalizeImportPrefix("team"))
+	assert.Equal(t, "team/a/", NormalizeImportPrefix("/team/a/"))
+}
+
+func TestLoadFingerprintKey(t *testing.T) {
+	t.Setenv("FINGERPRINT_KEY", "")
+	key, err := LoadFingerprintKey()
+	require.NoError(t, err)
+	assert.Nil(t, key)
+
+	t.Setenv("FINGERPRINT_KEY", "too-short")
+	_, err = LoadFingerprintKey()
+	assert.Error(t, err)
+
+	t.Setenv("FINGERPRINT_KEY", strings.Repeat("k", 32))
+	key, err = LoadFingerprintKey()
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+}
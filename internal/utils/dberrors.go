@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// Domain errors for database failures that are the client's fault rather than the server's.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrConflict         = errors.New("already exists")
+	ErrInvalidReference = errors.New("references a missing record")
+	ErrConstraint       = errors.New("violates a constraint")
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	pgNotNullViolation    = "23502"
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+	pgCheckViolation      = "23514"
+)
+
+// MapDBError wraps err in the matching domain error so callers can use errors.Is on it.
+// Errors that do not map are returned unchanged.
+func MapDBError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case pgUniqueViolation:
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case pgForeignKeyViolation:
+		return fmt.Errorf("%w: %w", ErrInvalidReference, err)
+	case pgNotNullViolation, pgCheckViolation:
+		return fmt.Errorf("%w: %w", ErrConstraint, err)
+	}
+	return err
+}
+
+// ConstraintName returns the constraint or unique index err violates, or ""
+// for other errors, for callers that tell several of them apart.
+func ConstraintName(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+	return ""
+}
+
+// RespondDBError responds 404, 409 or 422 for database errors that map to a domain error, naming
+// resource in the message, and 500 for everything else.
+func RespondDBError(c echo.Context, err error, resource string) error {
+	err = MapDBError(err)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return RespondError(c, http.StatusNotFound, resource+" not found")
+	case errors.Is(err, ErrConflict):
+		return RespondError(c, http.StatusConflict, resource+" already exists")
+	case errors.Is(err, ErrInvalidReference):
+		return RespondError(c, http.StatusUnprocessableEntity, resource+" references a record that does not exist")
+	case errors.Is(err, ErrConstraint):
+		return RespondError(c, http.StatusUnprocessableEntity, resource+" is invalid")
+	}
+	c.Logger().Errorf("database error: %v", err)
+	return RespondError(c, http.StatusInternalServerError, "internal server error")
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapDBError(t *testing.T) {
+	other := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "no rows", err: sql.ErrNoRows, want: ErrNotFound},
+		{name: "wrapped no rows", err: fmt.Errorf("get user: %w", sql.ErrNoRows), want: ErrNotFound},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, want: ErrConflict},
+		{name: "foreign key violation", err: &pq.Error{Code: "23503"}, want: ErrInvalidReference},
+		{name: "check violation", err: &pq.Error{Code: "23514"}, want: ErrConstraint},
+		{name: "other postgres error", err: &pq.Error{Code: "40001"}, want: nil},
+		{name: "other error", err: other, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MapDBError(tt.err)
+			assert.ErrorIs(t, got, tt.err)
+			if tt.want == nil {
+				assert.Equal(t, tt.err, got)
+				return
+			}
+			assert.ErrorIs(t, got, tt.want)
+		})
+	}
+
+	assert.NoError(t, MapDBError(nil))
+}
+
+func TestConstraintName(t *testing.T) {
+	err := &pq.Error{Code: "23505", Constraint: "images_owner_id_external_id_key"}
+	assert.Equal(t, "images_owner_id_external_id_key", ConstraintName(fmt.Errorf("create image: %w", err)))
+	assert.Equal(t, "images_owner_id_external_id_key", ConstraintName(MapDBError(err)))
+	assert.Empty(t, ConstraintName(sql.ErrNoRows))
+}
+
+func TestRespondDBError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: sql.ErrNoRows, want: http.StatusNotFound},
+		{err: &pq.Error{Code: "23505"}, want: http.StatusConflict},
+		{err: &pq.Error{Code: "23503"}, want: http.StatusUnprocessableEntity},
+		{err: errors.New("boom"), want: http.StatusInternalServerError},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+		assert.NoError(t, RespondDBError(c, tt.err, "account"))
+		assert.Equal(t, tt.want, rec.Code, tt.err.Error())
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"regexp"
+	"slices"
+)
+
+// featureFlagPattern is the shape of feature flag names, e.g. "batch_create_v2".
+var featureFlagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// ValidFeatureFlag reports whether name can be used as a feature flag.
+func ValidFeatureFlag(name string) bool {
+	return featureFlagPattern.MatchString(name)
+}
+
+// FeatureFlags merges the flags enabled for everyone with those of a user,
+// sorted and without duplicates.
+func FeatureFlags(global, user []string) []string {
+	flags := append([]string{}, global...)
+	flags = append(flags, user...)
+	slices.Sort(flags)
+	return slices.Compact(flags)
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFeatureFlag(t *testing.T) {
+	for _, name := range []string{"batch_create_v2", "upload.v2", "x"} {
+		assert.True(t, ValidFeatureFlag(name), name)
+	}
+	for _, name := range []string{"", "Batch", "-flag", "has space", string(make([]byte, 65))} {
+		assert.False(t, ValidFeatureFlag(name), name)
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, FeatureFlags([]string{"c", "a"}, []string{"b", "a"}))
+	assert.Equal(t, []string{}, FeatureFlags(nil, nil))
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HIBPClient queries a Have I Been Pwned compatible range API (or a local mirror of it)
+// using k-anonymity, so only the first 5 characters of the password hash leave the server.
+type HIBPClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewHIBPClient(baseURL string) *HIBPClient {
+	return &HIBPClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// BreachCount returns how many times the password appears in the breach corpus.
+func (c *HIBPClient) BreachCount(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected hibp status: %d", res.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		return strconv.Atoi(count)
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"net/netip"
+)
+
+// ParseCIDR parses an IPv4 or IPv6 range, or a single address, into its
+// canonical form with the host bits cleared.
+func ParseCIDR(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if prefix.Addr().Is4In6() {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+// IPAllowed reports whether ip falls in one of cidrs. Unparsable addresses
+// and ranges never match.
+func IPAllowed(ip string, cidrs []string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, cidr := range cidrs {
+		prefix, err := ParseCIDR(cidr)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
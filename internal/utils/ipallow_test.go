@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCIDR(t *testing.T) {
+	for in, want := range map[string]string{
+		"203.0.113.7/24":      "203.0.113.0/24",
+		"203.0.113.7":         "203.0.113.7/32",
+		"2001:db8::1/48":      "2001:db8::/48",
+		"2001:db8::1":         "2001:db8::1/128",
+		"::ffff:10.0.0.1":     "10.0.0.1/32",
+		"::ffff:10.0.0.0/104": "10.0.0.0/8",
+	} {
+		prefix, err := ParseCIDR(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, prefix.String(), in)
+	}
+	for _, in := range []string{"", "10.0.0.0/33", "office", "10.0.0.0/"} {
+		_, err := ParseCIDR(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestIPAllowed(t *testing.T) {
+	cidrs := []string{"203.0.113.0/24", "2001:db8::/32"}
+
+	assert.True(t, IPAllowed("203.0.113.200", cidrs))
+	assert.True(t, IPAllowed("::ffff:203.0.113.9", cidrs))
+	assert.True(t, IPAllowed("2001:db8:1::5", cidrs))
+	assert.False(t, IPAllowed("198.51.100.1", cidrs))
+	assert.False(t, IPAllowed("", cidrs))
+	assert.False(t, IPAllowed("203.0.113.1", nil))
+}
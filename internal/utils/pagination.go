@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+type PaginationMeta struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// GetPagination reads the page and limit query params, applying defaults and bounds.
+func GetPagination(c echo.Context) (int, int, error) {
+	page, limit := 1, DefaultPageLimit
+	if v := c.QueryParam("page"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 {
+			return 0, 0, errors.New("invalid page")
+		}
+		page = p
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > MaxPageLimit {
+			return 0, 0, errors.New("invalid limit")
+		}
+		limit = l
+	}
+	return page, limit, nil
+}
+
+func NewPaginationMeta(page, limit, total int) PaginationMeta {
+	return PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}
+}
+
+// Cursor is a position in a feed ordered by a sequence number, then ID. The
+// zero Cursor is before every row.
+type Cursor struct {
+	Seq int64
+	ID  uuid.UUID
+}
+
+// cursorPrefix tells cursors from the time-ordered ones handed out before.
+const cursorPrefix = "s"
+
+// Encode returns the cursor as an opaque, URL-safe string.
+func (c Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(c.Seq, 10) + "." + c.ID.String()))
+}
+
+// DecodeCursor parses a cursor made by Cursor.Encode. A cursor of the
+// earlier time-ordered feed decodes to the zero Cursor, so its client starts
+// over.
+func DecodeCursor(s string) (Cursor, error) {
+	errInvalid := errors.New("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errInvalid
+	}
+	text, current := strings.CutPrefix(string(raw), cursorPrefix)
+	number, id, ok := strings.Cut(text, ".")
+	if !ok {
+		return Cursor{}, errInvalid
+	}
+	seq, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return Cursor{}, errInvalid
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, errInvalid
+	}
+	if !current {
+		return Cursor{}, nil
+	}
+	return Cursor{Seq: seq, ID: uid}, nil
+}
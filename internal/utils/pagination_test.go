@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	cur := Cursor{Seq: 7301, ID: uuid.New()}
+	got, err := DecodeCursor(cur.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cur, got)
+
+	got, err = DecodeCursor(Cursor{}.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{}, got)
+
+	// A time-ordered cursor from before starts the feed over.
+	legacy := base64.RawURLEncoding.EncodeToString([]byte("1792053000123456." + cur.ID.String()))
+	got, err = DecodeCursor(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{}, got)
+
+	for _, s := range []string{"", "not base64!", "MTIz", "YWJjLm5vdC1hLXV1aWQ"} {
+		_, err := DecodeCursor(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// paramKey is the context key of a path parameter parsed by
+// middleware.UUIDParams, apart from keys such as the authenticated userID.
+func paramKey(name string) string {
+	return "param." + name
+}
+
+// SetParamUUID stores the parsed value of the path parameter name.
+func SetParamUUID(c echo.Context, name string, id uuid.UUID) {
+	c.Set(paramKey(name), id)
+}
+
+// ParamUUID returns the path parameter name as parsed by
+// middleware.UUIDParams, or uuid.Nil on routes without it.
+func ParamUUID(c echo.Context, name string) uuid.UUID {
+	id, _ := c.Get(paramKey(name)).(uuid.UUID)
+	return id
+}
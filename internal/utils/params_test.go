@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamUUID(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+	userID, batchID := uuid.New(), uuid.New()
+	c.Set("userID", userID)
+
+	assert.Equal(t, uuid.Nil, ParamUUID(c, "batchID"))
+	SetParamUUID(c, "batchID", batchID)
+	SetParamUUID(c, "userID", uuid.New())
+	assert.Equal(t, batchID, ParamUUID(c, "batchID"))
+	assert.Equal(t, userID, c.Get("userID"), "path parameters do not replace the authenticated user")
+}
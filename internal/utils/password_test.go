@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	tests := []struct {
+		name               string
+		password           string
+		expectedViolations int
+	}{
+		{
+			name:               "valid password",
+			password:           "Passw0rd!",
+			expectedViolations: 0,
+		},
+		{
+			name:               "too short",
+			password:           "Pa0!",
+			expectedViolations: 1,
+		},
+		{
+			name:               "missing character classes",
+			password:           "password",
+			expectedViolations: 3,
+		},
+		{
+			name:               "empty password",
+			password:           "",
+			expectedViolations: 5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations := policy.Validate(test.password)
+			assert.Len(t, violations, test.expectedViolations)
+		})
+	}
+}
+
+func TestHIBPClientBreachCount(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/range/5BAA6" {
+			fmt.Fprint(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n")
+			return
+		}
+		fmt.Fprint(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\r\n")
+	}))
+	defer server.Close()
+
+	client := NewHIBPClient(server.URL)
+
+	count, err := client.BreachCount(context.Background(), "password")
+	assert.NoError(t, err)
+	assert.Equal(t, 3861493, count)
+
+	count, err = client.BreachCount(context.Background(), "not-in-the-list")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrNotPublic is returned for addresses that are not routable on the
+// internet.
+var ErrNotPublic = errors.New("address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, which is not public
+// even though netip does not count it as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddr reports whether ip is routable on the internet.
+func IsPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// DialPublicOnly is a net.Dialer Control func that refuses connections to
+// addresses that are not public. It runs after DNS resolution, so redirects
+// and DNS changes cannot point outgoing requests at internal services.
+func DialPublicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddr(ip) {
+		return fmt.Errorf("%s: %w", ip, ErrNotPublic)
+	}
+	return nil
+}
+
+// CheckPublicHost resolves host and fails unless every address it resolves
+// to is public. It catches URLs users register up front; requests still go
+// through DialPublicOnly because the records can change later.
+func CheckPublicHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return fmt.Errorf("%s resolves to %s: %w", host, addr.Unmap(), ErrNotPublic)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.215.14":         true,
+		"2606:2800:21f:cb07::1": true,
+		"127.0.0.1":             false,
+		"10.1.2.3":              false,
+		"172.16.0.1":            false,
+		"192.168.1.1":           false,
+		"169.254.169.254":       false,
+		"100.64.0.1":            false,
+		"0.0.0.0":               false,
+		"::1":                   false,
+		"fd00::1":               false,
+		"::ffff:127.0.0.1":      false,
+	} {
+		assert.Equal(t, want, IsPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckPublicHost(t *testing.T) {
+	assert.ErrorIs(t, CheckPublicHost(context.Background(), "127.0.0.1"), ErrNotPublic)
+	assert.Error(t, CheckPublicHost(context.Background(), "::1"))
+	assert.Error(t, CheckPublicHost(context.Background(), "169.254.169.254"))
+	assert.NoError(t, CheckPublicHost(context.Background(), "93.184.215.14"))
+}
@@ -5,12 +5,19 @@ import (
 )
 
 type ErrorResponse struct {
-	Message string `json:"message"`
+	Message string       `json:"message" example:"batch not found"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field" example:"password"`
+	Message string `json:"message" example:"must be at least 8 characters long"`
 }
 
 type SuccessResponse struct {
-	Message string `json:"message"`
-	Data    any    `json:"data,omitempty"`
+	Message string          `json:"message" example:"batch retrieved successfully"`
+	Data    any             `json:"data,omitempty"`
+	Meta    *PaginationMeta `json:"meta,omitempty"`
 }
 
 func RespondError(c echo.Context, code int, msg string) error {
@@ -19,9 +26,24 @@ func RespondError(c echo.Context, code int, msg string) error {
 	})
 }
 
+func RespondFieldErrors(c echo.Context, code int, msg string, errs []FieldError) error {
+	return c.JSON(code, ErrorResponse{
+		Message: msg,
+		Errors:  errs,
+	})
+}
+
 func RespondJSON(c echo.Context, code int, msg string, data any) error {
 	return c.JSON(code, SuccessResponse{
 		Message: msg,
 		Data:    data,
 	})
 }
+
+func RespondPaginated(c echo.Context, code int, msg string, data any, meta PaginationMeta) error {
+	return c.JSON(code, SuccessResponse{
+		Message: msg,
+		Data:    data,
+		Meta:    &meta,
+	})
+}
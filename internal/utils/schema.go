@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Servers and workers of one build share the range of database schemas they
+// run against, the goose versions of sql/schema:
+//
+//   - SchemaVersion is the newest migration the build knows. A newer schema
+//     is only warned about: migrations must be additive for at least one
+//     release (new tables, nullable or defaulted columns, new enum values),
+//     so a build that is still rolling out keeps working after the next
+//     release has migrated.
+//   - MinSchemaVersion is the oldest schema the build runs against, the
+//     newest migration its queries depend on. An older schema is refused, so
+//     a build deployed ahead of its migrations does not take requests or
+//     tasks on a half-migrated database. Raise it with every migration the
+//     code starts to use, and drop columns or tables only once no running
+//     build reads them.
+const (
+	SchemaVersion    = 73
+	MinSchemaVersion = 73
+)
+
+// ErrSchemaTooOld is returned by CheckSchema when migrations this build
+// depends on have not been applied yet.
+var ErrSchemaTooOld = errors.New("database schema is older than this build supports")
+
+// CheckSchema returns the migration version of db, failing with
+// ErrSchemaTooOld below MinSchemaVersion. Callers should warn when it is
+// above SchemaVersion.
+func CheckSchema(ctx context.Context, db *sql.DB) (int64, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return 0, fmt.Errorf("read migration version: %w", err)
+	}
+	defer rows.Close()
+	var migrations []gooseMigration
+	for rows.Next() {
+		var m gooseMigration
+		if err := rows.Scan(&m.version, &m.applied); err != nil {
+			return 0, err
+		}
+		migrations = append(migrations, m)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	version := currentSchemaVersion(migrations)
+	if version < MinSchemaVersion {
+		return version, fmt.Errorf("%w: at version %d, need %d", ErrSchemaTooOld, version, MinSchemaVersion)
+	}
+	return version, nil
+}
+
+// gooseMigration is a row of goose's version table.
+type gooseMigration struct {
+	version int64
+	applied bool
+}
+
+// currentSchemaVersion works out the version from goose's log of migrations,
+// newest first, the way goose does: the newest migration applied and not
+// rolled back since.
+func currentSchemaVersion(migrations []gooseMigration) int64 {
+	rolledBack := make(map[int64]bool)
+	for _, m := range migrations {
+		if rolledBack[m.version] {
+			continue
+		}
+		if m.applied {
+			return m.version
+		}
+		rolledBack[m.version] = true
+	}
+	return 0
+}
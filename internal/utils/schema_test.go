@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrentSchemaVersion(t *testing.T) {
+	assert.Equal(t, int64(0), currentSchemaVersion(nil))
+	assert.Equal(t, int64(3), currentSchemaVersion([]gooseMigration{{3, true}, {2, true}, {1, true}}))
+	// 3 was applied, then rolled back.
+	assert.Equal(t, int64(2), currentSchemaVersion([]gooseMigration{{3, false}, {3, true}, {2, true}}))
+	// 2 was rolled back and applied again.
+	assert.Equal(t, int64(2), currentSchemaVersion([]gooseMigration{{2, true}, {2, false}, {2, true}, {1, true}}))
+}
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	entries, err := os.ReadDir("../../sql/schema")
+	require.NoError(t, err)
+	var newest int64
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		require.NoError(t, err, entry.Name())
+		newest = max(newest, version)
+	}
+	assert.Equal(t, newest, int64(SchemaVersion), "SchemaVersion must be the newest migration in sql/schema")
+	assert.LessOrEqual(t, MinSchemaVersion, SchemaVersion)
+}
@@ -0,0 +1,13 @@
+package utils
+
+import "strings"
+
+// likeEscaper escapes the wildcards of LIKE and ILIKE patterns, for queries
+// that match with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike makes s match itself literally inside a LIKE pattern, so a
+// search for "50%_off" does not match every name starting with "50".
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
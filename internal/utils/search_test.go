@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"spring-catalog", "spring-catalog"},
+		{"50%", `50\%`},
+		{"img_001", `img\_001`},
+		{`C:\photos`, `C:\\photos`},
+		{`\%_`, `\\\%\_`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, EscapeLike(tt.in), tt.in)
+	}
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownSecretKey is returned when a secret was encrypted with a key that
+// is no longer in the keyring.
+var ErrUnknownSecretKey = errors.New("secret encrypted with unknown key")
+
+// Keyring encrypts credentials stored in the database with AES-256-GCM. Each
+// tenant gets its own key, derived from a master key with HKDF, so a
+// ciphertext cannot be moved to another user's row. Ciphertexts name the
+// master key they were made with, so old keys can stay in the keyring for
+// decryption while everything is re-encrypted with the primary one.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring parses keys like "2025b:<base64>,2025a:<base64>", where every
+// master key is 32 random bytes. The first key is the primary key used for
+// encryption, the others are only used to decrypt.
+func NewKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	for _, entry := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key %q must look like <id>:<base64 key>", entry)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", id, len(key))
+		}
+		if k.primary == "" {
+			k.primary = id
+		}
+		k.keys[id] = key
+	}
+	return k, nil
+}
+
+// Encrypt seals plaintext for tenantID with the primary key.
+func (k *Keyring) Encrypt(tenantID uuid.UUID, plaintext string) (string, error) {
+	aead, err := k.aead(k.primary, tenantID)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return k.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a ciphertext made by Encrypt for the same tenant.
+func (k *Keyring) Decrypt(tenantID uuid.UUID, ciphertext string) (string, error) {
+	id, encoded, ok := strings.Cut(ciphertext, ":")
+	if !ok {
+		return "", fmt.Errorf("malformed secret")
+	}
+	aead, err := k.aead(id, tenantID)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed secret: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed secret")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether ciphertext was made with a key other than the
+// primary one.
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	id, _, _ := strings.Cut(ciphertext, ":")
+	return id != k.primary
+}
+
+// PrimaryKeyID is the id of the key new secrets are encrypted with.
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+func (k *Keyring) aead(id string, tenantID uuid.UUID) (cipher.AEAD, error) {
+	master, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSecretKey, id)
+	}
+	key, err := hkdf.Key(sha256.New, master, nil, "image-go secrets "+tenantID.String(), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	oldKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32)))
+	tenant := uuid.New()
+
+	old, err := NewKeyring("k1:" + oldKey)
+	require.NoError(t, err)
+	ciphertext, err := old.Encrypt(tenant, "sftp-password")
+	require.NoError(t, err)
+	assert.NotContains(t, ciphertext, "sftp-password")
+
+	rotated, err := NewKeyring("k2:" + newKey + ",k1:" + oldKey)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", rotated.PrimaryKeyID())
+	assert.True(t, rotated.NeedsRotation(ciphertext))
+	plaintext, err := rotated.Decrypt(tenant, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "sftp-password", plaintext)
+
+	reencrypted, err := rotated.Encrypt(tenant, plaintext)
+	require.NoError(t, err)
+	assert.False(t, rotated.NeedsRotation(reencrypted))
+
+	_, err = rotated.Decrypt(uuid.New(), reencrypted)
+	assert.Error(t, err, "secrets are bound to their tenant")
+
+	retired, err := NewKeyring("k2:" + newKey)
+	require.NoError(t, err)
+	_, err = retired.Decrypt(tenant, ciphertext)
+	assert.ErrorIs(t, err, ErrUnknownSecretKey)
+
+	for _, spec := range []string{"", "k1", "k1:not-base64", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), "k1:" + oldKey + ",k1:" + newKey} {
+		_, err := NewKeyring(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -27,24 +27,119 @@ func GenerateJWT(userID uuid.UUID, tokenSecret string) (string, error) {
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTExpiry(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ValidateJWTExpiry is ValidateJWT that also returns when the token expires,
+// for connections that must not outlive it.
+func ValidateJWTExpiry(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (any, error) {
 		return []byte(tokenSecret), nil
-	})
+	}, jwt.WithIssuer("image-go"), jwt.WithExpirationRequired())
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok {
 		userID, err := claims.GetSubject()
 		if err != nil {
-			return uuid.Nil, err
+			return uuid.Nil, time.Time{}, err
 		}
 		id, err := uuid.Parse(userID)
 		if err != nil {
-			return uuid.Nil, err
+			return uuid.Nil, time.Time{}, err
 		}
-		return id, nil
+		return id, claims.ExpiresAt.Time, nil
+	}
+	return uuid.Nil, time.Time{}, errors.New("unknown claims type, cannot proceed")
+}
+
+// SocketClaims let a client that cannot set headers, such as a browser,
+// open GET /ws. SessionExpiresAt is the expiry of the access token the
+// ticket was issued for, which the socket does not outlive.
+type SocketClaims struct {
+	SessionExpiresAt *jwt.NumericDate `json:"session_exp"`
+	jwt.RegisteredClaims
+}
+
+// GenerateSocketTicket issues a short-lived ticket for opening a WebSocket
+// in a query parameter. It uses a distinct issuer so it is rejected by
+// ValidateJWT.
+func GenerateSocketTicket(userID uuid.UUID, sessionExpiresAt time.Time, tokenSecret string, expiresIn time.Duration) (string, error) {
+	jwt := jwt.NewWithClaims(jwt.SigningMethodHS256, SocketClaims{
+		SessionExpiresAt: jwt.NewNumericDate(sessionExpiresAt),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "image-go-socket",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	})
+	return jwt.SignedString([]byte(tokenSecret))
+}
+
+// ValidateSocketTicket returns the user of a socket ticket and the expiry of
+// the session it was issued for.
+func ValidateSocketTicket(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
+	claims := &SocketClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		return []byte(tokenSecret), nil
+	}, jwt.WithIssuer("image-go-socket"), jwt.WithExpirationRequired())
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	if claims.SessionExpiresAt == nil {
+		return uuid.Nil, time.Time{}, errors.New("ticket has no session expiry")
+	}
+	return userID, claims.SessionExpiresAt.Time, nil
+}
+
+// UploadClaims scope a token to uploading images into a single batch.
+type UploadClaims struct {
+	BatchID string `json:"batch_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateUploadJWT issues a short-lived token that only grants access to the direct upload endpoints of one batch.
+// It uses a distinct issuer so it is rejected by ValidateJWT.
+func GenerateUploadJWT(userID, batchID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	jwt := jwt.NewWithClaims(jwt.SigningMethodHS256, UploadClaims{
+		BatchID: batchID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "image-go-upload",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	})
+	token, err := jwt.SignedString([]byte(tokenSecret))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func ValidateUploadJWT(tokenString, tokenSecret string) (uuid.UUID, uuid.UUID, error) {
+	claims := &UploadClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		return []byte(tokenSecret), nil
+	}, jwt.WithIssuer("image-go-upload"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	batchID, err := uuid.Parse(claims.BatchID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
 	}
-	return uuid.Nil, errors.New("unknown claims type, cannot proceed")
+	return userID, batchID, nil
 }
 
 func GenerateRefresh() (string, error) {
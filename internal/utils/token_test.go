@@ -3,6 +3,7 @@ package utils
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -20,6 +21,51 @@ func TestGenerateAndValidateJWT(t *testing.T) {
 	assert.Equal(t, id, uuid)
 }
 
+func TestGenerateAndValidateUploadJWT(t *testing.T) {
+	userID := uuid.New()
+	batchID := uuid.New()
+	token, err := GenerateUploadJWT(userID, batchID, "secret", 5*time.Minute)
+	assert.Nil(t, err)
+
+	gotUserID, gotBatchID, err := ValidateUploadJWT(token, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, userID, gotUserID)
+	assert.Equal(t, batchID, gotBatchID)
+
+	_, err = ValidateJWT(token, "secret")
+	assert.NotNil(t, err, "upload token must not be accepted as an access token")
+
+	accessToken, err := GenerateJWT(userID, "secret")
+	assert.Nil(t, err)
+	_, _, err = ValidateUploadJWT(accessToken, "secret")
+	assert.NotNil(t, err, "access token must not be accepted as an upload token")
+}
+
+func TestGenerateAndValidateSocketTicket(t *testing.T) {
+	userID := uuid.New()
+	accessToken, err := GenerateJWT(userID, "secret")
+	assert.Nil(t, err)
+	_, sessionExpiresAt, err := ValidateJWTExpiry(accessToken, "secret")
+	assert.Nil(t, err)
+
+	ticket, err := GenerateSocketTicket(userID, sessionExpiresAt, "secret", 30*time.Second)
+	assert.Nil(t, err)
+	gotUserID, gotExpiresAt, err := ValidateSocketTicket(ticket, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, userID, gotUserID)
+	assert.WithinDuration(t, sessionExpiresAt, gotExpiresAt, time.Second)
+
+	_, err = ValidateJWT(ticket, "secret")
+	assert.NotNil(t, err, "socket ticket must not be accepted as an access token")
+	_, _, err = ValidateSocketTicket(accessToken, "secret")
+	assert.NotNil(t, err, "access token must not be accepted as a socket ticket")
+
+	expired, err := GenerateSocketTicket(userID, sessionExpiresAt, "secret", -time.Second)
+	assert.Nil(t, err)
+	_, _, err = ValidateSocketTicket(expired, "secret")
+	assert.NotNil(t, err, "expired tickets are refused")
+}
+
 func TestGenerateRefreshToken(t *testing.T) {
 	token, err := GenerateRefresh()
 	assert.NotNil(t, token)
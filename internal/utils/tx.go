@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+type txContextKey struct{}
+
+// Tx is a request-scoped transaction. Callbacks registered with AfterCommit
+// run only once the transaction has been committed.
+type Tx struct {
+	*sql.Tx
+	afterCommit []func()
+}
+
+// Commit commits the transaction and then runs the AfterCommit callbacks in order.
+func (t *Tx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	for _, fn := range t.afterCommit {
+		fn()
+	}
+	return nil
+}
+
+// ContextWithTx returns a copy of ctx that carries tx.
+func ContextWithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// Queries returns dbQueries bound to the transaction carried by ctx, or
+// dbQueries itself when the request is not wrapped in one.
+func Queries(ctx context.Context, dbQueries *database.Queries) *database.Queries {
+	if tx, ok := ctx.Value(txContextKey{}).(*Tx); ok {
+		return dbQueries.WithTx(tx.Tx)
+	}
+	return dbQueries
+}
+
+// AfterCommit defers fn until the transaction carried by ctx commits, so side
+// effects such as queue messages never refer to rows that are rolled back or
+// not yet visible. Without a transaction fn runs immediately.
+func AfterCommit(ctx context.Context, fn func()) {
+	if tx, ok := ctx.Value(txContextKey{}).(*Tx); ok {
+		tx.afterCommit = append(tx.afterCommit, fn)
+		return
+	}
+	fn()
+}
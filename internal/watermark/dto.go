@@ -0,0 +1,26 @@
+package watermark
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WatermarkResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	SizeBytes   int64     `json:"size_bytes"`
+	// IsDefault is set on the watermark new batches get when created
+	// without one.
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateWatermarkRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
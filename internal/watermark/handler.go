@@ -0,0 +1,395 @@
+package watermark
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// maxWatermarkSize is the largest watermark file accepted for upload.
+	maxWatermarkSize = 10 << 20
+	// MaxWatermarkDimension bounds either side of a watermark. Watermarks are
+	// drawn at a fraction of the image width, so larger ones only cost the
+	// worker memory.
+	MaxWatermarkDimension = 4096
+)
+
+var (
+	ErrUnsupportedWatermark = errors.New("unsupported watermark file, expected JPEG, PNG or WebP")
+	ErrWatermarkTooLarge    = fmt.Errorf("watermark too large, expected at most %dx%d pixels", MaxWatermarkDimension, MaxWatermarkDimension)
+)
+
+type WatermarkHandler struct {
+	validator *validator.Validate
+	dbQueries *database.Queries
+	config    *utils.Config
+}
+
+func NewHandler(validator *validator.Validate, dbQueries *database.Queries, config *utils.Config) *WatermarkHandler {
+	return &WatermarkHandler{
+		validator: validator,
+		dbQueries: dbQueries,
+		config:    config,
+	}
+}
+
+// GetAll godoc
+// @Summary Get list of watermarks
+// @Description Retrieve the watermarks in the authenticated user's library
+// @Tags watermarks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=[]WatermarkResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /watermarks [get]
+func (h *WatermarkHandler) GetAll(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	watermarks, err := h.dbQueries.GetUserWatermarks(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	watermarksRes := make([]WatermarkResponse, len(watermarks))
+	for i, w := range watermarks {
+		watermarksRes[i] = toWatermarkResponse(w, user.DefaultWatermarkID)
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "watermarks retrieved successfully", watermarksRes)
+}
+
+// GetByID godoc
+// @Summary Get watermark
+// @Description Retrieve a watermark of the authenticated user's library
+// @Tags watermarks
+// @Produce json
+// @Security BearerAuth
+// @Param watermarkID path string true "Watermark ID"
+// @Success 200 {object} utils.SuccessResponse{data=WatermarkResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /watermarks/{watermarkID} [get]
+func (h *WatermarkHandler) GetByID(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	watermarkUUID := utils.ParamUUID(c, "watermarkID")
+
+	watermark, err := h.dbQueries.GetUserWatermarkByID(c.Request().Context(), database.GetUserWatermarkByIDParams{
+		ID:     watermarkUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "watermark not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "watermark retrieved successfully", toWatermarkResponse(watermark, user.DefaultWatermarkID))
+}
+
+// Upload godoc
+// @Summary Upload watermark
+// @Description Add a JPEG, PNG or WebP watermark (max 10 MB, 4096 pixels per side) to the library, to be referenced by watermark_id when creating batches
+// @Tags watermarks
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param name formData string false "Watermark name, defaults to the file name"
+// @Param file formData file true "Watermark image"
+// @Success 201 {object} utils.SuccessResponse{data=WatermarkResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 413 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /watermarks [post]
+func (h *WatermarkHandler) Upload(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "no watermark file uploaded")
+	}
+	if file.Size > maxWatermarkSize {
+		return utils.RespondError(c, http.StatusRequestEntityTooLarge, "watermark file too large")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxWatermarkSize+1))
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if len(data) > maxWatermarkSize {
+		return utils.RespondError(c, http.StatusRequestEntityTooLarge, "watermark file too large")
+	}
+
+	mediaType, cfg, err := ValidateWatermark(data)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	name := c.FormValue("name")
+	if name == "" {
+		name = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	}
+	if len(name) > 255 {
+		return utils.RespondError(c, http.StatusBadRequest, "watermark name too long")
+	}
+
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	storage, err := h.config.Storage(user.Region)
+	if err != nil {
+		c.Logger().Errorf("failed to upload watermark: %v", err)
+		return utils.RespondError(c, http.StatusServiceUnavailable, "data region is not available")
+	}
+
+	key := "watermarks/" + userID.String() + "/" + utils.GetAssetPath(mediaType)
+	_, err = storage.S3Client.PutObject(c.Request().Context(), &s3.PutObjectInput{
+		Bucket:      aws.String(storage.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(mediaType),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	watermark, err := h.dbQueries.CreateWatermark(c.Request().Context(), database.CreateWatermarkParams{
+		UserID:      userID,
+		Name:        name,
+		Key:         key,
+		Url:         utils.GetObjectURL(storage.S3CfDistribution, key),
+		ContentType: mediaType,
+		Width:       int32(cfg.Width),
+		Height:      int32(cfg.Height),
+		SizeBytes:   int64(len(data)),
+		Region:      user.Region,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusCreated, "watermark uploaded successfully", toWatermarkResponse(watermark, uuid.NullUUID{}))
+}
+
+// Update godoc
+// @Summary Rename watermark
+// @Description Rename a watermark of the authenticated user's library
+// @Tags watermarks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param watermarkID path string true "Watermark ID"
+// @Param watermark body UpdateWatermarkRequest true "Update Watermark Request"
+// @Success 200 {object} utils.SuccessResponse{data=WatermarkResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /watermarks/{watermarkID} [patch]
+func (h *WatermarkHandler) Update(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	watermarkUUID := utils.ParamUUID(c, "watermarkID")
+
+	var body UpdateWatermarkRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	watermark, err := h.dbQueries.UpdateWatermarkName(c.Request().Context(), database.UpdateWatermarkNameParams{
+		Name:   body.Name,
+		ID:     watermarkUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "watermark not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	user, err := h.dbQueries.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "watermark updated successfully", toWatermarkResponse(watermark, user.DefaultWatermarkID))
+}
+
+// DeleteByID godoc
+// @Summary Delete watermark
+// @Description Remove a watermark from the library. Batches already using it keep rendering it; if it was the default watermark, new batches no longer get one
+// @Tags watermarks
+// @Produce json
+// @Security BearerAuth
+// @Param watermarkID path string true "Watermark ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /watermarks/{watermarkID} [delete]
+func (h *WatermarkHandler) DeleteByID(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	watermarkUUID := utils.ParamUUID(c, "watermarkID")
+
+	deleted, err := h.dbQueries.DeleteWatermarkByID(c.Request().Context(), database.DeleteWatermarkByIDParams{
+		ID:     watermarkUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	if deleted == 0 {
+		return utils.RespondError(c, http.StatusNotFound, "watermark not found")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "watermark deleted successfully", nil)
+}
+
+// SetDefault godoc
+// @Summary Set default watermark
+// @Description Make a library watermark the account default, applied to new batches created without any watermark unless they opt out with skip_default_watermark
+// @Tags watermarks
+// @Produce json
+// @Security BearerAuth
+// @Param watermarkID path string true "Watermark ID"
+// @Success 200 {object} utils.SuccessResponse{data=WatermarkResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /watermarks/{watermarkID}/default [put]
+func (h *WatermarkHandler) SetDefault(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	watermarkUUID := utils.ParamUUID(c, "watermarkID")
+
+	watermark, err := h.dbQueries.GetUserWatermarkByID(c.Request().Context(), database.GetUserWatermarkByIDParams{
+		ID:     watermarkUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "watermark not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	defaultID := uuid.NullUUID{UUID: watermark.ID, Valid: true}
+	if err := h.dbQueries.SetUserDefaultWatermark(c.Request().Context(), database.SetUserDefaultWatermarkParams{
+		ID:                 userID,
+		DefaultWatermarkID: defaultID,
+	}); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return utils.RespondJSON(c, http.StatusOK, "default watermark set successfully", toWatermarkResponse(watermark, defaultID))
+}
+
+// ClearDefault godoc
+// @Summary Clear default watermark
+// @Description Stop applying a watermark to new batches created without one
+// @Tags watermarks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /watermarks/default [delete]
+func (h *WatermarkHandler) ClearDefault(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	if err := h.dbQueries.SetUserDefaultWatermark(c.Request().Context(), database.SetUserDefaultWatermarkParams{
+		ID: userID,
+	}); err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "default watermark cleared successfully", nil)
+}
+
+// ValidateWatermark checks that data is a JPEG, PNG or WebP image of a sane
+// size and returns its media type and dimensions.
+func ValidateWatermark(data []byte) (string, image.Config, error) {
+	mediaType := http.DetectContentType(data)
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return "", image.Config{}, ErrUnsupportedWatermark
+	}
+
+	cfg, err := DecodeWatermarkConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", image.Config{}, err
+	}
+	return mediaType, cfg, nil
+}
+
+// DecodeWatermarkConfig reads the dimensions of a watermark image, failing
+// with ErrWatermarkTooLarge when a side exceeds MaxWatermarkDimension.
+func DecodeWatermarkConfig(r io.Reader) (image.Config, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return image.Config{}, ErrUnsupportedWatermark
+	}
+	if cfg.Width > MaxWatermarkDimension || cfg.Height > MaxWatermarkDimension {
+		return image.Config{}, ErrWatermarkTooLarge
+	}
+	return cfg, nil
+}
+
+func toWatermarkResponse(w database.Watermark, defaultID uuid.NullUUID) WatermarkResponse {
+	return WatermarkResponse{
+		ID:          w.ID,
+		Name:        w.Name,
+		URL:         w.Url,
+		ContentType: w.ContentType,
+		Width:       int(w.Width),
+		Height:      int(w.Height),
+		SizeBytes:   w.SizeBytes,
+		IsDefault:   defaultID.Valid && defaultID.UUID == w.ID,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
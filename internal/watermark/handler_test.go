@@ -0,0 +1,47 @@
+package watermark
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWatermark(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 20))))
+	pngData := buf.Bytes()
+	buf = bytes.Buffer{}
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8000, 10))))
+	widePNG := buf.Bytes()
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType string
+		wantErr  error
+	}{
+		{name: "png", data: pngData, wantType: "image/png"},
+		{name: "empty file", data: nil, wantErr: ErrUnsupportedWatermark},
+		{name: "not an image", data: []byte("GIF89a"), wantErr: ErrUnsupportedWatermark},
+		{name: "truncated png", data: pngData[:12], wantErr: ErrUnsupportedWatermark},
+		{name: "too wide", data: widePNG, wantErr: ErrWatermarkTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, cfg, err := ValidateWatermark(tt.data)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantType, mediaType)
+			assert.Equal(t, 40, cfg.Width)
+			assert.Equal(t, 20, cfg.Height)
+		})
+	}
+}
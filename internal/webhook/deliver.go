@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const (
+	// deliveryTimeout bounds a single webhook request.
+	deliveryTimeout = 10 * time.Second
+	// responseExcerptSize is how much of a response body the delivery log keeps.
+	responseExcerptSize = 1 << 10
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-ImageGo-Event"
+	HeaderSignature = "X-ImageGo-Signature"
+)
+
+// Events sent to every webhook of a user.
+const (
+	// EventTest is sent by the test endpoint.
+	EventTest = "webhook.test"
+	// EventImageFailed is sent when an image is marked failed.
+	EventImageFailed = "image.failed"
+	// EventBatchCompleted is sent when no image of a batch is left to
+	// process, also when some failed.
+	EventBatchCompleted = "batch.completed"
+	// EventBatchRestored is sent when the objects of an archived batch are
+	// retrievable again.
+	EventBatchRestored = "batch.restored"
+)
+
+// client only connects to public addresses, checked on every connection so
+// DNS changes cannot point deliveries at internal services. Redirects are not
+// followed; the redirect response is recorded like any other.
+var client = &http.Client{
+	Timeout: deliveryTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: utils.DialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Envelope is the body of every delivery.
+type Envelope struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the signature header for a body sent at t, in the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Receivers recompute it with their secret and reject old timestamps to stop
+// replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver POSTs a signed payload to hook and records the attempt in its
+// delivery log. Failed requests are recorded too; only errors reading the
+// secret or writing the log are returned.
+func Deliver(ctx context.Context, dbQueries *database.Queries, secrets *utils.Keyring, hook database.Webhook, event string, payload []byte) (database.WebhookDelivery, error) {
+	delivery, _, err := deliver(ctx, dbQueries, secrets, hook, event, payload, uuid.NullUUID{})
+	return delivery, err
+}
+
+// deliver is Deliver for an attempt at a queued event. refused reports that
+// the attempt was not made because the URL points at an address that is not
+// public, which retrying will not fix.
+func deliver(ctx context.Context, dbQueries *database.Queries, secrets *utils.Keyring, hook database.Webhook, event string, payload []byte, eventID uuid.NullUUID) (delivery database.WebhookDelivery, refused bool, err error) {
+	secret, err := secrets.Decrypt(hook.UserID, hook.Secret)
+	if err != nil {
+		return database.WebhookDelivery{}, false, fmt.Errorf("decrypt webhook secret: %w", err)
+	}
+	params, sendErr := send(ctx, client, hook.Url, secret, event, payload)
+	params.WebhookID = hook.ID
+	params.EventID = eventID
+	delivery, err = dbQueries.CreateWebhookDelivery(ctx, params)
+	return delivery, errors.Is(sendErr, utils.ErrNotPublic), err
+}
+
+// send makes one delivery attempt and describes its outcome. The error is
+// the reason no response was received, also recorded in the params.
+func send(ctx context.Context, client *http.Client, url, secret, event string, payload []byte) (database.CreateWebhookDeliveryParams, error) {
+	params := database.CreateWebhookDeliveryParams{
+		Event:   event,
+		Payload: json.RawMessage(payload),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		params.Error = sql.NullString{String: err.Error(), Valid: true}
+		return params, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "image-go-webhooks")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderSignature, Sign(secret, time.Now(), payload))
+
+	start := time.Now()
+	res, err := client.Do(req)
+	params.LatencyMs = int32(time.Since(start).Milliseconds())
+	if err != nil {
+		params.Error = sql.NullString{String: err.Error(), Valid: true}
+		return params, err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, responseExcerptSize))
+	excerpt := sanitizeExcerpt(body)
+	params.StatusCode = sql.NullInt32{Int32: int32(res.StatusCode), Valid: true}
+	params.ResponseExcerpt = sql.NullString{String: excerpt, Valid: excerpt != ""}
+	return params, nil
+}
+
+// sanitizeExcerpt makes a response body storable as TEXT: Postgres rejects
+// invalid UTF-8 and NUL bytes, which binary bodies and cut-off multibyte
+// characters would otherwise bring in.
+func sanitizeExcerpt(body []byte) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(string(body), ""), "\x00", "")
+}
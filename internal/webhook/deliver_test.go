@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"webhook.test"}`)
+
+	sig := Sign("whsec_a", at, body)
+	assert.True(t, strings.HasPrefix(sig, "t=1700000000,v1="))
+	assert.Equal(t, sig, Sign("whsec_a", at, body))
+	assert.NotEqual(t, sig, Sign("whsec_b", at, body))
+	assert.NotEqual(t, sig, Sign("whsec_a", at.Add(time.Second), body))
+	assert.NotEqual(t, sig, Sign("whsec_a", at, []byte(`{}`)))
+}
+
+func TestSend(t *testing.T) {
+	payload := []byte(`{"event":"webhook.test"}`)
+
+	t.Run("records the response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, payload, body)
+			assert.Equal(t, EventTest, r.Header.Get(HeaderEvent))
+
+			var ts int64
+			_, err := fmt.Sscanf(r.Header.Get(HeaderSignature), "t=%d,", &ts)
+			require.NoError(t, err)
+			assert.Equal(t, Sign("whsec_a", time.Unix(ts, 0), body), r.Header.Get(HeaderSignature))
+
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(strings.Repeat("x", 2*responseExcerptSize)))
+		}))
+		defer server.Close()
+
+		params, err := send(context.Background(), server.Client(), server.URL, "whsec_a", EventTest, payload)
+		require.NoError(t, err)
+		assert.Equal(t, int32(http.StatusAccepted), params.StatusCode.Int32)
+		assert.False(t, params.Error.Valid)
+		assert.Len(t, params.ResponseExcerpt.String, responseExcerptSize)
+		assert.JSONEq(t, string(payload), string(params.Payload))
+	})
+
+	t.Run("records connection errors", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		params, err := send(context.Background(), server.Client(), server.URL, "whsec_a", EventTest, payload)
+		assert.Error(t, err)
+		assert.False(t, params.StatusCode.Valid)
+		assert.True(t, params.Error.Valid)
+	})
+
+	t.Run("refuses internal addresses", func(t *testing.T) {
+		var called bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		params, err := send(context.Background(), client, server.URL, "whsec_a", EventTest, payload)
+		assert.ErrorIs(t, err, utils.ErrNotPublic)
+		assert.False(t, called)
+		assert.False(t, params.StatusCode.Valid)
+		assert.True(t, params.Error.Valid)
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/hook" {
+				http.Redirect(w, r, "/internal", http.StatusFound)
+				return
+			}
+			t.Errorf("followed redirect to %s", r.URL.Path)
+		}))
+		defer server.Close()
+
+		c := server.Client()
+		c.CheckRedirect = client.CheckRedirect
+		params, err := send(context.Background(), c, server.URL+"/hook", "whsec_a", EventTest, payload)
+		require.NoError(t, err)
+		assert.Equal(t, int32(http.StatusFound), params.StatusCode.Int32)
+	})
+
+	t.Run("sanitizes the excerpt", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok\x00\xff\xfe done"))
+		}))
+		defer server.Close()
+
+		params, err := send(context.Background(), server.Client(), server.URL, "whsec_a", EventTest, payload)
+		require.NoError(t, err)
+		assert.Equal(t, "ok done", params.ResponseExcerpt.String)
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryDelay(1))
+	assert.Equal(t, time.Minute, retryDelay(2))
+	assert.Equal(t, 32*time.Minute, retryDelay(maxAttempts-1))
+}
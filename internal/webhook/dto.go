@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+)
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" validate:"required,http_url,max=2048"`
+}
+
+type WebhookResponse struct {
+	ID  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+	// Secret signs deliveries. It is only returned when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeliveryResponse struct {
+	ID uuid.UUID `json:"id"`
+	// EventID groups the attempts at one queued event; it is null for test
+	// deliveries and redeliveries.
+	EventID *uuid.UUID      `json:"event_id"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload" swaggertype:"object"`
+	// Success reports a 2xx response.
+	Success         bool      `json:"success"`
+	StatusCode      *int      `json:"status_code"`
+	Error           string    `json:"error"`
+	LatencyMs       int       `json:"latency_ms"`
+	ResponseExcerpt string    `json:"response_excerpt"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// TestEventData is the data of EventTest deliveries.
+type TestEventData struct {
+	WebhookID uuid.UUID `json:"webhook_id"`
+	Message   string    `json:"message"`
+}
+
+// ImageFailedData is the data of EventImageFailed deliveries.
+type ImageFailedData struct {
+	ImageID       uuid.UUID `json:"image_id"`
+	BatchID       uuid.UUID `json:"batch_id"`
+	ExternalID    string    `json:"external_id"`
+	Filename      string    `json:"filename"`
+	FailureReason string    `json:"failure_reason"`
+}
+
+// BatchCompletedData is the data of EventBatchCompleted deliveries. Status
+// is completed, failed, cancelled or expired, as returned for the batch by
+// the API.
+type BatchCompletedData struct {
+	BatchID    uuid.UUID `json:"batch_id"`
+	Name       string    `json:"name"`
+	ExternalID string    `json:"external_id"`
+	Status     string    `json:"status"`
+	Total      int64     `json:"total"`
+	Completed  int64     `json:"completed"`
+	Failed     int64     `json:"failed"`
+	Cancelled  int64     `json:"cancelled"`
+	Expired    int64     `json:"expired"`
+	ReportURL  string    `json:"report_url"`
+}
+
+// BatchRestoredData is the data of EventBatchRestored deliveries.
+type BatchRestoredData struct {
+	BatchID    uuid.UUID `json:"batch_id"`
+	Name       string    `json:"name"`
+	ExternalID string    `json:"external_id"`
+	// RetrievableUntil is when S3 expires the restored copies again.
+	RetrievableUntil time.Time `json:"retrievable_until"`
+}
+
+func toWebhookResponse(w database.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:        w.ID,
+		URL:       w.Url,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func toDeliveryResponse(d database.WebhookDelivery) DeliveryResponse {
+	res := DeliveryResponse{
+		ID:              d.ID,
+		Event:           d.Event,
+		Payload:         d.Payload,
+		Error:           d.Error.String,
+		LatencyMs:       int(d.LatencyMs),
+		ResponseExcerpt: d.ResponseExcerpt.String,
+		CreatedAt:       d.CreatedAt,
+	}
+	if d.EventID.Valid {
+		res.EventID = &d.EventID.UUID
+	}
+	if d.StatusCode.Valid {
+		code := int(d.StatusCode.Int32)
+		res.StatusCode = &code
+		res.Success = code >= 200 && code < 300
+	}
+	return res
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+const (
+	// maxAttempts is how often an event is sent before it is given up.
+	maxAttempts = 8
+	// retryBaseDelay is the wait after the first failed attempt; it doubles
+	// with every further one.
+	retryBaseDelay = 30 * time.Second
+	// claimSize is how many due events one poll delivers.
+	claimSize = 20
+	// claimLease keeps claimed events from other servers while they are
+	// delivered, longer than claimSize attempts can take.
+	claimLease = claimSize*deliveryTimeout + time.Minute
+)
+
+// Enqueue queues event with data for every webhook of the user. Queued
+// events are delivered by PollEvents; pass a transaction's queries to queue
+// them only if it commits.
+func Enqueue(ctx context.Context, dbQueries *database.Queries, userID uuid.UUID, event string, data any) error {
+	payload, err := json.Marshal(Envelope{
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	return dbQueries.EnqueueWebhookEvents(ctx, database.EnqueueWebhookEventsParams{
+		Event:   event,
+		Payload: payload,
+		UserID:  userID,
+	})
+}
+
+// retryDelay is how long to wait after the attempts-th failed attempt.
+func retryDelay(attempts int) time.Duration {
+	return retryBaseDelay << (attempts - 1)
+}
+
+// PollEvents delivers due webhook events every interval until ctx is done.
+// Claiming an event moves its next attempt past the lease in one statement,
+// so several servers polling at once send each attempt once.
+func PollEvents(ctx context.Context, dbQueries *database.Queries, secrets *utils.Keyring, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deliverDueEvents(ctx, dbQueries, secrets)
+		}
+	}
+}
+
+func deliverDueEvents(ctx context.Context, dbQueries *database.Queries, secrets *utils.Keyring) {
+	events, err := dbQueries.ClaimDueWebhookEvents(ctx, database.ClaimDueWebhookEventsParams{
+		LeaseSeconds: int32(claimLease / time.Second),
+		PageLimit:    claimSize,
+	})
+	if err != nil {
+		log.Printf("error claim webhook events: %v", err)
+		return
+	}
+	for _, e := range events {
+		if err := deliverEvent(ctx, dbQueries, secrets, e); err != nil {
+			log.Printf("error deliver webhook event %s: %v", e.ID, err)
+		}
+	}
+}
+
+// deliverEvent makes one attempt at e and schedules the next one when it
+// fails. Errors leave e to be claimed again once its lease is over.
+func deliverEvent(ctx context.Context, dbQueries *database.Queries, secrets *utils.Keyring, e database.WebhookEvent) error {
+	hook, err := dbQueries.GetWebhookByID(ctx, e.WebhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The webhook was deleted since.
+		return dbQueries.FailWebhookEvent(ctx, e.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	delivery, refused, err := deliver(ctx, dbQueries, secrets, hook, e.Event, e.Payload, uuid.NullUUID{UUID: e.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	attempts := int(e.Attempts) + 1
+	switch {
+	case toDeliveryResponse(delivery).Success:
+		return dbQueries.MarkWebhookEventDelivered(ctx, e.ID)
+	case refused, attempts >= maxAttempts:
+		return dbQueries.FailWebhookEvent(ctx, e.ID)
+	default:
+		return dbQueries.RetryWebhookEvent(ctx, database.RetryWebhookEventParams{
+			ID:            e.ID,
+			NextAttemptAt: time.Now().UTC().Add(retryDelay(attempts)),
+		})
+	}
+}
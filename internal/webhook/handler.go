@@ -0,0 +1,291 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/rickyroynardson/image-go/internal/utils"
+)
+
+type WebhookHandler struct {
+	validator *validator.Validate
+	dbQueries *database.Queries
+	config    *utils.Config
+}
+
+func NewHandler(validator *validator.Validate, dbQueries *database.Queries, config *utils.Config) *WebhookHandler {
+	return &WebhookHandler{
+		validator: validator,
+		dbQueries: dbQueries,
+		config:    config,
+	}
+}
+
+// GetAll godoc
+// @Summary Get list of webhooks
+// @Description Retrieve the webhooks registered by the authenticated user
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.SuccessResponse{data=[]WebhookResponse}
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetAll(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	webhooks, err := h.dbQueries.GetUserWebhooks(c.Request().Context(), userID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	webhooksRes := make([]WebhookResponse, len(webhooks))
+	for i, w := range webhooks {
+		webhooksRes[i] = toWebhookResponse(w)
+	}
+	return utils.RespondJSON(c, http.StatusOK, "webhooks retrieved successfully", webhooksRes)
+}
+
+// Create godoc
+// @Summary Create webhook
+// @Description Register a URL to receive signed event deliveries. The response includes the signing secret, which is not shown again. The URL must resolve to public addresses only; redirects are not followed
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body CreateWebhookRequest true "Create Webhook Request"
+// @Success 201 {object} utils.SuccessResponse{data=WebhookResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /webhooks [post]
+func (h *WebhookHandler) Create(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+
+	var body CreateWebhookRequest
+	if err := c.Bind(&body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := h.validator.Struct(body); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+	target, err := url.Parse(body.URL)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "invalid url")
+	}
+	if err := utils.CheckPublicHost(c.Request().Context(), target.Hostname()); err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, "url must point to a public address")
+	}
+
+	if h.config.Secrets == nil {
+		return utils.RespondError(c, http.StatusServiceUnavailable, "webhooks are not enabled on this server")
+	}
+
+	token, err := utils.GenerateRefresh()
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	secret := "whsec_" + token
+	encrypted, err := h.config.Secrets.Encrypt(userID, secret)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	webhook, err := h.dbQueries.CreateWebhook(c.Request().Context(), database.CreateWebhookParams{
+		UserID: userID,
+		Url:    body.URL,
+		Secret: encrypted,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	res := toWebhookResponse(webhook)
+	res.Secret = secret
+	return utils.RespondJSON(c, http.StatusCreated, "webhook created successfully", res)
+}
+
+// DeleteByID godoc
+// @Summary Delete webhook
+// @Description Stop sending events to a webhook
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param webhookID path string true "Webhook ID"
+// @Success 200 {object} utils.SuccessResponse{data=nil}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /webhooks/{webhookID} [delete]
+func (h *WebhookHandler) DeleteByID(c echo.Context) error {
+	userID := c.Get("userID").(uuid.UUID)
+	webhookUUID := utils.ParamUUID(c, "webhookID")
+
+	err := h.dbQueries.DeleteWebhookByID(c.Request().Context(), database.DeleteWebhookByIDParams{
+		ID:     webhookUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "webhook deleted successfully", nil)
+}
+
+// Test godoc
+// @Summary Test webhook
+// @Description Send a signed webhook.test event to the webhook right away and return the recorded delivery
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param webhookID path string true "Webhook ID"
+// @Success 200 {object} utils.SuccessResponse{data=DeliveryResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /webhooks/{webhookID}/test [post]
+func (h *WebhookHandler) Test(c echo.Context) error {
+	webhook, err := h.getWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(Envelope{
+		Event:     EventTest,
+		CreatedAt: time.Now().UTC(),
+		Data: TestEventData{
+			WebhookID: webhook.ID,
+			Message:   "This is a test delivery from Image Go.",
+		},
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return h.deliver(c, webhook, EventTest, payload)
+}
+
+// GetDeliveries godoc
+// @Summary Get webhook deliveries
+// @Description List the delivery attempts of a webhook, newest first, with their status code, latency and the start of the response body
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param webhookID path string true "Webhook ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} utils.SuccessResponse{data=[]DeliveryResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /webhooks/{webhookID}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(c echo.Context) error {
+	webhook, err := h.getWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	page, limit, err := utils.GetPagination(c)
+	if err != nil {
+		return utils.RespondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	deliveries, err := h.dbQueries.GetWebhookDeliveries(c.Request().Context(), database.GetWebhookDeliveriesParams{
+		WebhookID:  webhook.ID,
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	})
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	total, err := h.dbQueries.CountWebhookDeliveries(c.Request().Context(), webhook.ID)
+	if err != nil {
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	deliveriesRes := make([]DeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		deliveriesRes[i] = toDeliveryResponse(d)
+	}
+	return utils.RespondPaginated(c, http.StatusOK, "webhook deliveries retrieved successfully", deliveriesRes, utils.NewPaginationMeta(page, limit, int(total)))
+}
+
+// Redeliver godoc
+// @Summary Redeliver webhook delivery
+// @Description Send the payload of an earlier delivery again, with a fresh signature, and return the new delivery
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param webhookID path string true "Webhook ID"
+// @Param deliveryID path string true "Delivery ID"
+// @Success 200 {object} utils.SuccessResponse{data=DeliveryResponse}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /webhooks/{webhookID}/deliveries/{deliveryID}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c echo.Context) error {
+	webhook, err := h.getWebhook(c)
+	if err != nil {
+		return err
+	}
+
+	deliveryUUID := utils.ParamUUID(c, "deliveryID")
+
+	delivery, err := h.dbQueries.GetWebhookDeliveryByID(c.Request().Context(), database.GetWebhookDeliveryByIDParams{
+		ID:        deliveryUUID,
+		WebhookID: webhook.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.RespondError(c, http.StatusNotFound, "delivery not found")
+		}
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+
+	return h.deliver(c, webhook, delivery.Event, delivery.Payload)
+}
+
+// getWebhook loads the webhook named by the webhookID path param, writing the
+// error response itself when it cannot.
+func (h *WebhookHandler) getWebhook(c echo.Context) (database.Webhook, error) {
+	userID := c.Get("userID").(uuid.UUID)
+
+	webhookUUID := utils.ParamUUID(c, "webhookID")
+
+	webhook, err := h.dbQueries.GetUserWebhookByID(c.Request().Context(), database.GetUserWebhookByIDParams{
+		ID:     webhookUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Webhook{}, utils.RespondError(c, http.StatusNotFound, "webhook not found")
+		}
+		return database.Webhook{}, utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return webhook, nil
+}
+
+func (h *WebhookHandler) deliver(c echo.Context, webhook database.Webhook, event string, payload []byte) error {
+	if h.config.Secrets == nil {
+		return utils.RespondError(c, http.StatusServiceUnavailable, "webhooks are not enabled on this server")
+	}
+
+	delivery, err := Deliver(c.Request().Context(), h.dbQueries, h.config.Secrets, webhook, event, payload)
+	if err != nil {
+		c.Logger().Errorf("failed to deliver webhook %s: %v", webhook.ID, err)
+		return utils.RespondError(c, http.StatusInternalServerError, "internal server error")
+	}
+	return utils.RespondJSON(c, http.StatusOK, "webhook delivered", toDeliveryResponse(delivery))
+}
@@ -0,0 +1,11 @@
+-- name: CreateAuthEvent :exec
+INSERT INTO auth_events(user_id, email, reason, ip_address) VALUES ($1, $2, $3, $4);
+
+-- name: CountAuthEventsByEmailAndIPSince :one
+SELECT COUNT(*) FROM auth_events WHERE email = $1 AND ip_address = $2 AND reason = $3 AND created_at > $4;
+
+-- name: GetAuthEventStats :many
+SELECT reason, date_trunc(sqlc.arg(bucket)::text, created_at)::timestamp AS bucket_start, COUNT(*) AS count FROM auth_events WHERE created_at >= sqlc.arg(since) GROUP BY reason, bucket_start ORDER BY bucket_start DESC, reason;
+
+-- name: CountAuthEventsByReason :many
+SELECT reason, COUNT(*) AS count FROM auth_events GROUP BY reason ORDER BY reason;
@@ -0,0 +1,11 @@
+-- name: CreateBatchComment :one
+INSERT INTO batch_comments(batch_id, image_id, parent_id, user_id, body) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetBatchComments :many
+SELECT * FROM batch_comments WHERE batch_id = $1 AND deleted_at IS NULL ORDER BY created_at;
+
+-- name: GetBatchCommentByID :one
+SELECT * FROM batch_comments WHERE id = $1 AND batch_id = $2 AND deleted_at IS NULL;
+
+-- name: DeleteBatchCommentByID :execrows
+UPDATE batch_comments SET deleted_at = NOW() WHERE id = $1 AND batch_id = $2 AND user_id = $3 AND deleted_at IS NULL;
@@ -0,0 +1,29 @@
+-- name: CreateBatchDelivery :one
+INSERT INTO batch_deliveries(batch_id, user_id, recipient, token_hash, subject, message, reply_to, logo_url, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *;
+
+-- name: CountUserBatchDeliveriesSince :one
+SELECT COUNT(*) FROM batch_deliveries WHERE user_id = $1 AND created_at > $2;
+
+-- name: ClaimDueBatchDeliveries :many
+UPDATE batch_deliveries d SET next_attempt_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int) WHERE d.id IN (SELECT q.id FROM batch_deliveries q WHERE q.sent_at IS NULL AND q.failed_at IS NULL AND q.next_attempt_at <= NOW() ORDER BY q.next_attempt_at LIMIT sqlc.arg(page_limit) FOR UPDATE SKIP LOCKED) RETURNING d.*;
+
+-- name: MarkBatchDeliverySent :exec
+UPDATE batch_deliveries SET send_attempts = send_attempts + 1, sent_at = NOW() WHERE id = $1;
+
+-- name: RetryBatchDelivery :exec
+UPDATE batch_deliveries SET send_attempts = send_attempts + 1, next_attempt_at = $2 WHERE id = $1;
+
+-- name: FailBatchDelivery :exec
+UPDATE batch_deliveries SET send_attempts = send_attempts + 1, failed_at = NOW() WHERE id = $1;
+
+-- name: GetBatchDeliveries :many
+SELECT * FROM batch_deliveries WHERE batch_id = $1 ORDER BY created_at DESC, id DESC;
+
+-- name: GetBatchDeliveryByTokenHash :one
+SELECT d.*, b.name AS batch_name, b.archive_status FROM batch_deliveries d INNER JOIN batches b ON b.id = d.batch_id WHERE d.token_hash = $1 AND d.sent_at IS NOT NULL AND d.expires_at > NOW() AND b.deleted_at IS NULL;
+
+-- name: RecordBatchDeliveryOpen :exec
+UPDATE batch_deliveries SET open_count = open_count + 1, first_opened_at = COALESCE(first_opened_at, NOW()), last_opened_at = NOW() WHERE id = $1;
+
+-- name: RecordBatchDeliveryDownload :exec
+UPDATE batch_deliveries SET download_count = download_count + 1, last_downloaded_at = NOW() WHERE id = $1;
@@ -0,0 +1,12 @@
+-- name: CreateBatchEvent :exec
+INSERT INTO batch_events(batch_id, user_id, type) VALUES ($1, $2, $3);
+
+-- name: GetBatchActivity :many
+SELECT a.* FROM (
+    SELECT c.id, 'comment'::text AS type, c.user_id, c.image_id, c.parent_id, c.body, c.created_at FROM batch_comments c WHERE c.batch_id = sqlc.arg(batch_id) AND c.deleted_at IS NULL
+    UNION ALL
+    SELECT e.id, e.type::text AS type, e.user_id, NULL::uuid AS image_id, NULL::uuid AS parent_id, ''::text AS body, e.created_at FROM batch_events e WHERE e.batch_id = sqlc.arg(batch_id)
+) a ORDER BY a.created_at DESC, a.id DESC LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountBatchActivity :one
+SELECT ((SELECT COUNT(*) FROM batch_comments c WHERE c.batch_id = $1 AND c.deleted_at IS NULL) + (SELECT COUNT(*) FROM batch_events e WHERE e.batch_id = $1))::bigint AS total;
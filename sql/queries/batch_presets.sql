@@ -0,0 +1,14 @@
+-- name: CreateBatchPreset :one
+INSERT INTO batch_presets(user_id, name, watermark_id, watermark_position, watermark_opacity, watermark_scale, output_format, output_quality, max_width, max_height) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING *;
+
+-- name: GetUserBatchPresets :many
+SELECT * FROM batch_presets WHERE user_id = $1 ORDER BY name;
+
+-- name: GetUserBatchPresetByID :one
+SELECT * FROM batch_presets WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateBatchPreset :one
+UPDATE batch_presets SET name = $3, watermark_id = $4, watermark_position = $5, watermark_opacity = $6, watermark_scale = $7, output_format = $8, output_quality = $9, max_width = $10, max_height = $11, updated_at = NOW() WHERE id = $1 AND user_id = $2 RETURNING *;
+
+-- name: DeleteBatchPresetByID :execrows
+DELETE FROM batch_presets WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,20 @@
+-- name: CreateBatchTransfer :one
+INSERT INTO batch_transfers(batch_id, from_user_id, to_user_id, message) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetUserBatchTransfers :many
+SELECT t.*, b.name AS batch_name, f.email AS from_email, r.email AS to_email FROM batch_transfers t INNER JOIN batches b ON b.id = t.batch_id INNER JOIN users f ON f.id = t.from_user_id INNER JOIN users r ON r.id = t.to_user_id WHERE (t.from_user_id = sqlc.arg(user_id) OR t.to_user_id = sqlc.arg(user_id)) AND (sqlc.narg(status)::batch_transfer_status IS NULL OR t.status = sqlc.narg(status)::batch_transfer_status) ORDER BY t.created_at DESC, t.id DESC;
+
+-- name: GetBatchTransferByID :one
+SELECT t.*, b.name AS batch_name, f.email AS from_email, r.email AS to_email FROM batch_transfers t INNER JOIN batches b ON b.id = t.batch_id INNER JOIN users f ON f.id = t.from_user_id INNER JOIN users r ON r.id = t.to_user_id WHERE t.id = $1;
+
+-- name: LockIncomingBatchTransfer :one
+SELECT * FROM batch_transfers WHERE id = $1 AND to_user_id = $2 AND status = 'pending' FOR UPDATE;
+
+-- name: RespondBatchTransfer :one
+UPDATE batch_transfers SET status = $2, responded_at = NOW() WHERE id = $1 AND status = 'pending' RETURNING *;
+
+-- name: CancelBatchTransfer :one
+UPDATE batch_transfers SET status = 'cancelled', responded_at = NOW() WHERE batch_id = $1 AND from_user_id = $2 AND status = 'pending' RETURNING *;
+
+-- name: TransferBatch :one
+UPDATE batches b SET user_id = sqlc.arg(to_user_id), watermark_id = NULL, watermark_font_id = NULL, updated_at = NOW() WHERE b.id = sqlc.arg(id) AND b.user_id = sqlc.arg(from_user_id) AND b.deleted_at IS NULL AND b.waiting_since IS NULL AND NOT EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing')) RETURNING *;
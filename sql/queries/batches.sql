@@ -1,14 +1,83 @@
 -- name: GetAllUserBatches :many
-SELECT b.*, COUNT(i.id) as image_count, COUNT(i.id) FILTER (WHERE i.status = 'pending') AS image_pending_count, COUNT(i.id) FILTER (WHERE i.status = 'processing') AS image_processing_count, COUNT(i.id) FILTER (WHERE i.status = 'completed') AS image_completed_count, COUNT(i.id) FILTER (WHERE i.status = 'failed') AS image_failed_count FROM batches b INNER JOIN images i ON i.batch_id = b.id AND i.deleted_at IS NULL WHERE b.user_id = $1 AND b.deleted_at IS NULL GROUP BY b.id ORDER BY b.created_at DESC;
+SELECT * FROM (SELECT b.*, c.image_count, c.image_pending_count, c.image_processing_count, c.image_completed_count, c.image_failed_count, c.image_cancelled_count, c.image_expired_count, batch_status(b.waiting_since, c.image_count, c.image_pending_count, c.image_processing_count, c.image_cancelled_count, c.image_expired_count, c.image_failed_count) AS status FROM batches b CROSS JOIN LATERAL (SELECT COUNT(i.id) AS image_count, COUNT(i.id) FILTER (WHERE i.status = 'pending') AS image_pending_count, COUNT(i.id) FILTER (WHERE i.status = 'processing') AS image_processing_count, COUNT(i.id) FILTER (WHERE i.status = 'completed') AS image_completed_count, COUNT(i.id) FILTER (WHERE i.status = 'failed') AS image_failed_count, COUNT(i.id) FILTER (WHERE i.status = 'cancelled') AS image_cancelled_count, COUNT(i.id) FILTER (WHERE i.status = 'expired') AS image_expired_count FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL) c WHERE b.user_id = sqlc.arg(user_id) AND b.deleted_at IS NULL AND c.image_count > 0 AND (sqlc.narg(external_id)::text IS NULL OR b.external_id = sqlc.narg(external_id)::text) AND (sqlc.narg(q)::text IS NULL OR b.name ILIKE '%' || sqlc.narg(q)::text || '%' ESCAPE '\' OR b.id IN (SELECT k.batch_id FROM images k WHERE k.deleted_at IS NULL AND k.key ILIKE '%' || sqlc.narg(q)::text || '%' ESCAPE '\'))) s WHERE sqlc.narg(status)::text IS NULL OR s.status = sqlc.narg(status)::text ORDER BY CASE WHEN sqlc.arg(sort)::text = 'name' THEN s.name END ASC, CASE WHEN sqlc.arg(sort)::text = '-name' THEN s.name END DESC, CASE WHEN sqlc.arg(sort)::text = 'image_count' THEN s.image_count END ASC, CASE WHEN sqlc.arg(sort)::text = '-image_count' THEN s.image_count END DESC, CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN s.created_at END ASC, s.created_at DESC, s.id DESC LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountUserBatches :one
+SELECT COUNT(*) FROM (SELECT batch_status(b.waiting_since, c.image_count, c.image_pending_count, c.image_processing_count, c.image_cancelled_count, c.image_expired_count, c.image_failed_count) AS status FROM batches b CROSS JOIN LATERAL (SELECT COUNT(i.id) AS image_count, COUNT(i.id) FILTER (WHERE i.status = 'pending') AS image_pending_count, COUNT(i.id) FILTER (WHERE i.status = 'processing') AS image_processing_count, COUNT(i.id) FILTER (WHERE i.status = 'completed') AS image_completed_count, COUNT(i.id) FILTER (WHERE i.status = 'failed') AS image_failed_count, COUNT(i.id) FILTER (WHERE i.status = 'cancelled') AS image_cancelled_count, COUNT(i.id) FILTER (WHERE i.status = 'expired') AS image_expired_count FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL) c WHERE b.user_id = sqlc.arg(user_id) AND b.deleted_at IS NULL AND c.image_count > 0 AND (sqlc.narg(external_id)::text IS NULL OR b.external_id = sqlc.narg(external_id)::text) AND (sqlc.narg(q)::text IS NULL OR b.name ILIKE '%' || sqlc.narg(q)::text || '%' ESCAPE '\' OR b.id IN (SELECT k.batch_id FROM images k WHERE k.deleted_at IS NULL AND k.key ILIKE '%' || sqlc.narg(q)::text || '%' ESCAPE '\'))) s WHERE sqlc.narg(status)::text IS NULL OR s.status = sqlc.narg(status)::text;
+
+-- name: GetUserBatchIDs :many
+SELECT id FROM batches WHERE user_id = $1 AND deleted_at IS NULL;
 
 -- name: GetUserBatchByID :one
 SELECT * FROM batches WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
+-- name: LockBatch :exec
+SELECT id FROM batches WHERE id = $1 FOR NO KEY UPDATE;
+
 -- name: CreateBatch :one
-INSERT INTO batches(user_id, name, watermark_key, watermark_url) VALUES ($1, $2, $3, $4) RETURNING *;
+INSERT INTO batches(user_id, name, watermark_key, watermark_url, preserve_filenames, collision_policy, watermark_text, watermark_font_id, watermark_position, output_format, output_quality, watermark_opacity, watermark_scale, max_concurrency, watermark_tile_spacing, max_width, max_height, preserve_metadata, invisible_watermark, jpeg_progressive, jpeg_subsampling, png_compression, similar_dedupe, external_id, transforms, crop_aspect_ratio, crop_x, crop_y, crop_width, crop_height, watermark_id, pipeline, region, report_csv, expires_at, deadline) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36) RETURNING *;
+
+-- name: CountActiveUserBatches :one
+SELECT COUNT(*) FROM batches b WHERE b.user_id = $1 AND b.deleted_at IS NULL AND b.waiting_since IS NULL AND EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing'));
+
+-- name: SetBatchWaiting :exec
+UPDATE batches SET waiting_since = NOW() WHERE id = $1;
+
+-- name: GetWaitingBatchUserIDs :many
+SELECT DISTINCT user_id FROM batches WHERE waiting_since IS NOT NULL AND deleted_at IS NULL;
+
+-- name: ClearBatchWaiting :exec
+UPDATE batches SET waiting_since = NULL, updated_at = NOW() WHERE id = $1;
+
+-- name: StartNextWaitingBatch :one
+UPDATE batches SET waiting_since = NULL, updated_at = NOW() WHERE id = (SELECT w.id FROM batches w WHERE w.user_id = $1 AND w.waiting_since IS NOT NULL AND w.deleted_at IS NULL ORDER BY w.waiting_since, w.id LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING *;
+
+-- name: UpdateBatchByID :one
+UPDATE batches b SET name = COALESCE(sqlc.narg(name), name), watermark_text = COALESCE(sqlc.narg(watermark_text), watermark_text), watermark_position = COALESCE(sqlc.narg(watermark_position)::watermark_position, watermark_position), watermark_opacity = COALESCE(sqlc.narg(watermark_opacity), watermark_opacity), watermark_scale = COALESCE(sqlc.narg(watermark_scale), watermark_scale), watermark_tile_spacing = COALESCE(sqlc.narg(watermark_tile_spacing), watermark_tile_spacing), updated_at = NOW() WHERE b.id = sqlc.arg(id) AND b.user_id = sqlc.arg(user_id) AND b.deleted_at IS NULL AND (NOT sqlc.arg(changes_watermark)::bool OR NOT EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.status <> 'pending' AND i.deleted_at IS NULL)) RETURNING *;
 
 -- name: DeleteBatchByID :exec
-UPDATE batches SET deleted_at = NOW() WHERE id = $1 AND user_id = $2;
+UPDATE batches SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ArchiveBatchByID :exec
+UPDATE batches SET archive_status = 'archived', archived_at = NOW(), updated_at = NOW() WHERE id = $1;
+
+-- name: UpdateBatchArchiveStatus :exec
+UPDATE batches SET archive_status = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: GetBatchesByArchiveStatus :many
+SELECT * FROM batches WHERE archive_status = $1 AND deleted_at IS NULL;
+
+-- name: LockBatchMaxConcurrency :one
+SELECT (b.max_concurrency * COALESCE((SELECT MAX(ub.concurrency_multiplier) FROM user_boosts ub WHERE ub.user_id = b.user_id AND ub.revoked_at IS NULL AND ub.starts_at <= NOW() AND ub.ends_at > NOW()), 1))::int AS max_concurrency FROM batches b WHERE b.id = $1 FOR UPDATE OF b;
+
+-- name: GetBatchOwnerByID :one
+SELECT user_id FROM batches WHERE id = $1;
+
+-- name: ClaimBatchReport :one
+UPDATE batches b SET report_generated_at = NOW() WHERE b.id = (SELECT i.batch_id FROM images i WHERE i.id = $1) AND b.deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM images p WHERE p.batch_id = b.id AND p.deleted_at IS NULL AND p.status IN ('pending', 'processing')) AND (b.report_generated_at IS NULL OR b.report_generated_at < (SELECT MAX(u.updated_at) FROM images u WHERE u.batch_id = b.id AND u.deleted_at IS NULL)) RETURNING *;
+
+-- name: SetBatchReportURLs :exec
+UPDATE batches SET report_url = $2, report_csv_url = $3 WHERE id = $1;
+
+-- name: GetNextOverdueBatch :one
+SELECT b.* FROM batches b WHERE b.region = $1 AND b.deadline <= NOW() AND b.deleted_at IS NULL AND EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status = 'pending') ORDER BY b.deadline LIMIT 1 FOR UPDATE SKIP LOCKED;
+
+-- name: ExpireNextBatch :one
+UPDATE batches SET deleted_at = COALESCE(deleted_at, NOW()), purged_at = NOW(), updated_at = NOW() WHERE id = (SELECT b.id FROM batches b WHERE b.region = $1 AND b.expires_at <= NOW() AND b.purged_at IS NULL AND NOT EXISTS (SELECT 1 FROM images i WHERE i.batch_id = b.id AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing')) ORDER BY b.expires_at LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING *;
+
+-- name: CountBatchesByWatermarkKey :one
+SELECT COUNT(*) FROM batches WHERE watermark_key = $1 AND purged_at IS NULL;
+
+-- name: CountOtherBatchesByWatermarkKey :one
+SELECT COUNT(*) FROM batches WHERE watermark_key = $1 AND id <> $2 AND purged_at IS NULL;
+
+-- name: GetUserTrash :many
+SELECT * FROM batches WHERE user_id = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL ORDER BY deleted_at DESC, id DESC LIMIT $2 OFFSET $3;
+
+-- name: CountUserTrash :one
+SELECT COUNT(*) FROM batches WHERE user_id = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL;
+
+-- name: RestoreDeletedBatch :one
+UPDATE batches SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL AND purged_at IS NULL RETURNING *;
 
--- name: HardDeleteBatchByID :exec
-DELETE FROM batches WHERE id = $1 AND user_id = $2;
+-- name: PurgeNextDeletedBatch :one
+UPDATE batches SET purged_at = NOW(), updated_at = NOW() WHERE id = (SELECT b.id FROM batches b WHERE b.region = sqlc.arg(region) AND b.deleted_at <= sqlc.arg(deleted_before) AND b.purged_at IS NULL ORDER BY b.deleted_at LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING *;
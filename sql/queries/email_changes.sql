@@ -0,0 +1,20 @@
+-- name: CreateEmailChange :one
+INSERT INTO email_changes(user_id, old_email, new_email, confirm_token_hash, undo_token_hash, expires_at, undo_expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: CancelPendingEmailChanges :exec
+UPDATE email_changes SET cancelled_at = NOW() WHERE user_id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL;
+
+-- name: GetPendingEmailChangeByConfirmToken :one
+SELECT * FROM email_changes WHERE confirm_token_hash = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW() FOR UPDATE;
+
+-- name: ConfirmEmailChange :exec
+UPDATE email_changes SET confirmed_at = NOW() WHERE id = $1;
+
+-- name: GetUndoableEmailChange :one
+SELECT * FROM email_changes WHERE undo_token_hash = $1 AND cancelled_at IS NULL AND reverted_at IS NULL AND undo_expires_at > NOW() FOR UPDATE;
+
+-- name: CancelEmailChange :exec
+UPDATE email_changes SET cancelled_at = NOW() WHERE id = $1;
+
+-- name: RevertEmailChange :exec
+UPDATE email_changes SET reverted_at = NOW() WHERE id = $1;
@@ -0,0 +1,11 @@
+-- name: GetEmailDomainRules :many
+SELECT * FROM email_domain_rules ORDER BY rule, domain;
+
+-- name: GetEmailDomainPolicy :one
+SELECT COUNT(*) FILTER (WHERE rule = 'block' AND (domain = $1 OR right($1, length(domain) + 1) = '.' || domain)) AS blocked_count, COUNT(*) FILTER (WHERE rule = 'allow') AS allow_count, COUNT(*) FILTER (WHERE rule = 'allow' AND (domain = $1 OR right($1, length(domain) + 1) = '.' || domain)) AS allowed_count FROM email_domain_rules;
+
+-- name: CreateEmailDomainRule :one
+INSERT INTO email_domain_rules(domain, rule) VALUES ($1, $2) RETURNING *;
+
+-- name: DeleteEmailDomainRuleByID :exec
+DELETE FROM email_domain_rules WHERE id = $1;
@@ -0,0 +1,11 @@
+-- name: GetUserFeatureFlags :many
+SELECT flag FROM user_feature_flags WHERE user_id = $1 ORDER BY flag;
+
+-- name: HasUserFeatureFlag :one
+SELECT EXISTS(SELECT 1 FROM user_feature_flags WHERE user_id = $1 AND flag = $2);
+
+-- name: EnableUserFeatureFlag :exec
+INSERT INTO user_feature_flags(user_id, flag) VALUES ($1, $2) ON CONFLICT DO NOTHING;
+
+-- name: DisableUserFeatureFlag :exec
+DELETE FROM user_feature_flags WHERE user_id = $1 AND flag = $2;
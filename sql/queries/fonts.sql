@@ -0,0 +1,17 @@
+-- name: CreateFont :one
+INSERT INTO fonts(user_id, name, key, format, size_bytes, region) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetUserFonts :many
+SELECT * FROM fonts WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC;
+
+-- name: GetUserFontByID :one
+SELECT * FROM fonts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: DeleteFontByID :one
+UPDATE fonts SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL RETURNING *;
+
+-- name: GetQueuedWatermarkFontKeys :many
+SELECT f.key FROM fonts f
+WHERE f.deleted_at IS NULL AND EXISTS (SELECT 1 FROM batches b JOIN images i ON i.batch_id = b.id WHERE b.watermark_font_id = f.id AND b.deleted_at IS NULL AND i.status IN ('pending', 'processing') AND i.deleted_at IS NULL)
+ORDER BY f.created_at DESC, f.id
+LIMIT $1;
@@ -1,14 +1,99 @@
 -- name: CreateImage :one
-INSERT INTO images(batch_id, key, original_url) VALUES($1, $2, $3) RETURNING *;
+INSERT INTO images(batch_id, key, original_url, filename, content_hash, watermark_position_override, watermark_opacity_override, watermark_scale_override, external_id, redactions, source_url) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING *;
+
+-- name: GetUserImageByContentHash :one
+SELECT i.* FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE b.user_id = $1 AND i.content_hash = $2 AND b.region = $3 AND b.archive_status = 'active' AND i.deleted_at IS NULL AND b.deleted_at IS NULL ORDER BY i.created_at LIMIT 1;
+
+-- name: GetBatchImageByKey :one
+SELECT * FROM images WHERE batch_id = $1 AND key = $2 AND deleted_at IS NULL ORDER BY created_at LIMIT 1;
+
+-- name: GetReferencedImageKeys :many
+SELECT DISTINCT key FROM images WHERE key = ANY(sqlc.arg(keys)::TEXT[]) AND deleted_at IS NULL;
+
+-- name: GetReferencedObjectURLs :many
+SELECT DISTINCT u.url::TEXT FROM images i CROSS JOIN LATERAL (VALUES (i.processed_url), (i.thumbnail_url)) AS u(url) WHERE u.url = ANY(sqlc.arg(urls)::TEXT[]) AND i.deleted_at IS NULL;
+
+-- name: GetImageKeysSharedOutsideBatch :many
+SELECT DISTINCT key FROM images WHERE key = ANY(sqlc.arg(keys)::TEXT[]) AND batch_id <> sqlc.arg(batch_id) AND deleted_at IS NULL;
+
+-- name: GetObjectURLsSharedOutsideBatch :many
+SELECT DISTINCT u.url::TEXT FROM images i CROSS JOIN LATERAL (VALUES (i.processed_url), (i.thumbnail_url)) AS u(url) WHERE u.url = ANY(sqlc.arg(urls)::TEXT[]) AND i.batch_id <> sqlc.arg(batch_id) AND i.deleted_at IS NULL;
 
 -- name: GetImageByID :one
-SELECT i.*, b.watermark_url, b.watermark_key FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE i.id = $1 AND i.deleted_at IS NULL AND b.deleted_at IS NULL;
+SELECT i.*, b.region AS batch_region, b.watermark_url, b.watermark_key, b.preserve_filenames, b.collision_policy, b.watermark_text, b.watermark_position, b.watermark_opacity, b.watermark_scale, b.watermark_tile_spacing, b.max_width, b.max_height, b.preserve_metadata, b.invisible_watermark, b.user_id, b.output_format, b.output_quality, b.jpeg_progressive, b.jpeg_subsampling, b.png_compression, b.similar_dedupe, b.transforms, b.crop_aspect_ratio, b.crop_x, b.crop_y, b.crop_width, b.crop_height, b.pipeline, b.deadline AS batch_deadline, f.key AS watermark_font_key, w.key AS library_watermark_key FROM images i INNER JOIN batches b ON b.id = i.batch_id LEFT JOIN fonts f ON f.id = b.watermark_font_id AND f.deleted_at IS NULL LEFT JOIN watermarks w ON w.id = b.watermark_id WHERE i.id = $1 AND i.deleted_at IS NULL AND b.deleted_at IS NULL;
 
 -- name: GetImagesByBatchID :many
 SELECT * FROM images WHERE batch_id = $1 AND deleted_at IS NULL ORDER BY created_at;
 
+-- name: GetBatchImageChanges :many
+SELECT * FROM images WHERE batch_id = sqlc.arg(batch_id) AND (change_xid, id) > (sqlc.arg(since_xid)::bigint, sqlc.arg(since_id)::uuid) AND change_xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint ORDER BY change_xid, id LIMIT sqlc.arg(page_limit);
+
 -- name: UpdateImageByID :exec
 UPDATE images SET processed_url = $1, status = $2, updated_at = NOW() WHERE id = $3 AND deleted_at IS NULL;
 
+-- name: RequeueRestoredBatchImages :many
+UPDATE images SET status = 'pending', failure_reason = NULL, updated_at = NOW() WHERE batch_id = $1 AND deleted_at IS NULL AND (status = 'pending' OR (status = 'failed' AND failure_reason = $2)) RETURNING id;
+
+-- name: FailImageByID :exec
+UPDATE images SET status = 'failed', processed_url = NULL, failure_reason = $2, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: SetImageOriginal :exec
+UPDATE images SET key = $1, original_url = $2, content_hash = $3, updated_at = NOW() WHERE id = $4 AND deleted_at IS NULL;
+
+-- name: CompleteImageByID :exec
+UPDATE images SET status = 'completed', updated_at = NOW(), processed_url = $1, palette = $2, blurhash = $3, thumbhash = $4, width = $5, height = $6, thumbnail_url = $7, applied_watermark_position = $8, processed_size = $9, processed_format = $10, original_width = $11, original_height = $12, original_size = $13, original_format = $14, original_dominant_color = $15, phash = $16, step_timings = $17, captured_at = $18, similar_to = NULL WHERE id = $19 AND deleted_at IS NULL;
+
+-- name: GetBatchSavings :many
+SELECT COALESCE(original_format, '')::text AS original_format, COALESCE(processed_format, '')::text AS processed_format, COUNT(*) AS image_count, SUM(original_size)::bigint AS original_bytes, SUM(processed_size)::bigint AS processed_bytes FROM images WHERE batch_id = $1 AND status = 'completed' AND deleted_at IS NULL AND original_size IS NOT NULL AND processed_size IS NOT NULL GROUP BY 1, 2 ORDER BY 1, 2;
+
+-- name: GetSimilarImage :one
+SELECT i.id FROM images i INNER JOIN batches b ON b.id = i.batch_id INNER JOIN batches cur ON cur.id = sqlc.arg(batch_id)::UUID WHERE b.user_id = cur.user_id AND b.region = cur.region AND i.id <> sqlc.arg(id)::UUID AND i.status = 'completed' AND i.processed_url IS NOT NULL AND i.phash IS NOT NULL AND i.deleted_at IS NULL AND b.deleted_at IS NULL AND b.archive_status = 'active' AND i.watermark_position_override IS NULL AND i.watermark_opacity_override IS NULL AND i.watermark_scale_override IS NULL AND i.placement_x IS NULL AND i.redactions = '[]' AND (b.id = cur.id OR (cur.similar_dedupe = 'account' AND NOT b.invisible_watermark AND NOT cur.invisible_watermark AND b.watermark_key IS NOT DISTINCT FROM cur.watermark_key AND b.watermark_id IS NOT DISTINCT FROM cur.watermark_id AND b.watermark_text IS NOT DISTINCT FROM cur.watermark_text AND b.watermark_font_id IS NOT DISTINCT FROM cur.watermark_font_id AND b.watermark_position = cur.watermark_position AND b.watermark_opacity = cur.watermark_opacity AND b.watermark_scale = cur.watermark_scale AND b.watermark_tile_spacing = cur.watermark_tile_spacing AND b.max_width IS NOT DISTINCT FROM cur.max_width AND b.max_height IS NOT DISTINCT FROM cur.max_height AND b.preserve_metadata = cur.preserve_metadata AND b.output_format = cur.output_format AND b.output_quality = cur.output_quality AND b.jpeg_progressive = cur.jpeg_progressive AND b.jpeg_subsampling = cur.jpeg_subsampling AND b.png_compression = cur.png_compression AND b.transforms = cur.transforms AND b.pipeline = cur.pipeline AND b.crop_aspect_ratio IS NOT DISTINCT FROM cur.crop_aspect_ratio AND b.crop_x IS NOT DISTINCT FROM cur.crop_x AND b.crop_y IS NOT DISTINCT FROM cur.crop_y AND b.crop_width IS NOT DISTINCT FROM cur.crop_width AND b.crop_height IS NOT DISTINCT FROM cur.crop_height)) AND bit_count((i.phash # sqlc.arg(phash)::BIGINT)::BIT(64)) <= sqlc.arg(max_distance)::INTEGER ORDER BY bit_count((i.phash # sqlc.arg(phash)::BIGINT)::BIT(64)), i.created_at LIMIT 1;
+
+-- name: LinkSimilarImageByID :execrows
+UPDATE images i SET status = 'completed', updated_at = NOW(), similar_to = s.id, processed_url = s.processed_url, thumbnail_url = s.thumbnail_url, palette = s.palette, blurhash = s.blurhash, thumbhash = s.thumbhash, width = s.width, height = s.height, applied_watermark_position = s.applied_watermark_position, processed_size = s.processed_size, processed_format = s.processed_format, original_width = sqlc.arg(original_width), original_height = sqlc.arg(original_height), original_size = sqlc.arg(original_size), original_format = sqlc.arg(original_format), original_dominant_color = sqlc.arg(original_dominant_color), phash = sqlc.arg(phash), captured_at = sqlc.arg(captured_at) FROM images s WHERE i.id = sqlc.arg(id) AND s.id = sqlc.arg(similar_to) AND i.deleted_at IS NULL AND s.deleted_at IS NULL AND s.status = 'completed' AND s.processed_url IS NOT NULL;
+
 -- name: DeleteImageByID :exec
 UPDATE images SET deleted_at = NOW() WHERE id = $1 AND batch_id = ANY($2::UUID[]);
+
+-- name: SearchUserImages :many
+SELECT i.* FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE b.user_id = sqlc.arg(user_id) AND b.deleted_at IS NULL AND i.deleted_at IS NULL AND (sqlc.narg(filename)::text IS NULL OR i.filename ILIKE '%' || sqlc.narg(filename)::text || '%' ESCAPE '\') AND (sqlc.narg(status)::image_status IS NULL OR i.status = sqlc.narg(status)::image_status) AND (sqlc.narg(from_time)::timestamp IS NULL OR i.created_at >= sqlc.narg(from_time)::timestamp) AND (sqlc.narg(to_time)::timestamp IS NULL OR i.created_at <= sqlc.narg(to_time)::timestamp) AND (sqlc.narg(batch_id)::uuid IS NULL OR i.batch_id = sqlc.narg(batch_id)::uuid) AND (sqlc.narg(external_id)::text IS NULL OR i.external_id = sqlc.narg(external_id)::text) AND (sqlc.narg(captured_from)::timestamp IS NULL OR i.captured_at >= sqlc.narg(captured_from)::timestamp) AND (sqlc.narg(captured_to)::timestamp IS NULL OR i.captured_at <= sqlc.narg(captured_to)::timestamp) ORDER BY CASE WHEN sqlc.arg(sort)::text = 'captured_at' THEN i.captured_at END ASC NULLS LAST, CASE WHEN sqlc.arg(sort)::text = '-captured_at' THEN i.captured_at END DESC NULLS LAST, CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN i.created_at END ASC, i.created_at DESC, i.id DESC LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountSearchUserImages :one
+SELECT COUNT(*) FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE b.user_id = sqlc.arg(user_id) AND b.deleted_at IS NULL AND i.deleted_at IS NULL AND (sqlc.narg(filename)::text IS NULL OR i.filename ILIKE '%' || sqlc.narg(filename)::text || '%' ESCAPE '\') AND (sqlc.narg(status)::image_status IS NULL OR i.status = sqlc.narg(status)::image_status) AND (sqlc.narg(from_time)::timestamp IS NULL OR i.created_at >= sqlc.narg(from_time)::timestamp) AND (sqlc.narg(to_time)::timestamp IS NULL OR i.created_at <= sqlc.narg(to_time)::timestamp) AND (sqlc.narg(batch_id)::uuid IS NULL OR i.batch_id = sqlc.narg(batch_id)::uuid) AND (sqlc.narg(external_id)::text IS NULL OR i.external_id = sqlc.narg(external_id)::text) AND (sqlc.narg(captured_from)::timestamp IS NULL OR i.captured_at >= sqlc.narg(captured_from)::timestamp) AND (sqlc.narg(captured_to)::timestamp IS NULL OR i.captured_at <= sqlc.narg(captured_to)::timestamp);
+
+-- name: GetStaleImages :many
+SELECT i.*, b.region AS batch_region FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE i.status IN ('pending', 'processing') AND i.updated_at < $1 AND i.deleted_at IS NULL AND b.waiting_since IS NULL ORDER BY i.updated_at;
+
+-- name: GetUserImageByID :one
+SELECT sqlc.embed(i), b.archive_status, b.region AS batch_region FROM images i INNER JOIN batches b ON b.id = i.batch_id WHERE i.id = $1 AND b.user_id = $2 AND i.deleted_at IS NULL AND b.deleted_at IS NULL;
+
+-- name: UpdateImageWatermarkPlacement :one
+UPDATE images SET placement_x = $1, placement_y = $2, placement_width = $3, placement_height = $4, processed_url = NULL, thumbnail_url = NULL, similar_to = NULL, status = 'pending', updated_at = NOW() WHERE id = $5 AND deleted_at IS NULL RETURNING *;
+
+-- name: DeleteUserImagesByIDs :many
+UPDATE images i SET deleted_at = NOW() FROM batches b WHERE b.id = i.batch_id AND b.user_id = $1 AND b.deleted_at IS NULL AND i.id = ANY(sqlc.arg(image_ids)::UUID[]) AND i.deleted_at IS NULL RETURNING i.*, b.region AS batch_region;
+
+-- name: RetryUserImagesByIDs :many
+UPDATE images i SET status = 'pending', updated_at = NOW() FROM batches b WHERE b.id = i.batch_id AND b.user_id = $1 AND b.deleted_at IS NULL AND b.archive_status IN ('active', 'restored') AND i.id = ANY(sqlc.arg(image_ids)::UUID[]) AND i.deleted_at IS NULL AND i.status = 'failed' RETURNING i.id, b.region AS batch_region;
+
+-- name: CancelPendingBatchImages :many
+UPDATE images SET status = 'cancelled', updated_at = NOW() WHERE batch_id = $1 AND status = 'pending' AND deleted_at IS NULL RETURNING id;
+
+-- name: ExpirePendingBatchImages :many
+UPDATE images SET status = 'expired', failure_reason = 'batch deadline passed', updated_at = NOW() WHERE batch_id = $1 AND status = 'pending' AND deleted_at IS NULL RETURNING id;
+
+-- name: ExpireImageByID :execrows
+UPDATE images SET status = 'expired', failure_reason = 'batch deadline passed', updated_at = NOW() WHERE id = $1 AND status IN ('pending', 'processing') AND deleted_at IS NULL;
+
+-- name: ClaimImageSlot :execrows
+UPDATE images i SET status = 'processing', failure_reason = NULL, attempts = i.attempts + 1, updated_at = NOW()
+WHERE i.id = sqlc.arg(id) AND i.deleted_at IS NULL AND i.status IN ('pending', 'processing') AND (
+    i.status = 'processing'
+    OR (SELECT COUNT(*) FROM images p WHERE p.batch_id = sqlc.arg(batch_id) AND p.status = 'processing' AND p.deleted_at IS NULL AND p.updated_at > sqlc.arg(active_since)) < sqlc.arg(max_concurrency)::bigint
+);
+
+-- name: GetBatchProgress :one
+SELECT COUNT(*) FILTER (WHERE status = 'pending') AS pending, COUNT(*) FILTER (WHERE status = 'processing') AS processing, COUNT(*) FILTER (WHERE status = 'completed') AS completed, COUNT(*) FILTER (WHERE status = 'failed') AS failed, COUNT(*) FILTER (WHERE status = 'cancelled') AS cancelled, COUNT(*) FILTER (WHERE status = 'expired') AS expired, COUNT(*) FILTER (WHERE status IN ('completed', 'failed') AND updated_at > NOW() - INTERVAL '5 minutes') AS recently_finished, COALESCE(EXTRACT(EPOCH FROM LEAST(INTERVAL '5 minutes', NOW() - MIN(created_at))), 0)::float8 AS window_seconds FROM images WHERE batch_id = $1 AND deleted_at IS NULL;
+
+-- name: DeleteBatchImages :many
+UPDATE images SET deleted_at = NOW(), updated_at = NOW() WHERE batch_id = $1 AND deleted_at IS NULL RETURNING *;
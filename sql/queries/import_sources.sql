@@ -0,0 +1,8 @@
+-- name: GetUserImportSources :many
+SELECT * FROM user_import_sources WHERE user_id = $1 ORDER BY bucket, prefix;
+
+-- name: CreateUserImportSource :one
+INSERT INTO user_import_sources(user_id, bucket, prefix) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: DeleteUserImportSource :execrows
+DELETE FROM user_import_sources WHERE id = $1 AND user_id = $2;
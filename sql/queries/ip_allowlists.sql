@@ -0,0 +1,11 @@
+-- name: GetUserIPAllowlist :many
+SELECT * FROM user_ip_allowlist_entries WHERE user_id = $1 ORDER BY created_at;
+
+-- name: GetUserAllowedCIDRs :many
+SELECT cidr FROM user_ip_allowlist_entries WHERE user_id = $1;
+
+-- name: CreateIPAllowlistEntry :one
+INSERT INTO user_ip_allowlist_entries(user_id, cidr, description) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: DeleteIPAllowlistEntry :execrows
+DELETE FROM user_ip_allowlist_entries WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,5 @@
+-- name: GetProcessedTask :one
+SELECT * FROM processed_tasks WHERE task_id = $1;
+
+-- name: CreateProcessedTask :execrows
+INSERT INTO processed_tasks(task_id, image_id, options_hash, outcome) VALUES($1, $2, $3, $4) ON CONFLICT (task_id) DO UPDATE SET image_id = EXCLUDED.image_id, options_hash = EXCLUDED.options_hash, outcome = EXCLUDED.outcome, created_at = NOW() WHERE processed_tasks.outcome = 'failed' AND EXISTS (SELECT 1 FROM images i WHERE i.id = EXCLUDED.image_id AND i.status IN ('pending', 'processing'));
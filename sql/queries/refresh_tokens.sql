@@ -1,5 +1,17 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens(user_id, token, expires_at) VALUES($1, $2, $3) RETURNING *;
+INSERT INTO refresh_tokens(user_id, token_hash, expires_at, user_agent, ip_address) VALUES($1, $2, $3, $4, $5) RETURNING *;
 
 -- name: GetRefreshToken :one
-SELECT * FROM refresh_tokens WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW();
+SELECT * FROM refresh_tokens WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW();
+
+-- name: TouchRefreshToken :exec
+UPDATE refresh_tokens SET last_used_at = NOW() WHERE id = $1;
+
+-- name: GetUserActiveRefreshTokens :many
+SELECT * FROM refresh_tokens WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() ORDER BY COALESCE(last_used_at, created_at) DESC;
+
+-- name: RevokeUserRefreshToken :execrows
+UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,8 @@
+-- name: CreateOutboxTask :exec
+INSERT INTO task_outbox(task_id, image_id, region, queue, payload, next_attempt_at) VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: DeleteOutboxTask :exec
+DELETE FROM task_outbox WHERE task_id = $1;
+
+-- name: ClaimDueOutboxTasks :many
+UPDATE task_outbox t SET next_attempt_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int) WHERE t.task_id IN (SELECT d.task_id FROM task_outbox d WHERE d.next_attempt_at <= NOW() ORDER BY d.next_attempt_at LIMIT sqlc.arg(page_limit) FOR UPDATE SKIP LOCKED) RETURNING t.*;
@@ -0,0 +1,11 @@
+-- name: CreateUserBoost :one
+INSERT INTO user_boosts(user_id, granted_by, reason, extra_active_batches, concurrency_multiplier, priority, starts_at, ends_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: GetUserBoosts :many
+SELECT * FROM user_boosts WHERE user_id = $1 ORDER BY starts_at DESC;
+
+-- name: GetActiveUserBoost :one
+SELECT COALESCE(MAX(extra_active_batches), 0)::int AS extra_active_batches, COALESCE(MAX(concurrency_multiplier), 1)::int AS concurrency_multiplier, COALESCE(BOOL_OR(priority), false)::bool AS priority FROM user_boosts WHERE user_id = $1 AND revoked_at IS NULL AND starts_at <= NOW() AND ends_at > NOW();
+
+-- name: RevokeUserBoost :one
+UPDATE user_boosts SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND ends_at > NOW() RETURNING *;
@@ -3,3 +3,30 @@ SELECT * FROM users WHERE email = $1 AND deleted_at IS NULL;
 
 -- name: CreateUser :one
 INSERT INTO users(email, password_hash) VALUES ($1, $2) RETURNING id, email, created_at, updated_at;
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: UpdateUserEmail :exec
+UPDATE users SET email = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL;
+
+-- name: LockUserByID :exec
+SELECT id FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: LockUserBatchQuota :exec
+SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE;
+
+-- name: ListUsers :many
+SELECT id, email, is_admin, disabled_at, created_at FROM users WHERE deleted_at IS NULL ORDER BY created_at, id LIMIT $1 OFFSET $2;
+
+-- name: UpdateUserPasswordByEmail :one
+UPDATE users SET password_hash = $1, updated_at = NOW() WHERE email = $2 AND deleted_at IS NULL RETURNING id;
+
+-- name: UpdateUserAdminByEmail :one
+UPDATE users SET is_admin = $1, updated_at = NOW() WHERE email = $2 AND deleted_at IS NULL RETURNING id;
+
+-- name: UpdateUserDisabledByEmail :one
+UPDATE users SET disabled_at = CASE WHEN sqlc.arg(disabled)::BOOLEAN THEN COALESCE(disabled_at, NOW()) END, updated_at = NOW() WHERE email = sqlc.arg(email) AND deleted_at IS NULL RETURNING id;
+
+-- name: UpdateUserRegionByEmail :one
+UPDATE users SET region = $1, updated_at = NOW() WHERE email = $2 AND deleted_at IS NULL RETURNING id;
@@ -0,0 +1,23 @@
+-- name: CreateWatermark :one
+INSERT INTO watermarks(user_id, name, key, url, content_type, width, height, size_bytes, region) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *;
+
+-- name: GetUserWatermarks :many
+SELECT * FROM watermarks WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC;
+
+-- name: GetUserWatermarkByID :one
+SELECT * FROM watermarks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: UpdateWatermarkName :one
+UPDATE watermarks SET name = $1, updated_at = NOW() WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL RETURNING *;
+
+-- name: DeleteWatermarkByID :execrows
+WITH cleared AS (
+    UPDATE users u SET default_watermark_id = NULL, updated_at = NOW() WHERE u.id = sqlc.arg(user_id) AND u.default_watermark_id = sqlc.arg(id)
+)
+UPDATE watermarks w SET deleted_at = NOW() WHERE w.id = sqlc.arg(id) AND w.user_id = sqlc.arg(user_id) AND w.deleted_at IS NULL;
+
+-- name: GetUserDefaultWatermark :one
+SELECT w.* FROM watermarks w JOIN users u ON u.default_watermark_id = w.id WHERE u.id = $1 AND w.deleted_at IS NULL;
+
+-- name: SetUserDefaultWatermark :exec
+UPDATE users SET default_watermark_id = $2, updated_at = NOW() WHERE id = $1;
@@ -0,0 +1,47 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks(user_id, url, secret) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: GetUserWebhooks :many
+SELECT * FROM webhooks WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC;
+
+-- name: GetUserWebhookByID :one
+SELECT * FROM webhooks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: DeleteWebhookByID :exec
+UPDATE webhooks SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: GetWebhookSecrets :many
+SELECT id, user_id, secret FROM webhooks;
+
+-- name: UpdateWebhookSecret :exec
+UPDATE webhooks SET secret = $1 WHERE id = $2;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries(webhook_id, event, payload, status_code, error, latency_ms, response_excerpt, event_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountWebhookDeliveries :one
+SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1;
+
+-- name: GetWebhookDeliveryByID :one
+SELECT * FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2;
+
+-- name: GetWebhookByID :one
+SELECT * FROM webhooks WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: EnqueueWebhookEvents :exec
+INSERT INTO webhook_events(webhook_id, event, payload) SELECT w.id, sqlc.arg(event)::text, sqlc.arg(payload)::jsonb FROM webhooks w WHERE w.user_id = sqlc.arg(user_id) AND w.deleted_at IS NULL;
+
+-- name: ClaimDueWebhookEvents :many
+UPDATE webhook_events e SET next_attempt_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int) WHERE e.id IN (SELECT d.id FROM webhook_events d WHERE d.delivered_at IS NULL AND d.failed_at IS NULL AND d.next_attempt_at <= NOW() ORDER BY d.next_attempt_at LIMIT sqlc.arg(page_limit) FOR UPDATE SKIP LOCKED) RETURNING e.*;
+
+-- name: MarkWebhookEventDelivered :exec
+UPDATE webhook_events SET attempts = attempts + 1, delivered_at = NOW() WHERE id = $1;
+
+-- name: RetryWebhookEvent :exec
+UPDATE webhook_events SET attempts = attempts + 1, next_attempt_at = $2 WHERE id = $1;
+
+-- name: FailWebhookEvent :exec
+UPDATE webhook_events SET attempts = attempts + 1, failed_at = NOW() WHERE id = $1;
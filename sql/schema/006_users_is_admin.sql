@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose down
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,14 @@
+-- +goose up
+CREATE TABLE auth_events(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    email VARCHAR(255),
+    reason VARCHAR(50) NOT NULL,
+    ip_address VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX auth_events_created_at_idx ON auth_events(created_at);
+CREATE INDEX auth_events_email_reason_created_at_idx ON auth_events(email, reason, created_at);
+
+-- +goose down
+DROP TABLE auth_events;
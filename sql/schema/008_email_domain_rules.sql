@@ -0,0 +1,19 @@
+-- +goose up
+CREATE TYPE email_domain_rule_type AS ENUM ('block', 'allow');
+CREATE TABLE email_domain_rules(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    domain VARCHAR(255) NOT NULL UNIQUE,
+    rule email_domain_rule_type NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+INSERT INTO email_domain_rules(domain, rule) VALUES
+    ('mailinator.com', 'block'),
+    ('guerrillamail.com', 'block'),
+    ('10minutemail.com', 'block'),
+    ('yopmail.com', 'block'),
+    ('temp-mail.org', 'block'),
+    ('trashmail.com', 'block');
+
+-- +goose down
+DROP TABLE email_domain_rules;
+DROP TYPE IF EXISTS email_domain_rule_type;
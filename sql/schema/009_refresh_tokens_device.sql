@@ -0,0 +1,9 @@
+-- +goose up
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN ip_address VARCHAR(64);
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at TIMESTAMP;
+
+-- +goose down
+ALTER TABLE refresh_tokens DROP COLUMN last_used_at;
+ALTER TABLE refresh_tokens DROP COLUMN ip_address;
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
@@ -0,0 +1,9 @@
+-- +goose up
+CREATE TYPE batch_archive_status AS ENUM ('active', 'archived', 'restoring', 'restored');
+ALTER TABLE batches ADD COLUMN archive_status batch_archive_status NOT NULL DEFAULT 'active';
+ALTER TABLE batches ADD COLUMN archived_at TIMESTAMP;
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN archived_at;
+ALTER TABLE batches DROP COLUMN archive_status;
+DROP TYPE IF EXISTS batch_archive_status;
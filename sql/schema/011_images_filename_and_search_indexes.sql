@@ -0,0 +1,14 @@
+-- +goose up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+ALTER TABLE images ADD COLUMN filename VARCHAR(255);
+CREATE INDEX images_batch_id_created_at_idx ON images(batch_id, created_at);
+CREATE INDEX images_status_idx ON images(status);
+CREATE INDEX images_filename_trgm_idx ON images USING gin (filename gin_trgm_ops);
+CREATE INDEX batches_user_id_idx ON batches(user_id);
+
+-- +goose down
+DROP INDEX IF EXISTS batches_user_id_idx;
+DROP INDEX IF EXISTS images_filename_trgm_idx;
+DROP INDEX IF EXISTS images_status_idx;
+DROP INDEX IF EXISTS images_batch_id_created_at_idx;
+ALTER TABLE images DROP COLUMN filename;
@@ -0,0 +1,9 @@
+-- +goose up
+CREATE TYPE output_collision_policy AS ENUM ('overwrite', 'suffix', 'error');
+ALTER TABLE batches ADD COLUMN preserve_filenames BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE batches ADD COLUMN collision_policy output_collision_policy NOT NULL DEFAULT 'suffix';
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN collision_policy;
+ALTER TABLE batches DROP COLUMN preserve_filenames;
+DROP TYPE IF EXISTS output_collision_policy;
@@ -0,0 +1,28 @@
+-- +goose up
+CREATE TABLE batch_comments(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    image_id UUID REFERENCES images(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES batch_comments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+CREATE INDEX batch_comments_batch_id_created_at_idx ON batch_comments(batch_id, created_at);
+
+CREATE TYPE batch_event_type AS ENUM ('created', 'archived', 'restore_requested', 'restored');
+CREATE TABLE batch_events(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type batch_event_type NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX batch_events_batch_id_created_at_idx ON batch_events(batch_id, created_at);
+
+-- +goose down
+DROP TABLE batch_events;
+DROP TYPE IF EXISTS batch_event_type;
+DROP TABLE batch_comments;
@@ -0,0 +1,19 @@
+-- +goose up
+CREATE TABLE fonts(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+CREATE INDEX fonts_user_id_idx ON fonts(user_id);
+ALTER TABLE batches ADD COLUMN watermark_text TEXT;
+ALTER TABLE batches ADD COLUMN watermark_font_id UUID REFERENCES fonts(id) ON DELETE SET NULL;
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN watermark_font_id;
+ALTER TABLE batches DROP COLUMN watermark_text;
+DROP TABLE fonts;
@@ -0,0 +1,11 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN placement_x DOUBLE PRECISION;
+ALTER TABLE images ADD COLUMN placement_y DOUBLE PRECISION;
+ALTER TABLE images ADD COLUMN placement_width DOUBLE PRECISION;
+ALTER TABLE images ADD COLUMN placement_height DOUBLE PRECISION;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN placement_height;
+ALTER TABLE images DROP COLUMN placement_width;
+ALTER TABLE images DROP COLUMN placement_y;
+ALTER TABLE images DROP COLUMN placement_x;
@@ -0,0 +1,7 @@
+-- +goose up
+CREATE TYPE watermark_position AS ENUM ('top-left', 'top-right', 'bottom-left', 'bottom-right', 'center', 'tiled');
+ALTER TABLE batches ADD COLUMN watermark_position watermark_position NOT NULL DEFAULT 'bottom-right';
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN watermark_position;
+DROP TYPE IF EXISTS watermark_position;
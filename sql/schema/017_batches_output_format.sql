@@ -0,0 +1,9 @@
+-- +goose up
+CREATE TYPE output_format AS ENUM ('jpeg', 'png');
+ALTER TABLE batches ADD COLUMN output_format output_format NOT NULL DEFAULT 'jpeg';
+ALTER TABLE batches ADD COLUMN output_quality INTEGER NOT NULL DEFAULT 50 CHECK (output_quality BETWEEN 1 AND 100);
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN output_quality;
+ALTER TABLE batches DROP COLUMN output_format;
+DROP TYPE IF EXISTS output_format;
@@ -0,0 +1,12 @@
+-- +goose NO TRANSACTION
+-- +goose up
+ALTER TYPE output_format ADD VALUE IF NOT EXISTS 'webp';
+
+-- +goose down
+UPDATE batches SET output_format = 'jpeg' WHERE output_format = 'webp';
+ALTER TABLE batches ALTER COLUMN output_format DROP DEFAULT;
+ALTER TYPE output_format RENAME TO output_format_old;
+CREATE TYPE output_format AS ENUM ('jpeg', 'png');
+ALTER TABLE batches ALTER COLUMN output_format TYPE output_format USING output_format::text::output_format;
+ALTER TABLE batches ALTER COLUMN output_format SET DEFAULT 'jpeg';
+DROP TYPE output_format_old;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN watermark_opacity INTEGER NOT NULL DEFAULT 50 CHECK (watermark_opacity BETWEEN 0 AND 100);
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN watermark_opacity;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN watermark_scale INTEGER NOT NULL DEFAULT 15 CHECK (watermark_scale BETWEEN 1 AND 100);
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN watermark_scale;
@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN max_concurrency INTEGER NOT NULL DEFAULT 10 CHECK (max_concurrency BETWEEN 1 AND 1000);
+CREATE INDEX images_batch_id_status_idx ON images(batch_id, status) WHERE deleted_at IS NULL;
+
+-- +goose down
+DROP INDEX IF EXISTS images_batch_id_status_idx;
+ALTER TABLE batches DROP COLUMN max_concurrency;
@@ -0,0 +1,14 @@
+-- +goose NO TRANSACTION
+-- +goose up
+ALTER TYPE watermark_position ADD VALUE IF NOT EXISTS 'diagonal';
+ALTER TABLE batches ADD COLUMN watermark_tile_spacing INTEGER NOT NULL DEFAULT 50 CHECK (watermark_tile_spacing BETWEEN 0 AND 500);
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN watermark_tile_spacing;
+UPDATE batches SET watermark_position = 'tiled' WHERE watermark_position = 'diagonal';
+ALTER TABLE batches ALTER COLUMN watermark_position DROP DEFAULT;
+ALTER TYPE watermark_position RENAME TO watermark_position_old;
+CREATE TYPE watermark_position AS ENUM ('top-left', 'top-right', 'bottom-left', 'bottom-right', 'center', 'tiled');
+ALTER TABLE batches ALTER COLUMN watermark_position TYPE watermark_position USING watermark_position::text::watermark_position;
+ALTER TABLE batches ALTER COLUMN watermark_position SET DEFAULT 'bottom-right';
+DROP TYPE watermark_position_old;
@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN palette TEXT[];
+ALTER TABLE images ADD COLUMN blurhash TEXT;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN blurhash;
+ALTER TABLE images DROP COLUMN palette;
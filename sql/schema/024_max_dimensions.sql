@@ -0,0 +1,11 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN max_width INTEGER CHECK (max_width BETWEEN 1 AND 10000);
+ALTER TABLE batches ADD COLUMN max_height INTEGER CHECK (max_height BETWEEN 1 AND 10000);
+ALTER TABLE images ADD COLUMN width INTEGER;
+ALTER TABLE images ADD COLUMN height INTEGER;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN height;
+ALTER TABLE images DROP COLUMN width;
+ALTER TABLE batches DROP COLUMN max_height;
+ALTER TABLE batches DROP COLUMN max_width;
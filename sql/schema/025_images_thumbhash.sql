@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN thumbhash TEXT;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN thumbhash;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN thumbnail_url TEXT;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN thumbnail_url;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN preserve_metadata BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN preserve_metadata;
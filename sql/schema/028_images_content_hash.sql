@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN content_hash TEXT;
+CREATE INDEX images_content_hash_idx ON images(content_hash) WHERE deleted_at IS NULL AND content_hash IS NOT NULL;
+
+-- +goose down
+DROP INDEX IF EXISTS images_content_hash_idx;
+ALTER TABLE images DROP COLUMN content_hash;
@@ -0,0 +1,14 @@
+-- +goose NO TRANSACTION
+-- +goose up
+ALTER TYPE watermark_position ADD VALUE IF NOT EXISTS 'auto';
+ALTER TABLE images ADD COLUMN applied_watermark_position watermark_position;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN applied_watermark_position;
+UPDATE batches SET watermark_position = 'bottom-right' WHERE watermark_position = 'auto';
+ALTER TABLE batches ALTER COLUMN watermark_position DROP DEFAULT;
+ALTER TYPE watermark_position RENAME TO watermark_position_old;
+CREATE TYPE watermark_position AS ENUM ('top-left', 'top-right', 'bottom-left', 'bottom-right', 'center', 'tiled', 'diagonal');
+ALTER TABLE batches ALTER COLUMN watermark_position TYPE watermark_position USING watermark_position::text::watermark_position;
+ALTER TABLE batches ALTER COLUMN watermark_position SET DEFAULT 'bottom-right';
+DROP TYPE watermark_position_old;
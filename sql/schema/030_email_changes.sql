@@ -0,0 +1,19 @@
+-- +goose up
+CREATE TABLE email_changes(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255) NOT NULL,
+    confirm_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    undo_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    undo_expires_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    reverted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX email_changes_user_id_idx ON email_changes(user_id);
+
+-- +goose down
+DROP TABLE email_changes;
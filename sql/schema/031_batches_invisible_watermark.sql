@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN invisible_watermark BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN invisible_watermark;
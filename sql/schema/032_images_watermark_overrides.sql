@@ -0,0 +1,9 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN watermark_position_override watermark_position;
+ALTER TABLE images ADD COLUMN watermark_opacity_override INTEGER CHECK (watermark_opacity_override BETWEEN 0 AND 100);
+ALTER TABLE images ADD COLUMN watermark_scale_override INTEGER CHECK (watermark_scale_override BETWEEN 1 AND 100);
+
+-- +goose down
+ALTER TABLE images DROP COLUMN watermark_scale_override;
+ALTER TABLE images DROP COLUMN watermark_opacity_override;
+ALTER TABLE images DROP COLUMN watermark_position_override;
@@ -0,0 +1,30 @@
+-- +goose up
+CREATE TABLE webhooks(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    -- Encrypted with the secrets keyring, see utils.Keyring.
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+CREATE INDEX webhooks_user_id_idx ON webhooks(user_id);
+
+CREATE TABLE webhook_deliveries(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    -- NULL when the request failed before a response arrived.
+    status_code INTEGER,
+    error TEXT,
+    latency_ms INTEGER NOT NULL,
+    response_excerpt TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX webhook_deliveries_webhook_id_created_at_idx ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- +goose down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,13 @@
+-- +goose up
+CREATE TYPE chroma_subsampling AS ENUM ('444', '422', '420');
+CREATE TYPE png_compression AS ENUM ('default', 'none', 'fast', 'best');
+ALTER TABLE batches ADD COLUMN jpeg_progressive BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE batches ADD COLUMN jpeg_subsampling chroma_subsampling NOT NULL DEFAULT '420';
+ALTER TABLE batches ADD COLUMN png_compression png_compression NOT NULL DEFAULT 'default';
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN png_compression;
+ALTER TABLE batches DROP COLUMN jpeg_subsampling;
+ALTER TABLE batches DROP COLUMN jpeg_progressive;
+DROP TYPE IF EXISTS png_compression;
+DROP TYPE IF EXISTS chroma_subsampling;
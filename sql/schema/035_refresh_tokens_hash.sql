@@ -0,0 +1,13 @@
+-- +goose up
+-- Refresh tokens are stored as the hex SHA-256 of the token.
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+UPDATE refresh_tokens SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_user_id_token_key;
+CREATE UNIQUE INDEX refresh_tokens_token_hash_idx ON refresh_tokens(token_hash);
+
+-- +goose down
+-- Hashes cannot be turned back into tokens, so every session is revoked.
+DROP INDEX IF EXISTS refresh_tokens_token_hash_idx;
+UPDATE refresh_tokens SET revoked_at = NOW() WHERE revoked_at IS NULL;
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_user_id_token_key UNIQUE(user_id, token);
@@ -0,0 +1,18 @@
+-- +goose up
+-- width and height describe the processed file.
+ALTER TABLE images ADD COLUMN original_width INTEGER;
+ALTER TABLE images ADD COLUMN original_height INTEGER;
+ALTER TABLE images ADD COLUMN original_size BIGINT;
+ALTER TABLE images ADD COLUMN original_format TEXT;
+ALTER TABLE images ADD COLUMN original_dominant_color TEXT;
+ALTER TABLE images ADD COLUMN processed_size BIGINT;
+ALTER TABLE images ADD COLUMN processed_format TEXT;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN processed_format;
+ALTER TABLE images DROP COLUMN processed_size;
+ALTER TABLE images DROP COLUMN original_dominant_color;
+ALTER TABLE images DROP COLUMN original_format;
+ALTER TABLE images DROP COLUMN original_size;
+ALTER TABLE images DROP COLUMN original_height;
+ALTER TABLE images DROP COLUMN original_width;
@@ -0,0 +1,11 @@
+-- +goose up
+CREATE TYPE similar_dedupe AS ENUM ('off', 'batch', 'account');
+ALTER TABLE batches ADD COLUMN similar_dedupe similar_dedupe NOT NULL DEFAULT 'off';
+ALTER TABLE images ADD COLUMN phash BIGINT;
+ALTER TABLE images ADD COLUMN similar_to UUID REFERENCES images(id) ON DELETE SET NULL;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN similar_to;
+ALTER TABLE images DROP COLUMN phash;
+ALTER TABLE batches DROP COLUMN similar_dedupe;
+DROP TYPE IF EXISTS similar_dedupe;
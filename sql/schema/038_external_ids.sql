@@ -0,0 +1,11 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN external_id VARCHAR(255);
+ALTER TABLE images ADD COLUMN external_id VARCHAR(255);
+CREATE UNIQUE INDEX batches_user_id_external_id_key ON batches(user_id, external_id) WHERE deleted_at IS NULL;
+CREATE INDEX images_external_id_idx ON images(external_id) WHERE external_id IS NOT NULL;
+
+-- +goose down
+DROP INDEX IF EXISTS images_external_id_idx;
+DROP INDEX IF EXISTS batches_user_id_external_id_key;
+ALTER TABLE images DROP COLUMN external_id;
+ALTER TABLE batches DROP COLUMN external_id;
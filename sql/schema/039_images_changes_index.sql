@@ -0,0 +1,5 @@
+-- +goose up
+CREATE INDEX images_batch_id_updated_at_id_idx ON images(batch_id, updated_at, id);
+
+-- +goose down
+DROP INDEX IF EXISTS images_batch_id_updated_at_id_idx;
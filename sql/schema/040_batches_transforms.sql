@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN transforms JSONB NOT NULL DEFAULT '[]';
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN transforms;
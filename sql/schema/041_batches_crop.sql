@@ -0,0 +1,19 @@
+-- +goose up
+-- A batch crops either to an aspect ratio, centered, or to a pixel rectangle.
+ALTER TABLE batches ADD COLUMN crop_aspect_ratio TEXT;
+ALTER TABLE batches ADD COLUMN crop_x INTEGER CHECK (crop_x >= 0);
+ALTER TABLE batches ADD COLUMN crop_y INTEGER CHECK (crop_y >= 0);
+ALTER TABLE batches ADD COLUMN crop_width INTEGER CHECK (crop_width > 0);
+ALTER TABLE batches ADD COLUMN crop_height INTEGER CHECK (crop_height > 0);
+ALTER TABLE batches ADD CONSTRAINT batches_crop_check CHECK (
+    (crop_x IS NULL AND crop_y IS NULL AND crop_width IS NULL AND crop_height IS NULL)
+    OR (crop_aspect_ratio IS NULL AND crop_x IS NOT NULL AND crop_y IS NOT NULL AND crop_width IS NOT NULL AND crop_height IS NOT NULL)
+);
+
+-- +goose down
+ALTER TABLE batches DROP CONSTRAINT batches_crop_check;
+ALTER TABLE batches DROP COLUMN crop_height;
+ALTER TABLE batches DROP COLUMN crop_width;
+ALTER TABLE batches DROP COLUMN crop_y;
+ALTER TABLE batches DROP COLUMN crop_x;
+ALTER TABLE batches DROP COLUMN crop_aspect_ratio;
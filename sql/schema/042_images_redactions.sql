@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN redactions JSONB NOT NULL DEFAULT '[]';
+
+-- +goose down
+ALTER TABLE images DROP COLUMN redactions;
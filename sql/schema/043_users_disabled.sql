@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
+
+-- +goose down
+ALTER TABLE users DROP COLUMN disabled_at;
@@ -0,0 +1,16 @@
+-- +goose up
+CREATE TYPE task_outcome AS ENUM ('completed', 'linked', 'failed');
+
+CREATE TABLE processed_tasks(
+    task_id UUID PRIMARY KEY,
+    image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    options_hash TEXT NOT NULL,
+    outcome task_outcome NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX processed_tasks_image_id_idx ON processed_tasks(image_id);
+
+-- +goose down
+DROP TABLE processed_tasks;
+DROP TYPE task_outcome;
@@ -0,0 +1,21 @@
+-- +goose up
+CREATE TABLE watermarks(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+CREATE INDEX watermarks_user_id_idx ON watermarks(user_id);
+ALTER TABLE batches ADD COLUMN watermark_id UUID REFERENCES watermarks(id) ON DELETE SET NULL;
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN watermark_id;
+DROP TABLE watermarks;
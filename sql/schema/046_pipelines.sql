@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN pipeline JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE images ADD COLUMN step_timings JSONB NOT NULL DEFAULT '[]';
+
+-- +goose down
+ALTER TABLE images DROP COLUMN step_timings;
+ALTER TABLE batches DROP COLUMN pipeline;
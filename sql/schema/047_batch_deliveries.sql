@@ -0,0 +1,24 @@
+-- +goose up
+CREATE TABLE batch_deliveries(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    subject VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    reply_to VARCHAR(255),
+    logo_url TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP,
+    open_count INTEGER NOT NULL DEFAULT 0,
+    first_opened_at TIMESTAMP,
+    last_opened_at TIMESTAMP,
+    download_count INTEGER NOT NULL DEFAULT 0,
+    last_downloaded_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX batch_deliveries_batch_id_created_at_idx ON batch_deliveries(batch_id, created_at);
+
+-- +goose down
+DROP TABLE batch_deliveries;
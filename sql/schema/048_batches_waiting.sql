@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN waiting_since TIMESTAMP;
+CREATE INDEX batches_waiting_since_idx ON batches(user_id, waiting_since) WHERE waiting_since IS NOT NULL;
+
+-- +goose down
+DROP INDEX batches_waiting_since_idx;
+ALTER TABLE batches DROP COLUMN waiting_since;
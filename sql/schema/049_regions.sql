@@ -0,0 +1,11 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN region VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE batches ADD COLUMN region VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE fonts ADD COLUMN region VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE watermarks ADD COLUMN region VARCHAR(32) NOT NULL DEFAULT '';
+
+-- +goose down
+ALTER TABLE watermarks DROP COLUMN region;
+ALTER TABLE fonts DROP COLUMN region;
+ALTER TABLE batches DROP COLUMN region;
+ALTER TABLE users DROP COLUMN region;
@@ -0,0 +1,18 @@
+-- +goose NO TRANSACTION
+-- +goose up
+ALTER TYPE image_status ADD VALUE IF NOT EXISTS 'cancelled';
+ALTER TYPE batch_event_type ADD VALUE IF NOT EXISTS 'cancelled';
+
+-- +goose down
+DELETE FROM batch_events WHERE type = 'cancelled';
+ALTER TYPE batch_event_type RENAME TO batch_event_type_old;
+CREATE TYPE batch_event_type AS ENUM ('created', 'archived', 'restore_requested', 'restored');
+ALTER TABLE batch_events ALTER COLUMN type TYPE batch_event_type USING type::text::batch_event_type;
+DROP TYPE batch_event_type_old;
+UPDATE images SET status = 'failed' WHERE status = 'cancelled';
+ALTER TABLE images ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE image_status RENAME TO image_status_old;
+CREATE TYPE image_status AS ENUM ('pending', 'processing', 'completed', 'failed');
+ALTER TABLE images ALTER COLUMN status TYPE image_status USING status::text::image_status;
+ALTER TABLE images ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE image_status_old;
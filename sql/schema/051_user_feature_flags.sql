@@ -0,0 +1,10 @@
+-- +goose up
+CREATE TABLE user_feature_flags(
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    flag VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, flag)
+);
+
+-- +goose down
+DROP TABLE user_feature_flags;
@@ -0,0 +1,7 @@
+-- +goose up
+-- Images created from a URL have an empty key until a worker fetches the
+-- original from source_url.
+ALTER TABLE images ADD COLUMN source_url TEXT;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN source_url;
@@ -0,0 +1,16 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN failure_reason TEXT;
+
+-- report_generated_at is set when a worker starts writing the report of a
+-- finished batch; the URLs once it has been stored.
+ALTER TABLE batches ADD COLUMN report_csv BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE batches ADD COLUMN report_url TEXT;
+ALTER TABLE batches ADD COLUMN report_csv_url TEXT;
+ALTER TABLE batches ADD COLUMN report_generated_at TIMESTAMP;
+
+-- +goose down
+ALTER TABLE batches DROP COLUMN report_generated_at;
+ALTER TABLE batches DROP COLUMN report_csv_url;
+ALTER TABLE batches DROP COLUMN report_url;
+ALTER TABLE batches DROP COLUMN report_csv;
+ALTER TABLE images DROP COLUMN failure_reason;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN default_watermark_id UUID REFERENCES watermarks(id) ON DELETE SET NULL;
+
+-- +goose down
+ALTER TABLE users DROP COLUMN default_watermark_id;
@@ -0,0 +1,12 @@
+-- +goose up
+CREATE TABLE user_ip_allowlist_entries(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cidr VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, cidr)
+);
+
+-- +goose down
+DROP TABLE user_ip_allowlist_entries;
@@ -0,0 +1,17 @@
+-- +goose up
+CREATE TABLE user_boosts(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    extra_active_batches INTEGER NOT NULL DEFAULT 0 CHECK (extra_active_batches BETWEEN 0 AND 100),
+    concurrency_multiplier INTEGER NOT NULL DEFAULT 1 CHECK (concurrency_multiplier BETWEEN 1 AND 10),
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL CHECK (ends_at > starts_at),
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX user_boosts_user_id_ends_at_idx ON user_boosts(user_id, ends_at);
+
+-- +goose down
+DROP TABLE user_boosts;
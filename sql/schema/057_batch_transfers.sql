@@ -0,0 +1,29 @@
+-- +goose NO TRANSACTION
+-- +goose up
+ALTER TYPE batch_event_type ADD VALUE IF NOT EXISTS 'transferred';
+
+CREATE TYPE batch_transfer_status AS ENUM ('pending', 'accepted', 'declined', 'cancelled');
+-- A transfer is offered by from_user_id at created_at and accepted or
+-- declined by to_user_id at responded_at.
+CREATE TABLE batch_transfers(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT NOT NULL DEFAULT '',
+    status batch_transfer_status NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP
+);
+CREATE UNIQUE INDEX batch_transfers_pending_batch_id_key ON batch_transfers(batch_id) WHERE status = 'pending';
+CREATE INDEX batch_transfers_to_user_id_created_at_idx ON batch_transfers(to_user_id, created_at);
+CREATE INDEX batch_transfers_from_user_id_created_at_idx ON batch_transfers(from_user_id, created_at);
+
+-- +goose down
+DROP TABLE batch_transfers;
+DROP TYPE batch_transfer_status;
+DELETE FROM batch_events WHERE type = 'transferred';
+ALTER TYPE batch_event_type RENAME TO batch_event_type_old;
+CREATE TYPE batch_event_type AS ENUM ('created', 'archived', 'restore_requested', 'restored', 'cancelled');
+ALTER TABLE batch_events ALTER COLUMN type TYPE batch_event_type USING type::text::batch_event_type;
+DROP TYPE batch_event_type_old;
@@ -0,0 +1,22 @@
+-- +goose up
+-- Events waiting to be delivered to a webhook, retried with backoff until a
+-- 2xx response (delivered_at) or the last attempt (failed_at).
+CREATE TABLE webhook_events(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX webhook_events_next_attempt_at_idx ON webhook_events(next_attempt_at) WHERE delivered_at IS NULL AND failed_at IS NULL;
+CREATE INDEX webhook_events_webhook_id_idx ON webhook_events(webhook_id);
+
+ALTER TABLE webhook_deliveries ADD COLUMN event_id UUID REFERENCES webhook_events(id) ON DELETE SET NULL;
+
+-- +goose down
+ALTER TABLE webhook_deliveries DROP COLUMN event_id;
+DROP TABLE webhook_events;
@@ -0,0 +1,20 @@
+-- +goose up
+CREATE TABLE batch_presets(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    watermark_id UUID REFERENCES watermarks(id) ON DELETE SET NULL,
+    watermark_position watermark_position,
+    watermark_opacity INTEGER CHECK (watermark_opacity BETWEEN 0 AND 100),
+    watermark_scale INTEGER CHECK (watermark_scale BETWEEN 1 AND 100),
+    output_format output_format,
+    output_quality INTEGER CHECK (output_quality BETWEEN 1 AND 100),
+    max_width INTEGER CHECK (max_width BETWEEN 1 AND 10000),
+    max_height INTEGER CHECK (max_height BETWEEN 1 AND 10000),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+-- +goose down
+DROP TABLE batch_presets;
@@ -0,0 +1,15 @@
+-- +goose NO TRANSACTION
+-- +goose up
+ALTER TYPE batch_event_type ADD VALUE IF NOT EXISTS 'expired';
+
+ALTER TABLE batches ADD COLUMN expires_at TIMESTAMP;
+CREATE INDEX batches_expires_at_idx ON batches(expires_at) WHERE expires_at IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose down
+DROP INDEX IF EXISTS batches_expires_at_idx;
+ALTER TABLE batches DROP COLUMN expires_at;
+DELETE FROM batch_events WHERE type = 'expired';
+ALTER TYPE batch_event_type RENAME TO batch_event_type_old;
+CREATE TYPE batch_event_type AS ENUM ('created', 'archived', 'restore_requested', 'restored', 'cancelled', 'transferred');
+ALTER TABLE batch_events ALTER COLUMN type TYPE batch_event_type USING type::text::batch_event_type;
+DROP TYPE batch_event_type_old;
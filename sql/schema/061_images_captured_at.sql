@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN captured_at TIMESTAMP;
+CREATE INDEX images_batch_id_captured_at_idx ON images(batch_id, captured_at) WHERE captured_at IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose down
+DROP INDEX IF EXISTS images_batch_id_captured_at_idx;
+ALTER TABLE images DROP COLUMN captured_at;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE images ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+-- +goose down
+ALTER TABLE images DROP COLUMN attempts;
@@ -0,0 +1,23 @@
+-- +goose NO TRANSACTION
+-- +goose up
+ALTER TYPE image_status ADD VALUE IF NOT EXISTS 'expired';
+ALTER TYPE batch_event_type ADD VALUE IF NOT EXISTS 'deadline_passed';
+
+ALTER TABLE batches ADD COLUMN deadline TIMESTAMP;
+CREATE INDEX batches_deadline_idx ON batches(deadline) WHERE deadline IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose down
+DROP INDEX IF EXISTS batches_deadline_idx;
+ALTER TABLE batches DROP COLUMN deadline;
+DELETE FROM batch_events WHERE type = 'deadline_passed';
+ALTER TYPE batch_event_type RENAME TO batch_event_type_old;
+CREATE TYPE batch_event_type AS ENUM ('created', 'archived', 'restore_requested', 'restored', 'cancelled', 'transferred', 'expired');
+ALTER TABLE batch_events ALTER COLUMN type TYPE batch_event_type USING type::text::batch_event_type;
+DROP TYPE batch_event_type_old;
+UPDATE images SET status = 'failed' WHERE status = 'expired';
+ALTER TABLE images ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE image_status RENAME TO image_status_old;
+CREATE TYPE image_status AS ENUM ('pending', 'processing', 'completed', 'failed', 'cancelled');
+ALTER TABLE images ALTER COLUMN status TYPE image_status USING status::text::image_status;
+ALTER TABLE images ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE image_status_old;
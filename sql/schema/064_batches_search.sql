@@ -0,0 +1,8 @@
+-- +goose up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX batches_name_trgm_idx ON batches USING gin (name gin_trgm_ops);
+CREATE INDEX images_key_trgm_idx ON images USING gin (key gin_trgm_ops);
+
+-- +goose down
+DROP INDEX IF EXISTS images_key_trgm_idx;
+DROP INDEX IF EXISTS batches_name_trgm_idx;
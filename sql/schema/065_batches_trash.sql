@@ -0,0 +1,9 @@
+-- +goose up
+ALTER TABLE batches ADD COLUMN purged_at TIMESTAMP;
+-- Batches removed when their TTL ran out already had their objects deleted.
+UPDATE batches SET purged_at = deleted_at WHERE deleted_at IS NOT NULL AND expires_at IS NOT NULL AND expires_at <= deleted_at;
+CREATE INDEX batches_trash_idx ON batches(deleted_at) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
+
+-- +goose down
+DROP INDEX IF EXISTS batches_trash_idx;
+ALTER TABLE batches DROP COLUMN purged_at;
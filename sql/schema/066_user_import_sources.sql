@@ -0,0 +1,12 @@
+-- +goose up
+CREATE TABLE user_import_sources(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    bucket VARCHAR(63) NOT NULL,
+    prefix VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, bucket, prefix)
+);
+
+-- +goose down
+DROP TABLE user_import_sources;
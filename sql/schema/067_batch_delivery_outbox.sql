@@ -0,0 +1,17 @@
+-- +goose up
+-- Delivery emails are sent by a poller rather than the request, retried until
+-- they are sent (sent_at) or given up (failed_at).
+ALTER TABLE batch_deliveries ADD COLUMN send_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE batch_deliveries ADD COLUMN next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW();
+ALTER TABLE batch_deliveries ADD COLUMN failed_at TIMESTAMP;
+-- Deliveries not sent so far failed while their request was answered.
+UPDATE batch_deliveries SET send_attempts = 1, failed_at = created_at WHERE sent_at IS NULL;
+CREATE INDEX batch_deliveries_next_attempt_at_idx ON batch_deliveries(next_attempt_at) WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX batch_deliveries_user_id_created_at_idx ON batch_deliveries(user_id, created_at);
+
+-- +goose down
+DROP INDEX batch_deliveries_user_id_created_at_idx;
+DROP INDEX batch_deliveries_next_attempt_at_idx;
+ALTER TABLE batch_deliveries DROP COLUMN failed_at;
+ALTER TABLE batch_deliveries DROP COLUMN next_attempt_at;
+ALTER TABLE batch_deliveries DROP COLUMN send_attempts;
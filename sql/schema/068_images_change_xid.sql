@@ -0,0 +1,26 @@
+-- +goose up
+-- change_xid is the transaction that last wrote the row. The changes feed
+-- only returns rows of transactions older than every one still running, so
+-- a change that commits later always sorts after the cursor handed out.
+ALTER TABLE images ADD COLUMN change_xid BIGINT NOT NULL DEFAULT 0;
+
+-- +goose StatementBegin
+CREATE FUNCTION images_set_change_xid() RETURNS trigger AS $$
+BEGIN
+    NEW.change_xid := pg_current_xact_id()::text::bigint;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER images_change_xid BEFORE INSERT OR UPDATE ON images FOR EACH ROW EXECUTE FUNCTION images_set_change_xid();
+
+DROP INDEX IF EXISTS images_batch_id_updated_at_id_idx;
+CREATE INDEX images_batch_id_change_xid_id_idx ON images(batch_id, change_xid, id);
+
+-- +goose down
+DROP INDEX IF EXISTS images_batch_id_change_xid_id_idx;
+CREATE INDEX images_batch_id_updated_at_id_idx ON images(batch_id, updated_at, id);
+DROP TRIGGER images_change_xid ON images;
+DROP FUNCTION images_set_change_xid();
+ALTER TABLE images DROP COLUMN change_xid;
@@ -0,0 +1,18 @@
+-- +goose up
+-- Image tasks are written here in the transaction that creates their images
+-- and removed once published, so a task whose publish fails after the commit
+-- is published again by a poller instead of being lost.
+CREATE TABLE task_outbox(
+    task_id UUID PRIMARY KEY,
+    image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    region TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    next_attempt_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX task_outbox_next_attempt_at_idx ON task_outbox(next_attempt_at);
+CREATE INDEX task_outbox_image_id_idx ON task_outbox(image_id);
+
+-- +goose down
+DROP TABLE task_outbox;
@@ -0,0 +1,10 @@
+-- +goose up
+-- Tasks of users with an active priority boost go to a separate queue of
+-- their region, which workers consume next to the shared one. The outbox
+-- keeps the queue a task was meant for.
+ALTER TABLE user_boosts ADD COLUMN priority BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE task_outbox ADD COLUMN queue TEXT NOT NULL DEFAULT '';
+
+-- +goose down
+ALTER TABLE task_outbox DROP COLUMN queue;
+ALTER TABLE user_boosts DROP COLUMN priority;
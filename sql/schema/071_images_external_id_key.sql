@@ -0,0 +1,48 @@
+-- +goose up
+-- Image external IDs are unique among the live images of a user. An index
+-- only sees its own row, so images carry the owner of their batch and
+-- whether it is in the trash, kept in step with the batch by triggers.
+ALTER TABLE images ADD COLUMN owner_id UUID;
+ALTER TABLE images ADD COLUMN batch_deleted BOOLEAN NOT NULL DEFAULT false;
+UPDATE images i SET owner_id = b.user_id, batch_deleted = b.deleted_at IS NOT NULL FROM batches b WHERE b.id = i.batch_id;
+ALTER TABLE images ALTER COLUMN owner_id SET NOT NULL;
+
+-- +goose StatementBegin
+CREATE FUNCTION images_set_owner() RETURNS trigger AS $$
+BEGIN
+    SELECT b.user_id, b.deleted_at IS NOT NULL INTO NEW.owner_id, NEW.batch_deleted FROM batches b WHERE b.id = NEW.batch_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION batches_sync_image_owner() RETURNS trigger AS $$
+BEGIN
+    UPDATE images SET owner_id = NEW.user_id, batch_deleted = NEW.deleted_at IS NOT NULL WHERE batch_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER images_owner BEFORE INSERT ON images FOR EACH ROW EXECUTE FUNCTION images_set_owner();
+CREATE TRIGGER batches_image_owner AFTER UPDATE OF user_id, deleted_at ON batches FOR EACH ROW
+    WHEN (OLD.user_id IS DISTINCT FROM NEW.user_id OR (OLD.deleted_at IS NULL) <> (NEW.deleted_at IS NULL))
+    EXECUTE FUNCTION batches_sync_image_owner();
+
+-- Duplicates written before the check moved into the index keep the
+-- external ID on their oldest image only.
+UPDATE images i SET external_id = NULL WHERE i.external_id IS NOT NULL AND i.deleted_at IS NULL AND NOT i.batch_deleted AND EXISTS (
+    SELECT 1 FROM images o WHERE o.owner_id = i.owner_id AND o.external_id = i.external_id AND o.deleted_at IS NULL AND NOT o.batch_deleted
+    AND (o.created_at, o.id) < (i.created_at, i.id)
+);
+CREATE UNIQUE INDEX images_owner_id_external_id_key ON images(owner_id, external_id) WHERE deleted_at IS NULL AND NOT batch_deleted;
+
+-- +goose down
+DROP INDEX IF EXISTS images_owner_id_external_id_key;
+DROP TRIGGER batches_image_owner ON batches;
+DROP TRIGGER images_owner ON images;
+DROP FUNCTION batches_sync_image_owner();
+DROP FUNCTION images_set_owner();
+ALTER TABLE images DROP COLUMN batch_deleted;
+ALTER TABLE images DROP COLUMN owner_id;
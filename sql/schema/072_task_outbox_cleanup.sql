@@ -0,0 +1,8 @@
+-- +goose up
+-- Cleanups of deleted objects go through the outbox as well, in the
+-- transaction that deletes their rows. They belong to no image.
+ALTER TABLE task_outbox ALTER COLUMN image_id DROP NOT NULL;
+
+-- +goose down
+DELETE FROM task_outbox WHERE image_id IS NULL;
+ALTER TABLE task_outbox ALTER COLUMN image_id SET NOT NULL;
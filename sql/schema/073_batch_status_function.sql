@@ -0,0 +1,19 @@
+-- +goose up
+-- The status of a batch derived from the counts of its images, as
+-- ImageStatusCounts.batchStatus does in the API, for queries that filter by it.
+-- +goose StatementBegin
+CREATE FUNCTION batch_status(waiting_since TIMESTAMP, image_count BIGINT, pending BIGINT, processing BIGINT, cancelled BIGINT, expired BIGINT, failed BIGINT) RETURNS TEXT LANGUAGE sql IMMUTABLE AS $$
+    SELECT CASE
+        WHEN waiting_since IS NOT NULL THEN 'waiting'
+        WHEN pending = image_count THEN 'pending'
+        WHEN pending + processing > 0 THEN 'processing'
+        WHEN cancelled > 0 THEN 'cancelled'
+        WHEN expired > 0 THEN 'expired'
+        WHEN failed > 0 THEN 'failed'
+        ELSE 'completed'
+    END
+$$;
+-- +goose StatementEnd
+
+-- +goose down
+DROP FUNCTION batch_status(TIMESTAMP, BIGINT, BIGINT, BIGINT, BIGINT, BIGINT, BIGINT);
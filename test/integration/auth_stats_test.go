@@ -0,0 +1,66 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/rickyroynardson/image-go/internal/admin"
+	"github.com/rickyroynardson/image-go/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthStatsRefreshFailures checks that refreshes without a token and with
+// an unknown one are counted under their own reasons, and that reasons which
+// did not occur are reported as zero, by auth-stats and by the metrics.
+func TestAuthStatsRefreshFailures(t *testing.T) {
+	env := setupEnvironment(t)
+	adminID, adminToken := registerUser(t, env, "stats-admin@example.com")
+	_, err := env.db.Exec("UPDATE users SET is_admin = true WHERE id = $1", adminID)
+	require.NoError(t, err)
+
+	res := doJSON(t, env.server.URL+"/api/v1/refresh", "", "")
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	for range 2 {
+		res = doJSON(t, env.server.URL+"/api/v1/refresh", "unknown-refresh-token", "")
+		res.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, env.server.URL+"/api/v1/admin/auth-stats", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var stats struct {
+		Data admin.AuthStatsResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&stats))
+
+	assert.Equal(t, 1, stats.Data.Totals[auth.ReasonRefreshMissingToken])
+	assert.Equal(t, 2, stats.Data.Totals[auth.ReasonRefreshInvalidToken])
+	assert.Contains(t, stats.Data.Totals, auth.ReasonLoginAccountLocked)
+	assert.Zero(t, stats.Data.Totals[auth.ReasonLoginAccountLocked])
+
+	req, err = http.NewRequest(http.MethodGet, env.server.URL+"/api/v1/admin/metrics", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/plain")
+	metrics, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(metrics), `imagego_refresh_failures_total{reason="refresh_missing_token"} 1`+"\n")
+	assert.Contains(t, string(metrics), `imagego_refresh_failures_total{reason="refresh_invalid_token"} 2`+"\n")
+	assert.Contains(t, string(metrics), `imagego_auth_failures_total{reason="login_invalid_credentials"} 0`+"\n")
+	assert.Contains(t, string(metrics), "imagego_login_lockouts_total 0\n")
+}
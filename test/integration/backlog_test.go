@@ -0,0 +1,70 @@
+//go:build integration
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateBatchQueueBacklog checks that batches are refused with 503 and
+// Retry-After while the task queue of the user's region holds
+// QUEUE_MAX_BACKLOG tasks, and accepted again below it. The region has no
+// workers, so its tasks stay queued.
+func TestCreateBatchQueueBacklog(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "backlog@example.com")
+
+	const region = "backlog"
+	env.cfg.Regions = map[string]utils.RegionStorage{region: {
+		S3Bucket:         env.cfg.S3Bucket,
+		S3CfDistribution: env.cfg.S3CfDistribution,
+		S3Client:         env.cfg.S3Client,
+	}}
+	_, err := env.db.Exec("UPDATE users SET region = $1 WHERE id = $2", region, userID)
+	require.NoError(t, err)
+	queue := utils.TaskQueue(region)
+	ch, _, err := pubsub.DeclareAndBind(env.conn, utils.ImageGoDirect, queue, queue, pubsub.QueueTypeDurable)
+	require.NoError(t, err)
+	t.Cleanup(func() { ch.Close() })
+	for range 2 {
+		require.NoError(t, pubsub.PublishJSON(ch, utils.ImageGoDirect, queue, batch.NewImageTask(uuid.New())))
+	}
+	require.Eventually(t, func() bool {
+		n, err := pubsub.QueueLength(ch, queue, pubsub.QueueTypeDurable)
+		return err == nil && n == 2
+	}, 10*time.Second, 100*time.Millisecond)
+
+	create := func() *http.Response {
+		t.Helper()
+		form, contentType := batchForm(t)
+		req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", form)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	env.cfg.QueueMaxBacklog = 2
+	res := create()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get("Retry-After"))
+	var batches int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM batches WHERE user_id = $1", userID).Scan(&batches))
+	assert.Zero(t, batches, "a refused batch is not stored")
+
+	env.cfg.QueueMaxBacklog = 3
+	res = create()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+}
@@ -0,0 +1,76 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListBatchesByStatus(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "batch-status@example.com")
+
+	seed := func(name string, waiting bool, statuses ...string) {
+		t.Helper()
+		var batchID string
+		require.NoError(t, env.db.QueryRow("INSERT INTO batches(user_id, name, waiting_since) VALUES ($1, $2, CASE WHEN $3::bool THEN NOW() END) RETURNING id", userID, name, waiting).Scan(&batchID))
+		for _, status := range statuses {
+			_, err := env.db.Exec("INSERT INTO images(batch_id, key, original_url, status) VALUES ($1, 'raw/photo.jpg', 'https://cdn.image-go.test/raw/photo.jpg', $2)", batchID, status)
+			require.NoError(t, err)
+		}
+	}
+	seed("waiting", true, "pending")
+	seed("pending", false, "pending", "pending")
+	seed("processing", false, "pending", "completed")
+	seed("cancelled", false, "cancelled", "failed", "completed")
+	seed("expired", false, "expired", "failed")
+	seed("failed", false, "failed", "completed")
+	seed("completed", false, "completed", "completed")
+	// Batches without images are not listed.
+	seed("empty", false)
+
+	list := func(status string) ([]string, int) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, env.server.URL+"/api/v1/batches?status="+status, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var body struct {
+			Data []struct {
+				Name   string `json:"name"`
+				Status string `json:"status"`
+			} `json:"data"`
+			Meta struct {
+				Total int `json:"total"`
+			} `json:"meta"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		var names []string
+		for _, batch := range body.Data {
+			assert.Equal(t, batch.Name, batch.Status, "status of batch %s", batch.Name)
+			names = append(names, batch.Name)
+		}
+		sort.Strings(names)
+		return names, body.Meta.Total
+	}
+
+	for _, status := range []string{"waiting", "pending", "processing", "cancelled", "expired", "failed", "completed"} {
+		t.Run(status, func(t *testing.T) {
+			names, total := list(status)
+			assert.Equal(t, []string{status}, names)
+			assert.Equal(t, 1, total)
+		})
+	}
+	names, total := list("")
+	assert.Len(t, names, 7)
+	assert.Equal(t, 7, total)
+}
@@ -0,0 +1,63 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchUpdate(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "update@example.com")
+
+	var batchID, imageID string
+	err := env.db.QueryRow("INSERT INTO batches(user_id, watermark_text) VALUES ($1, 'Studio') RETURNING id", userID).Scan(&batchID)
+	require.NoError(t, err)
+	err = env.db.QueryRow("INSERT INTO images(batch_id, key, original_url) VALUES ($1, 'raw/photo.jpg', 'https://cdn.image-go.test/raw/photo.jpg') RETURNING id", batchID).Scan(&imageID)
+	require.NoError(t, err)
+	patch := func(body string) int {
+		req, err := http.NewRequest(http.MethodPatch, env.server.URL+"/api/v1/batches/"+batchID, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	opacity := func() int {
+		var opacity int
+		require.NoError(t, env.db.QueryRow("SELECT watermark_opacity FROM batches WHERE id = $1", batchID).Scan(&opacity))
+		return opacity
+	}
+
+	assert.Equal(t, http.StatusOK, patch(`{"watermark_opacity": 40}`))
+	assert.Equal(t, 40, opacity())
+
+	_, err = env.db.Exec("UPDATE images SET status = 'processing' WHERE id = $1", imageID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, patch(`{"watermark_opacity": 90}`))
+	assert.Equal(t, 40, opacity())
+	// Renaming is always allowed.
+	assert.Equal(t, http.StatusOK, patch(`{"name": "renamed"}`))
+
+	// The update itself refuses, for a worker that claims an image after the
+	// handler looked.
+	_, err = env.dbQueries.UpdateBatchByID(context.Background(), database.UpdateBatchByIDParams{
+		ID:               uuid.MustParse(batchID),
+		UserID:           uuid.MustParse(userID),
+		WatermarkOpacity: sql.NullInt32{Int32: 90, Valid: true},
+		ChangesWatermark: true,
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, 40, opacity())
+}
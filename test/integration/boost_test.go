@@ -0,0 +1,59 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPriorityBoost grants a boost with a starts_at outside UTC and checks it
+// is stored in UTC and active right away, and that batches of the boosted
+// user, whose tasks go to the priority queue, are processed.
+func TestPriorityBoost(t *testing.T) {
+	env := setupEnvironment(t)
+	adminID, adminToken := registerUser(t, env, "boost-admin@example.com")
+	_, err := env.db.Exec("UPDATE users SET is_admin = true WHERE id = $1", adminID)
+	require.NoError(t, err)
+	userID, accessToken := registerUser(t, env, "boosted@example.com")
+
+	startsAt := time.Now().Add(-time.Hour).In(time.FixedZone("WIB", 7*60*60)).Truncate(time.Second)
+	body := fmt.Sprintf(`{"duration_minutes":180,"starts_at":%q,"concurrency_multiplier":2,"priority":true,"reason":"deadline"}`, startsAt.Format(time.RFC3339))
+	res := doJSON(t, env.server.URL+"/api/v1/admin/users/"+userID+"/boosts", adminToken, body)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var created struct {
+		Data admin.UserBoostResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	assert.True(t, created.Data.Active)
+	assert.True(t, created.Data.Priority)
+
+	var stored string
+	require.NoError(t, env.db.QueryRow("SELECT to_char(starts_at, 'YYYY-MM-DD HH24:MI:SS') FROM user_boosts WHERE id = $1", created.Data.ID).Scan(&stored))
+	assert.Equal(t, startsAt.UTC().Format(time.DateTime), stored)
+
+	form, contentType := batchForm(t)
+	req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", form)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	upload, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	upload.Body.Close()
+	require.Equal(t, http.StatusCreated, upload.StatusCode)
+
+	batchID := latestBatchID(t, env)
+	require.Eventually(t, func() bool {
+		var status string
+		err := env.db.QueryRow("SELECT status FROM images WHERE batch_id = $1", batchID).Scan(&status)
+		return err == nil && status == "completed"
+	}, 60*time.Second, 500*time.Millisecond)
+}
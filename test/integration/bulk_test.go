@@ -0,0 +1,84 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBulkImages checks that bulk retries and deletes go through the task
+// outbox: retried images are processed and the outbox is emptied once their
+// tasks and cleanups are published.
+func TestBulkImages(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "bulk@example.com")
+
+	photo := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 48; y++ {
+			photo.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 32, 255})
+		}
+	}
+	var photoBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photoBuf, photo, nil))
+	_, err := env.cfg.S3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(env.cfg.S3Bucket),
+		Key:         aws.String("raw/bulk.jpg"),
+		Body:        bytes.NewReader(photoBuf.Bytes()),
+		ContentType: aws.String("image/jpeg"),
+	})
+	require.NoError(t, err)
+
+	seed := func(status string) string {
+		t.Helper()
+		var imageID string
+		err := env.db.QueryRow("WITH b AS (INSERT INTO batches(user_id) VALUES ($1) RETURNING id) INSERT INTO images(batch_id, key, original_url, status) SELECT id, 'raw/bulk.jpg', 'https://cdn.image-go.test/raw/bulk.jpg', $2 FROM b RETURNING id", userID, status).Scan(&imageID)
+		require.NoError(t, err)
+		return imageID
+	}
+	send := func(method, path, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, env.server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	outboxEmpty := func() bool {
+		var count int
+		err := env.db.QueryRow("SELECT COUNT(*) FROM task_outbox").Scan(&count)
+		return err == nil && count == 0
+	}
+
+	failed := seed("failed")
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/images/retry", `{"image_ids":["`+failed+`"]}`))
+	require.Eventually(t, func() bool {
+		var status string
+		err := env.db.QueryRow("SELECT status FROM images WHERE id = $1", failed).Scan(&status)
+		return err == nil && status == "completed"
+	}, 60*time.Second, 500*time.Millisecond)
+	assert.Eventually(t, outboxEmpty, 10*time.Second, 100*time.Millisecond)
+
+	completed := seed("completed")
+	require.Equal(t, http.StatusOK, send(http.MethodDelete, "/api/v1/images", `{"image_ids":["`+completed+`","`+failed+`"]}`))
+	var live int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM images WHERE id IN ($1, $2) AND deleted_at IS NULL", completed, failed).Scan(&live))
+	assert.Zero(t, live)
+	assert.Eventually(t, outboxEmpty, 10*time.Second, 100*time.Millisecond, "the cleanup is published")
+}
@@ -0,0 +1,63 @@
+//go:build integration
+
+package integration
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCancelBatch checks that cancelling a batch marks only its live pending
+// images as cancelled, clears its wait, records the event, and that a batch
+// with nothing left to cancel is refused.
+func TestCancelBatch(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "cancel@example.com")
+	_, otherToken := registerUser(t, env, "cancel-other@example.com")
+
+	var batchID string
+	require.NoError(t, env.db.QueryRow("INSERT INTO batches(user_id, waiting_since) VALUES ($1, NOW()) RETURNING id", userID).Scan(&batchID))
+	seed := func(status string, deleted bool) string {
+		t.Helper()
+		var imageID string
+		err := env.db.QueryRow(`INSERT INTO images(batch_id, key, original_url, status, deleted_at)
+			VALUES ($1, 'raw/cancel.jpg', 'https://cdn.image-go.test/raw/cancel.jpg', $2, CASE WHEN $3::bool THEN NOW() END) RETURNING id`,
+			batchID, status, deleted).Scan(&imageID)
+		require.NoError(t, err)
+		return imageID
+	}
+	images := map[string]string{
+		seed("pending", false):    "cancelled",
+		seed("pending", false):    "cancelled",
+		seed("processing", false): "processing",
+		seed("completed", false):  "completed",
+		seed("pending", true):     "pending",
+	}
+	cancel := func(token string) int {
+		t.Helper()
+		res := doJSON(t, env.server.URL+"/api/v1/batches/"+batchID+"/cancel", token, "")
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Equal(t, http.StatusNotFound, cancel(otherToken))
+	require.Equal(t, http.StatusOK, cancel(accessToken))
+
+	for imageID, want := range images {
+		var status string
+		require.NoError(t, env.db.QueryRow("SELECT status FROM images WHERE id = $1", imageID).Scan(&status))
+		assert.Equal(t, want, status, imageID)
+	}
+	var waitingSince sql.NullTime
+	require.NoError(t, env.db.QueryRow("SELECT waiting_since FROM batches WHERE id = $1", batchID).Scan(&waitingSince))
+	assert.False(t, waitingSince.Valid, "a cancelled batch stops waiting")
+	var events int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM batch_events WHERE batch_id = $1 AND type = 'cancelled'", batchID).Scan(&events))
+	assert.Equal(t, 1, events)
+
+	assert.Equal(t, http.StatusConflict, cancel(accessToken), "no pending images are left")
+}
@@ -0,0 +1,132 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchComments checks commenting on a batch and one of its images,
+// replying, deleting a comment whose reply is then promoted, and that the
+// activity feed lists comments and lifecycle events newest first.
+func TestBatchComments(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "comments@example.com")
+	_, otherToken := registerUser(t, env, "comments-other@example.com")
+
+	seedBatch := func() string {
+		t.Helper()
+		var batchID string
+		require.NoError(t, env.db.QueryRow("INSERT INTO batches(user_id) VALUES ($1) RETURNING id", userID).Scan(&batchID))
+		_, err := env.db.Exec("INSERT INTO batch_events(batch_id, user_id, type) VALUES ($1, $2, 'created')", batchID, userID)
+		require.NoError(t, err)
+		return batchID
+	}
+	batchID, otherBatchID := seedBatch(), seedBatch()
+	var imageID string
+	require.NoError(t, env.db.QueryRow("INSERT INTO images(batch_id, key, original_url) VALUES ($1, 'raw/comment.jpg', 'https://cdn.image-go.test/raw/comment.jpg') RETURNING id", otherBatchID).Scan(&imageID))
+
+	send := func(method, path, token, body string, out any) int {
+		t.Helper()
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req, err := http.NewRequest(method, env.server.URL+"/api/v1/batches/"+path, reader)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		if out != nil && res.StatusCode < 300 {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+		}
+		return res.StatusCode
+	}
+	comment := func(batchID, body string) (batch.CommentResponse, int) {
+		t.Helper()
+		var created struct {
+			Data batch.CommentResponse `json:"data"`
+		}
+		status := send(http.MethodPost, batchID+"/comments", accessToken, body, &created)
+		return created.Data, status
+	}
+
+	root, status := comment(batchID, `{"body":"looks good"}`)
+	require.Equal(t, http.StatusCreated, status)
+	reply, status := comment(batchID, `{"body":"agreed","parent_id":"`+root.ID.String()+`"}`)
+	require.Equal(t, http.StatusCreated, status)
+	require.NotNil(t, reply.ParentID)
+	assert.Equal(t, root.ID, *reply.ParentID)
+
+	t.Run("rejects references outside the batch", func(t *testing.T) {
+		_, status := comment(otherBatchID, `{"body":"wrong thread","parent_id":"`+root.ID.String()+`"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		_, status = comment(batchID, `{"body":"wrong image","image_id":"`+imageID+`"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		_, status = comment(batchID, `{"body":"missing parent","parent_id":"`+uuid.NewString()+`"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("image comment", func(t *testing.T) {
+		created, status := comment(otherBatchID, `{"body":"crop this","image_id":"`+imageID+`"}`)
+		require.Equal(t, http.StatusCreated, status)
+		require.NotNil(t, created.ImageID)
+		assert.Equal(t, imageID, created.ImageID.String())
+	})
+
+	t.Run("other users cannot see or delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, batchID+"/comments", otherToken, "", nil))
+		assert.Equal(t, http.StatusNotFound, send(http.MethodPost, batchID+"/comments", otherToken, `{"body":"hi"}`, nil))
+		assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, batchID+"/comments/"+root.ID.String(), otherToken, "", nil))
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, batchID+"/activity", otherToken, "", nil))
+	})
+
+	var threads struct {
+		Data []batch.CommentResponse `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, send(http.MethodGet, batchID+"/comments", accessToken, "", &threads))
+	require.Len(t, threads.Data, 1)
+	assert.Equal(t, root.ID, threads.Data[0].ID)
+	require.Len(t, threads.Data[0].Replies, 1)
+	assert.Equal(t, reply.ID, threads.Data[0].Replies[0].ID)
+
+	var activity struct {
+		Data []batch.ActivityResponse `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, send(http.MethodGet, batchID+"/activity", accessToken, "", &activity))
+	var types []string
+	for _, a := range activity.Data {
+		types = append(types, a.Type)
+	}
+	assert.Equal(t, []string{"comment", "comment", "created"}, types)
+	assert.Equal(t, reply.ID, activity.Data[0].ID)
+	assert.Equal(t, "agreed", activity.Data[0].Body)
+
+	require.Equal(t, http.StatusOK, send(http.MethodDelete, batchID+"/comments/"+root.ID.String(), accessToken, "", nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, batchID+"/comments/"+root.ID.String(), accessToken, "", nil))
+
+	var promoted struct {
+		Data []batch.CommentResponse `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, send(http.MethodGet, batchID+"/comments", accessToken, "", &promoted))
+	require.Len(t, promoted.Data, 1, "the reply of a deleted comment is promoted")
+	assert.Equal(t, reply.ID, promoted.Data[0].ID)
+	assert.Empty(t, promoted.Data[0].Replies)
+
+	var remaining struct {
+		Data []batch.ActivityResponse `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, send(http.MethodGet, batchID+"/activity", accessToken, "", &remaining))
+	assert.Len(t, remaining.Data, 2)
+}
@@ -0,0 +1,86 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompleteImage checks that a processed image is completed with all its
+// metadata by the single CompleteImageByID write, and that the write leaves
+// deleted images alone.
+func TestCompleteImage(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "complete@example.com")
+
+	t.Run("pipeline stores metadata with the status", func(t *testing.T) {
+		form, contentType := batchForm(t)
+		req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", form)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		batchID := latestBatchID(t, env)
+
+		require.Eventually(t, func() bool {
+			var status string
+			err := env.db.QueryRow("SELECT status FROM images WHERE batch_id = $1", batchID).Scan(&status)
+			return err == nil && status == "completed"
+		}, 60*time.Second, 500*time.Millisecond)
+
+		var (
+			processedURL, processedFormat, originalFormat sql.NullString
+			width, height, originalWidth, originalHeight  sql.NullInt32
+			processedSize, originalSize                   sql.NullInt64
+			stepTimings                                   []byte
+		)
+		err = env.db.QueryRow(`SELECT processed_url, processed_format, original_format, width, height, original_width, original_height, processed_size, original_size, step_timings
+			FROM images WHERE batch_id = $1`, batchID).Scan(&processedURL, &processedFormat, &originalFormat, &width, &height, &originalWidth, &originalHeight, &processedSize, &originalSize, &stepTimings)
+		require.NoError(t, err)
+		assert.True(t, processedURL.Valid)
+		assert.Equal(t, "image/jpeg", processedFormat.String)
+		assert.Equal(t, "image/jpeg", originalFormat.String)
+		assert.Equal(t, sql.NullInt32{Int32: 640, Valid: true}, originalWidth)
+		assert.Equal(t, sql.NullInt32{Int32: 480, Valid: true}, originalHeight)
+		assert.True(t, width.Valid && height.Valid)
+		assert.Positive(t, processedSize.Int64)
+		assert.Positive(t, originalSize.Int64)
+		var timings []json.RawMessage
+		require.NoError(t, json.Unmarshal(stepTimings, &timings))
+		assert.NotEmpty(t, timings)
+	})
+
+	t.Run("deleted images are not completed", func(t *testing.T) {
+		var imageID uuid.UUID
+		err := env.db.QueryRow(`WITH b AS (INSERT INTO batches(user_id) VALUES ($1) RETURNING id)
+			INSERT INTO images(batch_id, key, original_url, status, deleted_at) SELECT id, 'raw/complete.jpg', 'https://cdn.image-go.test/raw/complete.jpg', 'processing', NOW() FROM b
+			RETURNING id`, userID).Scan(&imageID)
+		require.NoError(t, err)
+
+		err = env.dbQueries.CompleteImageByID(context.Background(), database.CompleteImageByIDParams{
+			ID:           imageID,
+			ProcessedUrl: sql.NullString{String: "https://cdn.image-go.test/processed/complete.jpg", Valid: true},
+			StepTimings:  json.RawMessage("[]"),
+		})
+		require.NoError(t, err)
+
+		var status string
+		var processedURL sql.NullString
+		require.NoError(t, env.db.QueryRow("SELECT status, processed_url FROM images WHERE id = $1", imageID).Scan(&status, &processedURL))
+		assert.Equal(t, "processing", status)
+		assert.False(t, processedURL.Valid)
+	})
+}
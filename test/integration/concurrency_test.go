@@ -0,0 +1,57 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchConcurrencyLimit checks that tasks deferred because their batch is
+// at its max_concurrency come back from the delay queue and finish.
+func TestBatchConcurrencyLimit(t *testing.T) {
+	env := setupEnvironment(t)
+	_, accessToken := registerUser(t, env, "concurrency@example.com")
+
+	photo := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	for x := 0; x < 320; x++ {
+		for y := 0; y < 240; y++ {
+			photo.Set(x, y, color.RGBA{uint8(x % 256), uint8(y % 256), 64, 255})
+		}
+	}
+	var photoBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photoBuf, photo, nil))
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	require.NoError(t, w.WriteField("name", "one at a time"))
+	require.NoError(t, w.WriteField("max_concurrency", "1"))
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg", "d.jpg"} {
+		writeFile(t, w, "files", name, "image/jpeg", photoBuf.Bytes())
+	}
+	require.NoError(t, w.Close())
+
+	req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	batchID := latestBatchID(t, env)
+	require.Eventually(t, func() bool {
+		var completed int
+		err := env.db.QueryRow("SELECT COUNT(*) FROM images WHERE batch_id = $1 AND status = 'completed'", batchID).Scan(&completed)
+		return err == nil && completed == 4
+	}, 90*time.Second, 500*time.Millisecond)
+}
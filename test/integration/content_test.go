@@ -0,0 +1,86 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamOriginal checks that originals are streamed through the API with
+// their validators, that ranges are answered with 206 and conditional requests
+// with 304, and that unprocessed images and other users' images are not found.
+func TestStreamOriginal(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "content@example.com")
+	_, otherToken := registerUser(t, env, "content-other@example.com")
+
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	_, err := env.cfg.S3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(env.cfg.S3Bucket),
+		Key:         aws.String("raw/content.jpg"),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("image/jpeg"),
+	})
+	require.NoError(t, err)
+	var imageID string
+	err = env.db.QueryRow(`WITH b AS (INSERT INTO batches(user_id) VALUES ($1) RETURNING id)
+		INSERT INTO images(batch_id, key, original_url) SELECT id, 'raw/content.jpg', 'https://cdn.image-go.test/raw/content.jpg' FROM b
+		RETURNING id`, userID).Scan(&imageID)
+	require.NoError(t, err)
+
+	get := func(path, token string, header map[string]string) (*http.Response, []byte) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, env.server.URL+"/api/v1/images/"+imageID+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	res, body := get("/original", accessToken, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, data, body)
+	assert.Equal(t, "image/jpeg", res.Header.Get("Content-Type"))
+	assert.Equal(t, "bytes", res.Header.Get("Accept-Ranges"))
+	assert.Equal(t, "private, max-age=31536000, immutable", res.Header.Get("Cache-Control"))
+	etag := res.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, res.Header.Get("Last-Modified"))
+
+	res, body = get("/original", accessToken, map[string]string{"Range": "bytes=10-19"})
+	assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	assert.Equal(t, data[10:20], body)
+	assert.Equal(t, "bytes 10-19/1000", res.Header.Get("Content-Range"))
+
+	res, _ = get("/original", accessToken, map[string]string{"Range": "bytes=5000-"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, res.StatusCode)
+
+	res, body = get("/original", accessToken, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+	assert.Empty(t, body)
+
+	res, _ = get("/original", accessToken, map[string]string{"If-None-Match": `"stale"`})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, _ = get("/content", accessToken, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "the image has not been processed")
+
+	res, _ = get("/original", otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
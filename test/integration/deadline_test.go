@@ -0,0 +1,71 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	imagesvc "github.com/rickyroynardson/image-go/internal/image"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchDeadline(t *testing.T) {
+	env := setupEnvironment(t)
+
+	var userID string
+	err := env.db.QueryRow("INSERT INTO users(email, password_hash) VALUES ('deadline@example.com', 'unused') RETURNING id").Scan(&userID)
+	require.NoError(t, err)
+	// overdueBatch inserts a batch whose deadline passed with one pending
+	// image, which a worker has not picked up yet.
+	overdueBatch := func(t *testing.T) (batchID, imageID string) {
+		t.Helper()
+		err := env.db.QueryRow("INSERT INTO batches(user_id, deadline) VALUES ($1, NOW() - INTERVAL '1 minute') RETURNING id", userID).Scan(&batchID)
+		require.NoError(t, err)
+		err = env.db.QueryRow("INSERT INTO images(batch_id, key, original_url) VALUES ($1, 'raw/photo.jpg', 'https://cdn.image-go.test/raw/photo.jpg') RETURNING id", batchID).Scan(&imageID)
+		require.NoError(t, err)
+		return batchID, imageID
+	}
+	imageStatus := func(imageID string) string {
+		var status string
+		require.NoError(t, env.db.QueryRow("SELECT status FROM images WHERE id = $1", imageID).Scan(&status))
+		return status
+	}
+
+	t.Run("worker skips the task", func(t *testing.T) {
+		_, imageID := overdueBatch(t)
+		ch, err := env.cfg.RabbitMQ.Get()
+		require.NoError(t, err)
+		defer env.cfg.RabbitMQ.Put(ch)
+		require.NoError(t, pubsub.PublishJSON(ch, utils.ImageGoDirect, utils.TaskQueue(""), batch.NewImageTask(uuid.MustParse(imageID))))
+
+		require.Eventually(t, func() bool { return imageStatus(imageID) == "expired" }, 30*time.Second, 100*time.Millisecond)
+		var attempts int
+		require.NoError(t, env.db.QueryRow("SELECT attempts FROM images WHERE id = $1", imageID).Scan(&attempts))
+		assert.Zero(t, attempts, "the image must not be claimed")
+	})
+
+	t.Run("poller expires pending images", func(t *testing.T) {
+		batchID, imageID := overdueBatch(t)
+		// A batch with time left is not touched.
+		var futureImageID string
+		err := env.db.QueryRow("WITH b AS (INSERT INTO batches(user_id, deadline) VALUES ($1, NOW() + INTERVAL '1 hour') RETURNING id) INSERT INTO images(batch_id, key, original_url) SELECT id, 'raw/later.jpg', 'https://cdn.image-go.test/raw/later.jpg' FROM b RETURNING id", userID).Scan(&futureImageID)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go imagesvc.PollOverdueBatches(ctx, env.db, env.dbQueries, env.cfg, 50*time.Millisecond)
+
+		require.Eventually(t, func() bool { return imageStatus(imageID) == "expired" }, 10*time.Second, 50*time.Millisecond)
+		var events int
+		require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM batch_events WHERE batch_id = $1 AND type = 'deadline_passed'", batchID).Scan(&events))
+		assert.Equal(t, 1, events)
+		assert.Equal(t, "pending", imageStatus(futureImageID))
+	})
+}
@@ -0,0 +1,6 @@
+// Package integration holds end-to-end tests that run the API and worker in-process against
+// dockerized Postgres, RabbitMQ and MinIO. They need a Docker daemon and only build with the
+// integration tag:
+//
+//	go test -tags integration ./test/integration/...
+package integration
@@ -0,0 +1,93 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	imagesvc "github.com/rickyroynardson/image-go/internal/image"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExpireBatches checks that a batch whose TTL ran out is removed with its
+// images and objects by the expiry poller, and that a batch with images still
+// processing waits for a later pass.
+func TestExpireBatches(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "expire@example.com")
+
+	cleanupQueue := utils.CleanupQueue("")
+	err := pubsub.SubscribeJSON(env.conn, utils.ImageGoDirect, cleanupQueue, cleanupQueue, pubsub.QueueTypeDurable, imagesvc.CleanupObjects(env.cfg))
+	require.NoError(t, err)
+
+	form, contentType := batchForm(t)
+	req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", form)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	batchID := latestBatchID(t, env)
+	require.Eventually(t, func() bool {
+		var status string
+		err := env.db.QueryRow("SELECT status FROM images WHERE batch_id = $1", batchID).Scan(&status)
+		return err == nil && status == "completed"
+	}, 60*time.Second, 500*time.Millisecond)
+
+	var originalKey, processedURL string
+	require.NoError(t, env.db.QueryRow("SELECT key, processed_url FROM images WHERE batch_id = $1", batchID).Scan(&originalKey, &processedURL))
+	keys := []string{originalKey, utils.GetObjectKey(env.cfg.S3CfDistribution, processedURL)}
+
+	var busyID string
+	err = env.db.QueryRow(`WITH b AS (INSERT INTO batches(user_id, expires_at) VALUES ($1, NOW() - INTERVAL '1 minute') RETURNING id)
+		INSERT INTO images(batch_id, key, original_url, status) SELECT id, 'raw/expire.jpg', 'https://cdn.image-go.test/raw/expire.jpg', 'processing' FROM b
+		RETURNING batch_id`, userID).Scan(&busyID)
+	require.NoError(t, err)
+	_, err = env.db.Exec("UPDATE batches SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1", batchID)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go batch.PollExpiredBatches(ctx, env.db, env.dbQueries, env.cfg, 100*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		var purgedAt sql.NullTime
+		err := env.db.QueryRow("SELECT purged_at FROM batches WHERE id = $1", batchID).Scan(&purgedAt)
+		return err == nil && purgedAt.Valid
+	}, 10*time.Second, 100*time.Millisecond)
+
+	var liveImages, events int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM images WHERE batch_id = $1 AND deleted_at IS NULL", batchID).Scan(&liveImages))
+	assert.Zero(t, liveImages)
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM batch_events WHERE batch_id = $1 AND type = 'expired'", batchID).Scan(&events))
+	assert.Equal(t, 1, events)
+
+	for _, key := range keys {
+		require.Eventually(t, func() bool {
+			_, err := env.cfg.S3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+				Bucket: aws.String(env.cfg.S3Bucket),
+				Key:    aws.String(key),
+			})
+			return err != nil
+		}, 10*time.Second, 100*time.Millisecond, "%s is removed", key)
+	}
+	var outbox int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM task_outbox").Scan(&outbox))
+	assert.Zero(t, outbox, "the published cleanup leaves the outbox")
+
+	var busyPurgedAt sql.NullTime
+	require.NoError(t, env.db.QueryRow("SELECT purged_at FROM batches WHERE id = $1", busyID).Scan(&busyPurgedAt))
+	assert.False(t, busyPurgedAt.Valid, "a batch with images processing waits")
+}
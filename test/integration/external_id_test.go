@@ -0,0 +1,70 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestImageExternalIDsUnique checks that the index keeps image external IDs
+// unique among the live images of a user, follows batches into and out of the
+// trash, and refuses a transfer that would give the recipient a duplicate.
+func TestImageExternalIDsUnique(t *testing.T) {
+	env := setupEnvironment(t)
+	senderID, senderToken := registerUser(t, env, "external-sender@example.com")
+	recipientID, recipientToken := registerUser(t, env, "external-recipient@example.com")
+
+	seed := func(userID, externalID string) (string, error) {
+		var batchID string
+		err := env.db.QueryRow("WITH b AS (INSERT INTO batches(user_id) VALUES ($1) RETURNING id) INSERT INTO images(batch_id, key, original_url, external_id, status) SELECT id, 'raw/photo.jpg', 'https://cdn.image-go.test/raw/photo.jpg', $2, 'completed' FROM b RETURNING batch_id", userID, externalID).Scan(&batchID)
+		return batchID, err
+	}
+	send := func(method, path, token string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, env.server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	first, err := seed(senderID, "sku-1")
+	require.NoError(t, err)
+	_, err = seed(senderID, "sku-1")
+	require.Error(t, err)
+	assert.Equal(t, "images_owner_id_external_id_key", utils.ConstraintName(err))
+	_, err = seed(recipientID, "sku-1")
+	require.NoError(t, err, "other users may use the same external ID")
+
+	require.Equal(t, http.StatusOK, send(http.MethodDelete, "/api/v1/batches/"+first, senderToken))
+	_, err = seed(senderID, "sku-1")
+	require.NoError(t, err, "images in the trash do not hold their external ID")
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/api/v1/batches/"+first+"/restore", senderToken))
+
+	offered, err := seed(senderID, "sku-2")
+	require.NoError(t, err)
+	_, err = seed(recipientID, "sku-2")
+	require.NoError(t, err)
+	res := doJSON(t, env.server.URL+"/api/v1/batches/"+offered+"/transfer", senderToken, `{"email":"external-recipient@example.com"}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var transfer struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&transfer))
+
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/api/v1/transfers/"+transfer.Data.ID+"/accept", recipientToken))
+	var ownerID string
+	require.NoError(t, env.db.QueryRow("SELECT user_id FROM batches WHERE id = $1", offered).Scan(&ownerID))
+	assert.Equal(t, senderID, ownerID, "the batch stays with the sender")
+}
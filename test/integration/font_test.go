@@ -0,0 +1,75 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestDeleteFont(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "font@example.com")
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	writeFile(t, w, "file", "regular.ttf", "font/ttf", goregular.TTF)
+	require.NoError(t, w.Close())
+	req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/fonts", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var uploaded struct {
+		Data struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&uploaded))
+	var key string
+	require.NoError(t, env.db.QueryRow("SELECT key FROM fonts WHERE id = $1", uploaded.Data.ID).Scan(&key))
+
+	var imageID uuid.UUID
+	err = env.db.QueryRow("WITH b AS (INSERT INTO batches(user_id, watermark_text, watermark_font_id) VALUES ($1, 'Studio', $2) RETURNING id) INSERT INTO images(batch_id, key, original_url) SELECT id, 'raw/photo.jpg', 'https://cdn.image-go.test/raw/photo.jpg' FROM b RETURNING id", userID, uploaded.Data.ID).Scan(&imageID)
+	require.NoError(t, err)
+	img, err := env.dbQueries.GetImageByID(context.Background(), imageID)
+	require.NoError(t, err)
+	assert.Equal(t, key, img.WatermarkFontKey.String)
+
+	remove := func(id uuid.UUID) int {
+		req, err := http.NewRequest(http.MethodDelete, env.server.URL+"/api/v1/fonts/"+id.String(), nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	require.Equal(t, http.StatusOK, remove(uploaded.Data.ID))
+
+	_, err = env.cfg.S3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(env.cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+	assert.Error(t, err, "the font file is removed")
+	img, err = env.dbQueries.GetImageByID(context.Background(), imageID)
+	require.NoError(t, err)
+	assert.False(t, img.WatermarkFontKey.Valid, "the batch falls back to the default font")
+
+	assert.Equal(t, http.StatusNotFound, remove(uploaded.Data.ID))
+	assert.Equal(t, http.StatusNotFound, remove(uuid.New()))
+}
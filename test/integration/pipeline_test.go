@@ -0,0 +1,345 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	_ "github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rickyroynardson/image-go/internal/auth"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/rickyroynardson/image-go/internal/database"
+	imagesvc "github.com/rickyroynardson/image-go/internal/image"
+	"github.com/rickyroynardson/image-go/internal/notify"
+	"github.com/rickyroynardson/image-go/internal/pubsub"
+	"github.com/rickyroynardson/image-go/internal/router"
+	"github.com/rickyroynardson/image-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/minio"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/modules/rabbitmq"
+)
+
+const (
+	testBucket         = "image-go-test"
+	testCfDistribution = "cdn.image-go.test"
+	testJwtSecret      = "integration-test-secret"
+)
+
+type environment struct {
+	db        *sql.DB
+	dbQueries *database.Queries
+	cfg       *utils.Config
+	conn      *amqp.Connection
+	server    *httptest.Server
+}
+
+func TestImagePipeline(t *testing.T) {
+	env := setupEnvironment(t)
+
+	_, accessToken := registerUser(t, env, "pipeline@example.com")
+
+	// Statuses after the first are collected from the events the worker
+	// publishes, in order, since polling can miss the short processing step.
+	var statusMu sync.Mutex
+	var seenStatuses []batch.ImageStatus
+	seeStatus := func(status batch.ImageStatus) {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		if len(seenStatuses) == 0 || seenStatuses[len(seenStatuses)-1] != status {
+			seenStatuses = append(seenStatuses, status)
+		}
+	}
+	statusQueue := utils.ImageGoStatus + ".pipeline"
+	err := pubsub.SubscribeJSON(env.conn, utils.ImageGoDirect, statusQueue, utils.ImageGoStatus, pubsub.QueueTypeTransient, func(e batch.ImageStatusEvent) pubsub.AckType {
+		seeStatus(e.Status)
+		return pubsub.Ack
+	})
+	require.NoError(t, err)
+
+	body, contentType := batchForm(t)
+	req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var created struct {
+		Data batch.CreateBatchResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	res.Body.Close()
+	require.Equal(t, "pending", created.Data.Status)
+	seeStatus(batch.ImageStatusPending)
+	require.Len(t, created.Data.Images, 1)
+	require.NotNil(t, created.Data.Images[0].Queued)
+	assert.True(t, *created.Data.Images[0].Queued, "the task is confirmed before the response")
+	var outboxTasks int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM task_outbox").Scan(&outboxTasks))
+	assert.Zero(t, outboxTasks, "published tasks leave the outbox")
+
+	batchID := latestBatchID(t, env)
+	var processed batch.ImageResponse
+	require.Eventually(t, func() bool {
+		req, _ := http.NewRequest(http.MethodGet, env.server.URL+"/api/v1/batches/"+batchID, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		defer res.Body.Close()
+		var detail struct {
+			Data batch.BatchResponse `json:"data"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&detail); err != nil || len(detail.Data.Images) != 1 {
+			return false
+		}
+		processed = detail.Data.Images[0]
+		return processed.Status == batch.ImageStatusCompleted || processed.Status == batch.ImageStatusFailed
+	}, 60*time.Second, 250*time.Millisecond)
+
+	assert.Equal(t, batch.ImageStatusCompleted, processed.Status)
+	assert.NotEmpty(t, processed.ProcessedURL)
+	assert.Eventually(t, func() bool {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		return len(seenStatuses) > 0 && seenStatuses[len(seenStatuses)-1] == batch.ImageStatusCompleted
+	}, 10*time.Second, 100*time.Millisecond, "the completed event arrives")
+	statusMu.Lock()
+	assert.Equal(t, []batch.ImageStatus{batch.ImageStatusPending, batch.ImageStatusProcessing, batch.ImageStatusCompleted}, seenStatuses)
+	statusMu.Unlock()
+
+	processedKey := utils.GetObjectKey(testCfDistribution, processed.ProcessedURL)
+	require.NotEmpty(t, processedKey)
+	obj, err := env.cfg.S3Client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(testBucket),
+		Key:    aws.String(processedKey),
+	})
+	require.NoError(t, err)
+	defer obj.Body.Close()
+	_, format, err := image.Decode(obj.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+}
+
+func setupEnvironment(t *testing.T) *environment {
+	t.Helper()
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("imagego"),
+		postgres.WithUsername("imagego"),
+		postgres.WithPassword("imagego"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, pgContainer)
+	require.NoError(t, err)
+	postgresURL, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	rabbitContainer, err := rabbitmq.Run(ctx, "rabbitmq:3.13-management-alpine")
+	testcontainers.CleanupContainer(t, rabbitContainer)
+	require.NoError(t, err)
+	rabbitURL, err := rabbitContainer.AmqpURL(ctx)
+	require.NoError(t, err)
+
+	minioContainer, err := minio.Run(ctx, "minio/minio:RELEASE.2024-01-16T16-07-38Z",
+		minio.WithUsername("minioadmin"),
+		minio.WithPassword("minioadmin"),
+	)
+	testcontainers.CleanupContainer(t, minioContainer)
+	require.NoError(t, err)
+	minioEndpoint, err := minioContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	db, err := sql.Open("postgres", postgresURL)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	applyMigrations(t, db)
+	dbQueries := database.New(db)
+
+	s3Client := s3.New(s3.Options{
+		BaseEndpoint: aws.String("http://" + minioEndpoint),
+		Region:       "us-east-1",
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("minioadmin", "minioadmin", ""),
+	})
+	_, err = s3Client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(testBucket)})
+	require.NoError(t, err)
+
+	conn, err := amqp.Dial(rabbitURL)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	require.NoError(t, ch.ExchangeDeclare(utils.ImageGoDirect, "direct", true, false, false, false, nil))
+	ch.Close()
+	channels := pubsub.NewChannelPool(conn, 4)
+	t.Cleanup(channels.Close)
+
+	cfg := &utils.Config{
+		JwtSecret:        testJwtSecret,
+		S3Bucket:         testBucket,
+		S3CfDistribution: testCfDistribution,
+		S3Client:         s3Client,
+		RabbitMQ:         channels,
+		PasswordPolicy:   utils.PasswordPolicy{MinLength: 8},
+	}
+
+	publishCh, _, err := pubsub.DeclareAndBind(conn, utils.ImageGoDirect, utils.ImageGoTask, utils.ImageGoTask, pubsub.QueueTypeDurable)
+	require.NoError(t, err)
+	t.Cleanup(func() { publishCh.Close() })
+
+	for _, queue := range []string{utils.TaskQueue(""), utils.PriorityTaskQueue("")} {
+		err = pubsub.SubscribeJSON(conn, utils.ImageGoDirect, queue, queue, pubsub.QueueTypeDurable, imagesvc.ProcessImage(db, dbQueries, cfg), pubsub.WithRetryDelay(imagesvc.DeferDelay))
+		require.NoError(t, err)
+	}
+
+	e := echo.New()
+	router.RegisterAPI(e, router.Options{
+		DB:        db,
+		DBQueries: dbQueries,
+		Config:    cfg,
+		Validator: validator.New(validator.WithRequiredStructEnabled()),
+		Hub:       notify.NewHub(),
+	})
+
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	return &environment{
+		db:        db,
+		dbQueries: dbQueries,
+		cfg:       cfg,
+		conn:      conn,
+		server:    server,
+	}
+}
+
+// applyMigrations runs the "up" section of every goose migration in order.
+func applyMigrations(t *testing.T, db *sql.DB) {
+	t.Helper()
+	files, err := filepath.Glob("../../sql/schema/*.sql")
+	require.NoError(t, err)
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		up, _, _ := strings.Cut(string(content), "-- +goose down")
+		up = strings.TrimPrefix(strings.TrimSpace(up), "-- +goose up")
+		_, err = db.Exec(up)
+		require.NoError(t, err, "failed to apply %s", file)
+	}
+}
+
+// registerUser registers and logs in a user, returning its ID and access
+// token.
+func registerUser(t *testing.T, env *environment, email string) (string, string) {
+	t.Helper()
+	res := doJSON(t, env.server.URL+"/api/v1/register", "", `{"email":"`+email+`","password":"password123","confirm_password":"password123"}`)
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = doJSON(t, env.server.URL+"/api/v1/login", "", `{"email":"`+email+`","password":"password123"}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var login struct {
+		Data auth.LoginResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&login))
+	require.NotEmpty(t, login.Data.AccessToken)
+
+	var userID string
+	require.NoError(t, env.db.QueryRow("SELECT id FROM users WHERE email = $1", email).Scan(&userID))
+	return userID, login.Data.AccessToken
+}
+
+func doJSON(t *testing.T, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return res
+}
+
+// batchForm builds a multipart batch with a JPEG photo and a PNG watermark.
+func batchForm(t *testing.T) (*bytes.Buffer, string) {
+	t.Helper()
+	photo := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for x := 0; x < 640; x++ {
+		for y := 0; y < 480; y++ {
+			photo.Set(x, y, color.RGBA{uint8(x % 256), uint8(y % 256), 128, 255})
+		}
+	}
+	var photoBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photoBuf, photo, nil))
+
+	logo := image.NewNRGBA(image.Rect(0, 0, 100, 50))
+	for x := 0; x < 100; x++ {
+		for y := 0; y < 50; y++ {
+			logo.Set(x, y, color.NRGBA{255, 255, 255, 200})
+		}
+	}
+	var logoBuf bytes.Buffer
+	require.NoError(t, png.Encode(&logoBuf, logo))
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	require.NoError(t, w.WriteField("name", "integration"))
+	writeFile(t, w, "files", "photo.jpg", "image/jpeg", photoBuf.Bytes())
+	writeFile(t, w, "watermark", "logo.png", "image/png", logoBuf.Bytes())
+	require.NoError(t, w.Close())
+	return &body, w.FormDataContentType()
+}
+
+func writeFile(t *testing.T, w *multipart.Writer, field, filename, contentType string, data []byte) {
+	t.Helper()
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, filename))
+	header.Set("Content-Type", contentType)
+	part, err := w.CreatePart(header)
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+}
+
+func latestBatchID(t *testing.T, env *environment) string {
+	t.Helper()
+	var id string
+	err := env.db.QueryRow("SELECT id FROM batches ORDER BY created_at DESC LIMIT 1").Scan(&id)
+	require.NoError(t, err)
+	return id
+}
@@ -0,0 +1,126 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateBatchLocksQuota checks that creating a batch waits for the lock
+// on the user, so concurrent creates count the active batches one after
+// another.
+func TestCreateBatchLocksQuota(t *testing.T) {
+	env := setupEnvironment(t)
+	env.cfg.MaxActiveBatches = 1
+	userID, accessToken := registerUser(t, env, "quota-lock@example.com")
+
+	tx, err := env.db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	_, err = tx.Exec("SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE", userID)
+	require.NoError(t, err)
+
+	form, contentType := batchForm(t)
+	req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", form)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	done := make(chan int, 1)
+	go func() {
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			done <- 0
+			return
+		}
+		res.Body.Close()
+		done <- res.StatusCode
+	}()
+
+	select {
+	case status := <-done:
+		t.Fatalf("create finished with %d while the user was locked", status)
+	case <-time.After(time.Second):
+	}
+	require.NoError(t, tx.Rollback())
+	select {
+	case status := <-done:
+		assert.Equal(t, http.StatusCreated, status)
+	case <-time.After(30 * time.Second):
+		t.Fatal("create did not finish once the user was unlocked")
+	}
+}
+
+// TestPollWaitingBatches checks that pollers keep a user at their limit and
+// start a waiting batch through the task outbox once there is room.
+func TestPollWaitingBatches(t *testing.T) {
+	env := setupEnvironment(t)
+	env.cfg.MaxActiveBatches = 1
+	userID, _ := registerUser(t, env, "quota-poll@example.com")
+
+	photo := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 48; y++ {
+			photo.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 32, 255})
+		}
+	}
+	var photoBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photoBuf, photo, nil))
+	_, err := env.cfg.S3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(env.cfg.S3Bucket),
+		Key:         aws.String("raw/quota.jpg"),
+		Body:        bytes.NewReader(photoBuf.Bytes()),
+		ContentType: aws.String("image/jpeg"),
+	})
+	require.NoError(t, err)
+
+	seed := func(waiting bool) string {
+		t.Helper()
+		var batchID string
+		err := env.db.QueryRow("WITH b AS (INSERT INTO batches(user_id, waiting_since) VALUES ($1, CASE WHEN $2::bool THEN NOW() END) RETURNING id) INSERT INTO images(batch_id, key, original_url) SELECT id, 'raw/quota.jpg', 'https://cdn.image-go.test/raw/quota.jpg' FROM b RETURNING batch_id", userID, waiting).Scan(&batchID)
+		require.NoError(t, err)
+		return batchID
+	}
+	// Its task is never published, so it stays active.
+	seed(false)
+	waiting := seed(true)
+
+	poll := func() context.CancelFunc {
+		ctx, cancel := context.WithCancel(context.Background())
+		for range 2 {
+			go batch.PollWaitingBatches(ctx, env.db, env.dbQueries, env.cfg, 50*time.Millisecond)
+		}
+		return cancel
+	}
+	stop := poll()
+	time.Sleep(time.Second)
+	stop()
+	var waitingSince sql.NullTime
+	require.NoError(t, env.db.QueryRow("SELECT waiting_since FROM batches WHERE id = $1", waiting).Scan(&waitingSince))
+	assert.True(t, waitingSince.Valid, "the user is at their limit")
+
+	time.Sleep(100 * time.Millisecond)
+	env.cfg.MaxActiveBatches = 2
+	defer poll()()
+	require.Eventually(t, func() bool {
+		var status string
+		err := env.db.QueryRow("SELECT status FROM images WHERE batch_id = $1", waiting).Scan(&status)
+		return err == nil && status == "completed"
+	}, 60*time.Second, 500*time.Millisecond)
+	var outboxed int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM task_outbox").Scan(&outboxed))
+	assert.Zero(t, outboxed, "published tasks leave the outbox")
+}
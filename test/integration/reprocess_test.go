@@ -0,0 +1,102 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReprocessBatch checks that reprocessing runs the originals of a batch
+// through the pipeline again as a new batch with the requested settings,
+// skipping cancelled images and leaving the source untouched.
+func TestReprocessBatch(t *testing.T) {
+	env := setupEnvironment(t)
+	_, accessToken := registerUser(t, env, "reprocess@example.com")
+
+	form, contentType := batchForm(t)
+	req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", form)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	sourceID := latestBatchID(t, env)
+
+	waitCompleted := func(batchID string) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			var pending int
+			err := env.db.QueryRow("SELECT COUNT(*) FROM images WHERE batch_id = $1 AND status NOT IN ('completed', 'cancelled')", batchID).Scan(&pending)
+			return err == nil && pending == 0
+		}, 60*time.Second, 500*time.Millisecond)
+	}
+	waitCompleted(sourceID)
+	_, err = env.db.Exec(`INSERT INTO images(batch_id, key, original_url, status)
+		VALUES ($1, 'raw/reprocess.jpg', 'https://cdn.image-go.test/raw/reprocess.jpg', 'cancelled')`, sourceID)
+	require.NoError(t, err)
+	var sourceKey, sourceProcessedURL string
+	err = env.db.QueryRow("SELECT key, processed_url FROM images WHERE batch_id = $1 AND status = 'completed'", sourceID).Scan(&sourceKey, &sourceProcessedURL)
+	require.NoError(t, err)
+
+	reprocess := func(batchID, body string) (batch.CreateBatchResponse, int) {
+		t.Helper()
+		res := doJSON(t, env.server.URL+"/api/v1/batches/"+batchID+"/reprocess", accessToken, body)
+		defer res.Body.Close()
+		var created struct {
+			Data batch.CreateBatchResponse `json:"data"`
+		}
+		if res.StatusCode == http.StatusCreated {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+		}
+		return created.Data, res.StatusCode
+	}
+
+	created, status := reprocess(sourceID, `{"name":"top-left","watermark_position":"top-left","watermark_opacity":20}`)
+	require.Equal(t, http.StatusCreated, status)
+	require.Len(t, created.Images, 1, "cancelled images are not reprocessed")
+	assert.Equal(t, sourceKey, created.Images[0].Key, "the original is reused")
+
+	var name, position string
+	var opacity, scale, sourceScale int
+	err = env.db.QueryRow("SELECT name, watermark_position, watermark_opacity, watermark_scale FROM batches WHERE id = $1", created.ID).Scan(&name, &position, &opacity, &scale)
+	require.NoError(t, err)
+	require.NoError(t, env.db.QueryRow("SELECT watermark_scale FROM batches WHERE id = $1", sourceID).Scan(&sourceScale))
+	assert.Equal(t, "top-left", name)
+	assert.Equal(t, "top-left", position)
+	assert.Equal(t, 20, opacity)
+	assert.Equal(t, sourceScale, scale, "settings not in the request are copied")
+
+	waitCompleted(created.ID.String())
+	var processedURL string
+	require.NoError(t, env.db.QueryRow("SELECT processed_url FROM images WHERE batch_id = $1", created.ID).Scan(&processedURL))
+	assert.NotEqual(t, sourceProcessedURL, processedURL)
+	require.NoError(t, env.db.QueryRow("SELECT processed_url FROM images WHERE batch_id = $1 AND status = 'completed'", sourceID).Scan(&processedURL))
+	assert.Equal(t, sourceProcessedURL, processedURL, "the source is left untouched")
+
+	t.Run("refused", func(t *testing.T) {
+		_, status := reprocess(sourceID, `{"watermark_opacity":101}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		var cancelledID string
+		err := env.db.QueryRow(`WITH b AS (INSERT INTO batches(user_id) SELECT user_id FROM batches WHERE id = $1 RETURNING id)
+			INSERT INTO images(batch_id, key, original_url, status) SELECT id, 'raw/reprocess.jpg', 'https://cdn.image-go.test/raw/reprocess.jpg', 'cancelled' FROM b
+			RETURNING batch_id`, sourceID).Scan(&cancelledID)
+		require.NoError(t, err)
+		_, status = reprocess(cancelledID, `{}`)
+		assert.Equal(t, http.StatusConflict, status, "nothing to reprocess")
+
+		_, err = env.db.Exec("UPDATE batches SET archive_status = 'archived' WHERE id = $1", sourceID)
+		require.NoError(t, err)
+		_, status = reprocess(sourceID, `{}`)
+		assert.Equal(t, http.StatusConflict, status, "archived")
+	})
+}
@@ -0,0 +1,131 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchBatches(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "search@example.com")
+	otherID, _ := registerUser(t, env, "search-other@example.com")
+
+	seed := func(owner, name, key string) {
+		t.Helper()
+		_, err := env.db.Exec("WITH b AS (INSERT INTO batches(user_id, name) VALUES ($1, $2) RETURNING id) INSERT INTO images(batch_id, key, original_url) SELECT id, $3, 'https://cdn.image-go.test/' || $3 FROM b", owner, name, key)
+		require.NoError(t, err)
+	}
+	seed(userID, "Spring Catalog", "raw/a1.jpg")
+	seed(userID, "summer", "raw/spring-catalog-cover.jpg")
+	seed(userID, "50% off", "raw/b1.jpg")
+	seed(userID, "500 items", "raw/b2.jpg")
+	seed(userID, "sale_2026", "raw/c1.jpg")
+	seed(userID, "sale-2026", "raw/c2.jpg")
+	seed(otherID, "spring catalog", "raw/d1.jpg")
+
+	search := func(q string) (int, []string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, env.server.URL+"/api/v1/batches/search?q="+url.QueryEscape(q), nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var body struct {
+			Data []struct {
+				Name string `json:"name"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		var names []string
+		for _, batch := range body.Data {
+			names = append(names, batch.Name)
+		}
+		sort.Strings(names)
+		return res.StatusCode, names
+	}
+
+	tests := []struct {
+		q      string
+		status int
+		names  []string
+	}{
+		{"spring-catalog", http.StatusOK, []string{"summer"}},
+		{"SPRING cat", http.StatusOK, []string{"Spring Catalog"}},
+		{"50%", http.StatusOK, []string{"50% off"}},
+		{"sale_", http.StatusOK, []string{"sale_2026"}},
+		{"raw/c", http.StatusOK, []string{"sale-2026", "sale_2026"}},
+		{"sp", http.StatusBadRequest, nil},
+		{"  ", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			status, names := search(tt.q)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+func TestSearchImages(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "image-search@example.com")
+
+	var batchID string
+	require.NoError(t, env.db.QueryRow("INSERT INTO batches(user_id) VALUES ($1) RETURNING id", userID).Scan(&batchID))
+	for _, filename := range []string{"50% off.jpg", "500 items.jpg", "sale_2026.jpg", "sale-2026.jpg"} {
+		_, err := env.db.Exec("INSERT INTO images(batch_id, key, original_url, filename) VALUES ($1, 'raw/a.jpg', 'https://cdn.image-go.test/raw/a.jpg', $2)", batchID, filename)
+		require.NoError(t, err)
+	}
+
+	search := func(query string) (int, []string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, env.server.URL+"/api/v1/images?"+query, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var body struct {
+			Data []struct {
+				Filename string `json:"filename"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		var filenames []string
+		for _, img := range body.Data {
+			filenames = append(filenames, img.Filename)
+		}
+		sort.Strings(filenames)
+		return res.StatusCode, filenames
+	}
+
+	long := strings.Repeat("a", 256)
+	tests := []struct {
+		name      string
+		query     string
+		status    int
+		filenames []string
+	}{
+		{"percent is literal", "filename=" + url.QueryEscape("50%"), http.StatusOK, []string{"50% off.jpg"}},
+		{"underscore is literal", "filename=" + url.QueryEscape("sale_"), http.StatusOK, []string{"sale_2026.jpg"}},
+		{"filename too long", "filename=" + long, http.StatusBadRequest, nil},
+		{"external_id too long", "external_id=" + long, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, filenames := search(tt.query)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.filenames, filenames)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetStaleImages checks which images the repair-stale-images admin
+// command picks up: pending and processing ones not updated since the cutoff,
+// leaving deleted images and those of waiting batches alone.
+func TestGetStaleImages(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, _ := registerUser(t, env, "stale@example.com")
+
+	seed := func(waiting bool, status string, age time.Duration, deleted bool) string {
+		t.Helper()
+		var imageID string
+		err := env.db.QueryRow(`WITH b AS (INSERT INTO batches(user_id, waiting_since) VALUES ($1, CASE WHEN $2::bool THEN NOW() END) RETURNING id)
+			INSERT INTO images(batch_id, key, original_url, status, updated_at, deleted_at)
+			SELECT id, 'raw/stale.jpg', 'https://cdn.image-go.test/raw/stale.jpg', $3, NOW() - make_interval(secs => $4), CASE WHEN $5::bool THEN NOW() END FROM b
+			RETURNING id`, userID, waiting, status, age.Seconds(), deleted).Scan(&imageID)
+		require.NoError(t, err)
+		return imageID
+	}
+	stalePending := seed(false, "pending", 2*time.Hour, false)
+	staleProcessing := seed(false, "processing", 3*time.Hour, false)
+	seed(false, "processing", 10*time.Minute, false)
+	seed(false, "completed", 5*time.Hour, false)
+	seed(false, "failed", 5*time.Hour, false)
+	seed(false, "pending", 5*time.Hour, true)
+	seed(true, "pending", 5*time.Hour, false)
+
+	images, err := env.dbQueries.GetStaleImages(context.Background(), time.Now().UTC().Add(-time.Hour))
+	require.NoError(t, err)
+	var ids []string
+	for _, img := range images {
+		ids = append(ids, img.ID.String())
+	}
+	want := []string{stalePending, staleProcessing}
+	sort.Strings(ids)
+	sort.Strings(want)
+	assert.Equal(t, want, ids)
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package integration
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAcceptTransfer checks that an accepted batch no longer refers to the
+// library watermark and font of the sender, and that a transfer whose batch
+// external ID the recipient already uses is refused.
+func TestAcceptTransfer(t *testing.T) {
+	env := setupEnvironment(t)
+	senderID, senderToken := registerUser(t, env, "transfer-sender@example.com")
+	recipientID, recipientToken := registerUser(t, env, "transfer-recipient@example.com")
+
+	var watermarkID, fontID string
+	require.NoError(t, env.db.QueryRow(`INSERT INTO watermarks(user_id, name, key, url, content_type, width, height, size_bytes, region)
+		VALUES ($1, 'logo', 'watermarks/logo.png', 'https://cdn.image-go.test/watermarks/logo.png', 'image/png', 100, 50, 1024, $2) RETURNING id`,
+		senderID, env.cfg.Region).Scan(&watermarkID))
+	require.NoError(t, env.db.QueryRow(`INSERT INTO fonts(user_id, name, key, format, size_bytes, region)
+		VALUES ($1, 'regular', 'fonts/regular.ttf', 'ttf', 1024, $2) RETURNING id`,
+		senderID, env.cfg.Region).Scan(&fontID))
+
+	seed := func(userID, externalID string) string {
+		t.Helper()
+		var batchID string
+		err := env.db.QueryRow("WITH b AS (INSERT INTO batches(user_id, external_id, watermark_id, watermark_font_id, watermark_text) VALUES ($1, NULLIF($2, ''), $3, $4, 'Studio') RETURNING id) INSERT INTO images(batch_id, key, original_url, status) SELECT id, 'raw/photo.jpg', 'https://cdn.image-go.test/raw/photo.jpg', 'completed' FROM b RETURNING batch_id",
+			userID, externalID, watermarkID, fontID).Scan(&batchID)
+		require.NoError(t, err)
+		return batchID
+	}
+	offer := func(batchID string) string {
+		t.Helper()
+		res := doJSON(t, env.server.URL+"/api/v1/batches/"+batchID+"/transfer", senderToken, `{"email":"transfer-recipient@example.com"}`)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		var transfer struct {
+			Data struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&transfer))
+		return transfer.Data.ID
+	}
+	accept := func(transferID string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/transfers/"+transferID+"/accept", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+recipientToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	owner := func(batchID string) (string, sql.NullString, sql.NullString) {
+		t.Helper()
+		var userID string
+		var watermark, font sql.NullString
+		require.NoError(t, env.db.QueryRow("SELECT user_id, watermark_id, watermark_font_id FROM batches WHERE id = $1", batchID).Scan(&userID, &watermark, &font))
+		return userID, watermark, font
+	}
+
+	t.Run("clears library references", func(t *testing.T) {
+		batchID := seed(senderID, "")
+		require.Equal(t, http.StatusOK, accept(offer(batchID)))
+
+		userID, watermark, font := owner(batchID)
+		assert.Equal(t, recipientID, userID)
+		assert.False(t, watermark.Valid, "the sender's watermark is not shared")
+		assert.False(t, font.Valid, "the sender's font is not shared")
+	})
+
+	t.Run("refuses a taken batch external ID", func(t *testing.T) {
+		_, err := env.db.Exec("INSERT INTO batches(user_id, external_id) VALUES ($1, 'order-1')", recipientID)
+		require.NoError(t, err)
+		batchID := seed(senderID, "order-1")
+		transferID := offer(batchID)
+
+		assert.Equal(t, http.StatusConflict, accept(transferID))
+		userID, watermark, _ := owner(batchID)
+		assert.Equal(t, senderID, userID, "the batch stays with the sender")
+		assert.True(t, watermark.Valid, "the batch keeps its watermark")
+	})
+}
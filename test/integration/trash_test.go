@@ -0,0 +1,94 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rickyroynardson/image-go/internal/batch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRestoreDeletedBatch checks that restoring a batch queues its pending
+// images and those failed because it was deleted, and that a restore whose
+// image external IDs were reused meanwhile is refused.
+func TestRestoreDeletedBatch(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "trash@example.com")
+
+	photo := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 48; y++ {
+			photo.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 32, 255})
+		}
+	}
+	var photoBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photoBuf, photo, nil))
+	_, err := env.cfg.S3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(env.cfg.S3Bucket),
+		Key:         aws.String("raw/trash.jpg"),
+		Body:        bytes.NewReader(photoBuf.Bytes()),
+		ContentType: aws.String("image/jpeg"),
+	})
+	require.NoError(t, err)
+
+	seed := func(externalID string, failureReason sql.NullString) string {
+		t.Helper()
+		var batchID string
+		require.NoError(t, env.db.QueryRow("INSERT INTO batches(user_id, deleted_at) VALUES ($1, NOW()) RETURNING id", userID).Scan(&batchID))
+		status := "pending"
+		if failureReason.Valid {
+			status = "failed"
+		}
+		_, err := env.db.Exec("INSERT INTO images(batch_id, key, original_url, external_id, status, failure_reason) VALUES ($1, 'raw/trash.jpg', 'https://cdn.image-go.test/raw/trash.jpg', NULLIF($2, ''), $3, $4)",
+			batchID, externalID, status, failureReason)
+		require.NoError(t, err)
+		return batchID
+	}
+	restore := func(batchID string) int {
+		req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches/"+batchID+"/restore", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("queues pending and delete-failed images", func(t *testing.T) {
+		pending := seed("", sql.NullString{})
+		failed := seed("", sql.NullString{String: batch.FailureBatchDeleted, Valid: true})
+		require.Equal(t, http.StatusOK, restore(pending))
+		require.Equal(t, http.StatusOK, restore(failed))
+
+		for _, batchID := range []string{pending, failed} {
+			require.Eventually(t, func() bool {
+				var status string
+				err := env.db.QueryRow("SELECT status FROM images WHERE batch_id = $1", batchID).Scan(&status)
+				return err == nil && status == "completed"
+			}, 60*time.Second, 500*time.Millisecond)
+		}
+	})
+
+	t.Run("refuses reused image external IDs", func(t *testing.T) {
+		deleted := seed("sku-1", sql.NullString{})
+		_, err := env.db.Exec("WITH b AS (INSERT INTO batches(user_id) VALUES ($1) RETURNING id) INSERT INTO images(batch_id, key, original_url, external_id, status) SELECT id, 'raw/other.jpg', 'https://cdn.image-go.test/raw/other.jpg', 'sku-1', 'completed' FROM b", userID)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusConflict, restore(deleted))
+		var deletedAt sql.NullTime
+		require.NoError(t, env.db.QueryRow("SELECT deleted_at FROM batches WHERE id = $1", deleted).Scan(&deletedAt))
+		assert.True(t, deletedAt.Valid, "the batch stays in the trash")
+	})
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfirmUpload checks that confirming a direct upload twice registers
+// a single image with the content hash of the uploaded object.
+func TestConfirmUpload(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "upload@example.com")
+
+	var batchID string
+	require.NoError(t, env.db.QueryRow("INSERT INTO batches(user_id) VALUES ($1) RETURNING id", userID).Scan(&batchID))
+
+	res := doJSON(t, env.server.URL+"/api/v1/batches/"+batchID+"/upload-token", accessToken, "")
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var token struct {
+		Data struct {
+			UploadToken string `json:"upload_token"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&token))
+	res.Body.Close()
+	uploadToken := token.Data.UploadToken
+
+	photo := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 48; y++ {
+			photo.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 64, 255})
+		}
+	}
+	var photoBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photoBuf, photo, nil))
+	key := "raw/" + batchID + "/photo.jpg"
+	_, err := env.cfg.S3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(env.cfg.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(photoBuf.Bytes()),
+		ContentType: aws.String("image/jpeg"),
+	})
+	require.NoError(t, err)
+
+	confirm := func() (int, string) {
+		t.Helper()
+		res := doJSON(t, env.server.URL+"/api/v1/uploads/confirm", uploadToken, `{"key":"`+key+`"}`)
+		defer res.Body.Close()
+		var body struct {
+			Data struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return res.StatusCode, body.Data.ID
+	}
+	status, first := confirm()
+	require.Equal(t, http.StatusCreated, status)
+	status, second := confirm()
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, first, second)
+
+	var count int
+	require.NoError(t, env.db.QueryRow("SELECT COUNT(*) FROM images WHERE batch_id = $1", batchID).Scan(&count))
+	assert.Equal(t, 1, count)
+	var contentHash string
+	require.NoError(t, env.db.QueryRow("SELECT content_hash FROM images WHERE id = $1", first).Scan(&contentHash))
+	sum := sha256.Sum256(photoBuf.Bytes())
+	assert.Equal(t, hex.EncodeToString(sum[:]), contentHash)
+
+	_, err = env.db.Exec("UPDATE batches SET deleted_at = NOW() WHERE id = $1", batchID)
+	require.NoError(t, err)
+	res = doJSON(t, env.server.URL+"/api/v1/uploads/presign", uploadToken, `{"content_type":"image/jpeg"}`)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "presigning for a deleted batch")
+}
@@ -0,0 +1,128 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteDefaultWatermark(t *testing.T) {
+	env := setupEnvironment(t)
+	userID, accessToken := registerUser(t, env, "watermark@example.com")
+
+	var watermarkID string
+	err := env.db.QueryRow(`INSERT INTO watermarks(user_id, name, key, url, content_type, width, height, size_bytes, region)
+		VALUES ($1, 'logo', 'watermarks/logo.png', 'https://cdn.image-go.test/watermarks/logo.png', 'image/png', 100, 50, 1024, $2) RETURNING id`,
+		userID, env.cfg.Region).Scan(&watermarkID)
+	require.NoError(t, err)
+	send := func(method, path string) int {
+		req, err := http.NewRequest(method, env.server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, send(http.MethodPut, "/api/v1/watermarks/"+watermarkID+"/default"))
+	require.Equal(t, http.StatusOK, send(http.MethodDelete, "/api/v1/watermarks/"+watermarkID))
+
+	var defaultID sql.NullString
+	require.NoError(t, env.db.QueryRow("SELECT default_watermark_id FROM users WHERE id = $1", userID).Scan(&defaultID))
+	assert.False(t, defaultID.Valid, "the deleted watermark is no longer the default")
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/api/v1/watermarks/"+watermarkID))
+}
+
+// TestCreateBatchManifestOverrides checks that manifest entries are stored as
+// watermark overrides of the named file only, that the overridden images are
+// processed, and that invalid manifests are refused.
+func TestCreateBatchManifestOverrides(t *testing.T) {
+	env := setupEnvironment(t)
+	_, accessToken := registerUser(t, env, "manifest@example.com")
+
+	photo := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	for x := 0; x < 320; x++ {
+		for y := 0; y < 240; y++ {
+			photo.Set(x, y, color.RGBA{uint8(x % 256), uint8(y % 256), 64, 255})
+		}
+	}
+	// The photos differ so the second is not taken for a duplicate.
+	var photoBuf, otherBuf, logoBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&photoBuf, photo, nil))
+	require.NoError(t, jpeg.Encode(&otherBuf, photo, &jpeg.Options{Quality: 90}))
+	require.NoError(t, png.Encode(&logoBuf, image.NewNRGBA(image.Rect(0, 0, 40, 20))))
+
+	create := func(manifest string) int {
+		t.Helper()
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		writeFile(t, w, "files", "portrait.jpg", "image/jpeg", photoBuf.Bytes())
+		writeFile(t, w, "files", "landscape.jpg", "image/jpeg", otherBuf.Bytes())
+		writeFile(t, w, "watermark", "logo.png", "image/png", logoBuf.Bytes())
+		require.NoError(t, w.WriteField("manifest", manifest))
+		require.NoError(t, w.Close())
+		req, err := http.NewRequest(http.MethodPost, env.server.URL+"/api/v1/batches", &body)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("invalid manifests", func(t *testing.T) {
+		for name, manifest := range map[string]string{
+			"not json":          `{"filename":`,
+			"unknown file":      `[{"filename":"missing.jpg","watermark_opacity":30}]`,
+			"named twice":       `[{"filename":"portrait.jpg"},{"filename":"portrait.jpg"}]`,
+			"invalid position":  `[{"filename":"portrait.jpg","watermark_position":"middle"}]`,
+			"opacity too large": `[{"filename":"portrait.jpg","watermark_opacity":101}]`,
+			"scale too small":   `[{"filename":"portrait.jpg","watermark_scale":0}]`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, create(manifest), name)
+		}
+	})
+
+	require.Equal(t, http.StatusCreated, create(`[{"filename":"portrait.jpg","watermark_position":"bottom-left","watermark_opacity":30,"watermark_scale":25}]`))
+	batchID := latestBatchID(t, env)
+
+	type overrides struct {
+		position sql.NullString
+		opacity  sql.NullInt32
+		scale    sql.NullInt32
+	}
+	stored := func(filename string) overrides {
+		t.Helper()
+		var o overrides
+		err := env.db.QueryRow(`SELECT watermark_position_override, watermark_opacity_override, watermark_scale_override
+			FROM images WHERE batch_id = $1 AND filename = $2`, batchID, filename).Scan(&o.position, &o.opacity, &o.scale)
+		require.NoError(t, err)
+		return o
+	}
+	assert.Equal(t, overrides{
+		position: sql.NullString{String: "bottom-left", Valid: true},
+		opacity:  sql.NullInt32{Int32: 30, Valid: true},
+		scale:    sql.NullInt32{Int32: 25, Valid: true},
+	}, stored("portrait.jpg"))
+	assert.Equal(t, overrides{}, stored("landscape.jpg"), "files missing from the manifest use the batch settings")
+
+	require.Eventually(t, func() bool {
+		var completed int
+		err := env.db.QueryRow("SELECT COUNT(*) FROM images WHERE batch_id = $1 AND status = 'completed'", batchID).Scan(&completed)
+		return err == nil && completed == 2
+	}, 60*time.Second, 500*time.Millisecond)
+}